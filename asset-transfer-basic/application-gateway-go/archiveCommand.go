@@ -0,0 +1,96 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+const archiveUsage = `usage: archive export -out <file.parquet|s3://bucket/prefix> [-page-size n]`
+
+// archiveCommand runs the archive sub-command given by args. archive export
+// writes the assets archived by the chaincode's ArchiveAsset to a Parquet file,
+// locally or as an object of an S3 bucket named after the time of the export,
+// for cold storage. The archive itself is left on the ledger.
+func archiveCommand(args []string) error {
+	if len(args) == 0 || args[0] != "export" {
+		return errors.New(archiveUsage)
+	}
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := flags.String("out", "", "Parquet file, or s3://bucket/prefix, to write the archived assets to")
+	pageSize := flags.Int("page-size", assetclient.DefaultPageSize, "archived assets read per evaluation")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New(archiveUsage)
+	}
+	var store *assetclient.S3BackupStore
+	if strings.HasPrefix(*out, "s3://") {
+		var err error
+		if store, err = assetclient.S3BackupStoreFromURL(*out); err != nil {
+			return err
+		}
+	}
+	if err := checkFunctionPolicy("GetArchivedAssets", false); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	var archived []*assetclient.ArchivedAsset
+	err = assetclient.ReadArchivedAssets(ctx, gw.GetNetwork(channelName()).GetContract(chaincodeName()), *pageSize, func(asset *assetclient.ArchivedAsset) error {
+		archived = append(archived, asset)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the archived assets: %w", err)
+	}
+	var file bytes.Buffer
+	if err := assetclient.WriteArchiveParquet(&file, archived); err != nil {
+		return err
+	}
+
+	location := *out
+	if store != nil {
+		name := "archive-" + time.Now().UTC().Format("20060102T150405Z") + ".parquet"
+		if err := store.Put(ctx, name, file.Bytes()); err != nil {
+			return err
+		}
+		location = "s3://" + store.Bucket + "/" + store.Prefix + name
+	} else if err := os.WriteFile(*out, file.Bytes(), 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d archived assets to %s\n", len(archived), location)
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+const assetDiffUsage = `usage: asset diff [-ignore fields] <id1> <id2>
+       asset diff [-ignore fields] <id> -file expected.json`
+
+// assetCommand runs the asset subcommands.
+func assetCommand(args []string) error {
+	if len(args) == 0 || args[0] != "diff" {
+		return errors.New(assetDiffUsage)
+	}
+	return assetDiffCommand(args[1:])
+}
+
+// assetDiffCommand prints the fields that differ between two assets, or between
+// an asset and the JSON object of a file, and fails when any field differs.
+// Nested objects, such as the metadata, are compared field by field.
+func assetDiffCommand(args []string) error {
+	flags := flag.NewFlagSet("asset diff", flag.ContinueOnError)
+	file := flags.String("file", "", "JSON file holding the expected asset")
+	ignore := flags.String("ignore", "updatedat", "comma separated fields left out of the comparison")
+
+	// flags may follow the asset IDs
+	var ids []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		ids = append(ids, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if (*file == "" && len(ids) != 2) || (*file != "" && len(ids) != 1) {
+		return errors.New(assetDiffUsage)
+	}
+
+	ignored := make(map[string]bool)
+	for _, field := range strings.Split(*ignore, ",") {
+		ignored[strings.TrimSpace(field)] = true
+	}
+	// the IDs of two assets always differ
+	if len(ids) == 2 {
+		ignored["ID"] = true
+	}
+
+	var expectedJSON []byte
+	if *file != "" {
+		var err error
+		if expectedJSON, err = os.ReadFile(*file); err != nil {
+			return fmt.Errorf("failed to read expected asset: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	contract := gw.GetNetwork(channelName()).GetContract(chaincodeName())
+	leftJSON, err := contract.EvaluateWithContext(ctx, "ReadAsset", client.WithArguments(ids[0]))
+	if err != nil {
+		return assetclient.NewMultiPeerError(err)
+	}
+	rightJSON := expectedJSON
+	if len(ids) == 2 {
+		if rightJSON, err = contract.EvaluateWithContext(ctx, "ReadAsset", client.WithArguments(ids[1])); err != nil {
+			return assetclient.NewMultiPeerError(err)
+		}
+	}
+
+	differences, err := diffAssets(leftJSON, rightJSON, ignored)
+	if err != nil {
+		return err
+	}
+	for _, line := range differences {
+		fmt.Println(line)
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("the assets differ in %d fields", len(differences))
+	}
+	fmt.Println("The assets match")
+	return nil
+}
+
+// diffAssets returns one line per field differing between two JSON objects,
+// formatted as "field: left -> right". Missing fields are shown as (missing).
+func diffAssets(leftJSON []byte, rightJSON []byte, ignored map[string]bool) ([]string, error) {
+	left, err := flattenAsset(leftJSON)
+	if err != nil {
+		return nil, err
+	}
+	right, err := flattenAsset(rightJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for name := range left {
+		names[name] = true
+	}
+	for name := range right {
+		names[name] = true
+	}
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		if !ignored[name] && !ignored[strings.SplitN(name, ".", 2)[0]] {
+			sortedNames = append(sortedNames, name)
+		}
+	}
+	sort.Strings(sortedNames)
+
+	var lines []string
+	for _, name := range sortedNames {
+		leftValue, inLeft := left[name]
+		rightValue, inRight := right[name]
+		if inLeft && inRight && reflect.DeepEqual(leftValue, rightValue) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s -> %s", name, displayValue(leftValue, inLeft), displayValue(rightValue, inRight)))
+	}
+	return lines, nil
+}
+
+// flattenAsset decodes a JSON object, naming the fields of nested objects
+// parent.field.
+func flattenAsset(assetJSON []byte) (map[string]any, error) {
+	var asset map[string]any
+	if err := json.Unmarshal(assetJSON, &asset); err != nil {
+		return nil, fmt.Errorf("expected a JSON object asset: %w", err)
+	}
+
+	fields := make(map[string]any)
+	var flatten func(prefix string, object map[string]any)
+	flatten = func(prefix string, object map[string]any) {
+		for name, value := range object {
+			if nested, ok := value.(map[string]any); ok {
+				flatten(prefix+name+".", nested)
+				continue
+			}
+			fields[prefix+name] = value
+		}
+	}
+	flatten("", asset)
+	return fields, nil
+}
+
+func displayValue(value any, present bool) string {
+	if !present {
+		return "(missing)"
+	}
+	valueJSON, _ := json.Marshal(value)
+	return string(valueJSON)
+}
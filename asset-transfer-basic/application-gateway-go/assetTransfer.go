@@ -11,179 +11,658 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path"
+	"strings"
 	"time"
 
+	"assetTransfer/pkg/assetclient"
+	"assetTransfer/pkg/config"
+	"assetTransfer/pkg/gatewaytest"
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	"github.com/hyperledger/fabric-gateway/pkg/hash"
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
-	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 )
 
-const (
-	mspID        = "Org1MSP"
-	cryptoPath   = "../../test-network/organizations/peerOrganizations/org1.example.com"
-	certPath     = cryptoPath + "/users/User1@org1.example.com/msp/signcerts"
-	keyPath      = cryptoPath + "/users/User1@org1.example.com/msp/keystore"
-	tlsCertPath  = cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt"
-	peerEndpoint = "dns:///localhost:7051"
-	gatewayPeer  = "peer0.org1.example.com"
+// cryptoPath is the MSP of Org1 on the test network, holding the default client
+// identity User1@org1.
+const cryptoPath = "../../test-network/organizations/peerOrganizations/org1.example.com"
+
+// settings are the global settings of the commands, set with their flag, their
+// environment variable or the -config file, in this order of precedence. The
+// settings of a command are read from the section of the file named after it.
+var settings = config.NewSet(flag.CommandLine)
+
+var configFile = settings.File("config", "ASSET_TRANSFER_CONFIG", "JSON file of settings, keyed by flag name")
+
+// The client identity and its Gateway peer, User1@org1 and peer0.org1 of the
+// test network by default. PEER_ENDPOINT and PEER_HOST_ALIAS reach another
+// peer, such as a gatewaytest server.
+var (
+	mspID        = settings.String("msp-id", "MSP_ID", "Org1MSP", "MSP ID of the client identity")
+	certPath     = settings.String("cert-path", "CERT_PATH", cryptoPath+"/users/User1@org1.example.com/msp/signcerts", "directory holding the certificate of the client identity")
+	keyPath      = settings.String("key-path", "KEY_PATH", cryptoPath+"/users/User1@org1.example.com/msp/keystore", "directory holding the private key of the client identity")
+	tlsCertPath  = settings.String("tls-cert-path", "TLS_CERT_PATH", cryptoPath+"/peers/peer0.org1.example.com/tls/ca.crt", "TLS CA certificate of the Gateway peer")
+	peerEndpoint = settings.String("peer-endpoint", "PEER_ENDPOINT", "dns:///localhost:7051", "endpoint of the Gateway peer")
+	gatewayPeer  = settings.String("peer-host-alias", "PEER_HOST_ALIAS", "peer0.org1.example.com", "name of the Gateway peer in its TLS certificate")
 )
 
-// Generate transaction ID based on current timestamp
-var now = time.Now()
-var transactionId = fmt.Sprintf("TRANS%d", now.Unix()*1e3+int64(now.Nanosecond())/1e6)
+// The chaincode the commands call, and the network it runs on.
+var (
+	channel            = settings.String("channel", "CHANNEL_NAME", "mychannel", "channel of the chaincode")
+	chaincode          = settings.String("chaincode", "CHAINCODE_NAME", "financial", "name of the chaincode")
+	consensus          = settings.String("consensus", "ORDERER_CONSENSUS", "", "consensus of the ordering service, bft for a SmartBFT network", checkConsensus)
+	commitQuorumOrgs   = settings.String("commit-quorum-orgs", "COMMIT_QUORUM_ORGS", "", "comma separated organizations whose peers must agree on commits on a BFT network, such as org1,org2")
+	gatewayRecord      = settings.String("gateway-record", "GATEWAY_RECORD", "", "golden file recording the Gateway calls, replayed by gatewaytest servers")
+	walletPath         = settings.String("wallet", "WALLET_PATH", "wallet", "directory of the wallet identities")
+	pageTokenKeySecret = settings.Secret("page-token-key", "PAGE_TOKEN_KEY", "key signing the page tokens of list, shared between users, instead of one derived from the client certificate")
+)
+
+// transactionId is the ID of the asset created by the demo, generated with the
+// strategy selected with -id-strategy.
+var transactionId string
+
+// identityLabel selects a wallet identity to connect as, instead of User1@org1 from the test network.
+var identityLabel = settings.String("identity", "IDENTITY", "", "wallet identity label to connect as, such as User1@org2")
+
+// demoParallelism bounds the number of independent demo transactions run at a time.
+var demoParallelism = settings.Int("parallel", "", 4, "maximum number of demo transactions run at a time")
+
+// maxTPS, maxInFlight and targetLatency pace the submissions of the bulk
+// commands, import and generate, so that a single job does not flood a shared
+// network.
+var (
+	maxTPS        = settings.Float("max-tps", "MAX_TPS", 0, "most transactions a bulk job submits per second, unbounded when 0", config.AtLeast(0.0))
+	maxInFlight   = settings.Int("max-in-flight", "MAX_IN_FLIGHT", 0, "most transactions a bulk job has in flight at a time, unbounded when 0", config.AtLeast(0))
+	targetLatency = settings.Duration("target-latency", "TARGET_LATENCY", 0, "submission latency above which a bulk job slows down from -max-tps, such as 2s", config.AtLeast(time.Duration(0)))
+)
+
+// idStrategy and idTemplate select how the IDs of new assets are generated.
+var (
+	idStrategy = settings.String("id-strategy", "ID_STRATEGY", "", "asset ID generation: ulid (default), uuid or sequence:<name>")
+	idTemplate = settings.String("id-template", "ID_TEMPLATE", "", "template formatting generated asset IDs, such as DLR-{date}-{id}")
+)
+
+// localLedger is the URL of a chaincode served against its in-memory ledger,
+// which the submit command runs its transactions on instead of a Fabric network.
+var localLedger = settings.String("local", "LOCAL_LEDGER_URL", "", "URL of a local ledger to submit to instead of the Fabric network, such as http://localhost:9999")
+
+const usage = `usage: assetTransfer [-config file] [-identity label] [-parallel n] [-max-tps n] [-max-in-flight n] [-target-latency d] [-id-strategy strategy] [-id-template template] [-local url] [command]
+
+commands:
+  demo       run the sample transactions (default), or a scenario file with
+             demo run --scenario <file>
+  identity   manage the identities in the wallet
+  discover   show the endorsing peers and endorsement policy of the chaincode
+  simulate   show the changes a transaction would make, without submitting it
+  list       list or export the assets, sorted with -sort balance:desc, selecting fields with -fields
+  events     print the chaincode events once each, in ledger order, or post them as CloudEvents
+  generate   create synthetic assets for performance testing
+  submit     submit any transaction, to chosen orderers with -orderers, or to
+             the local ledger of the chaincode with -local, waiting for the
+             orderers, the commit or the commit on N organizations with -wait
+  import     create the assets of a CSV or Excel file or, with -format legacy,
+             of a core-banking extract, or replay the failed rows with
+             import -replay-dlq <file>; import template writes a template
+             of the file from the asset schema
+  asset      compare two assets, or an asset with a file, with asset diff
+  backup     stream the world state writes to an encrypted backup log, in a
+             file or s3://bucket/prefix, resuming after its last block
+  restore    replay a backup log into the channel, such as a new channel
+  replay     submit the transactions of the blocks of the channel again to
+             another channel or chaincode version, at the original pace or
+             -speed times faster, optionally transformed with -transform
+  version    show the build of the chaincode serving the Gateway peer
+  archive    write the archived assets to a Parquet file or S3, with
+             archive export -out <file|s3://bucket/prefix>
+  slo        summarize the commit latency log of the REST server against its
+             objective, with slo report -log <file> [-hours n]
+  config     show the settings in effect and where they come from, with
+             config print-effective`
 
 func main() {
-	clientConnection := newGrpcConnection()
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if err := settings.Resolve(os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	switch flag.Arg(0) {
+	case "":
+		if err := runDemo(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "demo":
+		if err := scenarioCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "identity":
+		if err := identityCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "discover":
+		if err := discoverCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "simulate":
+		if err := simulateCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "list":
+		if err := listCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "generate":
+		if err := generateCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "submit":
+		if err := submitCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "import":
+		if err := importCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "asset":
+		if err := assetCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "events":
+		if err := eventsCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "backup":
+		if err := backupCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "restore":
+		if err := restoreCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "replay":
+		if err := replayCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "version":
+		if err := versionCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "archive":
+		if err := archiveCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "slo":
+		if err := sloCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "config":
+		if err := configCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// runDemo connects to the Gateway and runs the sample transactions, closing the
+// connection whatever the outcome.
+func runDemo() error {
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
 	defer clientConnection.Close()
 
-	id := newIdentity()
-	sign := newSign()
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	// interrupting the demo cancels the transactions in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	network := gw.GetNetwork(channelName())
+	contract := network.GetContract(chaincodeName())
+
+	if transactionId, err = newAssetID(ctx, contract); err != nil {
+		return err
+	}
+
+	// The steps of a stage are independent of each other, and each stage needs
+	// the ledger left by the previous one.
+	stages := [][]demoStep{
+		{{"InitLedger", initLedger}},
+		{{"GetAllTransactions", getAllTransactions}, {"CreateTransaction", createTransaction}, {"UpdateTransaction", exampleErrorHandling}},
+		{{"ReadTransaction", readTransactionByID}},
+		{{"TransferFunds", transferFunds}},
+	}
+	for _, stage := range stages {
+		if err := runDemoStage(ctx, contract, stage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// demoStep is a sample transaction of the demo, writing its progress to out.
+type demoStep struct {
+	name string
+	run  func(ctx context.Context, contract *client.Contract, out io.Writer) error
+}
+
+// runDemoStage runs the steps of a stage concurrently, at most -parallel at a
+// time. The output of every step is printed in order once all have finished,
+// and the errors of the failed steps are returned together.
+func runDemoStage(ctx context.Context, contract *client.Contract, steps []demoStep) error {
+	group, ctx := assetclient.NewGroup(ctx, *demoParallelism)
+	outputs := make([]bytes.Buffer, len(steps))
+	for i, step := range steps {
+		out := &outputs[i]
+		group.Go(step.name, func(ctx context.Context) error {
+			return step.run(ctx, contract, out)
+		})
+	}
+	err := group.Wait()
+
+	for _, output := range outputs {
+		os.Stdout.Write(output.Bytes())
+	}
+	return err
+}
 
-	gw, err := client.Connect(
-		id,
+// connectGateway connects to the Gateway over clientConnection as the given identity.
+func connectGateway(clientConnection grpc.ClientConnInterface, id identity.Identity, sign identity.Sign) (*client.Gateway, error) {
+	options := []client.ConnectOption{
 		client.WithSign(sign),
 		client.WithHash(hash.SHA256),
 		client.WithClientConnection(clientConnection),
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
+		client.WithEvaluateTimeout(5 * time.Second),
+		client.WithEndorseTimeout(15 * time.Second),
+	}
+	consensus, err := ordererConsensus()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer gw.Close()
+	return client.Connect(id, append(options, consensus.TimeoutOptions()...)...)
+}
+
+// newBulkPacer returns the pacer of the submissions of a bulk command, set with
+// -max-tps, -max-in-flight and -target-latency.
+func newBulkPacer() (*assetclient.Pacer, error) {
+	return assetclient.NewPacer(assetclient.PacerConfig{
+		MaxTPS:        *maxTPS,
+		MaxInFlight:   *maxInFlight,
+		TargetLatency: *targetLatency,
+	})
+}
+
+// newAssetID generates the ID of a new asset with the strategy selected with -id-strategy.
+func newAssetID(ctx context.Context, contract *client.Contract) (string, error) {
+	generator, err := assetclient.NewIDGenerator(*idStrategy, *idTemplate, contract)
+	if err != nil {
+		return "", err
+	}
+	return generator.NextID(ctx)
+}
 
-	chaincodeName := "financial"
-	if ccname := os.Getenv("CHAINCODE_NAME"); ccname != "" {
-		chaincodeName = ccname
+// ordererConsensus returns the consensus type of the ordering service, set to bft
+// with -consensus for a SmartBFT network.
+func ordererConsensus() (assetclient.Consensus, error) {
+	return assetclient.ParseConsensus(*consensus)
+}
+
+// checkConsensus validates the -consensus setting.
+func checkConsensus(value string) error {
+	_, err := assetclient.ParseConsensus(value)
+	return err
+}
+
+// waitForCommit waits for the commit status of a transaction. On a BFT network
+// with -commit-quorum-orgs set, such as org1,org2, the peers of every listed
+// organization must agree on the status.
+func waitForCommit(ctx context.Context, commit *client.Commit) (*client.Status, error) {
+	quorumOrgs := *commitQuorumOrgs
+	if consensus, _ := ordererConsensus(); consensus != assetclient.ConsensusBFT || quorumOrgs == "" {
+		return commit.StatusWithContext(ctx)
+	}
+
+	_, id, sign, err := clientIdentity()
+	if err != nil {
+		return nil, err
+	}
+	peers := make(map[string]*client.Gateway)
+	for _, org := range strings.Split(quorumOrgs, ",") {
+		peer, err := testNetworkPeer(org)
+		if err != nil {
+			return nil, err
+		}
+		clientConnection, err := newGrpcConnection(peer)
+		if err != nil {
+			return nil, err
+		}
+		defer clientConnection.Close()
+
+		gw, err := connectGateway(clientConnection, id, sign)
+		if err != nil {
+			return nil, err
+		}
+		defer gw.Close()
+		peers[peer.gatewayPeer] = gw
 	}
 
-	channelName := "mychannel"
-	if cname := os.Getenv("CHANNEL_NAME"); cname != "" {
-		channelName = cname
+	status, err := assetclient.QuorumCommitStatus(ctx, commit, len(peers), peers)
+	if err != nil {
+		return nil, err
 	}
+	fmt.Printf("*** Commit confirmed in block %d by %d peers\n", status.BlockNumber, status.Confirmations)
+	return &client.Status{Code: status.Code, Successful: status.Successful, TransactionID: status.TransactionID, BlockNumber: status.BlockNumber}, nil
+}
 
-	network := gw.GetNetwork(channelName)
-	contract := network.GetContract(chaincodeName)
+// defaultIdentityName names the identity used without -identity in the
+// function policy.
+const defaultIdentityName = "default"
 
-	initLedger(contract)
-	getAllTransactions(contract)
-	createTransaction(contract)
-	readTransactionByID(contract)
-	transferFunds(contract)
-	exampleErrorHandling(contract)
+// checkFunctionPolicy fails unless the function policy of the FUNCTION_POLICY
+// file, if any, allows the identity selected with -identity to call function,
+// submitting it when submit is set.
+func checkFunctionPolicy(function string, submit bool) error {
+	policy, err := assetclient.FunctionPolicyFromEnv()
+	if err != nil {
+		return err
+	}
+	name := *identityLabel
+	if name == "" {
+		name = defaultIdentityName
+	}
+	return policy.Check(name, function, submit)
+}
+
+// clientIdentity returns the identity selected with -identity and the Gateway
+// peer of its organization, or User1@org1 of the test network by default.
+func clientIdentity() (peerConfig, *identity.X509Identity, identity.Sign, error) {
+	if *identityLabel == "" {
+		id, err := newIdentity()
+		if err != nil {
+			return peerConfig{}, nil, nil, err
+		}
+		sign, err := newSign()
+		if err != nil {
+			return peerConfig{}, nil, nil, err
+		}
+		return defaultPeer(), id, sign, nil
+	}
+
+	walletID, err := newWallet().get(*identityLabel)
+	if err != nil {
+		return peerConfig{}, nil, nil, err
+	}
+	id, err := walletID.x509Identity()
+	if err != nil {
+		return peerConfig{}, nil, nil, err
+	}
+	sign, err := walletID.sign()
+	if err != nil {
+		return peerConfig{}, nil, nil, err
+	}
+
+	peer := defaultPeer()
+	if walletID.Org != "" {
+		if peer, err = testNetworkPeer(walletID.Org); err != nil {
+			return peerConfig{}, nil, nil, err
+		}
+	}
+	return peer, id, sign, nil
+}
+
+// chaincodeName returns the chaincode to use, set with -chaincode.
+func chaincodeName() string {
+	return *chaincode
+}
+
+// channelName returns the channel to use, set with -channel.
+func channelName() string {
+	return *channel
+}
+
+// peerConfig describes how to reach the Gateway peer of an organization.
+type peerConfig struct {
+	endpoint    string
+	gatewayPeer string
+	tlsCertPath string
+}
+
+// defaultPeer returns the Gateway peer of Org1 on the test network, or the peer
+// at -peer-endpoint named -peer-host-alias in its TLS certificate.
+func defaultPeer() peerConfig {
+	return peerConfig{
+		endpoint:    *peerEndpoint,
+		gatewayPeer: *gatewayPeer,
+		tlsCertPath: *tlsCertPath,
+	}
+}
+
+// testNetworkPeer returns the peer0 of a test network organization, such as org2.
+func testNetworkPeer(org string) (peerConfig, error) {
+	ports := map[string]int{"org1": 7051, "org2": 9051, "org3": 11051}
+	port, ok := ports[org]
+	if !ok {
+		return peerConfig{}, fmt.Errorf("no test network peer is known for organization %s", org)
+	}
+
+	orgPath := fmt.Sprintf("../../test-network/organizations/peerOrganizations/%s.example.com", org)
+	return peerConfig{
+		endpoint:    fmt.Sprintf("dns:///localhost:%d", port),
+		gatewayPeer: fmt.Sprintf("peer0.%s.example.com", org),
+		tlsCertPath: fmt.Sprintf("%s/peers/peer0.%s.example.com/tls/ca.crt", orgPath, org),
+	}, nil
 }
 
 // newGrpcConnection creates a gRPC connection to the Gateway server.
-func newGrpcConnection() *grpc.ClientConn {
-	certificatePEM, err := os.ReadFile(tlsCertPath)
+func newGrpcConnection(peer peerConfig) (*grpc.ClientConn, error) {
+	certificatePEM, err := assetclient.ReadPEM("TLS_CA_PEM", func() ([]byte, error) { return os.ReadFile(peer.tlsCertPath) })
 	if err != nil {
-		panic(fmt.Errorf("failed to read TLS certificate file: %w", err))
+		return nil, fmt.Errorf("failed to read TLS certificate file: %w", err)
 	}
 
 	certificate, err := identity.CertificateFromPEM(certificatePEM)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	certPool := x509.NewCertPool()
 	certPool.AddCert(certificate)
-	transportCredentials := credentials.NewClientTLSFromCert(certPool, gatewayPeer)
 
-	connection, err := grpc.NewClient(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	// TLS_PIN_SHA256, TLS_MIN_VERSION and TLS_CIPHER_SUITES harden the connection
+	// beyond trusting the CA, and are checked with a handshake before connecting
+	tlsOptions, err := assetclient.TLSOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := tlsOptions.Config(certPool, peer.gatewayPeer)
+	if tlsOptions.IsSet() {
+		if err := assetclient.CheckTLS(context.Background(), peer.endpoint, tlsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	options := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	// -gateway-record records the transactions of the command in a golden
+	// file, replayed by gatewaytest servers
+	if path := *gatewayRecord; path != "" {
+		recorder, err := gatewaytest.OpenRecorder(path)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.WithChainUnaryInterceptor(recorder.Interceptor()))
+	}
+
+	connection, err := grpc.NewClient(peer.endpoint, options...)
 	if err != nil {
-		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
 	}
 
-	return connection
+	return connection, nil
 }
 
-// Helper functions remain the same
-func newIdentity() *identity.X509Identity {
-	// ... (same as original)
-	return id
+// newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
+func newIdentity() (*identity.X509Identity, error) {
+	certificatePEM, err := assetclient.ReadPEM("CERT_PEM", func() ([]byte, error) { return readFirstFile(*certPath) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return identity.NewX509Identity(*mspID, certificate)
 }
 
-func newSign() identity.Sign {
-	// ... (same as original)
-	return sign
+// newSign creates a function that generates a digital signature from a message digest using a private key.
+func newSign() (identity.Sign, error) {
+	privateKeyPEM, err := assetclient.ReadPEM("KEY_PEM", func() ([]byte, error) { return readFirstFile(*keyPath) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
+	}
+
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return identity.NewPrivateKeySign(privateKey)
 }
 
 func readFirstFile(dirPath string) ([]byte, error) {
-	// ... (same as original)
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	fileNames, err := dir.Readdirnames(1)
+	if err != nil {
+		return nil, err
+	}
+
 	return os.ReadFile(path.Join(dirPath, fileNames[0]))
 }
 
 // Modified transaction functions for the new business logic
-func initLedger(contract *client.Contract) {
-	fmt.Printf("\n--> Submit Transaction: InitLedger, initializing the financial ledger\n")
+func initLedger(ctx context.Context, contract *client.Contract, out io.Writer) error {
+	fmt.Fprintf(out, "\n--> Submit Transaction: InitLedger, initializing the financial ledger\n")
 
-	_, err := contract.SubmitTransaction("InitLedger")
+	_, err := contract.SubmitWithContext(ctx, "InitLedger")
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+		return fmt.Errorf("failed to submit transaction: %w", err)
 	}
 
-	fmt.Printf("*** Transaction committed successfully\n")
+	fmt.Fprintf(out, "*** Transaction committed successfully\n")
+	return nil
 }
 
-func getAllTransactions(contract *client.Contract) {
-	fmt.Println("\n--> Evaluate Transaction: GetAllTransactions, returns all financial transactions on the ledger")
+func getAllTransactions(ctx context.Context, contract *client.Contract, out io.Writer) error {
+	fmt.Fprintln(out, "\n--> Evaluate Transaction: GetAllTransactions, returns all financial transactions on the ledger")
 
-	evaluateResult, err := contract.EvaluateTransaction("GetAllTransactions")
+	evaluateResult, err := contract.EvaluateWithContext(ctx, "GetAllTransactions")
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		return fmt.Errorf("failed to evaluate transaction: %w", err)
+	}
+	result, err := formatJSON(evaluateResult)
+	if err != nil {
+		return err
 	}
-	result := formatJSON(evaluateResult)
 
-	fmt.Printf("*** Result:%s\n", result)
+	fmt.Fprintf(out, "*** Result:%s\n", result)
+	return nil
 }
 
-func createTransaction(contract *client.Contract) {
-	fmt.Printf("\n--> Submit Transaction: CreateTransaction, creates new financial transaction\n")
+func createTransaction(ctx context.Context, contract *client.Contract, out io.Writer) error {
+	fmt.Fprintf(out, "\n--> Submit Transaction: CreateTransaction, creates new financial transaction\n")
 
-	_, err := contract.SubmitTransaction(
+	_, err := contract.SubmitWithContext(
+		ctx,
 		"CreateTransaction",
-		transactionId,
-		"DEALER101",
-		"9877890123",
-		"1234",
-		"1000.00",
-		"ACTIVE",
-		"500.00",
-		"CREDIT",
-		"Initial deposit",
+		client.WithArguments(
+			transactionId,
+			"DEALER101",
+			"9877890123",
+			"1234",
+			"1000.00",
+			"ACTIVE",
+			"500.00",
+			"CREDIT",
+			"Initial deposit",
+		),
 	)
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+		return fmt.Errorf("failed to submit transaction: %w", err)
 	}
 
-	fmt.Printf("*** Transaction committed successfully\n")
+	fmt.Fprintf(out, "*** Transaction committed successfully\n")
+	return nil
 }
 
-func readTransactionByID(contract *client.Contract) {
-	fmt.Printf("\n--> Evaluate Transaction: ReadTransaction, returns transaction details\n")
+func readTransactionByID(ctx context.Context, contract *client.Contract, out io.Writer) error {
+	fmt.Fprintf(out, "\n--> Evaluate Transaction: ReadTransaction, returns transaction details\n")
 
-	evaluateResult, err := contract.EvaluateTransaction("ReadTransaction", transactionId)
+	evaluateResult, err := contract.EvaluateWithContext(ctx, "ReadTransaction", client.WithArguments(transactionId))
+	if err != nil {
+		return fmt.Errorf("failed to evaluate transaction: %w", err)
+	}
+	result, err := formatJSON(evaluateResult)
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		return err
 	}
-	result := formatJSON(evaluateResult)
 
-	fmt.Printf("*** Result:%s\n", result)
+	fmt.Fprintf(out, "*** Result:%s\n", result)
+	return nil
 }
 
-func transferFunds(contract *client.Contract) {
-	fmt.Printf("\n--> Async Submit Transaction: TransferFunds, processes a fund transfer\n")
+func transferFunds(ctx context.Context, contract *client.Contract, out io.Writer) error {
+	fmt.Fprintf(out, "\n--> Async Submit Transaction: TransferFunds, processes a fund transfer\n")
 
-	submitResult, commit, err := contract.SubmitAsync(
+	submitResult, commit, err := contract.SubmitAsyncWithContext(
+		ctx,
 		"TransferFunds",
 		client.WithArguments(
 			transactionId,
@@ -193,33 +672,77 @@ func transferFunds(contract *client.Contract) {
 		),
 	)
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
+		return fmt.Errorf("failed to submit transaction asynchronously: %w", err)
 	}
 
-	fmt.Printf("\n*** Successfully submitted transfer transaction: %s\n", string(submitResult))
-	fmt.Println("*** Waiting for transaction commit.")
+	fmt.Fprintf(out, "\n*** Successfully submitted transfer transaction: %s\n", string(submitResult))
+	fmt.Fprintln(out, "*** Waiting for transaction commit.")
 
-	if commitStatus, err := commit.Status(); err != nil {
-		panic(fmt.Errorf("failed to get commit status: %w", err))
+	if commitStatus, err := waitForCommit(ctx, commit); err != nil {
+		return fmt.Errorf("failed to get commit status: %w", err)
 	} else if !commitStatus.Successful {
-		panic(fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code)))
+		return fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code))
 	}
 
-	fmt.Printf("*** Transaction committed successfully\n")
+	fmt.Fprintf(out, "*** Transaction committed successfully\n")
+	return nil
 }
 
 // Error handling remains similar but with updated context
-func exampleErrorHandling(contract *client.Contract) {
-	fmt.Println("\n--> Submit Transaction: UpdateTransaction TRANS123, transaction does not exist and should return an error")
+func exampleErrorHandling(ctx context.Context, contract *client.Contract, out io.Writer) error {
+	fmt.Fprintln(out, "\n--> Submit Transaction: UpdateTransaction TRANS123, transaction does not exist and should return an error")
 
-	_, err := contract.SubmitTransaction("UpdateTransaction", "TRANS123", "1000.00", "CREDIT", "Invalid transaction")
-	// ... (rest of error handling remains the same)
+	_, err := contract.SubmitWithContext(ctx, "UpdateTransaction", client.WithArguments("TRANS123", "1000.00", "CREDIT", "Invalid transaction"))
+	if err == nil {
+		return errors.New("******** FAILED to return an error")
+	}
+
+	fmt.Fprintln(out, "*** Successfully caught the error:")
+
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	var commitErr *client.CommitError
+
+	if errors.As(err, &endorseErr) {
+		fmt.Fprintf(out, "Endorse error for transaction %s with gRPC status %v: %s\n", endorseErr.TransactionID, status.Code(endorseErr), endorseErr)
+	} else if errors.As(err, &submitErr) {
+		fmt.Fprintf(out, "Submit error for transaction %s with gRPC status %v: %s\n", submitErr.TransactionID, status.Code(submitErr), submitErr)
+	} else if errors.As(err, &commitStatusErr) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintf(out, "Timeout waiting for transaction %s commit status: %s", commitStatusErr.TransactionID, commitStatusErr)
+		} else {
+			fmt.Fprintf(out, "Error obtaining commit status for transaction %s with gRPC status %v: %s\n", commitStatusErr.TransactionID, status.Code(commitStatusErr), commitStatusErr)
+		}
+	} else if errors.As(err, &commitErr) {
+		fmt.Fprintf(out, "Transaction %s failed to commit with status %d: %s\n", commitErr.TransactionID, int32(commitErr.Code), err)
+	} else {
+		return fmt.Errorf("unexpected error type %T: %w", err, err)
+	}
+
+	// Any error that originates from a peer or orderer node external to the gateway will have its details
+	// embedded within the gRPC status error. The following code shows how to extract that.
+	multiErr := assetclient.NewMultiPeerError(err)
+	if len(multiErr.Peers) > 0 {
+		fmt.Fprintln(out, "Error Details:")
+
+		for _, peer := range multiErr.Peers {
+			fmt.Fprintf(out, "- address: %s; mspId: %s; message: %s\n", peer.Address, peer.MspID, peer.Message)
+		}
+	}
+
+	// Domain errors returned by the chaincode are decoded into typed errors.
+	var chaincodeErr *assetclient.ChaincodeError
+	if errors.As(multiErr, &chaincodeErr) {
+		fmt.Fprintf(out, "Chaincode error %s (asset not found: %t): %s\n", chaincodeErr.Code, errors.Is(multiErr, assetclient.ErrAssetNotFound), chaincodeErr.Message)
+	}
+	return nil
 }
 
-func formatJSON(data []byte) string {
+func formatJSON(data []byte) (string, error) {
 	var prettyJSON bytes.Buffer
 	if err := json.Indent(&prettyJSON, data, "", "  "); err != nil {
-		panic(fmt.Errorf("failed to parse JSON: %w", err))
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
 	}
-	return prettyJSON.String()
+	return prettyJSON.String(), nil
 }
@@ -0,0 +1,196 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// backupCommand streams the world state writes of the chaincode, block by
+// block, to an encrypted append-only backup log in a local file or S3, until
+// interrupted. It resumes after the last block already in the log, so it can
+// run as a service restarted at will. The log is encrypted with the key of
+// BACKUP_KEY. Private data is not backed up, as blocks only carry its hashes.
+func backupCommand(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	commandSettings := settings.Sub(flags)
+	location := commandSettings.String("log", "BACKUP_LOG", "", "backup log file, or s3://bucket/prefix")
+	startBlock := flags.Uint64("start-block", 0, "block to back up from when the log is empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := commandSettings.Resolve(os.LookupEnv); err != nil {
+		return err
+	}
+	if *location == "" {
+		return errors.New("the backup log must be set with -log or BACKUP_LOG")
+	}
+	key, err := assetclient.BackupKeyFromEnv()
+	if err != nil {
+		return err
+	}
+	store, err := assetclient.BackupStoreFor(*location)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	backup, err := assetclient.OpenBackupLog(ctx, store, key)
+	if err != nil {
+		return err
+	}
+	start := max(*startBlock, backup.NextBlock())
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	blocks, err := gw.GetNetwork(channelName()).BlockEvents(ctx, client.WithStartBlock(start))
+	if err != nil {
+		return fmt.Errorf("failed to read blocks: %w", err)
+	}
+	fmt.Printf("Backing up %s on %s from block %d to %s\n", chaincodeName(), channelName(), start, *location)
+
+	for block := range blocks {
+		writes, err := assetclient.BlockWrites(block, chaincodeName())
+		if err != nil {
+			return err
+		}
+		if len(writes.Writes) == 0 {
+			continue
+		}
+		if err := backup.Append(ctx, writes); err != nil {
+			return err
+		}
+		fmt.Printf("%d\t%d writes\n", writes.Number, len(writes.Writes))
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("the block stream ended, restart to resume from block %d", backup.NextBlock())
+}
+
+// restoreCommand replays a backup log into the channel of -channel, such as
+// a new channel when recovering from a disaster, with the RestoreState
+// transaction function. Only the last write of every key is replayed, deleted
+// keys being skipped, in transactions of -batch writes. The chaincode must have
+// the state-restore feature flag on, and the identity must be an admin.
+func restoreCommand(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	commandSettings := settings.Sub(flags)
+	location := commandSettings.String("log", "BACKUP_LOG", "", "backup log file, or s3://bucket/prefix")
+	batch := flags.Int("batch", 200, "writes per RestoreState transaction, up to 500")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := commandSettings.Resolve(os.LookupEnv); err != nil {
+		return err
+	}
+	if *location == "" {
+		return errors.New("the backup log must be set with -log or BACKUP_LOG")
+	}
+	if *batch <= 0 || *batch > 500 {
+		return fmt.Errorf("-batch must be between 1 and 500, got %d", *batch)
+	}
+	if err := checkFunctionPolicy("RestoreState", true); err != nil {
+		return err
+	}
+	key, err := assetclient.BackupKeyFromEnv()
+	if err != nil {
+		return err
+	}
+	store, err := assetclient.BackupStoreFor(*location)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// keep the last write of every key, in the order the keys were first written
+	var writes []assetclient.BackupWrite
+	latest := map[string]int{}
+	lastBlock := uint64(0)
+	err = assetclient.ReadBackup(ctx, store, key, func(block *assetclient.BackupBlock) error {
+		for _, write := range block.Writes {
+			if i, ok := latest[write.Key]; ok {
+				writes[i] = write
+			} else {
+				latest[write.Key] = len(writes)
+				writes = append(writes, write)
+			}
+		}
+		lastBlock = block.Number
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	type stateWrite struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	var live []stateWrite
+	for _, write := range writes {
+		if !write.IsDelete {
+			live = append(live, stateWrite{Key: write.Key, Value: string(write.Value)})
+		}
+	}
+	fmt.Printf("Restoring %d keys backed up until block %d to %s on %s\n", len(live), lastBlock, chaincodeName(), channelName())
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	contract := gw.GetNetwork(channelName()).GetContract(chaincodeName())
+	for start := 0; start < len(live); start += *batch {
+		end := min(start+*batch, len(live))
+		writesJSON, err := json.Marshal(live[start:end])
+		if err != nil {
+			return err
+		}
+		if _, err := contract.SubmitWithContext(ctx, "RestoreState", client.WithArguments(string(writesJSON))); err != nil {
+			return fmt.Errorf("failed to restore keys %d to %d: %w", start+1, end, assetclient.NewMultiPeerError(err))
+		}
+		fmt.Printf("Restored %d of %d keys\n", end, len(live))
+	}
+	return nil
+}
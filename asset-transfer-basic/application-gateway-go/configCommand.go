@@ -0,0 +1,58 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+const configUsage = `usage: config print-effective [-json]`
+
+// configCommand prints the global settings in effect, with where each comes
+// from: its flag, its environment variable, the -config file or its default.
+// Secrets are masked.
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print-effective" {
+		return errors.New(configUsage)
+	}
+	flags := flag.NewFlagSet("print-effective", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the settings as JSON")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	effective := settings.Effective()
+	if *asJSON {
+		type effectiveSetting struct {
+			Name   string `json:"name"`
+			Value  string `json:"value"`
+			Source string `json:"source"`
+			Env    string `json:"env,omitempty"`
+		}
+		output := make([]effectiveSetting, 0, len(effective))
+		for _, setting := range effective {
+			output = append(output, effectiveSetting{setting.Name, setting.Value, setting.Source.String(), setting.Env})
+		}
+		outputJSON, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(outputJSON))
+		return nil
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "SETTING\tVALUE\tSOURCE\tENV")
+	for _, setting := range effective {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", setting.Name, setting.Value, setting.Source, setting.Env)
+	}
+	return table.Flush()
+}
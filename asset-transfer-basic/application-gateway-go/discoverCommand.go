@@ -0,0 +1,74 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+// discoverCommand lists the peers able to endorse the chaincode, the effective
+// endorsement policy and the minimum set of organizations satisfying it.
+func discoverCommand(args []string) error {
+	flags := flag.NewFlagSet("discover", flag.ContinueOnError)
+	channel := flags.String("channel", channelName(), "channel of the chaincode")
+	chaincode := flags.String("chaincode", chaincodeName(), "chaincode to plan endorsements for")
+	collections := flags.String("collections", "", "comma separated private data collections the transaction writes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	var collectionNames []string
+	if *collections != "" {
+		collectionNames = strings.Split(*collections, ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	plan, err := assetclient.NewDiscoverer(clientConnection, id, sign).Endorsers(ctx, *channel, *chaincode, collectionNames...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Endorsement policy: %s\n", plan.Policy())
+	fmt.Printf("Minimum endorsing organizations: %s\n\n", strings.Join(plan.MinimumOrganizations(), ", "))
+
+	groups := make([]string, 0, len(plan.Groups))
+	for group := range plan.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "GROUP\tMSPID\tENDPOINT\tLEDGER HEIGHT")
+	for _, group := range groups {
+		for _, endorser := range plan.Groups[group] {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%d\n", group, endorser.MspID, endorser.Endpoint, endorser.LedgerHeight)
+		}
+	}
+	return table.Flush()
+}
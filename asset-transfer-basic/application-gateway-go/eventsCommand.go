@@ -0,0 +1,142 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// eventsCommand prints the chaincode events from a block onwards, each once and
+// in ledger order, as a reference consumer for bridges and indexers. With
+// -cloudevents, events are printed as structured CloudEvents, or posted to the
+// -sink URL in the chosen content mode, such as a Knative broker. Delivery stops
+// at the first event the sink rejects, to be resumed with -after-block. With
+// -state-store, the replicas of the consumer sharing the store resume after
+// the last event any of them applied, and deliver each event once.
+func eventsCommand(args []string) error {
+	flags := flag.NewFlagSet("events", flag.ContinueOnError)
+	commandSettings := settings.Sub(flags)
+	startBlock := flags.Uint64("start-block", 0, "block to read events from")
+	afterBlock := flags.Int64("after-block", -1, "block of the last event already applied, to skip on replay")
+	afterTransaction := flags.Int("after-transaction", 0, "index within -after-block of the last event already applied")
+	window := flags.Int("dedupe-window", 10000, "number of recent events remembered to detect duplicates")
+	cloudEvents := flags.String("cloudevents", "", "encode events as CloudEvents in binary or structured mode")
+	sink := commandSettings.String("sink", "CLOUDEVENTS_SINK", "", "URL to post the CloudEvents to, instead of printing them")
+	source := flags.String("source", "", "CloudEvents source, /fabric/<channel>/<chaincode> by default")
+	stateStore := commandSettings.String("state-store", "STATE_STORE", "", "directory, redis:// or postgres:// URL of the store shared by the replicas of the consumer")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := commandSettings.Resolve(os.LookupEnv); err != nil {
+		return err
+	}
+	if *sink != "" && *cloudEvents == "" {
+		*cloudEvents = assetclient.CloudEventsBinary
+	}
+	if *cloudEvents != "" && *cloudEvents != assetclient.CloudEventsBinary && *cloudEvents != assetclient.CloudEventsStructured {
+		return fmt.Errorf("unknown CloudEvents mode %q, expected binary or structured", *cloudEvents)
+	}
+	encoder := &assetclient.CloudEventEncoder{Source: *source}
+	if encoder.Source == "" {
+		encoder.Source = "/fabric/" + channelName() + "/" + chaincodeName()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	var last *assetclient.EventPosition
+	if *afterBlock >= 0 {
+		last = &assetclient.EventPosition{BlockNumber: uint64(*afterBlock), TransactionIndex: *afterTransaction}
+	}
+	var state *assetclient.EventState
+	if *stateStore != "" {
+		store, err := assetclient.StateStoreFor(*stateStore)
+		if err != nil {
+			return err
+		}
+		state = &assetclient.EventState{Store: store, Prefix: "events/" + channelName() + "/" + chaincodeName()}
+		saved, err := state.Last(ctx)
+		if err != nil {
+			return err
+		}
+		// a saved position wins over the flags, which only start a new consumer
+		if saved != nil {
+			last, *startBlock = saved, saved.BlockNumber
+		}
+	}
+
+	events, err := gw.GetNetwork(channelName()).ChaincodeEvents(ctx, chaincodeName(), client.WithStartBlock(*startBlock))
+	if err != nil {
+		return fmt.Errorf("failed to read chaincode events: %w", err)
+	}
+
+	guard := assetclient.NewEventGuard(*window, last)
+	err = assetclient.ApplyChaincodeEvents(ctx, events, guard, func(ctx context.Context, event *client.ChaincodeEvent) error {
+		if state == nil {
+			return deliverEvent(ctx, encoder, *sink, *cloudEvents, event)
+		}
+		// the guard accepted the event, so it is the last applied
+		position, _ := guard.Last()
+		claimed, err := state.Claim(ctx, position)
+		if err != nil || !claimed {
+			return err
+		}
+		if err := deliverEvent(ctx, encoder, *sink, *cloudEvents, event); err != nil {
+			return errors.Join(err, state.Release(context.WithoutCancel(ctx), position))
+		}
+		return state.Save(ctx, position)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// deliverEvent posts an event to sink, or prints it as a structured CloudEvent
+// or as text.
+func deliverEvent(ctx context.Context, encoder *assetclient.CloudEventEncoder, sink string, cloudEvents string, event *client.ChaincodeEvent) error {
+	switch {
+	case sink != "":
+		if err := encoder.Deliver(ctx, http.DefaultClient, sink, cloudEvents, event); err != nil {
+			return err
+		}
+		fmt.Printf("%d\t%s\t%s\tdelivered\n", event.BlockNumber, event.TransactionID, event.EventName)
+	case cloudEvents != "":
+		body, _, err := encoder.Structured(event)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(body))
+	default:
+		fmt.Printf("%d\t%s\t%s\t%s\n", event.BlockNumber, event.TransactionID, event.EventName, event.Payload)
+	}
+	return nil
+}
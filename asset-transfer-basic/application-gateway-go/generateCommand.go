@@ -0,0 +1,218 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// generateCommand generates synthetic assets for performance environments and
+// creates them on the ledger, or writes them to a JSON file with -out.
+func generateCommand(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	count := flags.Int("count", 1000, "number of assets generated")
+	dealers := flags.Int("dealers", 10, "number of dealers the assets are spread over")
+	dealerPrefix := flags.String("dealer-prefix", "DEALER", "prefix of the generated dealer IDs")
+	msisdnRange := flags.String("msisdn-range", "9800000000-9899999999", "range of the generated MSISDNs")
+	balances := flags.String("balances", "lognormal:1500:1.2", "distribution of the opening balances: fixed:<amount>, uniform:<min>:<max> or lognormal:<median>:<sigma>")
+	duplicateRate := flags.Float64("duplicate-rate", 0.02, "fraction of assets reusing the MSISDN of another asset")
+	inactiveRate := flags.Float64("inactive-rate", 0.05, "fraction of assets generated INACTIVE")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed making the generated data reproducible")
+	allocateFloat := flags.Bool("allocate-float", true, "allocate the float each dealer needs for the opening balances before loading")
+	out := flags.String("out", "", "write the generated assets to this JSON file instead of loading them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config := assetclient.GeneratorConfig{
+		Count:         *count,
+		Dealers:       *dealers,
+		DealerPrefix:  *dealerPrefix,
+		DuplicateRate: *duplicateRate,
+		InactiveRate:  *inactiveRate,
+		Seed:          *seed,
+	}
+	var err error
+	if config.MSISDNFirst, config.MSISDNLast, err = parseMSISDNRange(*msisdnRange); err != nil {
+		return err
+	}
+	if config.Balances, err = assetclient.ParseDistribution(*balances); err != nil {
+		return err
+	}
+	assets, err := assetclient.GenerateAssets(config)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Generated %d assets with seed %d\n", len(assets), *seed)
+	pacer, err := newBulkPacer()
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		assetsJSON, err := json.MarshalIndent(assets, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(*out, assetsJSON, 0o644)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	contract := gw.GetNetwork(channelName()).GetContract(chaincodeName())
+	if *allocateFloat {
+		if err := allocateGeneratedFloat(ctx, contract, assets); err != nil {
+			return err
+		}
+	}
+	return loadGeneratedAssets(ctx, contract, pacer, assets)
+}
+
+// parseMSISDNRange parses a range of MSISDNs written first-last.
+func parseMSISDNRange(text string) (int64, int64, error) {
+	firstText, lastText, found := strings.Cut(text, "-")
+	first, firstErr := strconv.ParseInt(firstText, 10, 64)
+	last, lastErr := strconv.ParseInt(lastText, 10, 64)
+	if !found || firstErr != nil || lastErr != nil || last < first {
+		return 0, 0, fmt.Errorf("invalid MSISDN range %q, expected first-last", text)
+	}
+	return first, last, nil
+}
+
+// allocateGeneratedFloat allocates to each dealer the total opening balance of
+// its generated assets, which CreateAsset draws from the dealer's float. Only
+// admins may allocate float, so the identity must be an admin.
+func allocateGeneratedFloat(ctx context.Context, contract *client.Contract, assets []assetclient.GeneratedAsset) error {
+	totals := make(map[string]float64)
+	for _, asset := range assets {
+		totals[asset.DealerID] += asset.Balance
+	}
+	dealerIDs := make([]string, 0, len(totals))
+	for dealerID := range totals {
+		dealerIDs = append(dealerIDs, dealerID)
+	}
+	sort.Strings(dealerIDs)
+
+	for _, dealerID := range dealerIDs {
+		amount := strconv.FormatFloat(totals[dealerID], 'f', 2, 64)
+		if _, err := contract.SubmitWithContext(ctx, "AllocateFloat", client.WithArguments(dealerID, amount)); err != nil {
+			return fmt.Errorf("failed to allocate the float of %s: %w", dealerID, assetclient.NewMultiPeerError(err))
+		}
+		fmt.Fprintf(os.Stderr, "Allocated %s to the float of %s\n", amount, dealerID)
+	}
+	return nil
+}
+
+// loadGeneratedAssets creates the generated assets with IDs of the -id-strategy.
+// CreateAsset draws down the float of the asset's dealer, so the assets of a
+// dealer are created one after the other to avoid MVCC read conflicts on its
+// float, and the dealers are loaded at most -parallel at a time, with their
+// submissions paced by pacer. Infrastructure errors are retried, and the load
+// stops at the first failure.
+func loadGeneratedAssets(ctx context.Context, contract *client.Contract, pacer *assetclient.Pacer, assets []assetclient.GeneratedAsset) error {
+	ids, err := assetclient.NewIDGenerator(*idStrategy, *idTemplate, contract)
+	if err != nil {
+		return err
+	}
+	byDealer := make(map[string][]assetclient.GeneratedAsset)
+	for _, asset := range assets {
+		byDealer[asset.DealerID] = append(byDealer[asset.DealerID], asset)
+	}
+
+	started := time.Now()
+	var loaded atomic.Int64
+	group, ctx := assetclient.NewGroup(ctx, *demoParallelism)
+	for dealerID, dealerAssets := range byDealer {
+		group.Go(dealerID, func(ctx context.Context) error {
+			for _, asset := range dealerAssets {
+				if err := createGeneratedAsset(ctx, contract, pacer, ids, asset); err != nil {
+					return err
+				}
+				if n := loaded.Add(1); n%100 == 0 {
+					fmt.Fprintf(os.Stderr, "Loaded %d of %d assets\n", n, len(assets))
+				}
+			}
+			return nil
+		})
+	}
+	err = group.Wait()
+
+	elapsed := time.Since(started)
+	fmt.Printf("Loaded %d of %d assets in %s (%.1f tx/s)\n", loaded.Load(), len(assets), elapsed.Round(time.Millisecond), float64(loaded.Load())/elapsed.Seconds())
+	return err
+}
+
+// createGeneratedAsset submits CreateAsset for a generated asset, with an ID of
+// the -id-strategy.
+func createGeneratedAsset(ctx context.Context, contract *client.Contract, pacer *assetclient.Pacer, ids assetclient.IDGenerator, asset assetclient.GeneratedAsset) error {
+	id, err := ids.NextID(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = createAsset(ctx, contract, pacer, id, asset, 3)
+	return err
+}
+
+// createAsset submits CreateAsset with the private details of the asset in the
+// transient data, retrying infrastructure errors until attempts submissions
+// were made. Each submission waits for pacer. It returns the number of
+// submissions made.
+func createAsset(ctx context.Context, contract *client.Contract, pacer *assetclient.Pacer, id string, asset assetclient.GeneratedAsset, attempts int) (int, error) {
+	details, err := json.Marshal(struct {
+		MPIN    string `json:"mpin"`
+		MSISDN  string `json:"msisdn"`
+		REMARKS string `json:"remarks"`
+	}{asset.MPIN, asset.MSISDN, asset.Remarks})
+	if err != nil {
+		return 0, err
+	}
+
+	submissions := 0
+	err = assetclient.Retry(ctx, attempts, 100*time.Millisecond, func(ctx context.Context) error {
+		return pacer.Do(ctx, func(ctx context.Context) error {
+			submissions++
+			_, err := contract.SubmitWithContext(ctx, "CreateAsset",
+				client.WithArguments(id, asset.DealerID, strconv.FormatFloat(asset.Balance, 'f', 2, 64), asset.Status,
+					strconv.FormatFloat(asset.TransAmount, 'f', 2, 64), asset.TransType),
+				client.WithTransient(map[string][]byte{"asset_details": details}))
+			return err
+		})
+	})
+	if err != nil {
+		return submissions, fmt.Errorf("failed to create asset %s: %w", id, assetclient.NewMultiPeerError(err))
+	}
+	return submissions, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const identityUsage = `usage: identity <command> [flags]
+
+commands:
+  list   [-org org]                                    list the identities in the wallet
+  add    <label> -mspdir dir [-org org] [-mspid id]     import an MSP directory
+  add    <label> -cert file -key file [-org org] [-mspid id]
+  remove <label>                                       remove an identity from the wallet
+  show   <label>                                       show certificate details of an identity`
+
+// identityCommand runs the identity sub-command given by args.
+func identityCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(identityUsage)
+	}
+
+	w := newWallet()
+	switch args[0] {
+	case "list":
+		return identityList(w, args[1:])
+	case "add":
+		return identityAdd(w, args[1:])
+	case "remove":
+		return identityRemove(w, args[1:])
+	case "show":
+		return identityShow(w, args[1:])
+	default:
+		return fmt.Errorf("unknown identity command %q\n%s", args[0], identityUsage)
+	}
+}
+
+func identityList(w *wallet, args []string) error {
+	flags := flag.NewFlagSet("identity list", flag.ContinueOnError)
+	org := flags.String("org", "", "only list identities of this organization")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ids, err := w.list()
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "LABEL\tORG\tMSP ID\tSUBJECT\tEXPIRES")
+	for _, id := range ids {
+		if *org != "" && id.Org != *org {
+			continue
+		}
+		certificate, err := id.certificate()
+		if err != nil {
+			return fmt.Errorf("identity %s: %w", id.label, err)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", id.label, id.Org, id.MspID, certificate.Subject.CommonName, certificate.NotAfter.Format(time.RFC3339))
+	}
+	return table.Flush()
+}
+
+func identityAdd(w *wallet, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("identity add: a label is required")
+	}
+	label := args[0]
+
+	flags := flag.NewFlagSet("identity add", flag.ContinueOnError)
+	mspDir := flags.String("mspdir", "", "MSP directory containing signcerts and keystore")
+	certFile := flags.String("cert", "", "PEM certificate file")
+	keyFile := flags.String("key", "", "PEM private key file")
+	org := flags.String("org", "", "organization label, such as org1")
+	mspID := flags.String("mspid", "", "MSP ID, derived from -org for test network organizations")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var id *walletIdentity
+	var err error
+	switch {
+	case *mspDir != "":
+		id, err = newWalletIdentityFromMSP(label, *mspID, *org, *mspDir)
+	case *certFile != "" && *keyFile != "":
+		var certificatePEM, privateKeyPEM []byte
+		if certificatePEM, err = os.ReadFile(*certFile); err != nil {
+			return fmt.Errorf("failed to read certificate file: %w", err)
+		}
+		if privateKeyPEM, err = os.ReadFile(*keyFile); err != nil {
+			return fmt.Errorf("failed to read private key file: %w", err)
+		}
+		id, err = newWalletIdentity(label, *mspID, *org, certificatePEM, privateKeyPEM)
+	default:
+		return errors.New("identity add: either -mspdir or both -cert and -key are required")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := w.put(id); err != nil {
+		return err
+	}
+	fmt.Printf("*** Added identity %s (%s) to wallet %s\n", label, id.MspID, w.dir)
+	return nil
+}
+
+func identityRemove(w *wallet, args []string) error {
+	if len(args) != 1 {
+		return errors.New("identity remove: exactly one label is required")
+	}
+	if err := w.remove(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("*** Removed identity %s from wallet %s\n", args[0], w.dir)
+	return nil
+}
+
+func identityShow(w *wallet, args []string) error {
+	if len(args) != 1 {
+		return errors.New("identity show: exactly one label is required")
+	}
+	id, err := w.get(args[0])
+	if err != nil {
+		return err
+	}
+	certificate, err := id.certificate()
+	if err != nil {
+		return err
+	}
+
+	expiry := "valid"
+	if now := time.Now(); now.After(certificate.NotAfter) {
+		expiry = "EXPIRED"
+	} else if certificate.NotAfter.Sub(now) < 30*24*time.Hour {
+		expiry = "expires within 30 days"
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "Label:\t%s\n", id.label)
+	fmt.Fprintf(table, "Organization:\t%s\n", id.Org)
+	fmt.Fprintf(table, "MSP ID:\t%s\n", id.MspID)
+	fmt.Fprintf(table, "Subject:\t%s\n", certificate.Subject)
+	fmt.Fprintf(table, "Organizational units:\t%s\n", strings.Join(certificate.Subject.OrganizationalUnit, ", "))
+	fmt.Fprintf(table, "Issuer:\t%s\n", certificate.Issuer)
+	fmt.Fprintf(table, "Serial number:\t%s\n", certificate.SerialNumber)
+	fmt.Fprintf(table, "Not before:\t%s\n", certificate.NotBefore.Format(time.RFC3339))
+	fmt.Fprintf(table, "Not after:\t%s (%s)\n", certificate.NotAfter.Format(time.RFC3339), expiry)
+	return table.Flush()
+}
@@ -0,0 +1,367 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+const importUsage = `usage: import [-dlq failed.jsonl] [-attempts n] <file.csv|file.xlsx>
+       import -format legacy -initial-mpin mpin [-report report.csv] [-dlq failed.jsonl] [-attempts n] <extract.txt>
+       import -replay-dlq failed.jsonl [-attempts n]
+       import template [-format csv|xlsx] [-o file]`
+
+// importCommand creates the assets of a CSV or Excel file, keeping their IDs.
+// Every row of the file is checked against the asset schema before any is
+// submitted, and the import stops with the list of problems when one is found.
+// A row failing after -attempts submissions is appended to the dead letter file
+// with its error and the import goes on, so that a large migration can be
+// resumed with -replay-dlq without submitting the imported rows again.
+// Replaying submits the rows of the dead letter file and leaves in it only the
+// rows failing again. With -format legacy, the file is a legacy core-banking
+// extract, converted to the rows of its accounts, and the outcome of each of
+// its records is written to the -report reconciliation file before importing.
+func importCommand(args []string) error {
+	if len(args) > 0 && args[0] == "template" {
+		return importTemplateCommand(args[1:])
+	}
+
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dlq := flags.String("dlq", "import-failed.jsonl", "file the rows failing to import are appended to")
+	replay := flags.String("replay-dlq", "", "submit the rows of this dead letter file again instead of importing a CSV file")
+	attempts := flags.Int("attempts", 3, "submissions of a row before it is dead-lettered")
+	format := flags.String("format", "csv", "format of the import file: csv, read as Excel when named .xlsx, or legacy for a core-banking extract")
+	initialMPIN := flags.String("initial-mpin", "", "MPIN of the assets of a legacy extract, which carries none")
+	reportPath := flags.String("report", "import-reconciliation.csv", "file the reconciliation report of a legacy extract is written to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (*replay == "" && flags.NArg() != 1) || (*replay != "" && flags.NArg() != 0) || *attempts < 1 {
+		return errors.New(importUsage)
+	}
+
+	var rows []assetclient.ImportRow
+	var err error
+	switch {
+	case *replay != "":
+		rows, err = readDeadLetterRows(*replay)
+	case *format == "legacy":
+		rows, err = readLegacyExtract(flags.Arg(0), *initialMPIN, *reportPath)
+	case *format == "csv":
+		rows, err = readImportFile(flags.Arg(0))
+	default:
+		return fmt.Errorf("unknown import format %q, expected csv or legacy", *format)
+	}
+	if err != nil {
+		return err
+	}
+	if *replay == "" {
+		if err := preflightImport(rows); err != nil {
+			return err
+		}
+	}
+	pacer, err := newBulkPacer()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	contract := gw.GetNetwork(channelName()).GetContract(chaincodeName())
+	if *replay != "" {
+		return replayDeadLetters(ctx, contract, pacer, *replay, rows, *attempts)
+	}
+
+	deadLetters, err := assetclient.NewDeadLetterWriter(*dlq)
+	if err != nil {
+		return err
+	}
+	defer deadLetters.Close()
+
+	imported, err := importRows(ctx, contract, pacer, rows, *attempts, deadLetters, false)
+	fmt.Printf("Imported %d of %d rows\n", imported, len(rows))
+	if err != nil {
+		return err
+	}
+	if failed := deadLetters.Written(); failed > 0 {
+		return fmt.Errorf("%d rows failed and were written to %s, resume with import -replay-dlq %s", failed, *dlq, *dlq)
+	}
+	return nil
+}
+
+// importTemplateCommand writes an import template generated from the asset
+// schema, with the notes on its columns and example rows.
+func importTemplateCommand(args []string) error {
+	flags := flag.NewFlagSet("import template", flag.ContinueOnError)
+	format := flags.String("format", "csv", "format of the template: csv or xlsx")
+	output := flags.String("o", "", "file the template is written to, import-template.<format> by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New(importUsage)
+	}
+	write := assetclient.WriteImportTemplateCSV
+	switch *format {
+	case "csv":
+	case "xlsx":
+		write = assetclient.WriteImportTemplateXLSX
+	default:
+		return fmt.Errorf("unknown template format %q, expected csv or xlsx", *format)
+	}
+	if *output == "" {
+		*output = "import-template." + *format
+	}
+
+	fields, err := assetclient.DefaultImportFields()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create import template: %w", err)
+	}
+	if err := write(file, fields); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote import template %s\n", *output)
+	return nil
+}
+
+// preflightImport checks every row against the asset schema, printing each
+// problem found, so that no row is submitted from a file needing corrections.
+func preflightImport(rows []assetclient.ImportRow) error {
+	fields, err := assetclient.DefaultImportFields()
+	if err != nil {
+		return err
+	}
+	problems := assetclient.ValidateImport(rows, fields)
+	if len(problems) == 0 {
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	return fmt.Errorf("the import file has %d problems, nothing was submitted", len(problems))
+}
+
+func readImportFile(path string) ([]assetclient.ImportRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return assetclient.ReadImportXLSX(file, info.Size())
+	}
+	return assetclient.ReadImportCSV(file)
+}
+
+// readLegacyExtract converts the legacy extract at path and writes its
+// reconciliation report to reportPath.
+func readLegacyExtract(path string, initialMPIN string, reportPath string) ([]assetclient.ImportRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open legacy extract: %w", err)
+	}
+	defer file.Close()
+
+	rows, report, err := assetclient.ReadLegacyExtract(file, assetclient.LegacyOptions{InitialMPIN: initialMPIN})
+	if err != nil {
+		return nil, err
+	}
+	reportFile, err := os.Create(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reconciliation report: %w", err)
+	}
+	defer reportFile.Close()
+	if err := report.WriteCSV(reportFile); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Extract %s of branch %s: %d records, %d imported, %d transformed, %d skipped, balance %.2f, reported in %s\n",
+		report.ExtractDate, report.Branch, report.Records, report.Imported, report.Transformed, report.Skipped, report.Balance, reportPath)
+	return rows, reportFile.Close()
+}
+
+func readDeadLetterRows(path string) ([]assetclient.ImportRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer file.Close()
+
+	letters, err := assetclient.ReadDeadLetters(file)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]assetclient.ImportRow, 0, len(letters))
+	for _, letter := range letters {
+		rows = append(rows, letter.Row)
+	}
+	return rows, nil
+}
+
+// replayDeadLetters imports the rows of a dead letter file again. The rows
+// failing again are written to a new file replacing the dead letter file once
+// the replay is over, so that an interrupted replay leaves the file unchanged.
+// The file is removed when every row was imported.
+func replayDeadLetters(ctx context.Context, contract *client.Contract, pacer *assetclient.Pacer, path string, rows []assetclient.ImportRow, attempts int) error {
+	replayPath := path + ".replay"
+	if err := os.Remove(replayPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	deadLetters, err := assetclient.NewDeadLetterWriter(replayPath)
+	if err != nil {
+		return err
+	}
+
+	imported, err := importRows(ctx, contract, pacer, rows, attempts, deadLetters, true)
+	fmt.Printf("Replayed %d of %d rows\n", imported, len(rows))
+	if closeErr := deadLetters.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := deadLetters.Written()
+	if failed == 0 {
+		if err := os.Remove(replayPath); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	}
+	if err := os.Rename(replayPath, path); err != nil {
+		return fmt.Errorf("failed to replace the dead letter file: %w", err)
+	}
+	return fmt.Errorf("%d rows failed again and were kept in %s", failed, path)
+}
+
+// importRows creates the assets of the rows and dead-letters the rows failing.
+// CreateAsset draws down the float of the asset's dealer, so the rows of a
+// dealer are imported in file order, one after the other, and the dealers at
+// most -parallel at a time, with their submissions paced by pacer. Rows left
+// unattempted, as the import was interrupted, are dead-lettered too. When
+// replaying, a row whose asset already exists counts as imported: its previous
+// submission failed to get the commit status, yet committed. It returns the
+// number of rows imported, and an error only when a dead letter could not be
+// written.
+func importRows(ctx context.Context, contract *client.Contract, pacer *assetclient.Pacer, rows []assetclient.ImportRow, attempts int, deadLetters *assetclient.DeadLetterWriter, replay bool) (int, error) {
+	byDealer := make(map[string][]int)
+	for i, row := range rows {
+		dealerID := row.Values["dealerid"]
+		byDealer[dealerID] = append(byDealer[dealerID], i)
+	}
+
+	settled := make([]bool, len(rows))
+	imported := make([]bool, len(rows))
+	group, _ := assetclient.NewGroup(ctx, *demoParallelism)
+	for dealerID, indexes := range byDealer {
+		group.Go(dealerID, func(ctx context.Context) error {
+			for _, i := range indexes {
+				if ctx.Err() != nil {
+					return nil
+				}
+				id, asset, err := importedAsset(rows[i])
+				submissions := 0
+				if err == nil {
+					submissions, err = createAsset(ctx, contract, pacer, id, asset, attempts)
+				}
+				if err == nil || (replay && errors.Is(err, assetclient.ErrAssetExists)) {
+					settled[i], imported[i] = true, true
+					continue
+				}
+				if ctx.Err() != nil && submissions == 0 {
+					return nil
+				}
+				if err := deadLetters.Write(assetclient.NewDeadLetter(rows[i], err, submissions)); err != nil {
+					return err
+				}
+				settled[i] = true
+			}
+			return nil
+		})
+	}
+	err := group.Wait()
+
+	count := 0
+	for i, row := range rows {
+		if imported[i] {
+			count++
+			continue
+		}
+		if settled[i] {
+			continue
+		}
+		if writeErr := deadLetters.Write(assetclient.NewDeadLetter(row, errors.New("not attempted: the import was interrupted"), 0)); writeErr != nil {
+			err = errors.Join(err, writeErr)
+		}
+	}
+	return count, err
+}
+
+// importedAsset parses the asset of an import row.
+func importedAsset(row assetclient.ImportRow) (string, assetclient.GeneratedAsset, error) {
+	values := row.Values
+	if values["id"] == "" {
+		return "", assetclient.GeneratedAsset{}, fmt.Errorf("line %d: the id is empty", row.Line)
+	}
+	balance, err := strconv.ParseFloat(values["balance"], 64)
+	if err != nil {
+		return "", assetclient.GeneratedAsset{}, fmt.Errorf("line %d: invalid balance %q", row.Line, values["balance"])
+	}
+	transAmount, err := strconv.ParseFloat(values["transamount"], 64)
+	if err != nil {
+		return "", assetclient.GeneratedAsset{}, fmt.Errorf("line %d: invalid transamount %q", row.Line, values["transamount"])
+	}
+
+	return values["id"], assetclient.GeneratedAsset{
+		DealerID:    values["dealerid"],
+		Balance:     balance,
+		Status:      values["status"],
+		TransAmount: transAmount,
+		TransType:   values["transtype"],
+		MSISDN:      values["msisdn"],
+		MPIN:        values["mpin"],
+		Remarks:     values["remarks"],
+	}, nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// listedAsset is the public summary of an asset returned by GetAssetsSorted.
+type listedAsset struct {
+	ID          string  `json:"ID"`
+	DealerID    string  `json:"dealerid"`
+	Balance     float64 `json:"balance"`
+	Status      string  `json:"status"`
+	TransAmount float64 `json:"transamount"`
+	TransType   string  `json:"transtype"`
+	UpdatedAt   string  `json:"updatedat"`
+}
+
+// listCommand lists the assets page by page, sorted by the chaincode, as a table
+// or, to export them, as a JSON array. A listing stopped after -pages pages
+// prints the page token resuming it with the same sort and page size. With
+// -fields, only the listed fields are fetched, with GetAssetsFilteredFields,
+// and printed.
+func listCommand(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	sortSpec := flags.String("sort", "", "sort by balance or updatedat, optionally followed by :asc or :desc")
+	pageSize := flags.Int("page-size", 100, "number of assets fetched per query")
+	asJSON := flags.Bool("json", false, "export the assets as a JSON array")
+	pageToken := flags.String("page-token", "", "resume the listing from the page token printed by a previous run")
+	maxPages := flags.Int("pages", 0, "stop after this number of pages, 0 lists every page")
+	fields := flags.String("fields", "", "comma-separated asset fields to fetch, such as balance,status; ID is always fetched")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	function, arguments := "GetAssetsSorted", []string{*sortSpec}
+	if *fields != "" {
+		function, arguments = "GetAssetsFilteredFields", []string{*fields, "", *sortSpec}
+	}
+
+	tokens := assetclient.NewPageTokens(pageTokenKey(id.Credentials()))
+	query := assetclient.QueryHash(function, arguments...)
+	bookmark, err := tokens.Decode(*pageToken, *pageSize, query)
+	if err != nil {
+		return fmt.Errorf("cannot resume the listing: %w", err)
+	}
+
+	contract := gw.GetNetwork(channelName()).GetContract(chaincodeName())
+	assets := []json.RawMessage{}
+	for pages := 1; ; pages++ {
+		pageJSON, err := contract.EvaluateWithContext(ctx, function, client.WithArguments(append(arguments, strconv.Itoa(*pageSize), bookmark)...))
+		if err != nil {
+			return assetclient.NewMultiPeerError(err)
+		}
+
+		var page struct {
+			Assets   []json.RawMessage `json:"assets"`
+			Bookmark string            `json:"bookmark"`
+		}
+		if err := json.Unmarshal(pageJSON, &page); err != nil {
+			return fmt.Errorf("failed to parse assets: %w", err)
+		}
+		assets = append(assets, page.Assets...)
+		if page.Bookmark == "" {
+			break
+		}
+		bookmark = page.Bookmark
+
+		if pages == *maxPages {
+			next, err := tokens.Encode(bookmark, *pageSize, query)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "More assets follow, resume with -page-token %s\n", next)
+			break
+		}
+	}
+
+	if *fields != "" {
+		return printAssetFields(assets, *fields, *asJSON)
+	}
+
+	listed := make([]listedAsset, len(assets))
+	for i, assetJSON := range assets {
+		if err := json.Unmarshal(assetJSON, &listed[i]); err != nil {
+			return fmt.Errorf("failed to parse assets: %w", err)
+		}
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listed)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tDEALER\tBALANCE\tSTATUS\tUPDATED")
+	for _, asset := range listed {
+		fmt.Fprintf(table, "%s\t%s\t%.2f\t%s\t%s\n", asset.ID, asset.DealerID, asset.Balance, asset.Status, asset.UpdatedAt)
+	}
+	return table.Flush()
+}
+
+// printAssetFields prints the projections of the assets fetched with -fields,
+// as they are or as a table with a column per field.
+func printAssetFields(assets []json.RawMessage, fields string, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(assets)
+	}
+
+	columns := []string{"ID"}
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "ID" {
+			columns = append(columns, field)
+		}
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, assetJSON := range assets {
+		var asset map[string]interface{}
+		if err := json.Unmarshal(assetJSON, &asset); err != nil {
+			return fmt.Errorf("failed to parse assets: %w", err)
+		}
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			if value, ok := asset[column]; ok {
+				values[i] = fmt.Sprint(value)
+			}
+		}
+		fmt.Fprintln(table, strings.Join(values, "\t"))
+	}
+	return table.Flush()
+}
+
+// pageTokenKey returns the key signing page tokens: -page-token-key when set, so
+// tokens can be shared between users, otherwise a key derived from the client
+// certificate, binding tokens to the identity listing the assets.
+func pageTokenKey(certificate []byte) []byte {
+	if key := *pageTokenKeySecret; key != "" {
+		return []byte(key)
+	}
+	key := sha256.Sum256(certificate)
+	return key[:]
+}
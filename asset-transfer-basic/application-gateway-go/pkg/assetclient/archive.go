@@ -0,0 +1,122 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// ArchivedAsset is an asset moved to the archive~ namespace of the chaincode by
+// ArchiveAsset, with its audit records, oldest first, kept as read.
+type ArchivedAsset struct {
+	ArchivedAt string            `json:"archivedat"`
+	Asset      Asset             `json:"asset"`
+	Audit      []json.RawMessage `json:"audit"`
+	TxID       string            `json:"txid"`
+}
+
+// ReadArchivedAssets calls fn with every archived asset, in id order, evaluating
+// the chaincode's GetArchivedAssets pageSize assets at a time, DefaultPageSize
+// when zero. The evaluation of a page is retried when it fails with a
+// retryable error.
+func ReadArchivedAssets(ctx context.Context, contract Evaluator, pageSize int, fn func(archived *ArchivedAsset) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	bookmark := ""
+	for {
+		var pageJSON []byte
+		err := Retry(ctx, listAttempts, listBackoff, func(ctx context.Context) error {
+			var err error
+			pageJSON, err = contract.EvaluateWithContext(ctx, "GetArchivedAssets", client.WithArguments(strconv.Itoa(pageSize), bookmark))
+			return err
+		})
+		if err != nil {
+			return DecodeChaincodeError(err)
+		}
+
+		var page struct {
+			Assets   []*ArchivedAsset `json:"assets"`
+			Bookmark string           `json:"bookmark"`
+		}
+		if err := json.Unmarshal(pageJSON, &page); err != nil {
+			return fmt.Errorf("failed to parse archived assets: %w", err)
+		}
+		for _, archived := range page.Assets {
+			if err := fn(archived); err != nil {
+				return err
+			}
+		}
+		if page.Bookmark == "" {
+			return nil
+		}
+		bookmark = page.Bookmark
+	}
+}
+
+// WriteArchiveParquet writes archived assets to w as a Parquet file, one row
+// per asset, for cold storage. The asset metadata and the audit records are
+// JSON strings.
+func WriteArchiveParquet(w io.Writer, archived []*ArchivedAsset) error {
+	stringColumn := func(name string) *parquetColumn {
+		return &parquetColumn{name: name, physical: parquetByteArray}
+	}
+	var (
+		id          = stringColumn("ID")
+		dealerID    = stringColumn("dealerid")
+		balance     = &parquetColumn{name: "balance", physical: parquetDouble}
+		status      = stringColumn("status")
+		transAmount = &parquetColumn{name: "transamount", physical: parquetDouble}
+		transType   = stringColumn("transtype")
+		detailsHash = stringColumn("detailshash")
+		detailsOrg  = stringColumn("detailsorg")
+		metadata    = stringColumn("metadata")
+		updatedAt   = stringColumn("updatedat")
+		version     = &parquetColumn{name: "version", physical: parquetInt64}
+		archivedAt  = stringColumn("archivedat")
+		txID        = stringColumn("txid")
+		audit       = stringColumn("audit")
+	)
+
+	for _, record := range archived {
+		metadataJSON := []byte("{}")
+		if len(record.Asset.Metadata) > 0 {
+			var err error
+			if metadataJSON, err = json.Marshal(record.Asset.Metadata); err != nil {
+				return err
+			}
+		}
+		auditJSON := []byte("[]")
+		if len(record.Audit) > 0 {
+			var err error
+			if auditJSON, err = json.Marshal(record.Audit); err != nil {
+				return err
+			}
+		}
+		id.appendString(record.Asset.ID)
+		dealerID.appendString(record.Asset.DealerID)
+		balance.appendDouble(record.Asset.Balance)
+		status.appendString(record.Asset.Status)
+		transAmount.appendDouble(record.Asset.TransAmount)
+		transType.appendString(record.Asset.TransType)
+		detailsHash.appendString(record.Asset.DetailsHash)
+		detailsOrg.appendString(record.Asset.DetailsOrg)
+		metadata.appendString(string(metadataJSON))
+		updatedAt.appendString(record.Asset.UpdatedAt)
+		version.appendInt64(record.Asset.Version)
+		archivedAt.appendString(record.ArchivedAt)
+		txID.appendString(record.TxID)
+		audit.appendString(string(auditJSON))
+	}
+
+	columns := []*parquetColumn{id, dealerID, balance, status, transAmount, transType, detailsHash, detailsOrg, metadata, updatedAt, version, archivedAt, txID, audit}
+	return writeParquet(w, columns, len(archived))
+}
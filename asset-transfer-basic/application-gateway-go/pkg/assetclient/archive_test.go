@@ -0,0 +1,73 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadArchivedAssetsPages(t *testing.T) {
+	contract := &fakeEvaluator{results: []fakeResult{
+		{json: `{"assets":[{"archivedat":"2024-01-02T00:00:00Z","asset":{"ID":"asset2","status":"CLOSED"},"audit":[{"action":"CreateAsset"}],"txid":"tx9"}],"bookmark":"b1"}`},
+		{json: `{"assets":[{"asset":{"ID":"asset5","status":"CLOSED"},"audit":[]}],"bookmark":""}`},
+	}}
+
+	var ids []string
+	err := ReadArchivedAssets(context.Background(), contract, 1, func(archived *ArchivedAsset) error {
+		ids = append(ids, archived.Asset.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != "asset2" || ids[1] != "asset5" || contract.functions[1] != "GetArchivedAssets" {
+		t.Fatalf("expected asset2 and asset5 from GetArchivedAssets, got %v from %v", ids, contract.functions)
+	}
+}
+
+func TestWriteArchiveParquet(t *testing.T) {
+	archived := []*ArchivedAsset{
+		{ArchivedAt: "2024-01-02T00:00:00Z", Asset: Asset{ID: "asset2", DealerID: "DEALER101", Balance: 0, Status: "CLOSED", Version: 4}, TxID: "tx9"},
+		{ArchivedAt: "2024-01-03T00:00:00Z", Asset: Asset{ID: "asset5", DealerID: "DEALER105", Status: "CLOSED", Version: 2}, TxID: "tx10"},
+	}
+	var file bytes.Buffer
+	if err := WriteArchiveParquet(&file, archived); err != nil {
+		t.Fatal(err)
+	}
+
+	data := file.Bytes()
+	if !bytes.HasPrefix(data, []byte(parquetMagic)) || !bytes.HasSuffix(data, []byte(parquetMagic)) {
+		t.Fatal("expected the file to start and end with PAR1")
+	}
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerSize <= 0 || footerSize > len(data)-12 {
+		t.Fatalf("invalid footer size %d for a file of %d bytes", footerSize, len(data))
+	}
+	footer := data[len(data)-8-footerSize : len(data)-8]
+	for _, name := range []string{"schema", "ID", "dealerid", "version", "audit"} {
+		if !bytes.Contains(footer, []byte(name)) {
+			t.Errorf("expected the footer to describe column %s", name)
+		}
+	}
+	// the first page holds the PLAIN encoded ids after its header
+	ids := append(binary.LittleEndian.AppendUint32(nil, 6), "asset2"...)
+	ids = append(binary.LittleEndian.AppendUint32(ids, 6), "asset5"...)
+	if !bytes.Contains(data[:len(data)-8-footerSize], ids) {
+		t.Fatal("expected the ID column to hold the PLAIN encoded ids")
+	}
+}
+
+func TestWriteArchiveParquetWithoutAssets(t *testing.T) {
+	var file bytes.Buffer
+	if err := WriteArchiveParquet(&file, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(file.Bytes(), []byte(parquetMagic)) {
+		t.Fatal("expected a valid file without rows")
+	}
+}
@@ -0,0 +1,212 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Defaults of the pages fetched by an AssetIterator and of the retries of each.
+const (
+	DefaultPageSize = 100
+	listAttempts    = 3
+	listBackoff     = 100 * time.Millisecond
+)
+
+// Evaluator evaluates transaction functions, such as a *client.Contract.
+type Evaluator interface {
+	EvaluateWithContext(ctx context.Context, transactionName string, options ...client.ProposalOption) ([]byte, error)
+}
+
+// Asset is the public summary of an asset. Liened is the part of the balance
+// pledged by liens, Held the part held under the hold policy, and Spendable the
+// rest, always reported by the chaincode.
+type Asset struct {
+	ID          string            `json:"ID"`
+	DealerID    string            `json:"dealerid"`
+	Balance     float64           `json:"balance"`
+	Status      string            `json:"status"`
+	TransAmount float64           `json:"transamount"`
+	TransType   string            `json:"transtype"`
+	DetailsHash string            `json:"detailshash"`
+	DetailsOrg  string            `json:"detailsorg,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Liened      float64           `json:"liened,omitempty"`
+	Held        float64           `json:"held,omitempty"`
+	Spendable   float64           `json:"spendable"`
+	UpdatedAt   string            `json:"updatedat"`
+	Version     int64             `json:"version"`
+}
+
+// Filter selects and orders the assets listed by ListAssets. Zero fields do not
+// constrain the result. Sort is a sort specification of the chaincode, such as
+// "balance:desc", and PageSize the number of assets fetched per evaluation,
+// DefaultPageSize when zero. Fields, the JSON names of the asset fields to
+// fetch such as "balance", leaves the other fields of the listed assets zero;
+// ID is always fetched.
+type Filter struct {
+	DealerID     string
+	Status       string
+	MinBalance   *float64
+	MaxBalance   *float64
+	UpdatedSince time.Time
+	Sort         string
+	PageSize     int
+	Fields       []string
+}
+
+// chaincodeFilter is the AssetFilter of the chaincode's GetAssetsFiltered.
+type chaincodeFilter struct {
+	DealerID     string   `json:"dealerid,omitempty"`
+	MaxBalance   *float64 `json:"maxbalance,omitempty"`
+	MinBalance   *float64 `json:"minbalance,omitempty"`
+	Status       string   `json:"status,omitempty"`
+	UpdatedSince string   `json:"updatedsince,omitempty"`
+}
+
+func (f Filter) chaincodeJSON() (string, error) {
+	filter := chaincodeFilter{
+		DealerID:   f.DealerID,
+		MaxBalance: f.MaxBalance,
+		MinBalance: f.MinBalance,
+		Status:     f.Status,
+	}
+	if !f.UpdatedSince.IsZero() {
+		filter.UpdatedSince = f.UpdatedSince.UTC().Format(time.RFC3339)
+	}
+	filterJSON, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	return string(filterJSON), nil
+}
+
+// AssetIterator iterates over the assets listed by ListAssets, fetching them a
+// page at a time as Next is called:
+//
+//	assets := assetclient.ListAssets(ctx, contract, filter)
+//	for assets.Next() {
+//		asset := assets.Asset()
+//		...
+//	}
+//	if err := assets.Err(); err != nil {
+//		...
+//	}
+//
+// The evaluation of a page is retried when it fails with a retryable error,
+// see IsRetryable. An AssetIterator is not safe for concurrent use.
+type AssetIterator struct {
+	ctx      context.Context
+	contract Evaluator
+	filter   string
+	sort     string
+	pageSize int
+	fields   string
+
+	bookmark string
+	page     []*Asset
+	asset    *Asset
+	done     bool
+	err      error
+}
+
+// ListAssets returns an iterator over the assets matching filter, evaluated with
+// the chaincode's GetAssetsFiltered, or GetAssetsFilteredFields when the filter
+// lists Fields. Nothing is evaluated before the first call to Next, and ctx
+// bounds every evaluation.
+func ListAssets(ctx context.Context, contract Evaluator, filter Filter) *AssetIterator {
+	iterator := &AssetIterator{ctx: ctx, contract: contract, sort: filter.Sort, pageSize: filter.PageSize, fields: strings.Join(filter.Fields, ",")}
+	if iterator.pageSize <= 0 {
+		iterator.pageSize = DefaultPageSize
+	}
+	iterator.filter, iterator.err = filter.chaincodeJSON()
+	return iterator
+}
+
+// Next advances to the next asset, fetching the next page when the current one
+// is exhausted. It returns false at the end of the assets or on an error,
+// returned by Err.
+func (it *AssetIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || it.done {
+			it.asset = nil
+			return false
+		}
+		it.err = it.fetch()
+	}
+	it.asset, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Asset returns the asset Next advanced to.
+func (it *AssetIterator) Asset() *Asset {
+	return it.asset
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *AssetIterator) Err() error {
+	return it.err
+}
+
+// ForEach calls fn with every remaining asset, stopping at the first error of
+// fn or of the iteration.
+func (it *AssetIterator) ForEach(fn func(asset *Asset) error) error {
+	for it.Next() {
+		if err := fn(it.Asset()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// Collect returns every remaining asset. Listings of many assets should rather
+// use Next or ForEach, which hold one page in memory at a time.
+func (it *AssetIterator) Collect() ([]*Asset, error) {
+	assets := []*Asset{}
+	err := it.ForEach(func(asset *Asset) error {
+		assets = append(assets, asset)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// fetch evaluates the page after the bookmark.
+func (it *AssetIterator) fetch() error {
+	var pageJSON []byte
+	err := Retry(it.ctx, listAttempts, listBackoff, func(ctx context.Context) error {
+		var err error
+		if it.fields != "" {
+			pageJSON, err = it.contract.EvaluateWithContext(ctx, "GetAssetsFilteredFields", client.WithArguments(it.fields, it.filter, it.sort, strconv.Itoa(it.pageSize), it.bookmark))
+		} else {
+			pageJSON, err = it.contract.EvaluateWithContext(ctx, "GetAssetsFiltered", client.WithArguments(it.filter, it.sort, strconv.Itoa(it.pageSize), it.bookmark))
+		}
+		return err
+	})
+	if err != nil {
+		return DecodeChaincodeError(err)
+	}
+
+	var page struct {
+		Assets   []*Asset `json:"assets"`
+		Bookmark string   `json:"bookmark"`
+	}
+	if err := json.Unmarshal(pageJSON, &page); err != nil {
+		return fmt.Errorf("failed to parse assets: %w", err)
+	}
+	it.page = page.Assets
+	it.bookmark = page.Bookmark
+	it.done = page.Bookmark == ""
+	return nil
+}
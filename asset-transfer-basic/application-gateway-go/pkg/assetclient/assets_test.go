@@ -0,0 +1,84 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeEvaluator returns its results in turn, one per evaluation.
+type fakeEvaluator struct {
+	results   []fakeResult
+	calls     int
+	functions []string
+}
+
+type fakeResult struct {
+	json string
+	err  error
+}
+
+func (e *fakeEvaluator) EvaluateWithContext(ctx context.Context, transactionName string, options ...client.ProposalOption) ([]byte, error) {
+	result := e.results[e.calls]
+	e.calls++
+	e.functions = append(e.functions, transactionName)
+	return []byte(result.json), result.err
+}
+
+func TestListAssetsPagesAndRetries(t *testing.T) {
+	contract := &fakeEvaluator{results: []fakeResult{
+		{json: `{"assets":[{"ID":"asset1"},{"ID":"asset2"}],"bookmark":"b1"}`},
+		{err: status.Error(codes.Unavailable, "peer unavailable")},
+		{json: `{"assets":[{"ID":"asset3"}],"bookmark":""}`},
+	}}
+
+	assets, err := ListAssets(context.Background(), contract, Filter{DealerID: "DEALER101", PageSize: 2}).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 3 || assets[0].ID != "asset1" || assets[2].ID != "asset3" {
+		t.Fatalf("expected asset1 to asset3, got %+v", assets)
+	}
+	if contract.calls != 3 {
+		t.Fatalf("expected 3 evaluations, got %d", contract.calls)
+	}
+}
+
+func TestListAssetsStopsOnBusinessError(t *testing.T) {
+	contract := &fakeEvaluator{results: []fakeResult{
+		{err: status.Error(codes.Unknown, `chaincode response 500, {"code":"INVALID_ARGUMENT","message":"invalid status \"LOST\""}`)},
+	}}
+
+	iterator := ListAssets(context.Background(), contract, Filter{Status: "LOST"})
+	if iterator.Next() {
+		t.Fatal("expected no asset")
+	}
+	if !errors.Is(iterator.Err(), ErrInvalidArgument) || contract.calls != 1 {
+		t.Fatalf("expected ErrInvalidArgument without retry, got %v after %d calls", iterator.Err(), contract.calls)
+	}
+}
+
+func TestListAssetsFetchesSelectedFields(t *testing.T) {
+	contract := &fakeEvaluator{results: []fakeResult{
+		{json: `{"assets":[{"ID":"asset1","balance":100}],"bookmark":""}`},
+	}}
+
+	assets, err := ListAssets(context.Background(), contract, Filter{Fields: []string{"balance"}}).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || assets[0].Balance != 100 || assets[0].Status != "" {
+		t.Fatalf("expected the balance of asset1 only, got %+v", assets)
+	}
+	if contract.functions[0] != "GetAssetsFilteredFields" {
+		t.Fatalf("expected GetAssetsFilteredFields, got %s", contract.functions[0])
+	}
+}
@@ -0,0 +1,339 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxBackupFrame bounds the size of a frame of a backup log file, so that a
+// corrupt length cannot exhaust memory.
+const maxBackupFrame = 64 << 20
+
+// ErrBackupCorrupt is returned when a backup log was altered, truncated in the
+// middle or encrypted with another key.
+var ErrBackupCorrupt = errors.New("backup log is corrupt")
+
+// BackupWrite is a world state write of the chaincode.
+type BackupWrite struct {
+	Key      string `json:"key"`
+	Value    []byte `json:"value,omitempty"`
+	IsDelete bool   `json:"isDelete,omitempty"`
+}
+
+// BackupBlock is the world state writes of the chaincode made by the valid
+// transactions of a block, in ledger order.
+type BackupBlock struct {
+	Number uint64        `json:"number"`
+	Writes []BackupWrite `json:"writes"`
+}
+
+// BlockWrites returns the world state writes made to namespace by the valid
+// transactions of a block. Private data writes are left out, as blocks only
+// carry their hashes.
+func BlockWrites(block *common.Block, namespace string) (*BackupBlock, error) {
+	result := &BackupBlock{Number: block.GetHeader().GetNumber(), Writes: []BackupWrite{}}
+	envelopes := block.GetData().GetData()
+	metadata := block.GetMetadata().GetMetadata()
+	if len(metadata) <= int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) || len(metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]) < len(envelopes) {
+		return nil, fmt.Errorf("block %d lacks the validation codes of its transactions", result.Number)
+	}
+	validationCodes := metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+
+	for i, envelopeBytes := range envelopes {
+		if peer.TxValidationCode(validationCodes[i]) != peer.TxValidationCode_VALID {
+			continue
+		}
+		var envelope common.Envelope
+		if err := proto.Unmarshal(envelopeBytes, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction %d of block %d: %w", i, result.Number, err)
+		}
+		var payload common.Payload
+		if err := proto.Unmarshal(envelope.GetPayload(), &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse payload of transaction %d of block %d: %w", i, result.Number, err)
+		}
+		var channelHeader common.ChannelHeader
+		if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), &channelHeader); err != nil {
+			return nil, fmt.Errorf("failed to parse channel header of transaction %d of block %d: %w", i, result.Number, err)
+		}
+		if common.HeaderType(channelHeader.GetType()) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue
+		}
+		var tx peer.Transaction
+		if err := proto.Unmarshal(payload.GetData(), &tx); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction %s: %w", channelHeader.GetTxId(), err)
+		}
+
+		for _, txAction := range tx.GetActions() {
+			action, err := transactionAction(txAction)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %s: %w", channelHeader.GetTxId(), err)
+			}
+			var txRWSet rwset.TxReadWriteSet
+			if err := proto.Unmarshal(action.GetResults(), &txRWSet); err != nil {
+				return nil, fmt.Errorf("failed to parse read/write set of transaction %s: %w", channelHeader.GetTxId(), err)
+			}
+			for _, nsRWSet := range txRWSet.GetNsRwset() {
+				if nsRWSet.GetNamespace() != namespace {
+					continue
+				}
+				var kvRWSet kvrwset.KVRWSet
+				if err := proto.Unmarshal(nsRWSet.GetRwset(), &kvRWSet); err != nil {
+					return nil, fmt.Errorf("failed to parse read/write set of transaction %s: %w", channelHeader.GetTxId(), err)
+				}
+				for _, write := range kvRWSet.GetWrites() {
+					result.Writes = append(result.Writes, BackupWrite{Key: write.GetKey(), Value: write.GetValue(), IsDelete: write.GetIsDelete()})
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// BackupKeyFromEnv returns the AES-256 key of backup logs, base64 encoded in
+// the BACKUP_KEY environment variable.
+func BackupKeyFromEnv() ([]byte, error) {
+	encoded := os.Getenv("BACKUP_KEY")
+	if encoded == "" {
+		return nil, errors.New("BACKUP_KEY must hold the base64 encoded 32 byte key of the backup log")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode BACKUP_KEY: %w", err)
+	}
+	return key, nil
+}
+
+// BackupStore keeps the frames of a backup log, in the order they were appended.
+type BackupStore interface {
+	// Append adds the frame holding block to the end of the log.
+	Append(ctx context.Context, block uint64, frame []byte) error
+	// Frames calls fn with every frame of the log, in order.
+	Frames(ctx context.Context, fn func(frame []byte) error) error
+}
+
+// BackupStoreFor returns the store of a backup log location, an S3 URL such as
+// s3://bucket/prefix or the path of a local file.
+func BackupStoreFor(location string) (BackupStore, error) {
+	if strings.HasPrefix(location, "s3://") {
+		return S3BackupStoreFromURL(location)
+	}
+	return &FileBackupStore{Path: location}, nil
+}
+
+// BackupLog appends blocks to an encrypted, append-only backup log. Every block
+// is a frame encrypted with AES-256-GCM and authenticated together with the
+// hash of the previous frame, so that reading the log detects frames that were
+// altered, removed or reordered. Concurrent appends are serialized.
+type BackupLog struct {
+	lock     sync.Mutex
+	store    BackupStore
+	aead     cipher.AEAD
+	previous [sha256.Size]byte
+	next     uint64
+}
+
+// OpenBackupLog reads the frames already in store, to append after them.
+func OpenBackupLog(ctx context.Context, store BackupStore, key []byte) (*BackupLog, error) {
+	aead, err := newBackupAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	log := &BackupLog{store: store, aead: aead}
+	log.previous, err = readBackupFrames(ctx, store, aead, func(block *BackupBlock) error {
+		log.next = block.Number + 1
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// NextBlock returns the block following the last one in the log, from which to
+// resume streaming.
+func (l *BackupLog) NextBlock() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.next
+}
+
+// Append encrypts block and adds it to the log. Blocks must be appended in
+// ledger order.
+func (l *BackupLog) Append(ctx context.Context, block *BackupBlock) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if block.Number < l.next {
+		return fmt.Errorf("block %d is already in the backup log, which continues from block %d", block.Number, l.next)
+	}
+	plaintext, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, l.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	frame := l.aead.Seal(nonce, nonce, plaintext, l.previous[:])
+
+	if err := l.store.Append(ctx, block.Number, frame); err != nil {
+		return fmt.Errorf("failed to append block %d to the backup log: %w", block.Number, err)
+	}
+	l.previous = sha256.Sum256(frame)
+	l.next = block.Number + 1
+	return nil
+}
+
+// ReadBackup decrypts the blocks of a backup log and calls fn with each, in
+// order. It fails with ErrBackupCorrupt when a frame does not follow the
+// previous one.
+func ReadBackup(ctx context.Context, store BackupStore, key []byte, fn func(block *BackupBlock) error) error {
+	aead, err := newBackupAEAD(key)
+	if err != nil {
+		return err
+	}
+	_, err = readBackupFrames(ctx, store, aead, fn)
+	return err
+}
+
+func newBackupAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the backup key must be 32 bytes long, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readBackupFrames decrypts the frames of store, returning the hash of the last.
+func readBackupFrames(ctx context.Context, store BackupStore, aead cipher.AEAD, fn func(block *BackupBlock) error) ([sha256.Size]byte, error) {
+	var previous [sha256.Size]byte
+	var next uint64
+	err := store.Frames(ctx, func(frame []byte) error {
+		if len(frame) < aead.NonceSize() {
+			return fmt.Errorf("%w: frame after block %d is too short", ErrBackupCorrupt, next)
+		}
+		plaintext, err := aead.Open(nil, frame[:aead.NonceSize()], frame[aead.NonceSize():], previous[:])
+		if err != nil {
+			return fmt.Errorf("%w: frame after block %d does not decrypt: %v", ErrBackupCorrupt, next, err)
+		}
+		var block BackupBlock
+		if err := json.Unmarshal(plaintext, &block); err != nil {
+			return fmt.Errorf("%w: frame after block %d: %v", ErrBackupCorrupt, next, err)
+		}
+		if block.Number < next {
+			return fmt.Errorf("%w: block %d follows block %d", ErrBackupCorrupt, block.Number, next-1)
+		}
+		if err := fn(&block); err != nil {
+			return err
+		}
+		previous = sha256.Sum256(frame)
+		next = block.Number + 1
+		return nil
+	})
+	return previous, err
+}
+
+// FileBackupStore keeps a backup log in a local file, each frame prefixed with
+// its length. A frame torn by a crash while it was appended is skipped by
+// Frames and overwritten by the next Append.
+type FileBackupStore struct {
+	Path string
+
+	lock sync.Mutex
+	// end is the offset following the last complete frame, once read by Frames
+	end     int64
+	scanned bool
+}
+
+// Append writes the frame at the end of the file and syncs it to disk.
+func (s *FileBackupStore) Append(_ context.Context, _ uint64, frame []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	file, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if s.scanned {
+		if err := file.Truncate(s.end); err != nil {
+			return err
+		}
+		s.scanned = false
+	}
+	record := make([]byte, 4, 4+len(frame))
+	binary.BigEndian.PutUint32(record, uint32(len(frame)))
+	if _, err := file.Write(append(record, frame...)); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Frames reads the frames of the file, none when it does not exist.
+func (s *FileBackupStore) Frames(ctx context.Context, fn func(frame []byte) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	file, err := os.Open(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.end, s.scanned = 0, true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for ctx.Err() == nil {
+		var header [4]byte
+		if _, err := io.ReadFull(reader, header[:]); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			s.end, s.scanned = offset, true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxBackupFrame {
+			return fmt.Errorf("%w: frame at offset %d is %d bytes long", ErrBackupCorrupt, offset, size)
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(reader, frame); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			s.end, s.scanned = offset, true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(frame); err != nil {
+			return err
+		}
+		offset += int64(len(header)) + int64(size)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,73 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupLogResumesAndDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{7}, 32)
+	path := filepath.Join(t.TempDir(), "backup.log")
+
+	backup, err := OpenBackupLog(ctx, &FileBackupStore{Path: path}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, number := range []uint64{3, 5} {
+		block := &BackupBlock{Number: number, Writes: []BackupWrite{{Key: "asset1", Value: []byte("secret balance")}}}
+		if err := backup.Append(ctx, block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	contents, _ := os.ReadFile(path)
+	if bytes.Contains(contents, []byte("secret balance")) {
+		t.Fatal("expected the backup log to be encrypted")
+	}
+
+	// a frame torn by a crash is dropped when the log is reopened
+	if err := os.WriteFile(path, append(contents, 0, 0, 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	backup, err = OpenBackupLog(ctx, &FileBackupStore{Path: path}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.NextBlock() != 6 {
+		t.Fatalf("expected to resume from block 6, got %d", backup.NextBlock())
+	}
+	if err := backup.Append(ctx, &BackupBlock{Number: 4}); err == nil {
+		t.Fatal("expected a block already in the log to be rejected")
+	}
+	if err := backup.Append(ctx, &BackupBlock{Number: 8, Writes: []BackupWrite{{Key: "asset1", IsDelete: true}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var numbers []uint64
+	err = ReadBackup(ctx, &FileBackupStore{Path: path}, key, func(block *BackupBlock) error {
+		numbers = append(numbers, block.Number)
+		return nil
+	})
+	if err != nil || len(numbers) != 3 || numbers[2] != 8 {
+		t.Fatalf("expected blocks 3, 5 and 8, got %v, %v", numbers, err)
+	}
+
+	// removing the first frame breaks the chain of the second
+	contents, _ = os.ReadFile(path)
+	firstFrame := 4 + int(contents[3]) + int(contents[2])<<8
+	if err := os.WriteFile(path, contents[firstFrame:], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err = ReadBackup(ctx, &FileBackupStore{Path: path}, key, func(*BackupBlock) error { return nil })
+	if !errors.Is(err, ErrBackupCorrupt) {
+		t.Fatalf("expected a removed frame to be detected, got %v", err)
+	}
+}
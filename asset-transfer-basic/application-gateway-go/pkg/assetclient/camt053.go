@@ -0,0 +1,285 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Camt053Namespace is the XML namespace of the ISO 20022 bank to customer
+// statements rendered by WriteCamt053.
+const Camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"
+
+// StatementCurrency is the currency of the balances of the ledger.
+const StatementCurrency = "INR"
+
+// StatementAccount is an asset of a dealer on a daily statement, with its
+// balance at the start and at the end of the day.
+type StatementAccount struct {
+	AssetID        string
+	OpeningBalance float64
+	ClosingBalance float64
+	// TransType is the type of the last transaction of the asset, such as CREDIT.
+	TransType string
+	UpdatedAt string
+}
+
+// Movement returns the change of the balance of the account over the day.
+func (a StatementAccount) Movement() float64 {
+	return math.Round((a.ClosingBalance-a.OpeningBalance)*100) / 100
+}
+
+// DailyStatement is the statement of a dealer for a day, the UTC calendar day
+// of Date: the opening and closing balances of each of its assets.
+type DailyStatement struct {
+	DealerID  string
+	Date      time.Time
+	Accounts  []StatementAccount
+	CreatedAt time.Time
+}
+
+// OpeningBalance returns the total balance of the dealer at the start of the day.
+func (s *DailyStatement) OpeningBalance() float64 {
+	var total float64
+	for _, account := range s.Accounts {
+		total += account.OpeningBalance
+	}
+	return total
+}
+
+// ClosingBalance returns the total balance of the dealer at the end of the day.
+func (s *DailyStatement) ClosingBalance() float64 {
+	var total float64
+	for _, account := range s.Accounts {
+		total += account.ClosingBalance
+	}
+	return total
+}
+
+// ReadDailyStatement reads the statement of a dealer for the UTC day of date:
+// the assets of the dealer, from every part of GetDealerStatement, with their
+// balances at the start and at the end of the day from GetBalanceSeries.
+// Assets the dealer no longer holds are not on the statement.
+func ReadDailyStatement(ctx context.Context, contract Evaluator, dealerID string, date time.Time) (*DailyStatement, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	statement := &DailyStatement{DealerID: dealerID, Date: day, CreatedAt: time.Now().UTC()}
+
+	var assets []*Asset
+	bookmark := ""
+	for {
+		var part struct {
+			Assets   []*Asset `json:"assets"`
+			Bookmark string   `json:"bookmark"`
+		}
+		if err := evaluateJSON(ctx, contract, &part, "GetDealerStatement", dealerID, "0", bookmark); err != nil {
+			return nil, err
+		}
+		assets = append(assets, part.Assets...)
+		if part.Bookmark == "" {
+			break
+		}
+		bookmark = part.Bookmark
+	}
+
+	// the last second of the day, so that a change at midnight is on the statement of the next day
+	from := day.Format(time.RFC3339)
+	to := day.Add(24*time.Hour - time.Second).Format(time.RFC3339)
+	for _, asset := range assets {
+		var samples []struct {
+			Balance float64 `json:"balance"`
+		}
+		if err := evaluateJSON(ctx, contract, &samples, "GetBalanceSeries", asset.ID, from, to, (24*time.Hour - time.Second).String()); err != nil {
+			return nil, err
+		}
+		if len(samples) != 2 {
+			return nil, fmt.Errorf("expected the opening and closing balances of asset %s, got %d samples", asset.ID, len(samples))
+		}
+		statement.Accounts = append(statement.Accounts, StatementAccount{
+			AssetID:        asset.ID,
+			OpeningBalance: samples[0].Balance,
+			ClosingBalance: samples[1].Balance,
+			TransType:      asset.TransType,
+			UpdatedAt:      asset.UpdatedAt,
+		})
+	}
+	return statement, nil
+}
+
+// evaluateJSON evaluates a transaction function, retrying infrastructure
+// errors, and unmarshals its result into v.
+func evaluateJSON(ctx context.Context, contract Evaluator, v any, function string, args ...string) error {
+	var result []byte
+	err := Retry(ctx, listAttempts, listBackoff, func(ctx context.Context) error {
+		var err error
+		result, err = contract.EvaluateWithContext(ctx, function, client.WithArguments(args...))
+		return err
+	})
+	if err != nil {
+		return DecodeChaincodeError(err)
+	}
+	if err := json.Unmarshal(result, v); err != nil {
+		return fmt.Errorf("failed to parse the result of %s: %w", function, err)
+	}
+	return nil
+}
+
+// WriteCamt053 writes the statement as an ISO 20022 camt.053 bank to customer
+// statement. The dealer is the account, each asset whose balance changed over
+// the day an entry booked with its net movement, and the bank transaction code
+// of an entry is the proprietary code of the last transaction type of the asset.
+func (s *DailyStatement) WriteCamt053(w io.Writer) error {
+	statementID := fmt.Sprintf("%s-%s", s.DealerID, s.Date.Format("20060102"))
+	fromTo := camtPeriod{
+		From: s.Date.Format(time.RFC3339),
+		To:   s.Date.Add(24*time.Hour - time.Second).Format(time.RFC3339),
+	}
+	date := s.Date.Format(time.DateOnly)
+
+	statement := camtStatement{
+		ID:       statementID,
+		Created:  s.CreatedAt.Format(time.RFC3339),
+		Period:   fromTo,
+		Account:  camtAccount{ID: s.DealerID, Currency: StatementCurrency},
+		Balances: []camtBalance{newCamtBalance("OPBD", s.OpeningBalance(), date), newCamtBalance("CLBD", s.ClosingBalance(), date)},
+	}
+
+	var sum, net float64
+	for _, account := range s.Accounts {
+		movement := account.Movement()
+		if movement == 0 {
+			continue
+		}
+		sum += math.Abs(movement)
+		net += movement
+		code := account.TransType
+		if code == "" {
+			code = "NOTPROVIDED"
+		}
+		// the asset may have changed since, its last update is then not the booking
+		booked := ""
+		if strings.HasPrefix(account.UpdatedAt, date) {
+			booked = account.UpdatedAt
+		}
+		statement.Entries = append(statement.Entries, camtEntry{
+			Reference:       account.AssetID,
+			Amount:          camtAmount{Currency: StatementCurrency, Value: formatCamtAmount(math.Abs(movement))},
+			CreditDebit:     creditDebit(movement),
+			Status:          "BOOK",
+			BookingDateTime: booked,
+			ValueDate:       date,
+			TransactionCode: code,
+			ServicerRef:     account.AssetID,
+		})
+	}
+	statement.Summary = camtSummary{
+		Entries:     len(statement.Entries),
+		Sum:         formatCamtAmount(sum),
+		Net:         formatCamtAmount(math.Abs(net)),
+		CreditDebit: creditDebit(net),
+	}
+
+	document := camtDocument{
+		Namespace: Camt053Namespace,
+		Header:    camtHeader{MessageID: statementID, Created: s.CreatedAt.Format(time.RFC3339)},
+		Statement: statement,
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to encode camt.053 statement: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newCamtBalance(code string, amount float64, date string) camtBalance {
+	return camtBalance{
+		Code:        code,
+		Amount:      camtAmount{Currency: StatementCurrency, Value: formatCamtAmount(math.Abs(amount))},
+		CreditDebit: creditDebit(amount),
+		Date:        date,
+	}
+}
+
+func creditDebit(amount float64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+func formatCamtAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// The camt.053 elements written by WriteCamt053.
+type (
+	camtDocument struct {
+		XMLName   xml.Name      `xml:"Document"`
+		Namespace string        `xml:"xmlns,attr"`
+		Header    camtHeader    `xml:"BkToCstmrStmt>GrpHdr"`
+		Statement camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	}
+	camtHeader struct {
+		MessageID string `xml:"MsgId"`
+		Created   string `xml:"CreDtTm"`
+	}
+	camtStatement struct {
+		ID       string        `xml:"Id"`
+		Created  string        `xml:"CreDtTm"`
+		Period   camtPeriod    `xml:"FrToDt"`
+		Account  camtAccount   `xml:"Acct"`
+		Balances []camtBalance `xml:"Bal"`
+		Summary  camtSummary   `xml:"TxsSummry"`
+		Entries  []camtEntry   `xml:"Ntry"`
+	}
+	camtPeriod struct {
+		From string `xml:"FrDtTm"`
+		To   string `xml:"ToDtTm"`
+	}
+	camtAccount struct {
+		ID       string `xml:"Id>Othr>Id"`
+		Currency string `xml:"Ccy"`
+	}
+	camtAmount struct {
+		Currency string `xml:"Ccy,attr"`
+		Value    string `xml:",chardata"`
+	}
+	camtBalance struct {
+		Code        string     `xml:"Tp>CdOrPrtry>Cd"`
+		Amount      camtAmount `xml:"Amt"`
+		CreditDebit string     `xml:"CdtDbtInd"`
+		Date        string     `xml:"Dt>Dt"`
+	}
+	camtSummary struct {
+		Entries     int    `xml:"TtlNtries>NbOfNtries"`
+		Sum         string `xml:"TtlNtries>Sum"`
+		Net         string `xml:"TtlNtries>TtlNetNtry>Amt"`
+		CreditDebit string `xml:"TtlNtries>TtlNetNtry>CdtDbtInd"`
+	}
+	camtEntry struct {
+		Reference       string     `xml:"NtryRef"`
+		Amount          camtAmount `xml:"Amt"`
+		CreditDebit     string     `xml:"CdtDbtInd"`
+		Status          string     `xml:"Sts>Cd"`
+		BookingDateTime string     `xml:"BookgDt>DtTm,omitempty"`
+		ValueDate       string     `xml:"ValDt>Dt"`
+		TransactionCode string     `xml:"BkTxCd>Prtry>Cd"`
+		ServicerRef     string     `xml:"NtryDtls>TxDtls>Refs>AcctSvcrRef"`
+	}
+)
@@ -0,0 +1,87 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadDailyStatement(t *testing.T) {
+	contract := &fakeEvaluator{results: []fakeResult{
+		{json: `{"assets":[{"ID":"asset1","transtype":"CREDIT","updatedat":"2024-01-02T10:00:00Z"}],"bookmark":"b1"}`},
+		{json: `{"assets":[{"ID":"asset2","transtype":"DEBIT","updatedat":"2024-01-01T09:00:00Z"}],"bookmark":""}`},
+		{json: `[{"balance":500,"exists":true},{"balance":1500,"exists":true}]`},
+		{json: `[{"balance":200,"exists":true},{"balance":200,"exists":true}]`},
+	}}
+
+	statement, err := ReadDailyStatement(context.Background(), contract, "DEALER101", time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(contract.functions, []string{"GetDealerStatement", "GetDealerStatement", "GetBalanceSeries", "GetBalanceSeries"}) {
+		t.Fatalf("unexpected evaluations %q", contract.functions)
+	}
+	expected := []StatementAccount{
+		{AssetID: "asset1", OpeningBalance: 500, ClosingBalance: 1500, TransType: "CREDIT", UpdatedAt: "2024-01-02T10:00:00Z"},
+		{AssetID: "asset2", OpeningBalance: 200, ClosingBalance: 200, TransType: "DEBIT", UpdatedAt: "2024-01-01T09:00:00Z"},
+	}
+	if !reflect.DeepEqual(statement.Accounts, expected) || !statement.Date.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected statement %+v", statement)
+	}
+}
+
+func TestWriteCamt053(t *testing.T) {
+	statement := &DailyStatement{
+		DealerID: "DEALER101",
+		Date:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Accounts: []StatementAccount{
+			{AssetID: "asset1", OpeningBalance: 500, ClosingBalance: 1500, TransType: "CREDIT", UpdatedAt: "2024-01-02T10:00:00Z"},
+			{AssetID: "asset2", OpeningBalance: 200, ClosingBalance: 200, TransType: "DEBIT"},
+			{AssetID: "asset3", OpeningBalance: 300, ClosingBalance: 50.25, TransType: "DEBIT", UpdatedAt: "2024-01-03T08:00:00Z"},
+		},
+		CreatedAt: time.Date(2024, 1, 3, 1, 0, 0, 0, time.UTC),
+	}
+
+	var out strings.Builder
+	if err := statement.WriteCamt053(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<Document xmlns="`+Camt053Namespace+`">`) {
+		t.Fatalf("unexpected document start %q", out.String()[:120])
+	}
+
+	var document camtDocument
+	if err := xml.Unmarshal([]byte(out.String()), &document); err != nil {
+		t.Fatal(err)
+	}
+	stmt := document.Statement
+	if document.Header.MessageID != "DEALER101-20240102" || stmt.Account.ID != "DEALER101" || stmt.Period.To != "2024-01-02T23:59:59Z" {
+		t.Fatalf("unexpected header %+v, %+v", document.Header, stmt)
+	}
+	expectedBalances := []camtBalance{
+		{Code: "OPBD", Amount: camtAmount{Currency: "INR", Value: "1000.00"}, CreditDebit: "CRDT", Date: "2024-01-02"},
+		{Code: "CLBD", Amount: camtAmount{Currency: "INR", Value: "1750.25"}, CreditDebit: "CRDT", Date: "2024-01-02"},
+	}
+	if !reflect.DeepEqual(stmt.Balances, expectedBalances) {
+		t.Fatalf("expected balances %+v, got %+v", expectedBalances, stmt.Balances)
+	}
+	if stmt.Summary != (camtSummary{Entries: 2, Sum: "1249.75", Net: "750.25", CreditDebit: "CRDT"}) {
+		t.Fatalf("unexpected summary %+v", stmt.Summary)
+	}
+	expectedEntries := []camtEntry{
+		{Reference: "asset1", Amount: camtAmount{Currency: "INR", Value: "1000.00"}, CreditDebit: "CRDT", Status: "BOOK",
+			BookingDateTime: "2024-01-02T10:00:00Z", ValueDate: "2024-01-02", TransactionCode: "CREDIT", ServicerRef: "asset1"},
+		{Reference: "asset3", Amount: camtAmount{Currency: "INR", Value: "249.75"}, CreditDebit: "DBIT", Status: "BOOK",
+			ValueDate: "2024-01-02", TransactionCode: "DEBIT", ServicerRef: "asset3"},
+	}
+	if !reflect.DeepEqual(stmt.Entries, expectedEntries) {
+		t.Fatalf("expected entries %+v, got %+v", expectedEntries, stmt.Entries)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// CloudEvents content modes of the HTTP protocol binding.
+const (
+	// CloudEventsBinary carries the event attributes as ce- headers and the
+	// payload as the body.
+	CloudEventsBinary = "binary"
+	// CloudEventsStructured carries the whole event as an
+	// application/cloudevents+json body.
+	CloudEventsStructured = "structured"
+)
+
+// cloudEventsContentType is the media type of structured mode events.
+const cloudEventsContentType = "application/cloudevents+json"
+
+// DefaultCloudEventTypePrefix prefixes the chaincode event name in the type of
+// the CloudEvents, such as org.hyperledger.fabric.chaincode.AssetChanged.
+const DefaultCloudEventTypePrefix = "org.hyperledger.fabric.chaincode."
+
+// CloudEvent is a chaincode event in the CloudEvents 1.0 JSON format. Payloads
+// that are JSON are carried as data, any other as data_base64. The block
+// number and chaincode name are the blocknumber and chaincode extensions.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+	BlockNumber     string          `json:"blocknumber"`
+	Chaincode       string          `json:"chaincode"`
+}
+
+// CloudEventEncoder encodes chaincode events as CloudEvents, for consumers such
+// as Knative or EventBridge. Source identifies the channel and chaincode the
+// events come from, such as /fabric/mychannel/basic, and TypePrefix is
+// DefaultCloudEventTypePrefix when empty. The ID of an event is its
+// transaction ID, unique as Fabric emits at most one event per transaction.
+type CloudEventEncoder struct {
+	Source     string
+	TypePrefix string
+}
+
+// Event returns the CloudEvent of a chaincode event.
+func (e *CloudEventEncoder) Event(event *client.ChaincodeEvent) *CloudEvent {
+	typePrefix := e.TypePrefix
+	if typePrefix == "" {
+		typePrefix = DefaultCloudEventTypePrefix
+	}
+	cloudEvent := &CloudEvent{
+		SpecVersion: "1.0",
+		ID:          event.TransactionID,
+		Source:      e.Source,
+		Type:        typePrefix + event.EventName,
+		BlockNumber: strconv.FormatUint(event.BlockNumber, 10),
+		Chaincode:   event.ChaincodeName,
+	}
+	switch {
+	case len(event.Payload) == 0:
+	case json.Valid(event.Payload):
+		cloudEvent.DataContentType = "application/json"
+		cloudEvent.Data = event.Payload
+	default:
+		cloudEvent.DataContentType = "application/octet-stream"
+		cloudEvent.DataBase64 = event.Payload
+	}
+	return cloudEvent
+}
+
+// Structured returns the body and content type of a chaincode event in the
+// structured content mode.
+func (e *CloudEventEncoder) Structured(event *client.ChaincodeEvent) ([]byte, string, error) {
+	body, err := json.Marshal(e.Event(event))
+	if err != nil {
+		return nil, "", err
+	}
+	return body, cloudEventsContentType, nil
+}
+
+// Binary returns the headers and body of a chaincode event in the binary
+// content mode.
+func (e *CloudEventEncoder) Binary(event *client.ChaincodeEvent) (http.Header, []byte) {
+	cloudEvent := e.Event(event)
+	header := http.Header{}
+	header.Set("ce-specversion", cloudEvent.SpecVersion)
+	header.Set("ce-id", cloudEvent.ID)
+	header.Set("ce-source", cloudEvent.Source)
+	header.Set("ce-type", cloudEvent.Type)
+	header.Set("ce-blocknumber", cloudEvent.BlockNumber)
+	header.Set("ce-chaincode", cloudEvent.Chaincode)
+	if cloudEvent.DataContentType != "" {
+		header.Set("Content-Type", cloudEvent.DataContentType)
+	}
+	return header, event.Payload
+}
+
+// NewRequest returns the HTTP POST request delivering a chaincode event to url
+// in the binary or structured content mode.
+func (e *CloudEventEncoder) NewRequest(ctx context.Context, url string, mode string, event *client.ChaincodeEvent) (*http.Request, error) {
+	var header http.Header
+	var body []byte
+	switch mode {
+	case CloudEventsBinary:
+		header, body = e.Binary(event)
+	case CloudEventsStructured:
+		var contentType string
+		var err error
+		if body, contentType, err = e.Structured(event); err != nil {
+			return nil, err
+		}
+		header = http.Header{"Content-Type": []string{contentType}}
+	default:
+		return nil, fmt.Errorf("unknown CloudEvents mode %q, expected binary or structured", mode)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header = header
+	return request, nil
+}
+
+// Deliver posts a chaincode event to url and fails unless the sink accepts it
+// with a 2xx status, so that the caller retries it from its last position.
+func (e *CloudEventEncoder) Deliver(ctx context.Context, httpClient *http.Client, url string, mode string, event *client.ChaincodeEvent) error {
+	request, err := e.NewRequest(ctx, url, mode, event)
+	if err != nil {
+		return err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to deliver event to %s: %w", url, err)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("event sink %s answered %s", url, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+func TestCloudEventEncoderStructured(t *testing.T) {
+	encoder := &CloudEventEncoder{Source: "/fabric/mychannel/basic"}
+	event := &client.ChaincodeEvent{BlockNumber: 7, TransactionID: "tx1", ChaincodeName: "basic", EventName: "AssetChanged", Payload: []byte(`{"ID":"asset1"}`)}
+
+	body, contentType, err := encoder.Structured(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/cloudevents+json" {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["specversion"] != "1.0" || decoded["id"] != "tx1" || decoded["type"] != "org.hyperledger.fabric.chaincode.AssetChanged" || decoded["blocknumber"] != "7" {
+		t.Fatalf("unexpected attributes %s", body)
+	}
+	if data, ok := decoded["data"].(map[string]any); !ok || data["ID"] != "asset1" || decoded["datacontenttype"] != "application/json" {
+		t.Fatalf("expected the JSON payload as data, got %s", body)
+	}
+
+	event.Payload = []byte{0xff, 0x00}
+	body, _, _ = encoder.Structured(event)
+	decoded = nil
+	json.Unmarshal(body, &decoded)
+	if decoded["data_base64"] != "/wA=" || decoded["data"] != nil {
+		t.Fatalf("expected a binary payload as data_base64, got %s", body)
+	}
+}
+
+func TestCloudEventEncoderDeliverBinary(t *testing.T) {
+	var header http.Header
+	var body []byte
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sink.Close()
+
+	encoder := &CloudEventEncoder{Source: "/fabric/mychannel/basic", TypePrefix: "com.example."}
+	event := &client.ChaincodeEvent{BlockNumber: 7, TransactionID: "tx1", ChaincodeName: "basic", EventName: "AssetsSwapped", Payload: []byte(`{"txid":"tx1"}`)}
+	if err := encoder.Deliver(context.Background(), sink.Client(), sink.URL, CloudEventsBinary, event); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("ce-id") != "tx1" || header.Get("ce-type") != "com.example.AssetsSwapped" || header.Get("ce-source") != "/fabric/mychannel/basic" || header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers %v", header)
+	}
+	if string(body) != `{"txid":"tx1"}` {
+		t.Fatalf("expected the payload as body, got %s", body)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := encoder.Deliver(context.Background(), failing.Client(), failing.URL, CloudEventsStructured, event); err == nil {
+		t.Fatal("expected a rejected event to fail")
+	}
+}
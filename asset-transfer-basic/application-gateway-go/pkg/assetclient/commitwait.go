@@ -0,0 +1,265 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// CommitWait is how far a submitted transaction gets before the submit returns,
+// trading the latency of the caller for the certainty of the outcome.
+type CommitWait string
+
+const (
+	// WaitNone returns once the transaction is endorsed and submits it in the
+	// background. The caller does not learn whether it was ordered.
+	WaitNone CommitWait = "none"
+	// WaitSubmit returns once the orderers accepted the transaction. It may
+	// still fail validation, such as with an MVCC read conflict.
+	WaitSubmit CommitWait = "submit"
+	// WaitCommit returns the commit status reported by the Gateway peer.
+	WaitCommit CommitWait = "commit"
+	// WaitOrgs returns once the peers of a number of organizations committed the
+	// transaction, as seen in their block events.
+	WaitOrgs CommitWait = "orgs"
+)
+
+// CommitPolicy selects the CommitWait of a submit, and with WaitOrgs the number
+// of organizations that must have committed the transaction.
+type CommitPolicy struct {
+	Wait CommitWait
+	Orgs int
+}
+
+// ParseCommitPolicy parses none, submit, commit, or orgs:N for the peers of N
+// organizations. An empty policy is commit.
+func ParseCommitPolicy(policy string) (CommitPolicy, error) {
+	wait, orgs, hasOrgs := strings.Cut(strings.ToLower(strings.TrimSpace(policy)), ":")
+	switch CommitWait(wait) {
+	case "":
+		return CommitPolicy{Wait: WaitCommit}, nil
+	case WaitNone, WaitSubmit, WaitCommit:
+		if hasOrgs {
+			return CommitPolicy{}, fmt.Errorf("the commit wait %s takes no organization count", wait)
+		}
+		return CommitPolicy{Wait: CommitWait(wait)}, nil
+	case WaitOrgs:
+		count, err := strconv.Atoi(orgs)
+		if err != nil || count < 1 {
+			return CommitPolicy{}, fmt.Errorf("invalid commit wait %q, expected orgs:N with N at least 1", policy)
+		}
+		return CommitPolicy{Wait: WaitOrgs, Orgs: count}, nil
+	default:
+		return CommitPolicy{}, fmt.Errorf("unknown commit wait %q, expected none, submit, commit or orgs:N", policy)
+	}
+}
+
+func (p CommitPolicy) String() string {
+	if p.Wait == WaitOrgs {
+		return fmt.Sprintf("%s:%d", p.Wait, p.Orgs)
+	}
+	return string(p.Wait)
+}
+
+// OrgBlocks opens the filtered block events of the channel from the peers of
+// an organization, starting at startBlock.
+type OrgBlocks struct {
+	MspID  string
+	Events func(ctx context.Context, startBlock uint64) (<-chan *peer.FilteredBlock, error)
+}
+
+// NetworkBlocks reads the block events of network, a channel of a Gateway
+// connected to a peer of organization mspID.
+func NetworkBlocks(mspID string, network *client.Network) OrgBlocks {
+	return OrgBlocks{
+		MspID: mspID,
+		Events: func(ctx context.Context, startBlock uint64) (<-chan *peer.FilteredBlock, error) {
+			return network.FilteredBlockEvents(ctx, client.WithStartBlock(startBlock))
+		},
+	}
+}
+
+// CommitOutcome is how far a submitted transaction got under a CommitPolicy.
+// Status is nil unless the policy waited for the commit, and Orgs lists the
+// organizations confirming it under WaitOrgs.
+type CommitOutcome struct {
+	TransactionID string
+	Policy        CommitPolicy
+	Result        []byte
+	Status        *client.Status
+	Orgs          []string
+}
+
+// CommitOrgsError reports that fewer organizations than required confirmed the
+// commit of a transaction.
+type CommitOrgsError struct {
+	TransactionID string
+	Required      int
+	Confirmed     []string
+	Errs          map[string]error
+}
+
+func (e *CommitOrgsError) Error() string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "%d of the required %d organizations confirmed the commit of transaction %s", len(e.Confirmed), e.Required, e.TransactionID)
+	orgs := make([]string, 0, len(e.Errs))
+	for org := range e.Errs {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	for _, org := range orgs {
+		fmt.Fprintf(&message, "; %s: %s", org, e.Errs[org])
+	}
+	return message.String()
+}
+
+// CommitWaiter submits transactions and waits for them under a CommitPolicy.
+type CommitWaiter struct {
+	// Orgs are the organizations whose block events confirm commits under WaitOrgs.
+	Orgs []OrgBlocks
+	// SubmitFailed is called with the error of a submit under WaitNone, which has
+	// no caller left to return it to.
+	SubmitFailed func(transactionID string, err error)
+}
+
+// Submit submits an endorsed transaction and waits for it as policy selects.
+// Under WaitNone the submit outlives ctx.
+func (w *CommitWaiter) Submit(ctx context.Context, transaction *client.Transaction, policy CommitPolicy) (*CommitOutcome, error) {
+	if err := w.check(policy); err != nil {
+		return nil, err
+	}
+	if policy.Wait == WaitNone {
+		go func() {
+			_, err := transaction.SubmitWithContext(context.WithoutCancel(ctx))
+			if err != nil && w.SubmitFailed != nil {
+				w.SubmitFailed(transaction.TransactionID(), err)
+			}
+		}()
+		return &CommitOutcome{TransactionID: transaction.TransactionID(), Policy: policy, Result: transaction.Result()}, nil
+	}
+
+	commit, err := transaction.SubmitWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	outcome, err := w.Wait(ctx, commit, policy)
+	if outcome != nil {
+		outcome.Result = transaction.Result()
+	}
+	return outcome, err
+}
+
+// Wait waits for a submitted transaction as policy selects. Under WaitNone and
+// WaitSubmit it returns at once. An invalid transaction is reported in the
+// Status of the outcome, not as an error.
+func (w *CommitWaiter) Wait(ctx context.Context, commit *client.Commit, policy CommitPolicy) (*CommitOutcome, error) {
+	if err := w.check(policy); err != nil {
+		return nil, err
+	}
+	outcome := &CommitOutcome{TransactionID: commit.TransactionID(), Policy: policy}
+	if policy.Wait != WaitCommit && policy.Wait != WaitOrgs {
+		return outcome, nil
+	}
+
+	status, err := commit.StatusWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	outcome.Status = status
+	if policy.Wait == WaitCommit {
+		return outcome, nil
+	}
+
+	outcome.Orgs, err = w.confirmOrgs(ctx, status, policy.Orgs)
+	return outcome, err
+}
+
+// check fails when policy waits for more organizations than are configured.
+func (w *CommitWaiter) check(policy CommitPolicy) error {
+	if policy.Wait == WaitOrgs && policy.Orgs > len(w.Orgs) {
+		return fmt.Errorf("the commit wait %s needs the block events of %d organizations, %d are configured", policy, policy.Orgs, len(w.Orgs))
+	}
+	return nil
+}
+
+// confirmOrgs waits until required organizations have status.TransactionID in
+// block status.BlockNumber with the validation code of status, and returns them.
+func (w *CommitWaiter) confirmOrgs(ctx context.Context, status *client.Status, required int) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type confirmation struct {
+		mspID string
+		err   error
+	}
+	confirmations := make(chan confirmation, len(w.Orgs))
+	var wg sync.WaitGroup
+	for _, org := range w.Orgs {
+		wg.Add(1)
+		go func(org OrgBlocks) {
+			defer wg.Done()
+			confirmations <- confirmation{org.MspID, confirmBlock(ctx, org, status)}
+		}(org)
+	}
+	go func() {
+		wg.Wait()
+		close(confirmations)
+	}()
+
+	failure := &CommitOrgsError{TransactionID: status.TransactionID, Required: required, Errs: make(map[string]error)}
+	for result := range confirmations {
+		if result.err != nil {
+			failure.Errs[result.mspID] = result.err
+			continue
+		}
+		failure.Confirmed = append(failure.Confirmed, result.mspID)
+		if len(failure.Confirmed) == required {
+			sort.Strings(failure.Confirmed)
+			return failure.Confirmed, nil
+		}
+	}
+	sort.Strings(failure.Confirmed)
+	return nil, failure
+}
+
+// confirmBlock reads the block of status from the block events of org and
+// checks that it holds the transaction with the same validation code.
+func confirmBlock(ctx context.Context, org OrgBlocks, status *client.Status) error {
+	blocks, err := org.Events(ctx, status.BlockNumber)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case block, ok := <-blocks:
+			if !ok {
+				return fmt.Errorf("the block events closed before block %d", status.BlockNumber)
+			}
+			if block.GetNumber() != status.BlockNumber {
+				continue
+			}
+			for _, transaction := range block.GetFilteredTransactions() {
+				if transaction.GetTxid() != status.TransactionID {
+					continue
+				}
+				if transaction.GetTxValidationCode() != status.Code {
+					return fmt.Errorf("committed with status %s, the Gateway peer reported %s", transaction.GetTxValidationCode(), status.Code)
+				}
+				return nil
+			}
+			return fmt.Errorf("block %d does not hold the transaction", status.BlockNumber)
+		}
+	}
+}
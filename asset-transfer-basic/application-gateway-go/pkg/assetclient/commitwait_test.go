@@ -0,0 +1,109 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+func TestParseCommitPolicy(t *testing.T) {
+	for policy, expected := range map[string]CommitPolicy{
+		"":        {Wait: WaitCommit},
+		"none":    {Wait: WaitNone},
+		"Submit":  {Wait: WaitSubmit},
+		"commit":  {Wait: WaitCommit},
+		"orgs:2":  {Wait: WaitOrgs, Orgs: 2},
+		" orgs:1": {Wait: WaitOrgs, Orgs: 1},
+	} {
+		parsed, err := ParseCommitPolicy(policy)
+		if err != nil || parsed != expected {
+			t.Errorf("expected %q to parse as %+v, got %+v, %v", policy, expected, parsed, err)
+		}
+	}
+	for _, policy := range []string{"orgs", "orgs:0", "orgs:x", "commit:2", "later"} {
+		if _, err := ParseCommitPolicy(policy); err == nil {
+			t.Errorf("expected %q to be rejected", policy)
+		}
+	}
+	if policy := (CommitPolicy{Wait: WaitOrgs, Orgs: 3}).String(); policy != "orgs:3" {
+		t.Errorf("expected orgs:3, got %s", policy)
+	}
+}
+
+// blocksOf returns the block events of an organization that sends blocks from
+// the start block on.
+func blocksOf(mspID string, blocks ...*peer.FilteredBlock) OrgBlocks {
+	return OrgBlocks{
+		MspID: mspID,
+		Events: func(ctx context.Context, startBlock uint64) (<-chan *peer.FilteredBlock, error) {
+			events := make(chan *peer.FilteredBlock, len(blocks))
+			for _, block := range blocks {
+				if block.GetNumber() >= startBlock {
+					events <- block
+				}
+			}
+			return events, nil
+		},
+	}
+}
+
+func filteredBlock(number uint64, txID string, code peer.TxValidationCode) *peer.FilteredBlock {
+	return &peer.FilteredBlock{
+		Number:               number,
+		FilteredTransactions: []*peer.FilteredTransaction{{Txid: txID, TxValidationCode: code}},
+	}
+}
+
+func TestCommitWaiterConfirmsOrgs(t *testing.T) {
+	status := &client.Status{TransactionID: "tx1", BlockNumber: 5, Code: peer.TxValidationCode_VALID, Successful: true}
+	waiter := &CommitWaiter{Orgs: []OrgBlocks{
+		blocksOf("Org1MSP", filteredBlock(4, "tx0", peer.TxValidationCode_VALID), filteredBlock(5, "tx1", peer.TxValidationCode_VALID)),
+		blocksOf("Org2MSP", filteredBlock(5, "tx1", peer.TxValidationCode_VALID)),
+		// a lagging peer never sends the block
+		blocksOf("Org3MSP"),
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	orgs, err := waiter.confirmOrgs(ctx, status, 2)
+	if err != nil || !slices.Equal(orgs, []string{"Org1MSP", "Org2MSP"}) {
+		t.Fatalf("expected Org1MSP and Org2MSP, got %q, %v", orgs, err)
+	}
+}
+
+func TestCommitWaiterReportsDisagreeingOrgs(t *testing.T) {
+	status := &client.Status{TransactionID: "tx1", BlockNumber: 5, Code: peer.TxValidationCode_VALID, Successful: true}
+	waiter := &CommitWaiter{Orgs: []OrgBlocks{
+		blocksOf("Org1MSP", filteredBlock(5, "tx1", peer.TxValidationCode_VALID)),
+		blocksOf("Org2MSP", filteredBlock(5, "tx1", peer.TxValidationCode_MVCC_READ_CONFLICT)),
+		blocksOf("Org3MSP", filteredBlock(5, "tx2", peer.TxValidationCode_VALID)),
+	}}
+
+	_, err := waiter.confirmOrgs(context.Background(), status, 2)
+	var orgsErr *CommitOrgsError
+	if !errors.As(err, &orgsErr) {
+		t.Fatalf("expected a CommitOrgsError, got %v", err)
+	}
+	if !slices.Equal(orgsErr.Confirmed, []string{"Org1MSP"}) || len(orgsErr.Errs) != 2 {
+		t.Fatalf("expected Org1MSP alone to confirm, got %+v", orgsErr)
+	}
+}
+
+func TestCommitWaiterNeedsEnoughOrgs(t *testing.T) {
+	waiter := &CommitWaiter{Orgs: []OrgBlocks{blocksOf("Org1MSP")}}
+	if err := waiter.check(CommitPolicy{Wait: WaitOrgs, Orgs: 2}); err == nil {
+		t.Fatal("expected waiting for 2 organizations to fail with 1 configured")
+	}
+	if err := waiter.check(CommitPolicy{Wait: WaitCommit}); err != nil {
+		t.Fatal(err)
+	}
+}
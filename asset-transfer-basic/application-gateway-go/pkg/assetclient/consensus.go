@@ -0,0 +1,177 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// Consensus is the consensus type of the ordering service of a channel.
+type Consensus string
+
+const (
+	// ConsensusRaft is crash fault tolerant ordering, finalizing a block once a
+	// majority of the orderers has it.
+	ConsensusRaft Consensus = "etcdraft"
+	// ConsensusBFT is the SmartBFT ordering of Fabric 3.x. Blocks are final once
+	// signed by a quorum of orderers, which takes more round trips than Raft.
+	ConsensusBFT Consensus = "BFT"
+)
+
+// ParseConsensus parses a consensus type such as raft, etcdraft, bft or smartbft,
+// case insensitively. An empty string is Raft, the test network default.
+func ParseConsensus(consensus string) (Consensus, error) {
+	switch strings.ToLower(consensus) {
+	case "", "raft", "etcdraft":
+		return ConsensusRaft, nil
+	case "bft", "smartbft":
+		return ConsensusBFT, nil
+	default:
+		return "", fmt.Errorf("unknown consensus type %q, expected raft or bft", consensus)
+	}
+}
+
+// SubmitTimeout is how long to wait for the ordering service to accept a transaction.
+func (c Consensus) SubmitTimeout() time.Duration {
+	if c == ConsensusBFT {
+		return 15 * time.Second
+	}
+	return 5 * time.Second
+}
+
+// CommitStatusTimeout is how long to wait for a submitted transaction to commit.
+func (c Consensus) CommitStatusTimeout() time.Duration {
+	if c == ConsensusBFT {
+		return 3 * time.Minute
+	}
+	return 1 * time.Minute
+}
+
+// TimeoutOptions returns the Gateway submit and commit status timeouts suited to
+// the consensus type.
+func (c Consensus) TimeoutOptions() []client.ConnectOption {
+	return []client.ConnectOption{
+		client.WithSubmitTimeout(c.SubmitTimeout()),
+		client.WithCommitStatusTimeout(c.CommitStatusTimeout()),
+	}
+}
+
+// PeerCommitStatus is the commit status of a transaction as reported by one peer.
+type PeerCommitStatus struct {
+	Peer   string
+	Status *client.Status
+	Err    error
+}
+
+// QuorumStatus is the commit status of a transaction agreed on by a quorum of peers.
+type QuorumStatus struct {
+	TransactionID string
+	BlockNumber   uint64
+	Code          peer.TxValidationCode
+	Successful    bool
+	// Confirmations is the number of peers reporting the agreed status.
+	Confirmations int
+	Peers         []PeerCommitStatus
+}
+
+// CommitQuorumError reports that fewer than the required number of peers agree
+// on the commit status of a transaction.
+type CommitQuorumError struct {
+	TransactionID string
+	Required      int
+	Peers         []PeerCommitStatus
+}
+
+func (e *CommitQuorumError) Error() string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "fewer than %d peers agree on the commit status of transaction %s", e.Required, e.TransactionID)
+	for _, status := range e.Peers {
+		if status.Err != nil {
+			fmt.Fprintf(&message, "; %s: %s", status.Peer, status.Err)
+		} else {
+			fmt.Fprintf(&message, "; %s: %s in block %d", status.Peer, status.Status.Code, status.Status.BlockNumber)
+		}
+	}
+	return message.String()
+}
+
+// QuorumCommitStatus waits for the commit status of a transaction on every
+// Gateway in peers, keyed by a peer name, and returns the status reported by
+// at least quorum of them. A single Gateway peer reports the status of its own
+// ledger only; requiring several peers to agree on the block and validation
+// code confirms the transaction's finality across the network.
+func QuorumCommitStatus(ctx context.Context, commit *client.Commit, quorum int, peers map[string]*client.Gateway) (*QuorumStatus, error) {
+	commitBytes, err := commit.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]PeerCommitStatus, 0, len(peers))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, gateway := range peers {
+		wg.Add(1)
+		go func(name string, gateway *client.Gateway) {
+			defer wg.Done()
+			result := PeerCommitStatus{Peer: name}
+			peerCommit, err := gateway.NewCommit(commitBytes)
+			if err == nil {
+				result.Status, err = peerCommit.StatusWithContext(ctx)
+			}
+			result.Err = err
+
+			lock.Lock()
+			defer lock.Unlock()
+			statuses = append(statuses, result)
+		}(name, gateway)
+	}
+	wg.Wait()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Peer < statuses[j].Peer })
+	return tallyCommitStatuses(commit.TransactionID(), quorum, statuses)
+}
+
+// tallyCommitStatuses returns the status reported by the most peers, provided at
+// least quorum of them report it.
+func tallyCommitStatuses(transactionID string, quorum int, statuses []PeerCommitStatus) (*QuorumStatus, error) {
+	type outcome struct {
+		blockNumber uint64
+		code        peer.TxValidationCode
+	}
+	counts := make(map[outcome]int)
+	for _, status := range statuses {
+		if status.Err == nil {
+			counts[outcome{status.Status.BlockNumber, status.Status.Code}]++
+		}
+	}
+
+	var agreed outcome
+	best := 0
+	for candidate, count := range counts {
+		if count > best || (count == best && candidate.blockNumber < agreed.blockNumber) {
+			agreed, best = candidate, count
+		}
+	}
+
+	if best > 0 && best >= quorum {
+		return &QuorumStatus{
+			TransactionID: transactionID,
+			BlockNumber:   agreed.blockNumber,
+			Code:          agreed.code,
+			Successful:    agreed.code == peer.TxValidationCode_VALID,
+			Confirmations: best,
+			Peers:         statuses,
+		}, nil
+	}
+	return nil, &CommitQuorumError{TransactionID: transactionID, Required: quorum, Peers: statuses}
+}
@@ -0,0 +1,61 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+func TestParseConsensus(t *testing.T) {
+	for input, expected := range map[string]Consensus{"": ConsensusRaft, "etcdraft": ConsensusRaft, "BFT": ConsensusBFT, "smartbft": ConsensusBFT} {
+		consensus, err := ParseConsensus(input)
+		if err != nil || consensus != expected {
+			t.Fatalf("ParseConsensus(%q) = %q, %v; expected %q", input, consensus, err, expected)
+		}
+	}
+	if _, err := ParseConsensus("solo"); err == nil {
+		t.Fatal("expected an error for an unknown consensus type")
+	}
+	if ConsensusBFT.CommitStatusTimeout() <= ConsensusRaft.CommitStatusTimeout() {
+		t.Fatal("expected BFT to wait longer for commit status than Raft")
+	}
+}
+
+func TestTallyCommitStatuses(t *testing.T) {
+	statuses := []PeerCommitStatus{
+		{Peer: "peer0.org1", Status: &client.Status{BlockNumber: 7, Code: peer.TxValidationCode_VALID}},
+		{Peer: "peer0.org2", Status: &client.Status{BlockNumber: 7, Code: peer.TxValidationCode_VALID}},
+		{Peer: "peer0.org3", Err: errors.New("unavailable")},
+	}
+
+	status, err := tallyCommitStatuses("tx1", 2, statuses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Successful || status.BlockNumber != 7 || status.Confirmations != 2 {
+		t.Fatalf("unexpected quorum status %+v", status)
+	}
+
+	_, err = tallyCommitStatuses("tx1", 3, statuses)
+	var quorumErr *CommitQuorumError
+	if !errors.As(err, &quorumErr) || quorumErr.Required != 3 {
+		t.Fatalf("expected a CommitQuorumError, got %v", err)
+	}
+}
+
+func TestTallyCommitStatusesDisagreement(t *testing.T) {
+	statuses := []PeerCommitStatus{
+		{Peer: "peer0.org1", Status: &client.Status{BlockNumber: 7, Code: peer.TxValidationCode_VALID}},
+		{Peer: "peer0.org2", Status: &client.Status{BlockNumber: 7, Code: peer.TxValidationCode_MVCC_READ_CONFLICT}},
+	}
+
+	if _, err := tallyCommitStatuses("tx1", 2, statuses); err == nil {
+		t.Fatal("expected peers disagreeing on the validation code not to reach a quorum")
+	}
+}
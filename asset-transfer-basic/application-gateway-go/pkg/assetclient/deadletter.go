@@ -0,0 +1,180 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImportColumns are the columns every import file must have, in any order.
+// The msisdn, mpin and remarks columns of the private details are optional.
+var ImportColumns = []string{"id", "dealerid", "balance", "status", "transamount", "transtype"}
+
+// ImportRow is one row of an import file, its values keyed by column name.
+type ImportRow struct {
+	Line   int               `json:"line"`
+	Values map[string]string `json:"values"`
+}
+
+// ReadImportCSV reads the rows of a CSV import file, whose first line names the
+// columns. Column names are matched ignoring case and surrounding spaces. Lines
+// starting with #, such as the notes of an import template, are skipped.
+func ReadImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CSV header: %w", err)
+	}
+	if err := normalizeImportHeader(header, "CSV"); err != nil {
+		return nil, err
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		row := ImportRow{Line: line, Values: make(map[string]string, len(header))}
+		for i, column := range header {
+			row.Values[column] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, row)
+	}
+}
+
+// normalizeImportHeader lowercases the column names of the header of an import
+// file and checks it has the ImportColumns.
+func normalizeImportHeader(header []string, format string) error {
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+	}
+	for _, required := range ImportColumns {
+		if !containsString(header, required) {
+			return fmt.Errorf("the %s header lacks the %s column", format, required)
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadLetter is an import row that could not be imported, with the error of its
+// last attempt. Code is the chaincode error code, if any.
+type DeadLetter struct {
+	Row      ImportRow `json:"row"`
+	Error    string    `json:"error"`
+	Code     string    `json:"code,omitempty"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// NewDeadLetter records the failure of row after attempts submissions.
+func NewDeadLetter(row ImportRow, err error, attempts int) DeadLetter {
+	letter := DeadLetter{Row: row, Error: err.Error(), Attempts: attempts, FailedAt: time.Now().UTC()}
+	var chaincodeErr *ChaincodeError
+	if errors.As(DecodeChaincodeError(err), &chaincodeErr) {
+		letter.Code = chaincodeErr.Code
+	}
+	return letter
+}
+
+// DeadLetterWriter appends dead letters to a file, one JSON object per line.
+// Every letter is synced to disk before Write returns, so the letters written
+// survive a crash of the import. It is safe for concurrent use.
+type DeadLetterWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	written int
+}
+
+// NewDeadLetterWriter opens the dead letter file at path, creating it when
+// missing and appending to it otherwise.
+func NewDeadLetterWriter(path string) (*DeadLetterWriter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the dead letter file: %w", err)
+	}
+	return &DeadLetterWriter{file: file}, nil
+}
+
+// Write appends a dead letter to the file.
+func (w *DeadLetterWriter) Write(letter DeadLetter) error {
+	letterJSON, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(append(letterJSON, '\n')); err != nil {
+		return fmt.Errorf("failed to write the dead letter of line %d: %w", letter.Row.Line, err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync the dead letter file: %w", err)
+	}
+	w.written++
+	return nil
+}
+
+// Written returns the number of dead letters written.
+func (w *DeadLetterWriter) Written() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Close closes the file.
+func (w *DeadLetterWriter) Close() error {
+	return w.file.Close()
+}
+
+// ReadDeadLetters reads the dead letters of a file written by DeadLetterWriter.
+// A truncated last line, left by a crash while writing it, is ignored.
+func ReadDeadLetters(r io.Reader) ([]DeadLetter, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var letters []DeadLetter
+	var pending error
+	for line := 1; scanner.Scan(); line++ {
+		if pending != nil {
+			return nil, pending
+		}
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			pending = fmt.Errorf("invalid dead letter on line %d: %w", line, err)
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
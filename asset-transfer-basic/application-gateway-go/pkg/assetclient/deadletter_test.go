@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadImportCSV(t *testing.T) {
+	rows, err := ReadImportCSV(strings.NewReader("ID, DealerID,balance,status,transamount,transtype,msisdn\nasset1,DEALER101, 100,ACTIVE,100,CREDIT,9877890123\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ImportRow{{Line: 2, Values: map[string]string{
+		"id": "asset1", "dealerid": "DEALER101", "balance": "100", "status": "ACTIVE",
+		"transamount": "100", "transtype": "CREDIT", "msisdn": "9877890123",
+	}}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rows)
+	}
+}
+
+func TestReadImportCSVRequiresColumns(t *testing.T) {
+	if _, err := ReadImportCSV(strings.NewReader("id,balance\nasset1,100\n")); err == nil {
+		t.Fatal("expected an error for missing columns")
+	}
+}
+
+func TestDeadLettersRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failed.jsonl")
+	writer, err := NewDeadLetterWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := ImportRow{Line: 2, Values: map[string]string{"id": "asset1"}}
+	if err := writer.Write(NewDeadLetter(row, errors.New("endorsement failed"), 3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash while writing leaves a truncated last line
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"row":{"line":3,`)
+	file.Close()
+
+	file, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	letters, err := ReadDeadLetters(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || !reflect.DeepEqual(letters[0].Row, row) || letters[0].Attempts != 3 || letters[0].Error != "endorsement failed" {
+		t.Fatalf("unexpected dead letters %+v", letters)
+	}
+}
+
+func TestReadDeadLettersRejectsCorruptLines(t *testing.T) {
+	if _, err := ReadDeadLetters(strings.NewReader("not json\n{\"attempts\":1}\n")); err == nil {
+		t.Fatal("expected an error for a corrupt line")
+	}
+}
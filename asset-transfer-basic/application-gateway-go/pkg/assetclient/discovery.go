@@ -0,0 +1,258 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/discovery"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gossip"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultPlanTTL = 30 * time.Second
+
+// Endorser is a peer able to endorse transactions of a chaincode.
+type Endorser struct {
+	Endpoint     string `json:"endpoint"`
+	MspID        string `json:"mspId"`
+	LedgerHeight uint64 `json:"ledgerHeight"`
+}
+
+// EndorsementPlan is the outcome of a service discovery query for a chaincode.
+// The effective endorsement policy, combining the chaincode policy with the
+// policies of the collections written, is satisfied by any one of its Layouts.
+type EndorsementPlan struct {
+	Chaincode string `json:"chaincode"`
+	// Groups maps each endorser group to its peers.
+	Groups map[string][]Endorser `json:"groups"`
+	// Layouts lists the alternative sets of endorsements, as the number of peers required per group.
+	Layouts []map[string]int `json:"layouts"`
+}
+
+// Organizations returns the MSP IDs of the peers in a layout, sorted.
+func (p *EndorsementPlan) Organizations(layout map[string]int) []string {
+	seen := make(map[string]bool)
+	var mspIDs []string
+	for group := range layout {
+		for _, endorser := range p.Groups[group] {
+			if !seen[endorser.MspID] {
+				seen[endorser.MspID] = true
+				mspIDs = append(mspIDs, endorser.MspID)
+			}
+		}
+	}
+	sort.Strings(mspIDs)
+	return mspIDs
+}
+
+// MinimumOrganizations returns the smallest set of organizations whose peers
+// satisfy the effective endorsement policy, preferring the layout needing
+// fewer endorsements when several involve as many organizations.
+func (p *EndorsementPlan) MinimumOrganizations() []string {
+	var best []string
+	bestEndorsements := 0
+	for _, layout := range p.Layouts {
+		mspIDs := p.Organizations(layout)
+		endorsements := 0
+		for _, quantity := range layout {
+			endorsements += quantity
+		}
+		if best == nil || len(mspIDs) < len(best) || (len(mspIDs) == len(best) && endorsements < bestEndorsements) {
+			best, bestEndorsements = mspIDs, endorsements
+		}
+	}
+	return best
+}
+
+// Policy describes the effective endorsement policy, such as
+// "(1 of Org1MSP AND 1 of Org2MSP) OR (2 of Org3MSP)".
+func (p *EndorsementPlan) Policy() string {
+	layouts := make([]string, 0, len(p.Layouts))
+	for _, layout := range p.Layouts {
+		groups := make([]string, 0, len(layout))
+		for group, quantity := range layout {
+			groups = append(groups, fmt.Sprintf("%d of %s", quantity, strings.Join(p.Organizations(map[string]int{group: quantity}), "|")))
+		}
+		sort.Strings(groups)
+		layouts = append(layouts, "("+strings.Join(groups, " AND ")+")")
+	}
+	return strings.Join(layouts, " OR ")
+}
+
+// Discoverer queries the service discovery of a peer for the endorsers of
+// chaincodes, caching the plans it returns for a short while.
+type Discoverer struct {
+	client discovery.DiscoveryClient
+	id     identity.Identity
+	sign   identity.Sign
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	plans map[string]cachedPlan
+}
+
+type cachedPlan struct {
+	plan    *EndorsementPlan
+	expires time.Time
+}
+
+// NewDiscoverer creates a Discoverer sending requests signed by the client
+// identity over conn, typically the connection to the Gateway peer.
+func NewDiscoverer(conn grpc.ClientConnInterface, id identity.Identity, sign identity.Sign) *Discoverer {
+	return &Discoverer{
+		client: discovery.NewDiscoveryClient(conn),
+		id:     id,
+		sign:   sign,
+		ttl:    defaultPlanTTL,
+		plans:  make(map[string]cachedPlan),
+	}
+}
+
+// Endorsers returns the endorsement plan of a chaincode on a channel. The
+// collections the transaction writes restrict the endorsers to their members.
+func (d *Discoverer) Endorsers(ctx context.Context, channel string, chaincode string, collections ...string) (*EndorsementPlan, error) {
+	cacheKey := strings.Join(append([]string{channel, chaincode}, collections...), "\x00")
+	d.mu.Lock()
+	cached, ok := d.plans[cacheKey]
+	d.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.plan, nil
+	}
+
+	plan, err := d.discover(ctx, channel, chaincode, collections)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.plans[cacheKey] = cachedPlan{plan: plan, expires: time.Now().Add(d.ttl)}
+	d.mu.Unlock()
+	return plan, nil
+}
+
+// EndorsingOrganizations returns the minimum set of organizations to endorse a
+// transaction writing to the given private data collections.
+func (d *Discoverer) EndorsingOrganizations(ctx context.Context, channel string, chaincode string, collections ...string) ([]string, error) {
+	plan, err := d.Endorsers(ctx, channel, chaincode, collections...)
+	if err != nil {
+		return nil, err
+	}
+
+	mspIDs := plan.MinimumOrganizations()
+	if len(mspIDs) == 0 {
+		return nil, fmt.Errorf("no organizations can endorse chaincode %s on channel %s", chaincode, channel)
+	}
+	return mspIDs, nil
+}
+
+func (d *Discoverer) discover(ctx context.Context, channel string, chaincode string, collections []string) (*EndorsementPlan, error) {
+	clientIdentity, err := proto.Marshal(&msp.SerializedIdentity{Mspid: d.id.MspID(), IdBytes: d.id.Credentials()})
+	if err != nil {
+		return nil, err
+	}
+
+	request := &discovery.Request{
+		Authentication: &discovery.AuthInfo{ClientIdentity: clientIdentity},
+		Queries: []*discovery.Query{{
+			Channel: channel,
+			Query: &discovery.Query_CcQuery{CcQuery: &discovery.ChaincodeQuery{
+				Interests: []*peer.ChaincodeInterest{{
+					Chaincodes: []*peer.ChaincodeCall{{Name: chaincode, CollectionNames: collections}},
+				}},
+			}},
+		}},
+	}
+	payload, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := d.sign(hash.SHA256(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign discovery request: %w", err)
+	}
+
+	response, err := d.client.Discover(ctx, &discovery.SignedRequest{Payload: payload, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service discovery: %w", err)
+	}
+	if len(response.GetResults()) == 0 {
+		return nil, errors.New("service discovery returned no results")
+	}
+
+	result := response.GetResults()[0]
+	if discoveryErr := result.GetError(); discoveryErr != nil {
+		return nil, fmt.Errorf("service discovery failed for chaincode %s: %s", chaincode, discoveryErr.GetContent())
+	}
+	descriptors := result.GetCcQueryRes().GetContent()
+	if len(descriptors) == 0 {
+		return nil, fmt.Errorf("service discovery returned no endorsers for chaincode %s", chaincode)
+	}
+
+	return newEndorsementPlan(descriptors[0])
+}
+
+// newEndorsementPlan converts the endorsement descriptor returned by service discovery.
+func newEndorsementPlan(descriptor *discovery.EndorsementDescriptor) (*EndorsementPlan, error) {
+	plan := &EndorsementPlan{
+		Chaincode: descriptor.GetChaincode(),
+		Groups:    make(map[string][]Endorser),
+	}
+
+	for group, peers := range descriptor.GetEndorsersByGroups() {
+		for _, discovered := range peers.GetPeers() {
+			endorser, err := newEndorser(discovered)
+			if err != nil {
+				return nil, err
+			}
+			plan.Groups[group] = append(plan.Groups[group], endorser)
+		}
+	}
+
+	for _, layout := range descriptor.GetLayouts() {
+		quantities := make(map[string]int)
+		for group, quantity := range layout.GetQuantitiesByGroup() {
+			quantities[group] = int(quantity)
+		}
+		plan.Layouts = append(plan.Layouts, quantities)
+	}
+
+	return plan, nil
+}
+
+func newEndorser(discovered *discovery.Peer) (Endorser, error) {
+	var serializedIdentity msp.SerializedIdentity
+	if err := proto.Unmarshal(discovered.GetIdentity(), &serializedIdentity); err != nil {
+		return Endorser{}, fmt.Errorf("failed to parse peer identity: %w", err)
+	}
+	endorser := Endorser{MspID: serializedIdentity.GetMspid()}
+
+	var membership gossip.GossipMessage
+	if err := proto.Unmarshal(discovered.GetMembershipInfo().GetPayload(), &membership); err != nil {
+		return Endorser{}, fmt.Errorf("failed to parse peer membership: %w", err)
+	}
+	endorser.Endpoint = membership.GetAliveMsg().GetMembership().GetEndpoint()
+
+	if discovered.GetStateInfo() != nil {
+		var stateInfo gossip.GossipMessage
+		if err := proto.Unmarshal(discovered.GetStateInfo().GetPayload(), &stateInfo); err != nil {
+			return Endorser{}, fmt.Errorf("failed to parse peer state: %w", err)
+		}
+		endorser.LedgerHeight = stateInfo.GetStateInfo().GetProperties().GetLedgerHeight()
+	}
+
+	return endorser, nil
+}
@@ -0,0 +1,50 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestPlan() *EndorsementPlan {
+	return &EndorsementPlan{
+		Chaincode: "financial",
+		Groups: map[string][]Endorser{
+			"G0": {{Endpoint: "peer0.org1.example.com:7051", MspID: "Org1MSP"}},
+			"G1": {{Endpoint: "peer0.org2.example.com:9051", MspID: "Org2MSP"}},
+			"G2": {{Endpoint: "peer0.org3.example.com:11051", MspID: "Org3MSP"}, {Endpoint: "peer1.org3.example.com:12051", MspID: "Org3MSP"}},
+		},
+		Layouts: []map[string]int{
+			{"G0": 1, "G1": 1},
+			{"G2": 2},
+			{"G2": 1},
+		},
+	}
+}
+
+func TestEndorsementPlanMinimumOrganizations(t *testing.T) {
+	plan := newTestPlan()
+	if mspIDs := plan.MinimumOrganizations(); !reflect.DeepEqual(mspIDs, []string{"Org3MSP"}) {
+		t.Fatalf("expected [Org3MSP], got %v", mspIDs)
+	}
+
+	plan.Layouts = plan.Layouts[:1]
+	if mspIDs := plan.MinimumOrganizations(); !reflect.DeepEqual(mspIDs, []string{"Org1MSP", "Org2MSP"}) {
+		t.Fatalf("expected [Org1MSP Org2MSP], got %v", mspIDs)
+	}
+
+	plan.Layouts = nil
+	if mspIDs := plan.MinimumOrganizations(); mspIDs != nil {
+		t.Fatalf("expected no organizations, got %v", mspIDs)
+	}
+}
+
+func TestEndorsementPlanPolicy(t *testing.T) {
+	expected := "(1 of Org1MSP AND 1 of Org2MSP) OR (2 of Org3MSP) OR (1 of Org3MSP)"
+	if policy := newTestPlan().Policy(); policy != expected {
+		t.Fatalf("expected %q, got %q", expected, policy)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/status"
+)
+
+// Domain errors returned by the chaincode, matched with errors.Is. Every one
+// but ErrLedgerUnavailable is a rejection that fails again when retried.
+var (
+	ErrAssetExists       = errors.New("asset already exists")
+	ErrAssetNotFound     = errors.New("asset not found")
+	ErrForbidden         = errors.New("forbidden")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrInvalidArgument   = errors.New("invalid argument")
+	ErrMaintenance       = errors.New("maintenance")
+	ErrSystemPaused      = errors.New("system paused")
+	ErrVersionConflict   = errors.New("version conflict")
+	// ErrLedgerUnavailable is an infrastructure failure of a peer accessing its ledger.
+	ErrLedgerUnavailable = errors.New("ledger unavailable")
+	// ErrLedgerRejected is a ledger access the peer refused, such as of an invalid key.
+	ErrLedgerRejected = errors.New("ledger rejected")
+)
+
+// domainErrors maps the chaincode error codes to their domain error.
+var domainErrors = map[string]error{
+	"ASSET_EXISTS":       ErrAssetExists,
+	"ASSET_NOT_FOUND":    ErrAssetNotFound,
+	"FORBIDDEN":          ErrForbidden,
+	"INSUFFICIENT_FUNDS": ErrInsufficientFunds,
+	"INVALID_ARGUMENT":   ErrInvalidArgument,
+	"MAINTENANCE":        ErrMaintenance,
+	"SYSTEM_PAUSED":      ErrSystemPaused,
+	"VERSION_CONFLICT":   ErrVersionConflict,
+	"LEDGER_UNAVAILABLE": ErrLedgerUnavailable,
+	"LEDGER_REJECTED":    ErrLedgerRejected,
+}
+
+// ChaincodeError is a domain error returned by the chaincode as a JSON object
+// with a code and a message. It matches the domain error of its code with
+// errors.Is and unwraps to the Gateway error it was decoded from. Retryable is
+// set for infrastructure failures, and unset for business rejections.
+type ChaincodeError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+
+	err error
+}
+
+// DecodeChaincodeError returns the ChaincodeError carried by a failed Gateway
+// call, or err unchanged when the chaincode did not return a domain error.
+func DecodeChaincodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var chaincodeErr *ChaincodeError
+	if errors.As(err, &chaincodeErr) {
+		return err
+	}
+
+	grpcStatus := status.Convert(err)
+	messages := []string{grpcStatus.Message()}
+	for _, detail := range grpcStatus.Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok {
+			messages = append(messages, detail.GetMessage())
+		}
+	}
+	for _, message := range messages {
+		if chaincodeErr := parseChaincodeError(message); chaincodeErr != nil {
+			chaincodeErr.err = err
+			return chaincodeErr
+		}
+	}
+	return err
+}
+
+// parseChaincodeError decodes the JSON error object embedded in a peer message
+// such as "chaincode response 500, {"code":...,"message":...}".
+func parseChaincodeError(message string) *ChaincodeError {
+	start := strings.Index(message, `{"code"`)
+	if start < 0 {
+		return nil
+	}
+	var chaincodeErr ChaincodeError
+	if err := json.NewDecoder(strings.NewReader(message[start:])).Decode(&chaincodeErr); err != nil || chaincodeErr.Code == "" {
+		return nil
+	}
+	return &chaincodeErr
+}
+
+// Error returns the message of the chaincode.
+func (e *ChaincodeError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is the domain error of the error code.
+func (e *ChaincodeError) Is(target error) bool {
+	domainErr, ok := domainErrors[e.Code]
+	return ok && domainErr == target
+}
+
+// Unwrap returns the Gateway error.
+func (e *ChaincodeError) Unwrap() error {
+	return e.err
+}
+
+// HTTPStatus returns the HTTP status code matching a domain error, or
+// http.StatusInternalServerError for any other error.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAssetNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAssetExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrSystemPaused), errors.Is(err, ErrMaintenance), errors.Is(err, ErrLedgerUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,53 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDecodeChaincodeError(t *testing.T) {
+	gatewayErr := status.Error(codes.Unknown, `evaluate call to endorser returned error: chaincode response 500, {"code":"ASSET_NOT_FOUND","message":"the asset asset9 does not exist"}`)
+
+	err := DecodeChaincodeError(fmt.Errorf("failed to read asset: %w", gatewayErr))
+	var chaincodeErr *ChaincodeError
+	if !errors.As(err, &chaincodeErr) {
+		t.Fatalf("expected a ChaincodeError, got %T", err)
+	}
+	if chaincodeErr.Code != "ASSET_NOT_FOUND" || chaincodeErr.Message != "the asset asset9 does not exist" {
+		t.Fatalf("unexpected chaincode error %+v", chaincodeErr)
+	}
+	if !errors.Is(err, ErrAssetNotFound) || errors.Is(err, ErrInsufficientFunds) {
+		t.Fatal("expected the error to match ErrAssetNotFound only")
+	}
+	if status.Code(errors.Unwrap(chaincodeErr)) != codes.Unknown {
+		t.Fatal("expected the ChaincodeError to unwrap to the Gateway error")
+	}
+	if code := HTTPStatus(err); code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, code)
+	}
+
+	multiErr := NewMultiPeerError(gatewayErr)
+	if multiErr.Code != "ASSET_NOT_FOUND" || !errors.Is(multiErr, ErrAssetNotFound) {
+		t.Fatalf("expected the MultiPeerError to carry the domain error, got %+v", multiErr)
+	}
+}
+
+func TestDecodeChaincodeErrorWithoutCode(t *testing.T) {
+	gatewayErr := status.Error(codes.Unknown, "chaincode response 500, failed to read from world state")
+
+	if err := DecodeChaincodeError(gatewayErr); err != gatewayErr {
+		t.Fatalf("expected the Gateway error unchanged, got %v", err)
+	}
+	if code := HTTPStatus(gatewayErr); code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, code)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/status"
+)
+
+// PeerError is the error reported by one peer or orderer node behind the Gateway.
+type PeerError struct {
+	Address string `json:"address"`
+	MspID   string `json:"mspId"`
+	Message string `json:"message"`
+}
+
+// MultiPeerError describes a failed Gateway call together with the errors reported
+// by each endorsing peer or orderer involved, so callers can tell which
+// organization rejected a transaction.
+type MultiPeerError struct {
+	// Stage is the step of the transaction flow that failed: endorse, submit, commit-status, commit or evaluate.
+	Stage         string      `json:"stage"`
+	TransactionID string      `json:"transactionId,omitempty"`
+	GRPCCode      string      `json:"grpcCode"`
+	Message       string      `json:"error"`
+	Peers         []PeerError `json:"peers"`
+	// Code is the error code of a domain error returned by the chaincode.
+	Code string `json:"code,omitempty"`
+	// Retryable tells infrastructure errors, which may succeed when retried,
+	// from business rejections. See IsRetryable.
+	Retryable bool `json:"retryable"`
+
+	err error
+}
+
+// NewMultiPeerError collects the Gateway error details embedded in err, decoding
+// the domain error returned by the chaincode, if any, so that the result matches
+// it with errors.Is and errors.As. It returns nil for a nil error and err itself
+// when it already is a MultiPeerError.
+func NewMultiPeerError(err error) *MultiPeerError {
+	if err == nil {
+		return nil
+	}
+
+	var multiErr *MultiPeerError
+	if errors.As(err, &multiErr) {
+		return multiErr
+	}
+
+	grpcStatus := status.Convert(err)
+	multiErr = &MultiPeerError{
+		Stage:     "evaluate",
+		GRPCCode:  grpcStatus.Code().String(),
+		Message:   grpcStatus.Message(),
+		Peers:     []PeerError{},
+		Retryable: IsRetryable(err),
+		err:       DecodeChaincodeError(err),
+	}
+
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	var commitErr *client.CommitError
+	switch {
+	case errors.As(err, &endorseErr):
+		multiErr.Stage, multiErr.TransactionID = "endorse", endorseErr.TransactionID
+	case errors.As(err, &submitErr):
+		multiErr.Stage, multiErr.TransactionID = "submit", submitErr.TransactionID
+	case errors.As(err, &commitStatusErr):
+		multiErr.Stage, multiErr.TransactionID = "commit-status", commitStatusErr.TransactionID
+	case errors.As(err, &commitErr):
+		multiErr.Stage, multiErr.TransactionID = "commit", commitErr.TransactionID
+		multiErr.Message = err.Error()
+	}
+
+	var chaincodeErr *ChaincodeError
+	if errors.As(multiErr.err, &chaincodeErr) {
+		multiErr.Code = chaincodeErr.Code
+	}
+
+	for _, detail := range grpcStatus.Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok {
+			multiErr.Peers = append(multiErr.Peers, PeerError{
+				Address: detail.GetAddress(),
+				MspID:   detail.GetMspId(),
+				Message: detail.GetMessage(),
+			})
+		}
+	}
+
+	return multiErr
+}
+
+// Error returns the Gateway message followed by the message of every peer.
+func (e *MultiPeerError) Error() string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "%s failed", e.Stage)
+	if e.TransactionID != "" {
+		fmt.Fprintf(&message, " for transaction %s", e.TransactionID)
+	}
+	fmt.Fprintf(&message, " (%s): %s", e.GRPCCode, e.Message)
+	for _, peer := range e.Peers {
+		fmt.Fprintf(&message, "; %s (%s): %s", peer.Address, peer.MspID, peer.Message)
+	}
+	return message.String()
+}
+
+// Unwrap returns the original Gateway error, or the ChaincodeError decoded from it.
+func (e *MultiPeerError) Unwrap() error {
+	return e.err
+}
+
+// MSPIDs returns the distinct MSP IDs of the peers that reported an error.
+func (e *MultiPeerError) MSPIDs() []string {
+	seen := make(map[string]bool)
+	var mspIDs []string
+	for _, peer := range e.Peers {
+		if !seen[peer.MspID] {
+			seen[peer.MspID] = true
+			mspIDs = append(mspIDs, peer.MspID)
+		}
+	}
+	return mspIDs
+}
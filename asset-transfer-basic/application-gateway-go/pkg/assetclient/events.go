@@ -0,0 +1,155 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// ErrEventOutOfOrder reports an event that was not applied yet but precedes an
+// event that was. Applying it would apply credits out of ledger order.
+var ErrEventOutOfOrder = errors.New("event out of ledger order")
+
+// EventPosition is the position of a chaincode event in the ledger. Fabric
+// emits at most one chaincode event per transaction, so EventIndex is zero for
+// events received from the Gateway.
+type EventPosition struct {
+	BlockNumber      uint64
+	TransactionIndex int
+	TransactionID    string
+	EventIndex       int
+}
+
+// before reports whether p precedes other in ledger order.
+func (p EventPosition) before(other EventPosition) bool {
+	if p.BlockNumber != other.BlockNumber {
+		return p.BlockNumber < other.BlockNumber
+	}
+	if p.TransactionIndex != other.TransactionIndex {
+		return p.TransactionIndex < other.TransactionIndex
+	}
+	return p.EventIndex < other.EventIndex
+}
+
+func (p EventPosition) String() string {
+	return fmt.Sprintf("block %d transaction %d (%s) event %d", p.BlockNumber, p.TransactionIndex, p.TransactionID, p.EventIndex)
+}
+
+// eventKey identifies an event in the deduplication window.
+type eventKey struct {
+	blockNumber   uint64
+	transactionID string
+	eventIndex    int
+}
+
+// EventGuard lets downstream consumers apply each chaincode event at most once
+// and in ledger order. Events at or before the last applied one are
+// duplicates, such as those replayed after a checkpoint is restored, while the
+// window of recently applied events tells them apart from events that were
+// skipped and arrive late, which are refused with ErrEventOutOfOrder.
+type EventGuard struct {
+	lock    sync.Mutex
+	window  int
+	seen    map[eventKey]struct{}
+	recent  []EventPosition
+	last    EventPosition
+	applied bool
+}
+
+// NewEventGuard creates a guard remembering the last window applied events. A
+// consumer restarting from its own store passes the position of the last event
+// it applied as last, or nil when it has applied none.
+func NewEventGuard(window int, last *EventPosition) *EventGuard {
+	if window <= 0 {
+		window = 1
+	}
+	guard := &EventGuard{window: window, seen: make(map[eventKey]struct{}, window)}
+	if last != nil {
+		guard.last, guard.applied = *last, true
+	}
+	return guard
+}
+
+// Accept reports whether the event at position should be applied, recording it
+// as applied if so. Duplicates are not accepted, and an event preceding the
+// last applied one without being a known duplicate fails with ErrEventOutOfOrder.
+func (g *EventGuard) Accept(position EventPosition) (bool, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	key := eventKey{position.BlockNumber, position.TransactionID, position.EventIndex}
+	if _, ok := g.seen[key]; ok {
+		return false, nil
+	}
+	if g.applied && !g.last.before(position) {
+		if len(g.recent) == 0 || position.before(g.recent[0]) {
+			// older than the window, so applied before the window was filled
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %s precedes the last applied %s", ErrEventOutOfOrder, position, g.last)
+	}
+
+	g.seen[key] = struct{}{}
+	g.recent = append(g.recent, position)
+	if len(g.recent) > g.window {
+		oldest := g.recent[0]
+		delete(g.seen, eventKey{oldest.BlockNumber, oldest.TransactionID, oldest.EventIndex})
+		g.recent = g.recent[1:]
+	}
+	g.last, g.applied = position, true
+	return true, nil
+}
+
+// Last returns the position of the last applied event, for the consumer to
+// store alongside the effects of applying it.
+func (g *EventGuard) Last() (EventPosition, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.last, g.applied
+}
+
+// ApplyChaincodeEvents calls apply for each event read from events, in ledger
+// order and at most once per event as decided by guard, until events is closed
+// or ctx is done. The Gateway delivers the events of a block in transaction
+// order, so events are numbered within their block as they arrive; replays
+// must start at a block boundary, such as with client.WithStartBlock, to number
+// them the same way. ApplyChaincodeEvents returns the first error of apply,
+// after which the consumer restarts from the position it stored last.
+func ApplyChaincodeEvents(ctx context.Context, events <-chan *client.ChaincodeEvent, guard *EventGuard, apply func(ctx context.Context, event *client.ChaincodeEvent) error) error {
+	var blockNumber uint64
+	transactionIndex := -1
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if event.BlockNumber != blockNumber || transactionIndex < 0 {
+				blockNumber, transactionIndex = event.BlockNumber, 0
+			} else {
+				transactionIndex++
+			}
+
+			position := EventPosition{BlockNumber: event.BlockNumber, TransactionIndex: transactionIndex, TransactionID: event.TransactionID}
+			accepted, err := guard.Accept(position)
+			if err != nil {
+				return err
+			}
+			if !accepted {
+				continue
+			}
+			if err := apply(ctx, event); err != nil {
+				return fmt.Errorf("failed to apply event %s at %s: %w", event.EventName, position, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+func TestEventGuardSkipsReplayedEvents(t *testing.T) {
+	guard := NewEventGuard(2, nil)
+	positions := []EventPosition{
+		{BlockNumber: 5, TransactionIndex: 0, TransactionID: "tx1"},
+		{BlockNumber: 5, TransactionIndex: 1, TransactionID: "tx2"},
+		{BlockNumber: 6, TransactionIndex: 0, TransactionID: "tx3"},
+	}
+	for _, position := range positions {
+		if accepted, err := guard.Accept(position); !accepted || err != nil {
+			t.Fatalf("expected %s to be accepted, got %v, %v", position, accepted, err)
+		}
+	}
+
+	// replayed after restoring the checkpoint of block 5, tx1 being older than the window
+	for _, position := range positions {
+		if accepted, err := guard.Accept(position); accepted || err != nil {
+			t.Fatalf("expected replayed %s to be skipped, got %v, %v", position, accepted, err)
+		}
+	}
+
+	if last, _ := guard.Last(); last.TransactionID != "tx3" {
+		t.Fatalf("expected the last applied event to be tx3, got %s", last)
+	}
+}
+
+func TestEventGuardRefusesEventsOutOfOrder(t *testing.T) {
+	guard := NewEventGuard(10, nil)
+	guard.Accept(EventPosition{BlockNumber: 5, TransactionIndex: 0, TransactionID: "tx1"})
+	guard.Accept(EventPosition{BlockNumber: 5, TransactionIndex: 2, TransactionID: "tx3"})
+
+	_, err := guard.Accept(EventPosition{BlockNumber: 5, TransactionIndex: 1, TransactionID: "tx2"})
+	if !errors.Is(err, ErrEventOutOfOrder) {
+		t.Fatalf("expected ErrEventOutOfOrder, got %v", err)
+	}
+}
+
+func TestEventGuardResumesFromStoredPosition(t *testing.T) {
+	guard := NewEventGuard(10, &EventPosition{BlockNumber: 7, TransactionIndex: 1, TransactionID: "tx8"})
+
+	if accepted, _ := guard.Accept(EventPosition{BlockNumber: 7, TransactionIndex: 0, TransactionID: "tx7"}); accepted {
+		t.Fatal("expected an event before the stored position to be skipped")
+	}
+	if accepted, _ := guard.Accept(EventPosition{BlockNumber: 8, TransactionIndex: 0, TransactionID: "tx9"}); !accepted {
+		t.Fatal("expected an event after the stored position to be accepted")
+	}
+}
+
+func TestApplyChaincodeEvents(t *testing.T) {
+	events := make(chan *client.ChaincodeEvent, 6)
+	for _, event := range []*client.ChaincodeEvent{
+		{BlockNumber: 3, TransactionID: "tx1", EventName: "Credit"},
+		{BlockNumber: 3, TransactionID: "tx2", EventName: "Credit"},
+		{BlockNumber: 4, TransactionID: "tx3", EventName: "Credit"},
+		// replayed from the start of block 3
+		{BlockNumber: 3, TransactionID: "tx1", EventName: "Credit"},
+		{BlockNumber: 3, TransactionID: "tx2", EventName: "Credit"},
+		{BlockNumber: 4, TransactionID: "tx3", EventName: "Credit"},
+	} {
+		events <- event
+	}
+	close(events)
+
+	var applied []string
+	err := ApplyChaincodeEvents(context.Background(), events, NewEventGuard(100, nil), func(_ context.Context, event *client.ChaincodeEvent) error {
+		applied = append(applied, event.TransactionID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(applied) != 3 || applied[0] != "tx1" || applied[1] != "tx2" || applied[2] != "tx3" {
+		t.Fatalf("expected each event applied once in order, got %v", applied)
+	}
+}
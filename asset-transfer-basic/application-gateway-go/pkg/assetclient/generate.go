@@ -0,0 +1,178 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// GeneratorConfig configures the synthetic assets created by GenerateAssets for
+// performance environments.
+type GeneratorConfig struct {
+	// Count is the number of assets generated.
+	Count int
+	// Dealers is the number of dealers the assets are spread over, named
+	// DealerPrefix followed by a number.
+	Dealers      int
+	DealerPrefix string
+	// MSISDNFirst and MSISDNLast bound the range of the generated MSISDNs.
+	MSISDNFirst int64
+	MSISDNLast  int64
+	// Balances is the distribution of the opening balances.
+	Balances Distribution
+	// DuplicateRate is the fraction of assets reusing the MSISDN of an earlier
+	// asset, as customers holding several wallets do.
+	DuplicateRate float64
+	// InactiveRate is the fraction of assets generated INACTIVE.
+	InactiveRate float64
+	// Seed makes the generated data reproducible.
+	Seed int64
+}
+
+// GeneratedAsset is a synthetic asset with the private details passed to
+// CreateAsset. Its ID is assigned when it is loaded.
+type GeneratedAsset struct {
+	DealerID    string  `json:"dealerId"`
+	Balance     float64 `json:"balance"`
+	Status      string  `json:"status"`
+	TransAmount float64 `json:"transAmount"`
+	TransType   string  `json:"transType"`
+	MSISDN      string  `json:"msisdn"`
+	MPIN        string  `json:"mpin"`
+	Remarks     string  `json:"remarks"`
+}
+
+// generatedRemarks are the remarks of generated assets, by transaction type.
+var generatedRemarks = map[string][]string{
+	"CREDIT": {"Salary credit", "Personal loan disbursement", "Business investment deposit", "Cash deposit at agent"},
+	"DEBIT":  {"Purchase transaction", "Electricity bill payment", "Airtime top-up", "Cash withdrawal at agent"},
+	"INIT":   {"New account creation"},
+}
+
+// GenerateAssets returns Count synthetic assets. The same configuration always
+// generates the same assets.
+func GenerateAssets(config GeneratorConfig) ([]GeneratedAsset, error) {
+	if config.Count < 0 || config.Dealers <= 0 {
+		return nil, fmt.Errorf("expected a non-negative count and at least one dealer")
+	}
+	if config.MSISDNLast < config.MSISDNFirst {
+		return nil, fmt.Errorf("the MSISDN range %d-%d is empty", config.MSISDNFirst, config.MSISDNLast)
+	}
+	if config.DuplicateRate < 0 || config.DuplicateRate > 1 || config.InactiveRate < 0 || config.InactiveRate > 1 {
+		return nil, fmt.Errorf("rates must be between 0 and 1")
+	}
+
+	random := rand.New(rand.NewSource(config.Seed))
+	msisdns := newMSISDNPicker(config.MSISDNFirst, config.MSISDNLast, random)
+	assets := make([]GeneratedAsset, 0, config.Count)
+	for i := 0; i < config.Count; i++ {
+		asset := GeneratedAsset{
+			DealerID: fmt.Sprintf("%s%d", config.DealerPrefix, 101+random.Intn(config.Dealers)),
+			Balance:  math.Round(config.Balances.Sample(random)*100) / 100,
+			Status:   "ACTIVE",
+			MPIN:     fmt.Sprintf("%04d", random.Intn(10000)),
+		}
+
+		if i > 0 && random.Float64() < config.DuplicateRate {
+			asset.MSISDN = assets[random.Intn(len(assets))].MSISDN
+		} else if asset.MSISDN, _ = msisdns.next(); asset.MSISDN == "" {
+			return nil, fmt.Errorf("the MSISDN range %d-%d holds less than %d numbers", config.MSISDNFirst, config.MSISDNLast, config.Count)
+		}
+
+		switch {
+		case random.Float64() < config.InactiveRate:
+			asset.Status, asset.TransType = "INACTIVE", "SUSPEND"
+			asset.Balance = 0
+		case asset.Balance == 0:
+			asset.TransType = "INIT"
+		case random.Intn(3) == 0:
+			asset.TransType = "DEBIT"
+			asset.TransAmount = math.Round(asset.Balance*random.Float64()*50) / 100
+		default:
+			asset.TransType = "CREDIT"
+			asset.TransAmount = asset.Balance
+		}
+		if remarks := generatedRemarks[asset.TransType]; len(remarks) > 0 {
+			asset.Remarks = remarks[random.Intn(len(remarks))]
+		} else {
+			asset.Remarks = "Account dormant"
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
+
+// msisdnPicker draws distinct MSISDNs from a range.
+type msisdnPicker struct {
+	first, size int64
+	used        map[int64]bool
+	random      *rand.Rand
+}
+
+func newMSISDNPicker(first, last int64, random *rand.Rand) *msisdnPicker {
+	return &msisdnPicker{first: first, size: last - first + 1, used: make(map[int64]bool), random: random}
+}
+
+// next returns an MSISDN not returned before, or false when the range is used up.
+func (p *msisdnPicker) next() (string, bool) {
+	if int64(len(p.used)) >= p.size {
+		return "", false
+	}
+	for {
+		n := p.first + p.random.Int63n(p.size)
+		if !p.used[n] {
+			p.used[n] = true
+			return strconv.FormatInt(n, 10), true
+		}
+	}
+}
+
+// Distribution is a distribution of amounts, parsed by ParseDistribution.
+type Distribution struct {
+	kind string
+	a, b float64
+}
+
+// ParseDistribution parses a distribution of amounts: fixed:<amount>,
+// uniform:<min>:<max>, or lognormal:<median>:<sigma> for the long tail of
+// real wallet balances.
+func ParseDistribution(spec string) (Distribution, error) {
+	parts := strings.Split(spec, ":")
+	params := make([]float64, len(parts)-1)
+	for i, part := range parts[1:] {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value < 0 {
+			return Distribution{}, fmt.Errorf("invalid parameter %q of distribution %q", part, spec)
+		}
+		params[i] = value
+	}
+
+	switch {
+	case parts[0] == "fixed" && len(params) == 1:
+		return Distribution{kind: "fixed", a: params[0]}, nil
+	case parts[0] == "uniform" && len(params) == 2 && params[0] <= params[1]:
+		return Distribution{kind: "uniform", a: params[0], b: params[1]}, nil
+	case parts[0] == "lognormal" && len(params) == 2 && params[0] > 0:
+		return Distribution{kind: "lognormal", a: params[0], b: params[1]}, nil
+	}
+	return Distribution{}, fmt.Errorf("unknown distribution %q, expected fixed:<amount>, uniform:<min>:<max> or lognormal:<median>:<sigma>", spec)
+}
+
+// Sample draws an amount from the distribution.
+func (d Distribution) Sample(random *rand.Rand) float64 {
+	switch d.kind {
+	case "fixed":
+		return d.a
+	case "uniform":
+		return d.a + random.Float64()*(d.b-d.a)
+	case "lognormal":
+		return d.a * math.Exp(d.b*random.NormFloat64())
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testGeneratorConfig(t *testing.T) GeneratorConfig {
+	balances, err := ParseDistribution("uniform:100:5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return GeneratorConfig{
+		Count:         200,
+		Dealers:       5,
+		DealerPrefix:  "DEALER",
+		MSISDNFirst:   9800000000,
+		MSISDNLast:    9800099999,
+		Balances:      balances,
+		DuplicateRate: 0.1,
+		InactiveRate:  0.05,
+		Seed:          42,
+	}
+}
+
+func TestGenerateAssetsIsReproducible(t *testing.T) {
+	config := testGeneratorConfig(t)
+	first, err := GenerateAssets(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateAssets(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("expected the same assets for the same seed")
+	}
+}
+
+func TestGenerateAssetsFollowsConfig(t *testing.T) {
+	config := testGeneratorConfig(t)
+	assets, err := GenerateAssets(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != config.Count {
+		t.Fatalf("expected %d assets, got %d", config.Count, len(assets))
+	}
+
+	msisdns := make(map[string]bool)
+	duplicates := 0
+	for _, asset := range assets {
+		if asset.MSISDN < "9800000000" || asset.MSISDN > "9800099999" {
+			t.Fatalf("MSISDN %s out of range", asset.MSISDN)
+		}
+		if msisdns[asset.MSISDN] {
+			duplicates++
+		}
+		msisdns[asset.MSISDN] = true
+
+		if asset.Status == "ACTIVE" && (asset.Balance < 100 || asset.Balance > 5000) {
+			t.Fatalf("balance %.2f out of the distribution", asset.Balance)
+		}
+		if asset.DealerID < "DEALER101" || asset.DealerID > "DEALER105" {
+			t.Fatalf("unexpected dealer %s", asset.DealerID)
+		}
+	}
+	if duplicates == 0 || duplicates > config.Count/4 {
+		t.Fatalf("expected about %.0f%% duplicate MSISDNs, got %d of %d", config.DuplicateRate*100, duplicates, config.Count)
+	}
+}
+
+func TestGenerateAssetsRejectsSmallMSISDNRange(t *testing.T) {
+	config := testGeneratorConfig(t)
+	config.MSISDNLast = config.MSISDNFirst + 9
+	config.DuplicateRate = 0
+	if _, err := GenerateAssets(config); err == nil {
+		t.Fatal("expected an error for a range smaller than the count")
+	}
+}
+
+func TestParseDistribution(t *testing.T) {
+	for _, spec := range []string{"fixed:500", "uniform:0:1000", "lognormal:1500:1.2"} {
+		if _, err := ParseDistribution(spec); err != nil {
+			t.Errorf("%s: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "fixed", "uniform:10:1", "lognormal:0:1", "normal:1:2", "fixed:-5"} {
+		if _, err := ParseDistribution(spec); err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
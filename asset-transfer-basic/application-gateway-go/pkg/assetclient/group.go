@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Group runs operations concurrently with bounded parallelism. It works like
+// errgroup.Group with SetLimit: the first failure cancels the context of the
+// group so operations in flight stop early. Unlike errgroup, Wait returns the
+// errors of every failed operation, and a panicking operation fails with an
+// error instead of crashing the process.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	lock sync.Mutex
+	errs []error
+}
+
+// NewGroup creates a group running at most limit operations at a time, or any
+// number when limit is zero or less. Operations run with the returned context,
+// derived from ctx and cancelled when an operation fails or Wait returns.
+func NewGroup(ctx context.Context, limit int) (*Group, context.Context) {
+	g := &Group{}
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	if limit > 0 {
+		g.slots = make(chan struct{}, limit)
+	}
+	return g, g.ctx
+}
+
+// Go runs the named operation once a slot is free. Operations waiting for a
+// slot when the group's context is cancelled are not run.
+func (g *Group) Go(name string, operation func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		if g.slots != nil {
+			select {
+			case g.slots <- struct{}{}:
+				defer func() { <-g.slots }()
+			case <-g.ctx.Done():
+				g.fail(name, fmt.Errorf("not run: %w", context.Cause(g.ctx)))
+				return
+			}
+		}
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				g.fail(name, fmt.Errorf("panic: %v", recovered))
+			}
+		}()
+		if err := operation(g.ctx); err != nil {
+			g.fail(name, err)
+		}
+	}()
+}
+
+// Wait waits for every operation and returns their errors joined, or nil when
+// all of them succeeded.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return errors.Join(g.errs...)
+}
+
+func (g *Group) fail(name string, err error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.errs = append(g.errs, fmt.Errorf("%s: %w", name, err))
+	g.cancel()
+}
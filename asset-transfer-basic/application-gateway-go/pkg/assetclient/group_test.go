@@ -0,0 +1,73 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupBoundsParallelism(t *testing.T) {
+	group, _ := NewGroup(context.Background(), 2)
+
+	var running, maxRunning atomic.Int32
+	for i := 0; i < 6; i++ {
+		group.Go("operation", func(context.Context) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if maxRunning.Load() > 2 {
+		t.Fatalf("expected at most 2 operations at a time, got %d", maxRunning.Load())
+	}
+}
+
+func TestGroupAggregatesErrors(t *testing.T) {
+	group, ctx := NewGroup(context.Background(), 0)
+	errFirst := errors.New("first failure")
+
+	started := make(chan struct{})
+	group.Go("first", func(context.Context) error {
+		<-started
+		return errFirst
+	})
+	group.Go("second", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	group.Go("third", func(context.Context) error {
+		panic("boom")
+	})
+
+	err := group.Wait()
+	if !errors.Is(err, errFirst) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the errors of every operation, got %v", err)
+	}
+	for _, name := range []string{"first:", "second:", "third: panic: boom"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("expected %q in %q", name, err)
+		}
+	}
+	if ctx.Err() == nil {
+		t.Fatal("expected the group context to be cancelled")
+	}
+}
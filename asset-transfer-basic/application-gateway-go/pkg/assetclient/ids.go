@@ -0,0 +1,174 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// IDGenerator generates unique asset IDs. Implementations are safe for
+// concurrent use, so batch loads can create assets in parallel.
+type IDGenerator interface {
+	NextID(ctx context.Context) (string, error)
+}
+
+// NewIDGenerator returns the generator of an ID strategy: uuid, ulid, or
+// sequence:<name> for values of a named chaincode sequence. A non-empty
+// template, such as "DLR-{date}-{id}", formats every generated ID.
+func NewIDGenerator(strategy string, template string, contract *client.Contract) (IDGenerator, error) {
+	var generator IDGenerator
+	name, argument, _ := strings.Cut(strategy, ":")
+	switch name {
+	case "uuid":
+		generator = UUIDGenerator{}
+	case "", "ulid":
+		generator = ULIDGenerator{}
+	case "sequence":
+		if argument == "" {
+			argument = "assets"
+		}
+		generator = NewSequenceGenerator(contract, argument, 100)
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q, expected uuid, ulid or sequence:<name>", strategy)
+	}
+
+	if template != "" {
+		if !strings.Contains(template, "{id}") {
+			return nil, fmt.Errorf("the ID template %q has no {id} placeholder", template)
+		}
+		generator = &TemplateGenerator{Template: template, Generator: generator}
+	}
+	return generator, nil
+}
+
+// UUIDGenerator generates random version 4 UUIDs.
+type UUIDGenerator struct{}
+
+// NextID returns a new UUID, such as 0b6ac5c6-5e21-4b0e-9d8b-3c8f5a3b7e1d.
+func (UUIDGenerator) NextID(context.Context) (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40 // version 4
+	uuid[8] = uuid[8]&0x3f | 0x80 // RFC 4122 variant
+
+	encoded := hex.EncodeToString(uuid[:])
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:], nil
+}
+
+// crockfordBase32 is the alphabet of ULIDs.
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates ULIDs: 26 characters sorting by creation time to the
+// millisecond, followed by 80 random bits.
+type ULIDGenerator struct{}
+
+// NextID returns a new ULID, such as 01J9Z3Q4T8V6X2K5M7N9P0R1S3.
+func (ULIDGenerator) NextID(context.Context) (string, error) {
+	return newULID(time.Now())
+}
+
+func newULID(t time.Time) (string, error) {
+	var ulid [16]byte
+	binary.BigEndian.PutUint64(ulid[:8], uint64(t.UnixMilli())<<16)
+	if _, err := rand.Read(ulid[6:]); err != nil {
+		return "", err
+	}
+
+	// 128 bits encoded 5 bits at a time, the first character holding the top 3 bits
+	encoded := make([]byte, 26)
+	hi := binary.BigEndian.Uint64(ulid[:8])
+	lo := binary.BigEndian.Uint64(ulid[8:])
+	for i := 25; i >= 0; i-- {
+		encoded[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(encoded), nil
+}
+
+// SequenceGenerator hands out the values of a named sequence kept by the
+// chaincode, reserving them in blocks to submit one transaction per block.
+type SequenceGenerator struct {
+	contract  *client.Contract
+	name      string
+	blockSize int
+
+	lock sync.Mutex
+	next int64
+	end  int64
+}
+
+// NewSequenceGenerator creates a generator reserving blockSize values of the
+// named sequence at a time. Values of a reserved block left unused when the
+// process exits are skipped, so IDs are unique but not gapless.
+func NewSequenceGenerator(contract *client.Contract, name string, blockSize int) *SequenceGenerator {
+	if blockSize <= 0 {
+		blockSize = 1
+	}
+	return &SequenceGenerator{contract: contract, name: name, blockSize: blockSize}
+}
+
+// NextID returns the next value of the sequence, reserving a new block when the
+// current one is used up.
+func (g *SequenceGenerator) NextID(ctx context.Context) (string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.next >= g.end {
+		first, err := g.reserve(ctx)
+		if err != nil {
+			return "", err
+		}
+		g.next, g.end = first, first+int64(g.blockSize)
+	}
+
+	id := g.next
+	g.next++
+	return strconv.FormatInt(id, 10), nil
+}
+
+// reserve submits NextSequence, retrying infrastructure errors such as the
+// MVCC read conflict of a concurrent reservation of the same sequence.
+func (g *SequenceGenerator) reserve(ctx context.Context) (int64, error) {
+	var result []byte
+	err := Retry(ctx, 5, 50*time.Millisecond, func(ctx context.Context) error {
+		var err error
+		result, err = g.contract.SubmitWithContext(ctx, "NextSequence", client.WithArguments(g.name, strconv.Itoa(g.blockSize)))
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve values of sequence %s: %w", g.name, NewMultiPeerError(err))
+	}
+	return strconv.ParseInt(string(result), 10, 64)
+}
+
+// TemplateGenerator formats the IDs of another generator with a template, in
+// which {id} stands for the generated ID and {date} for the current UTC date as
+// 20060102.
+type TemplateGenerator struct {
+	Template  string
+	Generator IDGenerator
+}
+
+// NextID returns the next ID of the wrapped generator, formatted with the template.
+func (g *TemplateGenerator) NextID(ctx context.Context) (string, error) {
+	id, err := g.Generator.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strings.NewReplacer("{id}", id, "{date}", time.Now().UTC().Format("20060102")).Replace(g.Template), nil
+}
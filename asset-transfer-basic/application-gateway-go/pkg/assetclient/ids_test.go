@@ -0,0 +1,73 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUUIDGenerator(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := UUIDGenerator{}.NextID(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !uuidPattern.MatchString(id) || seen[id] {
+			t.Fatalf("expected a new version 4 UUID, got %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestULIDSortsByTime(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 10; i++ {
+		id, err := newULID(start.Add(time.Duration(i) * time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != 26 || strings.Trim(id, crockfordBase32) != "" {
+			t.Fatalf("expected 26 Crockford base32 characters, got %s", id)
+		}
+		ids = append(ids, id)
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatalf("expected ULIDs to sort by creation time, got %v", ids)
+	}
+	// the first 10 characters encode the timestamp
+	if ids[0][:10] != "01HWT0D7G0" {
+		t.Fatalf("unexpected timestamp encoding %s", ids[0][:10])
+	}
+}
+
+func TestNewIDGeneratorTemplate(t *testing.T) {
+	generator, err := NewIDGenerator("uuid", "DLR-{date}-{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := generator.NextID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "DLR-" + time.Now().UTC().Format("20060102") + "-"
+	if !strings.HasPrefix(id, prefix) || len(id) != len(prefix)+36 {
+		t.Fatalf("expected a UUID prefixed with %s, got %s", prefix, id)
+	}
+
+	if _, err := NewIDGenerator("uuid", "DLR-{date}", nil); err == nil {
+		t.Fatal("expected a template without {id} to be rejected")
+	}
+	if _, err := NewIDGenerator("timestamp", "", nil); err == nil {
+		t.Fatal("expected an unknown strategy to be rejected")
+	}
+}
@@ -0,0 +1,574 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxImportRemarksLength is the longest remarks CreateAsset accepts, in
+// characters.
+const maxImportRemarksLength = 140
+
+// ImportField describes a column of an import file: the values it accepts, as
+// given by the asset schema for the asset columns, and the notes of the import
+// templates.
+type ImportField struct {
+	Column string
+	// Required fields must have a value on every row.
+	Required bool
+	// Type is string or number.
+	Type        string
+	Enum        []string
+	Minimum     *float64
+	MaxLength   int
+	Pattern     string
+	Description string
+	Examples    []string
+}
+
+// Note describes the values of the field in a sentence, for the notes of the
+// import templates.
+func (f ImportField) Note() string {
+	var rules []string
+	if f.Required {
+		rules = append(rules, "required")
+	} else {
+		rules = append(rules, "optional")
+	}
+	rules = append(rules, f.Type)
+	if len(f.Enum) > 0 {
+		rules = append(rules, "one of "+strings.Join(f.Enum, ", "))
+	}
+	if f.Minimum != nil {
+		rules = append(rules, "at least "+strconv.FormatFloat(*f.Minimum, 'f', -1, 64))
+	}
+	if f.MaxLength > 0 {
+		rules = append(rules, fmt.Sprintf("at most %d characters", f.MaxLength))
+	}
+	if f.Pattern != "" {
+		rules = append(rules, "matching "+f.Pattern)
+	}
+	note := f.Column + ": " + strings.Join(rules, ", ")
+	if f.Description != "" {
+		note += ". " + f.Description
+	}
+	return note
+}
+
+// DefaultImportFields returns the fields of the import files of this version
+// of the chaincode, from the asset schema of DefaultSchemas.
+func DefaultImportFields() ([]ImportField, error) {
+	schemas, err := DefaultSchemas()
+	if err != nil {
+		return nil, err
+	}
+	return ImportFields(schemas["asset"])
+}
+
+// ImportFields returns the fields of the import files: the ImportColumns,
+// described by the properties of the asset schema, then the msisdn, mpin and
+// remarks of the private details. CreateAsset needs a value for every field
+// but the remarks, and refuses the DELETED status.
+func ImportFields(asset *Schema) ([]ImportField, error) {
+	if asset == nil {
+		return nil, errors.New("the asset schema is missing")
+	}
+	fields := make([]ImportField, 0, len(ImportColumns)+3)
+	for _, column := range ImportColumns {
+		property := column
+		if column == "id" {
+			property = "ID"
+		}
+		schema, ok := asset.Properties[property]
+		if !ok {
+			return nil, fmt.Errorf("the asset schema lacks the %s property", property)
+		}
+		field := ImportField{
+			Column:      column,
+			Required:    true,
+			Type:        "string",
+			Minimum:     schema.Minimum,
+			Pattern:     schema.Pattern,
+			Description: schema.Description,
+			Examples:    schema.Examples,
+		}
+		for _, kind := range schema.Type {
+			if kind == "number" || kind == "integer" {
+				field.Type = "number"
+			}
+		}
+		for _, value := range schema.Enum {
+			if value != "DELETED" {
+				field.Enum = append(field.Enum, value)
+			}
+		}
+		if field.Pattern != "" {
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return nil, fmt.Errorf("invalid pattern of the %s property: %w", property, err)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return append(fields,
+		ImportField{Column: "msisdn", Required: true, Type: "string", Pattern: "^[0-9]+$",
+			Description: "Mobile number of the account holder, kept in the private details", Examples: []string{"9800000101", "9800000102"}},
+		ImportField{Column: "mpin", Required: true, Type: "string",
+			Description: "MPIN of the account, kept in the private details", Examples: []string{"1234", "5678"}},
+		ImportField{Column: "remarks", Type: "string", MaxLength: maxImportRemarksLength,
+			Description: "Remarks of the account, kept in the private details", Examples: []string{"migrated", ""}},
+	), nil
+}
+
+// templateExamples returns the example rows of a template, the nth taking the
+// nth example of each field, or its last one.
+func templateExamples(fields []ImportField, rows int) [][]string {
+	examples := make([][]string, rows)
+	for i := range examples {
+		examples[i] = make([]string, len(fields))
+		for j, field := range fields {
+			switch {
+			case i < len(field.Examples):
+				examples[i][j] = field.Examples[i]
+			case len(field.Examples) > 0:
+				examples[i][j] = field.Examples[len(field.Examples)-1]
+			case len(field.Enum) > 0:
+				examples[i][j] = field.Enum[0]
+			}
+		}
+	}
+	return examples
+}
+
+// WriteImportTemplateCSV writes a CSV import template: the notes on the fields
+// as # comment lines, skipped by ReadImportCSV, the header and two example
+// rows to be replaced by the assets to import.
+func WriteImportTemplateCSV(w io.Writer, fields []ImportField) error {
+	var notes strings.Builder
+	notes.WriteString("# Import template generated from the asset schema. Lines starting with # are ignored.\n")
+	notes.WriteString("# Replace the example rows with the assets to import.\n")
+	for _, field := range fields {
+		notes.WriteString("# " + field.Note() + "\n")
+	}
+	if _, err := io.WriteString(w, notes.String()); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.Column
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(templateExamples(fields, 2)); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// ImportProblem is a value of an import file which CreateAsset would refuse.
+type ImportProblem struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column"`
+	Message string `json:"message"`
+}
+
+func (p ImportProblem) String() string {
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Column, p.Message)
+}
+
+// ValidateImport checks every row of an import file against fields, before
+// any of them is submitted, so that a file is corrected as a whole instead of
+// failing row by row half way through an import. It returns the problems in
+// the order of the rows, none when every row is valid.
+func ValidateImport(rows []ImportRow, fields []ImportField) []ImportProblem {
+	patterns := make(map[string]*regexp.Regexp)
+	for _, field := range fields {
+		if field.Pattern != "" {
+			// ImportFields checked the patterns of the schema
+			patterns[field.Column], _ = regexp.Compile(field.Pattern)
+		}
+	}
+
+	var problems []ImportProblem
+	lines := make(map[string]int, len(rows))
+	for _, row := range rows {
+		report := func(column string, format string, args ...any) {
+			problems = append(problems, ImportProblem{Line: row.Line, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+		for _, field := range fields {
+			value := row.Values[field.Column]
+			if value == "" {
+				if field.Required {
+					report(field.Column, "a value is required")
+				}
+				continue
+			}
+			if field.Type == "number" {
+				number, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					report(field.Column, "%q is not a number", value)
+					continue
+				}
+				if field.Minimum != nil && number < *field.Minimum {
+					report(field.Column, "%s is less than %s", value, strconv.FormatFloat(*field.Minimum, 'f', -1, 64))
+				}
+			}
+			if len(field.Enum) > 0 && !containsString(field.Enum, value) {
+				report(field.Column, "%q is not one of %s", value, strings.Join(field.Enum, ", "))
+			}
+			if field.MaxLength > 0 && utf8.RuneCountInString(value) > field.MaxLength {
+				report(field.Column, "%d characters are more than the %d allowed", utf8.RuneCountInString(value), field.MaxLength)
+			}
+			if pattern := patterns[field.Column]; pattern != nil && !pattern.MatchString(value) {
+				report(field.Column, "%q does not match %s", value, field.Pattern)
+			}
+		}
+		if id := row.Values["id"]; id != "" {
+			if line, ok := lines[id]; ok {
+				report("id", "%s is also the id of line %d", id, line)
+			} else {
+				lines[id] = row.Line
+			}
+		}
+	}
+	return problems
+}
+
+// The parts of a SpreadsheetML workbook read and written for import files.
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	text := t.Text
+	for _, run := range t.Runs {
+		text += run.Text
+	}
+	return text
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// WriteImportTemplateXLSX writes an Excel import template: a Template sheet with
+// the header and two example rows, whose cells check the values of the fields
+// with an enumeration or a minimum as they are typed, and a Notes sheet on the
+// fields. ReadImportXLSX reads the first sheet only.
+func WriteImportTemplateXLSX(w io.Writer, fields []ImportField) error {
+	var sheet bytes.Buffer
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.Column
+	}
+	writeXLSXRow(&sheet, 1, header, nil, 1)
+	for i, example := range templateExamples(fields, 2) {
+		writeXLSXRow(&sheet, i+2, example, fields, 0)
+	}
+	sheet.WriteString(`</sheetData>`)
+
+	var validations bytes.Buffer
+	count := 0
+	for i, field := range fields {
+		cells := fmt.Sprintf("%s2:%s1048576", xlsxColumn(i), xlsxColumn(i))
+		switch {
+		case len(field.Enum) > 0:
+			fmt.Fprintf(&validations, `<dataValidation type="list" allowBlank="%d" showErrorMessage="1" errorTitle="%s" error="%s" sqref="%s"><formula1>"%s"</formula1></dataValidation>`,
+				xlsxBool(!field.Required), xmlEscape(field.Column), xmlEscape("Expected one of "+strings.Join(field.Enum, ", ")), cells, xmlEscape(strings.Join(field.Enum, ",")))
+		case field.Type == "number" && field.Minimum != nil:
+			minimum := strconv.FormatFloat(*field.Minimum, 'f', -1, 64)
+			fmt.Fprintf(&validations, `<dataValidation type="decimal" operator="greaterThanOrEqual" allowBlank="%d" showErrorMessage="1" errorTitle="%s" error="%s" sqref="%s"><formula1>%s</formula1></dataValidation>`,
+				xlsxBool(!field.Required), xmlEscape(field.Column), xmlEscape("Expected a number of at least "+minimum), cells, minimum)
+		default:
+			continue
+		}
+		count++
+	}
+	if count > 0 {
+		fmt.Fprintf(&sheet, `<dataValidations count="%d">%s</dataValidations>`, count, validations.String())
+	}
+	sheet.WriteString(`</worksheet>`)
+
+	var notes bytes.Buffer
+	notes.WriteString(xml.Header)
+	notes.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	writeXLSXRow(&notes, 1, []string{"Import template generated from the asset schema. Replace the example rows of the Template sheet with the assets to import."}, nil, 0)
+	for i, field := range fields {
+		writeXLSXRow(&notes, i+2, []string{field.Note()}, nil, 0)
+	}
+	notes.WriteString(`</sheetData></worksheet>`)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+			`<sheet name="Template" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/>` +
+			`</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`},
+		{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font/><font><b/></font></fonts>` +
+			`<fills count="1"><fill><patternFill patternType="none"/></fill></fills>` +
+			`<borders count="1"><border/></borders>` +
+			`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf/><xf fontId="1" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+		{"xl/worksheets/sheet2.xml", notes.String()},
+	}
+	archive := zip.NewWriter(w)
+	for _, part := range parts {
+		writer, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, part.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeXLSXRow writes a row of cells with the style of index style, the
+// values of the number fields as numbers and the others as inline strings.
+func writeXLSXRow(sheet *bytes.Buffer, number int, values []string, fields []ImportField, style int) {
+	fmt.Fprintf(sheet, `<row r="%d">`, number)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		ref := fmt.Sprintf("%s%d", xlsxColumn(i), number)
+		if fields != nil && fields[i].Type == "number" {
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				fmt.Fprintf(sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, value)
+				continue
+			}
+		}
+		fmt.Fprintf(sheet, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(value))
+	}
+	sheet.WriteString(`</row>`)
+}
+
+// xlsxColumn returns the letters naming the column of index i, A for 0.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxColumnIndex returns the index of the column of a cell reference such as
+// C12, -1 when it names none.
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 {
+		return -1
+	}
+	return index - 1
+}
+
+func xlsxBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func xmlEscape(text string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
+
+// ReadImportXLSX reads the rows of the first sheet of an Excel import file,
+// whose first row names the columns, as ReadImportCSV does. Empty rows are
+// skipped, and the Line of a row is its row number in the sheet.
+func ReadImportXLSX(r io.ReaderAt, size int64) ([]ImportRow, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the Excel file: %w", err)
+	}
+	var workbook xlsxWorkbook
+	if err := readXLSXPart(archive, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("the Excel file has no sheet")
+	}
+	var relationships xlsxRelationships
+	if err := readXLSXPart(archive, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return nil, err
+	}
+	sheetPart := ""
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID == workbook.Sheets[0].ID {
+			sheetPart = relationship.Target
+		}
+	}
+	if sheetPart == "" {
+		return nil, fmt.Errorf("the sheet %s of the Excel file has no part", workbook.Sheets[0].Name)
+	}
+	if strings.HasPrefix(sheetPart, "/") {
+		sheetPart = strings.TrimPrefix(sheetPart, "/")
+	} else {
+		sheetPart = path.Join("xl", sheetPart)
+	}
+
+	var shared xlsxSharedStrings
+	if err := readXLSXPart(archive, "xl/sharedStrings.xml", &shared); err != nil && !errors.Is(err, errXLSXPartMissing) {
+		return nil, err
+	}
+	var sheet xlsxWorksheet
+	if err := readXLSXPart(archive, sheetPart, &sheet); err != nil {
+		return nil, err
+	}
+
+	var header []string
+	var rows []ImportRow
+	number := 0
+	for _, sheetRow := range sheet.Rows {
+		number++
+		if sheetRow.Number > 0 {
+			number = sheetRow.Number
+		}
+		values := make(map[int]string)
+		last := -1
+		for i, cell := range sheetRow.Cells {
+			column := i
+			if cell.Ref != "" {
+				column = xlsxColumnIndex(cell.Ref)
+			}
+			if column < 0 {
+				return nil, fmt.Errorf("row %d: invalid cell reference %q", number, cell.Ref)
+			}
+			var value string
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, fmt.Errorf("row %d: invalid shared string %q", number, cell.Value)
+				}
+				value = shared.Items[index].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			default:
+				value = cell.Value
+			}
+			if value = strings.TrimSpace(value); value != "" {
+				values[column] = value
+				last = max(last, column)
+			}
+		}
+		if last < 0 {
+			continue
+		}
+
+		if header == nil {
+			header = make([]string, last+1)
+			for column, value := range values {
+				header[column] = value
+			}
+			if err := normalizeImportHeader(header, "Excel"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		row := ImportRow{Line: number, Values: make(map[string]string, len(header))}
+		for column, name := range header {
+			if name != "" {
+				row.Values[name] = values[column]
+			}
+		}
+		rows = append(rows, row)
+	}
+	if header == nil {
+		return nil, errors.New("the Excel file has no header row")
+	}
+	return rows, nil
+}
+
+var errXLSXPartMissing = errors.New("missing part")
+
+// readXLSXPart decodes the XML part name of archive into v.
+func readXLSXPart(archive *zip.Reader, name string, v any) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("the Excel file lacks %s: %w", name, errXLSXPartMissing)
+	}
+	defer file.Close()
+	if err := xml.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("invalid %s in the Excel file: %w", name, err)
+	}
+	return nil
+}
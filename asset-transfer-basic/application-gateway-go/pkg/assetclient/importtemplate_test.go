@@ -0,0 +1,102 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestImportTemplateCSVReadsBackValid(t *testing.T) {
+	fields, err := DefaultImportFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template bytes.Buffer
+	if err := WriteImportTemplateCSV(&template, fields); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(template.String(), "# status: required, string, one of ACTIVE, INACTIVE, SUSPENDED, CLOSED") {
+		t.Fatalf("expected the notes to describe the status, got:\n%s", template.String())
+	}
+
+	rows, err := ReadImportCSV(&template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Values["id"] != "asset101" || rows[1].Values["status"] != "INACTIVE" {
+		t.Fatalf("unexpected example rows %+v", rows)
+	}
+	if problems := ValidateImport(rows, fields); len(problems) != 0 {
+		t.Fatalf("expected the example rows to be valid, got %v", problems)
+	}
+}
+
+func TestImportTemplateXLSXReadsBackValid(t *testing.T) {
+	fields, err := DefaultImportFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template bytes.Buffer
+	if err := WriteImportTemplateXLSX(&template, fields); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ReadImportXLSX(bytes.NewReader(template.Bytes()), int64(template.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 2 || rows[0].Values["balance"] != "1500.00" || rows[1].Values["msisdn"] != "9800000102" {
+		t.Fatalf("unexpected example rows %+v", rows)
+	}
+	if problems := ValidateImport(rows, fields); len(problems) != 0 {
+		t.Fatalf("expected the example rows to be valid, got %v", problems)
+	}
+}
+
+func TestValidateImportReportsEveryProblem(t *testing.T) {
+	fields, err := DefaultImportFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "id,dealerid,balance,status,transamount,transtype,msisdn,mpin\n" +
+		"asset1,DEALER101,100,ACTIVE,0,CREDIT,9800000001,1234\n" +
+		"asset2,DEALER101,-5,DELETED,x,CREDIT,98-00,1234\n" +
+		"asset1,,100,ACTIVE,0,CREDIT,9800000003,\n"
+	rows, err := ReadImportCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems := ValidateImport(rows, fields)
+	got := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		got = append(got, problem.String())
+	}
+	expected := []string{
+		`line 3: balance: -5 is less than 0`,
+		`line 3: status: "DELETED" is not one of ACTIVE, INACTIVE, SUSPENDED, CLOSED`,
+		`line 3: transamount: "x" is not a number`,
+		`line 3: msisdn: "98-00" does not match ^[0-9]+$`,
+		`line 4: dealerid: a value is required`,
+		`line 4: mpin: a value is required`,
+		`line 4: id: asset1 is also the id of line 2`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != name {
+			t.Errorf("column %d: expected %s, got %s", i, name, got)
+		}
+		if got := xlsxColumnIndex(name + "12"); got != i {
+			t.Errorf("column %s: expected %d, got %d", name, i, got)
+		}
+	}
+}
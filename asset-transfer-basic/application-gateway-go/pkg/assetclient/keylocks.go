@@ -0,0 +1,141 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+)
+
+// KeyLocks orders the submissions changing the same keys, such as the ID of a
+// hot wallet, so that a transaction is only endorsed once the previous one on
+// its keys is done. Concurrent transactions on one asset would otherwise read
+// the same version and all but the first fail validation with an MVCC read
+// conflict. Submissions on different keys run in parallel, and those waiting
+// for a key get it in arrival order. A nil KeyLocks does not order anything.
+type KeyLocks struct {
+	lock  sync.Mutex
+	queue map[string]*keyQueue
+}
+
+// keyQueue holds the token of a key, taken by its holder, and counts the
+// holder and waiters so that free keys are forgotten.
+type keyQueue struct {
+	token chan struct{}
+	users int
+}
+
+// NewKeyLocks returns KeyLocks with every key free.
+func NewKeyLocks() *KeyLocks {
+	return &KeyLocks{queue: make(map[string]*keyQueue)}
+}
+
+// Lock waits until every key is free and takes them, returning the function
+// freeing them, which may be called more than once. The keys are taken in
+// sorted order, so that submissions sharing several keys cannot deadlock. It
+// fails, holding none of the keys, when ctx is done first.
+func (l *KeyLocks) Lock(ctx context.Context, keys ...string) (func(), error) {
+	if l == nil || len(keys) == 0 {
+		return func() {}, nil
+	}
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	taken := make([]string, 0, len(keys))
+	unlock := sync.OnceFunc(func() {
+		for _, key := range taken {
+			l.release(key)
+		}
+	})
+	for _, key := range keys {
+		if err := l.acquire(ctx, key); err != nil {
+			unlock()
+			return nil, err
+		}
+		taken = append(taken, key)
+	}
+	return unlock, nil
+}
+
+func (l *KeyLocks) acquire(ctx context.Context, key string) error {
+	l.lock.Lock()
+	queue, ok := l.queue[key]
+	if !ok {
+		queue = &keyQueue{token: make(chan struct{}, 1)}
+		queue.token <- struct{}{}
+		l.queue[key] = queue
+	}
+	queue.users++
+	l.lock.Unlock()
+
+	select {
+	case <-queue.token:
+		return nil
+	case <-ctx.Done():
+		l.lock.Lock()
+		l.leave(key, queue)
+		l.lock.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (l *KeyLocks) release(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	queue := l.queue[key]
+	queue.token <- struct{}{}
+	l.leave(key, queue)
+}
+
+// leave removes a holder or waiter of key, forgetting the key once it has none.
+func (l *KeyLocks) leave(key string, queue *keyQueue) {
+	queue.users--
+	if queue.users == 0 {
+		delete(l.queue, key)
+	}
+}
+
+// assetArgFunctions are the chaincode functions changing the asset named by
+// their first argument.
+var assetArgFunctions = map[string]bool{
+	"ApproveMPINReset":    true,
+	"ArchiveAsset":        true,
+	"ArchiveRestore":      true,
+	"CompleteMPINReset":   true,
+	"ConfirmMSISDNChange": true,
+	"DeleteAsset":         true,
+	"PatchAsset":          true,
+	"PlaceLien":           true,
+	"ReleaseHold":         true,
+	"ReleaseLien":         true,
+	"RequestMPINReset":    true,
+	"RequestMSISDNChange": true,
+	"SplitAsset":          true,
+	"TransferAsset":       true,
+	"UpdateAsset":         true,
+}
+
+// AssetKeys returns the IDs of the existing assets a chaincode function changes,
+// as found in its arguments, to order its submissions with KeyLocks. It returns
+// none for the functions changing no asset, or assets not named by their
+// arguments.
+func AssetKeys(function string, args []string) []string {
+	switch {
+	case assetArgFunctions[function] && len(args) > 0:
+		return []string{args[0]}
+	case function == "SwapAssets" && len(args) > 1:
+		return []string{args[0], args[1]}
+	case function == "MergeAssets" && len(args) > 1:
+		var sourceIDs []string
+		if err := json.Unmarshal([]byte(args[1]), &sourceIDs); err != nil {
+			return []string{args[0]}
+		}
+		return append([]string{args[0]}, sourceIDs...)
+	}
+	return nil
+}
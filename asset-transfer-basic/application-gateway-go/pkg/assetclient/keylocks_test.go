@@ -0,0 +1,122 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyLocksSerializeSameKey(t *testing.T) {
+	locks := NewKeyLocks()
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := locks.Lock(context.Background(), "asset1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer unlock()
+			current := running.Add(1)
+			defer running.Add(-1)
+			if current > maxRunning.Load() {
+				maxRunning.Store(current)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning.Load() != 1 {
+		t.Fatalf("expected submissions on one key to run one at a time, %d ran at once", maxRunning.Load())
+	}
+	if len(locks.queue) != 0 {
+		t.Fatalf("expected free keys to be forgotten, %d are kept", len(locks.queue))
+	}
+}
+
+func TestKeyLocksKeepOtherKeysParallel(t *testing.T) {
+	locks := NewKeyLocks()
+	unlock, err := locks.Lock(context.Background(), "asset1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	other, err := locks.Lock(ctx, "asset2")
+	if err != nil {
+		t.Fatalf("expected another key to be free: %v", err)
+	}
+	other()
+}
+
+func TestKeyLocksGiveUpWithContext(t *testing.T) {
+	locks := NewKeyLocks()
+	unlock, err := locks.Lock(context.Background(), "asset2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := locks.Lock(ctx, "asset1", "asset2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to end with the context, got %v", err)
+	}
+
+	// the key taken before giving up is free again
+	free, err := locks.Lock(context.Background(), "asset1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	free()
+	unlock()
+	unlock()
+	if len(locks.queue) != 0 {
+		t.Fatalf("expected free keys to be forgotten, %d are kept", len(locks.queue))
+	}
+}
+
+func TestNilKeyLocksDoNotWait(t *testing.T) {
+	var locks *KeyLocks
+	for i := 0; i < 2; i++ {
+		unlock, err := locks.Lock(context.Background(), "asset1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unlock()
+	}
+}
+
+func TestAssetKeys(t *testing.T) {
+	tests := []struct {
+		function string
+		args     []string
+		expected []string
+	}{
+		{"UpdateAsset", []string{"asset1", "DEALER101", "100", "ACTIVE", "100", "CREDIT"}, []string{"asset1"}},
+		{"TransferAsset", []string{"asset1", "DEALER102"}, []string{"asset1"}},
+		{"SwapAssets", []string{"asset1", "asset2"}, []string{"asset1", "asset2"}},
+		{"MergeAssets", []string{"asset1", `["asset2","asset3"]`}, []string{"asset1", "asset2", "asset3"}},
+		{"CreateAsset", []string{"asset9", "DEALER101", "100", "ACTIVE", "100", "CREDIT"}, nil},
+		{"AllocateFloat", []string{"DEALER101", "100"}, nil},
+	}
+	for _, test := range tests {
+		if keys := AssetKeys(test.function, test.args); !slices.Equal(keys, test.expected) {
+			t.Errorf("expected the keys of %s to be %v, got %v", test.function, test.expected, keys)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The legacy core-banking extract is a text file of fixed-width records, each
+// starting with its record type:
+//
+//	HD  header: extract date YYYYMMDD (3-10), branch (11-16)
+//	AC  account: account number (3-18), dealer (19-28), MSISDN (29-43), status
+//	    (44), balance in paise (45-58), balance sign C or D (59), remarks (60-99)
+//	TX  transaction: account number (3-18), then the ISO 8583 data elements of
+//	    the message as |-separated NAME=value pairs, such as
+//	    MTI=0200|DE3=210000|DE4=000000050000|DE7=0102120000|DE39=00
+//	TR  trailer: number of AC and TX records (3-10)
+//
+// Positions are 1-based and inclusive. Account statuses are A active, I
+// inactive, D dormant and C closed.
+const (
+	legacyHeader      = "HD"
+	legacyAccount     = "AC"
+	legacyTransaction = "TX"
+	legacyTrailer     = "TR"
+
+	// legacyAccountMinLength is the length of an account record without its
+	// remarks, which legacy exports trim of their trailing spaces.
+	legacyAccountMinLength = 59
+	// legacyRemarksLength is the longest remarks the chaincode accepts.
+	legacyRemarksLength = 140
+)
+
+// Reconciliation actions of the records of a legacy extract.
+const (
+	ReconcileImported    = "imported"
+	ReconcileTransformed = "transformed"
+	ReconcileSkipped     = "skipped"
+)
+
+// legacyProcessingCodes maps the transaction type of an ISO 8583 processing
+// code, its first two digits, to the transaction type of an asset.
+var legacyProcessingCodes = map[string]string{
+	"00": "DEBIT",  // purchase
+	"01": "DEBIT",  // cash withdrawal
+	"20": "CREDIT", // refund
+	"21": "CREDIT", // deposit
+}
+
+// LegacyOptions configures the conversion of a legacy extract.
+type LegacyOptions struct {
+	// InitialMPIN is the MPIN of the converted assets. The extract carries no
+	// MPINs, so subscribers set theirs on first use.
+	InitialMPIN string
+}
+
+// ReconciliationEntry reports what the conversion did with a record of a
+// legacy extract, and why for transformed and skipped records.
+type ReconciliationEntry struct {
+	Line    int    `json:"line"`
+	Record  string `json:"record"`
+	Account string `json:"account,omitempty"`
+	Action  string `json:"action"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ReconciliationReport lists the records of a legacy extract with their
+// outcome, for matching the import against the core-banking totals.
+type ReconciliationReport struct {
+	ExtractDate string                `json:"extractDate"`
+	Branch      string                `json:"branch"`
+	Records     int                   `json:"records"`
+	Imported    int                   `json:"imported"`
+	Transformed int                   `json:"transformed"`
+	Skipped     int                   `json:"skipped"`
+	Balance     float64               `json:"balance"`
+	Entries     []ReconciliationEntry `json:"entries"`
+}
+
+func (r *ReconciliationReport) add(entry ReconciliationEntry) {
+	switch entry.Action {
+	case ReconcileImported:
+		r.Imported++
+	case ReconcileTransformed:
+		r.Transformed++
+	case ReconcileSkipped:
+		r.Skipped++
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+// WriteCSV writes the entries of the report as CSV, with a header line.
+func (r *ReconciliationReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"line", "record", "account", "action", "reason"}); err != nil {
+		return err
+	}
+	for _, entry := range r.Entries {
+		if err := writer.Write([]string{strconv.Itoa(entry.Line), entry.Record, entry.Account, entry.Action, entry.Reason}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// legacyAccountRow is an account of the extract being converted.
+type legacyAccountRow struct {
+	row     ImportRow
+	entry   int
+	reasons []string
+	// transactionTime is the transmission date and time of the latest approved
+	// transaction of the account, empty without one.
+	transactionTime string
+}
+
+// ReadLegacyExtract converts a legacy core-banking extract into import rows,
+// one per account, with the columns of ReadImportCSV. The amount and type of
+// the latest approved transaction of an account become its transamount and
+// transtype. Records that cannot be converted are skipped, and every record is
+// reported. It fails when the extract is truncated, its trailer missing or not
+// matching the records read.
+func ReadLegacyExtract(r io.Reader, options LegacyOptions) ([]ImportRow, *ReconciliationReport, error) {
+	if options.InitialMPIN == "" {
+		return nil, nil, errors.New("an initial MPIN is required, the legacy extract carries none")
+	}
+
+	report := &ReconciliationReport{}
+	accounts := make(map[string]*legacyAccountRow)
+	var order []*legacyAccountRow
+	trailer := -1
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		record := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		if trailer >= 0 {
+			return nil, nil, fmt.Errorf("line %d: records follow the trailer", line)
+		}
+
+		recordType := record[:min(2, len(record))]
+		switch recordType {
+		case legacyHeader:
+			if len(record) < 16 {
+				return nil, nil, fmt.Errorf("line %d: the header is %d characters long, expected 16", line, len(record))
+			}
+			report.ExtractDate, report.Branch = record[2:10], strings.TrimSpace(record[10:16])
+		case legacyAccount:
+			report.Records++
+			account, entry := convertLegacyAccount(line, record, options)
+			if entry.Action != ReconcileSkipped {
+				if _, ok := accounts[entry.Account]; ok {
+					entry.Action, entry.Reason = ReconcileSkipped, "duplicate account number"
+				}
+			}
+			report.add(entry)
+			if entry.Action != ReconcileSkipped {
+				account.entry = len(report.Entries) - 1
+				accounts[entry.Account] = account
+				order = append(order, account)
+			}
+		case legacyTransaction:
+			report.Records++
+			report.add(applyLegacyTransaction(line, record, accounts))
+		case legacyTrailer:
+			count, err := strconv.Atoi(strings.TrimSpace(record[2:min(10, len(record))]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: invalid trailer record count %q", line, record[2:])
+			}
+			trailer = count
+		default:
+			return nil, nil, fmt.Errorf("line %d: unknown record type %q", line, recordType)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if trailer < 0 {
+		return nil, nil, errors.New("the extract has no trailer, it may be truncated")
+	}
+	if trailer != report.Records {
+		return nil, nil, fmt.Errorf("the trailer counts %d records, the extract holds %d", trailer, report.Records)
+	}
+
+	rows := make([]ImportRow, 0, len(order))
+	for _, account := range order {
+		if account.transactionTime == "" {
+			account.row.Values["transtype"] = "INIT"
+			account.row.Values["transamount"] = account.row.Values["balance"]
+			account.reasons = append(account.reasons, "no approved transaction, recorded as INIT")
+		}
+		entry := &report.Entries[account.entry]
+		if len(account.reasons) > 0 {
+			entry.Action, entry.Reason = ReconcileTransformed, strings.Join(account.reasons, "; ")
+			report.Imported--
+			report.Transformed++
+		}
+		balance, _ := strconv.ParseFloat(account.row.Values["balance"], 64)
+		report.Balance += balance
+		rows = append(rows, account.row)
+	}
+	return rows, report, nil
+}
+
+// convertLegacyAccount converts an account record into the import row of its
+// asset, returning the entry reporting it as imported or skipped.
+func convertLegacyAccount(line int, record string, options LegacyOptions) (*legacyAccountRow, ReconciliationEntry) {
+	entry := ReconciliationEntry{Line: line, Record: legacyAccount, Action: ReconcileImported}
+	if len(record) < legacyAccountMinLength {
+		entry.Action, entry.Reason = ReconcileSkipped, fmt.Sprintf("the record is %d characters long, expected at least %d", len(record), legacyAccountMinLength)
+		return nil, entry
+	}
+	account := strings.TrimLeft(strings.TrimSpace(record[2:18]), "0")
+	entry.Account = account
+	skip := func(reason string) (*legacyAccountRow, ReconciliationEntry) {
+		entry.Action, entry.Reason = ReconcileSkipped, reason
+		return nil, entry
+	}
+	if account == "" {
+		return skip("the account number is empty")
+	}
+
+	converted := &legacyAccountRow{row: ImportRow{Line: line, Values: map[string]string{
+		"id":       account,
+		"dealerid": strings.TrimSpace(record[18:28]),
+		"mpin":     options.InitialMPIN,
+	}}}
+	values := converted.row.Values
+	if values["dealerid"] == "" {
+		return skip("the dealer is empty")
+	}
+
+	msisdn := strings.TrimSpace(record[28:43])
+	if len(msisdn) == 12 && strings.HasPrefix(msisdn, "91") {
+		msisdn = msisdn[2:]
+		converted.reasons = append(converted.reasons, "country code removed from the MSISDN")
+	}
+	if len(msisdn) != 10 || strings.Trim(msisdn, "0123456789") != "" {
+		return skip(fmt.Sprintf("invalid MSISDN %q", msisdn))
+	}
+	values["msisdn"] = msisdn
+
+	switch record[43] {
+	case 'A':
+		values["status"] = "ACTIVE"
+	case 'I':
+		values["status"] = "INACTIVE"
+	case 'D':
+		values["status"] = "INACTIVE"
+		converted.reasons = append(converted.reasons, "dormant account imported as INACTIVE")
+	case 'C':
+		return skip("closed account")
+	default:
+		return skip(fmt.Sprintf("unknown status %q", record[43]))
+	}
+
+	paise, err := strconv.ParseInt(strings.TrimSpace(record[44:58]), 10, 64)
+	if err != nil {
+		return skip(fmt.Sprintf("invalid balance %q", record[44:58]))
+	}
+	switch record[58] {
+	case 'C':
+	case 'D':
+		if paise != 0 {
+			return skip("overdrawn account, balances may not be negative")
+		}
+	default:
+		return skip(fmt.Sprintf("unknown balance sign %q", record[58]))
+	}
+	values["balance"] = formatPaise(paise)
+
+	remarks := strings.Join(strings.Fields(record[legacyAccountMinLength:]), " ")
+	if utf8.RuneCountInString(remarks) > legacyRemarksLength {
+		remarks = string([]rune(remarks)[:legacyRemarksLength])
+		converted.reasons = append(converted.reasons, "remarks truncated")
+	}
+	values["remarks"] = remarks
+
+	return converted, entry
+}
+
+// applyLegacyTransaction applies a transaction record to its account, keeping
+// the latest approved transaction, and returns the entry reporting it.
+func applyLegacyTransaction(line int, record string, accounts map[string]*legacyAccountRow) ReconciliationEntry {
+	entry := ReconciliationEntry{Line: line, Record: legacyTransaction, Action: ReconcileImported}
+	skip := func(reason string) ReconciliationEntry {
+		entry.Action, entry.Reason = ReconcileSkipped, reason
+		return entry
+	}
+	if len(record) < 18 {
+		return skip(fmt.Sprintf("the record is %d characters long, expected at least 18", len(record)))
+	}
+	entry.Account = strings.TrimLeft(strings.TrimSpace(record[2:18]), "0")
+
+	elements := make(map[string]string)
+	for _, element := range strings.Split(record[18:], "|") {
+		name, value, found := strings.Cut(strings.TrimSpace(element), "=")
+		if !found {
+			return skip(fmt.Sprintf("malformed data element %q", element))
+		}
+		elements[strings.ToUpper(name)] = value
+	}
+	if mti := elements["MTI"]; mti != "0200" && mti != "0220" {
+		return skip(fmt.Sprintf("message type %q is not a financial transaction", mti))
+	}
+	if code := elements["DE39"]; code != "00" {
+		return skip(fmt.Sprintf("declined with response code %q", code))
+	}
+	transType, ok := legacyProcessingCodes[elements["DE3"][:min(2, len(elements["DE3"]))]]
+	if !ok {
+		return skip(fmt.Sprintf("unknown processing code %q", elements["DE3"]))
+	}
+	paise, err := strconv.ParseInt(elements["DE4"], 10, 64)
+	if err != nil || paise < 0 {
+		return skip(fmt.Sprintf("invalid amount %q", elements["DE4"]))
+	}
+	transmitted := elements["DE7"]
+	if len(transmitted) != 10 || strings.Trim(transmitted, "0123456789") != "" {
+		return skip(fmt.Sprintf("invalid transmission date and time %q", transmitted))
+	}
+
+	account, ok := accounts[entry.Account]
+	if !ok {
+		return skip("no imported account precedes the transaction")
+	}
+	if transmitted >= account.transactionTime {
+		account.transactionTime = transmitted
+		account.row.Values["transtype"] = transType
+		account.row.Values["transamount"] = formatPaise(paise)
+	}
+	return entry
+}
+
+// formatPaise formats an amount in paise as rupees.
+func formatPaise(paise int64) string {
+	return strconv.FormatFloat(float64(paise)/100, 'f', 2, 64)
+}
@@ -0,0 +1,104 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// legacyAccountRecord formats an account record of a legacy extract.
+func legacyAccountRecord(account string, dealer string, msisdn string, status byte, paise int64, sign byte, remarks string) string {
+	return fmt.Sprintf("AC%016s%-10s%15s%c%014d%c%s", account, dealer, msisdn, status, paise, sign, remarks)
+}
+
+func TestReadLegacyExtract(t *testing.T) {
+	extract := strings.Join([]string{
+		"HD20240102BR0042",
+		legacyAccountRecord("1001", "DEALER101", "9877890123", 'A', 10000000, 'C', "Personal loan   disbursement"),
+		legacyAccountRecord("1002", "DEALER101", "919811234567", 'D', 50000, 'C', ""),
+		legacyAccountRecord("1003", "DEALER102", "9822345678", 'C', 0, 'C', "Closed by customer"),
+		legacyAccountRecord("1001", "DEALER101", "9877890123", 'A', 100, 'C', "Duplicate"),
+		legacyAccountRecord("1004", "DEALER102", "9822345678", 'A', 2500, 'D', ""),
+		"TX0000000000001001MTI=0200|DE3=210000|DE4=000000050000|DE7=0102120000|DE39=00",
+		"TX0000000000001001MTI=0200|DE3=000000|DE4=000000020000|DE7=0102130000|DE39=00",
+		"TX0000000000001001MTI=0200|DE3=000000|DE4=000000090000|DE7=0102140000|DE39=51",
+		"TX0000000000009999MTI=0200|DE3=000000|DE4=000000001000|DE7=0102140000|DE39=00",
+		"TR00000009",
+	}, "\r\n")
+
+	rows, report, err := ReadLegacyExtract(strings.NewReader(extract), LegacyOptions{InitialMPIN: "0000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ImportRow{
+		{Line: 2, Values: map[string]string{
+			"id": "1001", "dealerid": "DEALER101", "balance": "100000.00", "status": "ACTIVE",
+			"transamount": "200.00", "transtype": "DEBIT", "msisdn": "9877890123", "mpin": "0000",
+			"remarks": "Personal loan disbursement",
+		}},
+		{Line: 3, Values: map[string]string{
+			"id": "1002", "dealerid": "DEALER101", "balance": "500.00", "status": "INACTIVE",
+			"transamount": "500.00", "transtype": "INIT", "msisdn": "9811234567", "mpin": "0000",
+			"remarks": "",
+		}},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rows)
+	}
+
+	if report.ExtractDate != "20240102" || report.Branch != "BR0042" || report.Records != 9 {
+		t.Fatalf("unexpected report header %+v", report)
+	}
+	if report.Imported != 3 || report.Transformed != 1 || report.Skipped != 5 || report.Balance != 100500 {
+		t.Fatalf("expected 3 imported, 1 transformed and 5 skipped records of 100500, got %+v", report)
+	}
+	transformed := report.Entries[1]
+	if transformed.Action != ReconcileTransformed || transformed.Reason != "country code removed from the MSISDN; dormant account imported as INACTIVE; no approved transaction, recorded as INIT" {
+		t.Fatalf("unexpected transformed entry %+v", transformed)
+	}
+	var skipped []string
+	for _, entry := range report.Entries {
+		if entry.Action == ReconcileSkipped {
+			skipped = append(skipped, entry.Reason)
+		}
+	}
+	expectedSkipped := []string{
+		"closed account",
+		"duplicate account number",
+		"overdrawn account, balances may not be negative",
+		`declined with response code "51"`,
+		"no imported account precedes the transaction",
+	}
+	if !reflect.DeepEqual(skipped, expectedSkipped) {
+		t.Fatalf("expected the skipped reasons %q, got %q", expectedSkipped, skipped)
+	}
+
+	var csv strings.Builder
+	if err := report.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(csv.String(), "line,record,account,action,reason\n2,AC,1001,imported,\n") {
+		t.Fatalf("unexpected report CSV %q", csv.String())
+	}
+}
+
+func TestReadLegacyExtractChecksTheTrailer(t *testing.T) {
+	account := legacyAccountRecord("1001", "DEALER101", "9877890123", 'A', 100, 'C', "")
+	for name, extract := range map[string]string{
+		"missing":    "HD20240102BR0042\n" + account + "\n",
+		"mismatched": "HD20240102BR0042\n" + account + "\nTR00000002\n",
+		"followed":   "HD20240102BR0042\nTR00000000\n" + account + "\n",
+	} {
+		if _, _, err := ReadLegacyExtract(strings.NewReader(extract), LegacyOptions{InitialMPIN: "0000"}); err == nil {
+			t.Errorf("expected the %s trailer to fail the conversion", name)
+		}
+	}
+	if _, _, err := ReadLegacyExtract(strings.NewReader("TR00000000\n"), LegacyOptions{}); err == nil {
+		t.Error("expected the conversion to require an initial MPIN")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// LocalLedger is a client of the chaincode served against its in-memory ledger
+// with LOCAL_LEDGER_ADDRESS, for developing the contract without a Fabric
+// network. Transactions run directly against the contract: they are neither
+// endorsed nor ordered, and the caller is a generated identity of MSPID, an
+// admin when Admin is set.
+type LocalLedger struct {
+	URL        string
+	MSPID      string
+	Admin      bool
+	HTTPClient *http.Client
+}
+
+// LocalResult is the outcome of a transaction submitted to a LocalLedger.
+type LocalResult struct {
+	TransactionID string
+	Payload       []byte
+	// EventName and EventPayload are the chaincode event set by the transaction, if any.
+	EventName    string
+	EventPayload []byte
+}
+
+// localRequest and localResponse are the JSON exchanged with the local ledger.
+type localRequest struct {
+	Function  string            `json:"function"`
+	Args      []string          `json:"args"`
+	Transient map[string][]byte `json:"transient,omitempty"`
+	Submit    bool              `json:"submit"`
+	MSPID     string            `json:"mspId,omitempty"`
+	Admin     bool              `json:"admin,omitempty"`
+}
+
+type localResponse struct {
+	TransactionID string `json:"transactionId"`
+	Status        int32  `json:"status"`
+	Message       string `json:"message"`
+	Payload       []byte `json:"payload"`
+	Event         *struct {
+		Name    string `json:"name"`
+		Payload []byte `json:"payload"`
+	} `json:"event"`
+	Committed bool `json:"committed"`
+}
+
+// NewLocalLedger returns a client of the local ledger at url, such as
+// http://localhost:9999.
+func NewLocalLedger(url string) *LocalLedger {
+	return &LocalLedger{URL: strings.TrimSuffix(url, "/"), HTTPClient: http.DefaultClient}
+}
+
+// Evaluate runs a transaction function without committing its writes and
+// returns its result.
+func (l *LocalLedger) Evaluate(ctx context.Context, function string, args ...string) ([]byte, error) {
+	response, err := l.invoke(ctx, localRequest{Function: function, Args: args})
+	if err != nil {
+		return nil, err
+	}
+	return response.Payload, nil
+}
+
+// Submit runs a transaction function with the transient data and commits its
+// writes when it succeeds. Errors of the contract are returned as a
+// *ChaincodeError when they carry an error code, so errors.Is matches the
+// domain errors as with a Fabric network.
+func (l *LocalLedger) Submit(ctx context.Context, function string, transient map[string][]byte, args ...string) (*LocalResult, error) {
+	response, err := l.invoke(ctx, localRequest{Function: function, Args: args, Transient: transient, Submit: true})
+	if err != nil {
+		return nil, err
+	}
+	result := &LocalResult{TransactionID: response.TransactionID, Payload: response.Payload}
+	if response.Event != nil {
+		result.EventName, result.EventPayload = response.Event.Name, response.Event.Payload
+	}
+	return result, nil
+}
+
+func (l *LocalLedger) invoke(ctx context.Context, request localRequest) (*localResponse, error) {
+	request.MSPID, request.Admin = l.MSPID, l.Admin
+	if request.Args == nil {
+		request.Args = []string{}
+	}
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, l.URL+"/transactions", bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpClient := l.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	httpResponse, err := httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the local ledger: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(httpResponse.Body, 4096))
+		return nil, fmt.Errorf("local ledger returned %s: %s", httpResponse.Status, strings.TrimSpace(string(message)))
+	}
+	var response localResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse the local ledger response: %w", err)
+	}
+	if response.Status >= http.StatusBadRequest {
+		if chaincodeErr := parseChaincodeError(response.Message); chaincodeErr != nil {
+			return nil, chaincodeErr
+		}
+		return nil, errors.New(response.Message)
+	}
+	return &response, nil
+}
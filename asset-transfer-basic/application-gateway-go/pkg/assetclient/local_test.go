@@ -0,0 +1,53 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocalLedgerSubmit(t *testing.T) {
+	var received localRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transactions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"transactionId":"tx1","status":200,"payload":"eyJJRCI6ImFzc2V0MSJ9","event":{"name":"AssetCreated","payload":"e30="},"committed":true}`))
+	}))
+	defer server.Close()
+
+	ledger := NewLocalLedger(server.URL + "/")
+	ledger.Admin = true
+	result, err := ledger.Submit(context.Background(), "CreateAsset", map[string][]byte{"asset_properties": []byte(`{}`)}, "asset1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !received.Submit || !received.Admin || received.Function != "CreateAsset" || len(received.Args) != 1 || string(received.Transient["asset_properties"]) != "{}" {
+		t.Fatalf("unexpected request %+v", received)
+	}
+	if result.TransactionID != "tx1" || string(result.Payload) != `{"ID":"asset1"}` || result.EventName != "AssetCreated" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestLocalLedgerChaincodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"transactionId":"tx1","status":500,"message":"{\"code\":\"ASSET_NOT_FOUND\",\"message\":\"the asset asset9 does not exist\"}"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewLocalLedger(server.URL).Evaluate(context.Background(), "ReadAsset", "asset9")
+	if !errors.Is(err, ErrAssetNotFound) {
+		t.Fatalf("expected ErrAssetNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/orderer"
+	"google.golang.org/protobuf/proto"
+)
+
+// Orderer is an ordering service node transactions can be broadcast to,
+// bypassing the orderer selection of the Gateway peer.
+type Orderer struct {
+	Address string
+	Client  orderer.AtomicBroadcastClient
+}
+
+// OrdererAttempt is the outcome of broadcasting a transaction to one orderer.
+// Status is the status returned by the orderer, such as SUCCESS or
+// SERVICE_UNAVAILABLE, and is empty when the call itself failed with Error.
+type OrdererAttempt struct {
+	Address  string        `json:"address"`
+	Status   string        `json:"status,omitempty"`
+	Info     string        `json:"info,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (a OrdererAttempt) String() string {
+	outcome := a.Status
+	if a.Error != "" {
+		outcome = a.Error
+	} else if a.Info != "" {
+		outcome += " (" + a.Info + ")"
+	}
+	return fmt.Sprintf("%s: %s in %s", a.Address, outcome, a.Duration.Round(time.Millisecond))
+}
+
+// SubmitDiagnostics records the orderers a transaction was broadcast to, in
+// order, and the orderer that accepted it, if any.
+type SubmitDiagnostics struct {
+	TransactionID string           `json:"transactionId"`
+	AcceptedBy    string           `json:"acceptedBy,omitempty"`
+	Attempts      []OrdererAttempt `json:"attempts"`
+}
+
+// OrdererRejectedError reports that no orderer accepted a transaction.
+type OrdererRejectedError struct {
+	Diagnostics *SubmitDiagnostics
+}
+
+func (e *OrdererRejectedError) Error() string {
+	attempts := make([]string, 0, len(e.Diagnostics.Attempts))
+	for _, attempt := range e.Diagnostics.Attempts {
+		attempts = append(attempts, attempt.String())
+	}
+	return fmt.Sprintf("no orderer accepted transaction %s: %s", e.Diagnostics.TransactionID, strings.Join(attempts, "; "))
+}
+
+// BroadcastTransaction sends an endorsed transaction to the orderers in turn
+// until one accepts it, waiting at most timeout for each. Unlike submitting
+// through the Gateway peer, which picks the orderers itself, the caller
+// chooses the orderers and their order, such as to avoid the orderers under
+// maintenance, and learns which one accepted the transaction. The commit
+// status is then read with NewCommit.
+func BroadcastTransaction(ctx context.Context, transaction *client.Transaction, orderers []Orderer, timeout time.Duration) (*SubmitDiagnostics, error) {
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var prepared gateway.PreparedTransaction
+	if err := proto.Unmarshal(transactionBytes, &prepared); err != nil {
+		return nil, fmt.Errorf("failed to read the prepared transaction: %w", err)
+	}
+	return BroadcastEnvelope(ctx, prepared.GetTransactionId(), prepared.GetEnvelope(), orderers, timeout)
+}
+
+// BroadcastEnvelope sends a transaction envelope to the orderers in turn until
+// one accepts it, waiting at most timeout for each.
+func BroadcastEnvelope(ctx context.Context, transactionID string, envelope *common.Envelope, orderers []Orderer, timeout time.Duration) (*SubmitDiagnostics, error) {
+	if len(orderers) == 0 {
+		return nil, errors.New("no orderers to broadcast to")
+	}
+
+	diagnostics := &SubmitDiagnostics{TransactionID: transactionID}
+	for _, node := range orderers {
+		if ctx.Err() != nil {
+			break
+		}
+		attempt, accepted := broadcast(ctx, node, envelope, timeout)
+		diagnostics.Attempts = append(diagnostics.Attempts, attempt)
+		if accepted {
+			diagnostics.AcceptedBy = node.Address
+			return diagnostics, nil
+		}
+	}
+	return diagnostics, &OrdererRejectedError{Diagnostics: diagnostics}
+}
+
+func broadcast(ctx context.Context, node Orderer, envelope *common.Envelope, timeout time.Duration) (OrdererAttempt, bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	attempt := OrdererAttempt{Address: node.Address}
+	response, err := broadcastOnce(ctx, node.Client, envelope)
+	attempt.Duration = time.Since(started)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	attempt.Status = response.GetStatus().String()
+	attempt.Info = response.GetInfo()
+	return attempt, response.GetStatus() == common.Status_SUCCESS
+}
+
+func broadcastOnce(ctx context.Context, broadcastClient orderer.AtomicBroadcastClient, envelope *common.Envelope) (*orderer.BroadcastResponse, error) {
+	stream, err := broadcastClient.Broadcast(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	if err := stream.Send(envelope); err != nil {
+		return nil, err
+	}
+	return stream.Recv()
+}
+
+// NewCommit returns the commit of a transaction broadcast with
+// BroadcastTransaction, to read its commit status from the Gateway peer. The
+// commit status request is signed by the Gateway's identity when the status is
+// read, so it must be the identity that signed the transaction.
+func NewCommit(gw *client.Gateway, transaction *client.Transaction) (*client.Commit, error) {
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var prepared gateway.PreparedTransaction
+	if err := proto.Unmarshal(transactionBytes, &prepared); err != nil {
+		return nil, fmt.Errorf("failed to read the prepared transaction: %w", err)
+	}
+
+	request, err := commitStatusRequest(prepared.GetEnvelope())
+	if err != nil {
+		return nil, err
+	}
+	requestBytes, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	commitBytes, err := proto.Marshal(&gateway.SignedCommitStatusRequest{Request: requestBytes})
+	if err != nil {
+		return nil, err
+	}
+	return gw.NewCommit(commitBytes)
+}
+
+// commitStatusRequest builds the commit status request of a transaction from
+// the channel and signature headers of its envelope.
+func commitStatusRequest(envelope *common.Envelope) (*gateway.CommitStatusRequest, error) {
+	var payload common.Payload
+	if err := proto.Unmarshal(envelope.GetPayload(), &payload); err != nil {
+		return nil, fmt.Errorf("failed to read the transaction payload: %w", err)
+	}
+	var channelHeader common.ChannelHeader
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), &channelHeader); err != nil {
+		return nil, fmt.Errorf("failed to read the channel header: %w", err)
+	}
+	var signatureHeader common.SignatureHeader
+	if err := proto.Unmarshal(payload.GetHeader().GetSignatureHeader(), &signatureHeader); err != nil {
+		return nil, fmt.Errorf("failed to read the signature header: %w", err)
+	}
+
+	return &gateway.CommitStatusRequest{
+		TransactionId: channelHeader.GetTxId(),
+		ChannelId:     channelHeader.GetChannelId(),
+		Identity:      signatureHeader.GetCreator(),
+	}, nil
+}
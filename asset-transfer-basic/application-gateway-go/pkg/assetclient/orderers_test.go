@@ -0,0 +1,83 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/orderer"
+	"google.golang.org/grpc"
+)
+
+type fakeBroadcastClient struct {
+	response *orderer.BroadcastResponse
+	err      error
+	sent     []*common.Envelope
+}
+
+func (c *fakeBroadcastClient) Broadcast(ctx context.Context, opts ...grpc.CallOption) (orderer.AtomicBroadcast_BroadcastClient, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &fakeBroadcastStream{client: c}, nil
+}
+
+// Deliver is not used by BroadcastEnvelope.
+func (c *fakeBroadcastClient) Deliver(ctx context.Context, opts ...grpc.CallOption) (orderer.AtomicBroadcast_DeliverClient, error) {
+	return nil, errors.New("deliver is not supported by the fake orderer")
+}
+
+type fakeBroadcastStream struct {
+	grpc.ClientStream
+	client *fakeBroadcastClient
+}
+
+func (s *fakeBroadcastStream) Send(envelope *common.Envelope) error {
+	s.client.sent = append(s.client.sent, envelope)
+	return nil
+}
+
+func (s *fakeBroadcastStream) Recv() (*orderer.BroadcastResponse, error) {
+	return s.client.response, nil
+}
+
+func (s *fakeBroadcastStream) CloseSend() error {
+	return nil
+}
+
+func TestBroadcastEnvelopeTriesOrderersInTurn(t *testing.T) {
+	down := &fakeBroadcastClient{err: errors.New("connection refused")}
+	maintenance := &fakeBroadcastClient{response: &orderer.BroadcastResponse{Status: common.Status_SERVICE_UNAVAILABLE, Info: "no Raft leader"}}
+	healthy := &fakeBroadcastClient{response: &orderer.BroadcastResponse{Status: common.Status_SUCCESS}}
+	orderers := []Orderer{{"orderer1:7050", down}, {"orderer2:7050", maintenance}, {"orderer3:7050", healthy}}
+
+	diagnostics, err := BroadcastEnvelope(context.Background(), "tx1", &common.Envelope{}, orderers, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics.AcceptedBy != "orderer3:7050" || len(diagnostics.Attempts) != 3 {
+		t.Fatalf("expected acceptance by orderer3 after 3 attempts, got %+v", diagnostics)
+	}
+	if diagnostics.Attempts[0].Error == "" || diagnostics.Attempts[1].Info != "no Raft leader" {
+		t.Fatalf("expected the failures to be recorded, got %+v", diagnostics.Attempts)
+	}
+	if len(healthy.sent) != 1 {
+		t.Fatalf("expected the envelope sent once to orderer3, got %d", len(healthy.sent))
+	}
+}
+
+func TestBroadcastEnvelopeFailsWhenNoOrdererAccepts(t *testing.T) {
+	maintenance := &fakeBroadcastClient{response: &orderer.BroadcastResponse{Status: common.Status_SERVICE_UNAVAILABLE}}
+	diagnostics, err := BroadcastEnvelope(context.Background(), "tx1", &common.Envelope{}, []Orderer{{"orderer1:7050", maintenance}}, time.Second)
+
+	var rejected *OrdererRejectedError
+	if !errors.As(err, &rejected) || rejected.Diagnostics != diagnostics || diagnostics.AcceptedBy != "" {
+		t.Fatalf("expected an OrdererRejectedError, got %v", err)
+	}
+}
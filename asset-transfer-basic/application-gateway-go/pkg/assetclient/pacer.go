@@ -0,0 +1,170 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// pacerAdjustInterval is the least time between two changes of the rate of a
+// Pacer, so that the latencies of the last change are seen before the next.
+const pacerAdjustInterval = time.Second
+
+// pacerMinRateFraction is the fraction of MaxTPS a Pacer slows down to at most.
+const pacerMinRateFraction = 0.05
+
+// PacerConfig bounds the submissions of a bulk job. Zero values are unbounded.
+type PacerConfig struct {
+	// MaxTPS is the most submissions started per second.
+	MaxTPS float64
+	// MaxInFlight is the most submissions running at a time.
+	MaxInFlight int
+	// TargetLatency is the submission latency above which the rate is halved,
+	// and below which it recovers towards MaxTPS. It requires MaxTPS.
+	TargetLatency time.Duration
+}
+
+// Pacer paces the submissions of a bulk job, such as an import, so that a
+// single job cannot flood a shared network. Submissions start at most MaxTPS
+// per second and MaxInFlight at a time. With a TargetLatency, the rate adapts
+// to the latency of the submissions, averaged over the last ones: it is halved
+// when the network slows down beyond the target, and grows again by a tenth of
+// MaxTPS once it is back under it. A nil Pacer does not pace.
+type Pacer struct {
+	config PacerConfig
+	slots  chan struct{}
+	now    func() time.Time
+
+	lock     sync.Mutex
+	rate     float64
+	next     time.Time
+	latency  time.Duration
+	adjusted time.Time
+}
+
+// NewPacer returns a Pacer bounding submissions as config selects.
+func NewPacer(config PacerConfig) (*Pacer, error) {
+	if config.MaxTPS < 0 || config.MaxInFlight < 0 || config.TargetLatency < 0 {
+		return nil, errors.New("the pacing limits must not be negative")
+	}
+	if config.TargetLatency > 0 && config.MaxTPS == 0 {
+		return nil, errors.New("a target latency needs a maximum rate to slow down from")
+	}
+
+	p := &Pacer{config: config, rate: config.MaxTPS, now: time.Now}
+	if config.MaxInFlight > 0 {
+		p.slots = make(chan struct{}, config.MaxInFlight)
+	}
+	return p, nil
+}
+
+// Do runs the submission operation once the pacing allows it, and records its
+// latency. It fails without running operation when ctx is done first.
+func (p *Pacer) Do(ctx context.Context, operation func(ctx context.Context) error) error {
+	if p == nil {
+		return operation(ctx)
+	}
+	if err := p.acquire(ctx); err != nil {
+		return err
+	}
+	started := p.now()
+	err := operation(ctx)
+	p.release(p.now().Sub(started))
+	return err
+}
+
+// Rate returns the submissions per second currently allowed, zero when unbounded.
+func (p *Pacer) Rate() float64 {
+	if p == nil {
+		return 0
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.rate
+}
+
+// acquire waits for a free in-flight slot, then for the start time of the
+// submission at the current rate.
+func (p *Pacer) acquire(ctx context.Context) error {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	delay := p.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		if p.slots != nil {
+			<-p.slots
+		}
+		return ctx.Err()
+	}
+}
+
+// reserve reserves the next start time at the current rate and returns how
+// long until it.
+func (p *Pacer) reserve() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.rate <= 0 {
+		return 0
+	}
+
+	now := p.now()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	p.next = start.Add(time.Duration(float64(time.Second) / p.rate))
+	return start.Sub(now)
+}
+
+// release frees the in-flight slot of a submission and adapts the rate to its
+// latency.
+func (p *Pacer) release(latency time.Duration) {
+	if p.slots != nil {
+		<-p.slots
+	}
+	if p.config.TargetLatency <= 0 {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.latency == 0 {
+		p.latency = latency
+	} else {
+		// exponentially weighted, so that a single slow submission does not
+		// slow the job down
+		p.latency = (4*p.latency + latency) / 5
+	}
+
+	now := p.now()
+	if now.Sub(p.adjusted) < pacerAdjustInterval {
+		return
+	}
+	switch {
+	case p.latency > p.config.TargetLatency:
+		p.rate = max(p.rate/2, p.config.MaxTPS*pacerMinRateFraction)
+	case p.rate < p.config.MaxTPS:
+		p.rate = min(p.rate+p.config.MaxTPS/10, p.config.MaxTPS)
+	default:
+		return
+	}
+	p.adjusted = now
+}
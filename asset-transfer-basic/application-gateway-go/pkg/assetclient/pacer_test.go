@@ -0,0 +1,121 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacerBoundsRate(t *testing.T) {
+	pacer, err := NewPacer(PacerConfig{MaxTPS: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := pacer.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(started); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected 6 submissions at 100 tx/s to take at least 50ms, took %s", elapsed)
+	}
+}
+
+func TestPacerBoundsInFlight(t *testing.T) {
+	pacer, err := NewPacer(PacerConfig{MaxInFlight: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = pacer.Do(context.Background(), func(context.Context) error {
+				current := running.Add(1)
+				defer running.Add(-1)
+				for {
+					previous := maxRunning.Load()
+					if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning.Load() > 2 {
+		t.Fatalf("expected at most 2 submissions at a time, got %d", maxRunning.Load())
+	}
+}
+
+func TestPacerAdaptsToLatency(t *testing.T) {
+	pacer, err := NewPacer(PacerConfig{MaxTPS: 100, TargetLatency: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	pacer.now = func() time.Time { return now }
+
+	pacer.release(time.Second)
+	if rate := pacer.Rate(); rate != 50 {
+		t.Fatalf("expected a slow submission to halve the rate to 50, got %v", rate)
+	}
+	pacer.release(time.Second)
+	if rate := pacer.Rate(); rate != 50 {
+		t.Fatalf("expected the rate to change at most once a second, got %v", rate)
+	}
+	for i := 0; i < 5; i++ {
+		now = now.Add(pacerAdjustInterval)
+		pacer.release(time.Second)
+	}
+	if rate := pacer.Rate(); rate != 5 {
+		t.Fatalf("expected the rate to slow down to 5 at most, got %v", rate)
+	}
+
+	// the average latency recovers over a number of fast submissions
+	for i := 0; i < 20; i++ {
+		pacer.release(10 * time.Millisecond)
+	}
+	now = now.Add(pacerAdjustInterval)
+	pacer.release(10 * time.Millisecond)
+	if rate := pacer.Rate(); rate != 15 {
+		t.Fatalf("expected the rate to grow by a tenth of the maximum to 15, got %v", rate)
+	}
+}
+
+func TestPacerStopsWaitingWhenCancelled(t *testing.T) {
+	pacer, err := NewPacer(PacerConfig{MaxInFlight: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pacer.slots <- struct{}{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = pacer.Do(ctx, func(context.Context) error {
+		t.Fatal("expected the submission not to run")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if _, err := NewPacer(PacerConfig{TargetLatency: time.Second}); err == nil {
+		t.Fatal("expected a target latency without a maximum rate to be rejected")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPageToken is returned for page tokens that are malformed or were not
+// signed with the key of the PageTokens decoding them.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// ErrPageTokenMismatch is returned for a page token issued for another query or
+// page size than the one it is presented with. Resuming a query with the
+// bookmark of another would silently skip or repeat results.
+var ErrPageTokenMismatch = errors.New("page token was issued for another query")
+
+// PageTokens encodes chaincode bookmarks in opaque page tokens, signed with a
+// secret key and bound to the page size and query they were returned for.
+// Applications hand the tokens to their callers in place of the bookmarks.
+type PageTokens struct {
+	key []byte
+}
+
+// NewPageTokens creates page tokens signed with key. Tokens are only accepted by
+// PageTokens sharing the key.
+func NewPageTokens(key []byte) *PageTokens {
+	return &PageTokens{key: key}
+}
+
+// pageToken is the signed content of a page token.
+type pageToken struct {
+	Bookmark string `json:"b"`
+	Filter   string `json:"f"`
+	PageSize int    `json:"s"`
+}
+
+// QueryHash identifies a query by the function evaluated and the arguments
+// selecting its results, excluding the page size and bookmark.
+func QueryHash(function string, args ...string) string {
+	hash := sha256.New()
+	for _, part := range append([]string{function}, args...) {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// Encode returns the page token resuming the query with given hash after
+// bookmark. The end of the results, an empty bookmark, is an empty token.
+func (p *PageTokens) Encode(bookmark string, pageSize int, queryHash string) (string, error) {
+	if bookmark == "" {
+		return "", nil
+	}
+	payload, err := json.Marshal(pageToken{Bookmark: bookmark, Filter: queryHash, PageSize: pageSize})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(p.sign(encoded)), nil
+}
+
+// Decode returns the bookmark of a page token, checking that it was signed with
+// the key and issued for the same page size and query. The empty token starts
+// the query from its first page.
+func (p *PageTokens) Decode(token string, pageSize int, queryHash string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", ErrInvalidPageToken
+	}
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(signatureBytes, p.sign(encoded)) {
+		return "", ErrInvalidPageToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidPageToken
+	}
+	var decoded pageToken
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return "", ErrInvalidPageToken
+	}
+
+	if decoded.Filter != queryHash || decoded.PageSize != pageSize {
+		return "", ErrPageTokenMismatch
+	}
+	return decoded.Bookmark, nil
+}
+
+func (p *PageTokens) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,70 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPageTokensRoundTrip(t *testing.T) {
+	tokens := NewPageTokens([]byte("secret"))
+	query := QueryHash("GetAssetsSorted", "balance:desc")
+
+	token, err := tokens.Encode("g1AAAA", 100, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bookmark, err := tokens.Decode(token, 100, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bookmark != "g1AAAA" {
+		t.Fatalf("expected bookmark g1AAAA, got %q", bookmark)
+	}
+
+	if token, _ := tokens.Encode("", 100, query); token != "" {
+		t.Fatalf("expected an empty token at the end of the results, got %q", token)
+	}
+	if bookmark, err := tokens.Decode("", 100, query); bookmark != "" || err != nil {
+		t.Fatalf("expected the first page for an empty token, got %q, %v", bookmark, err)
+	}
+}
+
+func TestPageTokensRejectMismatches(t *testing.T) {
+	tokens := NewPageTokens([]byte("secret"))
+	query := QueryHash("GetAssetsSorted", "balance:desc")
+	token, err := tokens.Encode("g1AAAA", 100, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		tokens   *PageTokens
+		token    string
+		pageSize int
+		query    string
+		expected error
+	}{
+		"other query":     {tokens, token, 100, QueryHash("GetAssetsSorted", "updatedat"), ErrPageTokenMismatch},
+		"other page size": {tokens, token, 50, query, ErrPageTokenMismatch},
+		"other key":       {NewPageTokens([]byte("other")), token, 100, query, ErrInvalidPageToken},
+		"tampered":        {tokens, "x" + token, 100, query, ErrInvalidPageToken},
+		"raw bookmark":    {tokens, "g1AAAA", 100, query, ErrInvalidPageToken},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := test.tokens.Decode(test.token, test.pageSize, test.query); !errors.Is(err, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestQueryHashSeparatesArguments(t *testing.T) {
+	if QueryHash("Query", "ab", "c") == QueryHash("Query", "a", "bc") {
+		t.Fatal("expected different hashes for different arguments")
+	}
+}
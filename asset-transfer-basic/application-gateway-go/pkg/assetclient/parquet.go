@@ -0,0 +1,224 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// Parquet physical types, and the encodings, converted type and page type of
+// the files written by writeParquet.
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetPlain    = 0
+	parquetRLE      = 3
+	parquetUTF8     = 0
+	parquetDataPage = 0
+)
+
+// Thrift compact protocol types of the Parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetColumn is a required column of a Parquet file, holding its values
+// PLAIN encoded: strings as UTF-8 byte arrays, float64 as doubles and int64.
+type parquetColumn struct {
+	name     string
+	physical int32
+	values   bytes.Buffer
+}
+
+func (c *parquetColumn) appendString(value string) {
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(value))))
+	c.values.WriteString(value)
+}
+
+func (c *parquetColumn) appendDouble(value float64) {
+	c.values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(value)))
+}
+
+func (c *parquetColumn) appendInt64(value int64) {
+	c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(value)))
+}
+
+// writeParquet writes rows rows of columns to w as a Parquet file of one
+// uncompressed row group, with one data page per column. It is the subset of
+// the format needed to export flat records without a Parquet dependency, read
+// by any Parquet reader such as Spark, DuckDB or pyarrow.
+func writeParquet(w io.Writer, columns []*parquetColumn, rows int) error {
+	file := bytes.NewBufferString(parquetMagic)
+
+	type chunk struct {
+		offset int64
+		size   int64
+	}
+	chunks := make([]chunk, len(columns))
+	var totalSize int64
+	for i, column := range columns {
+		header := &thriftWriter{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(column.values.Len()))
+		header.i32(3, int32(column.values.Len()))
+		header.beginStruct(5)
+		header.i32(1, int32(rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.stop()
+
+		chunks[i] = chunk{offset: int64(file.Len()), size: int64(header.buf.Len() + column.values.Len())}
+		totalSize += chunks[i].size
+		file.Write(header.buf.Bytes())
+		file.Write(column.values.Bytes())
+	}
+
+	footer := &thriftWriter{}
+	footer.i32(1, 1)
+	footer.beginList(2, thriftStruct, len(columns)+1)
+	footer.beginElement()
+	footer.binary(4, []byte("schema"))
+	footer.i32(5, int32(len(columns)))
+	footer.endStruct()
+	for _, column := range columns {
+		footer.beginElement()
+		footer.i32(1, column.physical)
+		footer.i32(3, 0) // REQUIRED
+		footer.binary(4, []byte(column.name))
+		if column.physical == parquetByteArray {
+			footer.i32(6, parquetUTF8)
+		}
+		footer.endStruct()
+	}
+	footer.i64(3, int64(rows))
+	if rows == 0 {
+		footer.beginList(4, thriftStruct, 0)
+	} else {
+		footer.beginList(4, thriftStruct, 1)
+		footer.beginElement()
+		footer.beginList(1, thriftStruct, len(columns))
+		for i, column := range columns {
+			footer.beginElement()
+			footer.i64(2, chunks[i].offset)
+			footer.beginStruct(3)
+			footer.i32(1, column.physical)
+			footer.beginList(2, thriftI32, 2)
+			footer.listI32(parquetPlain)
+			footer.listI32(parquetRLE)
+			footer.beginList(3, thriftBinary, 1)
+			footer.listBinary([]byte(column.name))
+			footer.i32(4, 0) // UNCOMPRESSED
+			footer.i64(5, int64(rows))
+			footer.i64(6, chunks[i].size)
+			footer.i64(7, chunks[i].size)
+			footer.i64(9, chunks[i].offset)
+			footer.endStruct()
+			footer.endStruct()
+		}
+		footer.i64(2, totalSize)
+		footer.i64(3, int64(rows))
+		footer.endStruct()
+	}
+	footer.binary(6, []byte("assetclient"))
+	footer.stop()
+
+	file.Write(footer.buf.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(footer.buf.Len())))
+	file.WriteString(parquetMagic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// thriftWriter writes a struct in the Thrift compact protocol, in which the
+// Parquet metadata is serialized. Fields must be written in increasing id
+// order within each struct.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	last   int16
+	parent []int16
+}
+
+func (t *thriftWriter) field(id int16, kind byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.buf.WriteByte(kind)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) varint(value int64) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64((value<<1)^(value>>63))))
+}
+
+func (t *thriftWriter) i32(id int16, value int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(value))
+}
+
+func (t *thriftWriter) i64(id int16, value int64) {
+	t.field(id, thriftI64)
+	t.varint(value)
+}
+
+func (t *thriftWriter) binary(id int16, value []byte) {
+	t.field(id, thriftBinary)
+	t.listBinary(value)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+// beginElement starts a struct element of a list.
+func (t *thriftWriter) beginElement() {
+	t.parent = append(t.parent, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.last = t.parent[len(t.parent)-1]
+	t.parent = t.parent[:len(t.parent)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+func (t *thriftWriter) beginList(id int16, kind byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | kind)
+		return
+	}
+	t.buf.WriteByte(0xf0 | kind)
+	t.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+func (t *thriftWriter) listI32(value int32) {
+	t.varint(int64(value))
+}
+
+func (t *thriftWriter) listBinary(value []byte) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(value))))
+	t.buf.Write(value)
+}
@@ -0,0 +1,56 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PEMFromEnv returns the PEM-encoded certificate or key held in an environment
+// variable, reporting false when the variable is unset or empty. Secret managers
+// and serverless platforms often mangle multi-line values, so the PEM may be
+// given as is, with its newlines escaped as \n, or base64 encoded.
+func PEMFromEnv(name string) ([]byte, bool, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil, false, nil
+	}
+
+	pemBytes, err := decodePEM(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return pemBytes, true, nil
+}
+
+// ReadPEM returns the PEM held in the environment variable name if it is set,
+// or else the PEM returned by readFile.
+func ReadPEM(name string, readFile func() ([]byte, error)) ([]byte, error) {
+	pemBytes, ok, err := PEMFromEnv(name)
+	if err != nil || ok {
+		return pemBytes, err
+	}
+	return readFile()
+}
+
+func decodePEM(value string) ([]byte, error) {
+	const pemHeader = "-----BEGIN "
+	if strings.Contains(value, pemHeader) {
+		return []byte(strings.ReplaceAll(value, `\n`, "\n") + "\n"), nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("neither PEM nor base64 encoded PEM: %w", err)
+	}
+	if !bytes.Contains(decoded, []byte(pemHeader)) {
+		return nil, fmt.Errorf("the base64 encoded value is not PEM")
+	}
+	return decoded, nil
+}
@@ -0,0 +1,60 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testPEM = "-----BEGIN CERTIFICATE-----\nMIIBszCCAVqgAwIBAgIUQ2VydGlmaWNhdGU=\n-----END CERTIFICATE-----\n"
+
+func TestPEMFromEnv(t *testing.T) {
+	encodings := map[string]string{
+		"plain":   testPEM,
+		"escaped": strings.ReplaceAll(strings.TrimSpace(testPEM), "\n", `\n`),
+		"base64":  base64.StdEncoding.EncodeToString([]byte(testPEM)),
+	}
+	for name, value := range encodings {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("TEST_CERT_PEM", value)
+
+			pemBytes, ok, err := PEMFromEnv("TEST_CERT_PEM")
+			if err != nil || !ok {
+				t.Fatalf("expected the PEM to be found, got %v, %v", ok, err)
+			}
+			block, _ := pem.Decode(pemBytes)
+			if block == nil || block.Type != "CERTIFICATE" {
+				t.Fatalf("expected a certificate block, got %q", pemBytes)
+			}
+		})
+	}
+}
+
+func TestPEMFromEnvInvalid(t *testing.T) {
+	t.Setenv("TEST_CERT_PEM", "not a certificate")
+
+	if _, _, err := PEMFromEnv("TEST_CERT_PEM"); err == nil {
+		t.Fatal("expected an error for a value that is not PEM")
+	}
+}
+
+func TestReadPEMFallsBackToFile(t *testing.T) {
+	t.Setenv("TEST_CERT_PEM", "")
+	fileErr := errors.New("no such file")
+
+	if _, err := ReadPEM("TEST_CERT_PEM", func() ([]byte, error) { return nil, fileErr }); err != fileErr {
+		t.Fatalf("expected the file to be read, got %v", err)
+	}
+
+	t.Setenv("TEST_CERT_PEM", testPEM)
+	pemBytes, err := ReadPEM("TEST_CERT_PEM", func() ([]byte, error) { return nil, fileErr })
+	if err != nil || string(pemBytes) != testPEM {
+		t.Fatalf("expected the PEM of the environment variable, got %q, %v", pemBytes, err)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Submitter submits transactions, such as a *client.Contract or a Client.
+type Submitter interface {
+	SubmitWithContext(ctx context.Context, name string, options ...client.ProposalOption) ([]byte, error)
+}
+
+// RollbackStrategy is what a Plan does with the steps already committed when a
+// step fails.
+type RollbackStrategy int
+
+const (
+	// RollbackCompensate submits the compensating transaction of every committed
+	// step, most recent first.
+	RollbackCompensate RollbackStrategy = iota
+	// RollbackNone leaves the committed steps, for plans resumed by hand.
+	RollbackNone
+)
+
+// PlanPhase is the phase of a step reported to the progress function of a Plan.
+type PlanPhase string
+
+// Phases of a step: started, then committed or failed. A committed step is
+// compensating, then compensated or compensation-failed, when a later one fails.
+const (
+	PhaseStarted            PlanPhase = "started"
+	PhaseCommitted          PlanPhase = "committed"
+	PhaseFailed             PlanPhase = "failed"
+	PhaseCompensating       PlanPhase = "compensating"
+	PhaseCompensated        PlanPhase = "compensated"
+	PhaseCompensationFailed PlanPhase = "compensation-failed"
+)
+
+// PlanProgress reports a step of a Plan changing phase. Committed counts the
+// steps committed so far, out of Total.
+type PlanProgress struct {
+	Step      string
+	Phase     PlanPhase
+	Committed int
+	Total     int
+	Err       error
+}
+
+// Plan composes several transactions into one logical operation, such as
+// creating an asset and configuring it. Steps are submitted one at a time, each
+// after the steps it depends on, and when one fails the committed steps are
+// undone with their compensating transactions. Transactions commit one by one:
+// other clients may see the state between two steps.
+type Plan struct {
+	steps    []*PlanStep
+	byName   map[string]*PlanStep
+	rollback RollbackStrategy
+	errs     []error
+}
+
+// PlanStep is a transaction of a Plan, configured with its fluent methods.
+type PlanStep struct {
+	plan       *Plan
+	name       string
+	call       planCall
+	after      []string
+	compensate *planCall
+}
+
+type planCall struct {
+	function  string
+	args      []string
+	transient map[string][]byte
+}
+
+// options returns the proposal options of the call.
+func (c *planCall) options() []client.ProposalOption {
+	options := []client.ProposalOption{client.WithArguments(c.args...)}
+	if c.transient != nil {
+		options = append(options, client.WithTransient(c.transient))
+	}
+	return options
+}
+
+// NewPlan creates an empty plan, compensating committed steps on failure.
+func NewPlan() *Plan {
+	return &Plan{byName: map[string]*PlanStep{}}
+}
+
+// OnFailure sets what is done with the committed steps when a step fails.
+func (p *Plan) OnFailure(strategy RollbackStrategy) *Plan {
+	p.rollback = strategy
+	return p
+}
+
+// Step adds a step submitting function with args, named for dependencies and
+// progress reports. Unless ordered otherwise with After, steps run in the order
+// they were added.
+func (p *Plan) Step(name string, function string, args ...string) *PlanStep {
+	step := &PlanStep{plan: p, name: name, call: planCall{function: function, args: args}}
+	if name == "" {
+		p.errs = append(p.errs, fmt.Errorf("the step submitting %s has no name", function))
+	} else if _, ok := p.byName[name]; ok {
+		p.errs = append(p.errs, fmt.Errorf("there are two steps named %s", name))
+	} else {
+		p.byName[name] = step
+	}
+	p.steps = append(p.steps, step)
+	return step
+}
+
+// WithTransient passes transient data to the transaction of the step.
+func (s *PlanStep) WithTransient(transient map[string][]byte) *PlanStep {
+	s.call.transient = transient
+	return s
+}
+
+// After makes the step wait for the named steps to commit.
+func (s *PlanStep) After(names ...string) *PlanStep {
+	s.after = append(s.after, names...)
+	return s
+}
+
+// CompensateWith sets the transaction undoing the step, submitted when a later
+// step fails. Steps without one are left as they are.
+func (s *PlanStep) CompensateWith(function string, args ...string) *PlanStep {
+	s.compensate = &planCall{function: function, args: args}
+	return s
+}
+
+// Step adds another step to the plan of s, for chaining.
+func (s *PlanStep) Step(name string, function string, args ...string) *PlanStep {
+	return s.plan.Step(name, function, args...)
+}
+
+// Plan returns the plan of the step, ending a chain.
+func (s *PlanStep) Plan() *Plan {
+	return s.plan
+}
+
+// PlanError is returned by Execute when a step fails. Compensated lists the
+// steps undone, and CompensationErr joins the failures of the compensating
+// transactions, leaving their steps committed.
+type PlanError struct {
+	Step            string
+	Err             error
+	Compensated     []string
+	CompensationErr error
+}
+
+func (e *PlanError) Error() string {
+	message := fmt.Sprintf("step %s failed: %v", e.Step, e.Err)
+	if len(e.Compensated) > 0 {
+		message += fmt.Sprintf("; compensated %s", strings.Join(e.Compensated, ", "))
+	}
+	if e.CompensationErr != nil {
+		message += fmt.Sprintf("; compensation failed: %v", e.CompensationErr)
+	}
+	return message
+}
+
+func (e *PlanError) Unwrap() error {
+	return e.Err
+}
+
+// Order returns the names of the steps in the order Execute submits them, or
+// an error for unknown dependencies and cycles.
+func (p *Plan) Order() ([]string, error) {
+	steps, err := p.order()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.name
+	}
+	return names, nil
+}
+
+// order sorts the steps topologically, keeping the order they were added in
+// among the steps ready to run.
+func (p *Plan) order() ([]*PlanStep, error) {
+	if err := errors.Join(p.errs...); err != nil {
+		return nil, err
+	}
+	for _, step := range p.steps {
+		for _, name := range step.after {
+			if _, ok := p.byName[name]; !ok {
+				return nil, fmt.Errorf("step %s depends on unknown step %s", step.name, name)
+			}
+		}
+	}
+
+	done := make(map[string]bool, len(p.steps))
+	ordered := make([]*PlanStep, 0, len(p.steps))
+	for len(ordered) < len(p.steps) {
+		progressed := false
+		for _, step := range p.steps {
+			if done[step.name] || !step.ready(done) {
+				continue
+			}
+			done[step.name] = true
+			ordered = append(ordered, step)
+			progressed = true
+			break
+		}
+		if !progressed {
+			var blocked []string
+			for _, step := range p.steps {
+				if !done[step.name] {
+					blocked = append(blocked, step.name)
+				}
+			}
+			return nil, fmt.Errorf("the dependencies of steps %s form a cycle", strings.Join(blocked, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// ready reports whether the steps s depends on are done.
+func (s *PlanStep) ready(done map[string]bool) bool {
+	for _, name := range s.after {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// Execute submits the steps of the plan with contract, calling progress, which
+// may be nil, as each step changes phase. It returns the results of the
+// committed steps by name, and a *PlanError when a step fails. Compensating
+// transactions run even when ctx is done, as ctx may be what failed the step.
+func (p *Plan) Execute(ctx context.Context, contract Submitter, progress func(PlanProgress)) (map[string][]byte, error) {
+	steps, err := p.order()
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = func(PlanProgress) {}
+	}
+
+	results := make(map[string][]byte, len(steps))
+	var committed []*PlanStep
+	for _, step := range steps {
+		progress(PlanProgress{Step: step.name, Phase: PhaseStarted, Committed: len(committed), Total: len(steps)})
+		result, err := contract.SubmitWithContext(ctx, step.call.function, step.call.options()...)
+		if err != nil {
+			progress(PlanProgress{Step: step.name, Phase: PhaseFailed, Committed: len(committed), Total: len(steps), Err: err})
+			planErr := &PlanError{Step: step.name, Err: err}
+			if p.rollback == RollbackCompensate {
+				p.compensate(context.WithoutCancel(ctx), contract, committed, len(steps), progress, planErr)
+			}
+			return results, planErr
+		}
+		results[step.name] = result
+		committed = append(committed, step)
+		progress(PlanProgress{Step: step.name, Phase: PhaseCommitted, Committed: len(committed), Total: len(steps)})
+	}
+	return results, nil
+}
+
+// compensate undoes the committed steps, most recent first, recording the
+// outcome in planErr.
+func (p *Plan) compensate(ctx context.Context, contract Submitter, committed []*PlanStep, total int, progress func(PlanProgress), planErr *PlanError) {
+	var errs []error
+	for i := len(committed) - 1; i >= 0; i-- {
+		step := committed[i]
+		if step.compensate == nil {
+			continue
+		}
+		progress(PlanProgress{Step: step.name, Phase: PhaseCompensating, Committed: len(committed), Total: total})
+		if _, err := contract.SubmitWithContext(ctx, step.compensate.function, step.compensate.options()...); err != nil {
+			progress(PlanProgress{Step: step.name, Phase: PhaseCompensationFailed, Committed: len(committed), Total: total, Err: err})
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		planErr.Compensated = append(planErr.Compensated, step.name)
+		progress(PlanProgress{Step: step.name, Phase: PhaseCompensated, Committed: len(committed), Total: total})
+	}
+	planErr.CompensationErr = errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// recordingSubmitter records the functions submitted, failing those in fail.
+type recordingSubmitter struct {
+	submitted []string
+	fail      map[string]error
+}
+
+func (s *recordingSubmitter) SubmitWithContext(_ context.Context, name string, _ ...client.ProposalOption) ([]byte, error) {
+	s.submitted = append(s.submitted, name)
+	if err := s.fail[name]; err != nil {
+		return nil, err
+	}
+	return []byte(name + " done"), nil
+}
+
+func onboardingPlan() *Plan {
+	return NewPlan().
+		Step("limits", "SetLimits", "asset9", "5000").After("link").
+		Step("create", "CreateAsset", "asset9", "DEALER101", "0", "ACTIVE", "0", "INIT").CompensateWith("DeleteAsset", "asset9").
+		Step("link", "LinkAsset", "asset9", "asset1").After("create").CompensateWith("UnlinkAsset", "asset9", "asset1").
+		Plan()
+}
+
+func TestPlanRunsStepsAfterTheirDependencies(t *testing.T) {
+	contract := &recordingSubmitter{}
+	var phases []PlanPhase
+	results, err := onboardingPlan().Execute(context.Background(), contract, func(progress PlanProgress) {
+		phases = append(phases, progress.Phase)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"CreateAsset", "LinkAsset", "SetLimits"}; !reflect.DeepEqual(contract.submitted, expected) {
+		t.Fatalf("expected %v, got %v", expected, contract.submitted)
+	}
+	if string(results["link"]) != "LinkAsset done" || len(phases) != 6 || phases[5] != PhaseCommitted {
+		t.Fatalf("unexpected results %v and phases %v", results, phases)
+	}
+}
+
+func TestPlanCompensatesCommittedStepsOnFailure(t *testing.T) {
+	refused := errors.New("limit too high")
+	contract := &recordingSubmitter{fail: map[string]error{"SetLimits": refused}}
+	_, err := onboardingPlan().Execute(context.Background(), contract, nil)
+
+	var planErr *PlanError
+	if !errors.As(err, &planErr) || planErr.Step != "limits" || !errors.Is(err, refused) {
+		t.Fatalf("expected the limits step to fail, got %v", err)
+	}
+	if expected := []string{"CreateAsset", "LinkAsset", "SetLimits", "UnlinkAsset", "DeleteAsset"}; !reflect.DeepEqual(contract.submitted, expected) {
+		t.Fatalf("expected %v, got %v", expected, contract.submitted)
+	}
+	if !reflect.DeepEqual(planErr.Compensated, []string{"link", "create"}) || planErr.CompensationErr != nil {
+		t.Fatalf("expected link and create to be compensated, got %v, %v", planErr.Compensated, planErr.CompensationErr)
+	}
+
+	contract = &recordingSubmitter{fail: map[string]error{"SetLimits": refused}}
+	_, err = onboardingPlan().OnFailure(RollbackNone).Execute(context.Background(), contract, nil)
+	if err == nil || len(contract.submitted) != 3 {
+		t.Fatalf("expected no compensation, got %v after %v", err, contract.submitted)
+	}
+}
+
+func TestPlanRejectsCycles(t *testing.T) {
+	plan := NewPlan().
+		Step("a", "A").After("b").
+		Step("b", "B").After("a").
+		Plan()
+	contract := &recordingSubmitter{}
+	if _, err := plan.Execute(context.Background(), contract, nil); err == nil || len(contract.submitted) != 0 {
+		t.Fatalf("expected the cycle to be rejected before submitting, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+)
+
+// AnyIdentity is the policy entry applying to the identities the policy does
+// not list by name.
+const AnyIdentity = "*"
+
+// ErrFunctionNotAllowed is returned for transaction functions the function
+// policy does not allow the signing identity to call. It also matches
+// ErrForbidden.
+var ErrFunctionNotAllowed = errors.New("function not allowed")
+
+// FunctionPolicy restricts the transaction functions each identity of the
+// client may evaluate and submit, checked before anything is sent to a peer. It
+// is a defense in depth against misconfigured clients, such as a reporting
+// service holding an identity that could change the ledger: the chaincode still
+// enforces its own access control. A nil policy allows every function.
+type FunctionPolicy struct {
+	// Identities maps identity names, or AnyIdentity, to their permissions.
+	// Identities without an entry, when there is no AnyIdentity entry, may not
+	// call any function.
+	Identities map[string]FunctionPermissions `json:"identities"`
+}
+
+// FunctionPermissions lists the functions an identity may evaluate and submit,
+// as names or path.Match patterns such as Get*.
+type FunctionPermissions struct {
+	Evaluate []string `json:"evaluate"`
+	Submit   []string `json:"submit"`
+}
+
+// FunctionPolicyError is a call rejected by the function policy.
+type FunctionPolicyError struct {
+	Identity string
+	Function string
+	Submit   bool
+}
+
+func (e *FunctionPolicyError) Error() string {
+	mode := "evaluate"
+	if e.Submit {
+		mode = "submit"
+	}
+	return fmt.Sprintf("identity %s is not allowed to %s %s", e.Identity, mode, e.Function)
+}
+
+// Is matches ErrFunctionNotAllowed and ErrForbidden.
+func (e *FunctionPolicyError) Is(target error) bool {
+	return target == ErrFunctionNotAllowed || target == ErrForbidden
+}
+
+// LoadFunctionPolicy reads a policy from a JSON file, such as
+//
+//	{"identities": {"reporting": {"evaluate": ["Get*", "ReadAsset"]}, "*": {"evaluate": ["*"], "submit": ["*"]}}}
+func LoadFunctionPolicy(file string) (*FunctionPolicy, error) {
+	policyJSON, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read function policy: %w", err)
+	}
+	var policy FunctionPolicy
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse function policy %s: %w", file, err)
+	}
+	for identity, permissions := range policy.Identities {
+		for _, pattern := range append(append([]string{}, permissions.Evaluate...), permissions.Submit...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q of identity %s in function policy %s: %w", pattern, identity, file, err)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// FunctionPolicyFromEnv loads the policy of the file named by FUNCTION_POLICY,
+// or returns a nil policy allowing every function when it is not set.
+func FunctionPolicyFromEnv() (*FunctionPolicy, error) {
+	file := os.Getenv("FUNCTION_POLICY")
+	if file == "" {
+		return nil, nil
+	}
+	return LoadFunctionPolicy(file)
+}
+
+// Check returns a *FunctionPolicyError unless identity may call function,
+// submitting it when submit is set.
+func (p *FunctionPolicy) Check(identity string, function string, submit bool) error {
+	if p == nil {
+		return nil
+	}
+	permissions, ok := p.Identities[identity]
+	if !ok {
+		permissions, ok = p.Identities[AnyIdentity]
+	}
+	patterns := permissions.Evaluate
+	if submit {
+		patterns = permissions.Submit
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, function); ok && matched {
+			return nil
+		}
+	}
+	return &FunctionPolicyError{Identity: identity, Function: function, Submit: submit}
+}
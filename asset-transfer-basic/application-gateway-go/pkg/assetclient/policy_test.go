@@ -0,0 +1,58 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFunctionPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.json")
+	policyJSON := `{"identities": {
+		"reporting": {"evaluate": ["Get*", "ReadAsset"]},
+		"*": {"evaluate": ["*"], "submit": ["CreateAsset", "UpdateAsset"]}
+	}}`
+	if err := os.WriteFile(file, []byte(policyJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadFunctionPolicy(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := []struct {
+		identity, function string
+		submit             bool
+	}{
+		{"reporting", "GetTotals", false},
+		{"reporting", "ReadAsset", false},
+		{"dealer", "ReadAssetDetails", false},
+		{"dealer", "UpdateAsset", true},
+	}
+	for _, call := range allowed {
+		if err := policy.Check(call.identity, call.function, call.submit); err != nil {
+			t.Errorf("expected %s to be allowed %s: %v", call.identity, call.function, err)
+		}
+	}
+
+	err = policy.Check("reporting", "UpdateAsset", true)
+	if !errors.Is(err, ErrFunctionNotAllowed) || HTTPStatus(err) != http.StatusForbidden {
+		t.Fatalf("expected the reporting identity to be denied submitting, got %v", err)
+	}
+	if err := policy.Check("reporting", "ReadAssetDetails", false); err == nil {
+		t.Fatal("expected a function the identity's entry does not list to be denied")
+	}
+	if err := policy.Check("dealer", "DeleteAsset", true); err == nil {
+		t.Fatal("expected a function the wildcard entry does not list to be denied")
+	}
+	var nilPolicy *FunctionPolicy
+	if err := nilPolicy.Check("anyone", "DeleteAsset", true); err != nil {
+		t.Fatalf("expected a nil policy to allow everything, got %v", err)
+	}
+}
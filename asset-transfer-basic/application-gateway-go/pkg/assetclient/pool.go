@@ -0,0 +1,258 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package assetclient contains the client side building blocks shared by the
+// gateway application and the REST server of the asset transfer sample.
+//
+// Unless documented otherwise, the types of the package are safe for concurrent
+// use by multiple goroutines once created, so that a server shares one instance
+// of each across its requests: a Client per signing identity, with its
+// EventSessions and CheckpointWriter, and one ConnectionManager, ReplicaRouter,
+// TxStatusStore or Discoverer per organization. The exported fields of
+// configuration structs, such as TLSOptions or GeneratorConfig, must not be
+// changed once in use. An AssetIterator belongs to the goroutine iterating it.
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultPoolSize    = 4
+	defaultIdleTimeout = 5 * time.Minute
+)
+
+// ErrPoolClosed is returned by calls made on a closed ConnectionPool.
+var ErrPoolClosed = errors.New("connection pool is closed")
+
+// PoolOption configures a ConnectionManager.
+type PoolOption func(*poolConfig)
+
+type poolConfig struct {
+	size        int
+	idleTimeout time.Duration
+}
+
+// WithPoolSize sets the number of gRPC connections kept per peer.
+func WithPoolSize(size int) PoolOption {
+	return func(config *poolConfig) {
+		if size > 0 {
+			config.size = size
+		}
+	}
+}
+
+// WithIdleTimeout sets how long a connection may stay unused before it is closed.
+// Closed connections are dialled again on their next use. Zero disables reaping.
+func WithIdleTimeout(timeout time.Duration) PoolOption {
+	return func(config *poolConfig) {
+		config.idleTimeout = timeout
+	}
+}
+
+// ConnectionManager maintains one ConnectionPool per peer endpoint.
+type ConnectionManager struct {
+	config poolConfig
+
+	mu    sync.Mutex
+	pools map[string]*ConnectionPool
+}
+
+// NewConnectionManager creates a manager whose pools use the given options.
+func NewConnectionManager(options ...PoolOption) *ConnectionManager {
+	config := poolConfig{size: defaultPoolSize, idleTimeout: defaultIdleTimeout}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return &ConnectionManager{config: config, pools: make(map[string]*ConnectionPool)}
+}
+
+// Pool returns the pool of connections to target, creating it with dialOptions on first use.
+func (m *ConnectionManager) Pool(target string, dialOptions ...grpc.DialOption) *ConnectionPool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pool, ok := m.pools[target]
+	if !ok {
+		pool = newConnectionPool(m.config, func() (*grpc.ClientConn, error) {
+			return grpc.NewClient(target, dialOptions...)
+		})
+		m.pools[target] = pool
+	}
+	return pool
+}
+
+// Close closes every pool of the manager.
+func (m *ConnectionManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for target, pool := range m.pools {
+		errs = append(errs, pool.Close())
+		delete(m.pools, target)
+	}
+	return errors.Join(errs...)
+}
+
+// ConnectionPool spreads calls to a peer over several gRPC connections, assigned
+// round-robin. It implements grpc.ClientConnInterface so it can be passed to
+// client.WithClientConnection in place of a single *grpc.ClientConn.
+type ConnectionPool struct {
+	dial        func() (*grpc.ClientConn, error)
+	idleTimeout time.Duration
+	slots       []*poolSlot
+	next        atomic.Uint64
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+type poolSlot struct {
+	mu       sync.Mutex
+	conn     *grpc.ClientConn
+	active   int
+	lastUsed time.Time
+	closed   bool
+}
+
+func newConnectionPool(config poolConfig, dial func() (*grpc.ClientConn, error)) *ConnectionPool {
+	pool := &ConnectionPool{
+		dial:        dial,
+		idleTimeout: config.idleTimeout,
+		slots:       make([]*poolSlot, config.size),
+		done:        make(chan struct{}),
+	}
+	for i := range pool.slots {
+		pool.slots[i] = &poolSlot{}
+	}
+
+	if pool.idleTimeout > 0 {
+		go pool.reapLoop()
+	}
+	return pool
+}
+
+// Invoke performs a unary RPC on the next connection of the pool.
+func (p *ConnectionPool) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	slot, conn, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	defer slot.release()
+
+	return conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream begins a streaming RPC on the next connection of the pool. The
+// connection is not reaped while the stream is receiving messages.
+func (p *ConnectionPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	slot, conn, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := conn.NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		slot.release()
+		return nil, err
+	}
+	return &pooledStream{ClientStream: stream, slot: slot}, nil
+}
+
+// Close stops reaping and closes every connection of the pool.
+func (p *ConnectionPool) Close() error {
+	var errs []error
+	p.closeOnce.Do(func() {
+		close(p.done)
+		for _, slot := range p.slots {
+			slot.mu.Lock()
+			slot.closed = true
+			if slot.conn != nil {
+				errs = append(errs, slot.conn.Close())
+				slot.conn = nil
+			}
+			slot.mu.Unlock()
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// acquire picks the next slot round-robin, dialling its connection if needed.
+func (p *ConnectionPool) acquire() (*poolSlot, *grpc.ClientConn, error) {
+	slot := p.slots[(p.next.Add(1)-1)%uint64(len(p.slots))]
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if slot.closed {
+		return nil, nil, ErrPoolClosed
+	}
+	if slot.conn == nil {
+		conn, err := p.dial()
+		if err != nil {
+			return nil, nil, err
+		}
+		slot.conn = conn
+	}
+	slot.active++
+	slot.lastUsed = time.Now()
+	return slot, slot.conn, nil
+}
+
+func (p *ConnectionPool) reapLoop() {
+	ticker := time.NewTicker(p.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.reap(now)
+		}
+	}
+}
+
+// reap closes the connections that have no call in flight and were last used
+// more than the idle timeout before now.
+func (p *ConnectionPool) reap(now time.Time) {
+	for _, slot := range p.slots {
+		slot.mu.Lock()
+		if slot.conn != nil && slot.active == 0 && now.Sub(slot.lastUsed) > p.idleTimeout {
+			slot.conn.Close()
+			slot.conn = nil
+		}
+		slot.mu.Unlock()
+	}
+}
+
+func (s *poolSlot) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	s.lastUsed = time.Now()
+}
+
+// pooledStream releases its pool slot once the stream has finished.
+type pooledStream struct {
+	grpc.ClientStream
+	slot     *poolSlot
+	released sync.Once
+}
+
+func (s *pooledStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.released.Do(s.slot.release)
+	}
+	return err
+}
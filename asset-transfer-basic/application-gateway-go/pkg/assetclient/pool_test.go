@@ -0,0 +1,103 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newHealthServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestConnectionPoolRoundRobin(t *testing.T) {
+	target := newHealthServer(t)
+	manager := NewConnectionManager(WithPoolSize(3), WithIdleTimeout(0))
+	defer manager.Close()
+
+	pool := manager.Pool(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if manager.Pool(target) != pool {
+		t.Fatal("expected the same pool for the same target")
+	}
+
+	healthClient := healthpb.NewHealthClient(pool)
+	for i := 0; i < 3; i++ {
+		if _, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, slot := range pool.slots {
+		if slot.conn == nil {
+			t.Errorf("slot %d was not used", i)
+		}
+		if slot.active != 0 {
+			t.Errorf("slot %d has %d calls in flight", i, slot.active)
+		}
+	}
+}
+
+func TestConnectionPoolReapsIdleConnections(t *testing.T) {
+	target := newHealthServer(t)
+	manager := NewConnectionManager(WithPoolSize(2), WithIdleTimeout(time.Hour))
+	defer manager.Close()
+
+	pool := manager.Pool(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	healthClient := healthpb.NewHealthClient(pool)
+	if _, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	pool.reap(time.Now())
+	if pool.slots[0].conn == nil {
+		t.Fatal("connection reaped before the idle timeout")
+	}
+
+	pool.reap(time.Now().Add(2 * time.Hour))
+	if pool.slots[0].conn != nil {
+		t.Fatal("idle connection was not reaped")
+	}
+
+	// the reaped slot dials again on its next turn
+	for i := 0; i < 2; i++ {
+		if _, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pool.slots[0].conn == nil {
+		t.Fatal("reaped slot was not dialled again")
+	}
+}
+
+func TestConnectionPoolClosed(t *testing.T) {
+	manager := NewConnectionManager(WithPoolSize(1))
+	pool := manager.Pool("127.0.0.1:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err := manager.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := pool.Invoke(context.Background(), "/grpc.health.v1.Health/Check", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{})
+	if err != ErrPoolClosed {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
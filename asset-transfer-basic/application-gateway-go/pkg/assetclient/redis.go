@@ -0,0 +1,257 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisStateStore is a StateStore kept in Redis, speaking its RESP protocol
+// over one connection, opened again after a failure. Keys are prefixed with
+// Prefix.
+type RedisStateStore struct {
+	// Address is the host:port of the server.
+	Address  string
+	Username string
+	Password string
+	Database int
+	// TLS connects over TLS with this configuration when set.
+	TLS    *tls.Config
+	Prefix string
+
+	lock   sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// RedisStateStoreFromURL returns the store of a
+// redis://[user:password@]host:port/database?prefix=p URL, rediss:// connecting
+// over TLS.
+func RedisStateStoreFromURL(rawURL string) (*RedisStateStore, error) {
+	location, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if (location.Scheme != "redis" && location.Scheme != "rediss") || location.Host == "" {
+		return nil, errors.New("invalid Redis URL, expected redis://host:port/database")
+	}
+	store := &RedisStateStore{Address: location.Host, Prefix: location.Query().Get("prefix")}
+	if location.Port() == "" {
+		store.Address = net.JoinHostPort(location.Hostname(), "6379")
+	}
+	if location.User != nil {
+		store.Username = location.User.Username()
+		store.Password, _ = location.User.Password()
+	}
+	if database := strings.Trim(location.Path, "/"); database != "" {
+		if store.Database, err = strconv.Atoi(database); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", database)
+		}
+	}
+	if location.Scheme == "rediss" {
+		store.TLS = &tls.Config{ServerName: location.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	return store, nil
+}
+
+func (s *RedisStateStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", s.Prefix+key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected Redis reply %v to GET", reply)
+	}
+	return value, true, nil
+}
+
+func (s *RedisStateStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.do(ctx, redisSet(s.Prefix+key, value, ttl)...)
+	return err
+}
+
+func (s *RedisStateStore) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	reply, err := s.do(ctx, append(redisSet(s.Prefix+key, value, ttl), "NX")...)
+	return reply != nil, err
+}
+
+func (s *RedisStateStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.Prefix+key)
+	return err
+}
+
+// Close closes the connection to the server.
+func (s *RedisStateStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// redisSet returns the SET command of a value, expiring after ttl if positive.
+func redisSet(key string, value []byte, ttl time.Duration) []any {
+	command := []any{"SET", key, value}
+	if ttl > 0 {
+		command = append(command, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	return command
+}
+
+// do sends a command and returns its reply: nil, a string for a status, an
+// int64, a []byte for a bulk string or a []any for an array. Replies of the
+// server reporting an error are returned as a RedisError.
+func (s *RedisStateStore) do(ctx context.Context, command ...any) (any, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := s.roundTrip(ctx, command)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// the connection is in an unknown state
+		s.conn.Close()
+		s.conn = nil
+	}
+	return reply, err
+}
+
+// connect opens the connection, authenticating and selecting the database.
+func (s *RedisStateStore) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if s.TLS != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: s.TLS}).DialContext(ctx, "tcp", s.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis at %s: %w", s.Address, err)
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+
+	var setup [][]any
+	switch {
+	case s.Username != "":
+		setup = append(setup, []any{"AUTH", s.Username, s.Password})
+	case s.Password != "":
+		setup = append(setup, []any{"AUTH", s.Password})
+	}
+	if s.Database != 0 {
+		setup = append(setup, []any{"SELECT", strconv.Itoa(s.Database)})
+	}
+	for _, command := range setup {
+		if _, err := s.roundTrip(ctx, command); err != nil {
+			conn.Close()
+			s.conn = nil
+			return fmt.Errorf("failed to set up the Redis connection: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *RedisStateStore) roundTrip(ctx context.Context, command []any) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if _, err := s.conn.Write(encodeRedisCommand(command)); err != nil {
+		return nil, err
+	}
+	return readRedisReply(s.reader)
+}
+
+// RedisError is an error reply of a Redis server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// encodeRedisCommand encodes a command as an array of bulk strings.
+func encodeRedisCommand(command []any) []byte {
+	encoded := []byte("*" + strconv.Itoa(len(command)) + "\r\n")
+	for _, argument := range command {
+		var value []byte
+		switch argument := argument.(type) {
+		case string:
+			value = []byte(argument)
+		case []byte:
+			value = argument
+		default:
+			value = []byte(fmt.Sprint(argument))
+		}
+		encoded = append(encoded, "$"+strconv.Itoa(len(value))+"\r\n"...)
+		encoded = append(encoded, value...)
+		encoded = append(encoded, "\r\n"...)
+	}
+	return encoded
+}
+
+// readRedisReply reads a RESP2 reply.
+func readRedisReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed Redis reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RedisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(payload)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		value := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		return value[:length], nil
+	case '*':
+		length, err := strconv.Atoi(payload)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		values := make([]any, length)
+		for i := range values {
+			if values[i], err = readRedisReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("malformed Redis reply %q", line)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves GET, SET with NX and PX, and DEL from memory, requiring the
+// password secret.
+type fakeRedis struct {
+	lock    sync.Mutex
+	values  map[string]string
+	ttls    map[string]string
+	clients int
+}
+
+func (f *fakeRedis) serve(t *testing.T, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		f.lock.Lock()
+		f.clients++
+		f.lock.Unlock()
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			authenticated := false
+			for {
+				request, err := readRedisReply(reader)
+				if err != nil {
+					return
+				}
+				var command []string
+				for _, argument := range request.([]any) {
+					command = append(command, string(argument.([]byte)))
+				}
+				fmt.Fprint(conn, f.reply(command, &authenticated))
+			}
+		}()
+	}
+}
+
+func (f *fakeRedis) reply(command []string, authenticated *bool) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	switch {
+	case command[0] == "AUTH" && command[len(command)-1] == "secret":
+		*authenticated = true
+		return "+OK\r\n"
+	case !*authenticated:
+		return "-NOAUTH Authentication required.\r\n"
+	case command[0] == "GET":
+		value, ok := f.values[command[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case command[0] == "SET":
+		options := strings.Join(command[3:], " ")
+		if _, ok := f.values[command[1]]; ok && strings.Contains(options, "NX") {
+			return "$-1\r\n"
+		}
+		f.values[command[1]], f.ttls[command[1]] = command[2], options
+		return "+OK\r\n"
+	case command[0] == "DEL":
+		delete(f.values, command[1])
+		return ":1\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func TestRedisStateStore(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	server := &fakeRedis{values: map[string]string{}, ttls: map[string]string{}}
+	go server.serve(t, listener)
+
+	store, err := StateStoreFor("redis://:secret@" + listener.Addr().String() + "?prefix=rest:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redisStore := store.(*RedisStateStore)
+	defer redisStore.Close()
+	ctx := context.Background()
+
+	if ok, err := store.PutIfAbsent(ctx, "idempotency/k1", []byte("pending\r\n"), time.Hour); err != nil || !ok {
+		t.Fatalf("expected the key to be claimed, got %v, %v", ok, err)
+	}
+	if ok, err := store.PutIfAbsent(ctx, "idempotency/k1", []byte("other"), time.Hour); err != nil || ok {
+		t.Fatalf("expected the key to be claimed already, got %v, %v", ok, err)
+	}
+	if server.ttls["rest:idempotency/k1"] != "PX 3600000 NX" {
+		t.Fatalf("unexpected options %q", server.ttls["rest:idempotency/k1"])
+	}
+	if value, ok, err := store.Get(ctx, "idempotency/k1"); err != nil || !ok || string(value) != "pending\r\n" {
+		t.Fatalf("unexpected value %q, %v, %v", value, ok, err)
+	}
+	if err := store.Delete(ctx, "idempotency/k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := store.Get(ctx, "idempotency/k1"); err != nil || ok {
+		t.Fatalf("expected the key to be deleted, got %v, %v", ok, err)
+	}
+
+	// a dropped connection is opened again and authenticated
+	redisStore.conn.Close()
+	if err := store.Put(ctx, "checkpoint", []byte("12"), 0); err == nil {
+		t.Fatal("expected the command on the closed connection to fail")
+	}
+	if err := store.Put(ctx, "checkpoint", []byte("12"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if server.clients != 2 {
+		t.Fatalf("expected a second connection, got %d", server.clients)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// ReplayTransaction is a transaction of the chaincode committed in a block,
+// with the function and arguments it was submitted with.
+type ReplayTransaction struct {
+	BlockNumber   uint64    `json:"blockNumber"`
+	TransactionID string    `json:"transactionId"`
+	Timestamp     time.Time `json:"timestamp"`
+	Function      string    `json:"function"`
+	Args          []string  `json:"args"`
+}
+
+// BlockTransactions returns the valid transactions of a block invoking
+// chaincode, in ledger order. Their transient data is not in the block, so
+// transactions passing MPINs or private data are replayed without it.
+func BlockTransactions(block *common.Block, chaincode string) ([]ReplayTransaction, error) {
+	number := block.GetHeader().GetNumber()
+	envelopes := block.GetData().GetData()
+	metadata := block.GetMetadata().GetMetadata()
+	if len(metadata) <= int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) || len(metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]) < len(envelopes) {
+		return nil, fmt.Errorf("block %d lacks the validation codes of its transactions", number)
+	}
+	validationCodes := metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+
+	var transactions []ReplayTransaction
+	for i, envelopeBytes := range envelopes {
+		if peer.TxValidationCode(validationCodes[i]) != peer.TxValidationCode_VALID {
+			continue
+		}
+		var envelope common.Envelope
+		if err := proto.Unmarshal(envelopeBytes, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction %d of block %d: %w", i, number, err)
+		}
+		var payload common.Payload
+		if err := proto.Unmarshal(envelope.GetPayload(), &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse payload of transaction %d of block %d: %w", i, number, err)
+		}
+		var channelHeader common.ChannelHeader
+		if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), &channelHeader); err != nil {
+			return nil, fmt.Errorf("failed to parse channel header of transaction %d of block %d: %w", i, number, err)
+		}
+		if common.HeaderType(channelHeader.GetType()) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue
+		}
+		var extension peer.ChaincodeHeaderExtension
+		if err := proto.Unmarshal(channelHeader.GetExtension(), &extension); err != nil {
+			return nil, fmt.Errorf("failed to parse chaincode header of transaction %s: %w", channelHeader.GetTxId(), err)
+		}
+		if extension.GetChaincodeId().GetName() != chaincode {
+			continue
+		}
+		var tx peer.Transaction
+		if err := proto.Unmarshal(payload.GetData(), &tx); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction %s: %w", channelHeader.GetTxId(), err)
+		}
+		if len(tx.GetActions()) == 0 {
+			continue
+		}
+		args, err := invocationArgs(tx.GetActions()[0])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", channelHeader.GetTxId(), err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		transaction := ReplayTransaction{
+			BlockNumber:   number,
+			TransactionID: channelHeader.GetTxId(),
+			Timestamp:     channelHeader.GetTimestamp().AsTime(),
+			Function:      string(args[0]),
+			Args:          make([]string, 0, len(args)-1),
+		}
+		for _, arg := range args[1:] {
+			transaction.Args = append(transaction.Args, string(arg))
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// invocationArgs returns the function and arguments of the chaincode
+// invocation proposed in an action of a transaction.
+func invocationArgs(txAction *peer.TransactionAction) ([][]byte, error) {
+	var actionPayload peer.ChaincodeActionPayload
+	if err := proto.Unmarshal(txAction.GetPayload(), &actionPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse chaincode action payload: %w", err)
+	}
+	var proposalPayload peer.ChaincodeProposalPayload
+	if err := proto.Unmarshal(actionPayload.GetChaincodeProposalPayload(), &proposalPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse proposal payload: %w", err)
+	}
+	var invocation peer.ChaincodeInvocationSpec
+	if err := proto.Unmarshal(proposalPayload.GetInput(), &invocation); err != nil {
+		return nil, fmt.Errorf("failed to parse chaincode invocation: %w", err)
+	}
+	return invocation.GetChaincodeSpec().GetInput().GetArgs(), nil
+}
+
+// ReplayTransform selects and rewrites the transactions of a replay, such as
+// to call the renamed functions of a new chaincode version. Only the functions
+// of Only are replayed if set, and the functions of Skip never.
+type ReplayTransform struct {
+	Only   []string          `json:"only,omitempty"`
+	Skip   []string          `json:"skip,omitempty"`
+	Rename map[string]string `json:"rename,omitempty"`
+	// Args maps functions to the arguments they are replayed with, each either
+	// an argument of the original transaction, such as "$1" for the first, or
+	// a literal value.
+	Args map[string][]string `json:"args,omitempty"`
+}
+
+// ReadReplayTransform reads a ReplayTransform from a JSON file.
+func ReadReplayTransform(path string) (*ReplayTransform, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay transform: %w", err)
+	}
+	var transform ReplayTransform
+	if err := json.Unmarshal(content, &transform); err != nil {
+		return nil, fmt.Errorf("failed to parse replay transform %s: %w", path, err)
+	}
+	return &transform, nil
+}
+
+// Apply returns the transaction to replay for transaction, and false if it is
+// not replayed. A nil transform replays every transaction as it was.
+func (t *ReplayTransform) Apply(transaction ReplayTransaction) (ReplayTransaction, bool, error) {
+	if t == nil {
+		return transaction, true, nil
+	}
+	if (len(t.Only) > 0 && !slices.Contains(t.Only, transaction.Function)) || slices.Contains(t.Skip, transaction.Function) {
+		return transaction, false, nil
+	}
+
+	result := transaction
+	if templates, ok := t.Args[transaction.Function]; ok {
+		result.Args = make([]string, 0, len(templates))
+		for _, template := range templates {
+			var index int
+			if _, err := fmt.Sscanf(template, "$%d", &index); err != nil || fmt.Sprintf("$%d", index) != template {
+				result.Args = append(result.Args, template)
+				continue
+			}
+			if index < 1 || index > len(transaction.Args) {
+				return transaction, false, fmt.Errorf("transaction %s of %s has no argument %s", transaction.TransactionID, transaction.Function, template)
+			}
+			result.Args = append(result.Args, transaction.Args[index-1])
+		}
+	}
+	if name, ok := t.Rename[transaction.Function]; ok {
+		result.Function = name
+	}
+	return result, true, nil
+}
+
+// ReplayClock spaces the transactions of a replay as they were originally
+// committed, Speed times faster. A Speed of 0 does not wait.
+type ReplayClock struct {
+	Speed float64
+
+	first time.Time
+	start time.Time
+}
+
+// Delay returns how long to wait at now before replaying a transaction
+// originally submitted at timestamp.
+func (c *ReplayClock) Delay(timestamp time.Time, now time.Time) time.Duration {
+	if c.Speed <= 0 {
+		return 0
+	}
+	if c.start.IsZero() {
+		c.first, c.start = timestamp, now
+		return 0
+	}
+	due := c.start.Add(time.Duration(float64(timestamp.Sub(c.first)) / c.Speed))
+	return max(due.Sub(now), 0)
+}
+
+// Wait waits until a transaction originally submitted at timestamp is due.
+func (c *ReplayClock) Wait(ctx context.Context, timestamp time.Time) error {
+	delay := c.Delay(timestamp, time.Now())
+	if delay == 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// endorserTransaction returns the envelope of a transaction invoking chaincode.
+func endorserTransaction(t *testing.T, txID string, chaincode string, timestamp time.Time, args ...string) []byte {
+	marshal := func(message proto.Message) []byte {
+		t.Helper()
+		bytes, err := proto.Marshal(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes
+	}
+	input := &peer.ChaincodeInput{}
+	for _, arg := range args {
+		input.Args = append(input.Args, []byte(arg))
+	}
+	invocation := &peer.ChaincodeInvocationSpec{ChaincodeSpec: &peer.ChaincodeSpec{Input: input}}
+	actionPayload := &peer.ChaincodeActionPayload{
+		ChaincodeProposalPayload: marshal(&peer.ChaincodeProposalPayload{Input: marshal(invocation)}),
+	}
+	transaction := &peer.Transaction{Actions: []*peer.TransactionAction{{Payload: marshal(actionPayload)}}}
+	channelHeader := &common.ChannelHeader{
+		Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+		TxId:      txID,
+		Timestamp: timestamppb.New(timestamp),
+		Extension: marshal(&peer.ChaincodeHeaderExtension{ChaincodeId: &peer.ChaincodeID{Name: chaincode}}),
+	}
+	payload := &common.Payload{Header: &common.Header{ChannelHeader: marshal(channelHeader)}, Data: marshal(transaction)}
+	return marshal(&common.Envelope{Payload: marshal(payload)})
+}
+
+func TestBlockTransactions(t *testing.T) {
+	at := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	block := &common.Block{
+		Header: &common.BlockHeader{Number: 7},
+		Data: &common.BlockData{Data: [][]byte{
+			endorserTransaction(t, "tx1", "basic", at, "CreateAsset", "asset1", "DEALER101"),
+			endorserTransaction(t, "tx2", "basic", at, "TransferAsset", "asset1", "DEALER102"),
+			endorserTransaction(t, "tx3", "other", at, "CreateAsset", "asset2"),
+			endorserTransaction(t, "tx4", "basic", at.Add(time.Second), "DeleteAsset", "asset1"),
+		}},
+		Metadata: &common.BlockMetadata{Metadata: [][]byte{
+			{}, {},
+			{byte(peer.TxValidationCode_VALID), byte(peer.TxValidationCode_MVCC_READ_CONFLICT), byte(peer.TxValidationCode_VALID), byte(peer.TxValidationCode_VALID)},
+		}},
+	}
+
+	transactions, err := BlockTransactions(block, "basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ReplayTransaction{
+		{BlockNumber: 7, TransactionID: "tx1", Timestamp: at, Function: "CreateAsset", Args: []string{"asset1", "DEALER101"}},
+		{BlockNumber: 7, TransactionID: "tx4", Timestamp: at.Add(time.Second), Function: "DeleteAsset", Args: []string{"asset1"}},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, transactions)
+	}
+}
+
+func TestReplayTransformApply(t *testing.T) {
+	transform := &ReplayTransform{
+		Skip:   []string{"DeleteAsset"},
+		Rename: map[string]string{"TransferAsset": "TransferAssetV2"},
+		Args:   map[string][]string{"TransferAsset": {"$2", "$1", "replayed"}},
+	}
+
+	transfer := ReplayTransaction{TransactionID: "tx1", Function: "TransferAsset", Args: []string{"asset1", "DEALER102"}}
+	replayed, ok, err := transform.Apply(transfer)
+	if err != nil || !ok {
+		t.Fatalf("expected the transfer to be replayed, got %t, %v", ok, err)
+	}
+	if replayed.Function != "TransferAssetV2" || !reflect.DeepEqual(replayed.Args, []string{"DEALER102", "asset1", "replayed"}) {
+		t.Fatalf("unexpected replayed transaction %+v", replayed)
+	}
+	if !reflect.DeepEqual(transfer.Args, []string{"asset1", "DEALER102"}) {
+		t.Fatal("expected the original transaction to be left as it was")
+	}
+
+	if _, ok, _ := transform.Apply(ReplayTransaction{Function: "DeleteAsset"}); ok {
+		t.Fatal("expected skipped functions not to be replayed")
+	}
+	if _, _, err := transform.Apply(ReplayTransaction{Function: "TransferAsset", Args: []string{"asset1"}}); err == nil {
+		t.Fatal("expected a missing argument to be reported")
+	}
+	if _, ok, _ := (&ReplayTransform{Only: []string{"CreateAsset"}}).Apply(transfer); ok {
+		t.Fatal("expected only the selected functions to be replayed")
+	}
+}
+
+func TestReplayClockDelay(t *testing.T) {
+	committed := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	clock := &ReplayClock{Speed: 2}
+	if delay := clock.Delay(committed, now); delay != 0 {
+		t.Fatalf("expected the first transaction not to wait, got %s", delay)
+	}
+	if delay := clock.Delay(committed.Add(10*time.Second), now.Add(time.Second)); delay != 4*time.Second {
+		t.Fatalf("expected to wait 4s, got %s", delay)
+	}
+	if delay := clock.Delay(committed.Add(10*time.Second), now.Add(6*time.Second)); delay != 0 {
+		t.Fatalf("expected a late transaction not to wait, got %s", delay)
+	}
+	if delay := (&ReplayClock{}).Delay(committed, now); delay != 0 {
+		t.Fatalf("expected an unpaced replay not to wait, got %s", delay)
+	}
+}
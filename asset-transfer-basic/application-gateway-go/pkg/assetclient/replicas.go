@@ -0,0 +1,154 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// replicaCooldown is how long a read replica that failed with Unavailable is skipped.
+const replicaCooldown = 30 * time.Second
+
+// Replica is a read peer that serves evaluated transactions.
+type Replica struct {
+	// Name identifies the replica in logs, typically its endpoint.
+	Name    string
+	Gateway *client.Gateway
+	// HealthURL is the health endpoint of the peer's operations service, such as
+	// http://peer1.org1.example.com:9444/healthz. Without one the replica is only
+	// taken out of rotation when calls to it fail.
+	HealthURL string
+
+	unhealthy atomic.Bool
+	downUntil atomic.Int64
+}
+
+// available reports whether the replica passed its last health check and is not cooling down.
+func (r *Replica) available(now time.Time) bool {
+	return !r.unhealthy.Load() && now.UnixNano() >= r.downUntil.Load()
+}
+
+// ReplicaRouter sends evaluated transactions to read replicas, round-robin over
+// the healthy ones, while submitted transactions stay on the submit Gateway. It
+// falls back to the submit Gateway when no replica is available.
+type ReplicaRouter struct {
+	submit     *client.Gateway
+	replicas   []*Replica
+	next       atomic.Uint64
+	httpClient *http.Client
+}
+
+// NewReplicaRouter creates a router evaluating on replicas and submitting on submit.
+func NewReplicaRouter(submit *client.Gateway, replicas ...*Replica) *ReplicaRouter {
+	return &ReplicaRouter{
+		submit:     submit,
+		replicas:   replicas,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// SubmitGateway returns the Gateway used for endorsing and submitting transactions.
+func (r *ReplicaRouter) SubmitGateway() *client.Gateway {
+	return r.submit
+}
+
+// ReadGateway returns the Gateway of the next available replica, or the submit
+// Gateway when none is, for a series of evaluations to be served by one peer,
+// such as those of a PinnedEvaluator.
+func (r *ReplicaRouter) ReadGateway() *client.Gateway {
+	if available := r.availableReplicas(time.Now()); len(available) > 0 {
+		return available[0].Gateway
+	}
+	return r.submit
+}
+
+// Evaluate evaluates a transaction on the next available replica. A replica
+// failing with Unavailable is skipped for a while and the call moves on to the
+// next one, then to the submit Gateway.
+func (r *ReplicaRouter) Evaluate(ctx context.Context, channel string, chaincode string, function string, options ...client.ProposalOption) ([]byte, error) {
+	for _, replica := range r.availableReplicas(time.Now()) {
+		result, err := replica.Gateway.GetNetwork(channel).GetContract(chaincode).EvaluateWithContext(ctx, function, options...)
+		if status.Code(err) != codes.Unavailable {
+			return result, err
+		}
+		replica.downUntil.Store(time.Now().Add(replicaCooldown).UnixNano())
+	}
+
+	return r.submit.GetNetwork(channel).GetContract(chaincode).EvaluateWithContext(ctx, function, options...)
+}
+
+// availableReplicas returns the available replicas, starting with the next one in turn.
+func (r *ReplicaRouter) availableReplicas(now time.Time) []*Replica {
+	if len(r.replicas) == 0 {
+		return nil
+	}
+
+	start := int((r.next.Add(1) - 1) % uint64(len(r.replicas)))
+	var available []*Replica
+	for i := range r.replicas {
+		replica := r.replicas[(start+i)%len(r.replicas)]
+		if replica.available(now) {
+			available = append(available, replica)
+		}
+	}
+	return available
+}
+
+// CheckHealth queries the health endpoint of every replica that has one.
+func (r *ReplicaRouter) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, replica := range r.replicas {
+		if replica.HealthURL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(replica *Replica) {
+			defer wg.Done()
+			replica.unhealthy.Store(r.probe(ctx, replica.HealthURL) != nil)
+		}(replica)
+	}
+	wg.Wait()
+}
+
+// RunHealthChecks checks the health of the replicas every interval until ctx is done.
+func (r *ReplicaRouter) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *ReplicaRouter) probe(ctx context.Context, healthURL string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return errors.New(response.Status)
+	}
+	return nil
+}
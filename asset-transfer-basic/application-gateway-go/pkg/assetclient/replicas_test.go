@@ -0,0 +1,59 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReplicaRouterRoundRobin(t *testing.T) {
+	replicas := []*Replica{{Name: "peer1"}, {Name: "peer2"}, {Name: "peer3"}}
+	router := NewReplicaRouter(nil, replicas...)
+
+	now := time.Now()
+	for i, expected := range []string{"peer1", "peer2", "peer3", "peer1"} {
+		available := router.availableReplicas(now)
+		if len(available) != 3 || available[0].Name != expected {
+			t.Fatalf("call %d: expected %s first of 3 replicas, got %d replicas starting with %s", i, expected, len(available), available[0].Name)
+		}
+	}
+
+	replicas[1].downUntil.Store(now.Add(replicaCooldown).UnixNano())
+	available := router.availableReplicas(now)
+	if len(available) != 2 || available[0].Name != "peer3" || available[1].Name != "peer1" {
+		t.Fatalf("expected peer3 and peer1 while peer2 cools down, got %v", available)
+	}
+	if available := router.availableReplicas(now.Add(2 * replicaCooldown)); len(available) != 3 {
+		t.Fatalf("expected peer2 back after its cooldown, got %d replicas", len(available))
+	}
+}
+
+func TestReplicaRouterCheckHealth(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	replicas := []*Replica{
+		{Name: "healthy", HealthURL: healthy.URL},
+		{Name: "unhealthy", HealthURL: unhealthy.URL},
+		{Name: "unchecked"},
+	}
+	router := NewReplicaRouter(nil, replicas...)
+	router.CheckHealth(context.Background())
+
+	available := router.availableReplicas(time.Now())
+	if len(available) != 2 || available[0].Name != "healthy" || available[1].Name != "unchecked" {
+		t.Fatalf("expected the healthy and unchecked replicas, got %v", available)
+	}
+}
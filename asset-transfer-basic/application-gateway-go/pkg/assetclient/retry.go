@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IsRetryable reports whether a failed Gateway call is an infrastructure error
+// that may succeed when retried, as opposed to a business rejection:
+//   - a retryable chaincode error, such as LEDGER_UNAVAILABLE;
+//   - a transaction invalidated by an MVCC or phantom read conflict;
+//   - an endorsement or evaluation that failed because the peers were
+//     unavailable, overloaded or aborted the call;
+//   - a submission the orderers were unavailable to accept.
+//
+// Chaincode errors with a business code are never retryable. Neither are
+// failures to get the commit status, as the transaction may have committed.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var chaincodeErr *ChaincodeError
+	if errors.As(DecodeChaincodeError(err), &chaincodeErr) {
+		return chaincodeErr.Retryable
+	}
+
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) {
+		return commitErr.Code == peer.TxValidationCode_MVCC_READ_CONFLICT || commitErr.Code == peer.TxValidationCode_PHANTOM_READ_CONFLICT
+	}
+	var commitStatusErr *client.CommitStatusError
+	if errors.As(err, &commitStatusErr) {
+		return false
+	}
+	var submitErr *client.SubmitError
+	if errors.As(err, &submitErr) {
+		return status.Code(submitErr) == codes.Unavailable
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retry calls operation until it succeeds, fails with an error that is not
+// retryable, or has been called attempts times, doubling the delay between
+// calls from backoff. It returns the last error.
+func Retry(ctx context.Context, attempts int, backoff time.Duration, operation func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = operation(ctx); err == nil || attempt >= attempts || !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
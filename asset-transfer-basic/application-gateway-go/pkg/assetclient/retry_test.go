@@ -0,0 +1,79 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"business rejection": {
+			status.Error(codes.Unknown, `chaincode response 500, {"code":"ASSET_NOT_FOUND","message":"the asset asset9 does not exist","retryable":false}`),
+			false,
+		},
+		"ledger failure": {
+			status.Error(codes.Unknown, `chaincode response 500, failed to read from world state: {"code":"LEDGER_UNAVAILABLE","message":"timeout","retryable":true}`),
+			true,
+		},
+		"ledger rejection": {
+			status.Error(codes.Unknown, `chaincode response 500, {"code":"LEDGER_REJECTED","message":"collection missing could not be found","retryable":false}`),
+			false,
+		},
+		"peer outage":         {status.Error(codes.Unavailable, "connection refused"), true},
+		"unknown failure":     {status.Error(codes.Unknown, "chaincode response 500, invalid amount"), false},
+		"mvcc conflict":       {&client.CommitError{Code: peer.TxValidationCode_MVCC_READ_CONFLICT}, true},
+		"policy failure":      {&client.CommitError{Code: peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}, false},
+		"no error":            {nil, false},
+		"cancelled by caller": {status.Error(codes.Canceled, "context canceled"), false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if retryable := IsRetryable(test.err); retryable != test.retryable {
+				t.Fatalf("expected retryable %t, got %t", test.retryable, retryable)
+			}
+		})
+	}
+
+	ledgerErr := DecodeChaincodeError(tests["ledger failure"].err)
+	if !errors.Is(ledgerErr, ErrLedgerUnavailable) {
+		t.Fatalf("expected ErrLedgerUnavailable, got %v", ledgerErr)
+	}
+}
+
+func TestRetryStopsAtBusinessErrors(t *testing.T) {
+	calls := 0
+	businessErr := status.Error(codes.Unknown, `chaincode response 500, {"code":"INSUFFICIENT_FUNDS","message":"low balance","retryable":false}`)
+	err := Retry(context.Background(), 5, time.Millisecond, func(context.Context) error {
+		calls++
+		return businessErr
+	})
+	if err != businessErr || calls != 1 {
+		t.Fatalf("expected a single call returning the business error, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), 3, time.Millisecond, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return status.Error(codes.Unavailable, "peer restarting")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success after 3 calls, got %d calls and %v", calls, err)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// KeyRead is a key read by a transaction, with the version it was read at.
+// A key that did not exist is read at version 0/0.
+type KeyRead struct {
+	Namespace string `json:"namespace"`
+	// Collection is set for private data reads, whose Key is the hex encoded hash of the key.
+	Collection string `json:"collection,omitempty"`
+	Key        string `json:"key"`
+	BlockNum   uint64 `json:"blockNum"`
+	TxNum      uint64 `json:"txNum"`
+}
+
+// KeyWrite is a key written or deleted by a transaction.
+type KeyWrite struct {
+	Namespace string `json:"namespace"`
+	// Collection is set for private data writes, whose Key and Value are the hashes of the key and value.
+	Collection string `json:"collection,omitempty"`
+	Key        string `json:"key"`
+	Value      []byte `json:"value,omitempty"`
+	IsDelete   bool   `json:"isDelete"`
+}
+
+// ReadWriteSet is the read/write set proposed by the endorsement of a transaction.
+type ReadWriteSet struct {
+	Reads  []KeyRead  `json:"reads"`
+	Writes []KeyWrite `json:"writes"`
+}
+
+// ParseReadWriteSet extracts the read/write set from the bytes of an endorsed
+// transaction, as returned by client.Transaction.Bytes.
+func ParseReadWriteSet(transaction []byte) (*ReadWriteSet, error) {
+	action, err := chaincodeAction(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	var txRWSet rwset.TxReadWriteSet
+	if err := proto.Unmarshal(action.GetResults(), &txRWSet); err != nil {
+		return nil, fmt.Errorf("failed to parse read/write set: %w", err)
+	}
+
+	result := &ReadWriteSet{Reads: []KeyRead{}, Writes: []KeyWrite{}}
+	for _, nsRWSet := range txRWSet.GetNsRwset() {
+		namespace := nsRWSet.GetNamespace()
+
+		var kvRWSet kvrwset.KVRWSet
+		if err := proto.Unmarshal(nsRWSet.GetRwset(), &kvRWSet); err != nil {
+			return nil, fmt.Errorf("failed to parse read/write set of %s: %w", namespace, err)
+		}
+		for _, read := range kvRWSet.GetReads() {
+			result.Reads = append(result.Reads, KeyRead{
+				Namespace: namespace,
+				Key:       read.GetKey(),
+				BlockNum:  read.GetVersion().GetBlockNum(),
+				TxNum:     read.GetVersion().GetTxNum(),
+			})
+		}
+		for _, write := range kvRWSet.GetWrites() {
+			result.Writes = append(result.Writes, KeyWrite{
+				Namespace: namespace,
+				Key:       write.GetKey(),
+				Value:     write.GetValue(),
+				IsDelete:  write.GetIsDelete(),
+			})
+		}
+
+		for _, collection := range nsRWSet.GetCollectionHashedRwset() {
+			var hashedRWSet kvrwset.HashedRWSet
+			if err := proto.Unmarshal(collection.GetHashedRwset(), &hashedRWSet); err != nil {
+				return nil, fmt.Errorf("failed to parse read/write set of collection %s: %w", collection.GetCollectionName(), err)
+			}
+			for _, read := range hashedRWSet.GetHashedReads() {
+				result.Reads = append(result.Reads, KeyRead{
+					Namespace:  namespace,
+					Collection: collection.GetCollectionName(),
+					Key:        hex.EncodeToString(read.GetKeyHash()),
+					BlockNum:   read.GetVersion().GetBlockNum(),
+					TxNum:      read.GetVersion().GetTxNum(),
+				})
+			}
+			for _, write := range hashedRWSet.GetHashedWrites() {
+				result.Writes = append(result.Writes, KeyWrite{
+					Namespace:  namespace,
+					Collection: collection.GetCollectionName(),
+					Key:        hex.EncodeToString(write.GetKeyHash()),
+					Value:      write.GetValueHash(),
+					IsDelete:   write.GetIsDelete(),
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// chaincodeAction unwraps the chaincode action endorsed in a transaction envelope.
+func chaincodeAction(transaction []byte) (*peer.ChaincodeAction, error) {
+	var envelope common.Envelope
+	if err := proto.Unmarshal(transaction, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction envelope: %w", err)
+	}
+	var payload common.Payload
+	if err := proto.Unmarshal(envelope.GetPayload(), &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction payload: %w", err)
+	}
+	var tx peer.Transaction
+	if err := proto.Unmarshal(payload.GetData(), &tx); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	if len(tx.GetActions()) == 0 {
+		return nil, errors.New("transaction has no actions")
+	}
+
+	return transactionAction(tx.GetActions()[0])
+}
+
+// transactionAction unwraps the chaincode action endorsed in an action of a
+// transaction.
+func transactionAction(txAction *peer.TransactionAction) (*peer.ChaincodeAction, error) {
+	var actionPayload peer.ChaincodeActionPayload
+	if err := proto.Unmarshal(txAction.GetPayload(), &actionPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse chaincode action payload: %w", err)
+	}
+	var responsePayload peer.ProposalResponsePayload
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), &responsePayload); err != nil {
+		return nil, fmt.Errorf("failed to parse proposal response payload: %w", err)
+	}
+	var action peer.ChaincodeAction
+	if err := proto.Unmarshal(responsePayload.GetExtension(), &action); err != nil {
+		return nil, fmt.Errorf("failed to parse chaincode action: %w", err)
+	}
+
+	return &action, nil
+}
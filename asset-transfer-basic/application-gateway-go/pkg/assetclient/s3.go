@@ -0,0 +1,280 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3BackupStore keeps the frames of a backup log as objects of an S3 bucket,
+// one per block, named after Prefix and the zero-padded block number so that
+// they list in order. Requests are signed with AWS Signature Version 4 and use
+// path-style URLs, which S3 compatible stores such as MinIO also accept.
+type S3BackupStore struct {
+	// Endpoint is the URL of the store, https://s3.<Region>.amazonaws.com when empty.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// HTTPClient is http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// S3BackupStoreFromURL returns the store of an s3://bucket/prefix URL, with the
+// credentials, region and endpoint of the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION and AWS_ENDPOINT_URL
+// environment variables.
+func S3BackupStoreFromURL(rawURL string) (*S3BackupStore, error) {
+	location, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 URL %q: %w", rawURL, err)
+	}
+	if location.Scheme != "s3" || location.Host == "" {
+		return nil, fmt.Errorf("invalid S3 URL %q, expected s3://bucket/prefix", rawURL)
+	}
+	store := &S3BackupStore{
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL"),
+		Region:          os.Getenv("AWS_REGION"),
+		Bucket:          location.Host,
+		Prefix:          strings.TrimPrefix(location.Path, "/"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if store.Region == "" {
+		store.Region = "us-east-1"
+	}
+	if store.AccessKeyID == "" || store.SecretAccessKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to use an S3 backup log")
+	}
+	if store.Prefix != "" && !strings.HasSuffix(store.Prefix, "/") {
+		store.Prefix += "/"
+	}
+	return store, nil
+}
+
+// Append stores the frame as the object of block.
+func (s *S3BackupStore) Append(ctx context.Context, block uint64, frame []byte) error {
+	response, err := s.do(ctx, http.MethodPut, fmt.Sprintf("%s%020d.frame", s.Prefix, block), nil, frame)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// Put stores body as the object name under Prefix, such as an export of the
+// archived assets.
+func (s *S3BackupStore) Put(ctx context.Context, name string, body []byte) error {
+	response, err := s.do(ctx, http.MethodPut, s.Prefix+name, nil, body)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// Frames reads the objects under Prefix in the order of their names.
+func (s *S3BackupStore) Frames(ctx context.Context, fn func(frame []byte) error) error {
+	var keys []string
+	continuation := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if continuation != "" {
+			query.Set("continuation-token", continuation)
+		}
+		response, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return err
+		}
+		var listing struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(response.Body).Decode(&listing)
+		response.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse the objects of s3://%s/%s: %w", s.Bucket, s.Prefix, err)
+		}
+		for _, object := range listing.Contents {
+			if strings.HasSuffix(object.Key, ".frame") {
+				keys = append(keys, object.Key)
+			}
+		}
+		if !listing.IsTruncated {
+			break
+		}
+		continuation = listing.NextContinuationToken
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		response, err := s.do(ctx, http.MethodGet, key, nil, nil)
+		if err != nil {
+			return err
+		}
+		frame, err := io.ReadAll(io.LimitReader(response.Body, maxBackupFrame+1))
+		response.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read s3://%s/%s: %w", s.Bucket, key, err)
+		}
+		if len(frame) > maxBackupFrame {
+			return fmt.Errorf("%w: s3://%s/%s is too large", ErrBackupCorrupt, s.Bucket, key)
+		}
+		if err := fn(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a signed request for an object of the bucket, or for the bucket when
+// key is empty, failing unless it succeeds with a 2xx status.
+func (s *S3BackupStore) do(ctx context.Context, method string, key string, query url.Values, body []byte) (*http.Response, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", endpoint, err)
+	}
+	path := "/" + s3Escape(s.Bucket, false)
+	if key != "" {
+		path += "/" + s3Escape(key, true)
+	}
+	requestURL := base.Scheme + "://" + base.Host + strings.TrimSuffix(base.Path, "/") + path
+	canonicalQuery := s3CanonicalQuery(query)
+	if canonicalQuery != "" {
+		requestURL += "?" + canonicalQuery
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(request, strings.TrimSuffix(base.Path, "/")+path, canonicalQuery, body, time.Now().UTC())
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s s3://%s/%s: %w", method, s.Bucket, key, err)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		response.Body.Close()
+		return nil, fmt.Errorf("failed to %s s3://%s/%s: %s %s", method, s.Bucket, key, response.Status, bytes.TrimSpace(message))
+	}
+	return response, nil
+}
+
+// sign adds the AWS Signature Version 4 headers to a request.
+func (s *S3BackupStore) sign(request *http.Request, path string, canonicalQuery string, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+
+	request.Header.Set("x-amz-content-sha256", hex.EncodeToString(payloadHash[:]))
+	request.Header.Set("x-amz-date", amzDate)
+	if s.SessionToken != "" {
+		request.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+	headers := map[string]string{"host": request.URL.Host}
+	for name := range request.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(request.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	signingKey := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), s.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery encodes query sorted by name, as signed by Signature Version 4.
+func s3CanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, s3Escape(name, false)+"="+s3Escape(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes all but the unreserved characters of RFC 3986, and
+// slashes when keepSlash is set.
+func s3Escape(value string, keepSlash bool) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			escaped.WriteByte(b)
+		case b == '/' && keepSlash:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,335 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Kinds of the differences between a chaincode response and its schema.
+const (
+	// DriftUnknownField is a field the schema does not list, such as one added
+	// by a newer chaincode.
+	DriftUnknownField = "unknown-field"
+	// DriftMissingField is a required field the response does not have.
+	DriftMissingField = "missing-field"
+	// DriftTypeChange is a value of another type than the schema's.
+	DriftTypeChange = "type-change"
+	// DriftInvalidJSON is a response that is not JSON at all.
+	DriftInvalidJSON = "invalid-json"
+)
+
+//go:embed schemas/*.json
+var embeddedSchemas embed.FS
+
+// Schema is the subset of JSON Schema the chaincode responses are validated
+// with: type, which may list several types, properties, required,
+// additionalProperties as a boolean, items and $ref naming another schema file.
+// Objects accept unknown fields unless additionalProperties is false. The
+// description, enum, minimum, pattern and examples of the asset properties
+// describe the columns of the import files, see ImportFields, and are not
+// checked against the chaincode responses.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 schemaTypes        `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Examples             []string           `json:"examples,omitempty"`
+}
+
+// schemaTypes is the type keyword, a single type name or a list of them.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// SchemaMismatch is a difference between a chaincode response and its schema,
+// at Path, a JSON pointer such as /assets/0/balance.
+type SchemaMismatch struct {
+	Function string
+	Path     string
+	Kind     string
+	Expected string
+	Actual   string
+}
+
+func (m SchemaMismatch) String() string {
+	switch m.Kind {
+	case DriftUnknownField:
+		return fmt.Sprintf("%s: unknown field %s", m.Function, m.Path)
+	case DriftMissingField:
+		return fmt.Sprintf("%s: missing field %s", m.Function, m.Path)
+	default:
+		return fmt.Sprintf("%s: %s at %s: expected %s, got %s", m.Function, m.Kind, m.Path, m.Expected, m.Actual)
+	}
+}
+
+// Validate returns the differences between document and the schema.
+func (s *Schema) Validate(document []byte) []SchemaMismatch {
+	var value any
+	if err := json.Unmarshal(document, &value); err != nil {
+		return []SchemaMismatch{{Path: "/", Kind: DriftInvalidJSON, Expected: "JSON", Actual: err.Error()}}
+	}
+	var mismatches []SchemaMismatch
+	s.validate(value, "", &mismatches)
+	return mismatches
+}
+
+func (s *Schema) validate(value any, pointer string, mismatches *[]SchemaMismatch) {
+	actual := jsonType(value)
+	if len(s.Type) > 0 && !s.allows(actual) {
+		*mismatches = append(*mismatches, SchemaMismatch{Path: pointerOrRoot(pointer), Kind: DriftTypeChange, Expected: strings.Join(s.Type, "|"), Actual: actual})
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		for _, field := range s.Required {
+			if _, ok := value[field]; !ok {
+				*mismatches = append(*mismatches, SchemaMismatch{Path: pointer + "/" + field, Kind: DriftMissingField})
+			}
+		}
+		fields := make([]string, 0, len(value))
+		for field := range value {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			property, ok := s.Properties[field]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*mismatches = append(*mismatches, SchemaMismatch{Path: pointer + "/" + field, Kind: DriftUnknownField, Actual: jsonType(value[field])})
+				}
+				continue
+			}
+			property.validate(value[field], pointer+"/"+field, mismatches)
+		}
+	case []any:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range value {
+			s.Items.validate(item, fmt.Sprintf("%s/%d", pointer, i), mismatches)
+		}
+	}
+}
+
+// allows reports whether the schema accepts values of the JSON type actual.
+// Integers are numbers too.
+func (s *Schema) allows(actual string) bool {
+	for _, allowed := range s.Type {
+		if allowed == actual || (allowed == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == float64(int64(value)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
+
+// LoadSchemas parses the schemas of the files, named after the transaction
+// function whose response they describe, such as ReadAsset.json, and resolves
+// the $ref of each to the schema of the file it names.
+func LoadSchemas(files map[string][]byte) (map[string]*Schema, error) {
+	parsed := make(map[string]*Schema, len(files))
+	for name, schemaJSON := range files {
+		var schema Schema
+		if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+			return nil, fmt.Errorf("failed to parse schema %s: %w", name, err)
+		}
+		parsed[name] = &schema
+	}
+
+	schemas := make(map[string]*Schema, len(parsed))
+	for name, schema := range parsed {
+		if err := resolveRefs(schema, parsed, map[string]bool{name: true}); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+		schemas[strings.TrimSuffix(name, ".json")] = schema
+	}
+	return schemas, nil
+}
+
+// resolveRefs replaces the schemas carrying a $ref with the schema they name,
+// failing on unknown and circular references.
+func resolveRefs(schema *Schema, files map[string]*Schema, resolving map[string]bool) error {
+	if schema.Ref != "" {
+		target, ok := files[schema.Ref]
+		if !ok {
+			return fmt.Errorf("unknown $ref %q", schema.Ref)
+		}
+		if resolving[schema.Ref] {
+			return fmt.Errorf("circular $ref %q", schema.Ref)
+		}
+		resolving[schema.Ref] = true
+		defer delete(resolving, schema.Ref)
+		if err := resolveRefs(target, files, resolving); err != nil {
+			return err
+		}
+		*schema = *target
+		return nil
+	}
+	for _, property := range schema.Properties {
+		if err := resolveRefs(property, files, resolving); err != nil {
+			return err
+		}
+	}
+	if schema.Items != nil {
+		return resolveRefs(schema.Items, files, resolving)
+	}
+	return nil
+}
+
+// DefaultSchemas returns the schemas of the responses of this version of the
+// chaincode, which the client was written against.
+func DefaultSchemas() (map[string]*Schema, error) {
+	entries, err := embeddedSchemas.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		files[entry.Name()], err = embeddedSchemas.ReadFile(path.Join("schemas", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return LoadSchemas(files)
+}
+
+// DriftKey counts the mismatches of one kind in the responses of a function.
+type DriftKey struct {
+	Function string
+	Kind     string
+}
+
+// DriftDetector validates chaincode responses against the schemas of their
+// function, logging and counting the mismatches, so that a chaincode upgraded
+// ahead of its clients, or the reverse, is noticed before clients misread its
+// responses. Responses are passed on unchanged: detection never fails a call.
+// Functions without a schema are not checked.
+type DriftDetector struct {
+	schemas map[string]*Schema
+	// Logger receives a line per mismatch, log.Default() when nil.
+	Logger *log.Logger
+
+	lock   sync.Mutex
+	counts map[DriftKey]uint64
+}
+
+// NewDriftDetector returns a detector validating the responses of the
+// functions of schemas, such as those of DefaultSchemas.
+func NewDriftDetector(schemas map[string]*Schema) *DriftDetector {
+	return &DriftDetector{schemas: schemas, counts: make(map[DriftKey]uint64)}
+}
+
+// Check validates the response of function, logs and counts its mismatches
+// and returns them.
+func (d *DriftDetector) Check(function string, response []byte) []SchemaMismatch {
+	schema, ok := d.schemas[function]
+	if !ok {
+		return nil
+	}
+	mismatches := schema.Validate(response)
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	logger := d.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for i := range mismatches {
+		mismatches[i].Function = function
+		d.counts[DriftKey{Function: function, Kind: mismatches[i].Kind}]++
+		logger.Printf("chaincode response drift: %s", mismatches[i])
+	}
+	return mismatches
+}
+
+// Counts returns the number of mismatches found so far by function and kind.
+func (d *DriftDetector) Counts() map[DriftKey]uint64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	counts := make(map[DriftKey]uint64, len(d.counts))
+	for key, count := range d.counts {
+		counts[key] = count
+	}
+	return counts
+}
+
+// Evaluator returns an Evaluator checking the responses of contract, such as
+// for ListAssets.
+func (d *DriftDetector) Evaluator(contract Evaluator) Evaluator {
+	return &driftEvaluator{contract: contract, detector: d}
+}
+
+type driftEvaluator struct {
+	contract Evaluator
+	detector *DriftDetector
+}
+
+func (e *driftEvaluator) EvaluateWithContext(ctx context.Context, transactionName string, options ...client.ProposalOption) ([]byte, error) {
+	result, err := e.contract.EvaluateWithContext(ctx, transactionName, options...)
+	if err == nil {
+		e.detector.Check(transactionName, result)
+	}
+	return result, err
+}
@@ -0,0 +1,71 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestDriftDetectorReportsMismatches(t *testing.T) {
+	schemas, err := DefaultSchemas()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	detector := NewDriftDetector(schemas)
+	detector.Logger = log.New(&logs, "", 0)
+
+	current := `{"ID":"asset1","balance":100,"dealerid":"DEALER101","detailshash":"ab","status":"ACTIVE","transamount":100,"transtype":"CREDIT","updatedat":"2024-01-01T00:00:00Z","version":1}`
+	if mismatches := detector.Check("ReadAsset", []byte(current)); len(mismatches) != 0 {
+		t.Fatalf("expected the current asset to match, got %v", mismatches)
+	}
+
+	drifted := `{"assets":[{"ID":"asset1","balance":"100","dealerid":"DEALER101","region":"north","updatedat":"2024-01-01T00:00:00Z"}],"bookmark":""}`
+	mismatches := detector.Check("GetAssetsFiltered", []byte(drifted))
+	got := make([]string, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		got = append(got, mismatch.Kind+" "+mismatch.Path)
+	}
+	expected := []string{
+		"missing-field /assets/0/status",
+		"type-change /assets/0/balance",
+		"unknown-field /assets/0/region",
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected mismatches %v, got %v", expected, got)
+	}
+
+	counts := detector.Counts()
+	if counts[DriftKey{Function: "GetAssetsFiltered", Kind: DriftTypeChange}] != 1 || len(counts) != 3 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+	if !strings.Contains(logs.String(), "GetAssetsFiltered: unknown field /assets/0/region") {
+		t.Fatalf("expected the mismatches to be logged, got %q", logs.String())
+	}
+}
+
+func TestDriftEvaluatorPassesResponsesThrough(t *testing.T) {
+	schemas, err := LoadSchemas(map[string][]byte{
+		"GetTotals.json": []byte(`{"type":"object","additionalProperties":false,"properties":{"assets":{"type":"integer"}}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector := NewDriftDetector(schemas)
+	detector.Logger = log.New(&bytes.Buffer{}, "", 0)
+	contract := &fakeEvaluator{results: []fakeResult{{json: `{"assets":2.5}`}}}
+
+	result, err := detector.Evaluator(contract).EvaluateWithContext(context.Background(), "GetTotals")
+	if err != nil || string(result) != `{"assets":2.5}` {
+		t.Fatalf("expected the response unchanged, got %s, %v", result, err)
+	}
+	if detector.Counts()[DriftKey{Function: "GetTotals", Kind: DriftTypeChange}] != 1 {
+		t.Fatalf("expected a type change, got %v", detector.Counts())
+	}
+}
@@ -0,0 +1,318 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// defaultEventBuffer is the number of events buffered for each subscriber of an
+// event session before it is dropped as too slow.
+const defaultEventBuffer = 256
+
+// Contract is the part of client.Contract used by a Client, satisfied by
+// *client.Contract.
+type Contract interface {
+	EvaluateWithContext(ctx context.Context, name string, options ...client.ProposalOption) ([]byte, error)
+	SubmitWithContext(ctx context.Context, name string, options ...client.ProposalOption) ([]byte, error)
+	NewProposal(name string, options ...client.ProposalOption) (*client.Proposal, error)
+}
+
+// EventSource opens a stream of the chaincode events of a chaincode, such as
+// client.Network.ChaincodeEvents bound to its name.
+type EventSource func(ctx context.Context, options ...client.ChaincodeEventsOption) (<-chan *client.ChaincodeEvent, error)
+
+// Client calls a chaincode as one identity and shares a single stream of its
+// events among any number of subscribers. It is safe for concurrent use, so a
+// server keeps one Client per signing identity for all its requests.
+type Client struct {
+	contract Contract
+	events   *EventSessions
+}
+
+// NewClient creates a client calling contract and subscribing to events, which
+// may be shared with the clients of other identities of the organization.
+func NewClient(contract Contract, events *EventSessions) *Client {
+	return &Client{contract: contract, events: events}
+}
+
+// NewGatewayClient creates a client of a chaincode for the identity of gateway,
+// recording the position of its event stream in checkpoint, which may be nil.
+func NewGatewayClient(gateway *client.Gateway, channel string, chaincode string, checkpoint *CheckpointWriter) *Client {
+	network := gateway.GetNetwork(channel)
+	source := func(ctx context.Context, options ...client.ChaincodeEventsOption) (<-chan *client.ChaincodeEvent, error) {
+		return network.ChaincodeEvents(ctx, chaincode, options...)
+	}
+	return NewClient(network.GetContract(chaincode), NewEventSessions(source, checkpoint, 0))
+}
+
+// EvaluateWithContext evaluates a transaction function of the chaincode.
+func (c *Client) EvaluateWithContext(ctx context.Context, name string, options ...client.ProposalOption) ([]byte, error) {
+	return c.contract.EvaluateWithContext(ctx, name, options...)
+}
+
+// SubmitWithContext submits a transaction function of the chaincode and waits
+// for its commit.
+func (c *Client) SubmitWithContext(ctx context.Context, name string, options ...client.ProposalOption) ([]byte, error) {
+	return c.contract.SubmitWithContext(ctx, name, options...)
+}
+
+// NewProposal creates a proposal of a transaction function of the chaincode,
+// for callers driving the endorsement and commit themselves.
+func (c *Client) NewProposal(name string, options ...client.ProposalOption) (*client.Proposal, error) {
+	return c.contract.NewProposal(name, options...)
+}
+
+// Subscribe returns the chaincode events received from now on, until ctx is
+// done. See EventSessions.Subscribe.
+func (c *Client) Subscribe(ctx context.Context) (<-chan *client.ChaincodeEvent, error) {
+	if c.events == nil {
+		return nil, errors.New("the client has no event source")
+	}
+	return c.events.Subscribe(ctx)
+}
+
+// EventSessions fans a single stream of chaincode events out to its
+// subscribers. The stream is opened by the first subscriber, resuming from the
+// checkpoint, and closed when the last one leaves. It is safe for concurrent
+// use.
+type EventSessions struct {
+	source     EventSource
+	checkpoint *CheckpointWriter
+	buffer     int
+
+	lock    sync.Mutex
+	current *eventSession
+}
+
+// eventSession is one stream of events and the subscribers it delivers to.
+type eventSession struct {
+	cancel      context.CancelFunc
+	subscribers map[chan *client.ChaincodeEvent]chan struct{}
+}
+
+// drop closes the channel of a subscriber and stops waiting for it to leave.
+func (session *eventSession) drop(subscriber chan *client.ChaincodeEvent) {
+	close(session.subscribers[subscriber])
+	delete(session.subscribers, subscriber)
+	close(subscriber)
+}
+
+// NewEventSessions creates the sessions of source, buffering up to buffer
+// events per subscriber, 256 when zero. Every event is recorded in checkpoint,
+// which may be nil, before it is delivered to the subscribers.
+func NewEventSessions(source EventSource, checkpoint *CheckpointWriter, buffer int) *EventSessions {
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+	return &EventSessions{source: source, checkpoint: checkpoint, buffer: buffer}
+}
+
+// Subscribe returns the events of the shared stream received from now on. The
+// channel is closed when ctx is done, when the stream ends, and when the
+// subscriber falls more than the buffer behind, so that a slow subscriber
+// cannot hold back the others. Subscribing again resumes after the checkpoint.
+// Consumers which must see every event keep their own position, as the
+// checkpoint moves past the events of dropped subscribers.
+func (s *EventSessions) Subscribe(ctx context.Context) (<-chan *client.ChaincodeEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session := s.current
+	if session == nil {
+		streamCtx, cancel := context.WithCancel(context.Background())
+		var options []client.ChaincodeEventsOption
+		if s.checkpoint != nil {
+			options = append(options, client.WithCheckpoint(s.checkpoint))
+		}
+		events, err := s.source(streamCtx, options...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		session = &eventSession{cancel: cancel, subscribers: map[chan *client.ChaincodeEvent]chan struct{}{}}
+		s.current = session
+		go s.run(session, events)
+	}
+
+	subscriber := make(chan *client.ChaincodeEvent, s.buffer)
+	dropped := make(chan struct{})
+	session.subscribers[subscriber] = dropped
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.unsubscribe(session, subscriber)
+		case <-dropped:
+		}
+	}()
+	return subscriber, nil
+}
+
+// run delivers the events of a session until its stream ends.
+func (s *EventSessions) run(session *eventSession, events <-chan *client.ChaincodeEvent) {
+	for event := range events {
+		if s.checkpoint != nil {
+			// a failed write leaves the previous position, replaying the event
+			_ = s.checkpoint.CheckpointChaincodeEvent(event)
+		}
+		s.lock.Lock()
+		for subscriber := range session.subscribers {
+			select {
+			case subscriber <- event:
+			default:
+				session.drop(subscriber)
+			}
+		}
+		s.lock.Unlock()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for subscriber := range session.subscribers {
+		session.drop(subscriber)
+	}
+	if s.current == session {
+		s.current = nil
+	}
+	session.cancel()
+}
+
+// unsubscribe removes a subscriber, closing the stream when none is left.
+func (s *EventSessions) unsubscribe(session *eventSession, subscriber chan *client.ChaincodeEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := session.subscribers[subscriber]; !ok {
+		return
+	}
+	session.drop(subscriber)
+	if len(session.subscribers) == 0 && s.current == session {
+		s.current = nil
+		session.cancel()
+	}
+}
+
+// CheckpointWriter records the position of an event stream, the single writer
+// of its file or StateStore record shared by every session. It implements
+// client.Checkpoint and is safe for concurrent use.
+type CheckpointWriter struct {
+	path string
+	// store and key hold the checkpoint in place of path when store is set.
+	store StateStore
+	key   string
+
+	lock     sync.Mutex
+	position checkpointPosition
+}
+
+type checkpointPosition struct {
+	BlockNumber   uint64 `json:"blockNumber"`
+	TransactionID string `json:"transactionId"`
+}
+
+// OpenCheckpointWriter reads the checkpoint of path, to update it as events are
+// received. An empty path keeps the checkpoint in memory only.
+func OpenCheckpointWriter(path string) (*CheckpointWriter, error) {
+	writer := &CheckpointWriter{path: path}
+	if path == "" {
+		return writer, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return writer, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &writer.position); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// NewStateCheckpointWriter reads the checkpoint kept under key in store, to
+// update it as events are received, such as one shared by the replicas of a
+// service.
+func NewStateCheckpointWriter(ctx context.Context, store StateStore, key string) (*CheckpointWriter, error) {
+	writer := &CheckpointWriter{store: store, key: key}
+	content, ok, err := store.Get(ctx, key)
+	if err != nil || !ok {
+		return writer, err
+	}
+	if err := json.Unmarshal(content, &writer.position); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", key, err)
+	}
+	return writer, nil
+}
+
+// BlockNumber returns the block from which to resume the stream.
+func (c *CheckpointWriter) BlockNumber() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.position.BlockNumber
+}
+
+// TransactionID returns the last transaction of BlockNumber whose event was
+// received, or "" when none was.
+func (c *CheckpointWriter) TransactionID() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.position.TransactionID
+}
+
+// CheckpointChaincodeEvent records event as received. The checkpoint only moves
+// forward, so events of an older block are ignored.
+func (c *CheckpointWriter) CheckpointChaincodeEvent(event *client.ChaincodeEvent) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if event.BlockNumber < c.position.BlockNumber {
+		return nil
+	}
+	position := checkpointPosition{BlockNumber: event.BlockNumber, TransactionID: event.TransactionID}
+	if err := c.write(position); err != nil {
+		return err
+	}
+	c.position = position
+	return nil
+}
+
+// write replaces the checkpoint file, so that a crash leaves either the
+// previous position or the new one.
+func (c *CheckpointWriter) write(position checkpointPosition) error {
+	if c.path == "" && c.store == nil {
+		return nil
+	}
+	content, err := json.Marshal(position)
+	if err != nil {
+		return err
+	}
+	if c.store != nil {
+		return c.store.Put(context.Background(), c.key, content, 0)
+	}
+	file, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), c.path)
+}
@@ -0,0 +1,158 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+type countingContract struct {
+	evaluated atomic.Int64
+	submitted atomic.Int64
+}
+
+func (c *countingContract) EvaluateWithContext(context.Context, string, ...client.ProposalOption) ([]byte, error) {
+	c.evaluated.Add(1)
+	return []byte("{}"), nil
+}
+
+func (c *countingContract) SubmitWithContext(context.Context, string, ...client.ProposalOption) ([]byte, error) {
+	c.submitted.Add(1)
+	return nil, nil
+}
+
+func (c *countingContract) NewProposal(string, ...client.ProposalOption) (*client.Proposal, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+// fakeEventSource hands out streams fed by the test, counting those opened and
+// closed.
+type fakeEventSource struct {
+	lock   sync.Mutex
+	events chan *client.ChaincodeEvent
+	opened int
+	closed chan struct{}
+}
+
+func (s *fakeEventSource) open(ctx context.Context, _ ...client.ChaincodeEventsOption) (<-chan *client.ChaincodeEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.opened++
+	events := make(chan *client.ChaincodeEvent)
+	s.events = events
+	s.closed = make(chan struct{})
+	closed := s.closed
+	go func() {
+		<-ctx.Done()
+		close(closed)
+	}()
+	return events, nil
+}
+
+func TestClientSharesOneEventStreamAcrossGoroutines(t *testing.T) {
+	contract := &countingContract{}
+	source := &fakeEventSource{}
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	checkpoint, err := OpenCheckpointWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := NewClient(contract, NewEventSessions(source.open, checkpoint, 16))
+
+	const subscribers = 20
+	ctx, cancel := context.WithCancel(context.Background())
+	streams := make(chan (<-chan *client.ChaincodeEvent), subscribers)
+	var wg sync.WaitGroup
+	for i := 0; i < subscribers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := shared.EvaluateWithContext(ctx, "ReadAsset"); err != nil {
+					t.Error(err)
+				}
+				if _, err := shared.SubmitWithContext(ctx, "UpdateAsset"); err != nil {
+					t.Error(err)
+				}
+			}
+			events, err := shared.Subscribe(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			streams <- events
+		}()
+	}
+	wg.Wait()
+	close(streams)
+	if contract.evaluated.Load() != 200 || contract.submitted.Load() != 200 {
+		t.Fatalf("expected 200 evaluations and submits, got %d and %d", contract.evaluated.Load(), contract.submitted.Load())
+	}
+	if source.opened != 1 {
+		t.Fatalf("expected a single event stream, got %d", source.opened)
+	}
+
+	for block := uint64(1); block <= 3; block++ {
+		source.events <- &client.ChaincodeEvent{BlockNumber: block, TransactionID: fmt.Sprintf("tx%d", block)}
+	}
+	for events := range streams {
+		for block := uint64(1); block <= 3; block++ {
+			if event := <-events; event.BlockNumber != block {
+				t.Fatalf("expected the event of block %d, got %d", block, event.BlockNumber)
+			}
+		}
+	}
+
+	// the stream is closed with the last subscriber, and reopened from the checkpoint
+	cancel()
+	<-source.closed
+	reopened, err := OpenCheckpointWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.BlockNumber() != 3 || reopened.TransactionID() != "tx3" {
+		t.Fatalf("expected the checkpoint to be at tx3 of block 3, got %s of block %d", reopened.TransactionID(), reopened.BlockNumber())
+	}
+	if _, err := shared.Subscribe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if source.opened != 2 {
+		t.Fatalf("expected the stream to be reopened, got %d streams", source.opened)
+	}
+}
+
+func TestEventSessionsDropSlowSubscribers(t *testing.T) {
+	source := &fakeEventSource{}
+	sessions := NewEventSessions(source.open, nil, 1)
+	ctx := context.Background()
+	slow, err := sessions.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := sessions.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for block := uint64(1); block <= 3; block++ {
+		source.events <- &client.ChaincodeEvent{BlockNumber: block}
+		if event := <-fast; event.BlockNumber != block {
+			t.Fatalf("expected the event of block %d, got %d", block, event.BlockNumber)
+		}
+	}
+	if event := <-slow; event == nil || event.BlockNumber != 1 {
+		t.Fatalf("expected the buffered event of block 1, got %v", event)
+	}
+	if _, ok := <-slow; ok {
+		t.Fatal("expected the slow subscriber to be dropped")
+	}
+}
@@ -0,0 +1,390 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// commitMetricsPrefix names the metrics of CommitMetrics.
+const commitMetricsPrefix = "asset_client_"
+
+// TxTimedOut is the status of a submitted transaction not seen in a block
+// within the timeout of CommitMetrics.
+const TxTimedOut = "TIMEOUT"
+
+// commitLatencyBuckets are the histogram buckets of the submit-to-commit
+// latency, in seconds. A block is cut at least every batch timeout of the
+// orderers, 2 seconds by default.
+var commitLatencyBuckets = []float64{0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60}
+
+// CommitSLO is the service level objective of the submit-to-commit latency: a
+// transaction is good when it commits VALID within Target, and Objective is the
+// fraction of the transactions that must be good, such as 0.99. The rest is
+// the error budget.
+type CommitSLO struct {
+	Target    time.Duration
+	Objective float64
+}
+
+// DefaultCommitSLO is the CommitSLO of 99% of the transactions committed valid
+// within 5 seconds.
+var DefaultCommitSLO = CommitSLO{Target: 5 * time.Second, Objective: 0.99}
+
+// CommitObservation is the outcome of one submitted transaction: its
+// validation code, or TxTimedOut, and the seconds from its submit to its block.
+type CommitObservation struct {
+	TransactionID string    `json:"transactionId"`
+	Function      string    `json:"function"`
+	SubmittedAt   time.Time `json:"submittedAt"`
+	Seconds       float64   `json:"seconds"`
+	Status        string    `json:"status"`
+}
+
+// Good reports whether the transaction met slo.
+func (o CommitObservation) Good(slo CommitSLO) bool {
+	return o.Status == peer.TxValidationCode_VALID.String() && o.Seconds <= slo.Target.Seconds()
+}
+
+// CommitMetrics measures the submit-to-commit latency of the transactions
+// submitted by this client, from the moment they are submitted to the orderers
+// to the block event of the peer carrying them, and counts them against a
+// CommitSLO. It serves them in the Prometheus text format, and appends every
+// observation to Log, read back by ReadCommitObservations for reports over
+// longer periods than the metrics are kept by the client. It is safe for
+// concurrent use.
+type CommitMetrics struct {
+	// SLO is the objective the transactions are counted against.
+	SLO CommitSLO
+	// Timeout is how long a transaction may go without being seen in a block
+	// before it is counted as TxTimedOut. Defaults to 2 minutes.
+	Timeout time.Duration
+	// Log receives the observations as JSON lines when set.
+	Log io.Writer
+
+	now func() time.Time
+
+	mu        sync.Mutex
+	pending   map[string]pendingCommit
+	functions map[string]*commitFunctionMetrics
+}
+
+type pendingCommit struct {
+	function    string
+	submittedAt time.Time
+}
+
+// commitFunctionMetrics are the metrics of the transactions of one function.
+type commitFunctionMetrics struct {
+	latency  []uint64
+	sum      float64
+	count    uint64
+	statuses map[string]uint64
+	good     uint64
+	bad      uint64
+}
+
+// Submitted starts measuring a transaction of function, submitted to the
+// orderers at submittedAt.
+func (m *CommitMetrics) Submitted(txID string, function string, submittedAt time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending == nil {
+		m.pending = make(map[string]pendingCommit)
+	}
+	m.pending[txID] = pendingCommit{function: function, submittedAt: submittedAt}
+}
+
+// Committed records the block of a transaction, as fed by the TxStatusStore.
+// Transactions not submitted through Submitted, such as those of other
+// clients, are ignored.
+func (m *CommitMetrics) Committed(status TxStatus) {
+	if m == nil || !status.Committed() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	submitted, ok := m.pending[status.TransactionID]
+	if !ok {
+		return
+	}
+	delete(m.pending, status.TransactionID)
+	m.observe(CommitObservation{
+		TransactionID: status.TransactionID,
+		Function:      submitted.function,
+		SubmittedAt:   submitted.submittedAt,
+		Seconds:       math.Max(m.clock().Sub(submitted.submittedAt).Seconds(), 0),
+		Status:        status.Status,
+	})
+}
+
+// Expire counts the transactions not seen in a block within the timeout as
+// TxTimedOut.
+func (m *CommitMetrics) Expire() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock()
+	for txID, submitted := range m.pending {
+		if now.Sub(submitted.submittedAt) < m.timeout() {
+			continue
+		}
+		delete(m.pending, txID)
+		m.observe(CommitObservation{
+			TransactionID: txID,
+			Function:      submitted.function,
+			SubmittedAt:   submitted.submittedAt,
+			Seconds:       now.Sub(submitted.submittedAt).Seconds(),
+			Status:        TxTimedOut,
+		})
+	}
+}
+
+// Run expires the transactions not seen in a block every interval, until ctx
+// is done.
+func (m *CommitMetrics) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Expire()
+		}
+	}
+}
+
+// observe records an observation, with m.mu held.
+func (m *CommitMetrics) observe(observation CommitObservation) {
+	if m.functions == nil {
+		m.functions = make(map[string]*commitFunctionMetrics)
+	}
+	metrics, ok := m.functions[observation.Function]
+	if !ok {
+		metrics = &commitFunctionMetrics{latency: make([]uint64, len(commitLatencyBuckets)), statuses: make(map[string]uint64)}
+		m.functions[observation.Function] = metrics
+	}
+	for i, bound := range commitLatencyBuckets {
+		if observation.Seconds <= bound {
+			metrics.latency[i]++
+		}
+	}
+	metrics.sum += observation.Seconds
+	metrics.count++
+	metrics.statuses[observation.Status]++
+	if observation.Good(m.SLO) {
+		metrics.good++
+	} else {
+		metrics.bad++
+	}
+
+	if m.Log == nil {
+		return
+	}
+	line, err := json.Marshal(observation)
+	if err == nil {
+		_, err = m.Log.Write(append(line, '\n'))
+	}
+	if err != nil {
+		log.Printf("Failed to log the commit of transaction %s: %s", observation.TransactionID, err)
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format. The
+// error budget burn rate over a window is the rate of slo_events_total with
+// outcome bad divided by that of all outcomes, over 1 - slo_objective.
+func (m *CommitMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	functions := make([]string, 0, len(m.functions))
+	for function := range m.functions {
+		functions = append(functions, function)
+	}
+	sort.Strings(functions)
+
+	writeCommitHelp(w, "commit_latency_seconds", "histogram", "Time from the submit of a transaction to its block, by function.")
+	for _, function := range functions {
+		metrics := m.functions[function]
+		for i, bound := range commitLatencyBuckets {
+			fmt.Fprintf(w, "%scommit_latency_seconds_bucket{function=%q,le=\"%g\"} %d\n", commitMetricsPrefix, function, bound, metrics.latency[i])
+		}
+		fmt.Fprintf(w, "%scommit_latency_seconds_bucket{function=%q,le=\"+Inf\"} %d\n", commitMetricsPrefix, function, metrics.count)
+		fmt.Fprintf(w, "%scommit_latency_seconds_sum{function=%q} %g\n", commitMetricsPrefix, function, metrics.sum)
+		fmt.Fprintf(w, "%scommit_latency_seconds_count{function=%q} %d\n", commitMetricsPrefix, function, metrics.count)
+	}
+	writeCommitHelp(w, "commits_total", "counter", "Submitted transactions, by function and validation code or TIMEOUT.")
+	for _, function := range functions {
+		statuses := make([]string, 0, len(m.functions[function].statuses))
+		for status := range m.functions[function].statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(w, "%scommits_total{function=%q,status=%q} %d\n", commitMetricsPrefix, function, status, m.functions[function].statuses[status])
+		}
+	}
+	writeCommitHelp(w, "slo_events_total", "counter", "Submitted transactions that met the commit SLO (good) or spent error budget (bad), by function.")
+	for _, function := range functions {
+		fmt.Fprintf(w, "%sslo_events_total{function=%q,outcome=\"good\"} %d\n", commitMetricsPrefix, function, m.functions[function].good)
+		fmt.Fprintf(w, "%sslo_events_total{function=%q,outcome=\"bad\"} %d\n", commitMetricsPrefix, function, m.functions[function].bad)
+	}
+	writeCommitHelp(w, "slo_target_seconds", "gauge", "Commit latency a transaction must stay within to meet the SLO.")
+	fmt.Fprintf(w, "%sslo_target_seconds %g\n", commitMetricsPrefix, m.SLO.Target.Seconds())
+	writeCommitHelp(w, "slo_objective", "gauge", "Fraction of the transactions that must meet the commit SLO.")
+	fmt.Fprintf(w, "%sslo_objective %g\n", commitMetricsPrefix, m.SLO.Objective)
+	writeCommitHelp(w, "pending_commits", "gauge", "Submitted transactions not yet seen in a block.")
+	fmt.Fprintf(w, "%spending_commits %d\n", commitMetricsPrefix, len(m.pending))
+}
+
+func writeCommitHelp(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", commitMetricsPrefix, name, help, commitMetricsPrefix, name, kind)
+}
+
+func (m *CommitMetrics) timeout() time.Duration {
+	if m.Timeout <= 0 {
+		return 2 * time.Minute
+	}
+	return m.Timeout
+}
+
+func (m *CommitMetrics) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+// ReadCommitObservations reads the observations logged by CommitMetrics from r,
+// keeping those submitted at or after since. A truncated last line, left by a
+// crash while writing it, is ignored.
+func ReadCommitObservations(r io.Reader, since time.Time) ([]CommitObservation, error) {
+	scanner := bufio.NewScanner(r)
+	var observations []CommitObservation
+	var pending error
+	for line := 1; scanner.Scan(); line++ {
+		if pending != nil {
+			return nil, pending
+		}
+		var observation CommitObservation
+		if err := json.Unmarshal(scanner.Bytes(), &observation); err != nil {
+			pending = fmt.Errorf("invalid commit observation on line %d: %w", line, err)
+			continue
+		}
+		if !observation.SubmittedAt.Before(since) {
+			observations = append(observations, observation)
+		}
+	}
+	return observations, scanner.Err()
+}
+
+// SLOReport summarizes the observations of a period against a CommitSLO, for
+// all the transactions and for those of each function.
+type SLOReport struct {
+	TargetSeconds float64      `json:"targetSeconds"`
+	Objective     float64      `json:"objective"`
+	Since         time.Time    `json:"since"`
+	Total         SLOSummary   `json:"total"`
+	Functions     []SLOSummary `json:"functions"`
+}
+
+// SLOSummary summarizes the observations of a set of transactions. Compliance
+// is the fraction of the transactions that met the SLO, and
+// ErrorBudgetRemaining the fraction of the error budget left, negative once it
+// is overspent. The latency percentiles include the transactions that timed
+// out, at the time they were given up on.
+type SLOSummary struct {
+	Function             string  `json:"function,omitempty"`
+	Transactions         int     `json:"transactions"`
+	Good                 int     `json:"good"`
+	Invalid              int     `json:"invalid"`
+	TimedOut             int     `json:"timedOut"`
+	P50Seconds           float64 `json:"p50Seconds"`
+	P95Seconds           float64 `json:"p95Seconds"`
+	P99Seconds           float64 `json:"p99Seconds"`
+	Compliance           float64 `json:"compliance"`
+	ErrorBudgetRemaining float64 `json:"errorBudgetRemaining"`
+}
+
+// NewSLOReport summarizes observations, submitted since, against slo.
+func NewSLOReport(observations []CommitObservation, slo CommitSLO, since time.Time) *SLOReport {
+	report := &SLOReport{
+		TargetSeconds: slo.Target.Seconds(),
+		Objective:     slo.Objective,
+		Since:         since,
+		Total:         summarizeCommits("", observations, slo),
+		Functions:     []SLOSummary{},
+	}
+
+	byFunction := make(map[string][]CommitObservation)
+	for _, observation := range observations {
+		byFunction[observation.Function] = append(byFunction[observation.Function], observation)
+	}
+	functions := make([]string, 0, len(byFunction))
+	for function := range byFunction {
+		functions = append(functions, function)
+	}
+	sort.Strings(functions)
+	for _, function := range functions {
+		report.Functions = append(report.Functions, summarizeCommits(function, byFunction[function], slo))
+	}
+	return report
+}
+
+func summarizeCommits(function string, observations []CommitObservation, slo CommitSLO) SLOSummary {
+	summary := SLOSummary{Function: function, Transactions: len(observations), Compliance: 1, ErrorBudgetRemaining: 1}
+	if len(observations) == 0 {
+		return summary
+	}
+
+	latencies := make([]float64, 0, len(observations))
+	for _, observation := range observations {
+		latencies = append(latencies, observation.Seconds)
+		switch {
+		case observation.Good(slo):
+			summary.Good++
+		case observation.Status == TxTimedOut:
+			summary.TimedOut++
+		case observation.Status != peer.TxValidationCode_VALID.String():
+			summary.Invalid++
+		}
+	}
+	sort.Float64s(latencies)
+	summary.P50Seconds = percentile(latencies, 0.50)
+	summary.P95Seconds = percentile(latencies, 0.95)
+	summary.P99Seconds = percentile(latencies, 0.99)
+
+	bad := float64(len(observations) - summary.Good)
+	summary.Compliance = float64(summary.Good) / float64(len(observations))
+	if budget := (1 - slo.Objective) * float64(len(observations)); budget > 0 {
+		summary.ErrorBudgetRemaining = 1 - bad/budget
+	} else if bad > 0 {
+		summary.ErrorBudgetRemaining = 0
+	}
+	return summary
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, fraction float64) float64 {
+	rank := int(math.Ceil(fraction*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
@@ -0,0 +1,95 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCommitMetrics() (*CommitMetrics, *time.Time, *bytes.Buffer) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var log bytes.Buffer
+	metrics := &CommitMetrics{SLO: CommitSLO{Target: 2 * time.Second, Objective: 0.9}, Timeout: time.Minute, Log: &log}
+	metrics.now = func() time.Time { return now }
+	return metrics, &now, &log
+}
+
+func TestCommitMetricsMeasureSubmitToCommit(t *testing.T) {
+	metrics, now, _ := newTestCommitMetrics()
+	start := *now
+
+	metrics.Submitted("tx1", "TransferAsset", start)
+	metrics.Submitted("tx2", "TransferAsset", start)
+	metrics.Submitted("tx3", "UpdateAsset", start)
+	*now = start.Add(1500 * time.Millisecond)
+	metrics.Committed(TxStatus{TransactionID: "tx1", Status: "VALID"})
+	metrics.Committed(TxStatus{TransactionID: "tx2", Status: "MVCC_READ_CONFLICT"})
+	metrics.Committed(TxStatus{TransactionID: "other", Status: "VALID"})
+	metrics.Committed(TxStatus{TransactionID: "tx3", Status: TxPending})
+	*now = start.Add(2 * time.Minute)
+	metrics.Expire()
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, expected := range []string{
+		`asset_client_commit_latency_seconds_bucket{function="TransferAsset",le="2"} 2`,
+		`asset_client_commit_latency_seconds_bucket{function="TransferAsset",le="1"} 0`,
+		`asset_client_commit_latency_seconds_count{function="UpdateAsset"} 1`,
+		`asset_client_commits_total{function="TransferAsset",status="MVCC_READ_CONFLICT"} 1`,
+		`asset_client_commits_total{function="UpdateAsset",status="TIMEOUT"} 1`,
+		`asset_client_slo_events_total{function="TransferAsset",outcome="good"} 1`,
+		`asset_client_slo_events_total{function="TransferAsset",outcome="bad"} 1`,
+		`asset_client_slo_target_seconds 2`,
+		`asset_client_slo_objective 0.9`,
+		`asset_client_pending_commits 0`,
+	} {
+		if !strings.Contains(body, expected+"\n") {
+			t.Errorf("expected the metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+}
+
+func TestSLOReportFromLog(t *testing.T) {
+	metrics, now, log := newTestCommitMetrics()
+	start := *now
+
+	metrics.Submitted("old", "TransferAsset", start.Add(-2*time.Hour))
+	metrics.Committed(TxStatus{TransactionID: "old", Status: "VALID"})
+	for i, latency := range []time.Duration{time.Second, time.Second, 3 * time.Second} {
+		txID := string(rune('a' + i))
+		metrics.Submitted(txID, "TransferAsset", start.Add(-latency))
+		metrics.Committed(TxStatus{TransactionID: txID, Status: "VALID"})
+	}
+	log.WriteString(`{"transactionId":"trunc`)
+
+	observations, err := ReadCommitObservations(log, start.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := NewSLOReport(observations, metrics.SLO, start.Add(-time.Hour))
+	total := report.Total
+	if total.Transactions != 3 || total.Good != 2 || total.P50Seconds != 1 || total.P99Seconds != 3 {
+		t.Fatalf("unexpected summary %+v", total)
+	}
+	// 1 bad transaction of 3 spends the budget of 0.3 more than 3 times
+	if total.ErrorBudgetRemaining > -2.3 || total.ErrorBudgetRemaining < -2.4 {
+		t.Fatalf("expected the error budget to be overspent, got %g", total.ErrorBudgetRemaining)
+	}
+	if len(report.Functions) != 1 || report.Functions[0].Function != "TransferAsset" {
+		t.Fatalf("unexpected functions %+v", report.Functions)
+	}
+}
+
+func TestSLOReportWithoutTransactions(t *testing.T) {
+	report := NewSLOReport(nil, DefaultCommitSLO, time.Time{})
+	if report.Total.Compliance != 1 || report.Total.ErrorBudgetRemaining != 1 {
+		t.Fatalf("expected an untouched budget, got %+v", report.Total)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"google.golang.org/protobuf/proto"
+)
+
+// HeightReader returns the height of the ledger of a channel, the number of its
+// blocks.
+type HeightReader func(ctx context.Context) (uint64, error)
+
+// QSCCHeight returns the HeightReader of channel evaluating GetChainInfo of
+// qscc, the query system chaincode of the peers, such as
+// network.GetContract("qscc").
+func QSCCHeight(qscc Evaluator, channel string) HeightReader {
+	return func(ctx context.Context) (uint64, error) {
+		result, err := qscc.EvaluateWithContext(ctx, "GetChainInfo", client.WithArguments(channel))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the height of channel %s: %w", channel, err)
+		}
+		var info common.BlockchainInfo
+		if err := proto.Unmarshal(result, &info); err != nil {
+			return 0, fmt.Errorf("invalid chain info of channel %s: %w", channel, err)
+		}
+		return info.GetHeight(), nil
+	}
+}
+
+// SnapshotMovedError is returned by the evaluations of a PinnedEvaluator once
+// a block was committed after the height it is pinned to.
+type SnapshotMovedError struct {
+	Pinned  uint64
+	Current uint64
+}
+
+func (e *SnapshotMovedError) Error() string {
+	return fmt.Sprintf("the ledger moved from height %d to %d during the read", e.Pinned, e.Current)
+}
+
+// PinnedEvaluator evaluates transactions at one height of the ledger, so that
+// the results of a series of evaluations, such as the queries of a report,
+// read the same state rather than a mix of blocks. Fabric evaluates against the
+// latest state only, so the height is read before the first evaluation and
+// again after each one, which fails with a *SnapshotMovedError when the ledger
+// moved on; the series is then read again, see ConsistentRead. The height and
+// the evaluations must be served by the same peer, such as the peer of a
+// Gateway whose organization has one, or a read replica. It is safe for
+// concurrent use.
+type PinnedEvaluator struct {
+	evaluator Evaluator
+	height    HeightReader
+	pinned    uint64
+}
+
+// PinHeight returns the evaluator of evaluator pinned to the current height of
+// the ledger, read with height.
+func PinHeight(ctx context.Context, evaluator Evaluator, height HeightReader) (*PinnedEvaluator, error) {
+	pinned, err := height(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &PinnedEvaluator{evaluator: evaluator, height: height, pinned: pinned}, nil
+}
+
+// Height returns the height the evaluations are pinned to: they read the state
+// of the ledger after the block Height-1.
+func (p *PinnedEvaluator) Height() uint64 {
+	return p.pinned
+}
+
+// EvaluateWithContext evaluates a transaction function, failing with a
+// *SnapshotMovedError when the ledger is past the pinned height afterwards.
+func (p *PinnedEvaluator) EvaluateWithContext(ctx context.Context, name string, options ...client.ProposalOption) ([]byte, error) {
+	result, err := p.evaluator.EvaluateWithContext(ctx, name, options...)
+	if err != nil {
+		return nil, err
+	}
+	current, err := p.height(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current != p.pinned {
+		return nil, &SnapshotMovedError{Pinned: p.pinned, Current: current}
+	}
+	return result, nil
+}
+
+// ConsistentRead calls read with an evaluator pinned to the current height of
+// the ledger, and calls it again, up to attempts times in all, while it fails
+// as the ledger moved on. It returns the height read by the successful call.
+// A busy channel may move on faster than a long read completes, so reads
+// should be short, or run when the channel is quiet.
+func ConsistentRead(ctx context.Context, evaluator Evaluator, height HeightReader, attempts int, read func(ctx context.Context, pinned *PinnedEvaluator) error) (uint64, error) {
+	var moved *SnapshotMovedError
+	for attempt := 1; ; attempt++ {
+		pinned, err := PinHeight(ctx, evaluator, height)
+		if err != nil {
+			return 0, err
+		}
+		err = read(ctx, pinned)
+		if err == nil {
+			return pinned.Height(), nil
+		}
+		if !errors.As(err, &moved) || attempt >= attempts || ctx.Err() != nil {
+			return 0, err
+		}
+	}
+}
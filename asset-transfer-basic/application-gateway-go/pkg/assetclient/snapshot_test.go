@@ -0,0 +1,79 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// movingLedger is a ledger whose height grows by one on each of the first
+// commits evaluations.
+type movingLedger struct {
+	height  uint64
+	commits int
+	calls   int
+}
+
+func (l *movingLedger) EvaluateWithContext(ctx context.Context, name string, options ...client.ProposalOption) ([]byte, error) {
+	l.calls++
+	if l.commits > 0 {
+		l.commits--
+		l.height++
+	}
+	return []byte(name), nil
+}
+
+func (l *movingLedger) Height(ctx context.Context) (uint64, error) {
+	return l.height, nil
+}
+
+func TestPinnedEvaluatorFailsOnceTheLedgerMoves(t *testing.T) {
+	ledger := &movingLedger{height: 10}
+	pinned, err := PinHeight(context.Background(), ledger, ledger.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := pinned.EvaluateWithContext(context.Background(), "GetTotals"); err != nil || string(result) != "GetTotals" {
+		t.Fatalf("expected the evaluation at the pinned height to succeed, got %q, %v", result, err)
+	}
+
+	ledger.commits = 1
+	_, err = pinned.EvaluateWithContext(context.Background(), "GetDealerFloat")
+	var moved *SnapshotMovedError
+	if !errors.As(err, &moved) || moved.Pinned != 10 || moved.Current != 11 {
+		t.Fatalf("expected the ledger to have moved from 10 to 11, got %v", err)
+	}
+}
+
+func TestConsistentReadRetriesWhileTheLedgerMoves(t *testing.T) {
+	ledger := &movingLedger{height: 10, commits: 2}
+	read := func(ctx context.Context, pinned *PinnedEvaluator) error {
+		for _, function := range []string{"GetTotals", "GetDealerFloat"} {
+			if _, err := pinned.EvaluateWithContext(ctx, function); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	height, err := ConsistentRead(context.Background(), ledger, ledger.Height, 3, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 12 || ledger.calls != 4 {
+		t.Fatalf("expected the third read to succeed at height 12 after 4 evaluations, got height %d after %d", height, ledger.calls)
+	}
+
+	ledger.commits = 5
+	_, err = ConsistentRead(context.Background(), ledger, ledger.Height, 2, read)
+	var moved *SnapshotMovedError
+	if !errors.As(err, &moved) {
+		t.Fatalf("expected the read to give up as the ledger kept moving, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateStore keeps the small records of the clients, such as event stream
+// checkpoints, the events already delivered and the responses of idempotent
+// requests. FileStateStore keeps them on the local file system, for a single
+// replica, while RedisStateStore and PostgresStateStore share them between the
+// replicas of a client. Records put with a ttl expire after it, zero keeping
+// them until deleted.
+type StateStore interface {
+	// Get returns the value of key, false when it is not set or expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Put sets the value of key.
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// PutIfAbsent sets the value of key unless it is set and not expired,
+	// reporting whether it did, atomically across the replicas sharing the store.
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Delete removes key, if set.
+	Delete(ctx context.Context, key string) error
+}
+
+// StateStoreFor returns the store of a location: a redis:// or rediss:// URL, a
+// postgres:// or postgresql:// URL, or the path of a local directory.
+func StateStoreFor(location string) (StateStore, error) {
+	switch {
+	case strings.HasPrefix(location, "redis://"), strings.HasPrefix(location, "rediss://"):
+		return RedisStateStoreFromURL(location)
+	case strings.HasPrefix(location, "postgres://"), strings.HasPrefix(location, "postgresql://"):
+		return PostgresStateStoreFromURL(location)
+	case location == "":
+		return nil, errors.New("the location of the state store must be set")
+	default:
+		return &FileStateStore{Dir: location}, nil
+	}
+}
+
+// FileStateStore keeps each record in a file of Dir, replaced atomically on
+// every change. PutIfAbsent is atomic across the processes sharing Dir on a
+// local file system, but network file systems may not honour it.
+type FileStateStore struct {
+	Dir string
+
+	lock sync.Mutex
+	now  func() time.Time
+}
+
+// fileStateRecord is the content of the file of a record.
+type fileStateRecord struct {
+	Value     []byte     `json:"value"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (s *FileStateStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	record, ok, err := s.read(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return record.Value, true, nil
+}
+
+func (s *FileStateStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	file, err := s.writeTemp(value, ttl)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return os.Rename(file, s.path(key))
+}
+
+func (s *FileStateStore) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	file, err := s.writeTemp(value, ttl)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(file)
+
+	// linking fails when the record exists, whichever process created it
+	for {
+		err := os.Link(file, s.path(key))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return false, err
+		}
+		_, ok, err := s.read(key)
+		if err != nil || ok {
+			return false, err
+		}
+		// the record expired, so it is replaced
+		if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+}
+
+func (s *FileStateStore) Delete(ctx context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file of key, escaped so that any key names a file of Dir.
+func (s *FileStateStore) path(key string) string {
+	return filepath.Join(s.Dir, url.PathEscape(key)+".json")
+}
+
+// read returns the record of key, false when it is missing or expired.
+func (s *FileStateStore) read(key string) (fileStateRecord, bool, error) {
+	var record fileStateRecord
+	content, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return record, false, nil
+	}
+	if err != nil {
+		return record, false, err
+	}
+	if err := json.Unmarshal(content, &record); err != nil {
+		return record, false, fmt.Errorf("invalid state record %s: %w", s.path(key), err)
+	}
+	if record.ExpiresAt != nil && !s.clock().Before(*record.ExpiresAt) {
+		return record, false, nil
+	}
+	return record, true, nil
+}
+
+// writeTemp writes a record to a new temporary file of Dir, synced to disk,
+// and returns its path.
+func (s *FileStateStore) writeTemp(value []byte, ttl time.Duration) (string, error) {
+	record := fileStateRecord{Value: value}
+	if ttl > 0 {
+		expiresAt := s.clock().Add(ttl)
+		record.ExpiresAt = &expiresAt
+	}
+	content, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(s.Dir, ".record-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+func (s *FileStateStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// EventState keeps the progress of a chaincode event consumer in a StateStore
+// under Prefix, so that its replicas resume where any of them stopped and
+// deliver each event once. Events are claimed for ClaimTTL, which must outlast
+// the replay of the events after a restored position; a day when zero.
+type EventState struct {
+	Store    StateStore
+	Prefix   string
+	ClaimTTL time.Duration
+}
+
+// Last returns the position of the last event saved, nil when none was.
+func (s *EventState) Last(ctx context.Context) (*EventPosition, error) {
+	value, ok, err := s.Store.Get(ctx, s.Prefix+"/last")
+	if err != nil || !ok {
+		return nil, err
+	}
+	var position EventPosition
+	if err := json.Unmarshal(value, &position); err != nil {
+		return nil, fmt.Errorf("invalid event position %s/last: %w", s.Prefix, err)
+	}
+	return &position, nil
+}
+
+// Save records position as the last event applied.
+func (s *EventState) Save(ctx context.Context, position EventPosition) error {
+	value, err := json.Marshal(position)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(ctx, s.Prefix+"/last", value, 0)
+}
+
+// Claim reports whether the event at position is delivered by this replica,
+// false when another replica, or this one before a restart, claimed it.
+func (s *EventState) Claim(ctx context.Context, position EventPosition) (bool, error) {
+	ttl := s.ClaimTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return s.Store.PutIfAbsent(ctx, s.claimKey(position), []byte(time.Now().UTC().Format(time.RFC3339)), ttl)
+}
+
+// Release gives up the claim of an event that failed to be delivered, for a
+// replica to deliver it again.
+func (s *EventState) Release(ctx context.Context, position EventPosition) error {
+	return s.Store.Delete(ctx, s.claimKey(position))
+}
+
+func (s *EventState) claimKey(position EventPosition) string {
+	return fmt.Sprintf("%s/claims/%d/%s/%d", s.Prefix, position.BlockNumber, position.TransactionID, position.EventIndex)
+}
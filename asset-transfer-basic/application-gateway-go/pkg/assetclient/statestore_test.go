@@ -0,0 +1,97 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+func TestFileStateStoreClaimsOnceUntilExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	store := &FileStateStore{Dir: t.TempDir(), now: func() time.Time { return now }}
+
+	// concurrent claims of the same key succeed once
+	var claimed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := store.PutIfAbsent(ctx, "events/claims/7/tx1/0", []byte("claimed"), time.Minute)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed.Load() != 1 {
+		t.Fatalf("expected one claim to succeed, got %d", claimed.Load())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := store.Get(ctx, "events/claims/7/tx1/0"); ok {
+		t.Fatal("expected the claim to have expired")
+	}
+	if ok, err := store.PutIfAbsent(ctx, "events/claims/7/tx1/0", []byte("again"), 0); err != nil || !ok {
+		t.Fatalf("expected an expired claim to be replaced, got %v, %v", ok, err)
+	}
+	if err := store.Delete(ctx, "events/claims/7/tx1/0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := store.Get(ctx, "events/claims/7/tx1/0"); ok {
+		t.Fatal("expected the claim to be deleted")
+	}
+}
+
+func TestCheckpointAndEventStateResumeFromStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := StateStoreFor(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := NewStateCheckpointWriter(ctx, store, "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.CheckpointChaincodeEvent(&client.ChaincodeEvent{BlockNumber: 12, TransactionID: "tx9"}); err != nil {
+		t.Fatal(err)
+	}
+	// another replica resumes from the shared checkpoint
+	writer, err = NewStateCheckpointWriter(ctx, store, "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.BlockNumber() != 12 || writer.TransactionID() != "tx9" {
+		t.Fatalf("unexpected checkpoint %d %s", writer.BlockNumber(), writer.TransactionID())
+	}
+
+	state := &EventState{Store: store, Prefix: "events/mychannel/basic"}
+	if last, err := state.Last(ctx); err != nil || last != nil {
+		t.Fatalf("expected no position yet, got %v, %v", last, err)
+	}
+	position := EventPosition{BlockNumber: 12, TransactionIndex: 3, TransactionID: "tx9"}
+	for i, expected := range []bool{true, false} {
+		if ok, err := state.Claim(ctx, position); err != nil || ok != expected {
+			t.Fatalf("claim %d: expected %v, got %v, %v", i, expected, ok, err)
+		}
+	}
+	if err := state.Save(ctx, position); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := state.Last(ctx); err != nil || *last != position {
+		t.Fatalf("expected %v, got %v, %v", position, last, err)
+	}
+}
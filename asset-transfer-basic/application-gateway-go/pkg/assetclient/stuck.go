@@ -0,0 +1,382 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// The states of a tracked transaction whose commit was not confirmed.
+const (
+	// StuckPending is a transaction submitted less than the deadline ago.
+	StuckPending = "PENDING"
+	// StuckLost is a transaction not seen in a block by its deadline, and not
+	// resent or failing to be resent.
+	StuckLost = "LOST"
+	// StuckInvalidated is a transaction committed with a validation code other
+	// than VALID, such as MVCC_READ_CONFLICT, and not endorsed again.
+	StuckInvalidated = "INVALIDATED"
+)
+
+// TrackedTx is a submitted transaction whose commit was not confirmed to its
+// submitter.
+type TrackedTx struct {
+	TransactionID string   `json:"transactionId"`
+	Function      string   `json:"function"`
+	Args          []string `json:"args,omitempty"`
+	// Identity names the identity that signed the transaction.
+	Identity string `json:"identity,omitempty"`
+	// Transaction is the signed transaction, resent unchanged when it is lost.
+	Transaction []byte `json:"transaction,omitempty"`
+	// Transient is set when the proposal carried transient data. It is not
+	// kept, so the transaction cannot be endorsed again.
+	Transient       bool      `json:"transient,omitempty"`
+	State           string    `json:"state"`
+	Code            string    `json:"code,omitempty"`
+	SubmittedAt     time.Time `json:"submittedAt"`
+	LastSubmittedAt time.Time `json:"lastSubmittedAt"`
+	Resubmissions   int       `json:"resubmissions"`
+	// Replaces is the invalidated transaction this one endorsed again.
+	Replaces string `json:"replaces,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// trackedTxRecord is a line of a TrackedTxLog: the new value of a transaction,
+// or the removal of the transaction Removed.
+type trackedTxRecord struct {
+	Tx      *TrackedTx `json:"tx,omitempty"`
+	Removed string     `json:"removed,omitempty"`
+}
+
+// TrackedTxLog keeps the tracked transactions in a file, one JSON record per
+// line, synced to disk before each change returns, so that they survive a
+// restart. Opening the log compacts it. It is safe for concurrent use.
+type TrackedTxLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	txs  map[string]TrackedTx
+}
+
+// OpenTrackedTxLog opens the log at path, creating it when missing. A truncated
+// last line, left by a crash while writing it, is ignored.
+func OpenTrackedTxLog(path string) (*TrackedTxLog, error) {
+	l := &TrackedTxLog{path: path, txs: make(map[string]TrackedTx)}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	if err := l.compact(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *TrackedTxLog) load() error {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open the tracked transaction log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var pending error
+	for line := 1; scanner.Scan(); line++ {
+		if pending != nil {
+			return pending
+		}
+		var record trackedTxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			pending = fmt.Errorf("invalid tracked transaction on line %d of %s: %w", line, l.path, err)
+			continue
+		}
+		if record.Tx != nil {
+			l.txs[record.Tx.TransactionID] = *record.Tx
+		} else {
+			delete(l.txs, record.Removed)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the log with the current transactions only, and opens it
+// for appending.
+func (l *TrackedTxLog) compact() error {
+	temporary := l.path + ".tmp"
+	file, err := os.OpenFile(temporary, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to compact the tracked transaction log: %w", err)
+	}
+	for _, tx := range l.list() {
+		recordJSON, err := json.Marshal(trackedTxRecord{Tx: &tx})
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if _, err := file.Write(append(recordJSON, '\n')); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to compact the tracked transaction log: %w", err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync the tracked transaction log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temporary, l.path); err != nil {
+		return fmt.Errorf("failed to compact the tracked transaction log: %w", err)
+	}
+
+	l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open the tracked transaction log: %w", err)
+	}
+	return nil
+}
+
+// Put records the new value of a transaction.
+func (l *TrackedTxLog) Put(tx TrackedTx) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.append(trackedTxRecord{Tx: &tx}); err != nil {
+		return err
+	}
+	l.txs[tx.TransactionID] = tx
+	return nil
+}
+
+// Remove stops tracking a transaction.
+func (l *TrackedTxLog) Remove(txID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.txs[txID]; !ok {
+		return nil
+	}
+	if err := l.append(trackedTxRecord{Removed: txID}); err != nil {
+		return err
+	}
+	delete(l.txs, txID)
+	return nil
+}
+
+func (l *TrackedTxLog) append(record trackedTxRecord) error {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(recordJSON, '\n')); err != nil {
+		return fmt.Errorf("failed to write the tracked transaction log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync the tracked transaction log: %w", err)
+	}
+	return nil
+}
+
+// Get returns a tracked transaction, false when it is not tracked.
+func (l *TrackedTxLog) Get(txID string) (TrackedTx, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tx, ok := l.txs[txID]
+	return tx, ok
+}
+
+// List returns the tracked transactions, oldest first.
+func (l *TrackedTxLog) List() []TrackedTx {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.list()
+}
+
+func (l *TrackedTxLog) list() []TrackedTx {
+	txs := make([]TrackedTx, 0, len(l.txs))
+	for _, tx := range l.txs {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if !txs[i].SubmittedAt.Equal(txs[j].SubmittedAt) {
+			return txs[i].SubmittedAt.Before(txs[j].SubmittedAt)
+		}
+		return txs[i].TransactionID < txs[j].TransactionID
+	})
+	return txs
+}
+
+// Close closes the file of the log.
+func (l *TrackedTxLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// StuckTxManager follows the transactions whose commit was not confirmed to
+// their submitter, such as after a timeout, until they are seen valid in a
+// block. A transaction not seen in a block by Deadline after its submission is
+// lost, and resent unchanged: a transaction ID commits once, so resending can
+// never apply it twice. A transaction committed invalid is endorsed again as a
+// new transaction when its function is Idempotent, and left for an operator
+// otherwise. Each transaction is resubmitted at most MaxResubmissions times.
+type StuckTxManager struct {
+	Log              *TrackedTxLog
+	Deadline         time.Duration
+	MaxResubmissions int
+	// Idempotent are the functions that are safe to endorse again, such as
+	// functions setting absolute values.
+	Idempotent map[string]bool
+	// Status returns the status of a transaction seen in a block, false when
+	// it was not seen.
+	Status func(txID string) (TxStatus, bool)
+	// Resend submits the signed transaction again, and returns its status once
+	// committed, nil when it was not committed in time.
+	Resend func(ctx context.Context, tx TrackedTx) (*TxStatus, error)
+	// Endorse endorses and submits the function of tx again as a new
+	// transaction, and returns it.
+	Endorse func(ctx context.Context, tx TrackedTx) (TrackedTx, error)
+
+	now func() time.Time
+}
+
+// Track starts following a transaction submitted now.
+func (m *StuckTxManager) Track(tx TrackedTx) error {
+	now := m.clock()
+	tx.State = StuckPending
+	tx.SubmittedAt, tx.LastSubmittedAt = now, now
+	return m.Log.Put(tx)
+}
+
+// Resolve stops following a transaction whose outcome its submitter learned.
+func (m *StuckTxManager) Resolve(txID string) error {
+	return m.Log.Remove(txID)
+}
+
+// Report returns the transactions followed, oldest first, without their
+// signed transactions.
+func (m *StuckTxManager) Report() []TrackedTx {
+	txs := m.Log.List()
+	for i := range txs {
+		txs[i].Transaction = nil
+	}
+	return txs
+}
+
+// Check classifies the transactions followed, and resubmits those it can.
+func (m *StuckTxManager) Check(ctx context.Context) error {
+	var errs []error
+	for _, tx := range m.Log.List() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := m.check(ctx, tx); err != nil {
+			errs = append(errs, fmt.Errorf("transaction %s: %w", tx.TransactionID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *StuckTxManager) check(ctx context.Context, tx TrackedTx) error {
+	now := m.clock()
+	status, seen := m.Status(tx.TransactionID)
+	// a duplicate is a resent transaction, whose first commit is what counts
+	if seen && status.Status == peer.TxValidationCode_DUPLICATE_TXID.String() {
+		seen = false
+	}
+
+	if !seen && tx.State != StuckInvalidated {
+		if tx.State == StuckPending && now.Sub(tx.LastSubmittedAt) < m.Deadline {
+			return nil
+		}
+		if tx.Resubmissions >= m.MaxResubmissions {
+			if tx.State == StuckLost {
+				return nil
+			}
+			tx.State = StuckLost
+			return m.Log.Put(tx)
+		}
+
+		tx.Resubmissions++
+		tx.LastSubmittedAt = now
+		resent, err := m.Resend(ctx, tx)
+		if err != nil {
+			tx.State, tx.Error = StuckLost, err.Error()
+			return m.Log.Put(tx)
+		}
+		// a duplicate was committed before, in a block whose status is not known
+		if resent == nil || resent.Status == peer.TxValidationCode_DUPLICATE_TXID.String() {
+			tx.State, tx.Error = StuckPending, ""
+			return m.Log.Put(tx)
+		}
+		status, seen = *resent, true
+	}
+	if !seen {
+		return nil
+	}
+	if status.Status == peer.TxValidationCode_VALID.String() {
+		return m.Log.Remove(tx.TransactionID)
+	}
+
+	changed := tx.State != StuckInvalidated || tx.Code != status.Status
+	tx.State, tx.Code = StuckInvalidated, status.Status
+	if !m.Idempotent[tx.Function] || tx.Transient || tx.Resubmissions >= m.MaxResubmissions {
+		if !changed {
+			return nil
+		}
+		return m.Log.Put(tx)
+	}
+
+	tx.Resubmissions++
+	replacement, err := m.Endorse(ctx, tx)
+	if err != nil {
+		tx.Error = err.Error()
+		return m.Log.Put(tx)
+	}
+	replacement.State = StuckPending
+	replacement.SubmittedAt, replacement.LastSubmittedAt = tx.SubmittedAt, now
+	replacement.Resubmissions = tx.Resubmissions
+	replacement.Replaces = tx.TransactionID
+	if err := m.Log.Put(replacement); err != nil {
+		return err
+	}
+	return m.Log.Remove(tx.TransactionID)
+}
+
+// Run checks the transactions every interval until ctx is done, logging the
+// errors.
+func (m *StuckTxManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to check stuck transactions: %s", err)
+			}
+		}
+	}
+}
+
+func (m *StuckTxManager) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
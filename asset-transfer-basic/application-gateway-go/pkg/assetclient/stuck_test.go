@@ -0,0 +1,128 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrackedTxLogSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stuck.jsonl")
+	log, err := OpenTrackedTxLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txID := range []string{"tx1", "tx2"} {
+		if err := log.Put(TrackedTx{TransactionID: txID, Function: "TransferFunds", State: StuckPending}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Remove("tx1"); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	// a line torn by a crash is ignored
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	file.WriteString(`{"tx":{"transactionId":"tx3"`)
+	file.Close()
+
+	log, err = OpenTrackedTxLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	txs := log.List()
+	if len(txs) != 1 || txs[0].TransactionID != "tx2" {
+		t.Fatalf("expected only tx2 to be tracked, got %+v", txs)
+	}
+}
+
+func TestStuckTxManagerResubmits(t *testing.T) {
+	log, err := OpenTrackedTxLog(filepath.Join(t.TempDir(), "stuck.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	statuses := map[string]TxStatus{}
+	var resent, endorsed []string
+	manager := &StuckTxManager{
+		Log:              log,
+		Deadline:         time.Minute,
+		MaxResubmissions: 2,
+		Idempotent:       map[string]bool{"UpdateAsset": true},
+		Status: func(txID string) (TxStatus, bool) {
+			status, ok := statuses[txID]
+			return status, ok
+		},
+		Resend: func(ctx context.Context, tx TrackedTx) (*TxStatus, error) {
+			resent = append(resent, tx.TransactionID)
+			return nil, nil
+		},
+		Endorse: func(ctx context.Context, tx TrackedTx) (TrackedTx, error) {
+			endorsed = append(endorsed, tx.TransactionID)
+			return TrackedTx{TransactionID: tx.TransactionID + "-again", Function: tx.Function, Args: tx.Args}, nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	for _, tx := range []TrackedTx{
+		{TransactionID: "lost", Function: "TransferFunds"},
+		{TransactionID: "valid", Function: "TransferFunds"},
+		{TransactionID: "conflict", Function: "UpdateAsset"},
+		{TransactionID: "rejected", Function: "TransferFunds"},
+	} {
+		if err := manager.Track(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	statuses["valid"] = TxStatus{Status: "VALID"}
+	statuses["conflict"] = TxStatus{Status: "MVCC_READ_CONFLICT"}
+	statuses["rejected"] = TxStatus{Status: "MVCC_READ_CONFLICT"}
+
+	// before the deadline only the committed transactions are classified
+	if err := manager.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(resent) != 0 || len(endorsed) != 1 || endorsed[0] != "conflict" {
+		t.Fatalf("unexpected resubmissions %v, %v", resent, endorsed)
+	}
+	if _, ok := log.Get("valid"); ok {
+		t.Fatal("expected the valid transaction no longer to be tracked")
+	}
+	if tx, _ := log.Get("rejected"); tx.State != StuckInvalidated || tx.Code != "MVCC_READ_CONFLICT" {
+		t.Fatalf("expected the transaction that is not idempotent to be invalidated, got %+v", tx)
+	}
+	if tx, _ := log.Get("conflict-again"); tx.Replaces != "conflict" || tx.State != StuckPending || tx.Resubmissions != 1 {
+		t.Fatalf("unexpected replacement %+v", tx)
+	}
+
+	// past the deadline the lost transactions are resent, until their resubmissions are used up
+	for _, expected := range [][]string{{"conflict-again", "lost"}, {"conflict-again", "lost", "lost"}} {
+		now = now.Add(2 * time.Minute)
+		if err := manager.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resent, expected) {
+			t.Fatalf("expected %v to be resent, got %v", expected, resent)
+		}
+	}
+	if tx, _ := log.Get("lost"); tx.State != StuckPending || tx.Resubmissions != 2 {
+		t.Fatalf("expected the resent transaction to be pending, got %+v", tx)
+	}
+	if tx, _ := log.Get("conflict-again"); tx.State != StuckLost || tx.Resubmissions != 2 {
+		t.Fatalf("expected the transaction to be lost once its resubmissions are used up, got %+v", tx)
+	}
+	if report := manager.Report(); len(report) != 3 {
+		t.Fatalf("expected 3 transactions in the report, got %+v", report)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TLSOptions hardens the TLS connections to the peers beyond trusting their CA:
+// pinning the peer certificates and restricting the TLS versions and cipher
+// suites negotiated.
+type TLSOptions struct {
+	// PinnedFingerprints are the SHA-256 fingerprints, in lowercase hex, of the
+	// peer TLS certificates accepted. Empty accepts any certificate issued by
+	// the CA.
+	PinnedFingerprints []string
+	// MinVersion is the minimum TLS version, such as tls.VersionTLS13. Zero
+	// keeps the Go default of TLS 1.2.
+	MinVersion uint16
+	// CipherSuites restricts the TLS 1.2 cipher suites. TLS 1.3 suites are not
+	// configurable in Go.
+	CipherSuites []uint16
+}
+
+// TLSOptionsFromEnv reads the TLS options from TLS_PIN_SHA256, a comma separated
+// list of certificate fingerprints, TLS_MIN_VERSION, 1.2 or 1.3, and
+// TLS_CIPHER_SUITES, a comma separated list of Go cipher suite names.
+func TLSOptionsFromEnv() (TLSOptions, error) {
+	return ParseTLSOptions(os.Getenv("TLS_PIN_SHA256"), os.Getenv("TLS_MIN_VERSION"), os.Getenv("TLS_CIPHER_SUITES"))
+}
+
+// ParseTLSOptions parses the TLS options. Fingerprints may be written with or
+// without colons, as printed by openssl x509 -fingerprint -sha256.
+func ParseTLSOptions(pins string, minVersion string, cipherSuites string) (TLSOptions, error) {
+	var options TLSOptions
+	for _, pin := range splitList(pins) {
+		fingerprint := strings.ToLower(strings.ReplaceAll(pin, ":", ""))
+		if decoded, err := hex.DecodeString(fingerprint); err != nil || len(decoded) != sha256.Size {
+			return TLSOptions{}, fmt.Errorf("invalid TLS certificate fingerprint %q, expected a SHA-256 hash in hex", pin)
+		}
+		options.PinnedFingerprints = append(options.PinnedFingerprints, fingerprint)
+	}
+
+	switch minVersion {
+	case "":
+	case "1.2":
+		options.MinVersion = tls.VersionTLS12
+	case "1.3":
+		options.MinVersion = tls.VersionTLS13
+	default:
+		return TLSOptions{}, fmt.Errorf("unsupported minimum TLS version %q, expected 1.2 or 1.3", minVersion)
+	}
+
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+	for _, name := range splitList(cipherSuites) {
+		id, ok := suites[name]
+		if !ok {
+			return TLSOptions{}, fmt.Errorf("unknown or insecure TLS cipher suite %q", name)
+		}
+		options.CipherSuites = append(options.CipherSuites, id)
+	}
+	return options, nil
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// IsSet reports whether any option is set.
+func (o TLSOptions) IsSet() bool {
+	return len(o.PinnedFingerprints) > 0 || o.MinVersion != 0 || len(o.CipherSuites) > 0
+}
+
+// Config returns the TLS configuration of connections to the peer named
+// serverName, trusting the certificates of roots and applying the options.
+func (o TLSOptions) Config(roots *x509.CertPool, serverName string) *tls.Config {
+	config := &tls.Config{
+		RootCAs:      roots,
+		ServerName:   serverName,
+		MinVersion:   o.MinVersion,
+		CipherSuites: o.CipherSuites,
+	}
+	if len(o.PinnedFingerprints) > 0 {
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return o.verifyPin(serverName, state)
+		}
+	}
+	return config
+}
+
+// PinMismatchError is returned when a peer presents a TLS certificate whose
+// fingerprint is not pinned.
+type PinMismatchError struct {
+	ServerName  string
+	Fingerprint string
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("TLS certificate of %s has SHA-256 fingerprint %s, which is not pinned in TLS_PIN_SHA256", e.ServerName, e.Fingerprint)
+}
+
+func (o TLSOptions) verifyPin(serverName string, state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return &PinMismatchError{ServerName: serverName}
+	}
+	fingerprint := CertificateFingerprint(state.PeerCertificates[0])
+	for _, pinned := range o.PinnedFingerprints {
+		if pinned == fingerprint {
+			return nil
+		}
+	}
+	return &PinMismatchError{ServerName: serverName, Fingerprint: fingerprint}
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a certificate in
+// lowercase hex, the format of pinned fingerprints.
+func CertificateFingerprint(certificate *x509.Certificate) string {
+	fingerprint := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(fingerprint[:])
+}
+
+// CheckTLS completes a TLS handshake with the peer at target using config, so
+// that pinning or version mismatches fail at startup with a clear error rather
+// than on the first gRPC call. Target may be a gRPC target such as
+// dns:///localhost:7051.
+func CheckTLS(ctx context.Context, target string, config *tls.Config) error {
+	address := target
+	if _, endpoint, found := strings.Cut(target, ":///"); found {
+		address = endpoint
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	dialer := tls.Dialer{NetDialer: &net.Dialer{}, Config: config}
+	connection, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("TLS verification of %s failed: %w", target, err)
+	}
+	return connection.Close()
+}
@@ -0,0 +1,63 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTLSOptions(t *testing.T) {
+	pin := strings.Repeat("AB:", 31) + "AB"
+	options, err := ParseTLSOptions(pin, "1.3", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.PinnedFingerprints[0] != strings.Repeat("ab", 32) {
+		t.Fatalf("expected a normalized fingerprint, got %s", options.PinnedFingerprints[0])
+	}
+	if options.MinVersion != tls.VersionTLS13 || options.CipherSuites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("unexpected options %+v", options)
+	}
+
+	if options, err := ParseTLSOptions("", "", ""); err != nil || options.IsSet() {
+		t.Fatalf("expected no options, got %+v, %v", options, err)
+	}
+	for name, args := range map[string][3]string{
+		"short pin":       {"abcd", "", ""},
+		"unknown version": {"", "1.1", ""},
+		"insecure suite":  {"", "", "TLS_RSA_WITH_RC4_128_SHA"},
+	} {
+		if _, err := ParseTLSOptions(args[0], args[1], args[2]); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheckTLSVerifiesPins(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	target := "dns:///" + server.Listener.Addr().String()
+
+	pinned := TLSOptions{PinnedFingerprints: []string{CertificateFingerprint(server.Certificate())}}
+	if err := CheckTLS(context.Background(), target, pinned.Config(roots, "example.com")); err != nil {
+		t.Fatalf("expected the pinned certificate to be accepted, got %v", err)
+	}
+
+	other := TLSOptions{PinnedFingerprints: []string{strings.Repeat("00", 32)}}
+	err := CheckTLS(context.Background(), target, other.Config(roots, "example.com"))
+	var mismatch *PinMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Fingerprint != CertificateFingerprint(server.Certificate()) {
+		t.Fatalf("expected a pin mismatch naming the presented certificate, got %v", err)
+	}
+}
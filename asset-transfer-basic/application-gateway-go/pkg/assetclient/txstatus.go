@@ -0,0 +1,148 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// TxPending is the status of a submitted transaction not yet seen in a block.
+const TxPending = "PENDING"
+
+// TxStatus is the commit status of a transaction. Status is TxPending until the
+// transaction is seen in a block, then the name of its validation code, such
+// as VALID or MVCC_READ_CONFLICT.
+type TxStatus struct {
+	TransactionID string    `json:"transactionId"`
+	Status        string    `json:"status"`
+	Code          int32     `json:"code"`
+	BlockNumber   uint64    `json:"blockNumber,omitempty"`
+	SubmittedAt   time.Time `json:"submittedAt,omitempty"`
+	CommittedAt   time.Time `json:"committedAt,omitempty"`
+}
+
+// Committed reports whether the transaction was seen in a block, valid or not.
+func (s TxStatus) Committed() bool {
+	return s.Status != TxPending
+}
+
+// TxStatusStore keeps the commit status of recent transactions in memory, fed
+// by the filtered block events of the channel, so that the status of a
+// transaction can be looked up after the request submitting it completed.
+// Entries expire ttl after their last change. It is safe for concurrent use.
+type TxStatusStore struct {
+	// OnCommitted, when set, is called by Run with the status of every
+	// transaction seen in a block.
+	OnCommitted func(status TxStatus)
+
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	statuses map[string]*txStatusEntry
+}
+
+type txStatusEntry struct {
+	status  TxStatus
+	expires time.Time
+}
+
+// NewTxStatusStore creates a store keeping statuses for ttl.
+func NewTxStatusStore(ttl time.Duration) *TxStatusStore {
+	return &TxStatusStore{ttl: ttl, now: time.Now, statuses: make(map[string]*txStatusEntry)}
+}
+
+// Submitted records a submitted transaction as pending, unless its block was
+// already seen.
+func (s *TxStatusStore) Submitted(txID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if entry, ok := s.statuses[txID]; ok {
+		entry.status.SubmittedAt = now
+		return
+	}
+	s.statuses[txID] = &txStatusEntry{
+		status:  TxStatus{TransactionID: txID, Status: TxPending, SubmittedAt: now},
+		expires: now.Add(s.ttl),
+	}
+}
+
+// Committed records the validation code of a transaction seen in a block.
+func (s *TxStatusStore) Committed(txID string, blockNumber uint64, code peer.TxValidationCode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	entry, ok := s.statuses[txID]
+	if !ok {
+		entry = &txStatusEntry{status: TxStatus{TransactionID: txID}}
+		s.statuses[txID] = entry
+	}
+	entry.status.Status = code.String()
+	entry.status.Code = int32(code)
+	entry.status.BlockNumber = blockNumber
+	entry.status.CommittedAt = now
+	entry.expires = now.Add(s.ttl)
+}
+
+// Get returns the status of a transaction, false when it is unknown or expired.
+func (s *TxStatusStore) Get(txID string) (TxStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.statuses[txID]
+	if !ok || !s.now().Before(entry.expires) {
+		return TxStatus{}, false
+	}
+	return entry.status, true
+}
+
+// Evict removes the expired statuses.
+func (s *TxStatusStore) Evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for txID, entry := range s.statuses {
+		if !now.Before(entry.expires) {
+			delete(s.statuses, txID)
+		}
+	}
+}
+
+// Run records the transactions of the blocks read from blocks, and evicts the
+// expired statuses, until the channel closes or ctx is done.
+func (s *TxStatusStore) Run(ctx context.Context, blocks <-chan *peer.FilteredBlock) {
+	ticker := time.NewTicker(s.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Evict()
+		case block, ok := <-blocks:
+			if !ok {
+				return
+			}
+			for _, transaction := range block.GetFilteredTransactions() {
+				s.Committed(transaction.GetTxid(), block.GetNumber(), transaction.GetTxValidationCode())
+				if s.OnCommitted == nil {
+					continue
+				}
+				if status, ok := s.Get(transaction.GetTxid()); ok {
+					s.OnCommitted(status)
+				}
+			}
+		}
+	}
+}
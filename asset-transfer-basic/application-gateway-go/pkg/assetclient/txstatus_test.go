@@ -0,0 +1,75 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+func newTestTxStatusStore(ttl time.Duration) (*TxStatusStore, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewTxStatusStore(ttl)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestTxStatusStoreTracksCommits(t *testing.T) {
+	store, _ := newTestTxStatusStore(time.Minute)
+
+	store.Submitted("tx1")
+	status, ok := store.Get("tx1")
+	if !ok || status.Committed() {
+		t.Fatalf("expected a pending transaction, got %+v, %t", status, ok)
+	}
+
+	store.Committed("tx1", 7, peer.TxValidationCode_MVCC_READ_CONFLICT)
+	status, ok = store.Get("tx1")
+	if !ok || !status.Committed() || status.BlockNumber != 7 || status.Code != int32(peer.TxValidationCode_MVCC_READ_CONFLICT) {
+		t.Fatalf("expected a committed transaction, got %+v, %t", status, ok)
+	}
+	if status.SubmittedAt.IsZero() {
+		t.Fatal("expected the submission time to be kept")
+	}
+}
+
+func TestTxStatusStoreKeepsCommitsSeenFirst(t *testing.T) {
+	store, _ := newTestTxStatusStore(time.Minute)
+
+	store.Committed("tx1", 3, peer.TxValidationCode_VALID)
+	store.Submitted("tx1")
+	if status, _ := store.Get("tx1"); !status.Committed() {
+		t.Fatalf("expected the commit to be kept, got %+v", status)
+	}
+}
+
+func TestTxStatusStoreExpires(t *testing.T) {
+	store, now := newTestTxStatusStore(time.Minute)
+
+	store.Submitted("tx1")
+	*now = now.Add(2 * time.Minute)
+	if _, ok := store.Get("tx1"); ok {
+		t.Fatal("expected the status to expire")
+	}
+	store.Evict()
+	if len(store.statuses) != 0 {
+		t.Fatalf("expected the expired status to be evicted, %d left", len(store.statuses))
+	}
+}
+
+func TestTxStatusStoreRunsOnBlocks(t *testing.T) {
+	store := NewTxStatusStore(time.Minute)
+	blocks := make(chan *peer.FilteredBlock, 1)
+	blocks <- &peer.FilteredBlock{Number: 9, FilteredTransactions: []*peer.FilteredTransaction{{Txid: "tx1"}}}
+	close(blocks)
+
+	store.Run(context.Background(), blocks)
+	if status, ok := store.Get("tx1"); !ok || status.BlockNumber != 9 {
+		t.Fatalf("expected tx1 committed in block 9, got %+v, %t", status, ok)
+	}
+}
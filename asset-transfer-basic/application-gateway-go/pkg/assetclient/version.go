@@ -0,0 +1,104 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// VersionInfo is the build of the chaincode serving a peer, as returned by
+// GetVersionInfo. Modified is set for builds of a checkout with uncommitted
+// changes, and PackageID is the package ID of the chaincode server.
+type VersionInfo struct {
+	BuildTime          string `json:"buildtime"`
+	ContractAPIVersion string `json:"contractapiversion"`
+	GitCommit          string `json:"gitcommit"`
+	GoVersion          string `json:"goversion"`
+	Modified           bool   `json:"modified,omitempty"`
+	PackageID          string `json:"packageid,omitempty"`
+	SchemaVersion      int    `json:"schemaversion"`
+}
+
+func (v *VersionInfo) String() string {
+	commit := v.GitCommit
+	if v.Modified {
+		commit += " (modified)"
+	}
+	return fmt.Sprintf("commit %s built %s, contract API %s, %s, state schema %d, package %s",
+		commit, v.BuildTime, v.ContractAPIVersion, v.GoVersion, v.SchemaVersion, v.PackageID)
+}
+
+// ReadVersionInfo evaluates GetVersionInfo, returning the build of the chaincode
+// on the peer the Gateway evaluates on.
+func ReadVersionInfo(ctx context.Context, contract Evaluator) (*VersionInfo, error) {
+	result, err := contract.EvaluateWithContext(ctx, "GetVersionInfo")
+	if err != nil {
+		return nil, err
+	}
+	var info VersionInfo
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse the version info: %w", err)
+	}
+	return &info, nil
+}
+
+// PeerVersion is the chaincode build of a peer, or the error reading it.
+type PeerVersion struct {
+	Peer    string       `json:"peer"`
+	Version *VersionInfo `json:"version,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// PeerVersions reads the chaincode build of the submit Gateway, named submitName,
+// and of every replica, whether available or not.
+func (r *ReplicaRouter) PeerVersions(ctx context.Context, channel string, chaincode string, submitName string) []PeerVersion {
+	versions := make([]PeerVersion, len(r.replicas)+1)
+	read := func(i int, name string, contract Evaluator) {
+		versions[i].Peer = name
+		info, err := ReadVersionInfo(ctx, contract)
+		if err != nil {
+			versions[i].Error = err.Error()
+			return
+		}
+		versions[i].Version = info
+	}
+
+	var wg sync.WaitGroup
+	for i, replica := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read(i+1, replica.Name, replica.Gateway.GetNetwork(channel).GetContract(chaincode))
+		}()
+	}
+	read(0, submitName, r.submit.GetNetwork(channel).GetContract(chaincode))
+	wg.Wait()
+	return versions
+}
+
+// VersionsDiffer reports whether the peers that answered run different builds
+// of the chaincode. Package IDs are not compared, as the packages of chaincode
+// servers differ by their connection.json.
+func VersionsDiffer(versions []PeerVersion) bool {
+	var first *VersionInfo
+	for _, version := range versions {
+		if version.Version == nil {
+			continue
+		}
+		if first == nil {
+			first = version.Version
+			continue
+		}
+		build := *version.Version
+		build.PackageID = first.PackageID
+		if build != *first {
+			return true
+		}
+	}
+	return false
+}
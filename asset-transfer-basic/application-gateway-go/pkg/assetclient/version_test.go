@@ -0,0 +1,39 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assetclient
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadVersionInfoAndCompareBuilds(t *testing.T) {
+	contract := &fakeEvaluator{results: []fakeResult{
+		{json: `{"buildtime":"2024-05-01T10:00:00Z","contractapiversion":"v2.0.0","gitcommit":"1a2b3c","goversion":"go1.22.3","packageid":"basic_1.0:aa","schemaversion":2}`},
+	}}
+	info, err := ReadVersionInfo(context.Background(), contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.GitCommit != "1a2b3c" || info.SchemaVersion != 2 || info.PackageID != "basic_1.0:aa" {
+		t.Fatalf("unexpected version info %+v", info)
+	}
+
+	org2 := *info
+	org2.PackageID = "basic_1.0:bb"
+	versions := []PeerVersion{
+		{Peer: "peer0", Version: info},
+		{Peer: "peer1", Error: "unavailable"},
+		{Peer: "peer2", Version: &org2},
+	}
+	if VersionsDiffer(versions) {
+		t.Fatal("expected packages of the same build to match")
+	}
+	rebuilt := *info
+	rebuilt.GitCommit = "4d5e6f"
+	if !VersionsDiffer(append(versions, PeerVersion{Peer: "peer3", Version: &rebuilt})) {
+		t.Fatal("expected another commit to differ")
+	}
+}
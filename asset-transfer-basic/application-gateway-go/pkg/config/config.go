@@ -0,0 +1,397 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package config resolves the settings of a command from its flags, the
+// environment and a JSON configuration file, in this order of precedence, over
+// their defaults. Settings are declared on a Set like flags on a FlagSet, and
+// each is registered as a flag of the FlagSet of the Set.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source is where the value of a setting comes from.
+type Source int
+
+// The sources of settings, in increasing order of precedence.
+const (
+	SourceDefault Source = iota
+	SourceFile
+	SourceEnv
+	SourceFlag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// masked is shown instead of the value of a secret setting.
+const masked = "********"
+
+// Setting is the effective value of a setting and where it comes from. The
+// Value of a secret setting is masked unless empty.
+type Setting struct {
+	Name   string
+	Env    string
+	Usage  string
+	Value  string
+	Source Source
+}
+
+// Set is a set of settings. The settings of a Set created with Sub are read
+// from the section of the configuration file named after their FlagSet.
+type Set struct {
+	flags    *flag.FlagSet
+	settings []*setting
+	parent   *Set
+	file     *setting
+
+	// filePath and values are the configuration file and its values,
+	// loaded by Resolve.
+	filePath string
+	values   map[string]json.RawMessage
+}
+
+type setting struct {
+	name   string
+	env    string
+	usage  string
+	secret bool
+	isBool bool
+	// parse parses, validates and stores a value of the setting.
+	parse  func(text string) error
+	value  string
+	source Source
+}
+
+// NewSet returns a Set registering its settings as flags of flags.
+func NewSet(flags *flag.FlagSet) *Set {
+	return &Set{flags: flags}
+}
+
+// Sub returns a Set for the flags of a subcommand, whose settings are read from
+// the section of the configuration file of s named after flags, such as
+// {"submit": {"wait": "commit"}}. s must be resolved before the returned Set.
+func (s *Set) Sub(flags *flag.FlagSet) *Set {
+	return &Set{flags: flags, parent: s}
+}
+
+// File declares the setting naming the configuration file. It is read from its
+// flag and environment variable only.
+func (s *Set) File(name string, env string, usage string) *string {
+	path := s.String(name, env, "", usage)
+	s.file = s.settings[len(s.settings)-1]
+	return path
+}
+
+// String declares a string setting, validated by checks.
+func (s *Set) String(name string, env string, value string, usage string, checks ...func(string) error) *string {
+	target := new(string)
+	s.declare(name, env, value, usage, func(text string) error {
+		for _, check := range checks {
+			if err := check(text); err != nil {
+				return err
+			}
+		}
+		*target = text
+		return nil
+	})
+	return target
+}
+
+// Secret declares a string setting whose value is masked in the effective settings.
+func (s *Set) Secret(name string, env string, usage string) *string {
+	target := s.String(name, env, "", usage)
+	s.settings[len(s.settings)-1].secret = true
+	return target
+}
+
+// Int declares an integer setting, validated by checks.
+func (s *Set) Int(name string, env string, value int, usage string, checks ...func(int) error) *int {
+	target := new(int)
+	s.declare(name, env, strconv.Itoa(value), usage, func(text string) error {
+		parsed, err := strconv.Atoi(text)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		return store(target, parsed, checks)
+	})
+	return target
+}
+
+// Float declares a floating point setting, validated by checks.
+func (s *Set) Float(name string, env string, value float64, usage string, checks ...func(float64) error) *float64 {
+	target := new(float64)
+	s.declare(name, env, strconv.FormatFloat(value, 'g', -1, 64), usage, func(text string) error {
+		parsed, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		return store(target, parsed, checks)
+	})
+	return target
+}
+
+// Duration declares a duration setting, such as 1m30s, validated by checks.
+func (s *Set) Duration(name string, env string, value time.Duration, usage string, checks ...func(time.Duration) error) *time.Duration {
+	target := new(time.Duration)
+	s.declare(name, env, value.String(), usage, func(text string) error {
+		parsed, err := time.ParseDuration(text)
+		if err != nil {
+			return errors.New("not a duration, such as 1m30s")
+		}
+		return store(target, parsed, checks)
+	})
+	return target
+}
+
+// Bool declares a boolean setting.
+func (s *Set) Bool(name string, env string, value bool, usage string) *bool {
+	target := new(bool)
+	s.declare(name, env, strconv.FormatBool(value), usage, func(text string) error {
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			return errors.New("not true or false")
+		}
+		*target = parsed
+		return nil
+	})
+	s.settings[len(s.settings)-1].isBool = true
+	return target
+}
+
+func store[T any](target *T, value T, checks []func(T) error) error {
+	for _, check := range checks {
+		if err := check(value); err != nil {
+			return err
+		}
+	}
+	*target = value
+	return nil
+}
+
+// declare adds a setting holding value by default, and registers its flag.
+func (s *Set) declare(name string, env string, value string, usage string, parse func(text string) error) {
+	setting := &setting{name: name, env: env, usage: usage, parse: parse, value: value}
+	if err := parse(value); err != nil {
+		panic(fmt.Sprintf("invalid default %q of setting %s: %v", value, name, err))
+	}
+	s.settings = append(s.settings, setting)
+
+	if env != "" {
+		usage = fmt.Sprintf("%s (env %s)", usage, env)
+	}
+	s.flags.Var(&flagValue{setting}, name, usage)
+}
+
+// Resolve sets the settings not set by their flag from their environment
+// variable, looked up with lookupEnv, else from the configuration file, else
+// to their default. It returns the errors of every invalid value, naming where
+// each comes from.
+func (s *Set) Resolve(lookupEnv func(string) (string, bool)) error {
+	var errs []error
+	if s.file != nil {
+		if err := s.resolveSetting(s.file, lookupEnv); err != nil {
+			errs = append(errs, err)
+		} else if err := s.loadFile(s.file.value); err != nil {
+			errs = append(errs, err)
+		}
+	} else if s.parent != nil {
+		if err := s.loadSection(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, setting := range s.settings {
+		if setting == s.file {
+			continue
+		}
+		if err := s.resolveSetting(setting, lookupEnv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Set) resolveSetting(setting *setting, lookupEnv func(string) (string, bool)) error {
+	if setting.source == SourceFlag {
+		return nil
+	}
+	if setting.env != "" {
+		if text, ok := lookupEnv(setting.env); ok && text != "" {
+			return s.apply(setting, text, SourceEnv, "environment variable "+setting.env)
+		}
+	}
+
+	raw, ok := s.values[setting.name]
+	if !ok || setting == s.file {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		// numbers and booleans are parsed from their JSON text
+		text = string(raw)
+	}
+	return s.apply(setting, text, SourceFile, "config file "+s.filePath)
+}
+
+func (s *Set) apply(setting *setting, text string, source Source, origin string) error {
+	if err := setting.parse(text); err != nil {
+		return invalidValue(setting, text, origin, err)
+	}
+	setting.value = text
+	setting.source = source
+	return nil
+}
+
+// loadFile reads the JSON object of the configuration file at path, if any.
+// Keys that are not settings of s must be the sections of subcommands.
+func (s *Set) loadFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(content, &values); err != nil {
+		return fmt.Errorf("config file %s is not a JSON object: %w", path, err)
+	}
+	s.filePath, s.values = path, values
+
+	for key, raw := range values {
+		if s.lookup(key) == nil && !isObject(raw) {
+			return fmt.Errorf("config file %s sets the unknown setting %s", path, key)
+		}
+	}
+	return nil
+}
+
+// loadSection takes the values of s from the section of the configuration file
+// of its parent named after its FlagSet.
+func (s *Set) loadSection() error {
+	s.filePath = s.parent.filePath
+	raw, ok := s.parent.values[s.flags.Name()]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &s.values); err != nil {
+		return fmt.Errorf("config file %s: the section %s is not a JSON object: %w", s.filePath, s.flags.Name(), err)
+	}
+	for key := range s.values {
+		if s.lookup(key) == nil {
+			return fmt.Errorf("config file %s sets the unknown setting %s of %s", s.filePath, key, s.flags.Name())
+		}
+	}
+	return nil
+}
+
+func (s *Set) lookup(name string) *setting {
+	for _, setting := range s.settings {
+		if setting.name == name {
+			return setting
+		}
+	}
+	return nil
+}
+
+// Effective returns the settings in order of declaration, with their values
+// and sources.
+func (s *Set) Effective() []Setting {
+	effective := make([]Setting, 0, len(s.settings))
+	for _, setting := range s.settings {
+		value := setting.value
+		if setting.secret && value != "" {
+			value = masked
+		}
+		effective = append(effective, Setting{
+			Name:   setting.name,
+			Env:    setting.env,
+			Usage:  setting.usage,
+			Value:  value,
+			Source: setting.source,
+		})
+	}
+	return effective
+}
+
+func invalidValue(setting *setting, text string, origin string, err error) error {
+	if setting.secret {
+		return fmt.Errorf("invalid %s from %s: %w", setting.name, origin, err)
+	}
+	return fmt.Errorf("invalid %s %q from %s: %w", setting.name, text, origin, err)
+}
+
+func isObject(raw json.RawMessage) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal(raw, &object) == nil
+}
+
+// flagValue is the flag.Value of a setting, marking it set by its flag.
+type flagValue struct {
+	setting *setting
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.setting == nil {
+		return ""
+	}
+	if v.setting.secret {
+		return ""
+	}
+	return v.setting.value
+}
+
+func (v *flagValue) Set(text string) error {
+	if err := v.setting.parse(text); err != nil {
+		return err
+	}
+	v.setting.value = text
+	v.setting.source = SourceFlag
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.setting != nil && v.setting.isBool
+}
+
+// OneOf checks that a string setting is one of values.
+func OneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, allowed := range values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s", strings.Join(values, ", "))
+	}
+}
+
+// AtLeast checks that a numeric setting is min or more.
+func AtLeast[T int | float64 | time.Duration](min T) func(T) error {
+	return func(value T) error {
+		if value < min {
+			return fmt.Errorf("expected at least %v", min)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a lookup of the environment variables of values.
+func env(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	path := writeConfigFile(t, `{"channel": "filechannel", "chaincode": "filecc", "parallel": 8, "local": true, "submit": {"wait": "orgs:2"}}`)
+
+	flags := flag.NewFlagSet("assetTransfer", flag.ContinueOnError)
+	settings := NewSet(flags)
+	settings.File("config", "CONFIG_FILE", "configuration file")
+	channel := settings.String("channel", "CHANNEL_NAME", "mychannel", "channel")
+	chaincode := settings.String("chaincode", "CHAINCODE_NAME", "basic", "chaincode")
+	peer := settings.String("peer", "PEER_ENDPOINT", "localhost:7051", "peer")
+	parallel := settings.Int("parallel", "", 4, "parallelism")
+	local := settings.Bool("local", "", false, "local ledger")
+	if err := flags.Parse([]string{"-channel", "flagchannel"}); err != nil {
+		t.Fatal(err)
+	}
+	err := settings.Resolve(env(map[string]string{"CONFIG_FILE": path, "CHANNEL_NAME": "envchannel", "CHAINCODE_NAME": "envcc"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *channel != "flagchannel" || *chaincode != "envcc" || *peer != "localhost:7051" || *parallel != 8 || !*local {
+		t.Fatalf("unexpected settings %s %s %s %d %t", *channel, *chaincode, *peer, *parallel, *local)
+	}
+	sources := make(map[string]Source)
+	for _, setting := range settings.Effective() {
+		sources[setting.Name] = setting.Source
+	}
+	if sources["config"] != SourceEnv || sources["channel"] != SourceFlag || sources["chaincode"] != SourceEnv ||
+		sources["peer"] != SourceDefault || sources["parallel"] != SourceFile {
+		t.Fatalf("unexpected sources %v", sources)
+	}
+
+	submitFlags := flag.NewFlagSet("submit", flag.ContinueOnError)
+	submit := settings.Sub(submitFlags)
+	wait := submit.String("wait", "COMMIT_WAIT", "commit", "commit wait")
+	if err := submitFlags.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := submit.Resolve(env(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if *wait != "orgs:2" {
+		t.Fatalf("expected the wait of the submit section, got %s", *wait)
+	}
+}
+
+func TestResolveReportsInvalidValues(t *testing.T) {
+	path := writeConfigFile(t, `{"parallel": "many", "target-latency": -1}`)
+
+	flags := flag.NewFlagSet("assetTransfer", flag.ContinueOnError)
+	settings := NewSet(flags)
+	settings.File("config", "", "configuration file")
+	settings.Int("parallel", "", 4, "parallelism", AtLeast(1))
+	settings.String("format", "IMPORT_FORMAT", "csv", "import format", OneOf("csv", "legacy"))
+	settings.Duration("target-latency", "", 0, "target latency", AtLeast(time.Duration(0)))
+	settings.Secret("page-token-key", "PAGE_TOKEN_KEY", "page token key")
+	if err := flags.Parse([]string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := settings.Resolve(env(map[string]string{"IMPORT_FORMAT": "xml"}))
+	if err == nil {
+		t.Fatal("expected the invalid values to be reported")
+	}
+	for _, expected := range []string{
+		`invalid parallel "many" from config file ` + path + `: not an integer`,
+		`invalid format "xml" from environment variable IMPORT_FORMAT: expected one of csv, legacy`,
+		`invalid target-latency "-1" from config file ` + path + `: not a duration`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err)
+		}
+	}
+
+	if err := flags.Set("parallel", "0"); err == nil {
+		t.Error("expected the flag to be validated")
+	}
+}
+
+func TestResolveRejectsUnknownSettings(t *testing.T) {
+	path := writeConfigFile(t, `{"chanel": "mychannel"}`)
+
+	flags := flag.NewFlagSet("assetTransfer", flag.ContinueOnError)
+	settings := NewSet(flags)
+	settings.File("config", "", "configuration file")
+	settings.String("channel", "", "mychannel", "channel")
+	if err := flags.Parse([]string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := settings.Resolve(env(nil)); err == nil || !strings.Contains(err.Error(), "unknown setting chanel") {
+		t.Fatalf("expected the unknown setting to be reported, got %v", err)
+	}
+}
+
+func TestEffectiveMasksSecrets(t *testing.T) {
+	flags := flag.NewFlagSet("assetTransfer", flag.ContinueOnError)
+	settings := NewSet(flags)
+	settings.Secret("page-token-key", "PAGE_TOKEN_KEY", "page token key")
+	if err := settings.Resolve(env(map[string]string{"PAGE_TOKEN_KEY": "s3cr3t"})); err != nil {
+		t.Fatal(err)
+	}
+
+	effective := settings.Effective()
+	if len(effective) != 1 || effective[0].Value != masked || effective[0].Source != SourceEnv {
+		t.Fatalf("expected a masked secret from the environment, got %+v", effective)
+	}
+}
@@ -0,0 +1,407 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gatewaytest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Golden is a recorded set of transactions, replayed by a Server to answer the
+// same calls as the network it was recorded against. Golden files are written
+// by a Recorder or by hand:
+//
+//	{
+//	  "description": "an asset read, a failed read and a conflicting update",
+//	  "transactions": [
+//	    {"function":"ReadAsset","args":["asset1"],"result":{"ID":"asset1","balance":100}},
+//	    {"function":"ReadAsset","args":["asset9"],"chaincodeError":"{\"code\":\"ASSET_NOT_FOUND\",\"message\":\"asset9\"}"},
+//	    {"function":"UpdateAsset","submit":true,"validationCode":"MVCC_READ_CONFLICT"}
+//	  ]
+//	}
+type Golden struct {
+	Description  string              `json:"description"`
+	Transactions []GoldenTransaction `json:"transactions"`
+}
+
+// GoldenTransaction is a call and its outcome. A call matches the transactions
+// of its function and, unless Args is omitted, its arguments, evaluated or
+// submitted as Submit tells. Matching transactions are served in turn, the
+// last one again once all were served.
+//
+// Result is returned as the JSON it holds, compacted as the contract API
+// marshals results however the golden file is indented, but for JSON strings,
+// returned as the raw string as the chaincode returns string results. The call fails with
+// Error, a gRPC status, or with ChaincodeError, the message of the chaincode,
+// once Delay, such as "2s", has passed. Submitted transactions commit with
+// ValidationCode, VALID when empty, and Event.
+type GoldenTransaction struct {
+	Function       string          `json:"function"`
+	Args           []string        `json:"args,omitempty"`
+	Submit         bool            `json:"submit,omitempty"`
+	Result         json.RawMessage `json:"result,omitempty"`
+	ChaincodeError string          `json:"chaincodeError,omitempty"`
+	Error          *GoldenError    `json:"error,omitempty"`
+	ValidationCode string          `json:"validationCode,omitempty"`
+	Event          *GoldenEvent    `json:"event,omitempty"`
+	Delay          string          `json:"delay,omitempty"`
+}
+
+// GoldenError is the gRPC status of a failed call. Code is the name of a gRPC
+// code, such as Unavailable or DeadlineExceeded.
+type GoldenError struct {
+	Code    string              `json:"code"`
+	Message string              `json:"message"`
+	Details []GoldenErrorDetail `json:"details,omitempty"`
+}
+
+// GoldenErrorDetail is the error of a peer or orderer behind the Gateway.
+type GoldenErrorDetail struct {
+	Address string `json:"address"`
+	MspID   string `json:"mspId"`
+	Message string `json:"message"`
+}
+
+// GoldenEvent is the chaincode event of a submitted transaction, with a payload
+// encoded as Result is.
+type GoldenEvent struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// LoadGolden reads and validates a golden file.
+func LoadGolden(path string) (*Golden, error) {
+	goldenJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var golden Golden
+	if err := json.Unmarshal(goldenJSON, &golden); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := golden.validate(); err != nil {
+		return nil, fmt.Errorf("invalid golden file %s: %w", path, err)
+	}
+	return &golden, nil
+}
+
+// validate checks the codes and delays of the transactions.
+func (g *Golden) validate() error {
+	for i, transaction := range g.Transactions {
+		if transaction.Function == "" {
+			return fmt.Errorf("transaction %d has no function", i)
+		}
+		if transaction.Error != nil {
+			if _, err := parseCode(transaction.Error.Code); err != nil {
+				return fmt.Errorf("transaction %d: %w", i, err)
+			}
+		}
+		if transaction.ValidationCode != "" {
+			if _, ok := peer.TxValidationCode_value[transaction.ValidationCode]; !ok {
+				return fmt.Errorf("transaction %d: unknown validation code %s", i, transaction.ValidationCode)
+			}
+		}
+		if transaction.Delay != "" {
+			if _, err := time.ParseDuration(transaction.Delay); err != nil {
+				return fmt.Errorf("transaction %d: invalid delay: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseCode returns the gRPC code named name.
+func parseCode(name string) (codes.Code, error) {
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		if code.String() == name {
+			return code, nil
+		}
+	}
+	return codes.Unknown, fmt.Errorf("unknown gRPC code %s", name)
+}
+
+// Replay answers the calls of the functions of golden with its transactions,
+// replacing their handlers.
+func (s *Server) Replay(golden *Golden) error {
+	if err := golden.validate(); err != nil {
+		return err
+	}
+	replay := &replay{transactions: golden.Transactions, served: make([]bool, len(golden.Transactions))}
+	for _, transaction := range golden.Transactions {
+		s.Handle(transaction.Function, replay.handle)
+	}
+	return nil
+}
+
+type replay struct {
+	mu           sync.Mutex
+	transactions []GoldenTransaction
+	served       []bool
+}
+
+// handle answers call with the first matching transaction not served yet, or
+// the last one matching.
+func (r *replay) handle(ctx context.Context, call *Call) ([]byte, error) {
+	r.mu.Lock()
+	match := -1
+	for i, transaction := range r.transactions {
+		if !transaction.matches(call) {
+			continue
+		}
+		match = i
+		if !r.served[i] {
+			break
+		}
+	}
+	if match < 0 {
+		r.mu.Unlock()
+		return nil, status.Errorf(codes.Unimplemented, "gatewaytest: no golden transaction for %s(%s)", call.Function, strings.Join(call.Args, ", "))
+	}
+	r.served[match] = true
+	transaction := r.transactions[match]
+	r.mu.Unlock()
+
+	return transaction.respond(ctx, call)
+}
+
+func (t *GoldenTransaction) matches(call *Call) bool {
+	return t.Function == call.Function && t.Submit == call.Submit && (t.Args == nil || slices.Equal(t.Args, call.Args))
+}
+
+// respond returns the outcome of the transaction for call.
+func (t *GoldenTransaction) respond(ctx context.Context, call *Call) ([]byte, error) {
+	if t.Delay != "" {
+		delay, _ := time.ParseDuration(t.Delay)
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if t.Error != nil {
+		return nil, t.Error.status().Err()
+	}
+	if t.ChaincodeError != "" {
+		return nil, ChaincodeError(t.ChaincodeError)
+	}
+	if t.ValidationCode != "" {
+		call.SetValidationCode(peer.TxValidationCode(peer.TxValidationCode_value[t.ValidationCode]))
+	}
+	if t.Event != nil {
+		call.SetEvent(t.Event.Name, decodeBytes(t.Event.Payload))
+	}
+	return decodeBytes(t.Result), nil
+}
+
+func (e *GoldenError) status() *status.Status {
+	code, _ := parseCode(e.Code)
+	grpcStatus := status.New(code, e.Message)
+	if len(e.Details) == 0 {
+		return grpcStatus
+	}
+	details := make([]protoadapt.MessageV1, len(e.Details))
+	for i, detail := range e.Details {
+		details[i] = &gateway.ErrorDetail{Address: detail.Address, MspId: detail.MspID, Message: detail.Message}
+	}
+	detailed, err := grpcStatus.WithDetails(details...)
+	if err != nil {
+		return grpcStatus
+	}
+	return detailed
+}
+
+// decodeBytes returns the bytes encoded in a golden value: the raw string of a
+// JSON string, otherwise the JSON itself, compacted.
+func decodeBytes(value json.RawMessage) []byte {
+	var text string
+	if len(value) > 0 && value[0] == '"' && json.Unmarshal(value, &text) == nil {
+		return []byte(text)
+	}
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, value) != nil {
+		return value
+	}
+	return compacted.Bytes()
+}
+
+// encodeBytes returns the golden value of bytes returned by a chaincode, as
+// decodeBytes decodes it.
+func encodeBytes(value []byte) json.RawMessage {
+	if len(value) == 0 {
+		return nil
+	}
+	if json.Valid(value) && value[0] != '"' {
+		return value
+	}
+	text, _ := json.Marshal(string(value))
+	return text
+}
+
+// Recorder records the transactions of a client connection as golden
+// transactions, installed on the connection with Interceptor:
+//
+//	recorder, err := gatewaytest.OpenRecorder("testdata/golden/list.json")
+//	connection, err := grpc.NewClient(endpoint, credentials, grpc.WithChainUnaryInterceptor(recorder.Interceptor()))
+//
+// Event streams are not recorded.
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	golden   Golden
+	endorsed map[string]int
+}
+
+// OpenRecorder returns a Recorder writing to the golden file at path after
+// every transaction, appending to the transactions it already holds. An empty
+// path records in memory only.
+func OpenRecorder(path string) (*Recorder, error) {
+	recorder := &Recorder{path: path, endorsed: map[string]int{}}
+	if path == "" {
+		return recorder, nil
+	}
+	golden, err := LoadGolden(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return recorder, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	recorder.golden = *golden
+	return recorder, nil
+}
+
+// Golden returns the transactions recorded so far.
+func (r *Recorder) Golden() *Golden {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	golden := Golden{Description: r.golden.Description, Transactions: slices.Clone(r.golden.Transactions)}
+	return &golden
+}
+
+// Interceptor returns the interceptor recording the calls of a connection. A
+// call that succeeds but cannot be recorded returns the recording error.
+func (r *Recorder) Interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, request interface{}, reply interface{}, connection *grpc.ClientConn, invoker grpc.UnaryInvoker, options ...grpc.CallOption) error {
+		err := invoker(ctx, method, request, reply, connection, options...)
+		recordErr := r.record(method, request, reply, err)
+		if err == nil && recordErr != nil {
+			return fmt.Errorf("failed to record %s: %w", method, recordErr)
+		}
+		return err
+	}
+}
+
+// record records the outcome of a call of method, failed with err.
+func (r *Recorder) record(method string, request interface{}, reply interface{}, err error) error {
+	switch method {
+	case "/gateway.Gateway/Evaluate":
+		call, parseErr := parseProposal(request.(*gateway.EvaluateRequest).GetProposedTransaction())
+		if parseErr != nil {
+			return parseErr
+		}
+		transaction := goldenTransaction(call, err)
+		if err == nil {
+			transaction.Result = encodeBytes(reply.(*gateway.EvaluateResponse).GetResult().GetPayload())
+		}
+		return r.append(transaction, "")
+
+	case "/gateway.Gateway/Endorse":
+		call, parseErr := parseProposal(request.(*gateway.EndorseRequest).GetProposedTransaction())
+		if parseErr != nil {
+			return parseErr
+		}
+		transaction := goldenTransaction(call, err)
+		transaction.Submit = true
+		if err == nil {
+			result, resultErr := transactionResult(reply.(*gateway.EndorseResponse).GetPreparedTransaction())
+			if resultErr != nil {
+				return resultErr
+			}
+			transaction.Result = encodeBytes(result)
+		}
+		return r.append(transaction, call.TransactionID)
+
+	case "/gateway.Gateway/CommitStatus":
+		if err != nil {
+			return nil
+		}
+		commitStatus := &gateway.CommitStatusRequest{}
+		if parseErr := proto.Unmarshal(request.(*gateway.SignedCommitStatusRequest).GetRequest(), commitStatus); parseErr != nil {
+			return parseErr
+		}
+		return r.setValidationCode(commitStatus.GetTransactionId(), reply.(*gateway.CommitStatusResponse).GetResult())
+	}
+	return nil
+}
+
+// goldenTransaction returns the golden transaction of call, failed with err.
+func goldenTransaction(call *Call, err error) GoldenTransaction {
+	transaction := GoldenTransaction{Function: call.Function, Args: call.Args}
+	if err != nil {
+		grpcStatus := status.Convert(err)
+		transaction.Error = &GoldenError{Code: grpcStatus.Code().String(), Message: grpcStatus.Message()}
+		for _, detail := range grpcStatus.Details() {
+			if detail, ok := detail.(*gateway.ErrorDetail); ok {
+				transaction.Error.Details = append(transaction.Error.Details, GoldenErrorDetail{
+					Address: detail.GetAddress(),
+					MspID:   detail.GetMspId(),
+					Message: detail.GetMessage(),
+				})
+			}
+		}
+	}
+	return transaction
+}
+
+func (r *Recorder) append(transaction GoldenTransaction, transactionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if transactionID != "" {
+		r.endorsed[transactionID] = len(r.golden.Transactions)
+	}
+	r.golden.Transactions = append(r.golden.Transactions, transaction)
+	return r.saveLocked()
+}
+
+func (r *Recorder) setValidationCode(transactionID string, code peer.TxValidationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.endorsed[transactionID]
+	if !ok || code == peer.TxValidationCode_VALID {
+		return nil
+	}
+	r.golden.Transactions[i].ValidationCode = code.String()
+	return r.saveLocked()
+}
+
+func (r *Recorder) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+	goldenJSON, err := json.MarshalIndent(r.golden, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(goldenJSON, '\n'), 0o644)
+}
@@ -0,0 +1,160 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gatewaytest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReplayGoldenAssets(t *testing.T) {
+	golden, err := LoadGolden("testdata/golden/assets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer()
+	defer server.Close()
+	if err := server.Replay(golden); err != nil {
+		t.Fatal(err)
+	}
+	network := connect(t, server, client.WithEvaluateTimeout(time.Second))
+	contract := network.GetContract("financial")
+	ctx := context.Background()
+
+	assets, err := assetclient.ListAssets(ctx, contract, assetclient.Filter{DealerID: "DEALER101", PageSize: 2}).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 3 || assets[2].ID != "asset3" || assets[2].Status != "INACTIVE" {
+		t.Fatalf("expected asset1 to asset3 after retrying the outage, got %+v", assets)
+	}
+
+	_, err = contract.EvaluateTransaction("ReadAsset", "asset9")
+	if !errors.Is(assetclient.DecodeChaincodeError(err), assetclient.ErrAssetNotFound) {
+		t.Fatalf("expected asset9 not to be found, got %v", err)
+	}
+	_, err = contract.EvaluateTransaction("GetTotals", "")
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected the slow query to time out, got %v", err)
+	}
+	state, err := contract.EvaluateTransaction("ReadState", "asset1")
+	if err != nil || string(state) != "raw state of asset1" {
+		t.Fatalf("expected the raw state, got %q, %v", state, err)
+	}
+	_, err = contract.EvaluateTransaction("ReadAsset", "asset2")
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected no golden transaction for asset2, got %v", err)
+	}
+
+	_, err = contract.SubmitTransaction("TransferAsset", "asset1", "DEALER202")
+	var commitErr *client.CommitError
+	if !errors.As(err, &commitErr) || commitErr.Code != peer.TxValidationCode_MVCC_READ_CONFLICT {
+		t.Fatalf("expected an MVCC read conflict, got %v", err)
+	}
+
+	eventsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := network.ChaincodeEvents(eventsCtx, "financial", client.WithStartBlock(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDealer, err := contract.SubmitTransaction("TransferAsset", "asset2", "DEALER202")
+	if err != nil || string(oldDealer) != "DEALER101" {
+		t.Fatalf("expected DEALER101, got %q, %v", oldDealer, err)
+	}
+	select {
+	case event := <-events:
+		if event.EventName != "AssetChanged" || event.BlockNumber != 2 {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the AssetChanged event")
+	}
+}
+
+func TestRecorderRecordsReplayableGolden(t *testing.T) {
+	live := NewServer()
+	defer live.Close()
+	live.Handle("ReadAsset", func(ctx context.Context, call *Call) ([]byte, error) {
+		if call.Args[0] != "asset1" {
+			return nil, ChaincodeError(`{"code":"ASSET_NOT_FOUND","message":"the asset ` + call.Args[0] + ` does not exist"}`)
+		}
+		return []byte(`{"ID":"asset1","balance":100}`), nil
+	})
+	live.Handle("ReadState", func(ctx context.Context, call *Call) ([]byte, error) {
+		return []byte(`"quoted"`), nil
+	})
+	live.Handle("TransferAsset", func(ctx context.Context, call *Call) ([]byte, error) {
+		call.SetValidationCode(peer.TxValidationCode_PHANTOM_READ_CONFLICT)
+		return []byte("DEALER101"), nil
+	})
+
+	path := filepath.Join(t.TempDir(), "recorded.json")
+	recorder, err := OpenRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection, err := live.Dial(grpc.WithChainUnaryInterceptor(recorder.Interceptor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := live.Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw, err := client.Connect(id.ID, client.WithSign(id.Sign), client.WithClientConnection(connection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+
+	run := func(contract *client.Contract) []string {
+		var outcomes []string
+		for _, args := range [][]string{{"ReadAsset", "asset1"}, {"ReadAsset", "asset9"}, {"ReadState", "asset1"}} {
+			result, err := contract.EvaluateTransaction(args[0], args[1:]...)
+			outcomes = append(outcomes, string(result)+"|"+status.Convert(err).Message())
+		}
+		_, err := contract.SubmitTransaction("TransferAsset", "asset1", "DEALER202")
+		var commitErr *client.CommitError
+		if errors.As(err, &commitErr) {
+			outcomes = append(outcomes, commitErr.Code.String())
+		}
+		return outcomes
+	}
+	recorded := run(gw.GetNetwork("mychannel").GetContract("financial"))
+
+	golden, err := LoadGolden(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(golden.Transactions) != 4 || golden.Transactions[3].ValidationCode != "PHANTOM_READ_CONFLICT" {
+		t.Fatalf("expected 4 transactions ending with a phantom read, got %+v", golden.Transactions)
+	}
+	replayed := NewServer()
+	defer replayed.Close()
+	if err := replayed.Replay(golden); err != nil {
+		t.Fatal(err)
+	}
+	replayedOutcomes := run(connect(t, replayed).GetContract("financial"))
+
+	if len(recorded) != 4 {
+		t.Fatalf("expected 4 outcomes, got %q", recorded)
+	}
+	for i := range recorded {
+		if recorded[i] != replayedOutcomes[i] {
+			t.Fatalf("outcome %d replayed as %q, recorded as %q", i, replayedOutcomes[i], recorded[i])
+		}
+	}
+}
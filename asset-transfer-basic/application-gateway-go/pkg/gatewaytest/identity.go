@@ -0,0 +1,105 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gatewaytest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+)
+
+// Identity is a client identity with a self-signed certificate, for clients of
+// a Server. The PEM encodings configure the commands of the application, which
+// read them from the CERT_PEM and KEY_PEM environment variables.
+type Identity struct {
+	ID             *identity.X509Identity
+	Sign           identity.Sign
+	CertificatePEM []byte
+	PrivateKeyPEM  []byte
+}
+
+// NewIdentity creates a client identity of organization mspID, with a new
+// ECDSA key and a certificate for commonName.
+func NewIdentity(mspID string, commonName string) (*Identity, error) {
+	certificate, err := newCertificate(commonName, nil)
+	if err != nil {
+		return nil, err
+	}
+	id, err := identity.NewX509Identity(mspID, certificate.certificate)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := identity.NewPrivateKeySign(certificate.key)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		ID:             id,
+		Sign:           sign,
+		CertificatePEM: certificate.CertificatePEM,
+		PrivateKeyPEM:  certificate.PrivateKeyPEM,
+	}, nil
+}
+
+// certificate is a self-signed certificate and its private key.
+type certificate struct {
+	CertificatePEM []byte
+	PrivateKeyPEM  []byte
+
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// newCertificate creates a self-signed certificate for commonName, valid for
+// the TLS servers dnsNames when set, and for a day.
+func newCertificate(commonName string, dnsNames []string) (*certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate{
+		CertificatePEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		certificate:    parsed,
+		key:            key,
+	}, nil
+}
@@ -0,0 +1,563 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package gatewaytest provides an in-process Fabric Gateway server, so that the
+// client library and the commands using it can be tested without a Fabric
+// network. The Server answers the transactions of the Gateway protocol with
+// handlers, or with golden fixtures recorded against a real network, see Golden
+// and Recorder:
+//
+//	server := gatewaytest.NewServer()
+//	defer server.Close()
+//	server.Handle("ReadAsset", func(ctx context.Context, call *gatewaytest.Call) ([]byte, error) {
+//		return []byte(`{"ID":"asset1"}`), nil
+//	})
+//	gw, err := server.Connect()
+//
+// Endorsement signatures and policies are not checked, and every submitted
+// transaction commits in a block of its own.
+package gatewaytest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/orderer"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// Defaults of the peer a Server reports in the details of its errors.
+const (
+	DefaultPeerAddress = "peer0.org1.example.com:7051"
+	DefaultMspID       = "Org1MSP"
+)
+
+const bufferSize = 1024 * 1024
+
+// Call is a transaction function call received by a Server. Submit is set for
+// calls endorsed to be submitted, and unset for evaluations.
+type Call struct {
+	TransactionID          string
+	Channel                string
+	Chaincode              string
+	Function               string
+	Args                   []string
+	Transient              map[string][]byte
+	EndorsingOrganizations []string
+	Submit                 bool
+
+	event          *peer.ChaincodeEvent
+	validationCode peer.TxValidationCode
+}
+
+// SetEvent sets the chaincode event of a submitted call, delivered to the
+// event listeners once the transaction commits valid.
+func (c *Call) SetEvent(name string, payload []byte) {
+	c.event = &peer.ChaincodeEvent{ChaincodeId: c.Chaincode, TxId: c.TransactionID, EventName: name, Payload: payload}
+}
+
+// SetValidationCode sets the commit status of a submitted call, such as
+// peer.TxValidationCode_MVCC_READ_CONFLICT. Calls commit valid by default.
+func (c *Call) SetValidationCode(code peer.TxValidationCode) {
+	c.validationCode = code
+}
+
+// Handler answers the calls of a transaction function with their result, or
+// with an error: a ChaincodeError, as the chaincode returns, or a gRPC status
+// error, returned to the client as it is.
+type Handler func(ctx context.Context, call *Call) ([]byte, error)
+
+type chaincodeError struct {
+	message string
+}
+
+func (e *chaincodeError) Error() string {
+	return "chaincode response 500, " + e.message
+}
+
+// ChaincodeError returns the error of a chaincode failing a call with message,
+// such as the JSON domain errors of the asset chaincode. The Server reports it
+// as a peer does, with the message in the error details.
+func ChaincodeError(message string) error {
+	return &chaincodeError{message: message}
+}
+
+// Server is an in-process Fabric Gateway. Its methods are safe for concurrent
+// use, and handlers may be added while it serves.
+type Server struct {
+	gateway.UnimplementedGatewayServer
+
+	// Address and MspID name the peer in the details of errors.
+	Address string
+	MspID   string
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	fallback Handler
+	pending  map[string]*Call
+	statuses map[string]*gateway.CommitStatusResponse
+	blocks   []eventBlock
+	height   uint64
+	changed  chan struct{}
+
+	servers     []*grpc.Server
+	listener    *bufconn.Listener
+	connections []*grpc.ClientConn
+	identity    *Identity
+}
+
+// eventBlock holds the chaincode events of a committed block.
+type eventBlock struct {
+	number uint64
+	events []*peer.ChaincodeEvent
+}
+
+// NewServer creates a Server without handlers. Calls of functions without a
+// handler fail with codes.Unimplemented.
+func NewServer() *Server {
+	return &Server{
+		Address:  DefaultPeerAddress,
+		MspID:    DefaultMspID,
+		handlers: map[string]Handler{},
+		pending:  map[string]*Call{},
+		statuses: map[string]*gateway.CommitStatusResponse{},
+		height:   1,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Handle sets the handler of the calls of function, replacing any previous one.
+func (s *Server) Handle(function string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[function] = handler
+}
+
+// HandleOthers sets the handler of the calls of functions without a handler of
+// their own.
+func (s *Server) HandleOthers(handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = handler
+}
+
+// Emit commits a block with events of chaincode, as other clients' transactions
+// do, and returns its number.
+func (s *Server) Emit(chaincode string, events ...*peer.ChaincodeEvent) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		event.ChaincodeId = chaincode
+	}
+	return s.commitLocked(events)
+}
+
+// commitLocked appends a block with events and wakes up the event streams.
+func (s *Server) commitLocked(events []*peer.ChaincodeEvent) uint64 {
+	number := s.height
+	s.height++
+	if len(events) > 0 {
+		s.blocks = append(s.blocks, eventBlock{number: number, events: events})
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return number
+}
+
+// Dial returns a connection to the Server, served in-process, closed by Close.
+// options add to the options of the connection, such as the interceptor of a
+// Recorder.
+func (s *Server) Dial(options ...grpc.DialOption) (*grpc.ClientConn, error) {
+	s.mu.Lock()
+	if s.listener == nil {
+		s.listener = bufconn.Listen(bufferSize)
+		s.serveLocked(s.listener)
+	}
+	listener := s.listener
+	s.mu.Unlock()
+
+	options = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, options...)
+	connection, err := grpc.NewClient("passthrough:///gatewaytest", options...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.connections = append(s.connections, connection)
+	s.mu.Unlock()
+	return connection, nil
+}
+
+// Connect connects a Gateway to the Server with its client identity, see
+// Identity, over a connection of Dial.
+func (s *Server) Connect(options ...client.ConnectOption) (*client.Gateway, error) {
+	id, err := s.Identity()
+	if err != nil {
+		return nil, err
+	}
+	connection, err := s.Dial()
+	if err != nil {
+		return nil, err
+	}
+	options = append([]client.ConnectOption{client.WithSign(id.Sign), client.WithClientConnection(connection)}, options...)
+	return client.Connect(id.ID, options...)
+}
+
+// Identity returns the client identity Connect connects with, a member of the
+// MspID organization.
+func (s *Server) Identity() (*Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.identity == nil {
+		id, err := NewIdentity(s.MspID, "User1@org1.example.com")
+		if err != nil {
+			return nil, err
+		}
+		s.identity = id
+	}
+	return s.identity, nil
+}
+
+// ListenTLS serves the Server on address, such as "localhost:0", with TLS, so
+// that other processes, such as the commands of the application, can connect.
+// It returns the address listened on and the PEM certificate to trust.
+func (s *Server) ListenTLS(address string, hostname string) (string, []byte, error) {
+	certificate, err := newCertificate(hostname, []string{hostname, "localhost"})
+	if err != nil {
+		return "", nil, err
+	}
+	keyPair, err := tls.X509KeyPair(certificate.CertificatePEM, certificate.PrivateKeyPEM)
+	if err != nil {
+		return "", nil, err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serveLocked(listener, grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{keyPair}})))
+	return listener.Addr().String(), certificate.CertificatePEM, nil
+}
+
+func (s *Server) serveLocked(listener net.Listener, options ...grpc.ServerOption) {
+	server := grpc.NewServer(options...)
+	gateway.RegisterGatewayServer(server, s)
+	s.servers = append(s.servers, server)
+	go server.Serve(listener)
+}
+
+// Close closes the connections of Dial and stops serving.
+func (s *Server) Close() {
+	s.mu.Lock()
+	connections, servers := s.connections, s.servers
+	s.connections, s.servers = nil, nil
+	s.mu.Unlock()
+	for _, connection := range connections {
+		connection.Close()
+	}
+	for _, server := range servers {
+		server.Stop()
+	}
+}
+
+// call runs the handler of call, translating its error as a peer reports it in
+// stage, evaluate or endorse.
+func (s *Server) call(ctx context.Context, call *Call, stage string) ([]byte, error) {
+	s.mu.Lock()
+	handler, ok := s.handlers[call.Function]
+	if !ok {
+		handler = s.fallback
+	}
+	s.mu.Unlock()
+	if handler == nil {
+		return nil, status.Errorf(codes.Unimplemented, "gatewaytest: no handler for %s", call.Function)
+	}
+
+	result, err := handler(ctx, call)
+	if err == nil {
+		return result, nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return nil, err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	var ccErr *chaincodeError
+	if !errors.As(err, &ccErr) {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	grpcStatus := status.New(codes.Aborted, "failed to endorse transaction, see attached details for more info")
+	if stage == "evaluate" {
+		grpcStatus = status.New(codes.Unknown, "evaluate call to endorser returned error: "+ccErr.Error())
+	}
+	detailed, detailsErr := grpcStatus.WithDetails(&gateway.ErrorDetail{Address: s.Address, MspId: s.MspID, Message: ccErr.Error()})
+	if detailsErr != nil {
+		return nil, grpcStatus.Err()
+	}
+	return nil, detailed.Err()
+}
+
+// Evaluate implements gateway.GatewayServer.
+func (s *Server) Evaluate(ctx context.Context, request *gateway.EvaluateRequest) (*gateway.EvaluateResponse, error) {
+	call, err := parseProposal(request.GetProposedTransaction())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	call.EndorsingOrganizations = request.GetTargetOrganizations()
+
+	result, err := s.call(ctx, call, "evaluate")
+	if err != nil {
+		return nil, err
+	}
+	return &gateway.EvaluateResponse{Result: &peer.Response{Status: 200, Payload: result}}, nil
+}
+
+// Endorse implements gateway.GatewayServer.
+func (s *Server) Endorse(ctx context.Context, request *gateway.EndorseRequest) (*gateway.EndorseResponse, error) {
+	call, err := parseProposal(request.GetProposedTransaction())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	call.EndorsingOrganizations = request.GetEndorsingOrganizations()
+	call.Submit = true
+
+	result, err := s.call(ctx, call, "endorse")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.pending[call.TransactionID] = call
+	s.mu.Unlock()
+	return &gateway.EndorseResponse{PreparedTransaction: preparedTransaction(call, result)}, nil
+}
+
+// Submit implements gateway.GatewayServer, committing the transaction at once.
+func (s *Server) Submit(ctx context.Context, request *gateway.SubmitRequest) (*gateway.SubmitResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call, ok := s.pending[request.GetTransactionId()]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "gatewaytest: transaction %s was not endorsed", request.GetTransactionId())
+	}
+	delete(s.pending, call.TransactionID)
+
+	var events []*peer.ChaincodeEvent
+	if call.event != nil && call.validationCode == peer.TxValidationCode_VALID {
+		events = append(events, call.event)
+	}
+	number := s.commitLocked(events)
+	s.statuses[call.TransactionID] = &gateway.CommitStatusResponse{Result: call.validationCode, BlockNumber: number}
+	return &gateway.SubmitResponse{}, nil
+}
+
+// CommitStatus implements gateway.GatewayServer.
+func (s *Server) CommitStatus(ctx context.Context, signed *gateway.SignedCommitStatusRequest) (*gateway.CommitStatusResponse, error) {
+	request := &gateway.CommitStatusRequest{}
+	if err := proto.Unmarshal(signed.GetRequest(), request); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response, ok := s.statuses[request.GetTransactionId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "gatewaytest: transaction %s was not submitted", request.GetTransactionId())
+	}
+	return response, nil
+}
+
+// ChaincodeEvents implements gateway.GatewayServer, sending the events of the
+// blocks committed from the start position of the request, newest by default.
+func (s *Server) ChaincodeEvents(signed *gateway.SignedChaincodeEventsRequest, stream gateway.Gateway_ChaincodeEventsServer) error {
+	request := &gateway.ChaincodeEventsRequest{}
+	if err := proto.Unmarshal(signed.GetRequest(), request); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.mu.Lock()
+	start := s.height
+	s.mu.Unlock()
+	switch position := request.GetStartPosition().GetType().(type) {
+	case *orderer.SeekPosition_Specified:
+		start = position.Specified.GetNumber()
+	case *orderer.SeekPosition_Oldest:
+		start = 0
+	}
+	next := start
+
+	for {
+		s.mu.Lock()
+		var blocks []eventBlock
+		for _, block := range s.blocks {
+			if block.number >= next {
+				blocks = append(blocks, block)
+			}
+		}
+		next = s.height
+		changed := s.changed
+		s.mu.Unlock()
+
+		for _, block := range blocks {
+			var events []*peer.ChaincodeEvent
+			for _, event := range resumedEvents(block, start, request.GetAfterTransactionId()) {
+				if event.GetChaincodeId() == request.GetChaincodeId() {
+					events = append(events, event)
+				}
+			}
+			if len(events) == 0 {
+				continue
+			}
+			if err := stream.Send(&gateway.ChaincodeEventsResponse{Events: events, BlockNumber: block.number}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+// resumedEvents returns the events of block, skipping those of the start block
+// up to and including the transaction afterTransactionID, when it is there.
+func resumedEvents(block eventBlock, start uint64, afterTransactionID string) []*peer.ChaincodeEvent {
+	if block.number != start || afterTransactionID == "" {
+		return block.events
+	}
+	for i, event := range block.events {
+		if event.GetTxId() == afterTransactionID {
+			return block.events[i+1:]
+		}
+	}
+	return block.events
+}
+
+// parseProposal returns the call of a signed proposal.
+func parseProposal(signed *peer.SignedProposal) (*Call, error) {
+	proposal := &peer.Proposal{}
+	if err := proto.Unmarshal(signed.GetProposalBytes(), proposal); err != nil {
+		return nil, fmt.Errorf("failed to parse the proposal: %w", err)
+	}
+	header := &common.Header{}
+	if err := proto.Unmarshal(proposal.GetHeader(), header); err != nil {
+		return nil, fmt.Errorf("failed to parse the proposal header: %w", err)
+	}
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(header.GetChannelHeader(), channelHeader); err != nil {
+		return nil, fmt.Errorf("failed to parse the channel header: %w", err)
+	}
+	extension := &peer.ChaincodeHeaderExtension{}
+	if err := proto.Unmarshal(channelHeader.GetExtension(), extension); err != nil {
+		return nil, fmt.Errorf("failed to parse the chaincode header: %w", err)
+	}
+	payload := &peer.ChaincodeProposalPayload{}
+	if err := proto.Unmarshal(proposal.GetPayload(), payload); err != nil {
+		return nil, fmt.Errorf("failed to parse the proposal payload: %w", err)
+	}
+	invocation := &peer.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(payload.GetInput(), invocation); err != nil {
+		return nil, fmt.Errorf("failed to parse the chaincode invocation: %w", err)
+	}
+
+	call := &Call{
+		TransactionID: channelHeader.GetTxId(),
+		Channel:       channelHeader.GetChannelId(),
+		Chaincode:     extension.GetChaincodeId().GetName(),
+		Transient:     payload.GetTransientMap(),
+		Args:          []string{},
+	}
+	args := invocation.GetChaincodeSpec().GetInput().GetArgs()
+	if len(args) == 0 {
+		return nil, errors.New("the proposal calls no function")
+	}
+	call.Function = string(args[0])
+	for _, arg := range args[1:] {
+		call.Args = append(call.Args, string(arg))
+	}
+	return call, nil
+}
+
+// preparedTransaction returns the envelope of the transaction of an endorsed
+// call, carrying its result as the endorsed chaincode response.
+func preparedTransaction(call *Call, result []byte) *common.Envelope {
+	action := marshal(&peer.ChaincodeAction{Response: &peer.Response{Status: 200, Payload: result}})
+	responsePayload := marshal(&peer.ProposalResponsePayload{Extension: action})
+	actionPayload := marshal(&peer.ChaincodeActionPayload{
+		Action: &peer.ChaincodeEndorsedAction{ProposalResponsePayload: responsePayload},
+	})
+	transaction := marshal(&peer.Transaction{Actions: []*peer.TransactionAction{{Payload: actionPayload}}})
+	channelHeader := marshal(&common.ChannelHeader{
+		Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+		ChannelId: call.Channel,
+		TxId:      call.TransactionID,
+	})
+	payload := marshal(&common.Payload{Header: &common.Header{ChannelHeader: channelHeader}, Data: transaction})
+	return &common.Envelope{Payload: payload}
+}
+
+// transactionResult returns the chaincode response carried by the envelope of
+// an endorsed transaction.
+func transactionResult(envelope *common.Envelope) ([]byte, error) {
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(envelope.GetPayload(), payload); err != nil {
+		return nil, err
+	}
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), transaction); err != nil {
+		return nil, err
+	}
+	for _, transactionAction := range transaction.GetActions() {
+		actionPayload := &peer.ChaincodeActionPayload{}
+		if err := proto.Unmarshal(transactionAction.GetPayload(), actionPayload); err != nil {
+			return nil, err
+		}
+		responsePayload := &peer.ProposalResponsePayload{}
+		if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+			return nil, err
+		}
+		action := &peer.ChaincodeAction{}
+		if err := proto.Unmarshal(responsePayload.GetExtension(), action); err != nil {
+			return nil, err
+		}
+		return action.GetResponse().GetPayload(), nil
+	}
+	return nil, errors.New("the transaction has no chaincode action")
+}
+
+// marshal marshals a message built by the Server. Marshaling only fails for
+// strings that are not valid UTF-8, which the fields copied from a parsed
+// proposal cannot hold.
+func marshal(message proto.Message) []byte {
+	bytes, err := proto.Marshal(message)
+	if err != nil {
+		panic(fmt.Sprintf("gatewaytest: failed to marshal %T: %v", message, err))
+	}
+	return bytes
+}
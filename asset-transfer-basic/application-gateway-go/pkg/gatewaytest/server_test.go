@@ -0,0 +1,132 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gatewaytest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// connect connects a Gateway to server, closed at the end of the test.
+func connect(t *testing.T, server *Server, options ...client.ConnectOption) *client.Network {
+	t.Helper()
+	gw, err := server.Connect(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gw.Close() })
+	return gw.GetNetwork("mychannel")
+}
+
+func TestEvaluateAndSubmitWithEvents(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Handle("ReadAsset", func(ctx context.Context, call *Call) ([]byte, error) {
+		if call.Submit || call.Channel != "mychannel" || call.Chaincode != "financial" || !slices.Equal(call.Args, []string{"asset1"}) {
+			t.Errorf("unexpected call %+v", call)
+		}
+		return []byte(`{"ID":"asset1"}`), nil
+	})
+	server.Handle("CreateAsset", func(ctx context.Context, call *Call) ([]byte, error) {
+		if string(call.Transient["asset_details"]) != `{"mpin":"1234"}` {
+			return nil, ChaincodeError(`{"code":"INVALID_ARGUMENT","message":"missing asset details"}`)
+		}
+		call.SetEvent("AssetCreated", []byte(call.Args[0]))
+		return nil, nil
+	})
+	network := connect(t, server)
+	contract := network.GetContract("financial")
+
+	result, err := contract.EvaluateTransaction("ReadAsset", "asset1")
+	if err != nil || string(result) != `{"ID":"asset1"}` {
+		t.Fatalf("expected asset1, got %s, %v", result, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := network.ChaincodeEvents(ctx, "financial", client.WithStartBlock(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = contract.Submit("CreateAsset", client.WithArguments("asset7"), client.WithTransient(map[string][]byte{"asset_details": []byte(`{"mpin":"1234"}`)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if event.EventName != "AssetCreated" || string(event.Payload) != "asset7" || event.BlockNumber != 1 {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the AssetCreated event")
+	}
+}
+
+func TestChaincodeErrorsDecodeAsDomainErrors(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.HandleOthers(func(ctx context.Context, call *Call) ([]byte, error) {
+		return nil, ChaincodeError(`{"code":"FORBIDDEN","message":"only the dealer of the asset may call ` + call.Function + `"}`)
+	})
+	contract := connect(t, server).GetContract("financial")
+
+	_, err := contract.EvaluateTransaction("ReadAssetDetails", "asset1")
+	evaluateErr := assetclient.NewMultiPeerError(err)
+	if !errors.Is(evaluateErr, assetclient.ErrForbidden) || evaluateErr.Stage != "evaluate" {
+		t.Fatalf("expected a forbidden evaluation, got %v", evaluateErr)
+	}
+	if len(evaluateErr.Peers) != 1 || evaluateErr.Peers[0].MspID != DefaultMspID {
+		t.Fatalf("expected the error of %s, got %+v", DefaultMspID, evaluateErr.Peers)
+	}
+
+	_, err = contract.SubmitTransaction("TransferAsset", "asset1", "DEALER202")
+	endorseErr := assetclient.NewMultiPeerError(err)
+	if !errors.Is(endorseErr, assetclient.ErrForbidden) || endorseErr.Stage != "endorse" || endorseErr.Retryable {
+		t.Fatalf("expected a forbidden endorsement, got %v", endorseErr)
+	}
+}
+
+func TestInvalidTransactionFailsCommit(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Handle("TransferAsset", func(ctx context.Context, call *Call) ([]byte, error) {
+		call.SetValidationCode(peer.TxValidationCode_MVCC_READ_CONFLICT)
+		return []byte("DEALER101"), nil
+	})
+	contract := connect(t, server).GetContract("financial")
+
+	_, err := contract.SubmitTransaction("TransferAsset", "asset1", "DEALER202")
+	var commitErr *client.CommitError
+	if !errors.As(err, &commitErr) || commitErr.Code != peer.TxValidationCode_MVCC_READ_CONFLICT {
+		t.Fatalf("expected an MVCC read conflict, got %v", err)
+	}
+	if !assetclient.IsRetryable(err) {
+		t.Fatal("expected the conflict to be retryable")
+	}
+}
+
+func TestEvaluateTimesOut(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Handle("GetTotals", func(ctx context.Context, call *Call) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	contract := connect(t, server, client.WithEvaluateTimeout(50*time.Millisecond)).GetContract("financial")
+
+	_, err := contract.EvaluateTransaction("GetTotals", "")
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+const replayUsage = `usage: replay -to-channel channel [-to-chaincode name] [-start-block n] [-end-block n] [-speed x] [-transform file.json] [-dry-run]`
+
+// replayCommand reads the blocks of -channel and submits the valid
+// transactions of -chaincode again, in ledger order, to -to-chaincode on
+// -to-channel, such as a new chaincode version to validate against production
+// traffic. Transactions are spaced as they were committed, -speed times
+// faster, 0 submitting them as fast as -max-tps and -max-in-flight allow, and
+// the -transform file selects and rewrites them. Without -end-block it
+// follows the channel until interrupted. A transaction failing on replay is
+// reported and the replay goes on, as it committed originally.
+func replayCommand(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	commandSettings := settings.Sub(flags)
+	toChannel := commandSettings.String("to-channel", "REPLAY_CHANNEL", "", "channel the transactions are submitted to")
+	toChaincode := commandSettings.String("to-chaincode", "REPLAY_CHAINCODE", "", "chaincode the transactions are submitted to, -chaincode by default")
+	startBlock := flags.Uint64("start-block", 0, "first block to replay")
+	endBlock := flags.Int64("end-block", -1, "last block to replay, following the channel when negative")
+	speed := flags.Float64("speed", 1, "replay speed relative to the original traffic, 0 not waiting")
+	transformPath := flags.String("transform", "", "JSON file selecting, renaming and rewriting the replayed transactions")
+	dryRun := flags.Bool("dry-run", false, "print the transactions to replay without submitting them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := commandSettings.Resolve(os.LookupEnv); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || (*toChannel == "" && !*dryRun) || *speed < 0 || (*endBlock >= 0 && uint64(*endBlock) < *startBlock) {
+		return errors.New(replayUsage)
+	}
+	if *toChaincode == "" {
+		*toChaincode = chaincodeName()
+	}
+	if *toChannel == channelName() && *toChaincode == chaincodeName() && !*dryRun {
+		return errors.New("replaying into the chaincode the transactions are read from would replay them again")
+	}
+	var transform *assetclient.ReplayTransform
+	if *transformPath != "" {
+		var err error
+		if transform, err = assetclient.ReadReplayTransform(*transformPath); err != nil {
+			return err
+		}
+	}
+	pacer, err := newBulkPacer()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	// the block stream ends with the context, once the last block is replayed
+	blocksCtx, cancelBlocks := context.WithCancel(ctx)
+	defer cancelBlocks()
+	blocks, err := gw.GetNetwork(channelName()).BlockEvents(blocksCtx, client.WithStartBlock(*startBlock))
+	if err != nil {
+		return fmt.Errorf("failed to read blocks: %w", err)
+	}
+	contract := gw.GetNetwork(*toChannel).GetContract(*toChaincode)
+	fmt.Printf("Replaying %s on %s from block %d to %s on %s\n", chaincodeName(), channelName(), *startBlock, *toChaincode, *toChannel)
+
+	clock := &assetclient.ReplayClock{Speed: *speed}
+	replayed, skipped, failed := 0, 0, 0
+replay:
+	for block := range blocks {
+		if *endBlock >= 0 && block.GetHeader().GetNumber() > uint64(*endBlock) {
+			break
+		}
+		transactions, err := assetclient.BlockTransactions(block, chaincodeName())
+		if err != nil {
+			return err
+		}
+		for _, original := range transactions {
+			transaction, ok, err := transform.Apply(original)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				skipped++
+				continue
+			}
+			if clock.Wait(ctx, transaction.Timestamp) != nil {
+				break replay
+			}
+			if *dryRun {
+				fmt.Printf("%d\t%s\t%s\t%s\n", transaction.BlockNumber, transaction.TransactionID, transaction.Function, strings.Join(transaction.Args, " "))
+				replayed++
+				continue
+			}
+
+			err = pacer.Do(ctx, func(ctx context.Context) error {
+				_, err := contract.SubmitWithContext(ctx, transaction.Function, client.WithArguments(transaction.Args...))
+				return err
+			})
+			if ctx.Err() != nil {
+				break replay
+			}
+			if err != nil {
+				failed++
+				fmt.Printf("%d\t%s\t%s\tFAILED\t%s\n", transaction.BlockNumber, transaction.TransactionID, transaction.Function, assetclient.NewMultiPeerError(err))
+				continue
+			}
+			replayed++
+		}
+		if *endBlock >= 0 && block.GetHeader().GetNumber() == uint64(*endBlock) {
+			break
+		}
+	}
+	fmt.Printf("Replayed %d transactions, %d failed and %d skipped\n", replayed, failed, skipped)
+	switch {
+	case failed > 0:
+		return fmt.Errorf("%d transactions that committed originally failed on replay", failed)
+	case ctx.Err() == nil && *endBlock < 0:
+		return errors.New("the block stream ended before the replay was interrupted")
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+const scenarioUsage = `usage: demo run --scenario <file> [--fail-fast]`
+
+// scenario is a sequence of contract calls read from a YAML file, such as
+// scenarios/basic.yaml. Arguments and transient values may reference
+// ${transactionId}, an asset ID generated for the run, and environment variables.
+type scenario struct {
+	Name      string         `yaml:"name"`
+	Channel   string         `yaml:"channel"`
+	Chaincode string         `yaml:"chaincode"`
+	Steps     []scenarioStep `yaml:"steps"`
+}
+
+// scenarioStep is one contract call, either submitted or evaluated.
+type scenarioStep struct {
+	Name      string            `yaml:"name"`
+	Submit    string            `yaml:"submit"`
+	Evaluate  string            `yaml:"evaluate"`
+	Args      []string          `yaml:"args"`
+	Transient map[string]any    `yaml:"transient"`
+	Expect    scenarioAssertion `yaml:"expect"`
+}
+
+// scenarioAssertion describes the expected outcome of a step. A step without
+// assertions only has to succeed.
+type scenarioAssertion struct {
+	// Error is the expected chaincode error code, or a part of the expected error message.
+	Error string `yaml:"error"`
+	// Result is the exact expected result.
+	Result *string `yaml:"result"`
+	// Contains is a part of the expected result.
+	Contains string `yaml:"contains"`
+	// JSON maps the fields of a JSON object result to their expected value.
+	JSON map[string]any `yaml:"json"`
+}
+
+// scenarioCommand runs the demo, or the scenario file given to demo run.
+func scenarioCommand(args []string) error {
+	if len(args) == 0 {
+		return runDemo()
+	}
+	if args[0] != "run" {
+		return errors.New(scenarioUsage)
+	}
+
+	flags := flag.NewFlagSet("demo run", flag.ContinueOnError)
+	scenarioPath := flags.String("scenario", "", "YAML file describing the contract calls to run")
+	failFast := flags.Bool("fail-fast", false, "stop at the first failing step")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *scenarioPath == "" {
+		return errors.New(scenarioUsage)
+	}
+
+	s, err := loadScenario(*scenarioPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	contract := gw.GetNetwork(s.Channel).GetContract(s.Chaincode)
+	if transactionId, err = newAssetID(ctx, contract); err != nil {
+		return err
+	}
+	fmt.Printf("Running scenario %q on %s/%s\n", s.Name, s.Channel, s.Chaincode)
+
+	failed := 0
+	for i, step := range s.Steps {
+		started := time.Now()
+		err := step.check(step.run(ctx, contract))
+		elapsed := time.Since(started).Round(time.Millisecond)
+		if err == nil {
+			fmt.Printf("PASS %d. %s (%s)\n", i+1, step.Name, elapsed)
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL %d. %s (%s): %s\n", i+1, step.Name, elapsed, err)
+		if *failFast || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d steps failed", failed, len(s.Steps))
+	}
+	fmt.Printf("All %d steps passed\n", len(s.Steps))
+	return nil
+}
+
+// loadScenario reads a scenario file, defaulting its channel and chaincode.
+func loadScenario(scenarioPath string) (*scenario, error) {
+	scenarioYAML, err := os.ReadFile(scenarioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var s scenario
+	if err := yaml.Unmarshal(scenarioYAML, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", scenarioPath, err)
+	}
+	if s.Name == "" {
+		s.Name = scenarioPath
+	}
+	if s.Channel == "" {
+		s.Channel = channelName()
+	}
+	if s.Chaincode == "" {
+		s.Chaincode = chaincodeName()
+	}
+
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		if (step.Submit == "") == (step.Evaluate == "") {
+			return nil, fmt.Errorf("step %d of %s must have exactly one of submit or evaluate", i+1, scenarioPath)
+		}
+		if step.Name == "" {
+			step.Name = step.Submit + step.Evaluate
+		}
+		// every step is checked before the first one runs
+		if err := checkFunctionPolicy(step.Submit+step.Evaluate, step.Submit != ""); err != nil {
+			return nil, fmt.Errorf("step %d of %s: %w", i+1, scenarioPath, err)
+		}
+	}
+	return &s, nil
+}
+
+// run submits or evaluates the contract call of the step.
+func (step scenarioStep) run(ctx context.Context, contract *client.Contract) ([]byte, error) {
+	args := make([]string, len(step.Args))
+	for i, arg := range step.Args {
+		args[i] = expandScenarioValue(arg)
+	}
+	options := []client.ProposalOption{client.WithArguments(args...)}
+
+	if len(step.Transient) > 0 {
+		transient := make(map[string][]byte, len(step.Transient))
+		for key, value := range step.Transient {
+			if text, ok := value.(string); ok {
+				transient[key] = []byte(expandScenarioValue(text))
+				continue
+			}
+			valueJSON, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode transient %s: %w", key, err)
+			}
+			transient[key] = []byte(expandScenarioValue(string(valueJSON)))
+		}
+		options = append(options, client.WithTransient(transient))
+	}
+
+	if step.Submit != "" {
+		return contract.SubmitWithContext(ctx, step.Submit, options...)
+	}
+	return contract.EvaluateWithContext(ctx, step.Evaluate, options...)
+}
+
+// check compares the outcome of the step with its assertions.
+func (step scenarioStep) check(result []byte, err error) error {
+	expect := step.Expect
+	if expect.Error != "" {
+		if err == nil {
+			return fmt.Errorf("expected error %q, got result %s", expect.Error, result)
+		}
+		multiErr := assetclient.NewMultiPeerError(err)
+		if multiErr.Code != expect.Error && !strings.Contains(multiErr.Error(), expect.Error) {
+			return fmt.Errorf("expected error %q, got %w", expect.Error, multiErr)
+		}
+		return nil
+	}
+	if err != nil {
+		return assetclient.NewMultiPeerError(err)
+	}
+
+	if expect.Result != nil && string(result) != expandScenarioValue(*expect.Result) {
+		return fmt.Errorf("expected result %q, got %q", expandScenarioValue(*expect.Result), result)
+	}
+	if expect.Contains != "" && !strings.Contains(string(result), expandScenarioValue(expect.Contains)) {
+		return fmt.Errorf("expected result to contain %q, got %q", expandScenarioValue(expect.Contains), result)
+	}
+	if len(expect.JSON) > 0 {
+		var fields map[string]any
+		if err := json.Unmarshal(result, &fields); err != nil {
+			return fmt.Errorf("expected a JSON object, got %q", result)
+		}
+		for name, want := range expect.JSON {
+			want, err := normalizeJSON(want)
+			if err != nil {
+				return err
+			}
+			if text, ok := want.(string); ok {
+				want = expandScenarioValue(text)
+			}
+			if got := fields[name]; !reflect.DeepEqual(got, want) {
+				return fmt.Errorf("expected %s to be %v, got %v", name, want, got)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeJSON converts a value decoded from YAML to the value it decodes to
+// from JSON, so that numbers compare as float64.
+func normalizeJSON(value any) (any, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	err = json.Unmarshal(valueJSON, &normalized)
+	return normalized, err
+}
+
+// expandScenarioValue replaces ${transactionId} with the asset ID of the run and
+// other ${NAME} references with environment variables.
+func expandScenarioValue(value string) string {
+	return os.Expand(value, func(name string) string {
+		if name == "transactionId" {
+			return transactionId
+		}
+		return os.Getenv(name)
+	})
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+const simulateUsage = `usage: simulate [-transient json] <function> [args...]`
+
+// simulateCommand endorses a transaction without submitting it to the orderer and
+// prints its read/write set, with each write shown as a diff against the current state.
+func simulateCommand(args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	transientJSON := flags.String("transient", "", "JSON object passed as transient data, one entry per member")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New(simulateUsage)
+	}
+	// a simulation is endorsed but never changes the ledger
+	if err := checkFunctionPolicy(flags.Arg(0), false); err != nil {
+		return err
+	}
+
+	options := []client.ProposalOption{client.WithArguments(flags.Args()[1:]...)}
+	if *transientJSON != "" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(*transientJSON), &fields); err != nil {
+			return fmt.Errorf("failed to parse transient data: %w", err)
+		}
+		transient := make(map[string][]byte, len(fields))
+		for key, value := range fields {
+			transient[key] = value
+		}
+		options = append(options, client.WithTransient(transient))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	contract := gw.GetNetwork(channelName()).GetContract(chaincodeName())
+	proposal, err := contract.NewProposal(flags.Arg(0), options...)
+	if err != nil {
+		return err
+	}
+	transaction, err := proposal.EndorseWithContext(ctx)
+	if err != nil {
+		return assetclient.NewMultiPeerError(err)
+	}
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		return err
+	}
+	rwSet, err := assetclient.ParseReadWriteSet(transactionBytes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Simulated %s as transaction %s, nothing was submitted\n", flags.Arg(0), transaction.TransactionID())
+	fmt.Printf("Result: %s\n", transaction.Result())
+
+	fmt.Printf("\nReads (%d):\n", len(rwSet.Reads))
+	for _, read := range rwSet.Reads {
+		fmt.Printf("  %s at version %d:%d\n", displayKey(read.Namespace, read.Collection, read.Key), read.BlockNum, read.TxNum)
+	}
+
+	fmt.Printf("\nWrites (%d):\n", len(rwSet.Writes))
+	for _, write := range rwSet.Writes {
+		fmt.Printf("\n%s\n", displayKey(write.Namespace, write.Collection, write.Key))
+		if write.Collection != "" {
+			if write.IsDelete {
+				fmt.Println("- private data deleted")
+			} else {
+				fmt.Printf("+ private data with value hash %s\n", hex.EncodeToString(write.Value))
+			}
+			continue
+		}
+
+		var current []byte
+		if write.Namespace == contract.ChaincodeName() {
+			if current, err = contract.EvaluateWithContext(ctx, "ReadState", client.WithArguments(write.Key)); err != nil {
+				return assetclient.NewMultiPeerError(err)
+			}
+		}
+		proposed := write.Value
+		if write.IsDelete {
+			proposed = nil
+		}
+		for _, line := range diffState(current, proposed) {
+			fmt.Println(line)
+		}
+	}
+
+	return nil
+}
+
+// displayKey renders a state key, showing composite keys as objectType~attribute~...
+func displayKey(namespace string, collection string, key string) string {
+	if strings.HasPrefix(key, "\x00") {
+		key = strings.ReplaceAll(strings.Trim(key, "\x00"), "\x00", "~")
+	}
+	if collection != "" {
+		return fmt.Sprintf("%s/%s key hash %s", namespace, collection, key)
+	}
+	return fmt.Sprintf("%s %s", namespace, key)
+}
+
+// diffState renders the change from the current to the proposed value of a key,
+// field by field for JSON objects. Unchanged fields are prefixed with spaces,
+// removed ones with "-" and added ones with "+".
+func diffState(current []byte, proposed []byte) []string {
+	switch {
+	case len(current) == 0 && len(proposed) == 0:
+		return []string{"  (no change)"}
+	case len(current) == 0:
+		return append([]string{"  (new key)"}, prefixLines("+ ", stateFields(proposed))...)
+	case len(proposed) == 0:
+		return append([]string{"  (deleted)"}, prefixLines("- ", stateFields(current))...)
+	}
+
+	currentFields, currentOK := objectFields(current)
+	proposedFields, proposedOK := objectFields(proposed)
+	if !currentOK || !proposedOK {
+		if string(current) == string(proposed) {
+			return []string{"  " + string(current)}
+		}
+		return []string{"- " + string(current), "+ " + string(proposed)}
+	}
+
+	names := make(map[string]bool)
+	for name := range currentFields {
+		names[name] = true
+	}
+	for name := range proposedFields {
+		names[name] = true
+	}
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	var lines []string
+	for _, name := range sortedNames {
+		currentValue, inCurrent := currentFields[name]
+		proposedValue, inProposed := proposedFields[name]
+		switch {
+		case inCurrent && inProposed && string(currentValue) == string(proposedValue):
+			lines = append(lines, fmt.Sprintf("  %s: %s", name, currentValue))
+		default:
+			if inCurrent {
+				lines = append(lines, fmt.Sprintf("- %s: %s", name, currentValue))
+			}
+			if inProposed {
+				lines = append(lines, fmt.Sprintf("+ %s: %s", name, proposedValue))
+			}
+		}
+	}
+	return lines
+}
+
+// stateFields renders a value as one line per field when it is a JSON object.
+func stateFields(value []byte) []string {
+	fields, ok := objectFields(value)
+	if !ok {
+		return []string{string(value)}
+	}
+
+	lines := make([]string, 0, len(fields))
+	for name, fieldValue := range fields {
+		lines = append(lines, fmt.Sprintf("%s: %s", name, fieldValue))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func objectFields(value []byte) (map[string]json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+func prefixLines(prefix string, lines []string) []string {
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return lines
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+const sloUsage = `usage: slo report -log file [-hours n] [-target d] [-objective f] [-json]`
+
+// sloCommand summarizes the commit latency log written by the REST server with
+// SLO_LOG over the last hours: the transactions that committed valid within
+// the target, the latency percentiles and the error budget left, in total and
+// by function.
+func sloCommand(args []string) error {
+	if len(args) == 0 || args[0] != "report" {
+		return errors.New(sloUsage)
+	}
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	logPath := flags.String("log", "", "commit latency log of the REST server, its SLO_LOG")
+	hours := flags.Float64("hours", 24, "hours to summarize, up to now")
+	target := flags.Duration("target", assetclient.DefaultCommitSLO.Target, "commit latency a transaction must stay within")
+	objective := flags.Float64("objective", assetclient.DefaultCommitSLO.Objective, "fraction of the transactions that must commit valid within -target")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *logPath == "" {
+		return errors.New(sloUsage)
+	}
+	if *hours <= 0 || *target <= 0 || *objective <= 0 || *objective >= 1 {
+		return errors.New("-hours and -target must be positive, and -objective between 0 and 1")
+	}
+
+	file, err := os.Open(*logPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	since := time.Now().Add(-time.Duration(*hours * float64(time.Hour)))
+	observations, err := assetclient.ReadCommitObservations(file, since)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *logPath, err)
+	}
+	report := assetclient.NewSLOReport(observations, assetclient.CommitSLO{Target: *target, Objective: *objective}, since)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Printf("Commits since %s, %.4g%% valid within %s:\n", report.Since.Format(time.RFC3339), *objective*100, *target)
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "FUNCTION\tTX\tGOOD\tINVALID\tTIMEOUT\tP50\tP95\tP99\tCOMPLIANCE\tBUDGET LEFT")
+	for _, summary := range append(report.Functions, report.Total) {
+		function := summary.Function
+		if function == "" {
+			function = "(all)"
+		}
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%.3gs\t%.3gs\t%.3gs\t%.2f%%\t%.1f%%\n",
+			function, summary.Transactions, summary.Good, summary.Invalid, summary.TimedOut,
+			summary.P50Seconds, summary.P95Seconds, summary.P99Seconds, summary.Compliance*100, summary.ErrorBudgetRemaining*100)
+	}
+	return table.Flush()
+}
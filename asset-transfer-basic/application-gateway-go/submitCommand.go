@@ -0,0 +1,297 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/orderer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ordererTLSCertPath is the TLS CA of the orderers of the test network.
+const ordererTLSCertPath = "../../test-network/organizations/ordererOrganizations/example.com/tlsca/tlsca.example.com-cert.pem"
+
+const submitUsage = `usage: submit [-orderers address[=server-name],...] [-transient key=json] [-wait submit|commit|orgs:N] [-wait-orgs org,...] <function> [args...]`
+
+// submitCommand submits any transaction function and reports which orderer
+// accepted it. By default the transaction is submitted through the Gateway
+// peer, which picks the orderers itself and reports the error of each orderer
+// it tried on failure. With -orderers, or ORDERER_ENDPOINTS, the endorsed
+// transaction is broadcast to the listed orderers in turn until one accepts
+// it, overriding the Gateway's choice, such as to skip the orderers under
+// maintenance. Every attempt is printed with its status and duration. With
+// -wait, or COMMIT_WAIT, the command returns once the orderers accepted the
+// transaction, once the Gateway peer committed it (the default), or once the
+// peers of N of the -wait-orgs organizations, or COMMIT_WAIT_ORGS, committed
+// it as seen in their block events. With -local, the transaction runs directly against the in-memory ledger of a
+// chaincode started with LOCAL_LEDGER_ADDRESS, without a Fabric network.
+func submitCommand(args []string) error {
+	flags := flag.NewFlagSet("submit", flag.ContinueOnError)
+	commandSettings := settings.Sub(flags)
+	orderers := commandSettings.String("orderers", "ORDERER_ENDPOINTS", "", "comma separated orderers to broadcast to in turn, as address or address=TLS server name")
+	tlsCertPath := commandSettings.String("orderer-tls-cert", "ORDERER_TLS_CERT", ordererTLSCertPath, "TLS CA certificate of the orderers")
+	transient := flags.String("transient", "", "transient data entry, as key=JSON value")
+	wait := commandSettings.String("wait", "COMMIT_WAIT", string(assetclient.WaitCommit), "what to wait for: submit, commit, or orgs:N for the commit on N of the -wait-orgs", checkCommitWait)
+	waitOrgs := commandSettings.String("wait-orgs", "COMMIT_WAIT_ORGS", "", "comma separated organizations whose block events confirm commits, such as org1,org2")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := commandSettings.Resolve(os.LookupEnv); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New(submitUsage)
+	}
+	policy, err := assetclient.ParseCommitPolicy(*wait)
+	if err != nil {
+		return err
+	}
+	// the command exits once it returns, leaving no time to submit in the background
+	if policy.Wait == assetclient.WaitNone {
+		return errors.New("the submit command waits at least for the orderers, -wait none is for long-running clients")
+	}
+	if err := checkFunctionPolicy(flags.Arg(0), true); err != nil {
+		return err
+	}
+
+	var transientData map[string][]byte
+	options := []client.ProposalOption{client.WithArguments(flags.Args()[1:]...)}
+	if *transient != "" {
+		key, value, found := strings.Cut(*transient, "=")
+		if !found || !json.Valid([]byte(value)) {
+			return fmt.Errorf("invalid transient entry %q, expected key=JSON value", *transient)
+		}
+		transientData = map[string][]byte{key: []byte(value)}
+		options = append(options, client.WithTransient(transientData))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *localLedger != "" {
+		return submitLocal(ctx, *localLedger, flags.Arg(0), transientData, flags.Args()[1:])
+	}
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	waiter, closeWaiter, err := newCommitWaiter(policy, *waitOrgs, id, sign)
+	if err != nil {
+		return err
+	}
+	defer closeWaiter()
+
+	contract := gw.GetNetwork(channelName()).GetContract(chaincodeName())
+	proposal, err := contract.NewProposal(flags.Arg(0), options...)
+	if err != nil {
+		return err
+	}
+	transaction, err := proposal.EndorseWithContext(ctx)
+	if err != nil {
+		return assetclient.NewMultiPeerError(err)
+	}
+
+	var commit *client.Commit
+	if *orderers == "" {
+		if commit, err = transaction.SubmitWithContext(ctx); err != nil {
+			return assetclient.NewMultiPeerError(err)
+		}
+		fmt.Printf("Transaction %s accepted by the orderers of the Gateway peer %s\n", transaction.TransactionID(), peer.gatewayPeer)
+	} else {
+		if commit, err = broadcastToOrderers(ctx, gw, transaction, *orderers, *tlsCertPath); err != nil {
+			return err
+		}
+	}
+
+	var status *client.Status
+	switch policy.Wait {
+	case assetclient.WaitSubmit:
+		if len(transaction.Result()) > 0 {
+			fmt.Println(string(transaction.Result()))
+		}
+		return nil
+	case assetclient.WaitOrgs:
+		outcome, err := waiter.Wait(ctx, commit, policy)
+		var orgsErr *assetclient.CommitOrgsError
+		if errors.As(err, &orgsErr) {
+			return err
+		} else if err != nil {
+			return assetclient.NewMultiPeerError(err)
+		}
+		fmt.Printf("Commit confirmed by %s\n", strings.Join(outcome.Orgs, ", "))
+		status = outcome.Status
+	default:
+		if status, err = waitForCommit(ctx, commit); err != nil {
+			return assetclient.NewMultiPeerError(err)
+		}
+	}
+	fmt.Printf("Committed in block %d with status %s\n", status.BlockNumber, status.Code)
+	if len(transaction.Result()) > 0 {
+		fmt.Println(string(transaction.Result()))
+	}
+	if !status.Successful {
+		return &client.CommitError{TransactionID: status.TransactionID, Code: status.Code}
+	}
+	return nil
+}
+
+// newCommitWaiter returns the waiter of policy, reading the block events of the
+// peers of the comma separated test network organizations orgs, such as
+// org1,org2, when policy waits for the commit on several organizations. The
+// returned function closes their connections.
+func newCommitWaiter(policy assetclient.CommitPolicy, orgs string, id identity.Identity, sign identity.Sign) (*assetclient.CommitWaiter, func(), error) {
+	waiter := &assetclient.CommitWaiter{}
+	var connections []*grpc.ClientConn
+	closeAll := func() {
+		for _, connection := range connections {
+			connection.Close()
+		}
+	}
+	if policy.Wait != assetclient.WaitOrgs || orgs == "" {
+		return waiter, closeAll, nil
+	}
+
+	for _, org := range strings.Split(orgs, ",") {
+		org = strings.TrimSpace(org)
+		peer, err := testNetworkPeer(org)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		connection, err := newGrpcConnection(peer)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		connections = append(connections, connection)
+
+		gw, err := connectGateway(connection, id, sign)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		// the Gateway holds no resources of its own beyond the connection
+		waiter.Orgs = append(waiter.Orgs, assetclient.NetworkBlocks(strings.ToUpper(org[:1])+org[1:]+"MSP", gw.GetNetwork(channelName())))
+	}
+	return waiter, closeAll, nil
+}
+
+// submitLocal submits the transaction to the local ledger at url, as an admin
+// when the wallet identity is labeled as one, such as Admin@org1.
+func submitLocal(ctx context.Context, url string, function string, transient map[string][]byte, args []string) error {
+	ledger := assetclient.NewLocalLedger(url)
+	ledger.Admin = strings.HasPrefix(strings.ToLower(*identityLabel), "admin")
+	result, err := ledger.Submit(ctx, function, transient, args...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Transaction %s committed to the local ledger %s\n", result.TransactionID, url)
+	if result.EventName != "" {
+		fmt.Printf("Event %s: %s\n", result.EventName, result.EventPayload)
+	}
+	if len(result.Payload) > 0 {
+		fmt.Println(string(result.Payload))
+	}
+	return nil
+}
+
+// broadcastToOrderers broadcasts the transaction to the listed orderers, prints
+// the outcome of each attempt, and returns the commit to wait for.
+func broadcastToOrderers(ctx context.Context, gw *client.Gateway, transaction *client.Transaction, endpoints string, tlsCertPath string) (*client.Commit, error) {
+	var orderers []assetclient.Orderer
+	for _, endpoint := range strings.Split(endpoints, ",") {
+		address, serverName, _ := strings.Cut(strings.TrimSpace(endpoint), "=")
+		connection, err := newOrdererConnection(address, serverName, tlsCertPath)
+		if err != nil {
+			return nil, err
+		}
+		defer connection.Close()
+		orderers = append(orderers, assetclient.Orderer{Address: address, Client: orderer.NewAtomicBroadcastClient(connection)})
+	}
+
+	consensus, err := ordererConsensus()
+	if err != nil {
+		return nil, err
+	}
+	diagnostics, err := assetclient.BroadcastTransaction(ctx, transaction, orderers, consensus.SubmitTimeout())
+	if diagnostics != nil {
+		for _, attempt := range diagnostics.Attempts {
+			fmt.Printf("Orderer %s\n", attempt)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Transaction %s accepted by orderer %s\n", diagnostics.TransactionID, diagnostics.AcceptedBy)
+
+	return assetclient.NewCommit(gw, transaction)
+}
+
+// checkCommitWait validates the -wait setting.
+func checkCommitWait(value string) error {
+	_, err := assetclient.ParseCommitPolicy(value)
+	return err
+}
+
+// newOrdererConnection creates a gRPC connection to an orderer. The TLS server
+// name defaults to the host of the address.
+func newOrdererConnection(address string, serverName string, tlsCertPath string) (*grpc.ClientConn, error) {
+	certificatePEM, err := os.ReadFile(tlsCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orderer TLS certificate file: %w", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+
+	if serverName == "" {
+		if serverName, _, err = net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("invalid orderer address %q: %w", address, err)
+		}
+	}
+	// the pinned fingerprints are those of the Gateway peer
+	tlsOptions, err := assetclient.TLSOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	tlsOptions.PinnedFingerprints = nil
+
+	connection, err := grpc.NewClient(address, grpc.WithTransportCredentials(credentials.NewTLS(tlsOptions.Config(certPool, serverName))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection to orderer %s: %w", address, err)
+	}
+	return connection, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+// versionCommand prints the build of the chaincode serving the Gateway peer, to
+// confirm which build a peer runs. Run it with PEER_ENDPOINT set to each peer
+// in turn to compare them.
+func versionCommand(args []string) error {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the version info as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFunctionPolicy("GetVersionInfo", false); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	peer, id, sign, err := clientIdentity()
+	if err != nil {
+		return err
+	}
+	clientConnection, err := newGrpcConnection(peer)
+	if err != nil {
+		return err
+	}
+	defer clientConnection.Close()
+
+	gw, err := connectGateway(clientConnection, id, sign)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	info, err := assetclient.ReadVersionInfo(ctx, gw.GetNetwork(channelName()).GetContract(chaincodeName()))
+	if err != nil {
+		return fmt.Errorf("failed to read the chaincode version: %w", assetclient.NewMultiPeerError(err))
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+	fmt.Printf("%s on %s: %s\n", chaincodeName(), peer.endpoint, info)
+	return nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+)
+
+// walletIdentityExtension is the file extension of identities stored in a wallet directory.
+const walletIdentityExtension = ".id"
+
+// testNetworkOrg matches the organization names used by the test network, such as org1.
+var testNetworkOrg = regexp.MustCompile(`^org(\d+)$`)
+
+// walletIdentity is an X.509 identity stored in the wallet, using the same file
+// layout as the Fabric SDK file system wallets.
+type walletIdentity struct {
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+	MspID   string `json:"mspId"`
+	Org     string `json:"org,omitempty"`
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+
+	label string
+}
+
+// wallet stores identities as files in a directory, one file per label.
+type wallet struct {
+	dir string
+}
+
+// newWallet returns the wallet at the path set with -wallet, ./wallet by default.
+func newWallet() *wallet {
+	return &wallet{dir: *walletPath}
+}
+
+// list returns the identities in the wallet sorted by label.
+func (w *wallet) list() ([]*walletIdentity, error) {
+	entries, err := os.ReadDir(w.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet directory: %w", err)
+	}
+
+	var ids []*walletIdentity
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), walletIdentityExtension) {
+			continue
+		}
+		id, err := w.get(strings.TrimSuffix(entry.Name(), walletIdentityExtension))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].label < ids[j].label
+	})
+	return ids, nil
+}
+
+// get returns the identity stored under label.
+func (w *wallet) get(label string) (*walletIdentity, error) {
+	idJSON, err := os.ReadFile(w.path(label))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("identity %s is not in the wallet %s", label, w.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity %s: %w", label, err)
+	}
+
+	id := &walletIdentity{label: label}
+	if err := json.Unmarshal(idJSON, id); err != nil {
+		return nil, fmt.Errorf("failed to parse identity %s: %w", label, err)
+	}
+	return id, nil
+}
+
+// put stores the identity under its label, replacing any existing identity.
+func (w *wallet) put(id *walletIdentity) error {
+	if err := os.MkdirAll(w.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create wallet directory: %w", err)
+	}
+
+	idJSON, err := json.MarshalIndent(id, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.path(id.label), idJSON, 0o600)
+}
+
+// remove deletes the identity stored under label.
+func (w *wallet) remove(label string) error {
+	err := os.Remove(w.path(label))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("identity %s is not in the wallet %s", label, w.dir)
+	}
+	return err
+}
+
+func (w *wallet) path(label string) string {
+	return path.Join(w.dir, label+walletIdentityExtension)
+}
+
+// newWalletIdentityFromMSP reads the first certificate in signcerts and the first key
+// in keystore of an MSP directory, as laid out by cryptogen and the Fabric CA client.
+func newWalletIdentityFromMSP(label string, mspID string, org string, mspDir string) (*walletIdentity, error) {
+	certificatePEM, err := readFirstFile(path.Join(mspDir, "signcerts"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	privateKeyPEM, err := readFirstFile(path.Join(mspDir, "keystore"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
+	}
+
+	return newWalletIdentity(label, mspID, org, certificatePEM, privateKeyPEM)
+}
+
+// newWalletIdentity validates the PEM encoded credentials and returns them as a wallet identity.
+func newWalletIdentity(label string, mspID string, org string, certificatePEM []byte, privateKeyPEM []byte) (*walletIdentity, error) {
+	if _, err := identity.CertificateFromPEM(certificatePEM); err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	if _, err := identity.PrivateKeyFromPEM(privateKeyPEM); err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	if mspID == "" {
+		mspID = defaultMSPID(org)
+	}
+	if mspID == "" {
+		return nil, errors.New("an MSP ID is required when the organization is not a test network organization")
+	}
+
+	id := &walletIdentity{
+		MspID:   mspID,
+		Org:     org,
+		Type:    "X.509",
+		Version: 1,
+		label:   label,
+	}
+	id.Credentials.Certificate = string(certificatePEM)
+	id.Credentials.PrivateKey = string(privateKeyPEM)
+	return id, nil
+}
+
+// certificate returns the parsed X.509 certificate of the identity.
+func (id *walletIdentity) certificate() (*x509.Certificate, error) {
+	return identity.CertificateFromPEM([]byte(id.Credentials.Certificate))
+}
+
+// x509Identity returns the identity used to connect to the Gateway.
+func (id *walletIdentity) x509Identity() (*identity.X509Identity, error) {
+	certificate, err := id.certificate()
+	if err != nil {
+		return nil, err
+	}
+	return identity.NewX509Identity(id.MspID, certificate)
+}
+
+// sign returns the signing function of the identity.
+func (id *walletIdentity) sign() (identity.Sign, error) {
+	privateKey, err := identity.PrivateKeyFromPEM([]byte(id.Credentials.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	return identity.NewPrivateKeySign(privateKey)
+}
+
+// defaultMSPID returns the MSP ID used by the test network for org, such as Org2MSP for org2.
+func defaultMSPID(org string) string {
+	match := testNetworkOrg.FindStringSubmatch(org)
+	if match == nil {
+		return ""
+	}
+	return "Org" + match[1] + "MSP"
+}
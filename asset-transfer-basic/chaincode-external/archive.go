@@ -0,0 +1,309 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// archiveObjectType is the composite key prefix of the archive~ namespace,
+// keyed by asset id, holding the closed assets moved out of the world state by
+// ArchiveAsset. Composite keys are outside the range scans and rich queries
+// over the assets, so archived assets no longer weigh on them.
+const archiveObjectType = "archive"
+
+// ArchivedAsset is an asset moved to the archive~ namespace with its audit
+// records, oldest first, by the transaction TXID at ARCHIVEDAT. The private
+// details of the asset stay in their collection.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type ArchivedAsset struct {
+	ARCHIVEDAT string         `json:"archivedat"`
+	ASSET      *Asset         `json:"asset"`
+	AUDIT      []*AuditRecord `json:"audit"`
+	TXID       string         `json:"txid"`
+}
+
+// ArchivePage is one page of the archived assets. BOOKMARK fetches the next
+// page and is empty after the last one.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type ArchivePage struct {
+	ASSETS   []*ArchivedAsset `json:"assets"`
+	BOOKMARK string           `json:"bookmark"`
+}
+
+// ArchiveAsset moves a CLOSED asset and its audit records from the world state
+// to the archive~ namespace, keeping the hot keyspace small as the ledger
+// grows. The archived asset is read with GetArchivedAsset and put back with
+// ArchiveRestore, and its id cannot be reused meanwhile. Only admins may call it.
+func (s *SmartContract) ArchiveAsset(ctx contractapi.TransactionContextInterface, id string) (*ArchivedAsset, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	asset, err := readAssetSummary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, assetNotFoundError(id)
+	}
+	if asset.STATUS != statusClosed {
+		return nil, businessError(errCodeInvalidArgument, "the asset %s is %s, only %s assets are archived", id, asset.STATUS, statusClosed)
+	}
+	err = requireNoHolds(asset)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	archived := &ArchivedAsset{
+		ARCHIVEDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		ASSET:      asset,
+		AUDIT:      []*AuditRecord{},
+		TXID:       ctx.GetStub().GetTxID(),
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(auditObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var record AuditRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		archived.AUDIT = append(archived.AUDIT, &record)
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete audit record: %v", err)
+		}
+	}
+	sortAuditRecords(archived.AUDIT)
+
+	err = updateAssetCounters(ctx, asset, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = updateDealerIndex(ctx, asset, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = updateStatusIndex(ctx, asset, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = deleteMSISDNChange(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	err = deleteMPINReset(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	err = putArchivedAsset(ctx, archived)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().DelState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete from world state: %v", err)
+	}
+
+	return archived, nil
+}
+
+// ArchiveRestore moves an archived asset and its audit records back to the
+// world state, as they were when archived apart from the version and update
+// time of the asset, which count the restore as a write. Only admins may call
+// it.
+func (s *SmartContract) ArchiveRestore(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	archived, err := readArchivedAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if archived == nil {
+		return nil, businessError(errCodeAssetNotFound, "the asset %s is not archived", id)
+	}
+	exists, err := s.AssetExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, businessError(errCodeAssetExists, "the asset %s already exists", id)
+	}
+
+	for _, record := range archived.AUDIT {
+		auditKey, err := ctx.GetStub().CreateCompositeKey(auditObjectType, []string{record.ASSETID, record.TXID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		recordJSON, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().PutState(auditKey, recordJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put to world state: %v", err)
+		}
+	}
+	archiveKey, err := ctx.GetStub().CreateCompositeKey(archiveObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(archiveKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete archived asset: %v", err)
+	}
+
+	err = restoreAssetSummary(ctx, archived.ASSET)
+	if err != nil {
+		return nil, err
+	}
+	return archived.ASSET, nil
+}
+
+// restoreAssetSummary writes an archived asset back to the world state like
+// putAssetSummary, its version following the one it was archived with.
+func restoreAssetSummary(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	asset.UPDATEDAT = timestamp.AsTime().UTC().Format(time.RFC3339)
+	asset.VERSION++
+
+	err = updateAssetCounters(ctx, nil, asset)
+	if err != nil {
+		return err
+	}
+	err = updateDealerIndex(ctx, nil, asset)
+	if err != nil {
+		return err
+	}
+	err = updateStatusIndex(ctx, nil, asset)
+	if err != nil {
+		return err
+	}
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(asset.ID, assetJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return nil
+}
+
+// GetArchivedAsset returns the archived asset with given id.
+func (s *SmartContract) GetArchivedAsset(ctx contractapi.TransactionContextInterface, id string) (*ArchivedAsset, error) {
+	archived, err := readArchivedAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if archived == nil {
+		return nil, businessError(errCodeAssetNotFound, "the asset %s is not archived", id)
+	}
+	return archived, nil
+}
+
+// GetArchivedAssets returns a page of the archived assets in id order, for
+// exports to cold storage.
+func (s *SmartContract) GetArchivedAssets(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*ArchivePage, error) {
+	if pageSize <= 0 {
+		return nil, businessError(errCodeInvalidArgument, "the page size must be positive")
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(archiveObjectType, []string{}, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &ArchivePage{ASSETS: []*ArchivedAsset{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var archived ArchivedAsset
+		err = json.Unmarshal(queryResponse.Value, &archived)
+		if err != nil {
+			return nil, err
+		}
+		page.ASSETS = append(page.ASSETS, &archived)
+	}
+	if len(page.ASSETS) == int(pageSize) {
+		page.BOOKMARK = metadata.GetBookmark()
+	}
+
+	return page, nil
+}
+
+// requireNotArchived fails when the asset id belongs to an archived asset, so
+// that it is not reused while the asset may be restored.
+func requireNotArchived(ctx contractapi.TransactionContextInterface, id string) error {
+	archived, err := readArchivedAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	if archived != nil {
+		return businessError(errCodeAssetExists, "the asset %s is archived", id)
+	}
+	return nil
+}
+
+// readArchivedAsset returns the archived asset with given id, or nil when it is
+// not archived.
+func readArchivedAsset(ctx contractapi.TransactionContextInterface, id string) (*ArchivedAsset, error) {
+	archiveKey, err := ctx.GetStub().CreateCompositeKey(archiveObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	archivedJSON, err := ctx.GetStub().GetState(archiveKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if archivedJSON == nil {
+		return nil, nil
+	}
+
+	var archived ArchivedAsset
+	err = json.Unmarshal(archivedJSON, &archived)
+	if err != nil {
+		return nil, err
+	}
+	return &archived, nil
+}
+
+func putArchivedAsset(ctx contractapi.TransactionContextInterface, archived *ArchivedAsset) error {
+	archiveKey, err := ctx.GetStub().CreateCompositeKey(archiveObjectType, []string{archived.ASSET.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	archivedJSON, err := json.Marshal(archived)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(archiveKey, archivedJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return nil
+}
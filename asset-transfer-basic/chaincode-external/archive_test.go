@@ -0,0 +1,111 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestArchiveMovesClosedAssets(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response := call(function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+
+	invoke("InitLedger")
+	if response := call("ArchiveAsset", "asset2"); response.Status == shim.OK {
+		t.Fatal("expected an ACTIVE asset not to be archived")
+	}
+	invoke("TransferAsset", "asset2", "DEALER101")
+	invoke("MergeAssets", "asset1", `["asset2"]`)
+
+	var archived ArchivedAsset
+	if err := json.Unmarshal(invoke("ArchiveAsset", "asset2"), &archived); err != nil {
+		t.Fatal(err)
+	}
+	if archived.ASSET.STATUS != statusClosed || len(archived.AUDIT) == 0 {
+		t.Fatalf("expected the closed asset and its audit records to be archived, got %+v", archived)
+	}
+	if call("ReadAsset", "asset2").Status == shim.OK {
+		t.Fatal("expected the archived asset to leave the world state")
+	}
+	var assets []*Asset
+	if err := json.Unmarshal(invoke("GetAllAssets"), &assets); err != nil {
+		t.Fatal(err)
+	}
+	for _, asset := range assets {
+		if asset.ID == "asset2" {
+			t.Fatal("expected the archived asset not to be listed")
+		}
+	}
+	// an asset without audit records has a nil audit trail
+	if audit := invoke("GetAuditTrail", "asset2"); len(audit) != 0 {
+		t.Fatalf("expected the audit records to be moved, got %s", audit)
+	}
+	response, err := ledger.invoke(localRequest{
+		Function:  "CreateAsset",
+		Args:      []string{"asset2", "DEALER101", "0", "ACTIVE", "0", "INIT"},
+		Transient: map[string][]byte{transientDetailsKey: []byte(`{"mpin":"1234","msisdn":"9800000000"}`)},
+		Submit:    true,
+		Admin:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response.Message, "is archived") {
+		t.Fatalf("expected the id of an archived asset not to be reused, got %q", response.Message)
+	}
+
+	var page ArchivePage
+	if err := json.Unmarshal(invoke("GetArchivedAssets", "10", ""), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.ASSETS) != 1 || page.ASSETS[0].ASSET.ID != "asset2" {
+		t.Fatalf("expected asset2 to be archived, got %+v", page.ASSETS)
+	}
+
+	invoke("ArchiveRestore", "asset2")
+	var restored Asset
+	if err := json.Unmarshal(invoke("ReadAsset", "asset2"), &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.STATUS != statusClosed || restored.VERSION <= archived.ASSET.VERSION {
+		t.Fatalf("expected the asset to be restored, got %+v", restored)
+	}
+	var audit []*AuditRecord
+	if err := json.Unmarshal(invoke("GetAuditTrail", "asset2"), &audit); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit) != len(archived.AUDIT)+1 || audit[len(audit)-1].ACTION != "ArchiveRestore" {
+		t.Fatalf("expected the audit records to be restored and the restore audited, got %d records", len(audit))
+	}
+	if call("GetArchivedAsset", "asset2").Status == shim.OK {
+		t.Fatal("expected the restored asset to leave the archive")
+	}
+}
@@ -0,0 +1,109 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// statusDeleted marks an asset that has been soft-deleted.
+const statusDeleted = "DELETED"
+
+// deleteBatchSize is the number of assets soft-deleted by one DeleteAssetsByDealer transaction.
+const deleteBatchSize = 100
+
+// DeleteResult describes the outcome of a DeleteAssetsByDealer call.
+// TOKEN confirms the deletion of the REMAINING assets in the next call.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DeleteResult struct {
+	DELETED   int    `json:"deleted"`
+	MATCHED   int    `json:"matched"`
+	REMAINING int    `json:"remaining"`
+	TOKEN     string `json:"token"`
+}
+
+// DeleteAssetsByDealer soft-deletes the assets of a dealer by setting their status to DELETED.
+// A dry run only counts the assets and returns the confirmation token that must be passed
+// to the next call. Each call deletes at most deleteBatchSize assets and returns the token
+// confirming the deletion of the remaining ones, so the caller repeats it until none remain.
+// A token is rejected when the set of assets of the dealer changed since it was issued.
+// The assets are found through the dealer~asset index, and none of them may have liens or
+// holds. Only admins may call it.
+func (s *SmartContract) DeleteAssetsByDealer(ctx contractapi.TransactionContextInterface, dealerID string, dryRun bool, confirmationToken string) (*DeleteResult, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	assets, err := dealerAssets(ctx, dealerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range assets {
+		err = requireNoLiens(asset)
+		if err != nil {
+			return nil, err
+		}
+		err = requireNoHolds(asset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &DeleteResult{MATCHED: len(assets), REMAINING: len(assets), TOKEN: deleteToken(dealerID, assets)}
+	if dryRun || len(assets) == 0 {
+		return result, nil
+	}
+	if confirmationToken != result.TOKEN {
+		return nil, businessError(errCodeInvalidArgument, "the confirmation token does not match the assets of dealer %s, run a dry run first", dealerID)
+	}
+
+	batch := assets
+	if len(batch) > deleteBatchSize {
+		batch = batch[:deleteBatchSize]
+	}
+	for _, asset := range batch {
+		asset.STATUS = statusDeleted
+		err = putAssetSummary(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		err = recordAudit(ctx, asset.ID, "DeleteAssetsByDealer")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	remaining := assets[len(batch):]
+	result.DELETED = len(batch)
+	result.REMAINING = len(remaining)
+	result.TOKEN = ""
+	if len(remaining) > 0 {
+		result.TOKEN = deleteToken(dealerID, remaining)
+	}
+
+	return result, nil
+}
+
+// requireOpen fails when an asset was soft-deleted or closed by a merge, as
+// neither may be written again.
+func requireOpen(asset *Asset) error {
+	if asset.STATUS == statusDeleted || asset.STATUS == statusClosed {
+		return businessError(errCodeInvalidArgument, "the asset %s is %s", asset.ID, asset.STATUS)
+	}
+	return nil
+}
+
+// deleteToken returns the hex encoded SHA-256 of the dealer and the ids of its assets.
+func deleteToken(dealerID string, assets []*Asset) string {
+	hash := sha256.New()
+	hash.Write([]byte(dealerID))
+	for _, asset := range assets {
+		hash.Write([]byte{0x00})
+		hash.Write([]byte(asset.ID))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,91 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestDeleteAssetsByDealer(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response := call(function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	dryRun := func(dealerID string) *DeleteResult {
+		t.Helper()
+		var result DeleteResult
+		if err := json.Unmarshal(invoke("DeleteAssetsByDealer", dealerID, "true", ""), &result); err != nil {
+			t.Fatal(err)
+		}
+		return &result
+	}
+
+	invoke("InitLedger")
+
+	response, err := ledger.invoke(localRequest{Function: "DeleteAssetsByDealer", Args: []string{"DEALER103", "true", ""}, Submit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status == shim.OK || !strings.Contains(response.Message, "not an admin") {
+		t.Errorf("expected a client to be refused, got status %d: %s", response.Status, response.Message)
+	}
+
+	invoke("PlaceLien", "asset3", "100", "BANK1", "loan1")
+	if response := call("DeleteAssetsByDealer", "DEALER103", "true", ""); response.Status == shim.OK || !strings.Contains(response.Message, "liens") {
+		t.Errorf("expected the assets of a dealer with liens not to be deleted, got status %d: %s", response.Status, response.Message)
+	}
+	invoke("ReleaseLien", "asset3", "loan1")
+
+	result := dryRun("DEALER103")
+	if result.MATCHED != 1 || result.DELETED != 0 || result.TOKEN == "" {
+		t.Fatalf("expected a dry run to match the asset of DEALER103, got %+v", result)
+	}
+	if response := call("DeleteAssetsByDealer", "DEALER103", "false", "stale"); response.Status == shim.OK || !strings.Contains(response.Message, errCodeInvalidArgument) {
+		t.Errorf("expected a wrong confirmation token to be an invalid argument, got status %d: %s", response.Status, response.Message)
+	}
+	if err := json.Unmarshal(invoke("DeleteAssetsByDealer", "DEALER103", "false", result.TOKEN), result); err != nil {
+		t.Fatal(err)
+	}
+	if result.DELETED != 1 || result.REMAINING != 0 {
+		t.Errorf("expected the asset to be deleted, got %+v", result)
+	}
+	if result := dryRun("DEALER103"); result.MATCHED != 0 {
+		t.Errorf("expected no assets of DEALER103 left, got %+v", result)
+	}
+
+	for _, write := range [][]string{
+		{"UpdateAsset", "asset3", "DEALER103", "1500", "ACTIVE", "0", "REVIEW"},
+		{"TransferAsset", "asset3", "DEALER101"},
+		{"PatchAsset", "asset3", `{"status":"ACTIVE"}`},
+	} {
+		if response := call(write[0], write[1:]...); response.Status == shim.OK || !strings.Contains(response.Message, statusDeleted) {
+			t.Errorf("expected %s of a deleted asset to be refused, got status %d: %s", write[0], response.Status, response.Message)
+		}
+	}
+}
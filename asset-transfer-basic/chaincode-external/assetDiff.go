@@ -0,0 +1,124 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// assetChangedEvent is the event listing the fields changed by UpdateAsset and
+// PatchAsset, so indexers keep a field-level history without reading the
+// previous state of the asset.
+const assetChangedEvent = "AssetChanged"
+
+// diffExcludedFields are the asset fields left out of the diff. The details
+// hash and organization reveal when the private details change, which the diff
+// reports without them, the spendable balance follows from the balance, liens
+// and holds, and the timestamp and version are in the event itself.
+var diffExcludedFields = map[string]bool{
+	"detailshash": true,
+	"detailsorg":  true,
+	"spendable":   true,
+	"updatedat":   true,
+	"version":     true,
+}
+
+// AssetDiff is the payload of the AssetChanged event. DETAILSCHANGED is set
+// when the private details changed, whose values are never part of the event.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetDiff struct {
+	CHANGES        []*FieldChange `json:"changes"`
+	DETAILSCHANGED bool           `json:"detailschanged,omitempty" metadata:",optional"`
+	ID             string         `json:"ID"`
+	TXID           string         `json:"txid"`
+	UPDATEDAT      string         `json:"updatedat"`
+	VERSION        int64          `json:"version"`
+}
+
+// FieldChange is the value of an asset field before and after a change, null
+// when the field is absent.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type FieldChange struct {
+	AFTER  json.RawMessage `json:"after"`
+	BEFORE json.RawMessage `json:"before"`
+	FIELD  string          `json:"field"`
+}
+
+// diffAssets returns the changes of the fields of the asset from before to
+// after, sorted by field.
+func diffAssets(before *Asset, after *Asset) ([]*FieldChange, error) {
+	beforeFields, err := assetFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := assetFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]*FieldChange, 0)
+	for field := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			afterFields[field] = nil
+		}
+	}
+	for field, value := range afterFields {
+		if diffExcludedFields[field] || bytes.Equal(beforeFields[field], value) {
+			continue
+		}
+		changes = append(changes, &FieldChange{AFTER: value, BEFORE: beforeFields[field], FIELD: field})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FIELD < changes[j].FIELD
+	})
+	return changes, nil
+}
+
+func assetFields(asset *Asset) (map[string]json.RawMessage, error) {
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(assetJSON, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// setAssetChangedEvent sets the AssetChanged event of a change of the asset
+// from before to after, as written. Changes of excluded fields only set no event.
+func setAssetChangedEvent(ctx contractapi.TransactionContextInterface, before *Asset, after *Asset) error {
+	changes, err := diffAssets(before, after)
+	if err != nil {
+		return err
+	}
+	detailsChanged := before.DETAILSHASH != after.DETAILSHASH
+	if len(changes) == 0 && !detailsChanged {
+		return nil
+	}
+
+	diffJSON, err := json.Marshal(AssetDiff{
+		CHANGES:        changes,
+		DETAILSCHANGED: detailsChanged,
+		ID:             after.ID,
+		TXID:           ctx.GetStub().GetTxID(),
+		UPDATEDAT:      after.UPDATEDAT,
+		VERSION:        after.VERSION,
+	})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(assetChangedEvent, diffJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
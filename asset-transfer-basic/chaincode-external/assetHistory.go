@@ -0,0 +1,160 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// maxBalanceSamples bounds the size of a GetBalanceSeries response.
+const maxBalanceSamples = 1000
+
+// BalanceSample describes the balance of an asset at a point in time.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type BalanceSample struct {
+	BALANCE   float64 `json:"balance"`
+	EXISTS    bool    `json:"exists"`
+	TIMESTAMP string  `json:"timestamp"`
+}
+
+// AssetHistoryEntry is one committed value of an asset key, with the
+// transaction that wrote it. ASSET is null for the deletions of the asset.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetHistoryEntry struct {
+	ASSET     *Asset `json:"asset"`
+	ISDELETE  bool   `json:"isdelete"`
+	TIMESTAMP string `json:"timestamp"`
+	TXID      string `json:"txid"`
+}
+
+// assetVersion is one committed value of an asset key.
+type assetVersion struct {
+	asset     *Asset
+	isDelete  bool
+	timestamp time.Time
+	txID      string
+}
+
+// GetBalanceSeries returns the balance of the asset sampled every interval from
+// from to to, both RFC3339 timestamps, reconstructed from the key history.
+// Interval is a Go duration such as "1h" or "24h". Samples taken before the asset
+// was created or after it was deleted report exists=false and a zero balance.
+func (s *SmartContract) GetBalanceSeries(ctx contractapi.TransactionContextInterface, id string, from string, to string, interval string) ([]*BalanceSample, error) {
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from timestamp: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to timestamp: %v", err)
+	}
+	step, err := time.ParseDuration(interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %v", err)
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	if end.Sub(start)/step >= maxBalanceSamples {
+		return nil, fmt.Errorf("the requested series exceeds %d samples", maxBalanceSamples)
+	}
+
+	versions, err := getAssetVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, assetNotFoundError(id)
+	}
+
+	var samples []*BalanceSample
+	next := 0
+	var current *assetVersion
+	for t := start; !t.After(end); t = t.Add(step) {
+		for next < len(versions) && !versions[next].timestamp.After(t) {
+			current = versions[next]
+			next++
+		}
+
+		sample := &BalanceSample{TIMESTAMP: t.UTC().Format(time.RFC3339)}
+		if current != nil && !current.isDelete {
+			sample.EXISTS = true
+			sample.BALANCE = current.asset.BALANCE
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// GetAssetHistory returns every committed value of the asset with given id,
+// oldest first, including its deletions, for the audit trail of dealer
+// accounts and their past balances.
+func (s *SmartContract) GetAssetHistory(ctx contractapi.TransactionContextInterface, id string) ([]*AssetHistoryEntry, error) {
+	versions, err := getAssetVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, assetNotFoundError(id)
+	}
+
+	history := make([]*AssetHistoryEntry, 0, len(versions))
+	for _, version := range versions {
+		history = append(history, &AssetHistoryEntry{
+			ASSET:     version.asset,
+			ISDELETE:  version.isDelete,
+			TIMESTAMP: version.timestamp.UTC().Format(time.RFC3339Nano),
+			TXID:      version.txID,
+		})
+	}
+	return history, nil
+}
+
+// getAssetVersions returns every committed value of the asset key, oldest first.
+func getAssetVersions(ctx contractapi.TransactionContextInterface, id string) ([]*assetVersion, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var versions []*assetVersion
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		version := &assetVersion{
+			isDelete:  modification.IsDelete,
+			timestamp: modification.Timestamp.AsTime(),
+			txID:      modification.TxId,
+		}
+		if !modification.IsDelete {
+			var asset Asset
+			err = json.Unmarshal(modification.Value, &asset)
+			if err != nil {
+				return nil, err
+			}
+			version.asset = &asset
+		}
+		versions = append(versions, version)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].timestamp.Before(versions[j].timestamp)
+	})
+
+	return versions, nil
+}
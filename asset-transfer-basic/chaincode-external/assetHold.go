@@ -0,0 +1,271 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// holdPolicyObjectType is the composite key prefix of the hold policy, which
+// has no attributes.
+const holdPolicyObjectType = "holdpolicy"
+
+// holdObjectType is the composite key prefix of the holds, keyed by the asset
+// and the transaction placing the hold.
+const holdObjectType = "hold"
+
+// Bases of a hold rule: the balance of the asset or the amount of the
+// transaction.
+const (
+	holdBasisBalance     = "balance"
+	holdBasisTransAmount = "transamount"
+)
+
+// HoldRule holds PERCENT percent of the BASIS of the transactions of a type,
+// the new balance of the asset or the transaction amount.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type HoldRule struct {
+	BASIS   string  `json:"basis"`
+	PERCENT float64 `json:"percent"`
+}
+
+// HoldPolicy maps transaction types to the rule of the hold placed by the
+// CreateAsset and UpdateAsset transactions of that type, such as
+// {"SUSPEND":{"basis":"balance","percent":100}} to hold the whole balance of
+// suspended assets or {"DISPUTE":{"basis":"transamount","percent":100}} to
+// hold the disputed amount.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type HoldPolicy struct {
+	RULES     map[string]*HoldRule `json:"rules"`
+	UPDATEDAT string               `json:"updatedat"`
+	UPDATEDBY string               `json:"updatedby"`
+}
+
+// Hold is an amount of the balance of ASSETID held by the transaction TXID of
+// type TRANSTYPE under the RULE of the hold policy, at PLACEDAT in the layout of
+// the audit timestamps. Like a lien it stays in the wallet but cannot be spent
+// until the hold is released.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type Hold struct {
+	AMOUNT    float64   `json:"amount"`
+	ASSETID   string    `json:"assetid"`
+	PLACEDAT  string    `json:"placedat"`
+	RULE      *HoldRule `json:"rule"`
+	TRANSTYPE string    `json:"transtype"`
+	TXID      string    `json:"txid"`
+}
+
+// SetHoldPolicy replaces the hold policy with policyJSON, a HoldPolicy without
+// its update fields. It applies to the transactions that follow; the holds in
+// place are kept. Only admins may call it.
+func (s *SmartContract) SetHoldPolicy(ctx contractapi.TransactionContextInterface, policyJSON string) (*HoldPolicy, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var policy HoldPolicy
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "the hold policy must be a JSON object: %v", err)
+	}
+	if policy.RULES == nil {
+		policy.RULES = map[string]*HoldRule{}
+	}
+	for transType, rule := range policy.RULES {
+		if transType == "" || rule == nil {
+			return nil, businessError(errCodeInvalidArgument, "a hold rule needs a transaction type and a rule")
+		}
+		if rule.BASIS != holdBasisBalance && rule.BASIS != holdBasisTransAmount {
+			return nil, businessError(errCodeInvalidArgument, "the basis of the %s hold rule must be %s or %s", transType, holdBasisBalance, holdBasisTransAmount)
+		}
+		if rule.PERCENT <= 0 || rule.PERCENT > 100 {
+			return nil, businessError(errCodeInvalidArgument, "the percentage %v of the %s hold rule must be above 0 and at most 100", rule.PERCENT, transType)
+		}
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	policy.UPDATEDAT = timestamp.AsTime().UTC().Format(time.RFC3339)
+	policy.UPDATEDBY = clientID
+
+	storedJSON, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	policyKey, err := ctx.GetStub().CreateCompositeKey(holdPolicyObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(policyKey, storedJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return &policy, nil
+}
+
+// GetHoldPolicy returns the hold policy, nil when none was set.
+func (s *SmartContract) GetHoldPolicy(ctx contractapi.TransactionContextInterface) (*HoldPolicy, error) {
+	return readHoldPolicy(ctx)
+}
+
+func readHoldPolicy(ctx contractapi.TransactionContextInterface) (*HoldPolicy, error) {
+	policyKey, err := ctx.GetStub().CreateCompositeKey(holdPolicyObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	policyJSON, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if policyJSON == nil {
+		return nil, nil
+	}
+
+	var policy HoldPolicy
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetHolds returns the holds in place on an asset, oldest first, with the
+// transactions that placed them.
+func (s *SmartContract) GetHolds(ctx contractapi.TransactionContextInterface, id string) ([]*Hold, error) {
+	if _, err := s.ReadAsset(ctx, id); err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(holdObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	holds := []*Hold{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var hold Hold
+		if err := json.Unmarshal(queryResponse.Value, &hold); err != nil {
+			return nil, err
+		}
+		holds = append(holds, &hold)
+	}
+	sort.SliceStable(holds, func(i, j int) bool { return holds[i].PLACEDAT < holds[j].PLACEDAT })
+
+	return holds, nil
+}
+
+// ReleaseHold removes the hold placed on an asset by the transaction txID,
+// returning the held amount to its spendable balance. Only admins can release
+// holds.
+func (s *SmartContract) ReleaseHold(ctx contractapi.TransactionContextInterface, id string, txID string) (*Hold, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	holdKey, err := ctx.GetStub().CreateCompositeKey(holdObjectType, []string{id, txID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hold key: %v", err)
+	}
+	holdJSON, err := ctx.GetStub().GetState(holdKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hold: %v", err)
+	}
+	if holdJSON == nil {
+		return nil, businessError(errCodeInvalidArgument, "the asset %s has no hold placed by transaction %s", id, txID)
+	}
+	var hold Hold
+	if err := json.Unmarshal(holdJSON, &hold); err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().DelState(holdKey); err != nil {
+		return nil, fmt.Errorf("failed to delete hold: %v", err)
+	}
+
+	// guard against rounding leaving a residue once the last hold is released
+	asset.HELD = math.Max(asset.HELD-hold.AMOUNT, 0)
+	if asset.HELD < 1e-9 {
+		asset.HELD = 0
+	}
+	return &hold, putAssetSummary(ctx, asset)
+}
+
+// placeHold places the hold the hold policy sets for the transaction type of
+// an asset about to be written by CreateAsset or UpdateAsset, adding it to
+// HELD. A hold is at most the spendable balance left by the liens and holds
+// already in place, and none is placed when nothing is left.
+func placeHold(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	policy, err := readHoldPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if policy == nil || policy.RULES[asset.TRANSTYPE] == nil {
+		return nil
+	}
+	rule := policy.RULES[asset.TRANSTYPE]
+
+	basis := asset.BALANCE
+	if rule.BASIS == holdBasisTransAmount {
+		basis = asset.TRANSAMOUNT
+	}
+	amount := math.Min(basis*rule.PERCENT/100, asset.BALANCE-asset.LIENED-asset.HELD)
+	if amount <= 0 {
+		return nil
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	hold := &Hold{
+		AMOUNT:    amount,
+		ASSETID:   asset.ID,
+		PLACEDAT:  timestamp.AsTime().UTC().Format(auditTimeLayout),
+		RULE:      rule,
+		TRANSTYPE: asset.TRANSTYPE,
+		TXID:      ctx.GetStub().GetTxID(),
+	}
+	holdKey, err := ctx.GetStub().CreateCompositeKey(holdObjectType, []string{asset.ID, hold.TXID})
+	if err != nil {
+		return fmt.Errorf("failed to create hold key: %v", err)
+	}
+	holdJSON, err := json.Marshal(hold)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(holdKey, holdJSON); err != nil {
+		return fmt.Errorf("failed to put hold: %v", err)
+	}
+
+	asset.HELD += amount
+	return nil
+}
+
+// requireNoHolds fails when an asset has holds, which must be released before
+// its balance can move out of it whole, it can change dealer or be removed.
+func requireNoHolds(asset *Asset) error {
+	if asset.HELD > 0 {
+		return businessError(errCodeInvalidArgument, "the asset %s has holds of %.2f", asset.ID, asset.HELD)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestHoldsFollowThePolicyAndLimitSpending(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response := call(function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	readAsset := func(id string) *Asset {
+		t.Helper()
+		var asset Asset
+		if err := json.Unmarshal(invoke("ReadAsset", id), &asset); err != nil {
+			t.Fatal(err)
+		}
+		return &asset
+	}
+	readHolds := func(id string) []*Hold {
+		t.Helper()
+		var holds []*Hold
+		if err := json.Unmarshal(invoke("GetHolds", id), &holds); err != nil {
+			t.Fatal(err)
+		}
+		return holds
+	}
+
+	invoke("InitLedger")
+	invoke("SetHoldPolicy", `{"rules":{"DISPUTE":{"basis":"transamount","percent":100},"SUSPEND":{"basis":"balance","percent":100}}}`)
+	if response := call("SetHoldPolicy", `{"rules":{"DISPUTE":{"basis":"fee","percent":100}}}`); response.Status == shim.OK {
+		t.Error("expected a hold rule with an unknown basis to be rejected")
+	}
+
+	// the disputed amount is held, the rest of the balance stays spendable
+	invoke("UpdateAsset", "asset3", "DEALER103", "1500", "ACTIVE", "400", "DISPUTE")
+	asset := readAsset("asset3")
+	if asset.HELD != 400 || asset.SPENDABLE != 1100 {
+		t.Fatalf("expected 400 held and 1100 spendable, got %.2f and %.2f", asset.HELD, asset.SPENDABLE)
+	}
+	holds := readHolds("asset3")
+	if len(holds) != 1 || holds[0].AMOUNT != 400 || holds[0].TRANSTYPE != "DISPUTE" || holds[0].TXID == "" {
+		t.Fatalf("expected a DISPUTE hold of 400 with its transaction, got %+v", holds)
+	}
+	response := call("UpdateAsset", "asset3", "DEALER103", "200", "ACTIVE", "1300", "DEBIT")
+	if response.Status == shim.OK || !strings.Contains(response.Message, errCodeInsufficientFunds) {
+		t.Errorf("expected a debit into the held balance to fail with %s, got status %d: %s", errCodeInsufficientFunds, response.Status, response.Message)
+	}
+	if response := call("DeleteAsset", "asset3"); response.Status == shim.OK {
+		t.Error("expected an asset with holds not to be deleted")
+	}
+	if response := call("TransferAsset", "asset3", "DEALER101"); response.Status == shim.OK {
+		t.Error("expected an asset with holds not to be transferred")
+	}
+	if response := call("UpdateAsset", "asset3", "DEALER101", "1500", "ACTIVE", "0", "TRANSFER"); response.Status == shim.OK {
+		t.Error("expected an asset with holds not to change dealer through an update")
+	}
+	for _, consent := range []localRequest{
+		{Function: "ConsentToSwap", Args: []string{"asset3", "asset1", "0"}, Submit: true, DealerID: "DEALER103"},
+		{Function: "ConsentToSwap", Args: []string{"asset1", "asset3", "0"}, Submit: true, DealerID: "DEALER101"},
+	} {
+		if response, err := ledger.invoke(consent); err != nil || response.Status != shim.OK {
+			t.Fatalf("ConsentToSwap failed: %v %+v", err, response)
+		}
+	}
+	if response := call("SwapAssets", "asset3", "asset1"); response.Status == shim.OK {
+		t.Error("expected an asset with holds not to be swapped")
+	}
+
+	// a suspension holds whatever the dispute left spendable
+	invoke("UpdateAsset", "asset3", "DEALER103", "1500", "SUSPENDED", "0", "SUSPEND")
+	asset = readAsset("asset3")
+	if asset.HELD != 1500 || asset.SPENDABLE != 0 {
+		t.Fatalf("expected the whole balance held, got %.2f held and %.2f spendable", asset.HELD, asset.SPENDABLE)
+	}
+	holds = readHolds("asset3")
+	if len(holds) != 2 || holds[1].AMOUNT != 1100 || holds[1].TRANSTYPE != "SUSPEND" {
+		t.Fatalf("expected a SUSPEND hold of 1100 after the dispute, got %+v", holds)
+	}
+
+	invoke("ReleaseHold", "asset3", holds[1].TXID)
+	invoke("ReleaseHold", "asset3", holds[0].TXID)
+	asset = readAsset("asset3")
+	if asset.HELD != 0 || asset.SPENDABLE != 1500 {
+		t.Errorf("expected no holds after releasing them, got %.2f held and %.2f spendable", asset.HELD, asset.SPENDABLE)
+	}
+	if holds := readHolds("asset3"); len(holds) != 0 {
+		t.Errorf("expected no holds after releasing them, got %+v", holds)
+	}
+	invoke("UpdateAsset", "asset3", "DEALER103", "200", "ACTIVE", "1300", "DEBIT")
+}
@@ -0,0 +1,83 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestLiensLimitTheSpendableBalance(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response := call(function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	readAsset := func(id string) *Asset {
+		t.Helper()
+		var asset Asset
+		if err := json.Unmarshal(invoke("ReadAsset", id), &asset); err != nil {
+			t.Fatal(err)
+		}
+		return &asset
+	}
+
+	invoke("InitLedger")
+	if payload := invoke("ReadAsset", "asset3"); !strings.Contains(string(payload), `"spendable":1500`) {
+		t.Errorf("expected the spendable balance of an asset without liens, got %s", payload)
+	}
+
+	invoke("PlaceLien", "asset3", "1000", "BANK1", "loan1")
+	asset := readAsset("asset3")
+	if asset.BALANCE != 1500 || asset.LIENED != 1000 || asset.SPENDABLE != 500 {
+		t.Fatalf("expected 1000 of 1500 liened and 500 spendable, got %.2f of %.2f and %.2f", asset.LIENED, asset.BALANCE, asset.SPENDABLE)
+	}
+	if response := call("PlaceLien", "asset3", "100", "BANK1", "loan1"); response.Status == shim.OK {
+		t.Error("expected a second lien with the same reference to be rejected")
+	}
+	if response := call("PlaceLien", "asset3", "600", "BANK2", "loan2"); response.Status == shim.OK || !strings.Contains(response.Message, errCodeInsufficientFunds) {
+		t.Errorf("expected a lien beyond the spendable balance to fail with %s, got status %d: %s", errCodeInsufficientFunds, response.Status, response.Message)
+	}
+	if response := call("UpdateAsset", "asset3", "DEALER103", "900", "ACTIVE", "600", "DEBIT"); response.Status == shim.OK || !strings.Contains(response.Message, errCodeInsufficientFunds) {
+		t.Errorf("expected a debit into the liened balance to fail with %s, got status %d: %s", errCodeInsufficientFunds, response.Status, response.Message)
+	}
+	invoke("UpdateAsset", "asset3", "DEALER103", "1100", "ACTIVE", "400", "DEBIT")
+	if asset := readAsset("asset3"); asset.LIENED != 1000 || asset.SPENDABLE != 100 {
+		t.Errorf("expected a debit to leave the lien and shrink the spendable balance to 100, got %.2f liened and %.2f spendable", asset.LIENED, asset.SPENDABLE)
+	}
+	if response := call("TransferAsset", "asset3", "DEALER101"); response.Status == shim.OK {
+		t.Error("expected an asset with liens not to be transferred")
+	}
+
+	invoke("ReleaseLien", "asset3", "loan1")
+	if asset := readAsset("asset3"); asset.LIENED != 0 || asset.SPENDABLE != 1100 {
+		t.Errorf("expected the whole balance spendable after releasing the lien, got %.2f liened and %.2f spendable", asset.LIENED, asset.SPENDABLE)
+	}
+	if response := call("ReleaseLien", "asset3", "loan1"); response.Status == shim.OK {
+		t.Error("expected releasing a released lien to fail")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// patchableFields are the asset fields PatchAsset may change. Balances only
+// change through transactions moving funds, and identity fields never change.
+var patchableFields = map[string]bool{
+	"metadata": true,
+	"remarks":  true,
+	"status":   true,
+}
+
+// PatchAsset applies a JSON merge patch (RFC 7396) to the status, remarks and
+// metadata of an asset, leaving the fields the patch does not name unchanged.
+// In metadata, a null value removes the entry and a null metadata removes all
+// of them. Patching the remarks rewrites the private details, so it must be
+// endorsed by members of the asset details collection, and maps them onto the
+// remark codes like CreateAsset. The changed fields are set as the
+// AssetChanged event. Soft-deleted and closed assets cannot be patched.
+func (s *SmartContract) PatchAsset(ctx contractapi.TransactionContextInterface, id string, patchJSON string) (*Asset, error) {
+	var patch map[string]json.RawMessage
+	err := json.Unmarshal([]byte(patchJSON), &patch)
+	if err != nil {
+		return nil, businessError(errCodeInvalidArgument, "the patch must be a JSON object: %v", err)
+	}
+	if len(patch) == 0 {
+		return nil, businessError(errCodeInvalidArgument, "the patch changes no field")
+	}
+	for field := range patch {
+		if !patchableFields[field] {
+			return nil, businessError(errCodeInvalidArgument, "the field %q cannot be patched, only %s", field, strings.Join(sortedPatchableFields(), ", "))
+		}
+	}
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = requireOpen(asset)
+	if err != nil {
+		return nil, err
+	}
+	before := *asset
+
+	if value, ok := patch["status"]; ok {
+		var status string
+		if err := json.Unmarshal(value, &status); err != nil {
+			return nil, businessError(errCodeInvalidArgument, "status must be a string")
+		}
+		// assets are only DELETED through DeleteAssetsByDealer
+		if !validStatuses[status] || status == statusDeleted {
+			return nil, businessError(errCodeInvalidArgument, "status %q is not valid", status)
+		}
+		asset.STATUS = status
+	}
+
+	if value, ok := patch["metadata"]; ok {
+		asset.METADATA, err = mergeMetadata(asset.METADATA, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	value, ok := patch["remarks"]
+	if !ok {
+		if err := putAssetSummary(ctx, asset); err != nil {
+			return nil, err
+		}
+		return asset, setAssetChangedEvent(ctx, &before, asset)
+	}
+
+	var remarks *string
+	if err := json.Unmarshal(value, &remarks); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "remarks must be a string or null")
+	}
+	details, err := readAssetDetails(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	details.REMARKS = ""
+	if remarks != nil {
+		details.REMARKS, err = normalizeRemarks(*remarks)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = applyRemarkCode(ctx, asset, details.REMARKS)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := putAsset(ctx, asset, details); err != nil {
+		return nil, err
+	}
+	return asset, setAssetChangedEvent(ctx, &before, asset)
+}
+
+// mergeMetadata merges a JSON merge patch into the metadata of an asset.
+func mergeMetadata(metadata map[string]string, patchJSON json.RawMessage) (map[string]string, error) {
+	var patch map[string]*string
+	if err := json.Unmarshal(patchJSON, &patch); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "metadata must be an object of strings or null")
+	}
+	if patch == nil {
+		return nil, nil
+	}
+
+	merged := make(map[string]string, len(metadata)+len(patch))
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = *value
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+func sortedPatchableFields() []string {
+	fields := make([]string, 0, len(patchableFields))
+	for field := range patchableFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
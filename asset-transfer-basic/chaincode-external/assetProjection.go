@@ -0,0 +1,153 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// assetFieldNames are the JSON names of the fields of an Asset, the fields a
+// projection may select.
+var assetFieldNames = jsonFieldNames(reflect.TypeOf(Asset{}))
+
+// jsonFieldNames returns the JSON names of the fields of the struct type t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// parseFields parses a comma-separated list of Asset fields, such as
+// "ID,balance,status". ID is always selected, so that the assets of a list
+// stay identifiable, and an empty list selects every field.
+func parseFields(fields string) (map[string]bool, error) {
+	if strings.TrimSpace(fields) == "" {
+		return nil, nil
+	}
+	selected := map[string]bool{"ID": true}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if !assetFieldNames[field] {
+			known := make([]string, 0, len(assetFieldNames))
+			for name := range assetFieldNames {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, businessError(errCodeInvalidArgument, "unknown asset field %q, expected one of %s", field, strings.Join(known, ", "))
+		}
+		selected[field] = true
+	}
+	return selected, nil
+}
+
+// projectAsset returns the selected fields of the asset, or all of them when
+// selected is nil. Fields omitted from the asset JSON stay omitted.
+func projectAsset(asset *Asset, selected map[string]bool) (map[string]json.RawMessage, error) {
+	projection, err := assetFields(asset)
+	if err != nil {
+		return nil, err
+	}
+	if selected != nil {
+		for name := range projection {
+			if !selected[name] {
+				delete(projection, name)
+			}
+		}
+	}
+	return projection, nil
+}
+
+// projectAssets returns the selected fields of each asset.
+func projectAssets(assets []*Asset, selected map[string]bool) ([]map[string]json.RawMessage, error) {
+	projections := make([]map[string]json.RawMessage, 0, len(assets))
+	for _, asset := range assets {
+		projection, err := projectAsset(asset, selected)
+		if err != nil {
+			return nil, err
+		}
+		projections = append(projections, projection)
+	}
+	return projections, nil
+}
+
+// marshalProjection returns the JSON of a projection. Object keys are sorted,
+// so that every peer endorses the same result.
+func marshalProjection(projection interface{}) (string, error) {
+	projectionJSON, err := json.Marshal(projection)
+	if err != nil {
+		return "", err
+	}
+	return string(projectionJSON), nil
+}
+
+// ReadAssetFields returns the fields of the asset with given id listed in
+// fields, comma-separated JSON names such as "ID,balance,status", as a JSON
+// object. List views use it to fetch only what they display.
+func (s *SmartContract) ReadAssetFields(ctx contractapi.TransactionContextInterface, id string, fields string) (string, error) {
+	selected, err := parseFields(fields)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	projection, err := projectAsset(asset, selected)
+	if err != nil {
+		return "", err
+	}
+	return marshalProjection(projection)
+}
+
+// GetAllAssetsFields returns the fields listed in fields of all assets found in
+// world state, as for ReadAssetFields, as a JSON array.
+func (s *SmartContract) GetAllAssetsFields(ctx contractapi.TransactionContextInterface, fields string) (string, error) {
+	selected, err := parseFields(fields)
+	if err != nil {
+		return "", err
+	}
+	assets, err := s.GetAllAssets(ctx)
+	if err != nil {
+		return "", err
+	}
+	projections, err := projectAssets(assets, selected)
+	if err != nil {
+		return "", err
+	}
+	return marshalProjection(projections)
+}
+
+// GetAssetsFilteredFields returns a page of the assets matching filterJSON, as
+// for GetAssetsFiltered, with only the fields listed in fields, as a JSON
+// object with the assets and the bookmark. fields comes first so that the
+// paging arguments stay last, as for every paginated function.
+func (s *SmartContract) GetAssetsFilteredFields(ctx contractapi.TransactionContextInterface, fields string, filterJSON string, sortSpec string, pageSize int32, bookmark string) (string, error) {
+	selected, err := parseFields(fields)
+	if err != nil {
+		return "", err
+	}
+	page, err := s.GetAssetsFiltered(ctx, filterJSON, sortSpec, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	projections, err := projectAssets(page.ASSETS, selected)
+	if err != nil {
+		return "", err
+	}
+	return marshalProjection(map[string]interface{}{
+		"assets":   projections,
+		"bookmark": page.BOOKMARK,
+	})
+}
@@ -0,0 +1,226 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// proofObjectType is the composite key prefix of exported asset proofs.
+const proofObjectType = "proof"
+
+// transientDetailsRecordKey is the transient map entry carrying the private
+// AssetDetails record of an imported asset.
+const transientDetailsRecordKey = "asset_details_record"
+
+// AssetProof describes the state of an asset at the time it was exported from a channel.
+// The proof itself is not signed: DIGEST is a plain SHA-256 of its content that
+// only detects changes. It is trusted because ImportAssetFromProof compares it
+// with the proof the export transaction recorded on the source channel.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetProof struct {
+	ASSET      Asset  `json:"asset"`
+	CHANNELID  string `json:"channelid"`
+	DIGEST     string `json:"digest"`
+	EXPORTEDAT string `json:"exportedat"`
+	EXPORTEDBY string `json:"exportedby"`
+	TXID       string `json:"txid"`
+}
+
+// ExportAssetProof records a proof of the current state of the asset on this
+// channel and returns it, ready to be imported on another channel. The asset
+// moves out of this channel: it is closed with a zero balance, so it cannot be
+// spent here as well. Assets with liens or holds cannot be exported. Only
+// admins may call it.
+func (s *SmartContract) ExportAssetProof(ctx contractapi.TransactionContextInterface, id string) (*AssetProof, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = requireOpen(asset)
+	if err != nil {
+		return nil, err
+	}
+	err = requireNoLiens(asset)
+	if err != nil {
+		return nil, err
+	}
+	err = requireNoHolds(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	exportedBy, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	proof := AssetProof{
+		ASSET:      *asset,
+		CHANNELID:  ctx.GetStub().GetChannelID(),
+		EXPORTEDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		EXPORTEDBY: exportedBy,
+		TXID:       ctx.GetStub().GetTxID(),
+	}
+	proof.DIGEST, err = proofDigest(&proof)
+	if err != nil {
+		return nil, err
+	}
+
+	proofKey, err := ctx.GetStub().CreateCompositeKey(proofObjectType, []string{id, proof.TXID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(proofKey, proofJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	asset.TRANSAMOUNT = asset.BALANCE
+	asset.TRANSTYPE = "EXPORT"
+	asset.BALANCE = 0
+	asset.STATUS = statusClosed
+	err = putAssetSummary(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proof, nil
+}
+
+// GetAssetProof returns the proof recorded on this channel by the export transaction txID.
+func (s *SmartContract) GetAssetProof(ctx contractapi.TransactionContextInterface, id string, txID string) (*AssetProof, error) {
+	proofKey, err := ctx.GetStub().CreateCompositeKey(proofObjectType, []string{id, txID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	proofJSON, err := ctx.GetStub().GetState(proofKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if proofJSON == nil {
+		return nil, fmt.Errorf("no proof of asset %s was exported by transaction %s", id, txID)
+	}
+
+	var proof AssetProof
+	err = json.Unmarshal(proofJSON, &proof)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proof, nil
+}
+
+// ImportAssetFromProof creates the asset described by an exported proof on this channel.
+// The proof is validated by querying chaincodeName on the source channel, which
+// requires the endorsing peers to be joined to both channels. The private
+// AssetDetails record of the asset is read from the "asset_details_record"
+// transient field and must match the details hash carried by the proof. The
+// lien and hold records stay on the source channel, so the liened and held
+// amounts of a proof are not imported.
+func (s *SmartContract) ImportAssetFromProof(ctx contractapi.TransactionContextInterface, chaincodeName string, proofJSON string) error {
+	var proof AssetProof
+	err := json.Unmarshal([]byte(proofJSON), &proof)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal proof: %v", err)
+	}
+
+	digest, err := proofDigest(&proof)
+	if err != nil {
+		return err
+	}
+	if digest != proof.DIGEST {
+		return fmt.Errorf("the digest of the proof of asset %s does not match its content", proof.ASSET.ID)
+	}
+	if proof.CHANNELID == ctx.GetStub().GetChannelID() {
+		return fmt.Errorf("the proof of asset %s was exported from this channel", proof.ASSET.ID)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(chaincodeName, [][]byte{[]byte("GetAssetProof"), []byte(proof.ASSET.ID), []byte(proof.TXID)}, proof.CHANNELID)
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to query proof on channel %s: %s", proof.CHANNELID, response.Message)
+	}
+	var recorded AssetProof
+	err = json.Unmarshal(response.Payload, &recorded)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal recorded proof: %v", err)
+	}
+	if recorded.DIGEST != proof.DIGEST {
+		return fmt.Errorf("the proof of asset %s does not match the proof recorded on channel %s", proof.ASSET.ID, proof.CHANNELID)
+	}
+
+	exists, err := s.AssetExists(ctx, proof.ASSET.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return businessError(errCodeAssetExists, "the asset %s already exists", proof.ASSET.ID)
+	}
+	err = requireNotArchived(ctx, proof.ASSET.ID)
+	if err != nil {
+		return err
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+	detailsJSON, ok := transientMap[transientDetailsRecordKey]
+	if !ok {
+		return fmt.Errorf("%s must be a key in the transient map", transientDetailsRecordKey)
+	}
+	var details AssetDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %v", transientDetailsRecordKey, err)
+	}
+	if details.ID != proof.ASSET.ID {
+		return fmt.Errorf("the details record belongs to asset %s, not %s", details.ID, proof.ASSET.ID)
+	}
+
+	asset := proof.ASSET
+	asset.LIENED = 0
+	asset.HELD = 0
+	err = putAsset(ctx, &asset, &details)
+	if err != nil {
+		return err
+	}
+	if asset.DETAILSHASH != proof.ASSET.DETAILSHASH {
+		return fmt.Errorf("the details record of asset %s does not match the proof", proof.ASSET.ID)
+	}
+
+	return nil
+}
+
+// proofDigest returns the hex encoded SHA-256 of the proof content, excluding the digest itself.
+func proofDigest(proof *AssetProof) (string, error) {
+	content := *proof
+	content.DIGEST = ""
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(contentJSON)
+	return hex.EncodeToString(digest[:]), nil
+}
@@ -0,0 +1,95 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestExportAssetProofMovesTheAssetOut(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response := call(function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+
+	invoke("InitLedger")
+	response, err := ledger.invoke(localRequest{Function: "ExportAssetProof", Args: []string{"asset3"}, Submit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status == shim.OK || !strings.Contains(response.Message, "not an admin") {
+		t.Errorf("expected a client not to export assets, got status %d: %s", response.Status, response.Message)
+	}
+	invoke("PlaceLien", "asset3", "100", "BANK1", "loan1")
+	if response := call("ExportAssetProof", "asset3"); response.Status == shim.OK || !strings.Contains(response.Message, "liens") {
+		t.Errorf("expected an asset with liens not to be exported, got status %d: %s", response.Status, response.Message)
+	}
+	invoke("ReleaseLien", "asset3", "loan1")
+
+	var proof AssetProof
+	if err := json.Unmarshal(invoke("ExportAssetProof", "asset3"), &proof); err != nil {
+		t.Fatal(err)
+	}
+	if proof.ASSET.BALANCE != 1500 || proof.ASSET.STATUS != "ACTIVE" || proof.CHANNELID != localChannel {
+		t.Errorf("expected the proof to carry the asset as exported, got %+v", proof)
+	}
+	var recorded AssetProof
+	if err := json.Unmarshal(invoke("GetAssetProof", "asset3", proof.TXID), &recorded); err != nil {
+		t.Fatal(err)
+	}
+	if recorded.DIGEST != proof.DIGEST {
+		t.Errorf("expected the recorded proof to match the exported one, got digest %s and %s", recorded.DIGEST, proof.DIGEST)
+	}
+
+	var source Asset
+	if err := json.Unmarshal(invoke("ReadAsset", "asset3"), &source); err != nil {
+		t.Fatal(err)
+	}
+	if source.STATUS != statusClosed || source.BALANCE != 0 || source.TRANSAMOUNT != 1500 || source.TRANSTYPE != "EXPORT" {
+		t.Errorf("expected the exported asset to be closed with a zero balance, got %+v", source)
+	}
+	if response := call("ExportAssetProof", "asset3"); response.Status == shim.OK {
+		t.Error("expected an exported asset not to be exported again")
+	}
+	if response := call("UpdateAsset", "asset3", "DEALER103", "0", "ACTIVE", "0", "REVIEW"); response.Status == shim.OK {
+		t.Error("expected an exported asset not to be updated")
+	}
+
+	if response := call("ImportAssetFromProof", "basic", string(invoke("GetAssetProof", "asset3", proof.TXID))); response.Status == shim.OK || !strings.Contains(response.Message, "exported from this channel") {
+		t.Errorf("expected a proof not to be imported on its own channel, got status %d: %s", response.Status, response.Message)
+	}
+	proof.ASSET.BALANCE = 15000
+	tampered, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response := call("ImportAssetFromProof", "basic", string(tampered)); response.Status == shim.OK || !strings.Contains(response.Message, "digest") {
+		t.Errorf("expected a changed proof to be rejected, got status %d: %s", response.Status, response.Message)
+	}
+}
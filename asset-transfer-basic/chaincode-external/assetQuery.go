@@ -0,0 +1,185 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// sortIndexes maps the fields assets can be sorted by to the CouchDB index
+// backing the sort, shipped in META-INF/statedb/couchdb/indexes.
+var sortIndexes = map[string]string{
+	"balance":   "indexBalanceDoc",
+	"updatedat": "indexUpdatedAtDoc",
+}
+
+// AssetPage is one page of the result of a rich query over the assets. BOOKMARK
+// fetches the next page and is empty after the last one.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetPage struct {
+	ASSETS   []*Asset `json:"assets"`
+	BOOKMARK string   `json:"bookmark"`
+}
+
+// GetAssetsSorted returns a page of the assets sorted by sortSpec, such as
+// "balance:desc" or "updatedat". An empty sortSpec returns the assets in the
+// order of the query, without using a sort index.
+func (s *SmartContract) GetAssetsSorted(ctx contractapi.TransactionContextInterface, sortSpec string, pageSize int32, bookmark string) (*AssetPage, error) {
+	selector := map[string]interface{}{
+		"ID":          map[string]interface{}{"$gt": nil},
+		"detailshash": map[string]interface{}{"$exists": true},
+	}
+	return queryAssetPage(ctx, selector, sortSpec, pageSize, bookmark)
+}
+
+// AssetFilter selects the assets returned by GetAssetsFiltered. Empty fields do
+// not constrain the result.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetFilter struct {
+	DEALERID     string   `json:"dealerid,omitempty" metadata:",optional"`
+	MAXBALANCE   *float64 `json:"maxbalance,omitempty" metadata:",optional"`
+	MINBALANCE   *float64 `json:"minbalance,omitempty" metadata:",optional"`
+	STATUS       string   `json:"status,omitempty" metadata:",optional"`
+	UPDATEDSINCE string   `json:"updatedsince,omitempty" metadata:",optional"`
+}
+
+// GetAssetsFiltered returns a page of the assets matching the AssetFilter in
+// filterJSON, sorted by sortSpec as for GetAssetsSorted. The filter is run by
+// CouchDB, so that applications need not fetch every asset to filter them.
+func (s *SmartContract) GetAssetsFiltered(ctx contractapi.TransactionContextInterface, filterJSON string, sortSpec string, pageSize int32, bookmark string) (*AssetPage, error) {
+	var filter AssetFilter
+	if filterJSON != "" {
+		decoder := json.NewDecoder(strings.NewReader(filterJSON))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&filter); err != nil {
+			return nil, businessError(errCodeInvalidArgument, "invalid asset filter: %v", err)
+		}
+	}
+
+	selector, err := filter.selector()
+	if err != nil {
+		return nil, err
+	}
+	return queryAssetPage(ctx, selector, sortSpec, pageSize, bookmark)
+}
+
+// selector returns the CouchDB selector of the filter.
+func (f AssetFilter) selector() (map[string]interface{}, error) {
+	selector := map[string]interface{}{
+		"ID":          map[string]interface{}{"$gt": nil},
+		"detailshash": map[string]interface{}{"$exists": true},
+	}
+	if f.DEALERID != "" {
+		selector["dealerid"] = f.DEALERID
+	}
+	if f.STATUS != "" {
+		if !validStatuses[f.STATUS] {
+			return nil, businessError(errCodeInvalidArgument, "invalid status %q", f.STATUS)
+		}
+		selector["status"] = f.STATUS
+	}
+
+	balance := map[string]interface{}{}
+	if f.MINBALANCE != nil {
+		balance["$gte"] = *f.MINBALANCE
+	}
+	if f.MAXBALANCE != nil {
+		balance["$lte"] = *f.MAXBALANCE
+	}
+	if f.MINBALANCE != nil && f.MAXBALANCE != nil && *f.MINBALANCE > *f.MAXBALANCE {
+		return nil, businessError(errCodeInvalidArgument, "the minimum balance %.2f exceeds the maximum %.2f", *f.MINBALANCE, *f.MAXBALANCE)
+	}
+	if len(balance) > 0 {
+		selector["balance"] = balance
+	}
+
+	if f.UPDATEDSINCE != "" {
+		since, err := time.Parse(time.RFC3339, f.UPDATEDSINCE)
+		if err != nil {
+			return nil, businessError(errCodeInvalidArgument, "updatedsince must be an RFC 3339 time: %v", err)
+		}
+		// updatedat is stored in UTC, so it compares as a string
+		selector["updatedat"] = map[string]interface{}{"$gte": since.UTC().Format(time.RFC3339)}
+	}
+	return selector, nil
+}
+
+// queryAssetPage runs a paginated rich query for the assets matching selector,
+// sorted by sortSpec.
+func queryAssetPage(ctx contractapi.TransactionContextInterface, selector map[string]interface{}, sortSpec string, pageSize int32, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, businessError(errCodeInvalidArgument, "the page size must be positive")
+	}
+
+	query := map[string]interface{}{"selector": selector}
+	if sortSpec != "" {
+		sort, index, err := parseSort(sortSpec)
+		if err != nil {
+			return nil, err
+		}
+		for name := range sort[0] {
+			// CouchDB only sorts by fields the selector constrains
+			if _, ok := selector[name]; !ok {
+				selector[name] = map[string]interface{}{"$gt": nil}
+			}
+		}
+		query["sort"] = sort
+		query["use_index"] = index
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryJSON), pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := &AssetPage{ASSETS: []*Asset{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		page.ASSETS = append(page.ASSETS, &asset)
+	}
+	if len(page.ASSETS) == int(pageSize) {
+		page.BOOKMARK = metadata.GetBookmark()
+	}
+
+	return page, nil
+}
+
+// parseSort converts a sort specification of the form field[:asc|desc] into
+// CouchDB sort syntax, and returns the design document of the index backing it.
+func parseSort(sortSpec string) ([]map[string]string, string, error) {
+	name, direction, _ := strings.Cut(strings.TrimSpace(sortSpec), ":")
+	name, direction = strings.ToLower(name), strings.ToLower(direction)
+	if direction == "" {
+		direction = "asc"
+	}
+
+	index, ok := sortIndexes[name]
+	if !ok {
+		return nil, "", businessError(errCodeInvalidArgument, "cannot sort by %q, expected balance or updatedat", name)
+	}
+	if direction != "asc" && direction != "desc" {
+		return nil, "", businessError(errCodeInvalidArgument, "invalid sort direction %q, expected asc or desc", direction)
+	}
+	return []map[string]string{{name: direction}}, index, nil
+}
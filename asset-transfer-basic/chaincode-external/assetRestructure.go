@@ -0,0 +1,198 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// statusClosed marks an asset whose balance was merged into another asset, or
+// that was exported to another channel by ExportAssetProof.
+const statusClosed = "CLOSED"
+
+// restructureEvent is the event listing the balance movements of a merge or split.
+const restructureEvent = "AssetsRestructured"
+
+// Allocation is the amount SplitAsset moves to an asset.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type Allocation struct {
+	AMOUNT  float64 `json:"amount"`
+	ASSETID string  `json:"assetid"`
+}
+
+// Movement is a balance moved from one asset to another.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type Movement struct {
+	AMOUNT float64 `json:"amount"`
+	FROM   string  `json:"from"`
+	TO     string  `json:"to"`
+}
+
+// RestructureResult lists the movements of a merge or split, in the order they
+// were made. It is also the payload of the AssetsRestructured event, as a
+// transaction can only set one event.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type RestructureResult struct {
+	KIND      string      `json:"kind"`
+	MOVEMENTS []*Movement `json:"movements"`
+	TXID      string      `json:"txid"`
+}
+
+// MergeAssets moves the balances of the source assets into the target asset and
+// closes the sources. All assets must belong to the same dealer, and the
+// sources may not have liens or holds.
+func (s *SmartContract) MergeAssets(ctx contractapi.TransactionContextInterface, targetID string, sourceIDs []string) (*RestructureResult, error) {
+	if len(sourceIDs) == 0 {
+		return nil, businessError(errCodeInvalidArgument, "no source assets to merge")
+	}
+	target, err := s.readRestructuredAsset(ctx, targetID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestructureResult{KIND: "merge", MOVEMENTS: []*Movement{}, TXID: ctx.GetStub().GetTxID()}
+	seen := map[string]bool{targetID: true}
+	for _, sourceID := range sourceIDs {
+		if seen[sourceID] {
+			return nil, businessError(errCodeInvalidArgument, "the asset %s is merged more than once", sourceID)
+		}
+		seen[sourceID] = true
+
+		source, err := s.readRestructuredAsset(ctx, sourceID, target.DEALERID)
+		if err != nil {
+			return nil, err
+		}
+		err = requireNoLiens(source)
+		if err != nil {
+			return nil, err
+		}
+		err = requireNoHolds(source)
+		if err != nil {
+			return nil, err
+		}
+
+		result.MOVEMENTS = append(result.MOVEMENTS, &Movement{AMOUNT: source.BALANCE, FROM: sourceID, TO: targetID})
+		target.BALANCE += source.BALANCE
+		source.TRANSAMOUNT = source.BALANCE
+		source.TRANSTYPE = "MERGE"
+		source.BALANCE = 0
+		source.STATUS = statusClosed
+		err = putRestructuredAsset(ctx, source, "MergeAssets")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	target.TRANSAMOUNT = 0
+	for _, movement := range result.MOVEMENTS {
+		target.TRANSAMOUNT += movement.AMOUNT
+	}
+	target.TRANSTYPE = "MERGE"
+	err = putRestructuredAsset(ctx, target, "MergeAssets")
+	if err != nil {
+		return nil, err
+	}
+
+	return result, setRestructureEvent(ctx, result)
+}
+
+// SplitAsset moves parts of the balance of the source asset to other assets of
+// the same dealer, failing when the allocations exceed the spendable balance.
+func (s *SmartContract) SplitAsset(ctx contractapi.TransactionContextInterface, sourceID string, allocations []Allocation) (*RestructureResult, error) {
+	if len(allocations) == 0 {
+		return nil, businessError(errCodeInvalidArgument, "no allocations to split into")
+	}
+	source, err := s.readRestructuredAsset(ctx, sourceID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0.0
+	seen := map[string]bool{sourceID: true}
+	for _, allocation := range allocations {
+		if seen[allocation.ASSETID] {
+			return nil, businessError(errCodeInvalidArgument, "the asset %s is allocated more than once", allocation.ASSETID)
+		}
+		seen[allocation.ASSETID] = true
+		if allocation.AMOUNT <= 0 || math.IsInf(allocation.AMOUNT, 0) || math.IsNaN(allocation.AMOUNT) {
+			return nil, businessError(errCodeInvalidArgument, "the amount allocated to %s must be a positive number", allocation.ASSETID)
+		}
+		total += allocation.AMOUNT
+	}
+	err = requireSpendable(source, total)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestructureResult{KIND: "split", MOVEMENTS: []*Movement{}, TXID: ctx.GetStub().GetTxID()}
+	for _, allocation := range allocations {
+		target, err := s.readRestructuredAsset(ctx, allocation.ASSETID, source.DEALERID)
+		if err != nil {
+			return nil, err
+		}
+
+		result.MOVEMENTS = append(result.MOVEMENTS, &Movement{AMOUNT: allocation.AMOUNT, FROM: sourceID, TO: allocation.ASSETID})
+		target.BALANCE += allocation.AMOUNT
+		target.TRANSAMOUNT = allocation.AMOUNT
+		target.TRANSTYPE = "SPLIT"
+		err = putRestructuredAsset(ctx, target, "SplitAsset")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	source.BALANCE -= total
+	source.TRANSAMOUNT = total
+	source.TRANSTYPE = "SPLIT"
+	err = putRestructuredAsset(ctx, source, "SplitAsset")
+	if err != nil {
+		return nil, err
+	}
+
+	return result, setRestructureEvent(ctx, result)
+}
+
+// readRestructuredAsset reads an asset taking part in a merge or split, which must
+// be open and, unless dealerID is empty, belong to dealerID.
+func (s *SmartContract) readRestructuredAsset(ctx contractapi.TransactionContextInterface, id string, dealerID string) (*Asset, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = requireOpen(asset)
+	if err != nil {
+		return nil, err
+	}
+	if dealerID != "" && asset.DEALERID != dealerID {
+		return nil, businessError(errCodeInvalidArgument, "the asset %s belongs to dealer %s, not %s", id, asset.DEALERID, dealerID)
+	}
+	return asset, nil
+}
+
+// putRestructuredAsset writes an asset changed by a merge or split and records
+// the change in its audit trail.
+func putRestructuredAsset(ctx contractapi.TransactionContextInterface, asset *Asset, action string) error {
+	err := putAssetSummary(ctx, asset)
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, asset.ID, action)
+}
+
+func setRestructureEvent(ctx contractapi.TransactionContextInterface, result *RestructureResult) error {
+	eventJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(restructureEvent, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestSplitAndMergeAssets(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke := func(function string, args []string, expectedError string) []byte {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectedError == "" && response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		if expectedError != "" && (response.Status == shim.OK || !strings.Contains(response.Message, expectedError)) {
+			t.Fatalf("expected %s to fail with %q, got status %d: %s", function, expectedError, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	split := func(allocations string, expectedError string) []byte {
+		t.Helper()
+		return invoke("SplitAsset", []string{"asset3", allocations}, expectedError)
+	}
+	merge := func(sourceIDs string, expectedError string) []byte {
+		t.Helper()
+		return invoke("MergeAssets", []string{"asset3", sourceIDs}, expectedError)
+	}
+	expectBalances := func(balances map[string]float64) {
+		t.Helper()
+		for id, balance := range balances {
+			var asset Asset
+			if err := json.Unmarshal(invoke("ReadAsset", []string{id}, ""), &asset); err != nil {
+				t.Fatal(err)
+			}
+			if asset.BALANCE != balance {
+				t.Errorf("expected asset %s to have a balance of %.2f, got %.2f", id, balance, asset.BALANCE)
+			}
+		}
+	}
+
+	invoke("InitLedger", nil, "")
+	invoke("TransferAsset", []string{"asset2", "DEALER103"}, "")
+	invoke("TransferAsset", []string{"asset4", "DEALER103"}, "")
+
+	split(`[{"assetid":"asset2","amount":1000},{"assetid":"asset4","amount":600}]`, errCodeInsufficientFunds)
+	split(`[{"assetid":"asset1","amount":100}]`, "belongs to dealer DEALER101")
+	split(`[{"assetid":"asset2","amount":100},{"assetid":"asset2","amount":100}]`, "allocated more than once")
+	split(`[{"assetid":"asset2","amount":0}]`, "must be a positive number")
+	var result RestructureResult
+	if err := json.Unmarshal(split(`[{"assetid":"asset2","amount":200},{"assetid":"asset4","amount":300}]`, ""), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.KIND != "split" || len(result.MOVEMENTS) != 2 {
+		t.Errorf("expected two split movements, got %+v", result)
+	}
+	expectBalances(map[string]float64{"asset3": 1000, "asset2": 700, "asset4": 25300})
+
+	invoke("PlaceLien", []string{"asset4", "100", "BANK1", "loan1"}, "")
+	merge(`["asset2","asset4"]`, "liens")
+	invoke("ReleaseLien", []string{"asset4", "loan1"}, "")
+	merge(`["asset1"]`, "belongs to dealer DEALER101")
+	merge(`["asset2","asset2"]`, "merged more than once")
+	if err := json.Unmarshal(merge(`["asset2","asset4"]`, ""), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.KIND != "merge" || len(result.MOVEMENTS) != 2 {
+		t.Errorf("expected two merge movements, got %+v", result)
+	}
+	expectBalances(map[string]float64{"asset3": 27000, "asset2": 0, "asset4": 0})
+	for _, id := range []string{"asset2", "asset4"} {
+		var asset Asset
+		if err := json.Unmarshal(invoke("ReadAsset", []string{id}, ""), &asset); err != nil {
+			t.Fatal(err)
+		}
+		if asset.STATUS != statusClosed {
+			t.Errorf("expected the merged asset %s to be closed, got %s", id, asset.STATUS)
+		}
+	}
+	merge(`["asset2"]`, "is "+statusClosed)
+	split(`[{"assetid":"asset4","amount":100}]`, "is "+statusClosed)
+}
@@ -0,0 +1,308 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Bounds of the rich queries of QueryAssets, so that an operator's query
+// cannot hold a peer busy for long.
+const (
+	maxQueryLength = 16 * 1024
+	maxQueryDepth  = 8
+	maxQueryValues = 100
+	maxQueryAssets = 1000
+)
+
+// metadataFieldPrefix prefixes the metadata entries named in a query, such as
+// metadata.remarkcode.
+const metadataFieldPrefix = "metadata."
+
+// queryLogicalOperators combine selectors, queryConditionOperators constrain a
+// field. Other CouchDB operators, such as $regex or $where, are refused as they
+// scan every document or run code.
+var (
+	queryLogicalOperators   = map[string]bool{"$and": true, "$or": true, "$nor": true, "$not": true}
+	queryConditionOperators = map[string]bool{
+		"$eq": true, "$ne": true, "$gt": true, "$gte": true, "$lt": true, "$lte": true,
+		"$in": true, "$nin": true, "$exists": true, "$not": true,
+	}
+)
+
+// QueryAssets returns the assets matching queryString, a CouchDB query such as
+// {"selector":{"status":"ACTIVE","balance":{"$gt":10000}}} with optionally
+// sort and use_index, for operators running ad hoc queries against a CouchDB
+// state database. The query is checked before it runs, see validateAssetQuery,
+// and only ever matches assets. At most 1000 assets are returned, a query
+// matching more failing. Only admins may call it.
+func (s *SmartContract) QueryAssets(ctx contractapi.TransactionContextInterface, queryString string) ([]*Asset, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	query, err := validateAssetQuery(queryString)
+	if err != nil {
+		return nil, err
+	}
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	assets := []*Asset{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if len(assets) == maxQueryAssets {
+			return nil, businessError(errCodeInvalidArgument, "the query matches more than %d assets, narrow its selector", maxQueryAssets)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, &asset)
+	}
+
+	return assets, nil
+}
+
+// validateAssetQuery parses a query of QueryAssets and returns the query to
+// run. The query may only have a selector, a sort and a use_index. The
+// selector may only name the fields of Asset, or metadata.key for an entry of
+// its metadata, and use the logical and condition operators, nested at most 8
+// deep with at most 100 values per $in or $nin. The selector is combined with
+// the one matching every asset, so that no other document of the world state
+// is returned.
+func validateAssetQuery(queryString string) (map[string]interface{}, error) {
+	if len(queryString) > maxQueryLength {
+		return nil, businessError(errCodeInvalidArgument, "the query is %d bytes long, at most %d are allowed", len(queryString), maxQueryLength)
+	}
+	decoder := json.NewDecoder(strings.NewReader(queryString))
+	decoder.UseNumber()
+	var query map[string]interface{}
+	if err := decoder.Decode(&query); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "the query must be a JSON object: %v", err)
+	}
+	for key := range query {
+		if key != "selector" && key != "sort" && key != "use_index" {
+			return nil, businessError(errCodeInvalidArgument, "the query may not have %q, only selector, sort and use_index", key)
+		}
+	}
+
+	selector, ok := query["selector"].(map[string]interface{})
+	if !ok {
+		return nil, businessError(errCodeInvalidArgument, "the query must have a selector object")
+	}
+	if err := validateSelector(selector, 1); err != nil {
+		return nil, err
+	}
+
+	assetSelector := map[string]interface{}{
+		"ID":          map[string]interface{}{"$gt": nil},
+		"detailshash": map[string]interface{}{"$exists": true},
+	}
+	if sort, ok := query["sort"]; ok {
+		fields, err := validateQuerySort(sort)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			// CouchDB only sorts by fields the selector constrains
+			if _, ok := assetSelector[field]; !ok {
+				assetSelector[field] = map[string]interface{}{"$gt": nil}
+			}
+		}
+	}
+	if index, ok := query["use_index"]; ok {
+		if err := validateQueryIndex(index); err != nil {
+			return nil, err
+		}
+	}
+
+	query["selector"] = map[string]interface{}{"$and": []interface{}{assetSelector, selector}}
+	return query, nil
+}
+
+// validateSelector checks a selector object at the given nesting depth.
+func validateSelector(selector map[string]interface{}, depth int) error {
+	if depth > maxQueryDepth {
+		return businessError(errCodeInvalidArgument, "the selector is nested more than %d deep", maxQueryDepth)
+	}
+	for key, value := range selector {
+		if !strings.HasPrefix(key, "$") {
+			if err := validateQueryField(key); err != nil {
+				return err
+			}
+			if condition, ok := value.(map[string]interface{}); ok {
+				if err := validateCondition(key, condition, depth+1); err != nil {
+					return err
+				}
+			} else if !isQueryScalar(value) {
+				return businessError(errCodeInvalidArgument, "the value of %s must be a string, number, boolean, null or condition", key)
+			}
+			continue
+		}
+
+		if !queryLogicalOperators[key] {
+			return businessError(errCodeInvalidArgument, "the operator %s is not allowed in a selector", key)
+		}
+		if key == "$not" {
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				return businessError(errCodeInvalidArgument, "$not must hold a selector object")
+			}
+			if err := validateSelector(nested, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		selectors, ok := value.([]interface{})
+		if !ok || len(selectors) == 0 {
+			return businessError(errCodeInvalidArgument, "%s must hold a non-empty array of selectors", key)
+		}
+		for _, item := range selectors {
+			nested, ok := item.(map[string]interface{})
+			if !ok {
+				return businessError(errCodeInvalidArgument, "%s must hold selector objects", key)
+			}
+			if err := validateSelector(nested, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateCondition checks the condition object constraining field.
+func validateCondition(field string, condition map[string]interface{}, depth int) error {
+	if depth > maxQueryDepth {
+		return businessError(errCodeInvalidArgument, "the selector is nested more than %d deep", maxQueryDepth)
+	}
+	if len(condition) == 0 {
+		return businessError(errCodeInvalidArgument, "the condition of %s is empty", field)
+	}
+	for operator, operand := range condition {
+		if !queryConditionOperators[operator] {
+			return businessError(errCodeInvalidArgument, "the operator %q is not allowed on %s", operator, field)
+		}
+		switch operator {
+		case "$not":
+			nested, ok := operand.(map[string]interface{})
+			if !ok {
+				return businessError(errCodeInvalidArgument, "$not on %s must hold a condition object", field)
+			}
+			if err := validateCondition(field, nested, depth+1); err != nil {
+				return err
+			}
+		case "$in", "$nin":
+			values, ok := operand.([]interface{})
+			if !ok || len(values) > maxQueryValues {
+				return businessError(errCodeInvalidArgument, "%s on %s must hold an array of at most %d values", operator, field, maxQueryValues)
+			}
+			for _, value := range values {
+				if !isQueryScalar(value) {
+					return businessError(errCodeInvalidArgument, "%s on %s must hold strings, numbers, booleans or null", operator, field)
+				}
+			}
+		case "$exists":
+			if _, ok := operand.(bool); !ok {
+				return businessError(errCodeInvalidArgument, "$exists on %s must be true or false", field)
+			}
+		default:
+			if !isQueryScalar(operand) {
+				return businessError(errCodeInvalidArgument, "%s on %s must hold a string, number, boolean or null", operator, field)
+			}
+		}
+	}
+	return nil
+}
+
+// validateQuerySort checks the sort of a query, an array of field names or of
+// objects mapping a field to asc or desc, and returns its fields.
+func validateQuerySort(sort interface{}) ([]string, error) {
+	items, ok := sort.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, businessError(errCodeInvalidArgument, "the sort must be a non-empty array")
+	}
+	fields := make([]string, 0, len(items))
+	for _, item := range items {
+		switch item := item.(type) {
+		case string:
+			fields = append(fields, item)
+		case map[string]interface{}:
+			if len(item) != 1 {
+				return nil, businessError(errCodeInvalidArgument, "each sort object must map one field to asc or desc")
+			}
+			for field, direction := range item {
+				if direction != "asc" && direction != "desc" {
+					return nil, businessError(errCodeInvalidArgument, "invalid sort direction %v of %s, expected asc or desc", direction, field)
+				}
+				fields = append(fields, field)
+			}
+		default:
+			return nil, businessError(errCodeInvalidArgument, "the sort must hold field names or objects")
+		}
+	}
+	for _, field := range fields {
+		if err := validateQueryField(field); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// validateQueryIndex checks the use_index of a query, the name of a design
+// document or an array of a design document and an index name.
+func validateQueryIndex(index interface{}) error {
+	switch index := index.(type) {
+	case string:
+		return nil
+	case []interface{}:
+		if len(index) >= 1 && len(index) <= 2 {
+			for _, part := range index {
+				if _, ok := part.(string); !ok {
+					return businessError(errCodeInvalidArgument, "use_index must hold strings")
+				}
+			}
+			return nil
+		}
+	}
+	return businessError(errCodeInvalidArgument, "use_index must be a design document, or a design document and an index name")
+}
+
+// validateQueryField checks that field names a field of Asset, or an entry of
+// its metadata.
+func validateQueryField(field string) error {
+	if assetFieldNames[field] {
+		return nil
+	}
+	if key, ok := strings.CutPrefix(field, metadataFieldPrefix); ok && key != "" {
+		return nil
+	}
+	return businessError(errCodeInvalidArgument, "unknown asset field %q in the query", field)
+}
+
+// isQueryScalar reports whether value is a JSON string, number, boolean or null.
+func isQueryScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, json.Number, bool:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// maxSampleSize bounds the size of a SampleAssets response.
+const maxSampleSize = 100
+
+// SampledAsset is an asset drawn by SampleAssets, with the number of committed
+// values of its key. SCORE is the hex encoded hash ranking it in the sample.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type SampledAsset struct {
+	ASSET        *Asset `json:"asset"`
+	HISTORYCOUNT int    `json:"historycount"`
+	SCORE        string `json:"score"`
+}
+
+// AssetSample is a pseudo-random sample of the assets for audit spot-checks.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetSample struct {
+	ASSETS     []*SampledAsset `json:"assets"`
+	POPULATION int             `json:"population"`
+	SEED       string          `json:"seed"`
+}
+
+// SampleAssets draws a pseudo-random sample of up to sampleSize assets, at most
+// 100, for audit spot-checks. Every asset is ranked by the SHA-256 hash of the
+// seed and its ID and the lowest ranked are drawn, so every endorser and every
+// later call with the same seed draws the same sample from the same ledger,
+// and an asset stays in the sample as other assets come and go. The sampled
+// assets are returned in rank order with the number of committed values of
+// their key.
+func (s *SmartContract) SampleAssets(ctx contractapi.TransactionContextInterface, seed string, sampleSize int) (*AssetSample, error) {
+	if seed == "" {
+		return nil, businessError(errCodeInvalidArgument, "the seed of a sample must be set")
+	}
+	if sampleSize <= 0 || sampleSize > maxSampleSize {
+		return nil, businessError(errCodeInvalidArgument, "the sample size must be between 1 and %d, not %d", maxSampleSize, sampleSize)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	sample := &AssetSample{ASSETS: []*SampledAsset{}, SEED: seed}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		sample.POPULATION++
+
+		score := sampleScore(seed, queryResponse.Key)
+		if len(sample.ASSETS) == sampleSize && score >= sample.ASSETS[sampleSize-1].SCORE {
+			continue
+		}
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+
+		// keep the sample sorted by score, dropping the highest ranked once full
+		i := sort.Search(len(sample.ASSETS), func(i int) bool { return sample.ASSETS[i].SCORE > score })
+		sample.ASSETS = append(sample.ASSETS, nil)
+		copy(sample.ASSETS[i+1:], sample.ASSETS[i:])
+		sample.ASSETS[i] = &SampledAsset{ASSET: &asset, SCORE: score}
+		if len(sample.ASSETS) > sampleSize {
+			sample.ASSETS = sample.ASSETS[:sampleSize]
+		}
+	}
+
+	for _, sampled := range sample.ASSETS {
+		versions, err := getAssetVersions(ctx, sampled.ASSET.ID)
+		if err != nil {
+			return nil, err
+		}
+		sampled.HISTORYCOUNT = len(versions)
+	}
+	return sample, nil
+}
+
+// sampleScore ranks the asset key in the samples drawn with seed.
+func sampleScore(seed string, key string) string {
+	hash := sha256.Sum256([]byte(seed + "\x00" + key))
+	return hex.EncodeToString(hash[:])
+}
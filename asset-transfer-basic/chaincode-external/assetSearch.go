@@ -0,0 +1,151 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Fields SearchAssets can search.
+const (
+	searchMSISDN  = "msisdn"
+	searchRemarks = "remarks"
+)
+
+// minSearchLength is the shortest MSISDN prefix or remarks keyword searched, so
+// that a search cannot list the details of every asset.
+const minSearchLength = 3
+
+// msisdnIndex is the CouchDB index of the assetDetailsCollection backing the
+// MSISDN prefix search, shipped in
+// META-INF/statedb/couchdb/collections/assetDetailsCollection/indexes.
+const msisdnIndex = "indexMsisdnDoc"
+
+// AssetSearchMatch is an asset found by SearchAssets, with the details that matched.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetSearchMatch struct {
+	ASSET   *Asset        `json:"asset"`
+	DETAILS *AssetDetails `json:"details"`
+}
+
+// AssetSearchPage is one page of the result of SearchAssets. BOOKMARK fetches
+// the next page and is empty after the last one.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetSearchPage struct {
+	BOOKMARK string              `json:"bookmark"`
+	MATCHES  []*AssetSearchMatch `json:"matches"`
+}
+
+// SearchAssets returns a page of the assets whose private details match, for
+// call-center agents locating a wallet from what the customer remembers. field
+// is "msisdn" to find the MSISDNs starting with prefixOrKeyword, or "remarks"
+// to find the remarks containing it, ignoring case. The MSISDN search uses the
+// index on msisdn, the remarks search scans the collection. Only the
+// assetDetailsCollection is searched, not the implicit collections, and only
+// peers of its member organizations can serve the search. The MSISDNs are
+// masked as for ReadAssetDetails.
+func (s *SmartContract) SearchAssets(ctx contractapi.TransactionContextInterface, field string, prefixOrKeyword string, pageSize int32, bookmark string) (*AssetSearchPage, error) {
+	if pageSize <= 0 {
+		return nil, businessError(errCodeInvalidArgument, "the page size must be positive")
+	}
+	skip := 0
+	if bookmark != "" {
+		var err error
+		skip, err = strconv.Atoi(bookmark)
+		if err != nil || skip < 0 {
+			return nil, businessError(errCodeInvalidArgument, "invalid bookmark %q", bookmark)
+		}
+	}
+
+	query, err := searchQuery(field, prefixOrKeyword)
+	if err != nil {
+		return nil, err
+	}
+	query["limit"] = pageSize
+	query["skip"] = skip
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataQueryResult(assetDetailsCollection, string(queryJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query private data collection: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := &AssetSearchPage{MATCHES: []*AssetSearchMatch{}}
+	scanned := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		scanned++
+
+		var details AssetDetails
+		err = json.Unmarshal(queryResponse.Value, &details)
+		if err != nil {
+			return nil, err
+		}
+		asset, err := readAssetSummary(ctx, details.ID)
+		if err != nil {
+			return nil, err
+		}
+		// details left behind by an asset moved to an implicit collection
+		if asset == nil || detailsCollection(asset) != assetDetailsCollection {
+			continue
+		}
+		masked, err := maskDetails(ctx, &details)
+		if err != nil {
+			return nil, err
+		}
+		page.MATCHES = append(page.MATCHES, &AssetSearchMatch{ASSET: asset, DETAILS: masked})
+	}
+	if scanned == int(pageSize) {
+		page.BOOKMARK = strconv.Itoa(skip + int(pageSize))
+	}
+
+	return page, nil
+}
+
+// searchQuery returns the CouchDB query of a search of field.
+func searchQuery(field string, prefixOrKeyword string) (map[string]interface{}, error) {
+	prefixOrKeyword = strings.TrimSpace(prefixOrKeyword)
+	if len(prefixOrKeyword) < minSearchLength {
+		return nil, businessError(errCodeInvalidArgument, "the search must have at least %d characters", minSearchLength)
+	}
+
+	switch strings.ToLower(field) {
+	case searchMSISDN:
+		for _, digit := range prefixOrKeyword {
+			if digit < '0' || digit > '9' {
+				return nil, businessError(errCodeInvalidArgument, "the MSISDN prefix %q must only have digits", prefixOrKeyword)
+			}
+		}
+		// a range rather than a regular expression, which CouchDB cannot run on an index
+		return map[string]interface{}{
+			"selector": map[string]interface{}{
+				"msisdn": map[string]interface{}{"$gte": prefixOrKeyword, "$lt": prefixOrKeyword + "\uffff"},
+			},
+			"sort":      []map[string]string{{"msisdn": "asc"}},
+			"use_index": msisdnIndex,
+		}, nil
+	case searchRemarks:
+		return map[string]interface{}{
+			"selector": map[string]interface{}{
+				"remarks": map[string]interface{}{"$regex": "(?i)" + regexp.QuoteMeta(prefixOrKeyword)},
+			},
+		}, nil
+	default:
+		return nil, businessError(errCodeInvalidArgument, "cannot search by %q, expected %s or %s", field, searchMSISDN, searchRemarks)
+	}
+}
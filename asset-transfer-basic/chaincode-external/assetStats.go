@@ -0,0 +1,60 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// AssetStats summarizes the assets in the world state. ASSETS, TOTALBALANCE and
+// AVERAGEBALANCE leave out the soft-deleted assets, like GetTotals, while
+// STATUSES counts the assets of every status, DELETED included.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetStats struct {
+	ASSETS         int            `json:"assets"`
+	AVERAGEBALANCE float64        `json:"averagebalance"`
+	STATUSES       map[string]int `json:"statuses"`
+	TOTALBALANCE   float64        `json:"totalbalance"`
+}
+
+// GetAssetStats returns the number of assets, by status and in total, and
+// their total and average balance. Unlike GetAllAssets it aggregates the
+// assets as it iterates over them, without holding them all, for dashboards
+// polling it often. GetTotals reads the same totals from counters without
+// scanning the assets.
+func (s *SmartContract) GetAssetStats(ctx contractapi.TransactionContextInterface) (*AssetStats, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	stats := &AssetStats{STATUSES: map[string]int{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		stats.STATUSES[asset.STATUS]++
+		if asset.STATUS == statusDeleted {
+			continue
+		}
+		stats.ASSETS++
+		stats.TOTALBALANCE += asset.BALANCE
+	}
+	if stats.ASSETS > 0 {
+		stats.AVERAGEBALANCE = stats.TOTALBALANCE / float64(stats.ASSETS)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,61 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestGetAssetStatsLeavesOutDeletedAssets(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	checkStats := func(expected AssetStats) {
+		t.Helper()
+		var stats AssetStats
+		if err := json.Unmarshal(invoke("GetAssetStats"), &stats); err != nil {
+			t.Fatal(err)
+		}
+		if stats.ASSETS != expected.ASSETS || stats.TOTALBALANCE != expected.TOTALBALANCE || stats.AVERAGEBALANCE != expected.AVERAGEBALANCE {
+			t.Errorf("expected %d assets holding %.2f, %.2f on average, got %d holding %.2f, %.2f on average",
+				expected.ASSETS, expected.TOTALBALANCE, expected.AVERAGEBALANCE, stats.ASSETS, stats.TOTALBALANCE, stats.AVERAGEBALANCE)
+		}
+		if !maps.Equal(stats.STATUSES, expected.STATUSES) {
+			t.Errorf("expected the status counts %v, got %v", expected.STATUSES, stats.STATUSES)
+		}
+	}
+
+	checkStats(AssetStats{STATUSES: map[string]int{}})
+
+	invoke("InitLedger")
+	checkStats(AssetStats{ASSETS: 7, AVERAGEBALANCE: 239000.0 / 7, STATUSES: map[string]int{"ACTIVE": 6, "INACTIVE": 1}, TOTALBALANCE: 239000})
+
+	var dryRun DeleteResult
+	if err := json.Unmarshal(invoke("DeleteAssetsByDealer", "DEALER106", "true", ""), &dryRun); err != nil {
+		t.Fatal(err)
+	}
+	invoke("DeleteAssetsByDealer", "DEALER106", "false", dryRun.TOKEN)
+	checkStats(AssetStats{ASSETS: 6, AVERAGEBALANCE: 227000.0 / 6, STATUSES: map[string]int{"ACTIVE": 5, "INACTIVE": 1, "DELETED": 1}, TOTALBALANCE: 227000})
+}
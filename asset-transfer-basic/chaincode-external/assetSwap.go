@@ -0,0 +1,309 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// swapConsentObjectType is the composite key prefix of the swap consents, keyed
+// by the asset and the asset it may be swapped with.
+const swapConsentObjectType = "swapconsent"
+
+// swapConsentKind is the expiry kind of the swap consents.
+const swapConsentKind = "swapconsent"
+
+// dealerAttribute is the certificate attribute naming the dealer of a caller.
+const dealerAttribute = "dealerid"
+
+// swapEvent is the event listing the assets exchanged by SwapAssets.
+const swapEvent = "AssetsSwapped"
+
+// Validity of a swap consent, in seconds.
+const (
+	defaultSwapConsentValidity = 24 * 60 * 60
+	maxSwapConsentValidity     = 7 * 24 * 60 * 60
+)
+
+// SwapConsent is the consent of the dealer owning ASSETID to exchange it for
+// COUNTERPARTID until EXPIRESAT. It lapses when the asset changes dealer.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type SwapConsent struct {
+	ASSETID       string `json:"assetid"`
+	CONSENTEDBY   string `json:"consentedby"`
+	COUNTERPARTID string `json:"counterpartid"`
+	DEALERID      string `json:"dealerid"`
+	EXPIRESAT     string `json:"expiresat"`
+	RECORDEDAT    string `json:"recordedat"`
+}
+
+// SwapResult describes the assets exchanged by SwapAssets. It is also the
+// payload of the AssetsSwapped event.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type SwapResult struct {
+	ASSETA    *Asset `json:"assetA"`
+	ASSETB    *Asset `json:"assetB"`
+	SWAPPEDAT string `json:"swappedat"`
+	TXID      string `json:"txid"`
+}
+
+func init() {
+	expiryReleasers[swapConsentKind] = releaseSwapConsent
+}
+
+// ConsentToSwap records the consent of the dealer owning an asset to exchange
+// it for the counterpart asset, for validForSeconds, a day when zero and at
+// most a week. The caller's certificate must carry the dealer in its dealerid
+// attribute. Recording the consent again renews it.
+func (s *SmartContract) ConsentToSwap(ctx contractapi.TransactionContextInterface, assetID string, counterpartID string, validForSeconds int) (*SwapConsent, error) {
+	if assetID == counterpartID {
+		return nil, businessError(errCodeInvalidArgument, "an asset cannot be swapped with itself")
+	}
+	if validForSeconds == 0 {
+		validForSeconds = defaultSwapConsentValidity
+	}
+	if validForSeconds < 0 || validForSeconds > maxSwapConsentValidity {
+		return nil, businessError(errCodeInvalidArgument, "a swap consent is valid for up to %d seconds, not %d", maxSwapConsentValidity, validForSeconds)
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireDealer(ctx, asset.DEALERID); err != nil {
+		return nil, err
+	}
+	exists, err := s.AssetExists(ctx, counterpartID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, assetNotFoundError(counterpartID)
+	}
+
+	// a renewed consent replaces the previous one and its expiry
+	if err := deleteSwapConsent(ctx, assetID, counterpartID); err != nil {
+		return nil, err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+	expiresAt := now.Add(time.Duration(validForSeconds) * time.Second)
+	consent := &SwapConsent{
+		ASSETID:       assetID,
+		CONSENTEDBY:   clientID,
+		COUNTERPARTID: counterpartID,
+		DEALERID:      asset.DEALERID,
+		EXPIRESAT:     expiresAt.Format(expiryTimeLayout),
+		RECORDEDAT:    now.Format(time.RFC3339),
+	}
+
+	key, err := swapConsentKey(ctx, assetID, counterpartID)
+	if err != nil {
+		return nil, err
+	}
+	consentJSON, err := json.Marshal(consent)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(key, consentJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return consent, registerExpiry(ctx, swapConsentKind, []string{assetID, counterpartID}, expiresAt)
+}
+
+// RevokeSwapConsent withdraws the consent to exchange an asset for the
+// counterpart asset. Only the dealer owning the asset may revoke it.
+func (s *SmartContract) RevokeSwapConsent(ctx contractapi.TransactionContextInterface, assetID string, counterpartID string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if err := requireDealer(ctx, asset.DEALERID); err != nil {
+		return err
+	}
+	consent, err := readSwapConsent(ctx, assetID, counterpartID)
+	if err != nil {
+		return err
+	}
+	if consent == nil {
+		return businessError(errCodeInvalidArgument, "there is no consent to swap %s with %s", assetID, counterpartID)
+	}
+	return deleteSwapConsent(ctx, assetID, counterpartID)
+}
+
+// GetSwapConsent returns the consent to exchange an asset for the counterpart
+// asset, or null when there is none. Expired consents are returned until
+// SweepExpired releases them.
+func (s *SmartContract) GetSwapConsent(ctx contractapi.TransactionContextInterface, assetID string, counterpartID string) (*SwapConsent, error) {
+	return readSwapConsent(ctx, assetID, counterpartID)
+}
+
+// SwapAssets atomically exchanges the dealers of two assets of different
+// dealers. Both dealers must have consented to the swap with ConsentToSwap,
+// with consents that have not expired and were recorded by the current dealer
+// of each asset. The consents are used up by the swap. Assets with liens, holds
+// or awaiting attestations, see RequireAttestation, cannot be swapped. Only the
+// two dealers and admins may call it.
+func (s *SmartContract) SwapAssets(ctx contractapi.TransactionContextInterface, assetIDa string, assetIDb string) (*SwapResult, error) {
+	if assetIDa == assetIDb {
+		return nil, businessError(errCodeInvalidArgument, "an asset cannot be swapped with itself")
+	}
+	assetA, err := s.ReadAsset(ctx, assetIDa)
+	if err != nil {
+		return nil, err
+	}
+	assetB, err := s.ReadAsset(ctx, assetIDb)
+	if err != nil {
+		return nil, err
+	}
+	if assetA.DEALERID == assetB.DEALERID {
+		return nil, businessError(errCodeInvalidArgument, "the assets %s and %s both belong to dealer %s", assetIDa, assetIDb, assetA.DEALERID)
+	}
+	if requireAdmin(ctx) != nil && requireDealer(ctx, assetA.DEALERID) != nil {
+		if err := requireDealer(ctx, assetB.DEALERID); err != nil {
+			return nil, err
+		}
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+	for _, asset := range []*Asset{assetA, assetB} {
+		if err := requireNoLiens(asset); err != nil {
+			return nil, err
+		}
+		if err := requireNoHolds(asset); err != nil {
+			return nil, err
+		}
+		if err := s.claimAttestations(ctx, asset.ID); err != nil {
+			return nil, err
+		}
+		counterpartID := assetIDb
+		if asset == assetB {
+			counterpartID = assetIDa
+		}
+		consent, err := readSwapConsent(ctx, asset.ID, counterpartID)
+		if err != nil {
+			return nil, err
+		}
+		if consent == nil || consent.DEALERID != asset.DEALERID || consent.EXPIRESAT <= now.Format(expiryTimeLayout) {
+			return nil, businessError(errCodeForbidden, "dealer %s has no valid consent to swap %s with %s", asset.DEALERID, asset.ID, counterpartID)
+		}
+		if err := deleteSwapConsent(ctx, asset.ID, counterpartID); err != nil {
+			return nil, err
+		}
+	}
+
+	assetA.DEALERID, assetB.DEALERID = assetB.DEALERID, assetA.DEALERID
+	for _, asset := range []*Asset{assetA, assetB} {
+		if err := putAssetSummary(ctx, asset); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(ctx, asset.ID, "SwapAssets"); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &SwapResult{ASSETA: assetA, ASSETB: assetB, SWAPPEDAT: now.Format(time.RFC3339), TXID: ctx.GetStub().GetTxID()}
+	eventJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().SetEvent(swapEvent, eventJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+	return result, nil
+}
+
+// requireDealer fails unless the dealerid attribute of the caller's certificate is dealerID.
+func requireDealer(ctx contractapi.TransactionContextInterface, dealerID string) error {
+	callerDealer, found, err := ctx.GetClientIdentity().GetAttributeValue(dealerAttribute)
+	if err != nil {
+		return fmt.Errorf("failed to get client attribute %s: %v", dealerAttribute, err)
+	}
+	if !found || callerDealer != dealerID {
+		return businessError(errCodeForbidden, "the caller does not act for dealer %s", dealerID)
+	}
+	return nil
+}
+
+func swapConsentKey(ctx contractapi.TransactionContextInterface, assetID string, counterpartID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(swapConsentObjectType, []string{assetID, counterpartID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
+
+// readSwapConsent returns the consent to swap an asset with the counterpart, or
+// nil when there is none.
+func readSwapConsent(ctx contractapi.TransactionContextInterface, assetID string, counterpartID string) (*SwapConsent, error) {
+	key, err := swapConsentKey(ctx, assetID, counterpartID)
+	if err != nil {
+		return nil, err
+	}
+	consentJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if consentJSON == nil {
+		return nil, nil
+	}
+
+	var consent SwapConsent
+	if err := json.Unmarshal(consentJSON, &consent); err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// deleteSwapConsent deletes a consent and its expiry index entry, if any.
+func deleteSwapConsent(ctx contractapi.TransactionContextInterface, assetID string, counterpartID string) error {
+	consent, err := readSwapConsent(ctx, assetID, counterpartID)
+	if err != nil || consent == nil {
+		return err
+	}
+	key, err := swapConsentKey(ctx, assetID, counterpartID)
+	if err != nil {
+		return err
+	}
+	expiresAt, err := time.Parse(expiryTimeLayout, consent.EXPIRESAT)
+	if err != nil {
+		return fmt.Errorf("malformed expiry %q of swap consent: %v", consent.EXPIRESAT, err)
+	}
+	if err := unregisterExpiry(ctx, swapConsentKind, []string{assetID, counterpartID}, expiresAt); err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// releaseSwapConsent deletes an expired consent, of the asset and counterpart
+// IDs in id, for SweepExpired, which removes its expiry index entry itself.
+func releaseSwapConsent(ctx contractapi.TransactionContextInterface, id []string) error {
+	if len(id) != 2 {
+		return fmt.Errorf("malformed swap consent id %q", id)
+	}
+	key, err := swapConsentKey(ctx, id[0], id[1])
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
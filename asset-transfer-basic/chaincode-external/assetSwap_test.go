@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestSwapNeedsCurrentConsents(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(dealerID string, function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: dealerID == "", DealerID: dealerID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(dealerID string, function string, args ...string) []byte {
+		t.Helper()
+		response := call(dealerID, function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	expectRefused := func(dealerID string, reason string) {
+		t.Helper()
+		response := call(dealerID, "SwapAssets", "asset1", "asset2")
+		if response.Status == shim.OK || !strings.Contains(response.Message, errCodeForbidden) {
+			t.Errorf("expected the swap to be refused %s, got status %d: %s", reason, response.Status, response.Message)
+		}
+	}
+
+	invoke("", "InitLedger")
+	if response := call("DEALER102", "ConsentToSwap", "asset1", "asset2", "0"); response.Status == shim.OK {
+		t.Error("expected a dealer not to consent for the asset of another dealer")
+	}
+	invoke("DEALER101", "ConsentToSwap", "asset1", "asset2", "0")
+	invoke("DEALER102", "ConsentToSwap", "asset2", "asset1", "0")
+	expectRefused("DEALER103", "to a third dealer")
+
+	invoke("DEALER102", "RevokeSwapConsent", "asset2", "asset1")
+	expectRefused("DEALER101", "after a revoked consent")
+
+	invoke("DEALER102", "ConsentToSwap", "asset2", "asset1", "1")
+	time.Sleep(1100 * time.Millisecond)
+	expectRefused("DEALER101", "after an expired consent")
+
+	invoke("DEALER102", "ConsentToSwap", "asset2", "asset1", "0")
+	var result SwapResult
+	if err := json.Unmarshal(invoke("DEALER101", "SwapAssets", "asset1", "asset2"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ASSETA.DEALERID != "DEALER102" || result.ASSETB.DEALERID != "DEALER101" {
+		t.Errorf("expected the dealers to be exchanged, got %s and %s", result.ASSETA.DEALERID, result.ASSETB.DEALERID)
+	}
+	if consent := invoke("", "GetSwapConsent", "asset1", "asset2"); string(consent) != "" && string(consent) != "null" {
+		t.Errorf("expected the swap to use up the consents, got %s", consent)
+	}
+	expectRefused("", "once the consents are used up")
+}
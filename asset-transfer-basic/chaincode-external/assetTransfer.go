@@ -5,19 +5,27 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
 )
 
 type serverConfig struct {
-	CCID    string
-	Address string
+	CCID           string
+	Address        string
+	MetricsAddress string
+	LocalAddress   string
+	LocalFile      string
 }
 
 // SmartContract provides functions for managing an Asset
@@ -25,41 +33,85 @@ type SmartContract struct {
 	contractapi.Contract
 }
 
-// Asset describes basic details of what makes up a simple asset
+// assetDetailsCollection is the private data collection holding the sensitive
+// part of every asset. See collections_config.json.
+const assetDetailsCollection = "assetDetailsCollection"
+
+// transientDetailsKey is the transient map entry carrying AssetDetails input.
+const transientDetailsKey = "asset_details"
+
+// Asset describes the public summary of an asset kept in the world state.
+// Sensitive details live in the private data collection, see AssetDetails, or
+// in the implicit collection of the organization DETAILSORG when set.
+// METADATA holds free-form attributes of the asset, changed with PatchAsset.
+// VERSION counts the writes of the asset, see putAssetSummary, and is 0 for
+// assets last written before it was introduced. LIENED is the part of the
+// balance pledged by liens, see PlaceLien, HELD the part held under the hold
+// policy, see GetHolds, and SPENDABLE the rest, always set when the asset is
+// encoded, see MarshalJSON.
 // Insert struct field in alphabetic order => to achieve determinism across languages
 type Asset struct {
-	BALANCE     float64 `json:"balance"`
-	DEALERID    string  `json:"dealerid"`
-	ID          string  `json:"ID"`
-	MPIN        string  `json:"mpin"`
-	MSISDN      string  `json:"msisdn"`
-	REMARKS     string  `json:"remarks"`
-	STATUS      string  `json:"status"`
-	TRANSAMOUNT float64 `json:"transamount"`
-	TRANSTYPE   string  `json:"transtype"`
+	BALANCE     float64           `json:"balance"`
+	DEALERID    string            `json:"dealerid"`
+	DETAILSHASH string            `json:"detailshash"`
+	DETAILSORG  string            `json:"detailsorg,omitempty" metadata:",optional"`
+	HELD        float64           `json:"held,omitempty" metadata:",optional"`
+	ID          string            `json:"ID"`
+	LIENED      float64           `json:"liened,omitempty" metadata:",optional"`
+	METADATA    map[string]string `json:"metadata,omitempty" metadata:",optional"`
+	SPENDABLE   float64           `json:"spendable"`
+	STATUS      string            `json:"status"`
+	TRANSAMOUNT float64           `json:"transamount"`
+	TRANSTYPE   string            `json:"transtype"`
+	UPDATEDAT   string            `json:"updatedat"`
+	VERSION     int64             `json:"version"`
+}
+
+// AssetDetails describes the private part of an asset, stored in the
+// assetDetailsCollection under the same key as the public summary.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetDetails struct {
+	ID       string `json:"ID"`
+	MPINHASH string `json:"mpinhash"`
+	MSISDN   string `json:"msisdn"`
+	REMARKS  string `json:"remarks"`
+}
+
+// assetDetailsInput is the transient payload accepted by CreateAsset and UpdateAsset.
+type assetDetailsInput struct {
+	MPIN    string `json:"mpin"`
+	MSISDN  string `json:"msisdn"`
+	REMARKS string `json:"remarks"`
 }
 
 // InitLedger adds a base set of assets to the ledger
 func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
-	assets := []Asset{
-		{ID: "asset1", DEALERID: "DEALER101", MSISDN: "9877890123", MPIN: "1598", BALANCE: 100000.00, STATUS: "ACTIVE", TRANSAMOUNT: 100000.00, TRANSTYPE: "CREDIT", REMARKS: "Personal loan disbursement"},
-		{ID: "asset2", DEALERID: "DEALER102", MSISDN: "9811234567", MPIN: "4321", BALANCE: 500.00, STATUS: "ACTIVE", TRANSAMOUNT: 500.00, TRANSTYPE: "INIT", REMARKS: "New account creation"},
-		{ID: "asset3", DEALERID: "DEALER103", MSISDN: "9876543212", MPIN: "9012", BALANCE: 1500.00, STATUS: "ACTIVE", TRANSAMOUNT: 200.00, TRANSTYPE: "DEBIT", REMARKS: "Purchase transaction"},
-		{ID: "asset4", DEALERID: "DEALER104", MSISDN: "9822345678", MPIN: "8765", BALANCE: 25000.00, STATUS: "ACTIVE", TRANSAMOUNT: 25000.00, TRANSTYPE: "CREDIT", REMARKS: "Business investment deposit"},
-		{ID: "asset5", DEALERID: "DEALER105", MSISDN: "9844567890", MPIN: "1357", BALANCE: 0.00, STATUS: "INACTIVE", TRANSAMOUNT: 0.00, TRANSTYPE: "SUSPEND", REMARKS: "Account dormant - no activity for 6 months"},
-		{ID: "asset6", DEALERID: "DEALER106", MSISDN: "9866789012", MPIN: "3579", BALANCE: 12000.00, STATUS: "ACTIVE", TRANSAMOUNT: 3000.00, TRANSTYPE: "DEBIT", REMARKS: "Electricity bill payment"},
-		{ID: "asset7", DEALERID: "DEALER107", MSISDN: "9877890123", MPIN: "1598", BALANCE: 100000.00, STATUS: "ACTIVE", TRANSAMOUNT: 100000.00, TRANSTYPE: "CREDIT", REMARKS: "Personal loan disbursement"},
-	}
-
-	for _, asset := range assets {
-		assetJSON, err := json.Marshal(asset)
-		if err != nil {
-			return err
-		}
+	seeds := []struct {
+		Asset
+		MPIN    string
+		MSISDN  string
+		REMARKS string
+	}{
+		{Asset: Asset{ID: "asset1", DEALERID: "DEALER101", BALANCE: 100000.00, STATUS: "ACTIVE", TRANSAMOUNT: 100000.00, TRANSTYPE: "CREDIT"}, MSISDN: "9877890123", MPIN: "1598", REMARKS: "Personal loan disbursement"},
+		{Asset: Asset{ID: "asset2", DEALERID: "DEALER102", BALANCE: 500.00, STATUS: "ACTIVE", TRANSAMOUNT: 500.00, TRANSTYPE: "INIT"}, MSISDN: "9811234567", MPIN: "4321", REMARKS: "New account creation"},
+		{Asset: Asset{ID: "asset3", DEALERID: "DEALER103", BALANCE: 1500.00, STATUS: "ACTIVE", TRANSAMOUNT: 200.00, TRANSTYPE: "DEBIT"}, MSISDN: "9876543212", MPIN: "9012", REMARKS: "Purchase transaction"},
+		{Asset: Asset{ID: "asset4", DEALERID: "DEALER104", BALANCE: 25000.00, STATUS: "ACTIVE", TRANSAMOUNT: 25000.00, TRANSTYPE: "CREDIT"}, MSISDN: "9822345678", MPIN: "8765", REMARKS: "Business investment deposit"},
+		{Asset: Asset{ID: "asset5", DEALERID: "DEALER105", BALANCE: 0.00, STATUS: "INACTIVE", TRANSAMOUNT: 0.00, TRANSTYPE: "SUSPEND"}, MSISDN: "9844567890", MPIN: "1357", REMARKS: "Account dormant - no activity for 6 months"},
+		{Asset: Asset{ID: "asset6", DEALERID: "DEALER106", BALANCE: 12000.00, STATUS: "ACTIVE", TRANSAMOUNT: 3000.00, TRANSTYPE: "DEBIT"}, MSISDN: "9866789012", MPIN: "3579", REMARKS: "Electricity bill payment"},
+		{Asset: Asset{ID: "asset7", DEALERID: "DEALER107", BALANCE: 100000.00, STATUS: "ACTIVE", TRANSAMOUNT: 100000.00, TRANSTYPE: "CREDIT"}, MSISDN: "9877890123", MPIN: "1598", REMARKS: "Personal loan disbursement"},
+	}
 
-		err = ctx.GetStub().PutState(asset.ID, assetJSON)
+	for _, seed := range seeds {
+		details := AssetDetails{
+			ID:       seed.ID,
+			MPINHASH: hashMPIN(seed.ID, seed.MPIN),
+			MSISDN:   seed.MSISDN,
+			REMARKS:  seed.REMARKS,
+		}
+		asset := seed.Asset
+		err := putAsset(ctx, &asset, &details)
 		if err != nil {
-			return fmt.Errorf("failed to put to world state: %v", err)
+			return err
 		}
 	}
 
@@ -67,42 +119,77 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 }
 
 // CreateAsset issues a new asset to the world state with given details.
-func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, dealerID string, msisdn string, mpin string, balance float64, status string, transAmount float64, transType string, remarks string) error {
-	exists, err := s.AssetExists(ctx, id)
+// The MSISDN, MPIN and remarks are read from the "asset_details" transient
+// field and written to the private data collection only. The remarks are
+// normalized, and the remark code they map to is set in the metadata. The
+// opening balance is drawn from the float of the dealer, and the hold policy
+// may hold part of it for the transaction type.
+func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, dealerID string, balance float64, status string, transAmount float64, transType string) error {
+	input, err := readDetailsInput(ctx)
 	if err != nil {
 		return err
 	}
-	if exists {
-		return fmt.Errorf("the asset %s already exists", id)
+	if input == nil {
+		return fmt.Errorf("%s must be a key in the transient map", transientDetailsKey)
 	}
 
 	asset := Asset{
 		ID:          id,
 		DEALERID:    dealerID,
-		MSISDN:      msisdn,
-		MPIN:        mpin,
 		BALANCE:     balance,
 		STATUS:      status,
 		TRANSAMOUNT: transAmount,
 		TRANSTYPE:   transType,
-		REMARKS:     remarks,
 	}
-	assetJSON, err := json.Marshal(asset)
+	return s.createAsset(ctx, &asset, input)
+}
+
+// createAsset writes a new asset with the private details of input.
+func (s *SmartContract) createAsset(ctx contractapi.TransactionContextInterface, asset *Asset, input *assetDetailsInput) error {
+	exists, err := s.AssetExists(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return businessError(errCodeAssetExists, "the asset %s already exists", asset.ID)
+	}
+	err = requireNotArchived(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+
+	details := AssetDetails{
+		ID:       asset.ID,
+		MPINHASH: hashMPIN(asset.ID, input.MPIN),
+		MSISDN:   input.MSISDN,
+		REMARKS:  input.REMARKS,
+	}
+	err = applyRemarkCode(ctx, asset, input.REMARKS)
+	if err != nil {
+		return err
+	}
+
+	// the opening balance of a wallet is credited from the float of its dealer
+	err = drawDownFloat(ctx, asset.DEALERID, asset.BALANCE)
+	if err != nil {
+		return err
+	}
+	err = placeHold(ctx, asset)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, assetJSON)
+	return putAsset(ctx, asset, &details)
 }
 
-// ReadAsset returns the asset stored in the world state with given id.
+// ReadAsset returns the public summary of the asset stored in the world state with given id.
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
 	assetJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
 	}
 	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, assetNotFoundError(id)
 	}
 
 	var asset Asset
@@ -114,44 +201,260 @@ func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, i
 	return &asset, nil
 }
 
+// ReadAssetDetails returns the private details of the asset with given id.
+// Only peers of organizations that are members of the collection can serve it.
+// With the mask-msisdn feature flag on, callers other than admins get the
+// MSISDN masked but for its last four digits.
+func (s *SmartContract) ReadAssetDetails(ctx contractapi.TransactionContextInterface, id string) (*AssetDetails, error) {
+	details, err := readAssetDetails(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return maskDetails(ctx, details)
+}
+
+// maskDetails masks the MSISDN of the details when the mask-msisdn feature flag
+// is on and the caller is not an admin.
+func maskDetails(ctx contractapi.TransactionContextInterface, details *AssetDetails) (*AssetDetails, error) {
+	mask, err := featureEnabled(ctx, featureMaskMSISDN)
+	if err != nil {
+		return nil, err
+	}
+	if mask && requireAdmin(ctx) != nil {
+		details.MSISDN = maskMSISDN(details.MSISDN)
+	}
+	return details, nil
+}
+
+// maskMSISDN replaces all but the last four digits of an MSISDN with asterisks.
+func maskMSISDN(msisdn string) string {
+	if len(msisdn) <= 4 {
+		return msisdn
+	}
+	return strings.Repeat("*", len(msisdn)-4) + msisdn[len(msisdn)-4:]
+}
+
+// readAssetDetails returns the stored private details of the asset with given
+// id, from the collection holding them.
+func readAssetDetails(ctx contractapi.TransactionContextInterface, id string) (*AssetDetails, error) {
+	asset, err := readAssetSummary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, assetNotFoundError(id)
+	}
+
+	detailsJSON, err := ctx.GetStub().GetPrivateData(detailsCollection(asset), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	if detailsJSON == nil {
+		return nil, fmt.Errorf("the details of asset %s do not exist", id)
+	}
+
+	var details AssetDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return nil, err
+	}
+
+	return &details, nil
+}
+
 // UpdateAsset updates an existing asset in the world state with provided parameters.
-func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, dealerID string, msisdn string, mpin string, balance float64, status string, transAmount float64, transType string, remarks string) error {
-	exists, err := s.AssetExists(ctx, id)
+// An increase of the balance is drawn from the float of the dealer, and a
+// decrease may not exceed the spendable balance. A change of dealer is refused
+// like TransferAsset for assets with liens, holds or awaiting attestations. The
+// hold policy may place a hold for the transaction type, see placeHold.
+// When the "asset_details" transient field is present the private details are
+// replaced as well, otherwise the stored details are kept. The MSISDN is only
+// changed by RequestMSISDNChange and the MPIN by RequestMPINReset, so the
+// details must carry the current ones. With the skip-unchanged-updates feature
+// flag on, an update that would store the current values succeeds without
+// writing anything. The changed fields are set as the AssetChanged event.
+// Soft-deleted and closed assets cannot be updated, see requireOpen.
+func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, dealerID string, balance float64, status string, transAmount float64, transType string) error {
+	current, err := s.ReadAsset(ctx, id)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("the asset %s does not exist", id)
+	err = requireOpen(current)
+	if err != nil {
+		return err
+	}
+
+	input, err := readDetailsInput(ctx)
+	if err != nil {
+		return err
 	}
 
 	// overwriting original asset with new asset
 	asset := Asset{
 		ID:          id,
 		DEALERID:    dealerID,
-		MSISDN:      msisdn,
-		MPIN:        mpin,
+		DETAILSHASH: current.DETAILSHASH,
+		DETAILSORG:  current.DETAILSORG,
+		HELD:        current.HELD,
+		LIENED:      current.LIENED,
+		METADATA:    current.METADATA,
 		BALANCE:     balance,
 		STATUS:      status,
 		TRANSAMOUNT: transAmount,
 		TRANSTYPE:   transType,
-		REMARKS:     remarks,
+		UPDATEDAT:   current.UPDATEDAT,
+		VERSION:     current.VERSION,
 	}
-	assetJSON, err := json.Marshal(asset)
+	var details *AssetDetails
+	if input != nil {
+		err = requireMSISDNUnchanged(ctx, id, input)
+		if err != nil {
+			return err
+		}
+		err = requireMPINUnchanged(ctx, id, input)
+		if err != nil {
+			return err
+		}
+		details = &AssetDetails{
+			ID:       id,
+			MPINHASH: hashMPIN(id, input.MPIN),
+			MSISDN:   input.MSISDN,
+			REMARKS:  input.REMARKS,
+		}
+		err = applyRemarkCode(ctx, &asset, input.REMARKS)
+		if err != nil {
+			return err
+		}
+	}
+
+	// re-sending the current record succeeds without writing, so it neither
+	// grows the history of the asset nor conflicts with concurrent updates
+	skipUnchanged, err := featureEnabled(ctx, featureSkipUnchangedUpdates)
+	if err != nil {
+		return err
+	}
+	unchanged, err := assetUnchanged(current, &asset, details)
+	if err != nil {
+		return err
+	}
+	if skipUnchanged && unchanged {
+		markUnchanged(ctx)
+		return nil
+	}
+
+	// a debit may not reach into the balance pledged by liens or held, and an asset
+	// with liens, holds or awaiting attestations stays with its dealer, like
+	// TransferAsset
+	if balance < current.BALANCE {
+		err = requireSpendable(current, current.BALANCE-balance)
+		if err != nil {
+			return err
+		}
+	}
+	if dealerID != current.DEALERID {
+		err = requireNoLiens(current)
+		if err != nil {
+			return err
+		}
+		err = requireNoHolds(current)
+		if err != nil {
+			return err
+		}
+		err = s.claimAttestations(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// a balance increase is a credit drawn from the float of the dealer
+	err = drawDownFloat(ctx, dealerID, balance-current.BALANCE)
+	if err != nil {
+		return err
+	}
+	err = placeHold(ctx, &asset)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, assetJSON)
+	if details == nil {
+		err = putAssetSummary(ctx, &asset)
+	} else {
+		err = putAsset(ctx, &asset, details)
+	}
+	if err != nil {
+		return err
+	}
+	return setAssetChangedEvent(ctx, current, &asset)
+}
+
+// assetUnchanged returns true when writing asset, and details if not nil, would
+// store the same values as current, apart from the update timestamp and version.
+func assetUnchanged(current *Asset, asset *Asset, details *AssetDetails) (bool, error) {
+	if details != nil {
+		detailsJSON, err := json.Marshal(details)
+		if err != nil {
+			return false, err
+		}
+		detailsHash := sha256.Sum256(detailsJSON)
+		if hex.EncodeToString(detailsHash[:]) != current.DETAILSHASH {
+			return false, nil
+		}
+	}
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(currentJSON, assetJSON), nil
 }
 
-// DeleteAsset deletes a given asset from the world state.
+// DeleteAsset deletes a given asset from the world state and its details from the private data collection.
+// Assets with liens or holds cannot be deleted.
 func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
-	exists, err := s.AssetExists(ctx, id)
+	asset, err := readAssetSummary(ctx, id)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return assetNotFoundError(id)
+	}
+	err = requireNoLiens(asset)
+	if err != nil {
+		return err
+	}
+	err = requireNoHolds(asset)
+	if err != nil {
+		return err
+	}
+
+	err = updateAssetCounters(ctx, asset, nil)
+	if err != nil {
+		return err
+	}
+	err = updateDealerIndex(ctx, asset, nil)
+	if err != nil {
+		return err
+	}
+	err = updateStatusIndex(ctx, asset, nil)
+	if err != nil {
+		return err
+	}
+	err = deleteMSISDNChange(ctx, asset)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("the asset %s does not exist", id)
+	err = deleteMPINReset(ctx, asset)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelPrivateData(detailsCollection(asset), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete from private data collection: %v", err)
 	}
 
 	return ctx.GetStub().DelState(id)
@@ -167,22 +470,55 @@ func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface,
 	return assetJSON != nil, nil
 }
 
+// VerifyAssetDetails returns true when the details hash recorded in the public
+// summary matches the hash of the private details held by the collection.
+// It can be called on any peer of the channel, members of the collection or not.
+func (s *SmartContract) VerifyAssetDetails(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	detailsHash, err := ctx.GetStub().GetPrivateDataHash(detailsCollection(asset), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private data hash: %v", err)
+	}
+	if detailsHash == nil {
+		return false, fmt.Errorf("the details of asset %s do not exist", id)
+	}
+
+	return hex.EncodeToString(detailsHash) == asset.DETAILSHASH, nil
+}
+
 // TransferAsset updates the DEALERID field of the asset with the given id in the world state.
+// Assets with liens or holds cannot be transferred, nor assets awaiting an
+// attestation required by RequireAttestation, nor soft-deleted or closed ones.
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, id string, newDealerID string) (string, error) {
 	asset, err := s.ReadAsset(ctx, id)
 	if err != nil {
 		return "", err
 	}
-
-	oldDealerID := asset.DEALERID
-	asset.DEALERID = newDealerID
-
-	assetJSON, err := json.Marshal(asset)
+	err = requireOpen(asset)
+	if err != nil {
+		return "", err
+	}
+	err = requireNoLiens(asset)
+	if err != nil {
+		return "", err
+	}
+	err = requireNoHolds(asset)
+	if err != nil {
+		return "", err
+	}
+	err = s.claimAttestations(ctx, id)
 	if err != nil {
 		return "", err
 	}
 
-	err = ctx.GetStub().PutState(id, assetJSON)
+	oldDealerID := asset.DEALERID
+	asset.DEALERID = newDealerID
+
+	err = putAssetSummary(ctx, asset)
 	if err != nil {
 		return "", err
 	}
@@ -190,9 +526,16 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 	return oldDealerID, nil
 }
 
-// GetAllAssets returns all assets found in world state
+// GetAllAssets returns the public summary of all assets found in world state
 func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	return s.GetAssetsByRange(ctx, "", "")
+}
+
+// GetAssetsByRange returns the public summary of the assets whose id is from
+// startKey included to endKey excluded, in id order. An empty startKey or
+// endKey leaves the range open on that side.
+func (s *SmartContract) GetAssetsByRange(ctx contractapi.TransactionContextInterface, startKey string, endKey string) ([]*Asset, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
 	if err != nil {
 		return nil, err
 	}
@@ -216,23 +559,225 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 	return assets, nil
 }
 
+// ReadState returns the raw world state value of a key, empty when the key does not exist.
+// Clients use it to compare the writes proposed by a simulated transaction with the current state.
+func (s *SmartContract) ReadState(ctx contractapi.TransactionContextInterface, key string) (string, error) {
+	value, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return string(value), nil
+}
+
+// putAsset writes the private details to the collection and the public
+// summary, carrying the hash of the details, to the world state. Details moving
+// to another collection, as the implicit-collections feature flag changes, are
+// deleted from the previous one.
+func putAsset(ctx contractapi.TransactionContextInterface, asset *Asset, details *AssetDetails) error {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	collection, detailsOrg, err := detailsWriteCollection(ctx)
+	if err != nil {
+		return err
+	}
+	previous, err := readAssetSummary(ctx, details.ID)
+	if err != nil {
+		return err
+	}
+	if previous != nil && detailsCollection(previous) != collection {
+		err = ctx.GetStub().DelPrivateData(detailsCollection(previous), details.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete from private data collection: %v", err)
+		}
+	}
+
+	err = ctx.GetStub().PutPrivateData(collection, details.ID, detailsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to private data collection: %v", err)
+	}
+	asset.DETAILSORG = detailsOrg
+
+	detailsHash := sha256.Sum256(detailsJSON)
+	asset.DETAILSHASH = hex.EncodeToString(detailsHash[:])
+
+	return putAssetSummary(ctx, asset)
+}
+
+// putAssetSummary writes the public summary of the asset to the world state,
+// stamped with the time of the transaction and the version following the stored
+// one, and updates the asset counters, the debits checked against the limits
+// and the dealer~asset index.
+func putAssetSummary(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	asset.UPDATEDAT = timestamp.AsTime().UTC().Format(time.RFC3339)
+
+	previous, err := readAssetSummary(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	asset.VERSION = 1
+	if previous != nil {
+		asset.VERSION = previous.VERSION + 1
+	}
+	err = updateAssetCounters(ctx, previous, asset)
+	if err != nil {
+		return err
+	}
+	err = recordDebit(ctx, previous, asset)
+	if err != nil {
+		return err
+	}
+	err = updateDealerIndex(ctx, previous, asset)
+	if err != nil {
+		return err
+	}
+	err = updateStatusIndex(ctx, previous, asset)
+	if err != nil {
+		return err
+	}
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(asset.ID, assetJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return nil
+}
+
+// readAssetSummary returns the public summary of the asset with given id, or nil
+// when it does not exist.
+func readAssetSummary(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return nil, nil
+	}
+
+	var asset Asset
+	err = json.Unmarshal(assetJSON, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// readDetailsInput returns the asset details passed in the transient map,
+// or nil when the caller did not provide any.
+func readDetailsInput(ctx contractapi.TransactionContextInterface) (*assetDetailsInput, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	detailsJSON, ok := transientMap[transientDetailsKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var input assetDetailsInput
+	err = json.Unmarshal(detailsJSON, &input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", transientDetailsKey, err)
+	}
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	return &input, nil
+}
+
+func (input *assetDetailsInput) validate() error {
+	if input.MSISDN == "" {
+		return fmt.Errorf("msisdn field must be a non-empty string")
+	}
+	if input.MPIN == "" {
+		return fmt.Errorf("mpin field must be a non-empty string")
+	}
+	remarks, err := normalizeRemarks(input.REMARKS)
+	if err != nil {
+		return err
+	}
+	input.REMARKS = remarks
+	return nil
+}
+
+// hashMPIN returns the hex encoded SHA-256 of the MPIN salted with the asset id,
+// so equal MPINs on different assets do not produce equal hashes.
+func hashMPIN(id string, mpin string) string {
+	mpinHash := sha256.Sum256([]byte(id + ":" + mpin))
+	return hex.EncodeToString(mpinHash[:])
+}
+
+// newChaincode returns the chaincode serving SmartContract, with its hooks, and
+// the test contracts of the build.
+func newChaincode() (*contractapi.ContractChaincode, error) {
+	contracts := []contractapi.ContractInterface{&SmartContract{
+		Contract: contractapi.Contract{
+			BeforeTransaction:         runBeforeHooks,
+			AfterTransaction:          runAfterHooks,
+			TransactionContextHandler: new(meteredContext),
+		},
+	}}
+	return contractapi.NewChaincode(append(contracts, testContracts...)...)
+}
+
 func main() {
 	// See chaincode.env.example
 	config := serverConfig{
-		CCID:    os.Getenv("CHAINCODE_ID"),
-		Address: os.Getenv("CHAINCODE_SERVER_ADDRESS"),
+		CCID:           os.Getenv("CHAINCODE_ID"),
+		Address:        os.Getenv("CHAINCODE_SERVER_ADDRESS"),
+		MetricsAddress: os.Getenv("METRICS_ADDRESS"),
+		LocalAddress:   os.Getenv("LOCAL_LEDGER_ADDRESS"),
+		LocalFile:      os.Getenv("LOCAL_LEDGER_FILE"),
 	}
+	if err := checkTenantMode(); err != nil {
+		log.Panicf("error configuring tenants: %s", err)
+	}
+	build := versionInfo()
+	log.Printf("asset-transfer-basic chaincode commit %s built %s, contract API %s, state schema %d", build.GITCOMMIT, build.BUILDTIME, build.CONTRACTAPIVERSION, build.SCHEMAVERSION)
 
-	chaincode, err := contractapi.NewChaincode(&SmartContract{})
-
+	chaincode, err := newChaincode()
 	if err != nil {
 		log.Panicf("error create asset-transfer-basic chaincode: %s", err)
 	}
 
+	var cc shim.Chaincode = chaincode
+	if config.MetricsAddress != "" {
+		metrics := newChaincodeMetrics()
+		serveMetrics(config.MetricsAddress, metrics)
+		cc = &metricsChaincode{Chaincode: chaincode, metrics: metrics}
+	}
+
+	if config.LocalAddress != "" {
+		ledger, err := newLocalLedger(cc, config.LocalFile)
+		if err != nil {
+			log.Panicf("error opening local ledger: %s", err)
+		}
+		if err := serveLocalLedger(config.LocalAddress, ledger); err != nil {
+			log.Panicf("error serving local ledger: %s", err)
+		}
+		return
+	}
+
 	server := &shim.ChaincodeServer{
 		CCID:     config.CCID,
 		Address:  config.Address,
-		CC:       chaincode,
+		CC:       cc,
 		TLSProps: getTLSProperties(),
 	}
 
@@ -0,0 +1,64 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// transientExpectedVersionKey is the transient map entry carrying the version
+// the caller expects the asset to have, such as from the If-Match header of a
+// REST request.
+const transientExpectedVersionKey = "expected_version"
+
+// conditionalFunctions are the transaction functions changing the asset named
+// by their first argument, which the caller may make conditional on its version.
+var conditionalFunctions = map[string]bool{
+	"DeleteAsset":   true,
+	"PatchAsset":    true,
+	"TransferAsset": true,
+	"UpdateAsset":   true,
+}
+
+func init() {
+	RegisterHook(HookBefore, versionHook)
+}
+
+// versionHook rejects a conditional function with a VERSION_CONFLICT error when
+// the asset does not have the version expected by the caller. The asset read by
+// the check is part of the read set of the transaction, so a concurrent change
+// committed before it invalidates the transaction as an MVCC read conflict.
+func versionHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	if !conditionalFunctions[call.FUNCTION] || len(call.ARGS) == 0 {
+		return nil
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+	expectedJSON, ok := transientMap[transientExpectedVersionKey]
+	if !ok {
+		return nil
+	}
+	expected, err := strconv.ParseInt(string(expectedJSON), 10, 64)
+	if err != nil {
+		return businessError(errCodeInvalidArgument, "invalid %s %q", transientExpectedVersionKey, expectedJSON)
+	}
+
+	asset, err := readAssetSummary(ctx, call.ARGS[0])
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return assetNotFoundError(call.ARGS[0])
+	}
+	if asset.VERSION != expected {
+		return businessError(errCodeVersionConflict, "the asset %s has version %d, not %d", asset.ID, asset.VERSION, expected)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// oracleObjectType is the composite key prefix of the oracle configuration,
+// shared by every tenant like the system state.
+const oracleObjectType = "oracle"
+
+// attestationObjectType is the composite key prefix of the attestations, keyed
+// by reference. Attestations state facts of the outside world and are shared
+// by every tenant.
+const attestationObjectType = "attestation"
+
+// attestationRequirementObjectType is the composite key prefix of the
+// attestations required to transfer an asset, keyed by asset and reference.
+const attestationRequirementObjectType = "attestationreq"
+
+// maxAttestationRefLength is the longest attestation reference accepted.
+const maxAttestationRefLength = 128
+
+// OracleKey is the public key verifying the signatures of attestations.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type OracleKey struct {
+	PUBLICKEY string `json:"publickey"`
+	UPDATEDAT string `json:"updatedat"`
+	UPDATEDBY string `json:"updatedby"`
+}
+
+// Attestation is a statement of the oracle, such as that the goods of an order
+// were delivered, signed with the key of the OracleKey.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type Attestation struct {
+	PAYLOAD     string `json:"payload"`
+	REF         string `json:"ref"`
+	SIGNATURE   string `json:"signature"`
+	SUBMITTEDAT string `json:"submittedat"`
+	SUBMITTEDBY string `json:"submittedby"`
+	TXID        string `json:"txid"`
+}
+
+// AttestationRequirement holds back the transfer of ASSETID until the
+// attestation REF is submitted.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AttestationRequirement struct {
+	ASSETID    string `json:"assetid"`
+	REF        string `json:"ref"`
+	REQUIREDAT string `json:"requiredat"`
+	REQUIREDBY string `json:"requiredby"`
+}
+
+// SetOracleKey sets the public key of the oracle, a PEM encoded PKIX ECDSA or
+// Ed25519 key. Attestations already submitted stay valid. Only admins may call it.
+func (s *SmartContract) SetOracleKey(ctx contractapi.TransactionContextInterface, publicKeyPEM string) (*OracleKey, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := parseOracleKey(publicKeyPEM); err != nil {
+		return nil, err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	oracleKey := &OracleKey{
+		PUBLICKEY: publicKeyPEM,
+		UPDATEDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		UPDATEDBY: clientID,
+	}
+	oracleKeyJSON, err := json.Marshal(oracleKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stub := sharedStub(ctx)
+	key, err := stub.CreateCompositeKey(oracleObjectType, []string{"key"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = stub.PutState(key, oracleKeyJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return oracleKey, nil
+}
+
+// GetOracleKey returns the public key of the oracle, nil when none is set.
+func (s *SmartContract) GetOracleKey(ctx contractapi.TransactionContextInterface) (*OracleKey, error) {
+	return readOracleKey(sharedStub(ctx))
+}
+
+// SubmitAttestation records the attestation ref of the oracle. signature is the
+// base64 encoded signature of ref, a line feed and payload by the oracle key;
+// ECDSA signatures are ASN.1 encoded and sign the SHA-256 digest. Anyone may
+// submit an attestation, as the signature authenticates it, but a reference is
+// attested only once.
+func (s *SmartContract) SubmitAttestation(ctx contractapi.TransactionContextInterface, ref string, payload string, signature string) (*Attestation, error) {
+	if err := validateAttestationRef(ref); err != nil {
+		return nil, err
+	}
+
+	stub := sharedStub(ctx)
+	oracleKey, err := readOracleKey(stub)
+	if err != nil {
+		return nil, err
+	}
+	if oracleKey == nil {
+		return nil, businessError(errCodeInvalidArgument, "no oracle key is set")
+	}
+	publicKey, err := parseOracleKey(oracleKey.PUBLICKEY)
+	if err != nil {
+		return nil, err
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, businessError(errCodeInvalidArgument, "the signature must be base64 encoded: %v", err)
+	}
+	if !verifyOracleSignature(publicKey, []byte(ref+"\n"+payload), signatureBytes) {
+		return nil, businessError(errCodeForbidden, "the signature of attestation %s does not verify with the oracle key", ref)
+	}
+
+	existing, err := readAttestation(stub, ref)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, businessError(errCodeAssetExists, "the attestation %s was submitted in transaction %s", ref, existing.TXID)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	attestation := &Attestation{
+		PAYLOAD:     payload,
+		REF:         ref,
+		SIGNATURE:   signature,
+		SUBMITTEDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		SUBMITTEDBY: clientID,
+		TXID:        ctx.GetStub().GetTxID(),
+	}
+	attestationJSON, err := json.Marshal(attestation)
+	if err != nil {
+		return nil, err
+	}
+	key, err := stub.CreateCompositeKey(attestationObjectType, []string{ref})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = stub.PutState(key, attestationJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	err = ctx.GetStub().SetEvent("AttestationSubmitted", attestationJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+	return attestation, nil
+}
+
+// GetAttestation returns the attestation ref, nil when it was not submitted.
+func (s *SmartContract) GetAttestation(ctx contractapi.TransactionContextInterface, ref string) (*Attestation, error) {
+	return readAttestation(sharedStub(ctx), ref)
+}
+
+// RequireAttestation holds back the transfer of an asset until the attestation
+// ref is submitted, whether by TransferAsset, SwapAssets or an UpdateAsset
+// changing its dealer. The transfer consumes the requirement. Only admins may
+// call it.
+func (s *SmartContract) RequireAttestation(ctx contractapi.TransactionContextInterface, id string, ref string) (*AttestationRequirement, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateAttestationRef(ref); err != nil {
+		return nil, err
+	}
+	if _, err := s.ReadAsset(ctx, id); err != nil {
+		return nil, err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	requirement := &AttestationRequirement{
+		ASSETID:    id,
+		REF:        ref,
+		REQUIREDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		REQUIREDBY: clientID,
+	}
+	requirementJSON, err := json.Marshal(requirement)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(attestationRequirementObjectType, []string{id, ref})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, requirementJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return requirement, nil
+}
+
+// RemoveAttestationRequirement no longer holds back the transfer of an asset
+// for the attestation ref. Only admins may call it.
+func (s *SmartContract) RemoveAttestationRequirement(ctx contractapi.TransactionContextInterface, id string, ref string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(attestationRequirementObjectType, []string{id, ref})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	requirementJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if requirementJSON == nil {
+		return businessError(errCodeInvalidArgument, "the transfer of asset %s does not require attestation %s", id, ref)
+	}
+	err = ctx.GetStub().DelState(key)
+	if err != nil {
+		return fmt.Errorf("failed to delete from world state: %v", err)
+	}
+	return nil
+}
+
+// GetAttestationRequirements returns the attestations the transfer of an asset
+// awaits, in order of their references.
+func (s *SmartContract) GetAttestationRequirements(ctx contractapi.TransactionContextInterface, id string) ([]*AttestationRequirement, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(attestationRequirementObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	requirements := []*AttestationRequirement{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var requirement AttestationRequirement
+		err = json.Unmarshal(queryResponse.Value, &requirement)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, &requirement)
+	}
+
+	return requirements, nil
+}
+
+// claimAttestations fails with FORBIDDEN unless every attestation required to
+// transfer the asset with given id was submitted, and removes the requirements
+// the transfer meets.
+func (s *SmartContract) claimAttestations(ctx contractapi.TransactionContextInterface, id string) error {
+	requirements, err := s.GetAttestationRequirements(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, requirement := range requirements {
+		attestation, err := readAttestation(sharedStub(ctx), requirement.REF)
+		if err != nil {
+			return err
+		}
+		if attestation == nil {
+			missing = append(missing, requirement.REF)
+		}
+	}
+	if len(missing) > 0 {
+		return businessError(errCodeForbidden, "the transfer of asset %s awaits the attestations %s", id, strings.Join(missing, ", "))
+	}
+
+	for _, requirement := range requirements {
+		key, err := ctx.GetStub().CreateCompositeKey(attestationRequirementObjectType, []string{id, requirement.REF})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(key)
+		if err != nil {
+			return fmt.Errorf("failed to delete from world state: %v", err)
+		}
+	}
+	return nil
+}
+
+func readOracleKey(stub shim.ChaincodeStubInterface) (*OracleKey, error) {
+	key, err := stub.CreateCompositeKey(oracleObjectType, []string{"key"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	oracleKeyJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if oracleKeyJSON == nil {
+		return nil, nil
+	}
+
+	var oracleKey OracleKey
+	err = json.Unmarshal(oracleKeyJSON, &oracleKey)
+	if err != nil {
+		return nil, err
+	}
+	return &oracleKey, nil
+}
+
+func readAttestation(stub shim.ChaincodeStubInterface, ref string) (*Attestation, error) {
+	key, err := stub.CreateCompositeKey(attestationObjectType, []string{ref})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	attestationJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if attestationJSON == nil {
+		return nil, nil
+	}
+
+	var attestation Attestation
+	err = json.Unmarshal(attestationJSON, &attestation)
+	if err != nil {
+		return nil, err
+	}
+	return &attestation, nil
+}
+
+// validateAttestationRef rejects empty references, references longer than
+// maxAttestationRefLength and references holding control characters, which
+// would make the signed message ambiguous.
+func validateAttestationRef(ref string) error {
+	if ref == "" || len(ref) > maxAttestationRefLength {
+		return businessError(errCodeInvalidArgument, "the attestation reference must be 1 to %d bytes long", maxAttestationRefLength)
+	}
+	if strings.IndexFunc(ref, unicode.IsControl) >= 0 {
+		return businessError(errCodeInvalidArgument, "the attestation reference %q holds a control character", ref)
+	}
+	return nil
+}
+
+// parseOracleKey parses a PEM encoded PKIX public key, which must be an ECDSA
+// or Ed25519 key.
+func parseOracleKey(publicKeyPEM string) (any, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, businessError(errCodeInvalidArgument, "the oracle key must be PEM encoded")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, businessError(errCodeInvalidArgument, "failed to parse the oracle key: %v", err)
+	}
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return publicKey, nil
+	default:
+		return nil, businessError(errCodeInvalidArgument, "the oracle key must be an ECDSA or Ed25519 key, not %T", publicKey)
+	}
+}
+
+// verifyOracleSignature reports whether signature signs message with publicKey.
+func verifyOracleSignature(publicKey any, message []byte, signature []byte) bool {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, signature)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestAttestationsHoldBackEveryDealerChange(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(dealerID string, function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: dealerID == "", DealerID: dealerID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(dealerID string, function string, args ...string) []byte {
+		t.Helper()
+		response := call(dealerID, function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	expectAwaiting := func(response *localResponse, change string) {
+		t.Helper()
+		if response.Status == shim.OK || !strings.Contains(response.Message, errCodeForbidden) || !strings.Contains(response.Message, "delivery-1") {
+			t.Errorf("expected %s to await the attestation, got status %d: %s", change, response.Status, response.Message)
+		}
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(ref string, payload string) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(ref+"\n"+payload)))
+	}
+
+	invoke("", "InitLedger")
+	invoke("", "SetOracleKey", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})))
+	invoke("", "RequireAttestation", "asset1", "delivery-1")
+
+	expectAwaiting(call("", "TransferAsset", "asset1", "DEALER102"), "a transfer")
+	expectAwaiting(call("", "UpdateAsset", "asset1", "DEALER102", "100000", "ACTIVE", "0", "TRANSFER"), "an update changing the dealer")
+	invoke("", "UpdateAsset", "asset1", "DEALER101", "100000", "ACTIVE", "0", "REVIEW")
+	invoke("DEALER101", "ConsentToSwap", "asset1", "asset2", "0")
+	invoke("DEALER102", "ConsentToSwap", "asset2", "asset1", "0")
+	expectAwaiting(call("DEALER101", "SwapAssets", "asset1", "asset2"), "a swap")
+
+	if response := call("", "SubmitAttestation", "delivery-1", "delivered", sign("delivery-1", "tampered")); response.Status == shim.OK {
+		t.Error("expected an attestation with a signature of another payload to be rejected")
+	}
+	invoke("", "SubmitAttestation", "delivery-1", "delivered", sign("delivery-1", "delivered"))
+	var result SwapResult
+	if err := json.Unmarshal(invoke("DEALER101", "SwapAssets", "asset1", "asset2"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ASSETA.DEALERID != "DEALER102" {
+		t.Errorf("expected the attested asset to be swapped to DEALER102, got %s", result.ASSETA.DEALERID)
+	}
+	var requirements []*AttestationRequirement
+	if err := json.Unmarshal(invoke("", "GetAttestationRequirements", "asset1"), &requirements); err != nil {
+		t.Fatal(err)
+	}
+	if len(requirements) != 0 {
+		t.Errorf("expected the swap to consume the requirement, got %+v", requirements)
+	}
+}
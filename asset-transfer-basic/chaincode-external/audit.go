@@ -0,0 +1,244 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// auditObjectType is the composite key prefix of audit records, keyed by asset and transaction.
+const auditObjectType = "audit"
+
+// transientAPIUserKey is the transient map entry naming the end user on whose
+// behalf an application submitted the transaction.
+const transientAPIUserKey = "api_user"
+
+// auditTimeLayout is a fixed width UTC layout, so audit timestamps sort lexicographically.
+const auditTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// maxAuditKeys bounds the key names listed in the read/write set summary of an
+// audit record. The counts still cover every key.
+const maxAuditKeys = 64
+
+// auditedFunctions maps the transaction functions audited by auditHook to the
+// function returning the id of the asset they change from their arguments.
+// DeleteAssetsByDealer, MergeAssets, SplitAsset and SwapAssets change assets
+// not named by a single argument and audit them themselves. ArchiveAsset is not
+// audited, as it moves the audit records out of the world state.
+var auditedFunctions = map[string]func(args []string) (string, error){
+	"CreateAsset":          firstArg,
+	"UpdateAsset":          firstArg,
+	"PatchAsset":           firstArg,
+	"DeleteAsset":          firstArg,
+	"TransferAsset":        firstArg,
+	"ExportAssetProof":     firstArg,
+	"ImportAssetFromProof": proofAssetID,
+	"RequestMPINReset":     firstArg,
+	"ApproveMPINReset":     firstArg,
+	"CompleteMPINReset":    firstArg,
+	"ArchiveRestore":       firstArg,
+}
+
+func init() {
+	RegisterHook(HookAfter, auditHook)
+}
+
+// AuditRecord describes a state change made to an asset and who made it.
+// CLIENTID and MSPID identify the submitting Fabric identity, APIUSER the end
+// user of the application that submitted on their behalf, if any. RWSET
+// summarizes the keys the transaction touched; records written before it was
+// introduced have none.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AuditRecord struct {
+	ACTION    string        `json:"action"`
+	APIUSER   string        `json:"apiuser"`
+	ASSETID   string        `json:"assetid"`
+	CLIENTID  string        `json:"clientid"`
+	MSPID     string        `json:"mspid"`
+	RWSET     *RWSetSummary `json:"rwset,omitempty" metadata:",optional"`
+	TIMESTAMP string        `json:"timestamp"`
+	TXID      string        `json:"txid"`
+}
+
+// RWSetSummary names the keys a transaction read and wrote up to the point it
+// was audited, without their values. Composite keys are shown as their object
+// type and attributes joined by ~, and private data keys as the collection and
+// the SHA-256 of the key, as on the ledger. READCOUNT and WRITECOUNT count the
+// distinct keys, of which at most maxAuditKeys are listed, sorted.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type RWSetSummary struct {
+	READCOUNT  int      `json:"readcount"`
+	READS      []string `json:"reads"`
+	WRITECOUNT int      `json:"writecount"`
+	WRITES     []string `json:"writes"`
+}
+
+// GetAuditTrail returns the audit records of the asset with given id, oldest first.
+func (s *SmartContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, id string) ([]*AuditRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(auditObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var records []*AuditRecord
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record AuditRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+
+	sortAuditRecords(records)
+	return records, nil
+}
+
+// auditHook records an audit entry for the asset changed by an audited
+// transaction. Transactions that left the asset unchanged are not audited.
+func auditHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	assetID, ok := auditedFunctions[call.FUNCTION]
+	if !ok || isUnchanged(ctx) {
+		return nil
+	}
+
+	id, err := assetID(call.ARGS)
+	if err != nil {
+		return err
+	}
+	return recordAudit(ctx, id, call.FUNCTION)
+}
+
+// recordAudit writes an audit record of action on the asset with given id.
+func recordAudit(ctx contractapi.TransactionContextInterface, id string, action string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	record := AuditRecord{
+		ACTION:    action,
+		APIUSER:   string(transientMap[transientAPIUserKey]),
+		ASSETID:   id,
+		CLIENTID:  clientID,
+		MSPID:     mspID,
+		RWSET:     rwSetSummary(ctx),
+		TIMESTAMP: timestamp.AsTime().UTC().Format(auditTimeLayout),
+		TXID:      ctx.GetStub().GetTxID(),
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	auditKey, err := ctx.GetStub().CreateCompositeKey(auditObjectType, []string{id, record.TXID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(auditKey, recordJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return nil
+}
+
+// rwSetSummary summarizes the keys read and written so far by the transaction
+// of ctx, or returns nil when its stub does not collect them. The sharded
+// counters and the usage record are written after the audit record and are
+// not part of it.
+func rwSetSummary(ctx contractapi.TransactionContextInterface) *RWSetSummary {
+	metered, ok := ctx.(*meteredContext)
+	if !ok {
+		return nil
+	}
+	reads := auditKeyNames(metered.stub.readKeys)
+	writes := auditKeyNames(metered.stub.writtenKeys)
+	return &RWSetSummary{
+		READCOUNT:  len(reads),
+		READS:      reads[:min(len(reads), maxAuditKeys)],
+		WRITECOUNT: len(writes),
+		WRITES:     writes[:min(len(writes), maxAuditKeys)],
+	}
+}
+
+// auditKeyNames returns the display names of keys, sorted.
+func auditKeyNames(keys map[string]struct{}) []string {
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, auditKeyName(key))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// auditKeyName returns a composite key as its object type and attributes
+// joined by ~, and any other key unchanged.
+func auditKeyName(key string) string {
+	if !strings.HasPrefix(key, "\x00") || !strings.HasSuffix(key, "\x00") || len(key) < 2 {
+		return key
+	}
+	return strings.Join(strings.Split(key[1:len(key)-1], "\x00"), "~")
+}
+
+// privateKeyName names a key of a private data collection by its hash, as the
+// audit records are public.
+func privateKeyName(collection string, key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return collection + ":" + hex.EncodeToString(hash[:])
+}
+
+// sortAuditRecords orders records by timestamp, as the composite keys sort by transaction id.
+func sortAuditRecords(records []*AuditRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].TIMESTAMP < records[j].TIMESTAMP
+	})
+}
+
+func firstArg(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("missing asset id argument")
+	}
+	return args[0], nil
+}
+
+// proofAssetID returns the id of the asset in the proof argument of ImportAssetFromProof.
+func proofAssetID(args []string) (string, error) {
+	if len(args) < 2 {
+		return "", fmt.Errorf("missing proof argument")
+	}
+
+	var proof AssetProof
+	err := json.Unmarshal([]byte(args[1]), &proof)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal proof: %v", err)
+	}
+	return proof.ASSET.ID, nil
+}
@@ -0,0 +1,99 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// contractAPIModule is the module of the contract API, whose version is read
+// from the build information of the binary.
+const contractAPIModule = "github.com/hyperledger/fabric-contract-api-go/v2"
+
+// stateSchemaVersion is the version of the layout of the world state written by
+// the contract, the newest of testdata/upgrade. It changes only with a layout
+// needing a migration, not with fields added compatibly.
+const stateSchemaVersion = 2
+
+// buildCommit and buildTime describe the build of the binary, set with
+//
+//	go build -ldflags "-X main.buildCommit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When left empty, the commit and its time are read from the version control
+// information Go stamps into binaries built within a git checkout.
+var (
+	buildCommit string
+	buildTime   string
+)
+
+// testContracts are the contracts served next to SmartContract in test builds,
+// registered by the files of the testhooks build tag.
+var testContracts []contractapi.ContractInterface
+
+// VersionInfo describes the chaincode build serving a peer.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type VersionInfo struct {
+	BUILDTIME          string `json:"buildtime"`
+	CONTRACTAPIVERSION string `json:"contractapiversion"`
+	GITCOMMIT          string `json:"gitcommit"`
+	GOVERSION          string `json:"goversion"`
+	MODIFIED           bool   `json:"modified,omitempty" metadata:",optional"`
+	PACKAGEID          string `json:"packageid,omitempty" metadata:",optional"`
+	SCHEMAVERSION      int    `json:"schemaversion"`
+	TESTHOOKS          bool   `json:"testhooks,omitempty" metadata:",optional"`
+}
+
+// GetVersionInfo returns the build of the chaincode server answering, so
+// operators can confirm which build each peer runs. Evaluate it on every peer:
+// the answer differs between peers running different builds.
+func (s *SmartContract) GetVersionInfo(ctx contractapi.TransactionContextInterface) (*VersionInfo, error) {
+	return versionInfo(), nil
+}
+
+// versionInfo returns the build of the binary, "unknown" for what the build
+// did not record.
+func versionInfo() *VersionInfo {
+	info := &VersionInfo{
+		BUILDTIME:          buildTime,
+		CONTRACTAPIVERSION: "unknown",
+		GITCOMMIT:          buildCommit,
+		GOVERSION:          runtime.Version(),
+		PACKAGEID:          os.Getenv("CHAINCODE_ID"),
+		SCHEMAVERSION:      stateSchemaVersion,
+		TESTHOOKS:          len(testContracts) > 0,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range build.Deps {
+			if dep.Path == contractAPIModule {
+				info.CONTRACTAPIVERSION = dep.Version
+			}
+		}
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.GITCOMMIT == "" {
+					info.GITCOMMIT = setting.Value
+				}
+			case "vcs.time":
+				if info.BUILDTIME == "" {
+					info.BUILDTIME = setting.Value
+				}
+			case "vcs.modified":
+				info.MODIFIED = setting.Value == "true"
+			}
+		}
+	}
+	if info.GITCOMMIT == "" {
+		info.GITCOMMIT = "unknown"
+	}
+	if info.BUILDTIME == "" {
+		info.BUILDTIME = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,296 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// counterObjectType is the composite key prefix of counter shards, keyed by
+// counter name, scope and shard.
+const counterObjectType = "counter"
+
+// counterShards is the number of keys every counter is spread over. A
+// transaction updates the shard picked by its transaction ID, so concurrent
+// transactions rarely write the same key and fail with an MVCC read conflict.
+// Reading a counter sums its shards.
+const counterShards = 16
+
+// Counters of the asset aggregates. The dealer counters are scoped by dealer,
+// the others by the empty scope. Soft-deleted assets are not counted. The
+// dealer volume accumulates the balance changes of the assets of a dealer, and
+// unlike the others cannot be rebuilt from the world state.
+const (
+	counterAssetCount    = "assets"
+	counterTotalSupply   = "supply"
+	counterDealerAssets  = "dealerassets"
+	counterDealerBalance = "dealerbalance"
+	counterDealerVolume  = "dealervolume"
+)
+
+func init() {
+	RegisterHook(HookAfter, counterHook)
+}
+
+// Totals aggregates the assets of a dealer, or of the whole ledger when DEALERID
+// is empty.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type Totals struct {
+	ASSETS   int     `json:"assets"`
+	BALANCE  float64 `json:"balance"`
+	DEALERID string  `json:"dealerid"`
+}
+
+// GetTotals returns the number of assets and their total balance, of a dealer
+// or, with an empty dealerID, of the whole ledger.
+func (s *SmartContract) GetTotals(ctx contractapi.TransactionContextInterface, dealerID string) (*Totals, error) {
+	countName, balanceName := counterAssetCount, counterTotalSupply
+	if dealerID != "" {
+		countName, balanceName = counterDealerAssets, counterDealerBalance
+	}
+
+	count, err := readCounter(ctx, countName, dealerID)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := readCounter(ctx, balanceName, dealerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Totals{ASSETS: int(count), BALANCE: balance, DEALERID: dealerID}, nil
+}
+
+// RebuildTotals recomputes the counters of the asset aggregates from the assets
+// in the world state, for ledgers holding assets written before the counters
+// were introduced. The dealer volumes are kept. Only admins may call it.
+func (s *SmartContract) RebuildTotals(ctx contractapi.TransactionContextInterface) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	counters := make(map[string]float64)
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return err
+		}
+		for key, delta := range assetCounterDeltas(nil, &asset) {
+			counters[key] += delta
+		}
+	}
+
+	// the rebuilt values are written to shard 0 and every other shard is removed
+	shardsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(counterObjectType, []string{})
+	if err != nil {
+		return err
+	}
+	defer shardsIterator.Close()
+
+	for shardsIterator.HasNext() {
+		queryResponse, err := shardsIterator.Next()
+		if err != nil {
+			return err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if attributes[0] == counterDealerVolume {
+			continue
+		}
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete counter shard: %v", err)
+		}
+	}
+
+	for _, key := range sortedCounterKeys(counters) {
+		name, scope := splitCounterKey(key)
+		shardKey, err := ctx.GetStub().CreateCompositeKey(counterObjectType, []string{name, scope, "0"})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PutState(shardKey, []byte(strconv.FormatFloat(counters[key], 'f', -1, 64)))
+		if err != nil {
+			return fmt.Errorf("failed to put to world state: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// updateAssetCounters records the changes of the counters caused by replacing
+// the asset previous with current, either of which is nil when the asset is
+// created or deleted.
+func updateAssetCounters(ctx contractapi.TransactionContextInterface, previous *Asset, current *Asset) error {
+	for key, delta := range assetCounterDeltas(previous, current) {
+		name, scope := splitCounterKey(key)
+		if err := addToCounter(ctx, name, scope, delta); err != nil {
+			return err
+		}
+	}
+
+	// the volume counts the credits and debits of the asset with its current
+	// dealer, a transfer to another dealer moves no funds
+	if current == nil || current.STATUS == statusDeleted {
+		return nil
+	}
+	var previousBalance float64
+	if previous != nil {
+		previousBalance = previous.BALANCE
+	}
+	return addToCounter(ctx, counterDealerVolume, current.DEALERID, math.Abs(current.BALANCE-previousBalance))
+}
+
+// assetCounterDeltas returns the changes of the counters, keyed by counterKey,
+// caused by replacing the asset previous with current.
+func assetCounterDeltas(previous *Asset, current *Asset) map[string]float64 {
+	deltas := make(map[string]float64)
+	apply := func(asset *Asset, sign float64) {
+		if asset == nil || asset.STATUS == statusDeleted {
+			return
+		}
+		deltas[counterKey(counterAssetCount, "")] += sign
+		deltas[counterKey(counterTotalSupply, "")] += sign * asset.BALANCE
+		deltas[counterKey(counterDealerAssets, asset.DEALERID)] += sign
+		deltas[counterKey(counterDealerBalance, asset.DEALERID)] += sign * asset.BALANCE
+	}
+	apply(previous, -1)
+	apply(current, 1)
+	return deltas
+}
+
+// addToCounter adds delta to the named counter of scope. Within a transaction
+// the changes are collected and written once by counterHook, since a
+// transaction does not read its own writes.
+func addToCounter(ctx contractapi.TransactionContextInterface, name string, scope string, delta float64) error {
+	if delta == 0 {
+		return nil
+	}
+	metered, ok := ctx.(*meteredContext)
+	if !ok {
+		return writeCounterDelta(ctx, counterKey(name, scope), delta)
+	}
+	if metered.counterDeltas == nil {
+		metered.counterDeltas = make(map[string]float64)
+	}
+	metered.counterDeltas[counterKey(name, scope)] += delta
+	return nil
+}
+
+// counterHook writes the counter changes collected during the transaction.
+func counterHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	metered, ok := ctx.(*meteredContext)
+	if !ok {
+		return nil
+	}
+	for _, key := range sortedCounterKeys(metered.counterDeltas) {
+		if err := writeCounterDelta(ctx, key, metered.counterDeltas[key]); err != nil {
+			return err
+		}
+	}
+	metered.counterDeltas = nil
+	return nil
+}
+
+// writeCounterDelta adds delta to the shard of the counter picked by the transaction ID.
+func writeCounterDelta(ctx contractapi.TransactionContextInterface, key string, delta float64) error {
+	if delta == 0 {
+		return nil
+	}
+	txHash := fnv.New32a()
+	txHash.Write([]byte(ctx.GetStub().GetTxID()))
+	shard := strconv.Itoa(int(txHash.Sum32() % counterShards))
+
+	name, scope := splitCounterKey(key)
+	shardKey, err := ctx.GetStub().CreateCompositeKey(counterObjectType, []string{name, scope, shard})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	valueBytes, err := ctx.GetStub().GetState(shardKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	var value float64
+	if valueBytes != nil {
+		value, err = strconv.ParseFloat(string(valueBytes), 64)
+		if err != nil {
+			return fmt.Errorf("invalid counter shard %s/%s/%s: %v", name, scope, shard, err)
+		}
+	}
+	err = ctx.GetStub().PutState(shardKey, []byte(strconv.FormatFloat(value+delta, 'f', -1, 64)))
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return nil
+}
+
+// readCounter returns the value of the named counter of scope, the sum of its shards.
+func readCounter(ctx contractapi.TransactionContextInterface, name string, scope string) (float64, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(counterObjectType, []string{name, scope})
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	var total float64
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		value, err := strconv.ParseFloat(string(queryResponse.Value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid counter shard of %s: %v", name, err)
+		}
+		total += value
+	}
+
+	return total, nil
+}
+
+// counterKey identifies a counter of scope among the collected changes.
+func counterKey(name string, scope string) string {
+	return name + "\x00" + scope
+}
+
+func splitCounterKey(key string) (string, string) {
+	name, scope, _ := strings.Cut(key, "\x00")
+	return name, scope
+}
+
+// sortedCounterKeys returns the keys of counters in order, so that every peer
+// writes the shards in the same order.
+func sortedCounterKeys(counters map[string]float64) []string {
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
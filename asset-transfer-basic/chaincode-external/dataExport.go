@@ -0,0 +1,252 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// dataExportConsentObjectType is the composite key prefix of the data export
+// consents, keyed by the asset and the organization it may be exported to.
+const dataExportConsentObjectType = "exportconsent"
+
+// dataExportConsentKind is the expiry kind of the data export consents.
+const dataExportConsentKind = "exportconsent"
+
+// maxDataExportConsentValidity is the longest a data export consent is valid,
+// the term of the data-sharing agreements between member organizations.
+const maxDataExportConsentValidity = 365 * 24 * time.Hour
+
+// DataExportConsent is the consent to export the full data of ASSETID to the
+// members of the organization REQUESTINGORG until EXPIRESAT. It lapses when the
+// asset changes dealer.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DataExportConsent struct {
+	ASSETID       string `json:"assetid"`
+	DEALERID      string `json:"dealerid"`
+	EXPIRESAT     string `json:"expiresat"`
+	GRANTEDAT     string `json:"grantedat"`
+	GRANTEDBY     string `json:"grantedby"`
+	REQUESTINGORG string `json:"requestingorg"`
+}
+
+// AssetExport is the full data of an asset exported to another organization,
+// with the consent allowing it.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type AssetExport struct {
+	ASSET      *Asset             `json:"asset"`
+	CONSENT    *DataExportConsent `json:"consent"`
+	DETAILS    *AssetDetails      `json:"details"`
+	EXPORTEDAT string             `json:"exportedat"`
+}
+
+func init() {
+	expiryReleasers[dataExportConsentKind] = releaseDataExportConsent
+}
+
+// GrantDataExport records the consent to export the full data of an asset to
+// the members of requestingOrg, an MSP ID, until expiry, an RFC 3339 time at
+// most a year away. Only the dealer owning the asset, named by the dealerid
+// attribute of the caller's certificate, and admins may grant it. Granting the
+// consent again renews it.
+func (s *SmartContract) GrantDataExport(ctx contractapi.TransactionContextInterface, assetID string, requestingOrg string, expiry string) (*DataExportConsent, error) {
+	if requestingOrg == "" {
+		return nil, businessError(errCodeInvalidArgument, "the requesting organization must be set")
+	}
+	expiresAt, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return nil, businessError(errCodeInvalidArgument, "the expiry %q is not an RFC 3339 time", expiry)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+	if !expiresAt.After(now) || expiresAt.Sub(now) > maxDataExportConsentValidity {
+		return nil, businessError(errCodeInvalidArgument, "a data export consent must expire within %s, not at %s", maxDataExportConsentValidity, expiry)
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if requireAdmin(ctx) != nil {
+		if err := requireDealer(ctx, asset.DEALERID); err != nil {
+			return nil, err
+		}
+	}
+
+	// a renewed consent replaces the previous one and its expiry
+	if err := deleteDataExportConsent(ctx, assetID, requestingOrg); err != nil {
+		return nil, err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	consent := &DataExportConsent{
+		ASSETID:       assetID,
+		DEALERID:      asset.DEALERID,
+		EXPIRESAT:     expiresAt.UTC().Format(expiryTimeLayout),
+		GRANTEDAT:     now.Format(time.RFC3339),
+		GRANTEDBY:     clientID,
+		REQUESTINGORG: requestingOrg,
+	}
+
+	key, err := dataExportConsentKey(ctx, assetID, requestingOrg)
+	if err != nil {
+		return nil, err
+	}
+	consentJSON, err := json.Marshal(consent)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(key, consentJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return consent, registerExpiry(ctx, dataExportConsentKind, []string{assetID, requestingOrg}, expiresAt)
+}
+
+// RevokeDataExport withdraws the consent to export the data of an asset to
+// requestingOrg. Only the dealer owning the asset and admins may revoke it.
+func (s *SmartContract) RevokeDataExport(ctx contractapi.TransactionContextInterface, assetID string, requestingOrg string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if requireAdmin(ctx) != nil {
+		if err := requireDealer(ctx, asset.DEALERID); err != nil {
+			return err
+		}
+	}
+	consent, err := readDataExportConsent(ctx, assetID, requestingOrg)
+	if err != nil {
+		return err
+	}
+	if consent == nil {
+		return businessError(errCodeInvalidArgument, "there is no consent to export %s to %s", assetID, requestingOrg)
+	}
+	return deleteDataExportConsent(ctx, assetID, requestingOrg)
+}
+
+// GetDataExportConsent returns the consent to export the data of an asset to
+// requestingOrg, or null when there is none. Expired consents are returned
+// until SweepExpired releases them.
+func (s *SmartContract) GetDataExportConsent(ctx contractapi.TransactionContextInterface, assetID string, requestingOrg string) (*DataExportConsent, error) {
+	return readDataExportConsent(ctx, assetID, requestingOrg)
+}
+
+// ExportAssetForOrg returns the full data of an asset, with the MSISDN of its
+// private details unmasked, to a member of an organization the dealer owning
+// the asset consented to export it to. The consent must not have expired and
+// must have been granted for the current dealer of the asset. The MPIN hash is
+// not exported. The private details are read from the collection holding them,
+// so the peer must be a member of it.
+func (s *SmartContract) ExportAssetForOrg(ctx contractapi.TransactionContextInterface, assetID string) (*AssetExport, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+
+	consent, err := readDataExportConsent(ctx, assetID, mspID)
+	if err != nil {
+		return nil, err
+	}
+	if consent == nil || consent.DEALERID != asset.DEALERID || consent.EXPIRESAT <= now.Format(expiryTimeLayout) {
+		return nil, businessError(errCodeForbidden, "there is no valid consent to export asset %s to %s", assetID, mspID)
+	}
+
+	details, err := readAssetDetails(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	details.MPINHASH = ""
+
+	return &AssetExport{
+		ASSET:      asset,
+		CONSENT:    consent,
+		DETAILS:    details,
+		EXPORTEDAT: now.Format(time.RFC3339),
+	}, nil
+}
+
+func dataExportConsentKey(ctx contractapi.TransactionContextInterface, assetID string, requestingOrg string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(dataExportConsentObjectType, []string{assetID, requestingOrg})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
+
+// readDataExportConsent returns the consent to export an asset to an
+// organization, or nil when there is none.
+func readDataExportConsent(ctx contractapi.TransactionContextInterface, assetID string, requestingOrg string) (*DataExportConsent, error) {
+	key, err := dataExportConsentKey(ctx, assetID, requestingOrg)
+	if err != nil {
+		return nil, err
+	}
+	consentJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if consentJSON == nil {
+		return nil, nil
+	}
+
+	var consent DataExportConsent
+	if err := json.Unmarshal(consentJSON, &consent); err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// deleteDataExportConsent deletes a consent and its expiry index entry, if any.
+func deleteDataExportConsent(ctx contractapi.TransactionContextInterface, assetID string, requestingOrg string) error {
+	consent, err := readDataExportConsent(ctx, assetID, requestingOrg)
+	if err != nil || consent == nil {
+		return err
+	}
+	key, err := dataExportConsentKey(ctx, assetID, requestingOrg)
+	if err != nil {
+		return err
+	}
+	expiresAt, err := time.Parse(expiryTimeLayout, consent.EXPIRESAT)
+	if err != nil {
+		return fmt.Errorf("malformed expiry %q of data export consent: %v", consent.EXPIRESAT, err)
+	}
+	if err := unregisterExpiry(ctx, dataExportConsentKind, []string{assetID, requestingOrg}, expiresAt); err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// releaseDataExportConsent deletes an expired consent, of the asset ID and
+// requesting organization in id, for SweepExpired, which removes its expiry
+// index entry itself.
+func releaseDataExportConsent(ctx contractapi.TransactionContextInterface, id []string) error {
+	if len(id) != 2 {
+		return fmt.Errorf("malformed data export consent id %q", id)
+	}
+	key, err := dataExportConsentKey(ctx, id[0], id[1])
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
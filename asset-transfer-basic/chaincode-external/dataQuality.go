@@ -0,0 +1,199 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Data quality checks reported by RunDataQualityChecks.
+const (
+	checkDuplicateMSISDN = "duplicate-msisdn"
+	checkNegativeBalance = "negative-balance"
+	checkInvalidStatus   = "invalid-status"
+	checkMissingDetails  = "missing-details"
+	checkOrphanedIndex   = "orphaned-index"
+)
+
+// validStatuses lists the statuses an asset may have.
+var validStatuses = map[string]bool{
+	"ACTIVE":      true,
+	"INACTIVE":    true,
+	"SUSPENDED":   true,
+	statusClosed:  true,
+	statusDeleted: true,
+}
+
+// dataQualityIndexes lists the composite key indexes whose first attribute is
+// an asset id, checked for entries left behind by deleted assets.
+var dataQualityIndexes = []string{subscriptionObjectType}
+
+// DataQualityIssue describes one problem found in the ledger data.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DataQualityIssue struct {
+	ASSETID string `json:"assetid"`
+	CHECK   string `json:"check"`
+	DETAIL  string `json:"detail"`
+	KEY     string `json:"key"`
+}
+
+// DataQualityReport is one page of a data quality run. BOOKMARK resumes the run
+// with the next page and is empty once every asset and index was scanned.
+// Duplicate MSISDNs are reported within the page; MSISDNDIGESTS maps the SHA-256
+// of each MSISDN to the assets of the page holding it, so that duplicates spanning
+// pages can be found by merging the digests of every page.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DataQualityReport struct {
+	BOOKMARK      string              `json:"bookmark"`
+	ISSUES        []*DataQualityIssue `json:"issues"`
+	MSISDNDIGESTS map[string][]string `json:"msisdndigests"`
+	SCANNED       int                 `json:"scanned"`
+}
+
+// RunDataQualityChecks scans a page of assets, then of the asset indexes, and
+// reports duplicate MSISDNs, negative balances, invalid statuses, assets without
+// private details and orphaned index entries. It must be run on a peer of an
+// organization that is a member of the asset details collection.
+func (s *SmartContract) RunDataQualityChecks(ctx contractapi.TransactionContextInterface, pageSize int, bookmark string) (*DataQualityReport, error) {
+	if pageSize <= 0 {
+		return nil, businessError(errCodeInvalidArgument, "the page size must be positive")
+	}
+	phase, ledgerBookmark, err := parseDataQualityBookmark(bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DataQualityReport{ISSUES: []*DataQualityIssue{}, MSISDNDIGESTS: make(map[string][]string)}
+	var fetched int
+	if phase == 0 {
+		fetched, ledgerBookmark, err = checkAssetsPage(ctx, report, int32(pageSize), ledgerBookmark)
+	} else {
+		fetched, ledgerBookmark, err = checkIndexPage(ctx, report, dataQualityIndexes[phase-1], int32(pageSize), ledgerBookmark)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if fetched < pageSize || ledgerBookmark == "" {
+		phase, ledgerBookmark = phase+1, ""
+	}
+	if phase <= len(dataQualityIndexes) {
+		report.BOOKMARK = fmt.Sprintf("%d:%s", phase, ledgerBookmark)
+	}
+
+	return report, nil
+}
+
+// checkAssetsPage checks a page of assets and returns the number of assets read and the next bookmark.
+func checkAssetsPage(ctx contractapi.TransactionContextInterface, report *DataQualityReport, pageSize int32, bookmark string) (int, string, error) {
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", pageSize, bookmark)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+		report.SCANNED++
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return 0, "", err
+		}
+		if asset.BALANCE < 0 {
+			report.addIssue(asset.ID, asset.ID, checkNegativeBalance, fmt.Sprintf("balance is %.2f", asset.BALANCE))
+		}
+		if !validStatuses[asset.STATUS] {
+			report.addIssue(asset.ID, asset.ID, checkInvalidStatus, fmt.Sprintf("status %q is not valid", asset.STATUS))
+		}
+
+		detailsJSON, err := ctx.GetStub().GetPrivateData(detailsCollection(&asset), asset.ID)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to read asset details: %v", err)
+		}
+		if detailsJSON == nil {
+			report.addIssue(asset.ID, asset.ID, checkMissingDetails, "no private details are recorded")
+			continue
+		}
+		var details AssetDetails
+		err = json.Unmarshal(detailsJSON, &details)
+		if err != nil {
+			return 0, "", err
+		}
+		digest := sha256.Sum256([]byte(details.MSISDN))
+		msisdnDigest := hex.EncodeToString(digest[:])
+		if holders := report.MSISDNDIGESTS[msisdnDigest]; len(holders) > 0 {
+			report.addIssue(asset.ID, asset.ID, checkDuplicateMSISDN, fmt.Sprintf("MSISDN is also held by asset %s", holders[0]))
+		}
+		report.MSISDNDIGESTS[msisdnDigest] = append(report.MSISDNDIGESTS[msisdnDigest], asset.ID)
+	}
+
+	return int(metadata.GetFetchedRecordsCount()), metadata.GetBookmark(), nil
+}
+
+// checkIndexPage checks that the assets referenced by a page of index entries exist
+// and returns the number of entries read and the next bookmark.
+func checkIndexPage(ctx contractapi.TransactionContextInterface, report *DataQualityReport, objectType string, pageSize int32, bookmark string) (int, string, error) {
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(objectType, []string{}, pageSize, bookmark)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+		report.SCANNED++
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(attributes) == 0 {
+			continue
+		}
+		assetJSON, err := ctx.GetStub().GetState(attributes[0])
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if assetJSON == nil {
+			report.addIssue(attributes[0], strings.Join(append([]string{objectType}, attributes...), "~"), checkOrphanedIndex, fmt.Sprintf("%s entry refers to a missing asset", objectType))
+		}
+	}
+
+	return int(metadata.GetFetchedRecordsCount()), metadata.GetBookmark(), nil
+}
+
+func (r *DataQualityReport) addIssue(assetID string, key string, check string, detail string) {
+	r.ISSUES = append(r.ISSUES, &DataQualityIssue{ASSETID: assetID, CHECK: check, DETAIL: detail, KEY: key})
+}
+
+// parseDataQualityBookmark splits a report bookmark into the phase of the run,
+// 0 for assets then one per index, and the ledger bookmark within that phase.
+func parseDataQualityBookmark(bookmark string) (int, string, error) {
+	if bookmark == "" {
+		return 0, "", nil
+	}
+
+	phaseText, ledgerBookmark, found := strings.Cut(bookmark, ":")
+	phase, err := strconv.Atoi(phaseText)
+	if !found || err != nil || phase < 0 || phase > len(dataQualityIndexes) {
+		return 0, "", businessError(errCodeInvalidArgument, "invalid bookmark %q", bookmark)
+	}
+	return phase, ledgerBookmark, nil
+}
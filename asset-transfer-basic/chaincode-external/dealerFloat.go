@@ -0,0 +1,131 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// floatObjectType is the composite key prefix of the dealer float pools, keyed by dealer.
+const floatObjectType = "float"
+
+// DealerFloat is the pool of funds a dealer draws on to credit the wallets of its
+// retail customers. It is tracked separately from the balances of the wallets.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DealerFloat struct {
+	ALLOCATED float64 `json:"allocated"`
+	BALANCE   float64 `json:"balance"`
+	DEALERID  string  `json:"dealerid"`
+	RETURNED  float64 `json:"returned"`
+	UPDATEDAT string  `json:"updatedat"`
+}
+
+// AllocateFloat adds amount to the float of a dealer. Only admins may allocate
+// float.
+func (s *SmartContract) AllocateFloat(ctx contractapi.TransactionContextInterface, dealerID string, amount float64) (*DealerFloat, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	float, err := readDealerFloat(ctx, dealerID)
+	if err != nil {
+		return nil, err
+	}
+
+	float.BALANCE += amount
+	float.ALLOCATED += amount
+	return float, putDealerFloat(ctx, float)
+}
+
+// ReturnFloat takes amount back from the float of a dealer, failing when the
+// float holds less than that. Only admins may return float.
+func (s *SmartContract) ReturnFloat(ctx contractapi.TransactionContextInterface, dealerID string, amount float64) (*DealerFloat, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	float, err := readDealerFloat(ctx, dealerID)
+	if err != nil {
+		return nil, err
+	}
+	if float.BALANCE < amount {
+		return nil, insufficientFundsError("the float of dealer "+dealerID, float.BALANCE, amount)
+	}
+
+	float.BALANCE -= amount
+	float.RETURNED += amount
+	return float, putDealerFloat(ctx, float)
+}
+
+// GetDealerFloat returns the float of a dealer, with a zero balance when none was allocated.
+func (s *SmartContract) GetDealerFloat(ctx contractapi.TransactionContextInterface, dealerID string) (*DealerFloat, error) {
+	return readDealerFloat(ctx, dealerID)
+}
+
+// drawDownFloat takes the credit of a retail wallet from the float of its dealer.
+// Debits leave the float unchanged.
+func drawDownFloat(ctx contractapi.TransactionContextInterface, dealerID string, credit float64) error {
+	if credit <= 0 {
+		return nil
+	}
+
+	float, err := readDealerFloat(ctx, dealerID)
+	if err != nil {
+		return err
+	}
+	if float.BALANCE < credit {
+		return insufficientFundsError("the float of dealer "+dealerID, float.BALANCE, credit)
+	}
+
+	float.BALANCE -= credit
+	return putDealerFloat(ctx, float)
+}
+
+func readDealerFloat(ctx contractapi.TransactionContextInterface, dealerID string) (*DealerFloat, error) {
+	floatKey, err := ctx.GetStub().CreateCompositeKey(floatObjectType, []string{dealerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	floatJSON, err := ctx.GetStub().GetState(floatKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if floatJSON == nil {
+		return &DealerFloat{DEALERID: dealerID}, nil
+	}
+
+	var float DealerFloat
+	err = json.Unmarshal(floatJSON, &float)
+	if err != nil {
+		return nil, err
+	}
+
+	return &float, nil
+}
+
+func putDealerFloat(ctx contractapi.TransactionContextInterface, float *DealerFloat) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	float.UPDATEDAT = timestamp.AsTime().UTC().Format(time.RFC3339)
+
+	floatJSON, err := json.Marshal(float)
+	if err != nil {
+		return err
+	}
+	floatKey, err := ctx.GetStub().CreateCompositeKey(floatObjectType, []string{float.DEALERID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(floatKey, floatJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return nil
+}
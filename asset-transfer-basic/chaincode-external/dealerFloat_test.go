@@ -0,0 +1,51 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestFloatRequiresAdmin(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, function := range []string{"AllocateFloat", "ReturnFloat"} {
+		response, err := ledger.invoke(localRequest{Function: function, Args: []string{"DEALER101", "100"}, Submit: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status == shim.OK || !strings.Contains(response.Message, "not an admin") {
+			t.Errorf("expected %s to be refused to a client, got status %d: %s", function, response.Status, response.Message)
+		}
+	}
+
+	for _, function := range []string{"AllocateFloat", "ReturnFloat"} {
+		response, err := ledger.invoke(localRequest{Function: function, Args: []string{"DEALER101", "100"}, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status != shim.OK {
+			t.Errorf("expected %s to succeed for an admin, got status %d: %s", function, response.Status, response.Message)
+		}
+	}
+
+	response, err := ledger.invoke(localRequest{Function: "AllocateFloat", Args: []string{"DEALER101", "-100"}, Submit: true, Admin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status == shim.OK || !strings.Contains(response.Message, `"code":"`+errCodeInvalidArgument+`","message":"invalid AllocateFloat arguments: the amount must not be negative"`) {
+		t.Errorf("expected a negative amount to be an invalid argument, got status %d: %s", response.Status, response.Message)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Composite key prefixes of the dealer hierarchy: the link of a dealer to its
+// parent, keyed by dealer, and the index of the children of a parent, keyed by
+// parent and child.
+const (
+	dealerParentObjectType = "dealerparent"
+	dealerChildObjectType  = "dealerchild"
+)
+
+// dealerParentChangedEvent is emitted with the DealerLink when the parent of a
+// dealer is set or removed.
+const dealerParentChangedEvent = "DealerParentChanged"
+
+// maxDealerDepth bounds the levels of the hierarchy above a dealer, and
+// maxRollupDealers the dealers a roll-up reads, so that a query fits in the
+// chaincode execution timeout.
+const (
+	maxDealerDepth   = 16
+	maxRollupDealers = 500
+)
+
+// DealerLink attaches a sub-dealer to its super-dealer. An empty PARENTID
+// detaches the dealer.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DealerLink struct {
+	DEALERID  string `json:"dealerid"`
+	PARENTID  string `json:"parentid"`
+	UPDATEDAT string `json:"updatedat"`
+	UPDATEDBY string `json:"updatedby"`
+}
+
+// DealerRollup aggregates the assets and the volume of a dealer and of every
+// dealer below it. DEALERS lists the dealers of the hierarchy breadth first,
+// starting with the dealer itself.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DealerRollup struct {
+	ASSETS   int                 `json:"assets"`
+	BALANCE  float64             `json:"balance"`
+	DEALERID string              `json:"dealerid"`
+	DEALERS  []*DealerRollupNode `json:"dealers"`
+	VOLUME   float64             `json:"volume"`
+}
+
+// DealerRollupNode holds the figures of one dealer of a roll-up: its own, and
+// the totals of the dealer and the dealers below it. DEPTH counts the levels
+// below the dealer rolled up, whose PARENTID is left empty.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DealerRollupNode struct {
+	ASSETS       int     `json:"assets"`
+	BALANCE      float64 `json:"balance"`
+	DEALERID     string  `json:"dealerid"`
+	DEPTH        int     `json:"depth"`
+	PARENTID     string  `json:"parentid"`
+	TOTALASSETS  int     `json:"totalassets"`
+	TOTALBALANCE float64 `json:"totalbalance"`
+	TOTALVOLUME  float64 `json:"totalvolume"`
+	VOLUME       float64 `json:"volume"`
+}
+
+// SetDealerParent places a dealer below the super-dealer parentID, or detaches
+// it with an empty parentID. A dealer cannot be placed below itself or below
+// one of its sub-dealers. Only admins may call it.
+func (s *SmartContract) SetDealerParent(ctx contractapi.TransactionContextInterface, dealerID string, parentID string) (*DealerLink, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if dealerID == "" {
+		return nil, businessError(errCodeInvalidArgument, "a dealer ID is required")
+	}
+	if parentID == dealerID {
+		return nil, businessError(errCodeInvalidArgument, "the dealer %s cannot be its own parent", dealerID)
+	}
+
+	if parentID != "" {
+		// walk up from the new parent, the dealer must not be one of its ancestors
+		ancestor := parentID
+		for depth := 1; ancestor != ""; depth++ {
+			if depth > maxDealerDepth {
+				return nil, businessError(errCodeInvalidArgument, "the hierarchy above %s exceeds %d levels", dealerID, maxDealerDepth)
+			}
+			link, err := readDealerLink(ctx, ancestor)
+			if err != nil {
+				return nil, err
+			}
+			if link == nil {
+				break
+			}
+			if link.PARENTID == dealerID {
+				return nil, businessError(errCodeInvalidArgument, "the dealer %s is above %s and cannot be its child", dealerID, parentID)
+			}
+			ancestor = link.PARENTID
+		}
+	}
+
+	previous, err := readDealerLink(ctx, dealerID)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		childKey, err := ctx.GetStub().CreateCompositeKey(dealerChildObjectType, []string{previous.PARENTID, dealerID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(childKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete from world state: %v", err)
+		}
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	link := &DealerLink{
+		DEALERID:  dealerID,
+		PARENTID:  parentID,
+		UPDATEDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		UPDATEDBY: clientID,
+	}
+	linkJSON, err := json.Marshal(link)
+	if err != nil {
+		return nil, err
+	}
+
+	parentKey, err := ctx.GetStub().CreateCompositeKey(dealerParentObjectType, []string{dealerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	if parentID == "" {
+		err = ctx.GetStub().DelState(parentKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete from world state: %v", err)
+		}
+	} else {
+		err = ctx.GetStub().PutState(parentKey, linkJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put to world state: %v", err)
+		}
+		childKey, err := ctx.GetStub().CreateCompositeKey(dealerChildObjectType, []string{parentID, dealerID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		// the index only needs the key, the value is a placeholder
+		err = ctx.GetStub().PutState(childKey, []byte{0x00})
+		if err != nil {
+			return nil, fmt.Errorf("failed to put to world state: %v", err)
+		}
+	}
+
+	err = ctx.GetStub().SetEvent(dealerParentChangedEvent, linkJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+	return link, nil
+}
+
+// GetDealerParent returns the link of a dealer to its super-dealer, or nil when
+// the dealer has none.
+func (s *SmartContract) GetDealerParent(ctx contractapi.TransactionContextInterface, dealerID string) (*DealerLink, error) {
+	return readDealerLink(ctx, dealerID)
+}
+
+// GetDealerChildren returns the IDs of the dealers directly below a dealer, in
+// order.
+func (s *SmartContract) GetDealerChildren(ctx contractapi.TransactionContextInterface, dealerID string) ([]string, error) {
+	return readDealerChildren(ctx, dealerID)
+}
+
+// GetDealerRollup aggregates the assets, balances and volumes of a dealer and
+// of every dealer below it. It fails when the hierarchy holds more than
+// maxRollupDealers dealers.
+func (s *SmartContract) GetDealerRollup(ctx contractapi.TransactionContextInterface, dealerID string) (*DealerRollup, error) {
+	nodes := []*DealerRollupNode{{DEALERID: dealerID}}
+	for next := 0; next < len(nodes); next++ {
+		node := nodes[next]
+		count, err := readCounter(ctx, counterDealerAssets, node.DEALERID)
+		if err != nil {
+			return nil, err
+		}
+		balance, err := readCounter(ctx, counterDealerBalance, node.DEALERID)
+		if err != nil {
+			return nil, err
+		}
+		volume, err := readCounter(ctx, counterDealerVolume, node.DEALERID)
+		if err != nil {
+			return nil, err
+		}
+		node.ASSETS, node.BALANCE, node.VOLUME = int(count), balance, volume
+
+		children, err := readDealerChildren(ctx, node.DEALERID)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if len(nodes) == maxRollupDealers {
+				return nil, businessError(errCodeInvalidArgument, "the hierarchy below %s holds more than %d dealers", dealerID, maxRollupDealers)
+			}
+			nodes = append(nodes, &DealerRollupNode{DEALERID: child, DEPTH: node.DEPTH + 1, PARENTID: node.DEALERID})
+		}
+	}
+
+	// breadth first order lists every dealer after its parent, so walking it
+	// backwards adds the totals of the children before those of their parent
+	byDealer := make(map[string]*DealerRollupNode, len(nodes))
+	for _, node := range nodes {
+		byDealer[node.DEALERID] = node
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		node := nodes[i]
+		node.TOTALASSETS += node.ASSETS
+		node.TOTALBALANCE += node.BALANCE
+		node.TOTALVOLUME += node.VOLUME
+		if i > 0 {
+			parent := byDealer[node.PARENTID]
+			parent.TOTALASSETS += node.TOTALASSETS
+			parent.TOTALBALANCE += node.TOTALBALANCE
+			parent.TOTALVOLUME += node.TOTALVOLUME
+		}
+	}
+
+	return &DealerRollup{
+		ASSETS:   nodes[0].TOTALASSETS,
+		BALANCE:  nodes[0].TOTALBALANCE,
+		DEALERID: dealerID,
+		DEALERS:  nodes,
+		VOLUME:   nodes[0].TOTALVOLUME,
+	}, nil
+}
+
+func readDealerLink(ctx contractapi.TransactionContextInterface, dealerID string) (*DealerLink, error) {
+	parentKey, err := ctx.GetStub().CreateCompositeKey(dealerParentObjectType, []string{dealerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	linkJSON, err := ctx.GetStub().GetState(parentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if linkJSON == nil {
+		return nil, nil
+	}
+
+	var link DealerLink
+	err = json.Unmarshal(linkJSON, &link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func readDealerChildren(ctx contractapi.TransactionContextInterface, dealerID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(dealerChildObjectType, []string{dealerID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	children := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		children = append(children, attributes[1])
+	}
+
+	return children, nil
+}
@@ -0,0 +1,150 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// dealerAssetObjectType is the composite key prefix of the dealer~asset index,
+// keyed by dealer and asset id, which lists the assets of a dealer without
+// scanning the world state. Soft-deleted assets are not indexed.
+const dealerAssetObjectType = "dealerasset"
+
+// QueryAssetsByDealer returns the public summary of the assets of a dealer, in
+// asset id order, read through the dealer~asset index. Assets last written
+// before the index was introduced are only listed once RebuildDealerIndex ran.
+func (s *SmartContract) QueryAssetsByDealer(ctx contractapi.TransactionContextInterface, dealerID string) ([]*Asset, error) {
+	if dealerID == "" {
+		return nil, businessError(errCodeInvalidArgument, "the dealer ID is required")
+	}
+	return dealerAssets(ctx, dealerID)
+}
+
+// dealerAssets returns the assets of the dealer that are not soft-deleted, in
+// asset id order, read through the dealer~asset index.
+func dealerAssets(ctx contractapi.TransactionContextInterface, dealerID string) ([]*Asset, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(dealerAssetObjectType, []string{dealerID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	assets := []*Asset{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+
+		asset, err := readAssetSummary(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		// the index is written with the asset, so this only skips stale entries
+		if asset == nil || asset.DEALERID != dealerID || asset.STATUS == statusDeleted {
+			continue
+		}
+		assets = append(assets, asset)
+	}
+
+	return assets, nil
+}
+
+// RebuildDealerIndex recreates the dealer~asset index from the assets in the
+// world state, for ledgers holding assets written before the index was
+// introduced. Only admins may call it.
+func (s *SmartContract) RebuildDealerIndex(ctx contractapi.TransactionContextInterface) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	return rebuildAssetIndex(ctx, dealerAssetObjectType, updateDealerIndex)
+}
+
+// rebuildAssetIndex deletes the entries of the asset index objectType and
+// writes them again with update for every asset in the world state.
+func rebuildAssetIndex(ctx contractapi.TransactionContextInterface, objectType string, update func(ctx contractapi.TransactionContextInterface, previous *Asset, current *Asset) error) error {
+	entriesIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return err
+	}
+	defer entriesIterator.Close()
+
+	for entriesIterator.HasNext() {
+		queryResponse, err := entriesIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s index entry: %v", objectType, err)
+		}
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return err
+		}
+		err = update(ctx, nil, &asset)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateDealerIndex moves the dealer~asset index entry of the asset previous
+// replaced by current, either of which is nil when the asset is created or
+// deleted. The entry of current is written on every update, so that assets
+// written before the index was introduced are indexed once they change.
+func updateDealerIndex(ctx contractapi.TransactionContextInterface, previous *Asset, current *Asset) error {
+	indexed := func(asset *Asset) bool {
+		return asset != nil && asset.STATUS != statusDeleted
+	}
+
+	if indexed(previous) && !(indexed(current) && previous.DEALERID == current.DEALERID) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(dealerAssetObjectType, []string{previous.DEALERID, previous.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete dealer index entry: %v", err)
+		}
+	}
+	if indexed(current) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(dealerAssetObjectType, []string{current.DEALERID, current.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put dealer index entry: %v", err)
+		}
+	}
+
+	return nil
+}
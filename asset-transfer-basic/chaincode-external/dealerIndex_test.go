@@ -0,0 +1,64 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestDealerIndexFollowsAssets(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	checkDealer := func(dealerID string, expected ...string) {
+		t.Helper()
+		var assets []*Asset
+		if err := json.Unmarshal(invoke("QueryAssetsByDealer", dealerID), &assets); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, asset := range assets {
+			ids = append(ids, asset.ID)
+		}
+		if !slices.Equal(ids, expected) {
+			t.Errorf("expected the assets of %s to be %v, got %v", dealerID, expected, ids)
+		}
+	}
+
+	invoke("InitLedger")
+	checkDealer("DEALER101", "asset1")
+	checkDealer("DEALER102", "asset2")
+
+	invoke("TransferAsset", "asset1", "DEALER102")
+	checkDealer("DEALER101")
+	checkDealer("DEALER102", "asset1", "asset2")
+
+	invoke("DeleteAsset", "asset2")
+	checkDealer("DEALER102", "asset1")
+
+	invoke("RebuildDealerIndex")
+	checkDealer("DEALER102", "asset1")
+	checkDealer("DEALER103", "asset3")
+}
@@ -0,0 +1,125 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// deprecationEvent is the event set by the deprecated functions, so the
+// operators can find the clients still calling them.
+const deprecationEvent = "FunctionDeprecated"
+
+// deprecatedFunction is a transaction function kept for old clients during an
+// API migration. args translates its arguments to those of the replacement,
+// and is nil when they are the same.
+type deprecatedFunction struct {
+	replacement string
+	args        func(args []string) []string
+}
+
+// deprecatedFunctions maps the deprecated transaction functions to the
+// functions replacing them. The hooks run for a deprecated function as for its
+// replacement, so it is validated, audited and charged the same way, and
+// allowed while the system is paused when the replacement is.
+var deprecatedFunctions = map[string]deprecatedFunction{
+	"CreateTransaction":  {replacement: "CreateAsset", args: createTransactionArgs},
+	"GetAllTransactions": {replacement: "GetAllAssets"},
+	"ReadTransaction":    {replacement: "ReadAsset"},
+}
+
+// DeprecationNotice is the payload of the FunctionDeprecated event.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DeprecationNotice struct {
+	FUNCTION    string `json:"function"`
+	REPLACEMENT string `json:"replacement"`
+	TXID        string `json:"txid"`
+}
+
+// CreateTransaction is the deprecated form of CreateAsset, taking the MSISDN,
+// MPIN and remarks as arguments rather than in the transient map.
+func (s *SmartContract) CreateTransaction(ctx contractapi.TransactionContextInterface, id string, dealerID string, msisdn string, mpin string, balance float64, status string, transAmount float64, transType string, remarks string) error {
+	if err := warnDeprecated(ctx, "CreateTransaction"); err != nil {
+		return err
+	}
+
+	input := &assetDetailsInput{MPIN: mpin, MSISDN: msisdn, REMARKS: remarks}
+	if err := input.validate(); err != nil {
+		return err
+	}
+	asset := Asset{
+		ID:          id,
+		DEALERID:    dealerID,
+		BALANCE:     balance,
+		STATUS:      status,
+		TRANSAMOUNT: transAmount,
+		TRANSTYPE:   transType,
+	}
+	return s.createAsset(ctx, &asset, input)
+}
+
+// ReadTransaction is the deprecated name of ReadAsset.
+func (s *SmartContract) ReadTransaction(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
+	if err := warnDeprecated(ctx, "ReadTransaction"); err != nil {
+		return nil, err
+	}
+	return s.ReadAsset(ctx, id)
+}
+
+// GetAllTransactions is the deprecated name of GetAllAssets.
+func (s *SmartContract) GetAllTransactions(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
+	if err := warnDeprecated(ctx, "GetAllTransactions"); err != nil {
+		return nil, err
+	}
+	return s.GetAllAssets(ctx)
+}
+
+// warnDeprecated logs the call of a deprecated function and sets the
+// FunctionDeprecated event naming its replacement. The event only reaches
+// clients for submitted transactions, evaluations are only logged.
+func warnDeprecated(ctx contractapi.TransactionContextInterface, function string) error {
+	notice := DeprecationNotice{
+		FUNCTION:    function,
+		REPLACEMENT: deprecatedFunctions[function].replacement,
+		TXID:        ctx.GetStub().GetTxID(),
+	}
+	log.Printf("deprecated function %s called in transaction %s, use %s", notice.FUNCTION, notice.TXID, notice.REPLACEMENT)
+
+	noticeJSON, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(deprecationEvent, noticeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// resolveDeprecated returns the function and arguments the hooks run for: the
+// replacement of a deprecated function, otherwise function and args unchanged.
+func resolveDeprecated(function string, args []string) (string, []string) {
+	deprecated, ok := deprecatedFunctions[function]
+	if !ok {
+		return function, args
+	}
+	if deprecated.args != nil {
+		args = deprecated.args(args)
+	}
+	return deprecated.replacement, args
+}
+
+// createTransactionArgs drops the msisdn, mpin and remarks arguments of
+// CreateTransaction, which CreateAsset takes in the transient map.
+func createTransactionArgs(args []string) []string {
+	if len(args) != 9 {
+		return args
+	}
+	return []string{args[0], args[1], args[4], args[5], args[6], args[7]}
+}
@@ -0,0 +1,110 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error codes of the domain errors returned to clients. Every code but
+// LEDGER_UNAVAILABLE is a rejection, which fails again when retried.
+const (
+	errCodeAssetExists       = "ASSET_EXISTS"
+	errCodeAssetNotFound     = "ASSET_NOT_FOUND"
+	errCodeForbidden         = "FORBIDDEN"
+	errCodeInsufficientFunds = "INSUFFICIENT_FUNDS"
+	errCodeInvalidArgument   = "INVALID_ARGUMENT"
+	errCodeMaintenance       = "MAINTENANCE"
+	errCodeSystemPaused      = "SYSTEM_PAUSED"
+	errCodeVersionConflict   = "VERSION_CONFLICT"
+	// errCodeLedgerUnavailable is an infrastructure failure of the peer reading
+	// or writing the ledger, which may succeed when retried.
+	errCodeLedgerUnavailable = "LEDGER_UNAVAILABLE"
+	// errCodeLedgerRejected is a read or write the shim or the peer refused,
+	// such as of an invalid key or an unknown collection.
+	errCodeLedgerRejected = "LEDGER_REJECTED"
+)
+
+// transientLedgerErrors are fragments, in lower case, of the messages of the
+// ledger failures that may pass when the transaction is retried: a broken
+// stream to the peer, timeouts, an unreachable or overloaded state database,
+// private data not reconciled yet and read conflicts.
+var transientLedgerErrors = []string{
+	"error sending",
+	"timeout",
+	"timed out",
+	"deadline exceeded",
+	"connection refused",
+	"connection reset",
+	"error handling couchdb request",
+	"http error calling couchdb",
+	"too many requests",
+	"leveldb: closed",
+	"private data matching public hash version is not available",
+	"mvcc",
+	"phantom",
+}
+
+// ChaincodeError is a domain error. Its message is a JSON object carrying the
+// error code, so that clients can tell the failure apart from the endorsement
+// error wrapping it. RETRYABLE tells infrastructure failures from business
+// rejections.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type ChaincodeError struct {
+	CODE      string `json:"code"`
+	MESSAGE   string `json:"message"`
+	RETRYABLE bool   `json:"retryable"`
+}
+
+// Error returns the error as a JSON object.
+func (e *ChaincodeError) Error() string {
+	errorJSON, err := json.Marshal(e)
+	if err != nil {
+		return e.MESSAGE
+	}
+	return string(errorJSON)
+}
+
+// businessError rejects a transaction with a non-retryable error code.
+func businessError(code string, format string, args ...interface{}) error {
+	return &ChaincodeError{CODE: code, MESSAGE: fmt.Sprintf(format, args...)}
+}
+
+// ledgerError returns a failure to access the ledger as a retryable
+// LEDGER_UNAVAILABLE error when it is transient, see transientLedgerErrors, and
+// as a LEDGER_REJECTED error otherwise, keeping errors that already carry a
+// code.
+func ledgerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var chaincodeErr *ChaincodeError
+	if errors.As(err, &chaincodeErr) {
+		return err
+	}
+	message := strings.ToLower(err.Error())
+	for _, fragment := range transientLedgerErrors {
+		if strings.Contains(message, fragment) {
+			return &ChaincodeError{CODE: errCodeLedgerUnavailable, MESSAGE: err.Error(), RETRYABLE: true}
+		}
+	}
+	return &ChaincodeError{CODE: errCodeLedgerRejected, MESSAGE: err.Error()}
+}
+
+func assetNotFoundError(id string) error {
+	return &ChaincodeError{CODE: errCodeAssetNotFound, MESSAGE: fmt.Sprintf("the asset %s does not exist", id)}
+}
+
+// insufficientFundsError reports that the funds held by owner, such as "the float
+// of dealer DEALER101", are less than the amount required.
+func insufficientFundsError(owner string, balance float64, amount float64) error {
+	return &ChaincodeError{
+		CODE:    errCodeInsufficientFunds,
+		MESSAGE: fmt.Sprintf("%s has a balance of %.2f, less than %.2f", owner, balance, amount),
+	}
+}
@@ -0,0 +1,154 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// expiryObjectType is the composite key prefix of the expiry index. Entries are
+// keyed by expiry time first so the index iterates in expiry order.
+const expiryObjectType = "expiry"
+
+// expiryTimeLayout is a fixed width UTC layout that sorts lexicographically.
+const expiryTimeLayout = "2006-01-02T15:04:05Z"
+
+// defaultSweepSize is the number of releases performed when SweepExpired is called with maxReleases <= 0.
+const defaultSweepSize = 100
+
+// expiryReleaser releases the record whose key has the attributes id once it
+// has expired.
+type expiryReleaser func(ctx contractapi.TransactionContextInterface, id []string) error
+
+// expiryReleasers maps a record kind to the function releasing it. Contract
+// features holding records with an expiry register themselves here.
+var expiryReleasers = map[string]expiryReleaser{}
+
+// ExpiryRelease describes a record released by SweepExpired. ID holds the
+// attributes of the key of the record, such as its asset ID.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type ExpiryRelease struct {
+	EXPIRESAT string   `json:"expiresat"`
+	ID        []string `json:"id"`
+	KIND      string   `json:"kind"`
+}
+
+// SweepResult describes the outcome of a SweepExpired call.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type SweepResult struct {
+	MORE     bool             `json:"more"`
+	RELEASED []*ExpiryRelease `json:"released"`
+}
+
+// SweepExpired releases up to maxReleases records whose expiry time is before the
+// transaction timestamp, oldest first: swap consents, data export consents,
+// pending MSISDN changes and MPIN reset cases. It emits a single
+// ExpiredReleased event listing them. When more reports true the caller should
+// invoke it again. The REST server submits it every SWEEP_INTERVAL, and on
+// POST /admin/sweep.
+func (s *SmartContract) SweepExpired(ctx contractapi.TransactionContextInterface, maxReleases int) (*SweepResult, error) {
+	if maxReleases <= 0 {
+		maxReleases = defaultSweepSize
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC().Format(expiryTimeLayout)
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(expiryObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	result := &SweepResult{RELEASED: []*ExpiryRelease{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(attributes) < 3 {
+			return nil, fmt.Errorf("malformed expiry index entry %q", queryResponse.Key)
+		}
+		release := &ExpiryRelease{EXPIRESAT: attributes[0], ID: attributes[2:], KIND: attributes[1]}
+		if release.EXPIRESAT >= now {
+			break
+		}
+		if len(result.RELEASED) == maxReleases {
+			result.MORE = true
+			break
+		}
+
+		// entries of kinds no longer known to the contract are dropped from the index
+		if releaser, ok := expiryReleasers[release.KIND]; ok {
+			err = releaser(ctx, release.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to release %s %s: %v", release.KIND, strings.Join(release.ID, " "), err)
+			}
+		}
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete expiry index entry: %v", err)
+		}
+		result.RELEASED = append(result.RELEASED, release)
+	}
+
+	if len(result.RELEASED) > 0 {
+		eventJSON, err := json.Marshal(result.RELEASED)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().SetEvent("ExpiredReleased", eventJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set event: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// registerExpiry adds the record of the given kind whose key has the attributes
+// id to the expiry index. The index entry holds the attributes rather than the
+// composite key of the record, which cannot be an attribute itself.
+func registerExpiry(ctx contractapi.TransactionContextInterface, kind string, id []string, expiresAt time.Time) error {
+	indexKey, err := expiryIndexKey(ctx, kind, id, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+// unregisterExpiry removes a record released before its expiry from the expiry index.
+func unregisterExpiry(ctx contractapi.TransactionContextInterface, kind string, id []string, expiresAt time.Time) error {
+	indexKey, err := expiryIndexKey(ctx, kind, id, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().DelState(indexKey)
+}
+
+func expiryIndexKey(ctx contractapi.TransactionContextInterface, kind string, id []string, expiresAt time.Time) (string, error) {
+	attributes := append([]string{expiresAt.UTC().Format(expiryTimeLayout), kind}, id...)
+	indexKey, err := ctx.GetStub().CreateCompositeKey(expiryObjectType, attributes)
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return indexKey, nil
+}
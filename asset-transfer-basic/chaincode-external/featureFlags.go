@@ -0,0 +1,148 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// featureObjectType is the composite key prefix of the feature flags, keyed by flag name.
+const featureObjectType = "feature"
+
+// Feature flags gating contract behaviors, so they can be turned on per channel
+// with a transaction rather than a chaincode upgrade.
+const (
+	// featureSkipUnchangedUpdates makes UpdateAsset succeed without writing when
+	// it would store the current values.
+	featureSkipUnchangedUpdates = "skip-unchanged-updates"
+	// featureMaskMSISDN masks all but the last four digits of the MSISDN returned
+	// by ReadAssetDetails to callers that are not admins.
+	featureMaskMSISDN = "mask-msisdn"
+	// featureImplicitCollections writes asset details to the implicit private
+	// collection of the submitting organization instead of assetDetailsCollection.
+	featureImplicitCollections = "implicit-collections"
+	// featureStateRestore lets admins write raw world state with RestoreState,
+	// to be turned on only while restoring a backup into a new channel.
+	featureStateRestore = "state-restore"
+)
+
+// featureDefaults are the known feature flags and their value while never set.
+// Flags not listed are rejected by SetFeatureFlag, so a misspelt flag cannot be
+// set without effect.
+var featureDefaults = map[string]bool{
+	featureSkipUnchangedUpdates: true,
+	featureMaskMSISDN:           false,
+	featureImplicitCollections:  false,
+	featureStateRestore:         false,
+}
+
+// FeatureFlag is a contract behavior turned on or off on the channel.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type FeatureFlag struct {
+	ENABLED   bool   `json:"enabled"`
+	NAME      string `json:"name"`
+	UPDATEDAT string `json:"updatedat,omitempty" metadata:",optional"`
+	UPDATEDBY string `json:"updatedby,omitempty" metadata:",optional"`
+}
+
+// SetFeatureFlag turns a feature flag on or off. Only admins of an organization
+// may call it, and the flags are shared by all tenants.
+func (s *SmartContract) SetFeatureFlag(ctx contractapi.TransactionContextInterface, name string, enabled bool) error {
+	if _, ok := featureDefaults[name]; !ok {
+		return businessError(errCodeInvalidArgument, "unknown feature flag %q", name)
+	}
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	flag := FeatureFlag{
+		ENABLED:   enabled,
+		NAME:      name,
+		UPDATEDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		UPDATEDBY: mspID,
+	}
+	flagJSON, err := json.Marshal(flag)
+	if err != nil {
+		return err
+	}
+
+	stub := sharedStub(ctx)
+	flagKey, err := stub.CreateCompositeKey(featureObjectType, []string{name})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = stub.PutState(flagKey, flagJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent("FeatureFlagChanged", flagJSON)
+}
+
+// GetFeatureFlags returns every known feature flag, ordered by name, with its
+// default value when it was never set.
+func (s *SmartContract) GetFeatureFlags(ctx contractapi.TransactionContextInterface) ([]*FeatureFlag, error) {
+	names := make([]string, 0, len(featureDefaults))
+	for name := range featureDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	flags := make([]*FeatureFlag, 0, len(names))
+	for _, name := range names {
+		flag, err := readFeatureFlag(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+// featureEnabled reports whether the named feature flag is on.
+func featureEnabled(ctx contractapi.TransactionContextInterface, name string) (bool, error) {
+	flag, err := readFeatureFlag(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return flag.ENABLED, nil
+}
+
+func readFeatureFlag(ctx contractapi.TransactionContextInterface, name string) (*FeatureFlag, error) {
+	stub := sharedStub(ctx)
+	flagKey, err := stub.CreateCompositeKey(featureObjectType, []string{name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	flagJSON, err := stub.GetState(flagKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if flagJSON == nil {
+		return &FeatureFlag{ENABLED: featureDefaults[name], NAME: name}, nil
+	}
+
+	var flag FeatureFlag
+	err = json.Unmarshal(flagJSON, &flag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flag, nil
+}
@@ -0,0 +1,74 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// HookPhase selects whether a hook runs before or after the transaction function.
+type HookPhase string
+
+const (
+	HookBefore HookPhase = "before"
+	HookAfter  HookPhase = "after"
+)
+
+// HookCall describes the transaction a hook runs for. RESULT is the value returned
+// by the transaction function and is only set for after hooks. For a deprecated
+// function, FUNCTION and ARGS are those of its replacement.
+type HookCall struct {
+	ARGS     []string
+	FUNCTION string
+	RESULT   interface{}
+}
+
+// Hook runs deployment-specific logic around every transaction function, such as
+// extra validation or notification events. Returning an error fails the transaction.
+// After hooks only run when the transaction function succeeded.
+type Hook func(ctx contractapi.TransactionContextInterface, call *HookCall) error
+
+// hooks holds the registered hooks of each phase, in registration order.
+var hooks = map[HookPhase][]Hook{}
+
+// RegisterHook adds fn to the hooks run in phase. Hooks are registered from the
+// init function of the file implementing them, so features can be added to or
+// removed from a deployment without editing the contract functions.
+func RegisterHook(phase HookPhase, fn Hook) {
+	hooks[phase] = append(hooks[phase], fn)
+}
+
+// runBeforeHooks is the BeforeTransaction function of the contract.
+func runBeforeHooks(ctx contractapi.TransactionContextInterface) error {
+	return runHooks(ctx, HookBefore, nil)
+}
+
+// runAfterHooks is the AfterTransaction function of the contract.
+func runAfterHooks(ctx contractapi.TransactionContextInterface, result interface{}) error {
+	return runHooks(ctx, HookAfter, result)
+}
+
+func runHooks(ctx contractapi.TransactionContextInterface, phase HookPhase, result interface{}) error {
+	if len(hooks[phase]) == 0 {
+		return nil
+	}
+
+	function, args := ctx.GetStub().GetFunctionAndParameters()
+	// functions may be called as contractName:function
+	if i := strings.LastIndex(function, ":"); i >= 0 {
+		function = function[i+1:]
+	}
+	function, args = resolveDeprecated(function, args)
+	call := &HookCall{ARGS: args, FUNCTION: function, RESULT: result}
+
+	for _, hook := range hooks[phase] {
+		if err := hook(ctx, call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// implicitCollectionPrefix names the implicit private data collection every
+// organization has on the channel, without a collection config.
+const implicitCollectionPrefix = "_implicit_org_"
+
+// implicitCollection returns the name of the implicit collection of an organization.
+func implicitCollection(mspID string) string {
+	return implicitCollectionPrefix + mspID
+}
+
+// detailsCollection returns the collection holding the details of an asset: the
+// implicit collection of the organization named by DETAILSORG, or the shared
+// assetDetailsCollection.
+func detailsCollection(asset *Asset) string {
+	if asset.DETAILSORG != "" {
+		return implicitCollection(asset.DETAILSORG)
+	}
+	return assetDetailsCollection
+}
+
+// detailsWriteCollection returns the collection the details written by the
+// transaction go to, and the organization owning it. With the
+// implicit-collections feature flag on, that is the implicit collection of the
+// caller's organization, whose peers must then endorse the transaction.
+func detailsWriteCollection(ctx contractapi.TransactionContextInterface) (string, string, error) {
+	implicit, err := featureEnabled(ctx, featureImplicitCollections)
+	if err != nil || !implicit {
+		return assetDetailsCollection, "", err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	return implicitCollection(mspID), mspID, nil
+}
+
+// ReadAssetPrivateDetailsFor returns the details of the asset with given id held
+// in the implicit collection of the organization mspID. Only members of that
+// organization may call it, on one of its own peers.
+func (s *SmartContract) ReadAssetPrivateDetailsFor(ctx contractapi.TransactionContextInterface, mspID string, id string) (*AssetDetails, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	if clientMSPID != mspID {
+		return nil, businessError(errCodeForbidden, "members of %s cannot read the implicit collection of %s", clientMSPID, mspID)
+	}
+	peerMSPID, err := shim.GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer MSP ID: %v", err)
+	}
+	if peerMSPID != mspID {
+		return nil, businessError(errCodeForbidden, "the peer of %s is not a member of the implicit collection of %s", peerMSPID, mspID)
+	}
+
+	detailsJSON, err := ctx.GetStub().GetPrivateData(implicitCollection(mspID), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	if detailsJSON == nil {
+		return nil, businessError(errCodeAssetNotFound, "the details of asset %s do not exist in the implicit collection of %s", id, mspID)
+	}
+
+	var details AssetDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return nil, err
+	}
+
+	return maskDetails(ctx, &details)
+}
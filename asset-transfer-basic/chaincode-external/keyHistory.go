@@ -0,0 +1,139 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// maxHistoryReportKeys bounds the keys whose history one GetKeyHistoryReport
+// call reads, since every key costs a history query.
+const maxHistoryReportKeys = 500
+
+// KeyHistoryStats is the history size of one key. Composite keys are shown as
+// their object type and attributes separated by ~.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type KeyHistoryStats struct {
+	BYTES    int    `json:"bytes"`
+	DELETES  int    `json:"deletes"`
+	KEY      string `json:"key"`
+	VERSIONS int    `json:"versions"`
+}
+
+// KeyHistoryReport is one page of a key history report. HOTKEYS lists the keys
+// of the page with the most versions, most first. BOOKMARK resumes the report
+// with the next page and is empty after the last one.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type KeyHistoryReport struct {
+	BOOKMARK string             `json:"bookmark"`
+	BYTES    int                `json:"bytes"`
+	HOTKEYS  []*KeyHistoryStats `json:"hotkeys"`
+	KEYS     int                `json:"keys"`
+	VERSIONS int                `json:"versions"`
+}
+
+// GetKeyHistoryReport counts the historical versions, and the bytes they hold,
+// of a page of keys: the simple keys from startKey to endKey, or the composite
+// keys of objectType when it is set, such as usage or float. It reports the top
+// keys with the most versions, to find the hot keys whose history grows fastest
+// and should be sharded. Only admins may call it, and it must be evaluated.
+func (s *SmartContract) GetKeyHistoryReport(ctx contractapi.TransactionContextInterface, objectType string, startKey string, endKey string, pageSize int, bookmark string, top int) (*KeyHistoryReport, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 || pageSize > maxHistoryReportKeys {
+		return nil, fmt.Errorf("the page size must be between 1 and %d", maxHistoryReportKeys)
+	}
+	if top <= 0 {
+		return nil, fmt.Errorf("the number of top keys must be positive")
+	}
+
+	var resultsIterator shim.StateQueryIteratorInterface
+	var err error
+	var nextBookmark string
+	if objectType != "" {
+		iterator, metadata, queryErr := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(objectType, []string{}, int32(pageSize), bookmark)
+		resultsIterator, err = iterator, queryErr
+		if metadata != nil {
+			nextBookmark = metadata.Bookmark
+		}
+	} else {
+		iterator, metadata, queryErr := ctx.GetStub().GetStateByRangeWithPagination(startKey, endKey, int32(pageSize), bookmark)
+		resultsIterator, err = iterator, queryErr
+		if metadata != nil {
+			nextBookmark = metadata.Bookmark
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	report := &KeyHistoryReport{HOTKEYS: []*KeyHistoryStats{}}
+	var stats []*KeyHistoryStats
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		keyStats, err := getKeyHistoryStats(ctx, queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		report.KEYS++
+		report.VERSIONS += keyStats.VERSIONS
+		report.BYTES += keyStats.BYTES
+		stats = append(stats, keyStats)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].VERSIONS > stats[j].VERSIONS
+	})
+	if len(stats) > top {
+		stats = stats[:top]
+	}
+	report.HOTKEYS = append(report.HOTKEYS, stats...)
+	report.BOOKMARK = resumeBookmark(report.KEYS, int32(pageSize), nextBookmark)
+
+	return report, nil
+}
+
+// getKeyHistoryStats counts the versions and bytes in the history of key.
+func getKeyHistoryStats(ctx contractapi.TransactionContextInterface, key string) (*KeyHistoryStats, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	stats := &KeyHistoryStats{KEY: key}
+	if strings.HasPrefix(key, "\x00") {
+		objectType, attributes, err := ctx.GetStub().SplitCompositeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		stats.KEY = strings.Join(append([]string{objectType}, attributes...), "~")
+	}
+
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		stats.VERSIONS++
+		stats.BYTES += len(modification.Value)
+		if modification.IsDelete {
+			stats.DELETES++
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,290 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// limitPolicyObjectType is the composite key prefix of the limit policy, which
+// has no attributes.
+const limitPolicyObjectType = "limitpolicy"
+
+// dailyDebitObjectType is the composite key prefix of the debits of an asset
+// in a day, keyed by asset ID and UTC date.
+const dailyDebitObjectType = "dailydebit"
+
+// limitWarningEvent is the event warning that debits brought assets close to
+// their limits, for the notification service to alert their owners.
+const limitWarningEvent = "LimitWarning"
+
+// Reasons of a LimitWarning.
+const (
+	limitReasonDailyDebit = "daily-debit-limit"
+	limitReasonMinBalance = "min-balance"
+)
+
+func init() {
+	RegisterHook(HookAfter, limitHook)
+}
+
+// LimitPolicy sets the limits the debits of assets are warned about. A debit
+// bringing the debits of an asset in the UTC day within WARNPERCENT percent of
+// DAILYDEBITLIMIT, or its balance below MINBALANCE, sets the LimitWarning
+// event. A zero limit is not warned about. The limits are not enforced.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type LimitPolicy struct {
+	DAILYDEBITLIMIT float64 `json:"dailydebitlimit"`
+	MINBALANCE      float64 `json:"minbalance"`
+	UPDATEDAT       string  `json:"updatedat"`
+	UPDATEDBY       string  `json:"updatedby"`
+	WARNPERCENT     float64 `json:"warnpercent"`
+}
+
+// LimitWarning is an asset a debit brought close to its limits.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type LimitWarning struct {
+	ASSETID         string   `json:"assetid"`
+	BALANCE         float64  `json:"balance"`
+	DAILYDEBITED    float64  `json:"dailydebited"`
+	DAILYDEBITLIMIT float64  `json:"dailydebitlimit,omitempty" metadata:",optional"`
+	DEALERID        string   `json:"dealerid"`
+	MINBALANCE      float64  `json:"minbalance,omitempty" metadata:",optional"`
+	REASONS         []string `json:"reasons"`
+}
+
+// LimitWarningEvent is the payload of the LimitWarning event. As a transaction
+// can only set one event, REPLACED holds the event the transaction set before,
+// such as AssetChanged, which the LimitWarning event replaces.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type LimitWarningEvent struct {
+	REPLACED *ReplacedEvent  `json:"replaced,omitempty" metadata:",optional"`
+	TXID     string          `json:"txid"`
+	WARNINGS []*LimitWarning `json:"warnings"`
+}
+
+// ReplacedEvent is an event replaced by a later event of its transaction.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type ReplacedEvent struct {
+	NAME    string          `json:"name"`
+	PAYLOAD json.RawMessage `json:"payload"`
+}
+
+// assetDebit is the debit of an asset by a transaction: the balance before the
+// transaction, the asset as last written and the amount debited.
+type assetDebit struct {
+	amount float64
+	asset  *Asset
+	before float64
+}
+
+// SetLimitPolicy replaces the limit policy with policyJSON, a LimitPolicy
+// without its update fields. Only admins may call it.
+func (s *SmartContract) SetLimitPolicy(ctx contractapi.TransactionContextInterface, policyJSON string) (*LimitPolicy, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var policy LimitPolicy
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "the limit policy must be a JSON object: %v", err)
+	}
+	if policy.DAILYDEBITLIMIT < 0 || policy.MINBALANCE < 0 {
+		return nil, businessError(errCodeInvalidArgument, "the limits must not be negative")
+	}
+	if policy.WARNPERCENT < 0 || policy.WARNPERCENT > 100 {
+		return nil, businessError(errCodeInvalidArgument, "the warning percentage %v must be between 0 and 100", policy.WARNPERCENT)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	policy.UPDATEDAT = timestamp.AsTime().UTC().Format(time.RFC3339)
+	policy.UPDATEDBY = clientID
+
+	storedJSON, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	policyKey, err := ctx.GetStub().CreateCompositeKey(limitPolicyObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(policyKey, storedJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return &policy, nil
+}
+
+// GetLimitPolicy returns the limit policy, nil when none was set.
+func (s *SmartContract) GetLimitPolicy(ctx contractapi.TransactionContextInterface) (*LimitPolicy, error) {
+	return readLimitPolicy(ctx)
+}
+
+func readLimitPolicy(ctx contractapi.TransactionContextInterface) (*LimitPolicy, error) {
+	policyKey, err := ctx.GetStub().CreateCompositeKey(limitPolicyObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	policyJSON, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if policyJSON == nil {
+		return nil, nil
+	}
+
+	var policy LimitPolicy
+	if err := json.Unmarshal(policyJSON, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// recordDebit records the debit made by replacing the asset previous with
+// current, if any. Within a transaction the debits are collected and checked
+// against the limits once by limitHook, since a transaction does not read its
+// own writes.
+func recordDebit(ctx contractapi.TransactionContextInterface, previous *Asset, current *Asset) error {
+	if previous == nil || current.STATUS == statusDeleted || current.BALANCE >= previous.BALANCE {
+		return nil
+	}
+	amount := previous.BALANCE - current.BALANCE
+	metered, ok := ctx.(*meteredContext)
+	if !ok {
+		return checkLimits(ctx, map[string]*assetDebit{current.ID: {amount: amount, asset: current, before: previous.BALANCE}})
+	}
+	if metered.debits == nil {
+		metered.debits = make(map[string]*assetDebit)
+	}
+	debit, ok := metered.debits[current.ID]
+	if !ok {
+		debit = &assetDebit{before: previous.BALANCE}
+		metered.debits[current.ID] = debit
+	}
+	debit.amount += amount
+	debit.asset = current
+	return nil
+}
+
+// limitHook checks the debits collected during the transaction.
+func limitHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	metered, ok := ctx.(*meteredContext)
+	if !ok || len(metered.debits) == 0 {
+		return nil
+	}
+	debits := metered.debits
+	metered.debits = nil
+	return checkLimits(ctx, debits)
+}
+
+// checkLimits adds the debits, keyed by asset ID, to the debits of their
+// assets in the day of the transaction, and sets the LimitWarning event for
+// the assets crossing a limit of the policy. Without a policy nothing is
+// recorded.
+func checkLimits(ctx contractapi.TransactionContextInterface, debits map[string]*assetDebit) error {
+	policy, err := readLimitPolicy(ctx)
+	if err != nil || policy == nil {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	day := timestamp.AsTime().UTC().Format(time.DateOnly)
+
+	ids := make([]string, 0, len(debits))
+	for id := range debits {
+		ids = append(ids, id)
+	}
+	// every peer writes the daily debits in the same order
+	sort.Strings(ids)
+
+	warnings := make([]*LimitWarning, 0)
+	for _, id := range ids {
+		debit := debits[id]
+		warning := &LimitWarning{
+			ASSETID:  id,
+			BALANCE:  debit.asset.BALANCE,
+			DEALERID: debit.asset.DEALERID,
+			REASONS:  []string{},
+		}
+		if policy.DAILYDEBITLIMIT > 0 {
+			debitedBefore, err := addDailyDebit(ctx, id, day, debit.amount)
+			if err != nil {
+				return err
+			}
+			warning.DAILYDEBITED = debitedBefore + debit.amount
+			warning.DAILYDEBITLIMIT = policy.DAILYDEBITLIMIT
+			threshold := policy.DAILYDEBITLIMIT * (1 - policy.WARNPERCENT/100)
+			if debitedBefore < threshold && warning.DAILYDEBITED >= threshold {
+				warning.REASONS = append(warning.REASONS, limitReasonDailyDebit)
+			}
+		}
+		if policy.MINBALANCE > 0 && debit.before >= policy.MINBALANCE && debit.asset.BALANCE < policy.MINBALANCE {
+			warning.MINBALANCE = policy.MINBALANCE
+			warning.REASONS = append(warning.REASONS, limitReasonMinBalance)
+		}
+		if len(warning.REASONS) > 0 {
+			warnings = append(warnings, warning)
+		}
+	}
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	event := LimitWarningEvent{TXID: ctx.GetStub().GetTxID(), WARNINGS: warnings}
+	if metered, ok := ctx.GetStub().(*meteredStub); ok && metered.eventName != "" {
+		event.REPLACED = &ReplacedEvent{NAME: metered.eventName, PAYLOAD: metered.eventPayload}
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(limitWarningEvent, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// addDailyDebit adds amount to the debits of the asset in day and returns the
+// debits before.
+func addDailyDebit(ctx contractapi.TransactionContextInterface, id string, day string, amount float64) (float64, error) {
+	debitKey, err := ctx.GetStub().CreateCompositeKey(dailyDebitObjectType, []string{id, day})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	valueBytes, err := ctx.GetStub().GetState(debitKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	var debited float64
+	if valueBytes != nil {
+		debited, err = strconv.ParseFloat(string(valueBytes), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid daily debits of %s on %s: %v", id, day, err)
+		}
+	}
+	err = ctx.GetStub().PutState(debitKey, []byte(strconv.FormatFloat(debited+amount, 'f', -1, 64)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return debited, nil
+}
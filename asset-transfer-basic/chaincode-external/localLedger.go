@@ -0,0 +1,616 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/attrmgr"
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// localChannel and localMSPID name the channel and default organization of the
+// local ledger.
+const (
+	localChannel = "local"
+	localMSPID   = "Org1MSP"
+)
+
+// emptyKeySubstitute starts the ranges with an empty start key, so they leave
+// out the composite keys as the peer does.
+const emptyKeySubstitute = "\x01"
+
+// errRichQueryUnsupported is returned by the rich queries, which need CouchDB.
+var errRichQueryUnsupported = errors.New("rich queries are not supported by the local ledger")
+
+// errLocalUnsupported is returned by the functions of the stub the local ledger
+// has no equivalent for, such as the validation parameters of keys.
+var errLocalUnsupported = errors.New("not supported by the local ledger")
+
+// localModification is one committed change of a key of the local ledger.
+type localModification struct {
+	TxID      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+	Value     []byte    `json:"value,omitempty"`
+	IsDelete  bool      `json:"isDelete,omitempty"`
+}
+
+// localSnapshot is the content of the file the local ledger is saved to.
+type localSnapshot struct {
+	State       map[string][]byte              `json:"state"`
+	PrivateData map[string]map[string][]byte   `json:"privateData"`
+	History     map[string][]localModification `json:"history"`
+}
+
+// localLedger is an in-memory ledger running the chaincode in process, for
+// developing the contract without a Fabric network. Transactions run one at a
+// time. As on a peer, they read the committed state only, not their own writes,
+// and their writes are committed when they are submitted and succeed. Rich
+// queries are not supported. The ledger is saved to a JSON file after every
+// commit when it has a path, and loaded from it on start.
+type localLedger struct {
+	chaincode shim.Chaincode
+	path      string
+
+	lock       sync.Mutex
+	snapshot   localSnapshot
+	identities map[string][]byte
+	// now returns the timestamp of the next transaction, so tests can move
+	// the clock past waiting periods.
+	now func() time.Time
+}
+
+func newLocalLedger(chaincode shim.Chaincode, path string) (*localLedger, error) {
+	ledger := &localLedger{
+		chaincode:  chaincode,
+		path:       path,
+		identities: make(map[string][]byte),
+		now:        time.Now,
+		snapshot: localSnapshot{
+			State:       make(map[string][]byte),
+			PrivateData: make(map[string]map[string][]byte),
+			History:     make(map[string][]localModification),
+		},
+	}
+	if path == "" {
+		return ledger, nil
+	}
+
+	snapshotJSON, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local ledger: %v", err)
+	}
+	if err := json.Unmarshal(snapshotJSON, &ledger.snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse local ledger %s: %v", path, err)
+	}
+	return ledger, nil
+}
+
+// localRequest is a transaction sent to the local ledger. MSPID defaults to
+// Org1MSP, Admin makes the caller an admin of its organization, and DealerID
+// sets the dealerid attribute of the caller's certificate.
+type localRequest struct {
+	Function  string            `json:"function"`
+	Args      []string          `json:"args"`
+	Transient map[string][]byte `json:"transient,omitempty"`
+	Submit    bool              `json:"submit"`
+	MSPID     string            `json:"mspId,omitempty"`
+	Admin     bool              `json:"admin,omitempty"`
+	DealerID  string            `json:"dealerId,omitempty"`
+}
+
+// localResponse is the outcome of a transaction run by the local ledger.
+type localResponse struct {
+	TransactionID string      `json:"transactionId"`
+	Status        int32       `json:"status"`
+	Message       string      `json:"message,omitempty"`
+	Payload       []byte      `json:"payload,omitempty"`
+	Event         *localEvent `json:"event,omitempty"`
+	Committed     bool        `json:"committed"`
+}
+
+type localEvent struct {
+	Name    string `json:"name"`
+	Payload []byte `json:"payload"`
+}
+
+// invoke runs a transaction and commits its writes when it is submitted and succeeds.
+func (l *localLedger) invoke(request localRequest) (*localResponse, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if request.MSPID == "" {
+		request.MSPID = localMSPID
+	}
+	creator, err := l.creator(request.MSPID, request.Admin, request.DealerID)
+	if err != nil {
+		return nil, err
+	}
+	txIDBytes := make([]byte, 32)
+	if _, err := rand.Read(txIDBytes); err != nil {
+		return nil, err
+	}
+
+	stub := &localStub{
+		ledger:         l,
+		txID:           hex.EncodeToString(txIDBytes),
+		timestamp:      l.now().UTC(),
+		args:           append([]string{request.Function}, request.Args...),
+		transient:      request.Transient,
+		creator:        creator,
+		writes:         make(map[string][]byte),
+		deletes:        make(map[string]bool),
+		private:        make(map[string]map[string][]byte),
+		privateDeletes: make(map[string]map[string]bool),
+	}
+	response := l.chaincode.Invoke(stub)
+
+	result := &localResponse{
+		TransactionID: stub.txID,
+		Status:        response.GetStatus(),
+		Message:       response.GetMessage(),
+		Payload:       response.GetPayload(),
+		Event:         stub.event,
+	}
+	if request.Submit && response.GetStatus() < shim.ERRORTHRESHOLD {
+		if err := l.commit(stub); err != nil {
+			return nil, err
+		}
+		result.Committed = true
+	}
+	return result, nil
+}
+
+// commit applies the writes of a transaction and saves the ledger.
+func (l *localLedger) commit(stub *localStub) error {
+	for key, value := range stub.writes {
+		l.snapshot.State[key] = value
+		l.snapshot.History[key] = append(l.snapshot.History[key], localModification{TxID: stub.txID, Timestamp: stub.timestamp, Value: value})
+	}
+	for key := range stub.deletes {
+		delete(l.snapshot.State, key)
+		l.snapshot.History[key] = append(l.snapshot.History[key], localModification{TxID: stub.txID, Timestamp: stub.timestamp, IsDelete: true})
+	}
+	for collection, values := range stub.private {
+		if l.snapshot.PrivateData[collection] == nil {
+			l.snapshot.PrivateData[collection] = make(map[string][]byte)
+		}
+		for key, value := range values {
+			l.snapshot.PrivateData[collection][key] = value
+		}
+	}
+	for collection, keys := range stub.privateDeletes {
+		for key := range keys {
+			delete(l.snapshot.PrivateData[collection], key)
+		}
+	}
+	return l.save()
+}
+
+// save writes the ledger to a temporary file renamed over its file, so a crash
+// never leaves a truncated ledger behind.
+func (l *localLedger) save() error {
+	if l.path == "" {
+		return nil
+	}
+	snapshotJSON, err := json.Marshal(l.snapshot)
+	if err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save local ledger: %v", err)
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(snapshotJSON); err != nil {
+		temporary.Close()
+		return fmt.Errorf("failed to save local ledger: %v", err)
+	}
+	if err := temporary.Close(); err != nil {
+		return fmt.Errorf("failed to save local ledger: %v", err)
+	}
+	return os.Rename(temporary.Name(), l.path)
+}
+
+// creator returns the serialized identity of a local user of mspID, with a
+// self-signed certificate of the admin or client organizational unit, carrying
+// the dealerid attribute like the certificates of Fabric CA when dealerID is set.
+func (l *localLedger) creator(mspID string, admin bool, dealerID string) ([]byte, error) {
+	ou := "client"
+	if admin {
+		ou = adminOU
+	}
+	name := mspID + "/" + ou + "/" + dealerID
+	if creator, ok := l.identities[name]; ok {
+		return creator, nil
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "local-" + ou, Organization: []string{mspID}, OrganizationalUnit: []string{ou}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * 365 * time.Hour),
+	}
+	if dealerID != "" {
+		attributesJSON, err := json.Marshal(&attrmgr.Attributes{Attrs: map[string]string{dealerAttribute: dealerID}})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: attrmgr.AttrOID, Value: attributesJSON})
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.identities[name] = creator
+	return creator, nil
+}
+
+// ServeHTTP runs the transaction posted as a localRequest to /transactions.
+func (l *localLedger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request localRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Function == "" {
+		http.Error(w, "the body must be a JSON transaction with a function", http.StatusBadRequest)
+		return
+	}
+	response, err := l.invoke(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("failed to write local ledger response: %s", err)
+	}
+}
+
+// serveLocalLedger serves the local ledger on /transactions of address.
+func serveLocalLedger(address string, ledger *localLedger) error {
+	mux := http.NewServeMux()
+	mux.Handle("POST /transactions", ledger)
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	log.Printf("local ledger listening on %s", address)
+	return server.ListenAndServe()
+}
+
+// localStub is the stub of a transaction run by the local ledger. Functions the
+// local ledger has no equivalent for fail with errLocalUnsupported.
+type localStub struct {
+	ledger    *localLedger
+	txID      string
+	timestamp time.Time
+	args      []string
+	transient map[string][]byte
+	creator   []byte
+	event     *localEvent
+
+	writes         map[string][]byte
+	deletes        map[string]bool
+	private        map[string]map[string][]byte
+	privateDeletes map[string]map[string]bool
+}
+
+func (s *localStub) GetArgs() [][]byte {
+	args := make([][]byte, len(s.args))
+	for i, arg := range s.args {
+		args[i] = []byte(arg)
+	}
+	return args
+}
+
+func (s *localStub) GetStringArgs() []string {
+	return s.args
+}
+
+func (s *localStub) GetFunctionAndParameters() (string, []string) {
+	return s.args[0], s.args[1:]
+}
+
+func (s *localStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *localStub) GetChannelID() string {
+	return localChannel
+}
+
+func (s *localStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.timestamp), nil
+}
+
+func (s *localStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *localStub) GetTransient() (map[string][]byte, error) {
+	if s.transient == nil {
+		return map[string][]byte{}, nil
+	}
+	return s.transient, nil
+}
+
+func (s *localStub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+func (s *localStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.event = &localEvent{Name: name, Payload: payload}
+	return nil
+}
+
+func (s *localStub) GetState(key string) ([]byte, error) {
+	return s.ledger.snapshot.State[key], nil
+}
+
+func (s *localStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	delete(s.deletes, key)
+	s.writes[key] = value
+	return nil
+}
+
+func (s *localStub) DelState(key string) error {
+	delete(s.writes, key)
+	s.deletes[key] = true
+	return nil
+}
+
+func (s *localStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *localStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(compositeKey, "\x00"), "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (s *localStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.GetStateByRangeWithPagination(startKey, endKey, 0, "")
+	return iterator, err
+}
+
+// GetStateByRangeWithPagination returns the keys from startKey, or from the
+// bookmark, which is the first key of the next page.
+func (s *localStub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	return s.page(func(key string) bool {
+		return key >= startKey && (endKey == "" || key < endKey)
+	}, pageSize)
+}
+
+func (s *localStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.GetStateByPartialCompositeKeyWithPagination(objectType, attributes, 0, "")
+	return iterator, err
+}
+
+func (s *localStub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.page(func(key string) bool {
+		return strings.HasPrefix(key, prefix) && key >= bookmark
+	}, pageSize)
+}
+
+// page returns the committed keys matching in order, at most pageSize of them
+// unless it is zero, with the first key of the next page as bookmark.
+func (s *localStub) page(match func(key string) bool, pageSize int32) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	keys := make([]string, 0)
+	for key := range s.ledger.snapshot.State {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	bookmark := ""
+	if pageSize > 0 && len(keys) > int(pageSize) {
+		bookmark = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+	iterator := &localIterator{}
+	for _, key := range keys {
+		iterator.results = append(iterator.results, &queryresult.KV{Namespace: localChannel, Key: key, Value: s.ledger.snapshot.State[key]})
+	}
+	return iterator, &peer.QueryResponseMetadata{FetchedRecordsCount: int32(len(keys)), Bookmark: bookmark}, nil
+}
+
+func (s *localStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQueryUnsupported
+}
+
+func (s *localStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, errRichQueryUnsupported
+}
+
+func (s *localStub) GetPrivateDataQueryResult(collection string, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQueryUnsupported
+}
+
+func (s *localStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	iterator := &localHistoryIterator{}
+	// the peer returns the most recent modification first
+	modifications := s.ledger.snapshot.History[key]
+	for i := len(modifications) - 1; i >= 0; i-- {
+		iterator.results = append(iterator.results, &queryresult.KeyModification{
+			TxId:      modifications[i].TxID,
+			Value:     modifications[i].Value,
+			Timestamp: timestamppb.New(modifications[i].Timestamp),
+			IsDelete:  modifications[i].IsDelete,
+		})
+	}
+	return iterator, nil
+}
+
+func (s *localStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.ledger.snapshot.PrivateData[collection][key], nil
+}
+
+func (s *localStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, ok := s.ledger.snapshot.PrivateData[collection][key]
+	if !ok {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *localStub) PutPrivateData(collection string, key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if s.private[collection] == nil {
+		s.private[collection] = make(map[string][]byte)
+	}
+	delete(s.privateDeletes[collection], key)
+	s.private[collection][key] = value
+	return nil
+}
+
+func (s *localStub) DelPrivateData(collection string, key string) error {
+	if s.privateDeletes[collection] == nil {
+		s.privateDeletes[collection] = make(map[string]bool)
+	}
+	delete(s.private[collection], key)
+	s.privateDeletes[collection][key] = true
+	return nil
+}
+
+// PurgePrivateData deletes the private data, the local ledger keeping no
+// history of private data to purge.
+func (s *localStub) PurgePrivateData(collection string, key string) error {
+	return s.DelPrivateData(collection, key)
+}
+
+func (s *localStub) GetPrivateDataByRange(collection string, startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errLocalUnsupported
+}
+
+func (s *localStub) GetPrivateDataByPartialCompositeKey(collection string, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errLocalUnsupported
+}
+
+func (s *localStub) SetStateValidationParameter(key string, ep []byte) error {
+	return errLocalUnsupported
+}
+
+func (s *localStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return nil, errLocalUnsupported
+}
+
+func (s *localStub) SetPrivateDataValidationParameter(collection string, key string, ep []byte) error {
+	return errLocalUnsupported
+}
+
+func (s *localStub) GetPrivateDataValidationParameter(collection string, key string) ([]byte, error) {
+	return nil, errLocalUnsupported
+}
+
+func (s *localStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) *peer.Response {
+	return &peer.Response{Status: shim.ERROR, Message: "invoking chaincode " + chaincodeName + " is " + errLocalUnsupported.Error()}
+}
+
+func (s *localStub) GetArgsSlice() ([]byte, error) {
+	var argsSlice []byte
+	for _, arg := range s.args {
+		argsSlice = append(argsSlice, arg...)
+	}
+	return argsSlice, nil
+}
+
+func (s *localStub) GetBinding() ([]byte, error) {
+	return nil, errLocalUnsupported
+}
+
+func (s *localStub) GetSignedProposal() (*peer.SignedProposal, error) {
+	return nil, errLocalUnsupported
+}
+
+// localIterator iterates over the results of a range query.
+type localIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *localIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *localIterator) Next() (*queryresult.KV, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *localIterator) Close() error {
+	return nil
+}
+
+// localHistoryIterator iterates over the modifications of a key.
+type localHistoryIterator struct {
+	results []*queryresult.KeyModification
+}
+
+func (i *localHistoryIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *localHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *localHistoryIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,196 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// maintenanceObjectType is the composite key prefix of the maintenance windows,
+// keyed by their start time.
+const maintenanceObjectType = "maintenance"
+
+func init() {
+	RegisterHook(HookBefore, maintenanceHook)
+}
+
+// MaintenanceWindow is a period during which only admins may change the ledger,
+// such as to run a migration without racing live traffic. START and END are
+// RFC 3339 UTC times, the window ending before END.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type MaintenanceWindow struct {
+	END         string `json:"end"`
+	REASON      string `json:"reason"`
+	SCHEDULEDBY string `json:"scheduledby"`
+	START       string `json:"start"`
+}
+
+// ScheduleMaintenance adds a maintenance window from start to end, given as RFC
+// 3339 times. Only admins may call it. The windows that ended are removed from
+// the schedule. Like the system state, the schedule is shared by all tenants.
+func (s *SmartContract) ScheduleMaintenance(ctx contractapi.TransactionContextInterface, start string, end string, reason string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	startTime, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return businessError(errCodeInvalidArgument, "start must be an RFC 3339 time: %v", err)
+	}
+	endTime, err := time.Parse(time.RFC3339, end)
+	if err != nil {
+		return businessError(errCodeInvalidArgument, "end must be an RFC 3339 time: %v", err)
+	}
+	if !endTime.After(startTime) {
+		return businessError(errCodeInvalidArgument, "the maintenance window must end after %s", start)
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if !endTime.After(timestamp.AsTime()) {
+		return businessError(errCodeInvalidArgument, "the maintenance window ended at %s", end)
+	}
+
+	windows, err := readMaintenanceWindows(ctx)
+	if err != nil {
+		return err
+	}
+	for _, window := range windows {
+		windowEnd, _ := time.Parse(time.RFC3339, window.END)
+		if !windowEnd.After(timestamp.AsTime()) {
+			if err := deleteMaintenanceWindow(ctx, window.START); err != nil {
+				return err
+			}
+		}
+	}
+
+	window := MaintenanceWindow{
+		END:         endTime.UTC().Format(time.RFC3339),
+		REASON:      reason,
+		SCHEDULEDBY: mspID,
+		START:       startTime.UTC().Format(time.RFC3339),
+	}
+	windowJSON, err := json.Marshal(window)
+	if err != nil {
+		return err
+	}
+
+	stub := sharedStub(ctx)
+	windowKey, err := stub.CreateCompositeKey(maintenanceObjectType, []string{window.START})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = stub.PutState(windowKey, windowJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return nil
+}
+
+// CancelMaintenance removes the maintenance window starting at start. Only
+// admins may call it, such as to reopen the ledger when a migration finished early.
+func (s *SmartContract) CancelMaintenance(ctx contractapi.TransactionContextInterface, start string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	startTime, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return businessError(errCodeInvalidArgument, "start must be an RFC 3339 time: %v", err)
+	}
+	start = startTime.UTC().Format(time.RFC3339)
+
+	windows, err := readMaintenanceWindows(ctx)
+	if err != nil {
+		return err
+	}
+	for _, window := range windows {
+		if window.START == start {
+			return deleteMaintenanceWindow(ctx, start)
+		}
+	}
+	return businessError(errCodeInvalidArgument, "no maintenance window starts at %s", start)
+}
+
+// GetMaintenanceSchedule returns the scheduled maintenance windows, earliest first.
+func (s *SmartContract) GetMaintenanceSchedule(ctx contractapi.TransactionContextInterface) ([]*MaintenanceWindow, error) {
+	return readMaintenanceWindows(ctx)
+}
+
+func readMaintenanceWindows(ctx contractapi.TransactionContextInterface) ([]*MaintenanceWindow, error) {
+	resultsIterator, err := sharedStub(ctx).GetStateByPartialCompositeKey(maintenanceObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	// the keys are RFC 3339 UTC times, which sort chronologically
+	var windows []*MaintenanceWindow
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var window MaintenanceWindow
+		err = json.Unmarshal(queryResponse.Value, &window)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, &window)
+	}
+
+	return windows, nil
+}
+
+func deleteMaintenanceWindow(ctx contractapi.TransactionContextInterface, start string) error {
+	stub := sharedStub(ctx)
+	windowKey, err := stub.CreateCompositeKey(maintenanceObjectType, []string{start})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = stub.DelState(windowKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete from world state: %v", err)
+	}
+	return nil
+}
+
+// maintenanceHook rejects the state-changing transaction functions of callers
+// other than admins during a maintenance window, with the end of the window.
+func maintenanceHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	if readOnlyFunctions[call.FUNCTION] {
+		return nil
+	}
+
+	windows, err := readMaintenanceWindows(ctx)
+	if err != nil || len(windows) == 0 {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC().Format(time.RFC3339)
+
+	for _, window := range windows {
+		if window.START <= now && now < window.END {
+			if requireAdmin(ctx) == nil {
+				return nil
+			}
+			return businessError(errCodeMaintenance, "maintenance until %s, %s is not allowed: %s", window.END, call.FUNCTION, window.REASON)
+		}
+	}
+	return nil
+}
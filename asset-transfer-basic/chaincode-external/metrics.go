@@ -0,0 +1,217 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// metricsPrefix names the metrics of the chaincode server.
+const metricsPrefix = "asset_chaincode_"
+
+// unknownFunction labels the metrics of calls to functions the contract does
+// not have, so that clients cannot add labels at will.
+const unknownFunction = "unknown"
+
+// Histogram buckets of the per transaction metrics.
+var (
+	durationBuckets     = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+	keysWrittenBuckets  = []float64{0, 1, 2, 5, 10, 20, 50, 100, 500}
+	bytesWrittenBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576}
+)
+
+// contractFunctions are the names of the transaction functions of the contract.
+var contractFunctions = func() map[string]bool {
+	functions := make(map[string]bool)
+	contractType := reflect.TypeOf(new(SmartContract))
+	for i := 0; i < contractType.NumMethod(); i++ {
+		functions[contractType.Method(i).Name] = true
+	}
+	return functions
+}()
+
+// histogram is a cumulative Prometheus histogram.
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// functionMetrics are the metrics of the transactions of one contract function.
+// The state metrics only count the transactions that succeeded, as the writes
+// of failed transactions are discarded.
+type functionMetrics struct {
+	succeeded        uint64
+	failed           uint64
+	duration         *histogram
+	reads            uint64
+	keysWritten      *histogram
+	bytesWritten     *histogram
+	lastKeysWritten  int
+	lastBytesWritten int
+}
+
+// chaincodeMetrics collects the metrics of the transactions endorsed or
+// evaluated by this chaincode server, labelled by contract function. They are
+// served in the Prometheus text format on /metrics of METRICS_ADDRESS. The
+// metrics count simulations: a transaction endorsed by the peers of two
+// organizations is counted by the chaincode server of each.
+type chaincodeMetrics struct {
+	mu        sync.Mutex
+	functions map[string]*functionMetrics
+}
+
+func newChaincodeMetrics() *chaincodeMetrics {
+	return &chaincodeMetrics{functions: make(map[string]*functionMetrics)}
+}
+
+// observe records a transaction of function, which ran for duration and
+// accessed the state through stub.
+func (m *chaincodeMetrics) observe(function string, succeeded bool, stub *meteredStub, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics, ok := m.functions[function]
+	if !ok {
+		metrics = &functionMetrics{
+			duration:     newHistogram(durationBuckets),
+			keysWritten:  newHistogram(keysWrittenBuckets),
+			bytesWritten: newHistogram(bytesWrittenBuckets),
+		}
+		m.functions[function] = metrics
+	}
+
+	metrics.duration.observe(duration.Seconds())
+	if !succeeded {
+		metrics.failed++
+		return
+	}
+	metrics.succeeded++
+	metrics.reads += uint64(stub.reads)
+	metrics.keysWritten.observe(float64(stub.writes))
+	metrics.bytesWritten.observe(float64(stub.bytesWritten))
+	metrics.lastKeysWritten = stub.writes
+	metrics.lastBytesWritten = stub.bytesWritten
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *chaincodeMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	functions := make([]string, 0, len(m.functions))
+	for function := range m.functions {
+		functions = append(functions, function)
+	}
+	sort.Strings(functions)
+
+	writeHelp(w, "transactions_total", "counter", "Transactions simulated, by function and result.")
+	for _, function := range functions {
+		fmt.Fprintf(w, "%stransactions_total{function=%q,result=\"success\"} %d\n", metricsPrefix, function, m.functions[function].succeeded)
+		fmt.Fprintf(w, "%stransactions_total{function=%q,result=\"failure\"} %d\n", metricsPrefix, function, m.functions[function].failed)
+	}
+	writeHelp(w, "transaction_duration_seconds", "histogram", "Time spent simulating a transaction.")
+	for _, function := range functions {
+		writeHistogram(w, "transaction_duration_seconds", function, m.functions[function].duration)
+	}
+	writeHelp(w, "state_reads_total", "counter", "Keys read by the transactions that succeeded.")
+	for _, function := range functions {
+		fmt.Fprintf(w, "%sstate_reads_total{function=%q} %d\n", metricsPrefix, function, m.functions[function].reads)
+	}
+	writeHelp(w, "keys_written", "histogram", "Keys written or deleted per transaction, private data included.")
+	for _, function := range functions {
+		writeHistogram(w, "keys_written", function, m.functions[function].keysWritten)
+	}
+	writeHelp(w, "bytes_written", "histogram", "Bytes of keys and values written per transaction, private data included.")
+	for _, function := range functions {
+		writeHistogram(w, "bytes_written", function, m.functions[function].bytesWritten)
+	}
+	writeHelp(w, "last_keys_written", "gauge", "Keys written by the last transaction that succeeded.")
+	for _, function := range functions {
+		fmt.Fprintf(w, "%slast_keys_written{function=%q} %d\n", metricsPrefix, function, m.functions[function].lastKeysWritten)
+	}
+	writeHelp(w, "last_bytes_written", "gauge", "Bytes written by the last transaction that succeeded.")
+	for _, function := range functions {
+		fmt.Fprintf(w, "%slast_bytes_written{function=%q} %d\n", metricsPrefix, function, m.functions[function].lastBytesWritten)
+	}
+}
+
+func writeHelp(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+}
+
+func writeHistogram(w io.Writer, name string, function string, h *histogram) {
+	for i, bound := range h.buckets {
+		fmt.Fprintf(w, "%s%s_bucket{function=%q,le=\"%g\"} %d\n", metricsPrefix, name, function, bound, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s%s_bucket{function=%q,le=\"+Inf\"} %d\n", metricsPrefix, name, function, h.count)
+	fmt.Fprintf(w, "%s%s_sum{function=%q} %g\n", metricsPrefix, name, function, h.sum)
+	fmt.Fprintf(w, "%s%s_count{function=%q} %d\n", metricsPrefix, name, function, h.count)
+}
+
+// metricsChaincode records the metrics of every transaction invoked on the
+// chaincode. It hands the contract a meteredStub of its own, so the state
+// accesses of the hooks are counted along with those of the function.
+type metricsChaincode struct {
+	shim.Chaincode
+	metrics *chaincodeMetrics
+}
+
+func (c *metricsChaincode) Invoke(stub shim.ChaincodeStubInterface) *peer.Response {
+	started := time.Now()
+	metered := &meteredStub{ChaincodeStubInterface: stub}
+	response := c.Chaincode.Invoke(metered)
+
+	function, _ := stub.GetFunctionAndParameters()
+	// functions may be called as contractName:function
+	if i := strings.LastIndex(function, ":"); i >= 0 {
+		function = function[i+1:]
+	}
+	if !contractFunctions[function] {
+		function = unknownFunction
+	}
+	c.metrics.observe(function, response.GetStatus() < shim.ERRORTHRESHOLD, metered, time.Since(started))
+	return response
+}
+
+// serveMetrics serves the metrics on /metrics of address until the process exits.
+func serveMetrics(address string, metrics *chaincodeMetrics) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("metrics server stopped: %s", err)
+		}
+	}()
+}
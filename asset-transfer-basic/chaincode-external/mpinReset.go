@@ -0,0 +1,336 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// mpinResetObjectType is the composite key prefix of the MPIN reset cases,
+// keyed by asset.
+const mpinResetObjectType = "mpinreset"
+
+// mpinResetKind is the expiry kind of the MPIN reset cases.
+const mpinResetKind = "mpinreset"
+
+// transientMPINResetKey is the transient map entry carrying the
+// mpinResetInput of CompleteMPINReset.
+const transientMPINResetKey = "mpin_reset"
+
+// Statuses of an MPIN reset case.
+const (
+	mpinResetRequested = "REQUESTED"
+	mpinResetApproved  = "APPROVED"
+)
+
+// Events of the MPIN reset workflow. Their payload is the MPINReset.
+const (
+	mpinResetRequestedEvent = "MPINResetRequested"
+	mpinResetApprovedEvent  = "MPINResetApproved"
+	mpinResetEvent          = "MPINReset"
+)
+
+// A requested MPIN reset lapses unless an admin approves it within
+// mpinResetApprovalWindow, and an approved one unless it is completed within
+// mpinResetValidity of the approval.
+const (
+	mpinResetApprovalWindow = 72 * time.Hour
+	mpinResetValidity       = 24 * time.Hour
+)
+
+// MPINReset is a case resetting the MPIN of an asset. It is requested by the
+// dealer of the asset or an admin, approved by an admin other than the
+// requester, and completed with the new MPIN before EXPIRESAT.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type MPINReset struct {
+	APPROVEDAT  string `json:"approvedat"`
+	APPROVEDBY  string `json:"approvedby"`
+	ASSETID     string `json:"assetid"`
+	EXPIRESAT   string `json:"expiresat"`
+	REQUESTEDAT string `json:"requestedat"`
+	REQUESTEDBY string `json:"requestedby"`
+	STATUS      string `json:"status"`
+	TXID        string `json:"txid"`
+}
+
+// mpinResetInput is the transient payload of CompleteMPINReset.
+type mpinResetInput struct {
+	MPINHASH string `json:"mpinhash"`
+}
+
+func init() {
+	expiryReleasers[mpinResetKind] = releaseMPINReset
+}
+
+// RequestMPINReset opens a case resetting the MPIN of an asset, which an admin
+// must approve with ApproveMPINReset within 72 hours. Only admins and the
+// dealer of the asset may request it, and a new request replaces the open case.
+func (s *SmartContract) RequestMPINReset(ctx contractapi.TransactionContextInterface, id string) (*MPINReset, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if requireAdmin(ctx) != nil {
+		if err := requireDealer(ctx, asset.DEALERID); err != nil {
+			return nil, err
+		}
+	}
+
+	// a new request replaces the open case and its expiry
+	if err := deleteMPINReset(ctx, asset); err != nil {
+		return nil, err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+	expiresAt := now.Add(mpinResetApprovalWindow)
+	reset := &MPINReset{
+		ASSETID:     id,
+		EXPIRESAT:   expiresAt.Format(expiryTimeLayout),
+		REQUESTEDAT: now.Format(time.RFC3339),
+		REQUESTEDBY: clientID,
+		STATUS:      mpinResetRequested,
+		TXID:        ctx.GetStub().GetTxID(),
+	}
+	if err := putMPINReset(ctx, reset, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return reset, setMPINResetEvent(ctx, mpinResetRequestedEvent, reset)
+}
+
+// ApproveMPINReset approves the open MPIN reset case of an asset, which can
+// then be completed with CompleteMPINReset within 24 hours. Only admins may
+// approve it, and not the one who requested it.
+func (s *SmartContract) ApproveMPINReset(ctx contractapi.TransactionContextInterface, id string) (*MPINReset, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	reset, now, err := readOpenMPINReset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reset.STATUS != mpinResetRequested {
+		return nil, businessError(errCodeForbidden, "the MPIN reset of asset %s is already approved", id)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	if clientID == reset.REQUESTEDBY {
+		return nil, businessError(errCodeForbidden, "the MPIN reset of asset %s must be approved by another admin than its requester", id)
+	}
+
+	previousExpiry, err := time.Parse(expiryTimeLayout, reset.EXPIRESAT)
+	if err != nil {
+		return nil, err
+	}
+	if err := unregisterExpiry(ctx, mpinResetKind, []string{id}, previousExpiry); err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(mpinResetValidity)
+	reset.APPROVEDAT = now.Format(time.RFC3339)
+	reset.APPROVEDBY = clientID
+	reset.EXPIRESAT = expiresAt.Format(expiryTimeLayout)
+	reset.STATUS = mpinResetApproved
+	if err := putMPINReset(ctx, reset, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return reset, setMPINResetEvent(ctx, mpinResetApprovedEvent, reset)
+}
+
+// CompleteMPINReset sets the MPIN of an asset whose reset case is approved and
+// has not lapsed, and closes the case. newMPINHash is the hex encoded SHA-256
+// of "<asset id>:<new MPIN>", and may instead be passed in the mpinhash field
+// of the "mpin_reset" transient field, newMPINHash being empty, to keep it out
+// of the transaction. Only admins and the dealer of the asset may complete it.
+func (s *SmartContract) CompleteMPINReset(ctx contractapi.TransactionContextInterface, id string, newMPINHash string) (*MPINReset, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if requireAdmin(ctx) != nil {
+		if err := requireDealer(ctx, asset.DEALERID); err != nil {
+			return nil, err
+		}
+	}
+	reset, _, err := readOpenMPINReset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reset.STATUS != mpinResetApproved {
+		return nil, businessError(errCodeForbidden, "the MPIN reset of asset %s awaits the approval of an admin", id)
+	}
+
+	if newMPINHash == "" {
+		transientMap, err := ctx.GetStub().GetTransient()
+		if err != nil {
+			return nil, fmt.Errorf("error getting transient: %v", err)
+		}
+		var input mpinResetInput
+		if inputJSON, ok := transientMap[transientMPINResetKey]; ok {
+			if err := json.Unmarshal(inputJSON, &input); err != nil {
+				return nil, businessError(errCodeInvalidArgument, "failed to unmarshal %s: %v", transientMPINResetKey, err)
+			}
+		}
+		newMPINHash = input.MPINHASH
+	}
+	newMPINHash = strings.ToLower(newMPINHash)
+	if decoded, err := hex.DecodeString(newMPINHash); err != nil || len(decoded) != 32 {
+		return nil, businessError(errCodeInvalidArgument, "the new MPIN hash must be a hex encoded SHA-256 hash")
+	}
+
+	details, err := readAssetDetails(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	details.MPINHASH = newMPINHash
+	if err := deleteMPINReset(ctx, asset); err != nil {
+		return nil, err
+	}
+	if err := putAsset(ctx, asset, details); err != nil {
+		return nil, err
+	}
+
+	return reset, setMPINResetEvent(ctx, mpinResetEvent, reset)
+}
+
+// requireMPINUnchanged fails when input, the details of UpdateAsset, would
+// change the MPIN of an asset, which only the MPIN reset workflow does.
+func requireMPINUnchanged(ctx contractapi.TransactionContextInterface, id string, input *assetDetailsInput) error {
+	details, err := readAssetDetails(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hashMPIN(id, input.MPIN) != details.MPINHASH {
+		return businessError(errCodeForbidden, "the MPIN of asset %s can only be changed with RequestMPINReset", id)
+	}
+	return nil
+}
+
+func mpinResetKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(mpinResetObjectType, []string{id})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
+
+// readMPINReset returns the MPIN reset case of an asset, or nil when there is
+// none.
+func readMPINReset(ctx contractapi.TransactionContextInterface, id string) (*MPINReset, error) {
+	key, err := mpinResetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resetJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if resetJSON == nil {
+		return nil, nil
+	}
+
+	var reset MPINReset
+	if err := json.Unmarshal(resetJSON, &reset); err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// readOpenMPINReset returns the MPIN reset case of an asset and the
+// transaction time, failing when there is no case or it lapsed.
+func readOpenMPINReset(ctx contractapi.TransactionContextInterface, id string) (*MPINReset, time.Time, error) {
+	reset, err := readMPINReset(ctx, id)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if reset == nil {
+		return nil, time.Time{}, businessError(errCodeAssetNotFound, "the asset %s has no open MPIN reset", id)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+	if now.Format(expiryTimeLayout) >= reset.EXPIRESAT {
+		return nil, time.Time{}, businessError(errCodeForbidden, "the MPIN reset of asset %s lapsed at %s", id, reset.EXPIRESAT)
+	}
+	return reset, now, nil
+}
+
+// putMPINReset writes the MPIN reset case and registers its expiry.
+func putMPINReset(ctx contractapi.TransactionContextInterface, reset *MPINReset, expiresAt time.Time) error {
+	key, err := mpinResetKey(ctx, reset.ASSETID)
+	if err != nil {
+		return err
+	}
+	resetJSON, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, resetJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return registerExpiry(ctx, mpinResetKind, []string{reset.ASSETID}, expiresAt)
+}
+
+func setMPINResetEvent(ctx contractapi.TransactionContextInterface, name string, reset *MPINReset) error {
+	resetJSON, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetEvent(name, resetJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// deleteMPINReset deletes the MPIN reset case of an asset, if any, and its
+// expiry.
+func deleteMPINReset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	reset, err := readMPINReset(ctx, asset.ID)
+	if err != nil || reset == nil {
+		return err
+	}
+	expiresAt, err := time.Parse(expiryTimeLayout, reset.EXPIRESAT)
+	if err != nil {
+		return err
+	}
+	if err := unregisterExpiry(ctx, mpinResetKind, []string{asset.ID}, expiresAt); err != nil {
+		return err
+	}
+	return releaseMPINReset(ctx, []string{asset.ID})
+}
+
+// releaseMPINReset deletes the MPIN reset case of the asset whose ID is id.
+func releaseMPINReset(ctx contractapi.TransactionContextInterface, id []string) error {
+	if len(id) != 1 {
+		return fmt.Errorf("malformed MPIN reset id %q", id)
+	}
+	key, err := mpinResetKey(ctx, id[0])
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
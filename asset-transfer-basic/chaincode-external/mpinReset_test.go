@@ -0,0 +1,82 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestMPINResetRequiresApproval(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke := func(request localRequest, expectedError string) []byte {
+		t.Helper()
+		request.Submit = true
+		request.Admin = true
+		response, err := ledger.invoke(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectedError == "" && response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", request.Function, response.Status, response.Message)
+		}
+		if expectedError != "" && (response.Status == shim.OK || !strings.Contains(response.Message, expectedError)) {
+			t.Fatalf("expected %s to fail with %q, got status %d: %s", request.Function, expectedError, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	updateMPIN := func(mpin string) localRequest {
+		details, err := json.Marshal(assetDetailsInput{MPIN: mpin, MSISDN: "9877890123", REMARKS: "Personal loan disbursement"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return localRequest{
+			Function:  "UpdateAsset",
+			Args:      []string{"asset1", "DEALER101", "100000", "ACTIVE", "100000", "CREDIT"},
+			Transient: map[string][]byte{transientDetailsKey: details},
+		}
+	}
+	newMPINHash := hashMPIN("asset1", "2468")
+
+	invoke(localRequest{Function: "InitLedger"}, "")
+	invoke(updateMPIN("2468"), "can only be changed with RequestMPINReset")
+	invoke(localRequest{Function: "CompleteMPINReset", Args: []string{"asset1", newMPINHash}}, "has no open MPIN reset")
+
+	invoke(localRequest{Function: "RequestMPINReset", Args: []string{"asset1"}}, "")
+	invoke(localRequest{Function: "ApproveMPINReset", Args: []string{"asset1"}}, "another admin than its requester")
+	invoke(localRequest{Function: "CompleteMPINReset", Args: []string{"asset1", newMPINHash}}, "awaits the approval")
+
+	invoke(localRequest{Function: "ApproveMPINReset", Args: []string{"asset1"}, MSPID: "Org2MSP"}, "")
+	invoke(localRequest{Function: "CompleteMPINReset", Args: []string{"asset1", "1234"}}, "hex encoded SHA-256")
+	invoke(localRequest{Function: "CompleteMPINReset", Args: []string{"asset1", newMPINHash}}, "")
+	invoke(localRequest{Function: "CompleteMPINReset", Args: []string{"asset1", newMPINHash}}, "has no open MPIN reset")
+
+	invoke(updateMPIN("1598"), "can only be changed with RequestMPINReset")
+	invoke(updateMPIN("2468"), "")
+
+	var records []*AuditRecord
+	if err := json.Unmarshal(invoke(localRequest{Function: "GetAuditTrail", Args: []string{"asset1"}}, ""), &records); err != nil {
+		t.Fatal(err)
+	}
+	audited := make(map[string]bool)
+	for _, record := range records {
+		audited[record.ACTION] = true
+	}
+	for _, action := range []string{"RequestMPINReset", "ApproveMPINReset", "CompleteMPINReset"} {
+		if !audited[action] {
+			t.Errorf("expected an audit record of %s", action)
+		}
+	}
+}
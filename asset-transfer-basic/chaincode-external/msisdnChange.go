@@ -0,0 +1,336 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// msisdnChangeObjectType is the composite key prefix of the pending MSISDN
+// changes, keyed by asset, in the world state and in the collection of the
+// asset details.
+const msisdnChangeObjectType = "msisdnchange"
+
+// msisdnChangeKind is the expiry kind of the pending MSISDN changes.
+const msisdnChangeKind = "msisdnchange"
+
+// transientMSISDNChangeKey is the transient map entry carrying the
+// msisdnChangeInput of RequestMSISDNChange.
+const transientMSISDNChangeKey = "msisdn_change"
+
+// Events of the MSISDN change workflow, for notifying the subscriber on their
+// current number. Their payload is the MSISDNChange, without the new number.
+const (
+	msisdnChangeRequestedEvent = "MSISDNChangeRequested"
+	msisdnChangedEvent         = "MSISDNChanged"
+)
+
+// A requested MSISDN change can be confirmed once the cooling-off period has
+// passed, leaving the subscriber time to object to a change they did not ask
+// for, and until it lapses.
+const (
+	msisdnChangeCoolingOff = 24 * time.Hour
+	msisdnChangeValidity   = 72 * time.Hour
+)
+
+// MSISDNChange is the public record of a pending change of the MSISDN of an
+// asset. The new MSISDN and the hash of the one-time password confirming it are
+// kept in the collection of the asset details.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type MSISDNChange struct {
+	ASSETID       string `json:"assetid"`
+	CONFIRMABLEAT string `json:"confirmableat"`
+	EXPIRESAT     string `json:"expiresat"`
+	REQUESTEDAT   string `json:"requestedat"`
+	REQUESTEDBY   string `json:"requestedby"`
+	TXID          string `json:"txid"`
+}
+
+// msisdnChangeDetails is the private part of a pending MSISDN change.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type msisdnChangeDetails struct {
+	MSISDN  string `json:"msisdn"`
+	OTPHASH string `json:"otphash"`
+}
+
+// msisdnChangeInput is the transient payload of RequestMSISDNChange. OTPHASH is
+// the hex encoded SHA-256 of "<asset id>:<one-time password>", the password
+// being sent to the new MSISDN by the caller's notification service.
+type msisdnChangeInput struct {
+	MSISDN  string `json:"msisdn"`
+	OTPHASH string `json:"otphash"`
+}
+
+func init() {
+	expiryReleasers[msisdnChangeKind] = releaseMSISDNChange
+}
+
+// RequestMSISDNChange starts changing the MSISDN of an asset to newMSISDN. The
+// hash of the one-time password confirming the change is read from the
+// "msisdn_change" transient field, which may also carry the new MSISDN in its
+// msisdn field, newMSISDN being empty, to keep it out of the transaction. The
+// change can be confirmed with ConfirmMSISDNChange after a cooling-off period
+// of 24 hours, and lapses 72 hours later. Only admins and the dealer of the
+// asset may request it, and a new request replaces the pending one.
+func (s *SmartContract) RequestMSISDNChange(ctx contractapi.TransactionContextInterface, id string, newMSISDN string) (*MSISDNChange, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if requireAdmin(ctx) != nil {
+		if err := requireDealer(ctx, asset.DEALERID); err != nil {
+			return nil, err
+		}
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+	inputJSON, ok := transientMap[transientMSISDNChangeKey]
+	if !ok {
+		return nil, businessError(errCodeInvalidArgument, "%s must be a key in the transient map", transientMSISDNChangeKey)
+	}
+	var input msisdnChangeInput
+	if err := json.Unmarshal(inputJSON, &input); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "failed to unmarshal %s: %v", transientMSISDNChangeKey, err)
+	}
+	if newMSISDN == "" {
+		newMSISDN = input.MSISDN
+	}
+	if newMSISDN == "" || strings.Trim(newMSISDN, "0123456789") != "" {
+		return nil, businessError(errCodeInvalidArgument, "the new MSISDN must be a non-empty string of digits")
+	}
+	if len(input.OTPHASH) != 64 {
+		return nil, businessError(errCodeInvalidArgument, "the otphash of %s must be a hex encoded SHA-256 hash", transientMSISDNChangeKey)
+	}
+	details, err := readAssetDetails(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if details.MSISDN == newMSISDN {
+		return nil, businessError(errCodeInvalidArgument, "the asset %s already has this MSISDN", id)
+	}
+
+	// a new request replaces the pending one and its expiry
+	if err := deleteMSISDNChange(ctx, asset); err != nil {
+		return nil, err
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+	confirmableAt := now.Add(msisdnChangeCoolingOff)
+	expiresAt := confirmableAt.Add(msisdnChangeValidity)
+	change := &MSISDNChange{
+		ASSETID:       id,
+		CONFIRMABLEAT: confirmableAt.Format(time.RFC3339),
+		EXPIRESAT:     expiresAt.Format(expiryTimeLayout),
+		REQUESTEDAT:   now.Format(time.RFC3339),
+		REQUESTEDBY:   clientID,
+		TXID:          ctx.GetStub().GetTxID(),
+	}
+
+	key, err := msisdnChangeKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	privateJSON, err := json.Marshal(msisdnChangeDetails{MSISDN: newMSISDN, OTPHASH: strings.ToLower(input.OTPHASH)})
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutPrivateData(detailsCollection(asset), key, privateJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to private data collection: %v", err)
+	}
+	changeJSON, err := json.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(key, changeJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+	if err := registerExpiry(ctx, msisdnChangeKind, []string{id}, expiresAt); err != nil {
+		return nil, err
+	}
+
+	err = ctx.GetStub().SetEvent(msisdnChangeRequestedEvent, changeJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+	return change, nil
+}
+
+// ConfirmMSISDNChange applies the pending MSISDN change of an asset, once its
+// cooling-off period has passed and before it lapses. otpHash is the hex
+// encoded SHA-256 of "<asset id>:<one-time password>" for the password received
+// on the new MSISDN. Only admins and the dealer of the asset may confirm it.
+func (s *SmartContract) ConfirmMSISDNChange(ctx contractapi.TransactionContextInterface, id string, otpHash string) (*MSISDNChange, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if requireAdmin(ctx) != nil {
+		if err := requireDealer(ctx, asset.DEALERID); err != nil {
+			return nil, err
+		}
+	}
+	change, err := readMSISDNChange(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, businessError(errCodeAssetNotFound, "the asset %s has no pending MSISDN change", id)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+	if now.Format(time.RFC3339) < change.CONFIRMABLEAT {
+		return nil, businessError(errCodeForbidden, "the MSISDN change of asset %s is in its cooling-off period until %s", id, change.CONFIRMABLEAT)
+	}
+	if now.Format(expiryTimeLayout) >= change.EXPIRESAT {
+		return nil, businessError(errCodeForbidden, "the MSISDN change of asset %s lapsed at %s", id, change.EXPIRESAT)
+	}
+
+	key, err := msisdnChangeKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	privateJSON, err := ctx.GetStub().GetPrivateData(detailsCollection(asset), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	if privateJSON == nil {
+		return nil, fmt.Errorf("the details of the MSISDN change of asset %s do not exist", id)
+	}
+	var pending msisdnChangeDetails
+	if err := json.Unmarshal(privateJSON, &pending); err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.ToLower(otpHash)), []byte(pending.OTPHASH)) != 1 {
+		return nil, businessError(errCodeForbidden, "the one-time password of the MSISDN change of asset %s does not match", id)
+	}
+
+	details, err := readAssetDetails(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	details.MSISDN = pending.MSISDN
+	if err := deleteMSISDNChange(ctx, asset); err != nil {
+		return nil, err
+	}
+	if err := putAsset(ctx, asset, details); err != nil {
+		return nil, err
+	}
+
+	changeJSON, err := json.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().SetEvent(msisdnChangedEvent, changeJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+	return change, nil
+}
+
+// requireMSISDNUnchanged fails when input, the details of UpdateAsset, would
+// change the MSISDN of an asset, which only the MSISDN change workflow does.
+func requireMSISDNUnchanged(ctx contractapi.TransactionContextInterface, id string, input *assetDetailsInput) error {
+	details, err := readAssetDetails(ctx, id)
+	if err != nil {
+		return err
+	}
+	if input.MSISDN != details.MSISDN {
+		return businessError(errCodeForbidden, "the MSISDN of asset %s can only be changed with RequestMSISDNChange", id)
+	}
+	return nil
+}
+
+func msisdnChangeKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(msisdnChangeObjectType, []string{id})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
+
+// readMSISDNChange returns the pending MSISDN change of an asset, or nil when
+// there is none.
+func readMSISDNChange(ctx contractapi.TransactionContextInterface, id string) (*MSISDNChange, error) {
+	key, err := msisdnChangeKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	changeJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if changeJSON == nil {
+		return nil, nil
+	}
+
+	var change MSISDNChange
+	if err := json.Unmarshal(changeJSON, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// deleteMSISDNChange deletes the pending MSISDN change of an asset, if any, and
+// its expiry.
+func deleteMSISDNChange(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	change, err := readMSISDNChange(ctx, asset.ID)
+	if err != nil || change == nil {
+		return err
+	}
+	expiresAt, err := time.Parse(expiryTimeLayout, change.EXPIRESAT)
+	if err != nil {
+		return err
+	}
+	if err := unregisterExpiry(ctx, msisdnChangeKind, []string{asset.ID}, expiresAt); err != nil {
+		return err
+	}
+	return releaseMSISDNChange(ctx, []string{asset.ID})
+}
+
+// releaseMSISDNChange deletes the pending MSISDN change, public and private, of
+// the asset whose ID is id.
+func releaseMSISDNChange(ctx contractapi.TransactionContextInterface, id []string) error {
+	if len(id) != 1 {
+		return fmt.Errorf("malformed MSISDN change id %q", id)
+	}
+	key, err := msisdnChangeKey(ctx, id[0])
+	if err != nil {
+		return err
+	}
+	asset, err := readAssetSummary(ctx, id[0])
+	if err != nil {
+		return err
+	}
+	if asset != nil {
+		err = ctx.GetStub().DelPrivateData(detailsCollection(asset), key)
+		if err != nil {
+			return fmt.Errorf("failed to delete from private data collection: %v", err)
+		}
+	}
+	return ctx.GetStub().DelState(key)
+}
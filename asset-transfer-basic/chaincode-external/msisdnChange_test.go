@@ -0,0 +1,88 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestMSISDNChangeCoolingOff(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Now()
+	ledger.now = func() time.Time { return clock }
+	invoke := func(request localRequest, expectedError string) []byte {
+		t.Helper()
+		request.Submit = true
+		request.Admin = true
+		response, err := ledger.invoke(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectedError == "" && response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", request.Function, response.Status, response.Message)
+		}
+		if expectedError != "" && (response.Status == shim.OK || !strings.Contains(response.Message, expectedError)) {
+			t.Fatalf("expected %s to fail with %q, got status %d: %s", request.Function, expectedError, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	requestChange := func(msisdn string, otp string) {
+		t.Helper()
+		input, err := json.Marshal(msisdnChangeInput{MSISDN: msisdn, OTPHASH: hashMPIN("asset3", otp)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		invoke(localRequest{Function: "RequestMSISDNChange", Args: []string{"asset3", ""}, Transient: map[string][]byte{transientMSISDNChangeKey: input}}, "")
+	}
+	confirm := func(otp string, expectedError string) {
+		t.Helper()
+		invoke(localRequest{Function: "ConfirmMSISDNChange", Args: []string{"asset3", hashMPIN("asset3", otp)}}, expectedError)
+	}
+	advance := func(d time.Duration) {
+		clock = clock.Add(d)
+	}
+
+	invoke(localRequest{Function: "InitLedger"}, "")
+	requestChange("9800000001", "4711")
+	confirm("4711", "cooling-off period")
+	advance(msisdnChangeCoolingOff - time.Minute)
+	confirm("4711", "cooling-off period")
+
+	advance(2 * time.Minute)
+	confirm("1234", "does not match")
+	confirm("4711", "")
+	var details AssetDetails
+	if err := json.Unmarshal(invoke(localRequest{Function: "ReadAssetDetails", Args: []string{"asset3"}}, ""), &details); err != nil {
+		t.Fatal(err)
+	}
+	if details.MSISDN != "9800000001" {
+		t.Errorf("expected the confirmed MSISDN 9800000001, got %s", details.MSISDN)
+	}
+	confirm("4711", "no pending MSISDN change")
+
+	requestChange("9800000002", "4712")
+	advance(msisdnChangeCoolingOff + msisdnChangeValidity + time.Minute)
+	confirm("4712", "lapsed")
+	var result SweepResult
+	if err := json.Unmarshal(invoke(localRequest{Function: "SweepExpired", Args: []string{"0"}}, ""), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.RELEASED) != 1 || result.RELEASED[0].KIND != msisdnChangeKind {
+		t.Errorf("expected the sweep to release the lapsed MSISDN change, got %+v", result.RELEASED)
+	}
+	confirm("4712", "no pending MSISDN change")
+}
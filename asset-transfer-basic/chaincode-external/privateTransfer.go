@@ -0,0 +1,245 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// privateTransferObjectType is the composite key prefix of the public records of
+// the private transfers, keyed by transfer id.
+const privateTransferObjectType = "privatetransfer"
+
+// bilateralCollectionPrefix names the private data collections shared by two
+// organizations only, followed by their MSP IDs in lexical order, such as
+// bilateral_Org1MSP_Org2MSP. Every pair of organizations transferring privately
+// needs its collection in collections_config.json.
+const bilateralCollectionPrefix = "bilateral_"
+
+// transientTransferKey is the transient map entry carrying the
+// PrivateTransferDetails of CreatePrivateTransfer.
+const transientTransferKey = "transfer_details"
+
+// minTransferSaltLength is the shortest salt accepted, so that the commitment of
+// a transfer cannot be found by hashing the likely amounts.
+const minTransferSaltLength = 16
+
+// PrivateTransfer is the public record of a transfer between two organizations,
+// whose details are kept in their bilateral collection. COMMITMENT is the
+// SHA-256 hash of the details, checked by VerifyPrivateTransfer when they are
+// disclosed, such as during a dispute.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type PrivateTransfer struct {
+	COLLECTION string   `json:"collection"`
+	COMMITMENT string   `json:"commitment"`
+	CREATEDAT  string   `json:"createdat"`
+	ID         string   `json:"ID"`
+	PARTIES    []string `json:"parties"`
+	TXID       string   `json:"txid"`
+}
+
+// PrivateTransferDetails are the terms of a private transfer, only known to the
+// two organizations. SALT is a random string chosen by the initiator.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type PrivateTransferDetails struct {
+	AMOUNT    float64 `json:"amount"`
+	FROMASSET string  `json:"fromasset"`
+	ID        string  `json:"ID"`
+	REMARKS   string  `json:"remarks"`
+	SALT      string  `json:"salt"`
+	TOASSET   string  `json:"toasset"`
+}
+
+// CreatePrivateTransfer records a transfer between the organization of the
+// caller and counterpartyMSPID. The details are read from the
+// "transfer_details" transient field and written to the bilateral collection of
+// the two organizations, and only their commitment to the world state. The
+// assets are neither read nor changed, as the read/write set is public: the
+// parties settle the transfer between themselves.
+func (s *SmartContract) CreatePrivateTransfer(ctx contractapi.TransactionContextInterface, id string, counterpartyMSPID string) (*PrivateTransfer, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+	detailsJSON, ok := transientMap[transientTransferKey]
+	if !ok {
+		return nil, businessError(errCodeInvalidArgument, "%s must be a key in the transient map", transientTransferKey)
+	}
+	var details PrivateTransferDetails
+	if err := json.Unmarshal(detailsJSON, &details); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "failed to unmarshal %s: %v", transientTransferKey, err)
+	}
+	if details.ID == "" {
+		details.ID = id
+	}
+	if details.ID != id {
+		return nil, businessError(errCodeInvalidArgument, "the details are of transfer %s, not %s", details.ID, id)
+	}
+	if details.AMOUNT <= 0 || math.IsInf(details.AMOUNT, 0) || math.IsNaN(details.AMOUNT) {
+		return nil, businessError(errCodeInvalidArgument, "the amount of a transfer must be a positive number")
+	}
+	if details.FROMASSET == "" || details.TOASSET == "" {
+		return nil, businessError(errCodeInvalidArgument, "a transfer needs a fromasset and a toasset")
+	}
+	if len(details.SALT) < minTransferSaltLength {
+		return nil, businessError(errCodeInvalidArgument, "the salt of a transfer must be at least %d characters long", minTransferSaltLength)
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	if counterpartyMSPID == "" || counterpartyMSPID == mspID {
+		return nil, businessError(errCodeInvalidArgument, "the counterparty must be another organization than %s", mspID)
+	}
+	existing, err := readPrivateTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, businessError(errCodeAssetExists, "the transfer %s already exists", id)
+	}
+
+	parties := []string{mspID, counterpartyMSPID}
+	sort.Strings(parties)
+	collection := bilateralCollectionPrefix + parties[0] + "_" + parties[1]
+	commitment, err := transferCommitment(&details)
+	if err != nil {
+		return nil, err
+	}
+	privateJSON, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutPrivateData(collection, id, privateJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to private data collection %s: %v", collection, err)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	transfer := &PrivateTransfer{
+		COLLECTION: collection,
+		COMMITMENT: commitment,
+		CREATEDAT:  timestamp.AsTime().UTC().Format(time.RFC3339),
+		ID:         id,
+		PARTIES:    parties,
+		TXID:       ctx.GetStub().GetTxID(),
+	}
+	transferJSON, err := json.Marshal(transfer)
+	if err != nil {
+		return nil, err
+	}
+	transferKey, err := ctx.GetStub().CreateCompositeKey(privateTransferObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(transferKey, transferJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return transfer, nil
+}
+
+// ReadPrivateTransfer returns the details of a private transfer. Only members of
+// the two organizations may call it, on one of their peers.
+func (s *SmartContract) ReadPrivateTransfer(ctx contractapi.TransactionContextInterface, id string) (*PrivateTransferDetails, error) {
+	transfer, err := readPrivateTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, businessError(errCodeAssetNotFound, "the transfer %s does not exist", id)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	if mspID != transfer.PARTIES[0] && mspID != transfer.PARTIES[1] {
+		return nil, businessError(errCodeForbidden, "members of %s are not a party to the transfer %s", mspID, id)
+	}
+
+	detailsJSON, err := ctx.GetStub().GetPrivateData(transfer.COLLECTION, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	if detailsJSON == nil {
+		return nil, fmt.Errorf("the details of transfer %s do not exist", id)
+	}
+	var details PrivateTransferDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// VerifyPrivateTransfer returns true when detailsJSON, the disclosed details of
+// a private transfer, match the commitment recorded for it. It can be called on
+// any peer of the channel, as it reads no private data.
+func (s *SmartContract) VerifyPrivateTransfer(ctx contractapi.TransactionContextInterface, id string, detailsJSON string) (bool, error) {
+	transfer, err := readPrivateTransfer(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if transfer == nil {
+		return false, businessError(errCodeAssetNotFound, "the transfer %s does not exist", id)
+	}
+	var details PrivateTransferDetails
+	if err := json.Unmarshal([]byte(detailsJSON), &details); err != nil {
+		return false, businessError(errCodeInvalidArgument, "failed to unmarshal the transfer details: %v", err)
+	}
+
+	commitment, err := transferCommitment(&details)
+	if err != nil {
+		return false, err
+	}
+	return commitment == transfer.COMMITMENT, nil
+}
+
+// transferCommitment returns the hex encoded SHA-256 hash of the details, as
+// marshaled from the struct so that any JSON rendering of them hashes the same.
+func transferCommitment(details *PrivateTransferDetails) (string, error) {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(detailsJSON)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// readPrivateTransfer returns the public record of a private transfer, or nil
+// when it does not exist.
+func readPrivateTransfer(ctx contractapi.TransactionContextInterface, id string) (*PrivateTransfer, error) {
+	transferKey, err := ctx.GetStub().CreateCompositeKey(privateTransferObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	transferJSON, err := ctx.GetStub().GetState(transferKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if transferJSON == nil {
+		return nil, nil
+	}
+
+	var transfer PrivateTransfer
+	err = json.Unmarshal(transferJSON, &transfer)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
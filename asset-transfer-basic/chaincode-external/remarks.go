@@ -0,0 +1,211 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// remarkCodeObjectType is the composite key prefix of the remark codes, keyed by code.
+const remarkCodeObjectType = "remarkcode"
+
+// remarkCodeMetadataKey is the metadata entry of an asset holding the remark
+// code its remarks map to. The code is public, so reports group assets by
+// reason without reading the free text, which stays in the private details.
+const remarkCodeMetadataKey = "remarkcode"
+
+// maxRemarksLength is the longest remarks accepted, in characters.
+const maxRemarksLength = 140
+
+// remarkCodePattern is the form of remark codes, such as LOAN_DISBURSEMENT.
+var remarkCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,31}$`)
+
+// RemarkCode is a reason code the free-text remarks of assets are mapped onto.
+// Remarks containing one of the KEYWORDS, ignoring case, or equal to the code
+// map to it. LABELS are the descriptions of the code for display, keyed by
+// locale such as "en" or "hi".
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type RemarkCode struct {
+	CODE      string            `json:"code"`
+	KEYWORDS  []string          `json:"keywords"`
+	LABELS    map[string]string `json:"labels,omitempty" metadata:",optional"`
+	UPDATEDAT string            `json:"updatedat"`
+	UPDATEDBY string            `json:"updatedby"`
+}
+
+// SetRemarkCode creates or replaces the remark code described by codeJSON, a
+// RemarkCode without its update fields. Only admins may call it.
+func (s *SmartContract) SetRemarkCode(ctx contractapi.TransactionContextInterface, codeJSON string) (*RemarkCode, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var code RemarkCode
+	if err := json.Unmarshal([]byte(codeJSON), &code); err != nil {
+		return nil, businessError(errCodeInvalidArgument, "the remark code must be a JSON object: %v", err)
+	}
+	if !remarkCodePattern.MatchString(code.CODE) {
+		return nil, businessError(errCodeInvalidArgument, "the remark code %q must be up to 32 capital letters, digits and underscores", code.CODE)
+	}
+	keywords := make([]string, 0, len(code.KEYWORDS))
+	for _, keyword := range code.KEYWORDS {
+		keyword, err := normalizeRemarks(keyword)
+		if err != nil {
+			return nil, err
+		}
+		if keyword == "" {
+			return nil, businessError(errCodeInvalidArgument, "the keywords of %s must not be empty", code.CODE)
+		}
+		keywords = append(keywords, strings.ToLower(keyword))
+	}
+	code.KEYWORDS = keywords
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	code.UPDATEDAT = timestamp.AsTime().UTC().Format(time.RFC3339)
+	code.UPDATEDBY = clientID
+
+	storedJSON, err := json.Marshal(code)
+	if err != nil {
+		return nil, err
+	}
+	codeKey, err := ctx.GetStub().CreateCompositeKey(remarkCodeObjectType, []string{code.CODE})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(codeKey, storedJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return &code, nil
+}
+
+// DeleteRemarkCode removes a remark code. Assets already mapped to it keep it
+// until their remarks change. Only admins may call it.
+func (s *SmartContract) DeleteRemarkCode(ctx contractapi.TransactionContextInterface, code string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	codeKey, err := ctx.GetStub().CreateCompositeKey(remarkCodeObjectType, []string{code})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(codeKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete from world state: %v", err)
+	}
+	return nil
+}
+
+// GetRemarkCodes returns the remark codes, in order of their codes.
+func (s *SmartContract) GetRemarkCodes(ctx contractapi.TransactionContextInterface) ([]*RemarkCode, error) {
+	return readRemarkCodes(ctx)
+}
+
+// normalizeRemarks trims remarks and collapses their runs of white space,
+// including line breaks, into single spaces. It fails on remarks longer than
+// maxRemarksLength or holding other control characters.
+func normalizeRemarks(remarks string) (string, error) {
+	normalized := strings.Join(strings.Fields(remarks), " ")
+	if length := utf8.RuneCountInString(normalized); length > maxRemarksLength {
+		return "", businessError(errCodeInvalidArgument, "the remarks are %d characters long, at most %d are allowed", length, maxRemarksLength)
+	}
+	for _, r := range normalized {
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			return "", businessError(errCodeInvalidArgument, "the remarks hold the invalid character %q", r)
+		}
+	}
+	return normalized, nil
+}
+
+// applyRemarkCode sets the remark code of the asset to the first code, in order,
+// that remarks map to, removing it when none does. Without remark codes
+// configured, the metadata of the asset is left unchanged.
+func applyRemarkCode(ctx contractapi.TransactionContextInterface, asset *Asset, remarks string) error {
+	codes, err := readRemarkCodes(ctx)
+	if err != nil || len(codes) == 0 {
+		return err
+	}
+
+	matched := ""
+	lowered := strings.ToLower(remarks)
+	for _, code := range codes {
+		if remarks == code.CODE {
+			matched = code.CODE
+		}
+		for _, keyword := range code.KEYWORDS {
+			if strings.Contains(lowered, keyword) {
+				matched = code.CODE
+			}
+		}
+		if matched != "" {
+			break
+		}
+	}
+
+	if matched != "" {
+		metadata := make(map[string]string, len(asset.METADATA)+1)
+		for key, value := range asset.METADATA {
+			metadata[key] = value
+		}
+		metadata[remarkCodeMetadataKey] = matched
+		asset.METADATA = metadata
+		return nil
+	}
+	if _, ok := asset.METADATA[remarkCodeMetadataKey]; ok {
+		metadata := make(map[string]string, len(asset.METADATA))
+		for key, value := range asset.METADATA {
+			if key != remarkCodeMetadataKey {
+				metadata[key] = value
+			}
+		}
+		asset.METADATA = nil
+		if len(metadata) > 0 {
+			asset.METADATA = metadata
+		}
+	}
+	return nil
+}
+
+func readRemarkCodes(ctx contractapi.TransactionContextInterface) ([]*RemarkCode, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(remarkCodeObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	codes := []*RemarkCode{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var code RemarkCode
+		err = json.Unmarshal(queryResponse.Value, &code)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, &code)
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,53 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// sequenceObjectType is the composite key prefix of the named sequences.
+const sequenceObjectType = "sequence"
+
+// NextSequence reserves count consecutive values of the named sequence and
+// returns the first one. Values start at 1 and are never handed out twice, so
+// clients reserving blocks of values can generate unique IDs in parallel.
+// Concurrent reservations of one sequence fail with an MVCC read conflict and
+// must be retried.
+func (s *SmartContract) NextSequence(ctx contractapi.TransactionContextInterface, name string, count int) (int64, error) {
+	if name == "" {
+		return 0, fmt.Errorf("the sequence name must not be empty")
+	}
+	if count <= 0 {
+		return 0, fmt.Errorf("the count must be positive")
+	}
+
+	sequenceKey, err := ctx.GetStub().CreateCompositeKey(sequenceObjectType, []string{name})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	currentBytes, err := ctx.GetStub().GetState(sequenceKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	var current int64
+	if currentBytes != nil {
+		current, err = strconv.ParseInt(string(currentBytes), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("the sequence %s holds an invalid value: %v", name, err)
+		}
+	}
+
+	err = ctx.GetStub().PutState(sequenceKey, []byte(strconv.FormatInt(current+int64(count), 10)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return current + 1, nil
+}
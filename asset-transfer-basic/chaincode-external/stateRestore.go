@@ -0,0 +1,60 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// maxRestoreWrites bounds the writes of a single RestoreState transaction.
+const maxRestoreWrites = 500
+
+// StateWrite is a world state write of a backup, replayed by RestoreState. The
+// values of the contract are text, JSON documents or the null byte of indexes.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type StateWrite struct {
+	DELETE bool   `json:"delete,omitempty" metadata:",optional"`
+	KEY    string `json:"key"`
+	VALUE  string `json:"value,omitempty" metadata:",optional"`
+}
+
+// RestoreState writes the keys of a backup to the world state as they are,
+// including the indexes and counters derived from the assets, so the state of
+// the backed up channel is rebuilt exactly. Only admins may call it, and only
+// while the state-restore feature flag is on. Private data is not part of the
+// backups, which only see its hashes.
+func (s *SmartContract) RestoreState(ctx contractapi.TransactionContextInterface, writes []StateWrite) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	enabled, err := featureEnabled(ctx, featureStateRestore)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return businessError(errCodeForbidden, "the %s feature flag is off", featureStateRestore)
+	}
+	if len(writes) > maxRestoreWrites {
+		return businessError(errCodeInvalidArgument, "a restore transaction can hold up to %d writes, got %d", maxRestoreWrites, len(writes))
+	}
+
+	stub := sharedStub(ctx)
+	for _, write := range writes {
+		if write.KEY == "" {
+			return businessError(errCodeInvalidArgument, "a restored write needs a key")
+		}
+		if write.DELETE {
+			err = stub.DelState(write.KEY)
+		} else {
+			err = stub.PutState(write.KEY, []byte(write.VALUE))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %q: %v", write.KEY, err)
+		}
+	}
+	return nil
+}
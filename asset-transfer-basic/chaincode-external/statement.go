@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Bounds of the keys a resumable report processes in one invocation, so that
+// reports over the whole ledger never run into the chaincode execution timeout.
+const (
+	defaultReportKeys = 200
+	maxReportKeys     = 1000
+)
+
+// DealerStatement is one part of the statement of a dealer: the assets of the
+// dealer among the SCANNED keys processed by the invocation, and their total
+// BALANCE. BOOKMARK resumes the statement with the next keys and is empty once
+// every asset was processed; the totals of the whole statement are the sums of
+// those of its parts.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type DealerStatement struct {
+	ASSETS   []*Asset `json:"assets"`
+	BALANCE  float64  `json:"balance"`
+	BOOKMARK string   `json:"bookmark"`
+	DEALERID string   `json:"dealerid"`
+	SCANNED  int      `json:"scanned"`
+}
+
+// GetDealerStatement returns the assets of a dealer, scanning at most maxKeys
+// keys of the world state per invocation, 200 when zero. It does not need
+// CouchDB, so it also serves ledgers on LevelDB, and large ledgers are read
+// over several invocations resumed with the returned bookmark.
+func (s *SmartContract) GetDealerStatement(ctx contractapi.TransactionContextInterface, dealerID string, maxKeys int, bookmark string) (*DealerStatement, error) {
+	if dealerID == "" {
+		return nil, businessError(errCodeInvalidArgument, "the dealer ID is required")
+	}
+	budget, err := reportKeyBudget(maxKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", budget, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	statement := &DealerStatement{ASSETS: []*Asset{}, DEALERID: dealerID}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		statement.SCANNED++
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+		if asset.DEALERID != dealerID || asset.STATUS == statusDeleted {
+			continue
+		}
+		statement.ASSETS = append(statement.ASSETS, &asset)
+		statement.BALANCE += asset.BALANCE
+	}
+
+	statement.BOOKMARK = resumeBookmark(statement.SCANNED, budget, metadata.GetBookmark())
+	return statement, nil
+}
+
+// reportKeyBudget returns the number of keys a resumable report processes in
+// one invocation, given as maxKeys by the caller.
+func reportKeyBudget(maxKeys int) (int32, error) {
+	if maxKeys == 0 {
+		return defaultReportKeys, nil
+	}
+	if maxKeys < 0 || maxKeys > maxReportKeys {
+		return 0, businessError(errCodeInvalidArgument, "a report processes between 1 and %d keys per invocation, not %d", maxReportKeys, maxKeys)
+	}
+	return int32(maxKeys), nil
+}
+
+// resumeBookmark returns the bookmark resuming a report after processing scanned
+// keys of a budget, empty when the range was exhausted before the budget.
+func resumeBookmark(scanned int, budget int32, ledgerBookmark string) string {
+	if scanned < int(budget) {
+		return ""
+	}
+	return ledgerBookmark
+}
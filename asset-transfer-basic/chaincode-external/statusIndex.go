@@ -0,0 +1,99 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// statusAssetObjectType is the composite key prefix of the status~asset index,
+// keyed by status and asset id, which lists the assets of a status without
+// scanning the world state or a rich query. Soft-deleted assets are not
+// indexed.
+const statusAssetObjectType = "statusasset"
+
+// QueryAssetsByStatus returns the public summary of the assets of a status,
+// such as ACTIVE, INACTIVE or SUSPENDED, in asset id order, read through the
+// status~asset index. Assets last written before the index was introduced are
+// only listed once RebuildStatusIndex ran.
+func (s *SmartContract) QueryAssetsByStatus(ctx contractapi.TransactionContextInterface, status string) ([]*Asset, error) {
+	if !validStatuses[status] || status == statusDeleted {
+		return nil, businessError(errCodeInvalidArgument, "invalid status %q", status)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(statusAssetObjectType, []string{status})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	assets := []*Asset{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+
+		asset, err := readAssetSummary(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		// the index is written with the asset, so this only skips stale entries
+		if asset == nil || asset.STATUS != status {
+			continue
+		}
+		assets = append(assets, asset)
+	}
+
+	return assets, nil
+}
+
+// RebuildStatusIndex recreates the status~asset index from the assets in the
+// world state, for ledgers holding assets written before the index was
+// introduced. Only admins may call it.
+func (s *SmartContract) RebuildStatusIndex(ctx contractapi.TransactionContextInterface) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	return rebuildAssetIndex(ctx, statusAssetObjectType, updateStatusIndex)
+}
+
+// updateStatusIndex moves the status~asset index entry of the asset previous
+// replaced by current, either of which is nil when the asset is created or
+// deleted, as updateDealerIndex does for the dealer~asset index.
+func updateStatusIndex(ctx contractapi.TransactionContextInterface, previous *Asset, current *Asset) error {
+	indexed := func(asset *Asset) bool {
+		return asset != nil && asset.STATUS != statusDeleted
+	}
+
+	if indexed(previous) && !(indexed(current) && previous.STATUS == current.STATUS) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(statusAssetObjectType, []string{previous.STATUS, previous.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete status index entry: %v", err)
+		}
+	}
+	if indexed(current) {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(statusAssetObjectType, []string{current.STATUS, current.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put status index entry: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestStatusIndexFollowsAssets(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	checkStatus := func(status string, expected ...string) {
+		t.Helper()
+		var assets []*Asset
+		if err := json.Unmarshal(invoke("QueryAssetsByStatus", status), &assets); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, asset := range assets {
+			ids = append(ids, asset.ID)
+		}
+		if !slices.Equal(ids, expected) {
+			t.Errorf("expected the %s assets to be %v, got %v", status, expected, ids)
+		}
+	}
+
+	invoke("InitLedger")
+	checkStatus("INACTIVE", "asset5")
+	checkStatus("SUSPENDED")
+
+	invoke("PatchAsset", "asset6", `{"status":"SUSPENDED"}`)
+	checkStatus("SUSPENDED", "asset6")
+	checkStatus("ACTIVE", "asset1", "asset2", "asset3", "asset4", "asset7")
+
+	invoke("DeleteAsset", "asset6")
+	checkStatus("SUSPENDED")
+
+	invoke("RebuildStatusIndex")
+	checkStatus("INACTIVE", "asset5")
+
+	response, err := ledger.invoke(localRequest{Function: "QueryAssetsByStatus", Args: []string{"DELETED"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status == shim.OK {
+		t.Fatal("expected deleted assets not to be listed")
+	}
+}
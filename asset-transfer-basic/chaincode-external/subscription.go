@@ -0,0 +1,135 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// subscriptionObjectType is the composite key prefix of notification subscriptions, keyed by asset and subscriber.
+const subscriptionObjectType = "subscription"
+
+// Subscription routes the events of an asset to a subscriber. SUBSCRIBERREF is
+// opaque to the chaincode, the event bridge resolves it to a webhook or topic.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type Subscription struct {
+	ASSETID       string `json:"assetid"`
+	CREATEDAT     string `json:"createdat"`
+	OWNERMSP      string `json:"ownermsp"`
+	SUBSCRIBERREF string `json:"subscriberref"`
+}
+
+// Subscribe records that the events of the asset with given id are to be
+// delivered to subscriberRef. Subscribing again refreshes the subscription.
+func (s *SmartContract) Subscribe(ctx contractapi.TransactionContextInterface, assetID string, subscriberRef string) error {
+	if subscriberRef == "" {
+		return fmt.Errorf("the subscriber reference must not be empty")
+	}
+	exists, err := s.AssetExists(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return assetNotFoundError(assetID)
+	}
+
+	ownerMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	subscription := Subscription{
+		ASSETID:       assetID,
+		CREATEDAT:     timestamp.AsTime().UTC().Format(time.RFC3339),
+		OWNERMSP:      ownerMSP,
+		SUBSCRIBERREF: subscriberRef,
+	}
+	subscriptionJSON, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+
+	subscriptionKey, err := ctx.GetStub().CreateCompositeKey(subscriptionObjectType, []string{assetID, subscriberRef})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(subscriptionKey, subscriptionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return nil
+}
+
+// Unsubscribe removes the subscription of subscriberRef to the asset with given id.
+// Only the organization that created the subscription may remove it.
+func (s *SmartContract) Unsubscribe(ctx contractapi.TransactionContextInterface, assetID string, subscriberRef string) error {
+	subscriptionKey, err := ctx.GetStub().CreateCompositeKey(subscriptionObjectType, []string{assetID, subscriberRef})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	subscriptionJSON, err := ctx.GetStub().GetState(subscriptionKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if subscriptionJSON == nil {
+		return fmt.Errorf("%s is not subscribed to asset %s", subscriberRef, assetID)
+	}
+
+	var subscription Subscription
+	err = json.Unmarshal(subscriptionJSON, &subscription)
+	if err != nil {
+		return err
+	}
+	clientMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	if clientMSP != subscription.OWNERMSP {
+		return fmt.Errorf("the subscription of %s to asset %s belongs to %s", subscriberRef, assetID, subscription.OWNERMSP)
+	}
+
+	err = ctx.GetStub().DelState(subscriptionKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete from world state: %v", err)
+	}
+
+	return nil
+}
+
+// GetSubscriptions returns the subscriptions to the asset with given id,
+// ordered by subscriber reference.
+func (s *SmartContract) GetSubscriptions(ctx contractapi.TransactionContextInterface, assetID string) ([]*Subscription, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(subscriptionObjectType, []string{assetID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var subscriptions []*Subscription
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var subscription Subscription
+		err = json.Unmarshal(queryResponse.Value, &subscription)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	return subscriptions, nil
+}
@@ -0,0 +1,199 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// systemObjectType is the composite key prefix of the system state, so it
+// cannot collide with an asset id.
+const systemObjectType = "system"
+
+// System states. While PAUSED, every transaction function changing the ledger
+// is rejected, for incident response such as when fraud is detected.
+const (
+	systemActive = "ACTIVE"
+	systemPaused = "PAUSED"
+)
+
+// adminOU is the organizational unit of admin certificates with Fabric node OUs enabled.
+const adminOU = "admin"
+
+// readOnlyFunctions are the transaction functions still allowed while the system
+// is paused. Functions not listed are rejected, so new functions are frozen by
+// default.
+var readOnlyFunctions = map[string]bool{
+	"AssetExists":                true,
+	"ExportAssetForOrg":          true,
+	"GetAllAssets":               true,
+	"GetAllAssetsFields":         true,
+	"GetArchivedAsset":           true,
+	"GetArchivedAssets":          true,
+	"GetAssetHistory":            true,
+	"GetAssetProof":              true,
+	"GetAssetStats":              true,
+	"GetAssetsByRange":           true,
+	"GetAssetsFilteredFields":    true,
+	"GetAssetsSorted":            true,
+	"GetAttestation":             true,
+	"GetAttestationRequirements": true,
+	"GetAuditTrail":              true,
+	"GetBalanceSeries":           true,
+	"GetDataExportConsent":       true,
+	"GetDealerChildren":          true,
+	"GetDealerFloat":             true,
+	"GetDealerParent":            true,
+	"GetDealerRollup":            true,
+	"GetDealerStatement":         true,
+	"GetFeatureFlags":            true,
+	"GetHoldPolicy":              true,
+	"GetHolds":                   true,
+	"GetKeyHistoryReport":        true,
+	"GetLimitPolicy":             true,
+	"GetMaintenanceSchedule":     true,
+	"GetOracleKey":               true,
+	"GetRemarkCodes":             true,
+	"GetSubscriptions":           true,
+	"GetSwapConsent":             true,
+	"GetSystemState":             true,
+	"GetTotals":                  true,
+	"GetUsageReport":             true,
+	"GetVersionInfo":             true,
+	"QueryAssets":                true,
+	"QueryAssetsByDealer":        true,
+	"QueryAssetsByStatus":        true,
+	"ReadAsset":                  true,
+	"ReadAssetDetails":           true,
+	"ReadAssetFields":            true,
+	"ReadAssetPrivateDetailsFor": true,
+	"ReadPrivateTransfer":        true,
+	"ReadState":                  true,
+	"RunDataQualityChecks":       true,
+	"SampleAssets":               true,
+	"SearchAssets":               true,
+	"SetSystemState":             true,
+	"VerifyAssetDetails":         true,
+	"VerifyPrivateTransfer":      true,
+}
+
+func init() {
+	RegisterHook(HookBefore, systemStateHook)
+}
+
+// SystemState is the emergency switch of the ledger.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type SystemState struct {
+	REASON    string `json:"reason"`
+	STATE     string `json:"state"`
+	UPDATEDAT string `json:"updatedat"`
+	UPDATEDBY string `json:"updatedby"`
+}
+
+// SetSystemState pauses or resumes every state-changing transaction function.
+// Only admins of an organization may call it, and the state is shared by all
+// tenants.
+func (s *SmartContract) SetSystemState(ctx contractapi.TransactionContextInterface, state string, reason string) error {
+	if state != systemActive && state != systemPaused {
+		return fmt.Errorf("invalid system state %q, expected %s or %s", state, systemPaused, systemActive)
+	}
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	systemState := SystemState{
+		REASON:    reason,
+		STATE:     state,
+		UPDATEDAT: timestamp.AsTime().UTC().Format(time.RFC3339),
+		UPDATEDBY: mspID,
+	}
+	stateJSON, err := json.Marshal(systemState)
+	if err != nil {
+		return err
+	}
+
+	stub := sharedStub(ctx)
+	stateKey, err := stub.CreateCompositeKey(systemObjectType, []string{"state"})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = stub.PutState(stateKey, stateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent("SystemStateChanged", stateJSON)
+}
+
+// GetSystemState returns the system state, ACTIVE when it was never set.
+func (s *SmartContract) GetSystemState(ctx contractapi.TransactionContextInterface) (*SystemState, error) {
+	return readSystemState(sharedStub(ctx))
+}
+
+func readSystemState(stub shim.ChaincodeStubInterface) (*SystemState, error) {
+	stateKey, err := stub.CreateCompositeKey(systemObjectType, []string{"state"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	stateJSON, err := stub.GetState(stateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if stateJSON == nil {
+		return &SystemState{STATE: systemActive}, nil
+	}
+
+	var systemState SystemState
+	err = json.Unmarshal(stateJSON, &systemState)
+	if err != nil {
+		return nil, err
+	}
+
+	return &systemState, nil
+}
+
+// systemStateHook rejects state-changing transaction functions while the system is paused.
+func systemStateHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	if readOnlyFunctions[call.FUNCTION] {
+		return nil
+	}
+
+	systemState, err := readSystemState(sharedStub(ctx))
+	if err != nil {
+		return err
+	}
+	if systemState.STATE == systemPaused {
+		return businessError(errCodeSystemPaused, "the system is paused since %s, %s is not allowed: %s", systemState.UPDATEDAT, call.FUNCTION, systemState.REASON)
+	}
+	return nil
+}
+
+// requireAdmin fails unless the caller holds an admin certificate of its organization.
+func requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	certificate, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	for _, ou := range certificate.Subject.OrganizationalUnit {
+		if ou == adminOU {
+			return nil
+		}
+	}
+	return businessError(errCodeForbidden, "the caller is not an admin")
+}
@@ -0,0 +1,80 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestPausedSystemOnlyAllowsReads(t *testing.T) {
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(admin bool, function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, Admin: admin})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	invoke := func(function string, args ...string) []byte {
+		t.Helper()
+		response := call(true, function, args...)
+		if response.Status != shim.OK {
+			t.Fatalf("%s failed with status %d: %s", function, response.Status, response.Message)
+		}
+		return response.Payload
+	}
+	writes := [][]string{
+		{"UpdateAsset", "asset3", "DEALER103", "1400", "ACTIVE", "100", "DEBIT"},
+		{"TransferAsset", "asset3", "DEALER101"},
+		{"PlaceLien", "asset3", "100", "BANK1", "loan1"},
+		{"AllocateFloat", "DEALER103", "100"},
+	}
+
+	invoke("InitLedger")
+	if response := call(false, "SetSystemState", systemPaused, "fraud investigation"); response.Status == shim.OK || !strings.Contains(response.Message, "not an admin") {
+		t.Errorf("expected a client not to pause the system, got status %d: %s", response.Status, response.Message)
+	}
+	if response := call(true, "SetSystemState", "FROZEN", "fraud investigation"); response.Status == shim.OK {
+		t.Error("expected an unknown system state to be rejected")
+	}
+	invoke("SetSystemState", systemPaused, "fraud investigation")
+
+	var systemState SystemState
+	if err := json.Unmarshal(invoke("GetSystemState"), &systemState); err != nil {
+		t.Fatal(err)
+	}
+	if systemState.STATE != systemPaused || systemState.REASON != "fraud investigation" || systemState.UPDATEDBY != localMSPID {
+		t.Errorf("expected the system paused by %s for fraud investigation, got %+v", localMSPID, systemState)
+	}
+	for _, write := range writes {
+		if response := call(true, write[0], write[1:]...); response.Status == shim.OK || !strings.Contains(response.Message, errCodeSystemPaused) || !strings.Contains(response.Message, "fraud investigation") {
+			t.Errorf("expected %s to be refused while paused, got status %d: %s", write[0], response.Status, response.Message)
+		}
+	}
+	var asset Asset
+	if err := json.Unmarshal(invoke("ReadAsset", "asset3"), &asset); err != nil {
+		t.Fatal(err)
+	}
+	if asset.BALANCE != 1500 || asset.DEALERID != "DEALER103" {
+		t.Errorf("expected the paused writes to leave asset3 unchanged, got %+v", asset)
+	}
+
+	invoke("SetSystemState", systemActive, "investigation closed")
+	for _, write := range writes {
+		invoke(write[0], write[1:]...)
+	}
+}
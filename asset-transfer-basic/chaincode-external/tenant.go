@@ -0,0 +1,393 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// Tenant modes, selected with the TENANT_MODE environment variable. The mode must
+// be the same on the chaincode servers of every organization, or endorsements
+// will not match.
+const (
+	// tenantModeNone shares one keyspace between all callers.
+	tenantModeNone = ""
+	// tenantModeMSP gives every organization its own keyspace.
+	tenantModeMSP = "msp"
+	// tenantModeAttribute gives every value of the tenant attribute of the caller's
+	// certificate its own keyspace.
+	tenantModeAttribute = "attribute"
+)
+
+// tenantAttribute is the certificate attribute naming the tenant of a caller.
+const tenantAttribute = "tenant"
+
+// tenantSeparator ends the tenant prefix of a key. Range ends use the next byte.
+const tenantSeparator = "/"
+
+var tenantMode = os.Getenv("TENANT_MODE")
+
+// checkTenantMode validates the configured tenant mode.
+func checkTenantMode() error {
+	switch tenantMode {
+	case tenantModeNone, tenantModeMSP, tenantModeAttribute:
+		return nil
+	default:
+		return fmt.Errorf("unknown TENANT_MODE %q, expected msp or attribute", tenantMode)
+	}
+}
+
+// tenantStub isolates the keyspace of a tenant by prefixing every key it reads or
+// writes, and the object type of every composite key, with the tenant of the
+// caller. Query results are limited to the keys of the tenant and returned
+// without the prefix, so contract functions, including GetAll queries, only
+// ever see the assets of their tenant.
+type tenantStub struct {
+	shim.ChaincodeStubInterface
+	prefix string
+	err    error
+}
+
+// newTenantStub resolves the tenant of the caller of stub in the configured mode.
+func newTenantStub(stub shim.ChaincodeStubInterface) *tenantStub {
+	tenant, err := resolveTenant(stub)
+	if err == nil && (tenant == "" || strings.Contains(tenant, tenantSeparator)) {
+		err = fmt.Errorf("invalid tenant %q", tenant)
+	}
+	if err != nil {
+		err = businessError(errCodeForbidden, "%v", err)
+	}
+	return &tenantStub{ChaincodeStubInterface: stub, prefix: tenant + tenantSeparator, err: err}
+}
+
+func resolveTenant(stub shim.ChaincodeStubInterface) (string, error) {
+	if tenantMode == tenantModeMSP {
+		mspID, err := cid.GetMSPID(stub)
+		if err != nil {
+			return "", fmt.Errorf("failed to get client MSP ID: %v", err)
+		}
+		return mspID, nil
+	}
+
+	tenant, found, err := cid.GetAttributeValue(stub, tenantAttribute)
+	if err != nil {
+		return "", fmt.Errorf("failed to get client attribute %s: %v", tenantAttribute, err)
+	}
+	if !found {
+		return "", fmt.Errorf("the client identity has no %s attribute", tenantAttribute)
+	}
+	return tenant, nil
+}
+
+// sharedStub returns the stub of ctx without tenant isolation, for the state
+// shared by every tenant such as the system state.
+func sharedStub(ctx contractapi.TransactionContextInterface) shim.ChaincodeStubInterface {
+	stub := ctx.GetStub()
+	if metered, ok := stub.(*meteredStub); ok {
+		if tenant, ok := metered.ChaincodeStubInterface.(*tenantStub); ok {
+			return tenant.ChaincodeStubInterface
+		}
+	}
+	return stub
+}
+
+// key returns the key of the tenant for a simple key. Composite keys already
+// carry the tenant in their object type, and are refused when it is another
+// tenant's.
+func (s *tenantStub) key(key string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if strings.HasPrefix(key, "\x00") {
+		if !strings.HasPrefix(key, "\x00"+s.prefix) {
+			return "", businessError(errCodeForbidden, "the key %q belongs to another tenant", key)
+		}
+		return key, nil
+	}
+	return s.prefix + key, nil
+}
+
+// keyRange returns the range of the tenant for a range of simple keys, where
+// empty keys stand for the start and end of the keyspace.
+func (s *tenantStub) keyRange(startKey, endKey string) (string, string, error) {
+	if s.err != nil {
+		return "", "", s.err
+	}
+	if endKey == "" {
+		return s.prefix + startKey, s.prefix[:len(s.prefix)-1] + string(tenantSeparator[0]+1), nil
+	}
+	return s.prefix + startKey, s.prefix + endKey, nil
+}
+
+func (s *tenantStub) objectType(objectType string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.prefix + objectType, nil
+}
+
+// ownKey reports whether a key returned by a query belongs to the tenant,
+// returning simple keys without their prefix.
+func (s *tenantStub) ownKey(key string) (string, bool) {
+	if strings.HasPrefix(key, "\x00") {
+		return key, strings.HasPrefix(key, "\x00"+s.prefix)
+	}
+	if strings.HasPrefix(key, s.prefix) {
+		return key[len(s.prefix):], true
+	}
+	return "", false
+}
+
+func (s *tenantStub) GetState(key string) ([]byte, error) {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetState(tenantKey)
+}
+
+func (s *tenantStub) PutState(key string, value []byte) error {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.PutState(tenantKey, value)
+}
+
+func (s *tenantStub) DelState(key string) error {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.DelState(tenantKey)
+}
+
+func (s *tenantStub) SetStateValidationParameter(key string, ep []byte) error {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.SetStateValidationParameter(tenantKey, ep)
+}
+
+func (s *tenantStub) GetStateValidationParameter(key string) ([]byte, error) {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetStateValidationParameter(tenantKey)
+}
+
+func (s *tenantStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := s.keyRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.own(s.ChaincodeStubInterface.GetStateByRange(startKey, endKey))
+}
+
+func (s *tenantStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	startKey, endKey, err := s.keyRange(startKey, endKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bookmark != "" {
+		bookmark = s.prefix + bookmark
+	}
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	iterator, err = s.own(iterator, err)
+	if metadata != nil {
+		if bookmark, ok := s.ownKey(metadata.Bookmark); ok {
+			metadata.Bookmark = bookmark
+		}
+	}
+	return iterator, metadata, err
+}
+
+func (s *tenantStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	tenantObjectType, err := s.objectType(objectType)
+	if err != nil {
+		return "", err
+	}
+	return s.ChaincodeStubInterface.CreateCompositeKey(tenantObjectType, attributes)
+}
+
+func (s *tenantStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	objectType, attributes, err := s.ChaincodeStubInterface.SplitCompositeKey(compositeKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimPrefix(objectType, s.prefix), attributes, nil
+}
+
+func (s *tenantStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	tenantObjectType, err := s.objectType(objectType)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetStateByPartialCompositeKey(tenantObjectType, keys)
+}
+
+func (s *tenantStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	tenantObjectType, err := s.objectType(objectType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.ChaincodeStubInterface.GetStateByPartialCompositeKeyWithPagination(tenantObjectType, keys, pageSize, bookmark)
+}
+
+// GetQueryResult runs a rich query over the whole namespace and drops the results
+// of other tenants. Pages of paginated queries may therefore hold fewer results
+// than requested.
+func (s *tenantStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.own(s.ChaincodeStubInterface.GetQueryResult(query))
+}
+
+func (s *tenantStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	iterator, metadata, err := s.ChaincodeStubInterface.GetQueryResultWithPagination(query, pageSize, bookmark)
+	iterator, err = s.own(iterator, err)
+	return iterator, metadata, err
+}
+
+func (s *tenantStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetHistoryForKey(tenantKey)
+}
+
+func (s *tenantStub) GetPrivateData(collection, key string) ([]byte, error) {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetPrivateData(collection, tenantKey)
+}
+
+func (s *tenantStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetPrivateDataHash(collection, tenantKey)
+}
+
+func (s *tenantStub) PutPrivateData(collection string, key string, value []byte) error {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.PutPrivateData(collection, tenantKey, value)
+}
+
+func (s *tenantStub) DelPrivateData(collection, key string) error {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.DelPrivateData(collection, tenantKey)
+}
+
+func (s *tenantStub) PurgePrivateData(collection, key string) error {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.PurgePrivateData(collection, tenantKey)
+}
+
+func (s *tenantStub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.SetPrivateDataValidationParameter(collection, tenantKey, ep)
+}
+
+func (s *tenantStub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	tenantKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetPrivateDataValidationParameter(collection, tenantKey)
+}
+
+func (s *tenantStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := s.keyRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.own(s.ChaincodeStubInterface.GetPrivateDataByRange(collection, startKey, endKey))
+}
+
+func (s *tenantStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	tenantObjectType, err := s.objectType(objectType)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChaincodeStubInterface.GetPrivateDataByPartialCompositeKey(collection, tenantObjectType, keys)
+}
+
+func (s *tenantStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.own(s.ChaincodeStubInterface.GetPrivateDataQueryResult(collection, query))
+}
+
+// own wraps an iterator so that it only returns the keys of the tenant.
+func (s *tenantStub) own(iterator shim.StateQueryIteratorInterface, err error) (shim.StateQueryIteratorInterface, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &tenantIterator{StateQueryIteratorInterface: iterator, stub: s}, nil
+}
+
+// tenantIterator skips the results of other tenants, reading one result ahead.
+type tenantIterator struct {
+	shim.StateQueryIteratorInterface
+	stub *tenantStub
+	next *queryresult.KV
+	err  error
+}
+
+func (it *tenantIterator) HasNext() bool {
+	for it.next == nil && it.err == nil && it.StateQueryIteratorInterface.HasNext() {
+		result, err := it.StateQueryIteratorInterface.Next()
+		if err != nil {
+			it.err = err
+			break
+		}
+		if key, ok := it.stub.ownKey(result.Key); ok {
+			result.Key = key
+			it.next = result
+		}
+	}
+	return it.next != nil || it.err != nil
+}
+
+func (it *tenantIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	result, err := it.next, it.err
+	it.next, it.err = nil, nil
+	return result, err
+}
@@ -0,0 +1,56 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	defer func(mode string) { tenantMode = mode }(tenantMode)
+	tenantMode = tenantModeMSP
+
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := newLocalLedger(chaincode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke := func(mspID string, function string, args ...string) *localResponse {
+		t.Helper()
+		response, err := ledger.invoke(localRequest{Function: function, Args: args, Submit: true, MSPID: mspID, Admin: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	if response := invoke("Org1MSP", "InitLedger"); response.Status != shim.OK {
+		t.Fatalf("InitLedger failed with status %d: %s", response.Status, response.Message)
+	}
+	if response := invoke("Org1MSP", "AllocateFloat", "DEALER101", "100"); response.Status != shim.OK {
+		t.Fatalf("AllocateFloat failed with status %d: %s", response.Status, response.Message)
+	}
+
+	if response := invoke("Org2MSP", "ReadAsset", "asset1"); response.Status == shim.OK {
+		t.Errorf("expected the asset of Org1MSP to be hidden from Org2MSP, got %s", response.Payload)
+	}
+
+	floatKey := "\x00Org1MSP/float\x00DEALER101\x00"
+	if response := invoke("Org1MSP", "ReadState", floatKey); response.Status != shim.OK || !strings.Contains(string(response.Payload), "DEALER101") {
+		t.Errorf("expected Org1MSP to read its own float, got status %d: %s %s", response.Status, response.Message, response.Payload)
+	}
+	if response := invoke("Org2MSP", "ReadState", floatKey); response.Status == shim.OK || !strings.Contains(response.Message, "another tenant") {
+		t.Errorf("expected Org2MSP to be refused the float of Org1MSP, got status %d: %s %s", response.Status, response.Message, response.Payload)
+	}
+	if response := invoke("Org2MSP", "ReadState", "Org1MSP/asset1"); response.Status != shim.OK || len(response.Payload) > 2 {
+		t.Errorf("expected a simple key of Org2MSP to stay in its keyspace, got status %d: %s %s", response.Status, response.Message, response.Payload)
+	}
+}
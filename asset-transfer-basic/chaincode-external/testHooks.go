@@ -0,0 +1,123 @@
+//go:build testhooks
+
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// TestHooksContract writes the world state and the private data collections
+// bypassing every validation and index of SmartContract, so integration tests
+// can corrupt a ledger on purpose and check the data quality checks and error
+// paths against it. It is only compiled into test builds:
+//
+//	go build -tags testhooks
+//
+// and its functions are called with the testhooks: prefix, such as
+// testhooks:PutRawState. Only admins may call them.
+type TestHooksContract struct {
+	contractapi.Contract
+}
+
+func init() {
+	log.Printf("WARNING: built with the testhooks tag, the ledger can be corrupted through the testhooks contract")
+	testContracts = append(testContracts, &TestHooksContract{
+		Contract: contractapi.Contract{
+			Name:                      "testhooks",
+			TransactionContextHandler: new(meteredContext),
+		},
+	})
+}
+
+// PutRawState writes value to key as is, such as malformed JSON for an asset.
+func (t *TestHooksContract) PutRawState(ctx contractapi.TransactionContextInterface, key string, value string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	err := ctx.GetStub().PutState(key, []byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return nil
+}
+
+// DeleteRawState deletes key, leaving the index entries and counters of what it
+// held in place.
+func (t *TestHooksContract) DeleteRawState(ctx contractapi.TransactionContextInterface, key string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	err := ctx.GetStub().DelState(key)
+	if err != nil {
+		return fmt.Errorf("failed to delete from world state: %v", err)
+	}
+	return nil
+}
+
+// PutCompositeState writes value to the composite key of objectType and
+// attributes, such as an index entry pointing to a missing asset or a counter
+// shard out of step with the assets.
+func (t *TestHooksContract) PutCompositeState(ctx contractapi.TransactionContextInterface, objectType string, attributes []string, value string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(key, []byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+	return nil
+}
+
+// DeleteCompositeState deletes the composite key of objectType and attributes,
+// such as the index entry of an existing asset.
+func (t *TestHooksContract) DeleteCompositeState(ctx contractapi.TransactionContextInterface, objectType string, attributes []string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(key)
+	if err != nil {
+		return fmt.Errorf("failed to delete from world state: %v", err)
+	}
+	return nil
+}
+
+// PutRawPrivateData writes value to key of collection as is, such as asset
+// details no longer matching the hash of the asset.
+func (t *TestHooksContract) PutRawPrivateData(ctx contractapi.TransactionContextInterface, collection string, key string, value string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	err := ctx.GetStub().PutPrivateData(collection, key, []byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to put to private data collection: %v", err)
+	}
+	return nil
+}
+
+// DeleteRawPrivateData deletes key of collection, such as the details of an
+// existing asset.
+func (t *TestHooksContract) DeleteRawPrivateData(ctx contractapi.TransactionContextInterface, collection string, key string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	err := ctx.GetStub().DelPrivateData(collection, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete from private data collection: %v", err)
+	}
+	return nil
+}
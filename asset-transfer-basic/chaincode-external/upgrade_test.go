@@ -0,0 +1,441 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// upgradeFixtures holds the golden state written by previous versions of the
+// contract, one file per version. Fixtures are never edited once committed: a
+// read path failing on one means the new version cannot read a ledger written by
+// that version, and needs a migration rather than a fixture change.
+const upgradeFixtures = "testdata/upgrade"
+
+// upgradeUncovered are the read-only functions the fixtures cannot exercise,
+// with the reason. Every other function of readOnlyFunctions must be called by
+// the reads of at least one fixture.
+var upgradeUncovered = map[string]string{
+	"ExportAssetForOrg":          "requires the client identity",
+	"GetAssetsFiltered":          "runs a CouchDB query",
+	"GetAssetsFilteredFields":    "runs a CouchDB query",
+	"GetAssetsSorted":            "runs a CouchDB query",
+	"GetKeyHistoryReport":        "requires an admin client identity",
+	"GetVersionInfo":             "reports the build of the chaincode, not the state",
+	"QueryAssets":                "runs a CouchDB query",
+	"ReadAssetPrivateDetailsFor": "requires the client identity and the MSP of the peer",
+	"ReadPrivateTransfer":        "requires the client identity",
+	"SearchAssets":               "runs a CouchDB query",
+	"SetSystemState":             "changes the ledger",
+}
+
+// upgradeFixture is the state of a ledger written by a previous version and the
+// reads the current version must still serve from it. Keys containing ~ are
+// composite keys, objectType~attribute~... Expected values are matched as a
+// subset: only the fields they list are compared.
+type upgradeFixture struct {
+	Description string                                `json:"description"`
+	State       map[string]json.RawMessage            `json:"state"`
+	PrivateData map[string]map[string]json.RawMessage `json:"privateData"`
+	History     map[string][]upgradeModification      `json:"history"`
+	Reads       []upgradeRead                         `json:"reads"`
+}
+
+type upgradeModification struct {
+	TxID      string          `json:"txId"`
+	Timestamp time.Time       `json:"timestamp"`
+	Value     json.RawMessage `json:"value"`
+	IsDelete  bool            `json:"isDelete"`
+}
+
+type upgradeRead struct {
+	Function string          `json:"function"`
+	Args     []string        `json:"args"`
+	Expected json.RawMessage `json:"expected"`
+	Error    string          `json:"error"`
+}
+
+func TestUpgradeFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(upgradeFixtures, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no fixtures found in %s", upgradeFixtures)
+	}
+
+	chaincode, err := newChaincode()
+	if err != nil {
+		t.Fatalf("the chaincode cannot start: %v", err)
+	}
+
+	covered := make(map[string]bool)
+	for _, path := range paths {
+		fixture := loadUpgradeFixture(t, path)
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			for _, read := range fixture.Reads {
+				covered[read.Function] = true
+				name := read.Function + "(" + strings.Join(read.Args, ",") + ")"
+				t.Run(name, func(t *testing.T) {
+					checkUpgradeRead(t, chaincode, fixture, read)
+				})
+			}
+		})
+	}
+
+	for function := range readOnlyFunctions {
+		if !covered[function] && upgradeUncovered[function] == "" {
+			t.Errorf("no fixture reads %s, add it to the reads of the latest fixture", function)
+		}
+	}
+}
+
+func loadUpgradeFixture(t *testing.T, path string) *upgradeFixture {
+	t.Helper()
+	fixtureJSON, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture upgradeFixture
+	if err := json.Unmarshal(fixtureJSON, &fixture); err != nil {
+		t.Fatalf("invalid fixture %s: %v", path, err)
+	}
+	return &fixture
+}
+
+// checkUpgradeRead invokes a read function of the contract through the
+// chaincode, with the schema checks of the contract API, on a fresh
+// transaction over the fixture state and compares its result.
+func checkUpgradeRead(t *testing.T, chaincode *contractapi.ContractChaincode, fixture *upgradeFixture, read upgradeRead) {
+	stub := newFixtureStub(t, fixture)
+	stub.args = append([]string{read.Function}, read.Args...)
+
+	response := chaincode.Invoke(stub)
+	if response.GetStatus() != shim.OK {
+		if read.Error == "" || !strings.Contains(response.GetMessage(), read.Error) {
+			t.Fatalf("unexpected error: %s", response.GetMessage())
+		}
+		return
+	}
+	if read.Error != "" {
+		t.Fatalf("expected error %q", read.Error)
+	}
+	if read.Expected == nil {
+		return
+	}
+
+	var result, expected any
+	if err := json.Unmarshal(read.Expected, &expected); err != nil {
+		t.Fatalf("invalid expected value: %v", err)
+	}
+	resultJSON := response.GetPayload()
+	if _, ok := expected.(string); ok {
+		// the contract API returns strings as they are
+		result = string(resultJSON)
+	} else if len(resultJSON) == 0 {
+		resultJSON = []byte("null")
+	} else if err := json.Unmarshal(resultJSON, &result); err != nil {
+		t.Fatalf("invalid result %s: %v", resultJSON, err)
+	}
+	if field, ok := matchesSubset(expected, result, "result"); !ok {
+		t.Errorf("%s differs\nexpected: %s\ngot:      %s", field, read.Expected, resultJSON)
+	}
+}
+
+// matchesSubset reports whether actual holds every field of expected, and the
+// path of the first field differing.
+func matchesSubset(expected any, actual any, path string) (string, bool) {
+	switch expected := expected.(type) {
+	case map[string]any:
+		object, ok := actual.(map[string]any)
+		if !ok {
+			return path, false
+		}
+		for name, value := range expected {
+			if field, ok := matchesSubset(value, object[name], path+"."+name); !ok {
+				return field, false
+			}
+		}
+		return "", true
+	case []any:
+		array, ok := actual.([]any)
+		if !ok || len(array) != len(expected) {
+			return path, false
+		}
+		for i, value := range expected {
+			if field, ok := matchesSubset(value, array[i], fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return field, false
+			}
+		}
+		return "", true
+	default:
+		return path, reflect.DeepEqual(expected, actual)
+	}
+}
+
+// fixtureStub serves the reads of a transaction from the state of a fixture,
+// for a client of Org1MSP. Functions it does not implement panic, through the
+// nil embedded interface.
+type fixtureStub struct {
+	shim.ChaincodeStubInterface
+	args        []string
+	state       map[string][]byte
+	privateData map[string]map[string][]byte
+	history     map[string][]upgradeModification
+}
+
+func newFixtureStub(t *testing.T, fixture *upgradeFixture) *fixtureStub {
+	t.Helper()
+	stub := &fixtureStub{
+		state:       make(map[string][]byte),
+		privateData: make(map[string]map[string][]byte),
+		history:     fixture.History,
+	}
+	for key, value := range fixture.State {
+		stub.state[fixtureKey(key)] = compactJSON(t, value)
+	}
+	for collection, values := range fixture.PrivateData {
+		stub.privateData[collection] = make(map[string][]byte)
+		for key, value := range values {
+			stub.privateData[collection][fixtureKey(key)] = compactJSON(t, value)
+		}
+	}
+	return stub
+}
+
+// fixtureKey converts a fixture key written objectType~attribute~... to a
+// composite key.
+func fixtureKey(key string) string {
+	parts := strings.Split(key, "~")
+	if len(parts) == 1 {
+		return key
+	}
+	composite, _ := fixtureCompositeKey(parts[0], parts[1:])
+	return composite
+}
+
+func fixtureCompositeKey(objectType string, attributes []string) (string, error) {
+	return "\x00" + objectType + "\x00" + strings.Join(append(attributes, ""), "\x00"), nil
+}
+
+// fixtureCreator returns the serialized identity of the client of the reads, a
+// self-signed certificate of Org1MSP.
+var fixtureCreator = sync.OnceValues(func() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fixture", Organization: []string{"org1.example.com"}},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2124, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: certPEM})
+})
+
+func compactJSON(t *testing.T, value json.RawMessage) []byte {
+	t.Helper()
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, value); err != nil {
+		t.Fatalf("invalid fixture value %s: %v", value, err)
+	}
+	return compacted.Bytes()
+}
+
+func (s *fixtureStub) GetArgs() [][]byte {
+	args := make([][]byte, len(s.args))
+	for i, arg := range s.args {
+		args[i] = []byte(arg)
+	}
+	return args
+}
+
+func (s *fixtureStub) GetStringArgs() []string {
+	return s.args
+}
+
+func (s *fixtureStub) GetFunctionAndParameters() (string, []string) {
+	return s.args[0], s.args[1:]
+}
+
+func (s *fixtureStub) GetTxID() string {
+	return "fixture"
+}
+
+func (s *fixtureStub) GetChannelID() string {
+	return "mychannel"
+}
+
+func (s *fixtureStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)), nil
+}
+
+func (s *fixtureStub) GetCreator() ([]byte, error) {
+	return fixtureCreator()
+}
+
+// SetEvent discards the event, such as the FunctionDeprecated event of the
+// deprecated reads.
+func (s *fixtureStub) SetEvent(name string, payload []byte) error {
+	return nil
+}
+
+func (s *fixtureStub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *fixtureStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.privateData[collection][key], nil
+}
+
+func (s *fixtureStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, ok := s.privateData[collection][key]
+	if !ok {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *fixtureStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return fixtureCompositeKey(objectType, attributes)
+}
+
+func (s *fixtureStub) SplitCompositeKey(key string) (string, []string, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "\x00"), "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (s *fixtureStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.GetStateByRangeWithPagination(startKey, endKey, 0, "")
+	return iterator, err
+}
+
+// GetStateByRangeWithPagination returns the simple keys from startKey, or the
+// bookmark, to endKey. The bookmark is the first key of the next page.
+func (s *fixtureStub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	return s.page(func(key string) bool {
+		return !strings.HasPrefix(key, "\x00") && key >= startKey && (endKey == "" || key < endKey)
+	}, pageSize)
+}
+
+func (s *fixtureStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.GetStateByPartialCompositeKeyWithPagination(objectType, attributes, 0, "")
+	return iterator, err
+}
+
+func (s *fixtureStub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	prefix, _ := fixtureCompositeKey(objectType, attributes)
+	return s.page(func(key string) bool {
+		return strings.HasPrefix(key, prefix) && key >= bookmark
+	}, pageSize)
+}
+
+// page returns the keys matching in order, at most pageSize of them when set.
+func (s *fixtureStub) page(match func(key string) bool, pageSize int32) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	var keys []string
+	for key := range s.state {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	metadata := &peer.QueryResponseMetadata{}
+	if pageSize > 0 && len(keys) > int(pageSize) {
+		metadata.Bookmark = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+	iterator := &fixtureIterator{}
+	for _, key := range keys {
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	metadata.FetchedRecordsCount = int32(len(keys))
+	return iterator, metadata, nil
+}
+
+func (s *fixtureStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	iterator := &fixtureHistoryIterator{}
+	for _, modification := range s.history[key] {
+		iterator.results = append(iterator.results, &queryresult.KeyModification{
+			TxId:      modification.TxID,
+			Value:     modification.Value,
+			Timestamp: timestamppb.New(modification.Timestamp),
+			IsDelete:  modification.IsDelete,
+		})
+	}
+	return iterator, nil
+}
+
+type fixtureIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *fixtureIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *fixtureIterator) Next() (*queryresult.KV, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *fixtureIterator) Close() error {
+	return nil
+}
+
+type fixtureHistoryIterator struct {
+	results []*queryresult.KeyModification
+}
+
+func (i *fixtureHistoryIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *fixtureHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *fixtureHistoryIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,330 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// usageObjectType is the composite key prefix of usage records, keyed by dealer,
+// month and transaction. One record per transaction avoids contention on a
+// shared counter key between concurrent transactions of a dealer.
+const usageObjectType = "usage"
+
+// usageMonthLayout formats the month a usage record is charged to.
+const usageMonthLayout = "2006-01"
+
+func init() {
+	RegisterHook(HookAfter, usageHook)
+}
+
+// UsageRecord is the cost of one transaction.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type UsageRecord struct {
+	BYTESWRITTEN int    `json:"byteswritten"`
+	FUNCTION     string `json:"function"`
+	READS        int    `json:"reads"`
+	TXID         string `json:"txid"`
+	WRITES       int    `json:"writes"`
+}
+
+// UsageReport totals the usage records of a dealer over a month.
+// Insert struct field in alphabetic order => to achieve determinism across languages
+type UsageReport struct {
+	BYTESWRITTEN int    `json:"byteswritten"`
+	DEALERID     string `json:"dealerid"`
+	MONTH        string `json:"month"`
+	READS        int    `json:"reads"`
+	TRANSACTIONS int    `json:"transactions"`
+	WRITES       int    `json:"writes"`
+}
+
+// GetUsageReport returns the state reads, writes and bytes written by the
+// transactions charged to a dealer in month, formatted as 2006-01.
+func (s *SmartContract) GetUsageReport(ctx contractapi.TransactionContextInterface, dealerID string, month string) (*UsageReport, error) {
+	if _, err := time.Parse(usageMonthLayout, month); err != nil {
+		return nil, fmt.Errorf("invalid month %q, expected YYYY-MM", month)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(usageObjectType, []string{dealerID, month})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	report := &UsageReport{DEALERID: dealerID, MONTH: month}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record UsageRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		report.TRANSACTIONS++
+		report.READS += record.READS
+		report.WRITES += record.WRITES
+		report.BYTESWRITTEN += record.BYTESWRITTEN
+	}
+
+	return report, nil
+}
+
+// usageHook charges the state accesses of a transaction that wrote to the ledger
+// to the dealer of the asset it changed.
+func usageHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	metered, ok := ctx.(*meteredContext)
+	if !ok || metered.stub.writes == 0 {
+		return nil
+	}
+	record := UsageRecord{
+		BYTESWRITTEN: metered.stub.bytesWritten,
+		FUNCTION:     call.FUNCTION,
+		READS:        metered.stub.reads,
+		TXID:         ctx.GetStub().GetTxID(),
+		WRITES:       metered.stub.writes,
+	}
+
+	dealerID, err := usageDealer(ctx, call)
+	if err != nil || dealerID == "" {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	month := timestamp.AsTime().UTC().Format(usageMonthLayout)
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	usageKey, err := ctx.GetStub().CreateCompositeKey(usageObjectType, []string{dealerID, month, record.TXID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(usageKey, recordJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state: %v", err)
+	}
+
+	return nil
+}
+
+// usageDealer returns the dealer a transaction is charged to: the dealer argument of
+// CreateAsset, DeleteAssetsByDealer and the float functions, otherwise the dealer
+// of the asset named by the first argument. Transactions not naming an asset are
+// not charged.
+func usageDealer(ctx contractapi.TransactionContextInterface, call *HookCall) (string, error) {
+	if len(call.ARGS) == 0 {
+		return "", nil
+	}
+	switch call.FUNCTION {
+	case "CreateAsset":
+		if len(call.ARGS) > 1 {
+			return call.ARGS[1], nil
+		}
+		return "", nil
+	case "DeleteAssetsByDealer", "AllocateFloat", "ReturnFloat":
+		return call.ARGS[0], nil
+	}
+
+	// the world state still holds the asset as it was before the transaction
+	assetJSON, err := ctx.GetStub().GetState(call.ARGS[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return "", nil
+	}
+	var asset Asset
+	if err := json.Unmarshal(assetJSON, &asset); err != nil {
+		return "", nil
+	}
+	return asset.DEALERID, nil
+}
+
+// meteredContext is the transaction context of the contract. It counts the state
+// reads and writes made through its stub for usage accounting, and collects the
+// changes to the sharded counters and the debits of the transaction. Unchanged
+// is set by transactions that succeeded without changing the asset they were
+// called for.
+type meteredContext struct {
+	contractapi.TransactionContext
+	stub          *meteredStub
+	counterDeltas map[string]float64
+	debits        map[string]*assetDebit
+	unchanged     bool
+}
+
+// SetStub wraps the stub of the transaction in a meteredStub, isolating the
+// keyspace of the caller's tenant first when TENANT_MODE is set.
+func (c *meteredContext) SetStub(stub shim.ChaincodeStubInterface) {
+	if tenantMode != tenantModeNone {
+		stub = newTenantStub(stub)
+	}
+	c.stub = &meteredStub{ChaincodeStubInterface: stub}
+	c.counterDeltas = nil
+	c.debits = nil
+	c.unchanged = false
+	c.TransactionContext.SetStub(c.stub)
+}
+
+// markUnchanged records that the transaction left its asset unchanged, so that
+// the after hooks have nothing to record for it.
+func markUnchanged(ctx contractapi.TransactionContextInterface) {
+	if metered, ok := ctx.(*meteredContext); ok {
+		metered.unchanged = true
+	}
+}
+
+// isUnchanged reports whether the transaction was marked by markUnchanged.
+func isUnchanged(ctx contractapi.TransactionContextInterface) bool {
+	metered, ok := ctx.(*meteredContext)
+	return ok && metered.unchanged
+}
+
+// meteredStub counts the keys read and written, and the bytes written, by a
+// transaction, and collects the names of the keys for the audit records and
+// the event set. Errors accessing the ledger are returned as LEDGER_UNAVAILABLE
+// errors when transient, and as LEDGER_REJECTED errors otherwise, see
+// ledgerError, so that clients only retry the failures that may pass.
+type meteredStub struct {
+	shim.ChaincodeStubInterface
+	reads        int
+	writes       int
+	bytesWritten int
+	readKeys     map[string]struct{}
+	writtenKeys  map[string]struct{}
+	eventName    string
+	eventPayload []byte
+}
+
+// touch adds key to the set of keys read or written by the transaction.
+func touch(keys *map[string]struct{}, key string) {
+	if *keys == nil {
+		*keys = make(map[string]struct{})
+	}
+	(*keys)[key] = struct{}{}
+}
+
+func (s *meteredStub) GetState(key string) ([]byte, error) {
+	s.reads++
+	touch(&s.readKeys, key)
+	value, err := s.ChaincodeStubInterface.GetState(key)
+	return value, ledgerError(err)
+}
+
+func (s *meteredStub) PutState(key string, value []byte) error {
+	s.writes++
+	touch(&s.writtenKeys, key)
+	s.bytesWritten += len(key) + len(value)
+	return ledgerError(s.ChaincodeStubInterface.PutState(key, value))
+}
+
+func (s *meteredStub) SetEvent(name string, payload []byte) error {
+	s.eventName, s.eventPayload = name, payload
+	return s.ChaincodeStubInterface.SetEvent(name, payload)
+}
+
+func (s *meteredStub) DelState(key string) error {
+	s.writes++
+	touch(&s.writtenKeys, key)
+	return ledgerError(s.ChaincodeStubInterface.DelState(key))
+}
+
+func (s *meteredStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return s.metered(s.ChaincodeStubInterface.GetStateByRange(startKey, endKey))
+}
+
+func (s *meteredStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	iterator, err = s.metered(iterator, err)
+	return iterator, metadata, err
+}
+
+func (s *meteredStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	return s.metered(s.ChaincodeStubInterface.GetStateByPartialCompositeKey(objectType, keys))
+}
+
+func (s *meteredStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKeyWithPagination(objectType, keys, pageSize, bookmark)
+	iterator, err = s.metered(iterator, err)
+	return iterator, metadata, err
+}
+
+func (s *meteredStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return s.metered(s.ChaincodeStubInterface.GetQueryResult(query))
+}
+
+func (s *meteredStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, metadata, err := s.ChaincodeStubInterface.GetQueryResultWithPagination(query, pageSize, bookmark)
+	iterator, err = s.metered(iterator, err)
+	return iterator, metadata, err
+}
+
+func (s *meteredStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	iterator, err := s.ChaincodeStubInterface.GetHistoryForKey(key)
+	return iterator, ledgerError(err)
+}
+
+func (s *meteredStub) GetPrivateData(collection, key string) ([]byte, error) {
+	s.reads++
+	touch(&s.readKeys, privateKeyName(collection, key))
+	value, err := s.ChaincodeStubInterface.GetPrivateData(collection, key)
+	return value, ledgerError(err)
+}
+
+func (s *meteredStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	s.reads++
+	touch(&s.readKeys, privateKeyName(collection, key))
+	hash, err := s.ChaincodeStubInterface.GetPrivateDataHash(collection, key)
+	return hash, ledgerError(err)
+}
+
+func (s *meteredStub) PutPrivateData(collection string, key string, value []byte) error {
+	s.writes++
+	touch(&s.writtenKeys, privateKeyName(collection, key))
+	s.bytesWritten += len(key) + len(value)
+	return ledgerError(s.ChaincodeStubInterface.PutPrivateData(collection, key, value))
+}
+
+func (s *meteredStub) DelPrivateData(collection, key string) error {
+	s.writes++
+	touch(&s.writtenKeys, privateKeyName(collection, key))
+	return ledgerError(s.ChaincodeStubInterface.DelPrivateData(collection, key))
+}
+
+// metered wraps an iterator so that every result read from it is counted.
+func (s *meteredStub) metered(iterator shim.StateQueryIteratorInterface, err error) (shim.StateQueryIteratorInterface, error) {
+	if err != nil {
+		return nil, ledgerError(err)
+	}
+	return &meteredIterator{StateQueryIteratorInterface: iterator, stub: s}, nil
+}
+
+type meteredIterator struct {
+	shim.StateQueryIteratorInterface
+	stub *meteredStub
+}
+
+func (it *meteredIterator) Next() (*queryresult.KV, error) {
+	it.stub.reads++
+	kv, err := it.StateQueryIteratorInterface.Next()
+	if err == nil {
+		touch(&it.stub.readKeys, kv.GetKey())
+	}
+	return kv, ledgerError(err)
+}
@@ -0,0 +1,110 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// validatedFunctions maps transaction functions to the validation of their arguments.
+var validatedFunctions = map[string]func(args []string) error{
+	"CreateAsset":   validateAssetArgs,
+	"UpdateAsset":   validateAssetArgs,
+	"TransferAsset": validateTransferArgs,
+	"AllocateFloat": validateFloatArgs,
+	"ReturnFloat":   validateFloatArgs,
+}
+
+func init() {
+	RegisterHook(HookBefore, validationHook)
+}
+
+// validationHook rejects transactions whose arguments describe an invalid asset.
+func validationHook(ctx contractapi.TransactionContextInterface, call *HookCall) error {
+	validate, ok := validatedFunctions[call.FUNCTION]
+	if !ok {
+		return nil
+	}
+
+	err := validate(call.ARGS)
+	var chaincodeErr *ChaincodeError
+	if errors.As(err, &chaincodeErr) {
+		chaincodeErr.MESSAGE = fmt.Sprintf("invalid %s arguments: %s", call.FUNCTION, chaincodeErr.MESSAGE)
+	}
+	return err
+}
+
+// validateAssetArgs validates the id, dealerID, balance, status, transAmount and
+// transType arguments of CreateAsset and UpdateAsset.
+func validateAssetArgs(args []string) error {
+	if len(args) != 6 {
+		return businessError(errCodeInvalidArgument, "expected 6 arguments, got %d", len(args))
+	}
+	if args[0] == "" {
+		return businessError(errCodeInvalidArgument, "the asset id must not be empty")
+	}
+	if args[1] == "" {
+		return businessError(errCodeInvalidArgument, "the dealer id must not be empty")
+	}
+	if err := validateAmount("balance", args[2]); err != nil {
+		return err
+	}
+	// assets are only DELETED through DeleteAssetsByDealer
+	if !validStatuses[args[3]] || args[3] == statusDeleted {
+		return businessError(errCodeInvalidArgument, "status %q is not valid", args[3])
+	}
+	if err := validateAmount("transaction amount", args[4]); err != nil {
+		return err
+	}
+	if args[5] == "" {
+		return businessError(errCodeInvalidArgument, "the transaction type must not be empty")
+	}
+	return nil
+}
+
+// validateTransferArgs validates the id and newDealerID arguments of TransferAsset.
+func validateTransferArgs(args []string) error {
+	if len(args) != 2 {
+		return businessError(errCodeInvalidArgument, "expected 2 arguments, got %d", len(args))
+	}
+	if args[1] == "" {
+		return businessError(errCodeInvalidArgument, "the new dealer id must not be empty")
+	}
+	return nil
+}
+
+// validateFloatArgs validates the dealerID and amount arguments of AllocateFloat and ReturnFloat.
+func validateFloatArgs(args []string) error {
+	if len(args) != 2 {
+		return businessError(errCodeInvalidArgument, "expected 2 arguments, got %d", len(args))
+	}
+	if args[0] == "" {
+		return businessError(errCodeInvalidArgument, "the dealer id must not be empty")
+	}
+	if err := validateAmount("amount", args[1]); err != nil {
+		return err
+	}
+	if amount, _ := strconv.ParseFloat(args[1], 64); amount == 0 {
+		return businessError(errCodeInvalidArgument, "the amount must not be zero")
+	}
+	return nil
+}
+
+// validateAmount rejects a value that is not a finite, non-negative number.
+func validateAmount(name string, value string) error {
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return businessError(errCodeInvalidArgument, "the %s %q is not a number", name, value)
+	}
+	if amount < 0 {
+		return businessError(errCodeInvalidArgument, "the %s must not be negative", name)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ module rest-api-go
 go 1.22.0
 
 require (
+	assetTransfer v0.0.0-00010101000000-000000000000
 	github.com/hyperledger/fabric-gateway v1.7.0
 	google.golang.org/grpc v1.67.1
 )
@@ -17,3 +18,5 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142 // indirect
 	google.golang.org/protobuf v1.35.1 // indirect
 )
+
+replace assetTransfer => ../application-gateway-go
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"rest-api-go/web"
+	"strconv"
+	"strings"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
 )
 
 func main() {
@@ -10,17 +17,177 @@ func main() {
 	cryptoPath := "../../test-network/organizations/peerOrganizations/org1.example.com"
 	orgConfig := web.OrgSetup{
 		OrgName:      "Org1",
-		MSPID:        "Org1MSP",
+		MSPID:        getEnvOrDefault("MSP_ID", "Org1MSP"),
 		CertPath:     cryptoPath + "/users/User1@org1.example.com/msp/signcerts/cert.pem",
 		KeyPath:      cryptoPath + "/users/User1@org1.example.com/msp/keystore/",
 		TLSCertPath:  cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt",
-		PeerEndpoint: "dns:///localhost:7051",
-		GatewayPeer:  "peer0.org1.example.com",
+		PeerEndpoint: getEnvOrDefault("PEER_ENDPOINT", "dns:///localhost:7051"),
+		GatewayPeer:  getEnvOrDefault("GATEWAY_PEER", "peer0.org1.example.com"),
+		Channel:      getEnvOrDefault("CHANNEL_NAME", "mychannel"),
+		Chaincode:    getEnvOrDefault("CHAINCODE_NAME", "financial"),
+		RoleIdentities: map[string]web.RoleIdentity{
+			"admin": {
+				CertPath: cryptoPath + "/users/Admin@org1.example.com/msp/signcerts/cert.pem",
+				KeyPath:  cryptoPath + "/users/Admin@org1.example.com/msp/keystore/",
+			},
+		},
+	}
+	// SWEEP_INTERVAL, e.g. 1m, submits SweepExpired on a schedule as the admin role.
+	if interval, err := time.ParseDuration(os.Getenv("SWEEP_INTERVAL")); err == nil {
+		orgConfig.SweepInterval = interval
+	}
+	// CERT_PEM, KEY_PEM and TLS_CA_PEM replace the crypto material files, for
+	// serverless deployments passing them from a secret manager. ADMIN_CERT_PEM
+	// and ADMIN_KEY_PEM do the same for the admin role.
+	admin := orgConfig.RoleIdentities["admin"]
+	for name, pemBytes := range map[string]*[]byte{
+		"CERT_PEM":       &orgConfig.CertPEM,
+		"KEY_PEM":        &orgConfig.KeyPEM,
+		"TLS_CA_PEM":     &orgConfig.TLSCertPEM,
+		"ADMIN_CERT_PEM": &admin.CertPEM,
+		"ADMIN_KEY_PEM":  &admin.KeyPEM,
+	} {
+		value, _, err := assetclient.PEMFromEnv(name)
+		if err != nil {
+			fmt.Println("Error reading PEM material: ", err)
+			os.Exit(1)
+		}
+		*pemBytes = value
+	}
+	orgConfig.RoleIdentities["admin"] = admin
+	if poolSize, err := strconv.Atoi(os.Getenv("GRPC_POOL_SIZE")); err == nil {
+		orgConfig.PoolSize = poolSize
+	}
+	if idleTimeout, err := time.ParseDuration(os.Getenv("GRPC_POOL_IDLE_TIMEOUT")); err == nil {
+		orgConfig.PoolIdleTimeout = idleTimeout
+	}
+	// READ_PEERS is a JSON array of read peers, e.g.
+	// [{"endpoint":"dns:///localhost:8051","gatewayPeer":"peer1.org1.example.com","healthUrl":"http://localhost:9445/healthz"}]
+	if readPeers := os.Getenv("READ_PEERS"); readPeers != "" {
+		if err := json.Unmarshal([]byte(readPeers), &orgConfig.ReadPeers); err != nil {
+			fmt.Println("Ignoring invalid READ_PEERS: ", err)
+		}
+	}
+	consensus, err := assetclient.ParseConsensus(os.Getenv("ORDERER_CONSENSUS"))
+	if err != nil {
+		fmt.Println("Error reading ORDERER_CONSENSUS: ", err)
+		os.Exit(1)
+	}
+	orgConfig.Consensus = consensus
+	if interval, err := time.ParseDuration(os.Getenv("READ_HEALTH_INTERVAL")); err == nil {
+		orgConfig.ReadHealthInterval = interval
+	}
+	tlsOptions, err := assetclient.TLSOptionsFromEnv()
+	if err != nil {
+		fmt.Println("Error reading TLS options: ", err)
+		os.Exit(1)
+	}
+	orgConfig.TLSOptions = tlsOptions
+	// COMMIT_WAIT is what submitting routes wait for: none, submit, commit or
+	// orgs:N. COMMIT_WAITS is a JSON object of the waits of functions, e.g.
+	// {"TransferAsset":"commit"}, and COMMIT_PEERS a JSON array of the peers of
+	// other organizations confirming commits, e.g.
+	// [{"mspId":"Org2MSP","endpoint":"dns:///localhost:9051","gatewayPeer":"peer0.org2.example.com","tlsCertPath":"..."}]
+	orgConfig.CommitWait = os.Getenv("COMMIT_WAIT")
+	if waits := os.Getenv("COMMIT_WAITS"); waits != "" {
+		if err := json.Unmarshal([]byte(waits), &orgConfig.CommitWaits); err != nil {
+			fmt.Println("Error reading COMMIT_WAITS: ", err)
+			os.Exit(1)
+		}
+	}
+	if commitPeers := os.Getenv("COMMIT_PEERS"); commitPeers != "" {
+		if err := json.Unmarshal([]byte(commitPeers), &orgConfig.CommitPeers); err != nil {
+			fmt.Println("Error reading COMMIT_PEERS: ", err)
+			os.Exit(1)
+		}
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("TX_STATUS_TTL")); err == nil {
+		orgConfig.TxStatusTTL = ttl
+	}
+	// STUCK_TX_LOG enables following the transactions whose commit was not
+	// confirmed, kept in that file. IDEMPOTENT_FUNCTIONS is a comma separated
+	// list of the functions endorsed again when they fail validation.
+	orgConfig.StuckTxLog = os.Getenv("STUCK_TX_LOG")
+	if deadline, err := time.ParseDuration(os.Getenv("STUCK_TX_DEADLINE")); err == nil {
+		orgConfig.StuckTxDeadline = deadline
+	}
+	if functions := os.Getenv("IDEMPOTENT_FUNCTIONS"); functions != "" {
+		orgConfig.IdempotentFunctions = strings.Split(functions, ",")
 	}
+	if maxResubmissions, err := strconv.Atoi(os.Getenv("MAX_RESUBMISSIONS")); err == nil {
+		orgConfig.MaxResubmissions = maxResubmissions
+	}
+	// SLO_TARGET and SLO_OBJECTIVE set the commit latency objective served by
+	// GET /metrics, e.g. 5s and 0.99, and SLO_LOG the file logging every commit
+	// for the slo report command of the client.
+	if target, err := time.ParseDuration(os.Getenv("SLO_TARGET")); err == nil {
+		orgConfig.CommitSLO.Target = target
+	}
+	if objective, err := strconv.ParseFloat(os.Getenv("SLO_OBJECTIVE"), 64); err == nil {
+		orgConfig.CommitSLO.Objective = objective
+	}
+	orgConfig.CommitLatencyLog = os.Getenv("SLO_LOG")
+	// SERIALIZE_ASSETS=true orders the submissions changing the same asset.
+	if serialize, err := strconv.ParseBool(os.Getenv("SERIALIZE_ASSETS")); err == nil {
+		orgConfig.SerializeAssets = serialize
+	}
+	// STATE_STORE is the directory, redis:// or postgres:// URL of the store
+	// shared by the replicas of the server, enabling Idempotency-Key headers.
+	if location := os.Getenv("STATE_STORE"); location != "" {
+		store, err := assetclient.StateStoreFor(location)
+		if err != nil {
+			fmt.Println("Error reading STATE_STORE: ", err)
+			os.Exit(1)
+		}
+		orgConfig.StateStore = store
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil {
+		orgConfig.IdempotencyTTL = ttl
+	}
+	orgConfig.CacheControl = os.Getenv("CACHE_CONTROL")
+	if key := os.Getenv("PAGE_TOKEN_KEY"); key != "" {
+		orgConfig.PageTokenKey = []byte(key)
+	}
+
+	// API_KEYS_FILE enables the API keys, kept in that file. API_KEY_IDENTITIES
+	// is a JSON object of the identities keys can be issued for, e.g.
+	// {"dealer101":{"certPath":"...","keyPath":"..."}}
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		apiKeys, err := web.NewFileAPIKeyStore(path)
+		if err != nil {
+			fmt.Println("Error loading API keys: ", err)
+			os.Exit(1)
+		}
+		orgConfig.APIKeys = apiKeys
+	}
+	if identities := os.Getenv("API_KEY_IDENTITIES"); identities != "" {
+		if err := json.Unmarshal([]byte(identities), &orgConfig.KeyIdentities); err != nil {
+			fmt.Println("Error reading API_KEY_IDENTITIES: ", err)
+			os.Exit(1)
+		}
+	}
+
+	// FUNCTION_POLICY names a JSON file restricting the functions each identity
+	// may evaluate and submit.
+	functionPolicy, err := assetclient.FunctionPolicyFromEnv()
+	if err != nil {
+		fmt.Println("Error loading function policy: ", err)
+		os.Exit(1)
+	}
+	orgConfig.FunctionPolicy = functionPolicy
 
 	orgSetup, err := web.Initialize(orgConfig)
 	if err != nil {
 		fmt.Println("Error initializing setup for Org1: ", err)
+		os.Exit(1)
 	}
 	web.Serve(web.OrgSetup(*orgSetup))
 }
+
+func getEnvOrDefault(env, defaultVal string) string {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		value = defaultVal
+	}
+	return value
+}
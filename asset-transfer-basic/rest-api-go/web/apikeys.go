@@ -0,0 +1,268 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to recognize.
+const apiKeyPrefix = "ak_"
+
+// ErrAPIKeyNotFound is returned for API keys that were never issued or were revoked.
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKey is an issued API key. Callers presenting it act as the dealer DealerID,
+// signing their transactions with the identity named Identity in
+// OrgSetup.KeyIdentities, or the organization's identity when empty. Only the
+// SHA-256 hash of the secret part of the key is kept.
+type APIKey struct {
+	ID        string     `json:"id"`
+	DealerID  string     `json:"dealerId"`
+	Identity  string     `json:"identity,omitempty"`
+	Hash      string     `json:"hash"`
+	IssuedBy  string     `json:"issuedBy"`
+	IssuedAt  time.Time  `json:"issuedAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// APIKeyStore keeps the issued API keys. FileAPIKeyStore keeps them in a JSON
+// file, deployments with a database implement the interface over it.
+type APIKeyStore interface {
+	// Add stores a newly issued key.
+	Add(key APIKey) error
+	// Get returns the key with given ID, revoked or not.
+	Get(id string) (APIKey, error)
+	// List returns every key, revoked or not.
+	List() ([]APIKey, error)
+	// Revoke marks the key with given ID revoked at revokedAt.
+	Revoke(id string, revokedAt time.Time) error
+}
+
+// FileAPIKeyStore is an APIKeyStore kept in a JSON file, rewritten on every change.
+type FileAPIKeyStore struct {
+	path string
+
+	lock sync.Mutex
+	keys map[string]APIKey
+}
+
+// NewFileAPIKeyStore loads the API keys of the file at path, which is created on
+// the first change when it does not exist.
+func NewFileAPIKeyStore(path string) (*FileAPIKeyStore, error) {
+	store := &FileAPIKeyStore{path: path, keys: make(map[string]APIKey)}
+
+	keysJSON, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(keysJSON, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file %s: %w", path, err)
+	}
+	for _, key := range keys {
+		store.keys[key.ID] = key
+	}
+	return store, nil
+}
+
+func (s *FileAPIKeyStore) Add(key APIKey) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.keys[key.ID]; ok {
+		return fmt.Errorf("API key %s already exists", key.ID)
+	}
+	s.keys[key.ID] = key
+	return s.save()
+}
+
+func (s *FileAPIKeyStore) Get(id string) (APIKey, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *FileAPIKeyStore) List() ([]APIKey, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := make([]APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].IssuedAt.Before(keys[j].IssuedAt)
+	})
+	return keys, nil
+}
+
+func (s *FileAPIKeyStore) Revoke(id string, revokedAt time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &revokedAt
+	s.keys[id] = key
+	return s.save()
+}
+
+// save writes the keys to a temporary file renamed over the file, so a crash
+// never leaves a truncated file behind.
+func (s *FileAPIKeyStore) save() error {
+	keys := make([]APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	keysJSON, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save API keys: %w", err)
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(keysJSON); err != nil {
+		temporary.Close()
+		return fmt.Errorf("failed to save API keys: %w", err)
+	}
+	if err := temporary.Close(); err != nil {
+		return fmt.Errorf("failed to save API keys: %w", err)
+	}
+	return os.Rename(temporary.Name(), s.path)
+}
+
+// newAPIKey returns a key issued to dealerID with its secret, formatted as
+// ak_<id>_<secret>.
+func newAPIKey(dealerID string, identity string, issuedBy string) (APIKey, string, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return APIKey{}, "", fmt.Errorf("failed to generate an API key: %w", err)
+	}
+	id, secret := hex.EncodeToString(random[:8]), hex.EncodeToString(random[8:])
+	key := APIKey{
+		ID:       id,
+		DealerID: dealerID,
+		Identity: identity,
+		Hash:     hashAPIKeySecret(secret),
+		IssuedBy: issuedBy,
+		IssuedAt: time.Now().UTC(),
+	}
+	return key, apiKeyPrefix + id + "_" + secret, nil
+}
+
+func hashAPIKeySecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// authenticateAPIKey returns the key presented in the X-API-Key header, failing
+// for unknown, revoked or mistyped keys.
+func (setup *OrgSetup) authenticateAPIKey(presented string) (APIKey, error) {
+	id, secret, found := strings.Cut(strings.TrimPrefix(presented, apiKeyPrefix), "_")
+	if !found || !strings.HasPrefix(presented, apiKeyPrefix) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	key, err := setup.APIKeys.Get(id)
+	if err != nil {
+		return APIKey{}, err
+	}
+	if key.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashAPIKeySecret(secret))) != 1 {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// registerAPIKeyRoutes registers the admin routes managing the API keys.
+func (setup *OrgSetup) registerAPIKeyRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /admin/api-keys", setup.withRole(roleAdmin, setup.adminIssueAPIKey))
+	mux.HandleFunc("GET /admin/api-keys", setup.withRole(roleAdmin, setup.adminListAPIKeys))
+	mux.HandleFunc("DELETE /admin/api-keys/{id}", setup.withRole(roleAdmin, setup.adminRevokeAPIKey))
+}
+
+// adminIssueAPIKey issues a key acting as the dealer dealerId, signing with the
+// identity named identity. The key is only returned in this response.
+func (setup *OrgSetup) adminIssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	dealerID, identity := r.FormValue("dealerId"), r.FormValue("identity")
+	if dealerID == "" {
+		http.Error(w, "dealerId is required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := setup.identityGateways[identity]; identity != "" && !ok {
+		http.Error(w, fmt.Sprintf("unknown identity %q", identity), http.StatusBadRequest)
+		return
+	}
+
+	key, secret, err := newAPIKey(dealerID, identity, claims(r).User)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := setup.APIKeys.Add(key); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response, err := json.Marshal(struct {
+		APIKey
+		Key string `json:"key"`
+	}{key, secret})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResult(w, http.StatusCreated, response)
+}
+
+// adminListAPIKeys returns the issued keys, without their secrets.
+func (setup *OrgSetup) adminListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := setup.APIKeys.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response, err := json.Marshal(keys)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResult(w, http.StatusOK, response)
+}
+
+// adminRevokeAPIKey revokes a key, rejecting the requests presenting it from now on.
+func (setup *OrgSetup) adminRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := setup.APIKeys.Revoke(r.PathValue("id"), time.Now().UTC())
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
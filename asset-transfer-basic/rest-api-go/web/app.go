@@ -1,9 +1,12 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"assetTransfer/pkg/assetclient"
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
@@ -17,13 +20,149 @@ type OrgSetup struct {
 	TLSCertPath  string
 	PeerEndpoint string
 	GatewayPeer  string
-	Gateway      client.Gateway
+	// CertPEM, KeyPEM and TLSCertPEM hold the PEM-encoded identity and TLS CA
+	// material, used in place of the files at CertPath, KeyPath and TLSCertPath
+	// when set, for deployments without the crypto material on disk.
+	CertPEM    []byte
+	KeyPEM     []byte
+	TLSCertPEM []byte
+	// TLSOptions pin the peer TLS certificates and restrict the TLS versions and
+	// cipher suites, on top of trusting the TLS CA.
+	TLSOptions assetclient.TLSOptions
+	// PoolSize is the number of gRPC connections opened to the peer, used round-robin.
+	PoolSize int
+	// PoolIdleTimeout closes pooled connections left unused for longer, zero keeps them open.
+	PoolIdleTimeout time.Duration
+	// UserHeader names the request header carrying the authenticated API user,
+	// set by the authenticating reverse proxy. Defaults to X-Forwarded-User.
+	UserHeader string
+	// RolesHeader and DealerHeader name the headers carrying the groups and dealer
+	// claims of the caller. Default to X-Forwarded-Groups and X-Forwarded-Dealer.
+	RolesHeader  string
+	DealerHeader string
+	// Channel and Chaincode are used by the role routes.
+	Channel   string
+	Chaincode string
+	// RoleIdentities maps a role to the identity signing its transactions.
+	// Roles without one use the identity of the organization.
+	RoleIdentities map[string]RoleIdentity
+	Gateway        client.Gateway
+	// Discoverer plans the endorsing organizations of private data writes.
+	Discoverer *assetclient.Discoverer
+	// ReadPeers serve the evaluated transactions of the organization's identity,
+	// leaving the peer at PeerEndpoint to endorse and submit transactions. Queries
+	// fall back to PeerEndpoint while no read peer is healthy.
+	ReadPeers []ReadPeer
+	// Consensus is the consensus type of the ordering service. BFT ordering waits
+	// longer for transactions to be ordered and committed. Defaults to Raft.
+	Consensus assetclient.Consensus
+	// CommitWait is what the role routes submitting transactions wait for before
+	// responding, as parsed by assetclient.ParseCommitPolicy, overridden per
+	// request with the wait query parameter. Defaults to submit.
+	CommitWait string
+	// CommitWaits sets the CommitWait of chaincode functions, such as commit for
+	// TransferAsset, letting high-volume functions respond sooner than others.
+	CommitWaits map[string]string
+	// CommitPeers are peers of the other organizations, whose block events confirm
+	// the commits waited for with orgs:N.
+	CommitPeers []CommitPeer
+	// ReadHealthInterval is how often the read peers' health endpoints are checked.
+	// Defaults to 10 seconds.
+	ReadHealthInterval time.Duration
+	// PageTokenKey signs the page tokens handed out in place of chaincode
+	// bookmarks. Defaults to a random key, invalidating tokens on restart and
+	// across replicas of the server.
+	PageTokenKey []byte
+	// TxStatusTTL is how long the commit status of a transaction is kept for
+	// GET /transactions/{txid}. Defaults to 15 minutes.
+	TxStatusTTL time.Duration
+	// CacheControl is the Cache-Control header of the assets read by dealers,
+	// which carry their version as ETag. Defaults to "private, no-cache", making
+	// clients revalidate their copy with If-None-Match.
+	CacheControl string
+	// APIKeys keeps the API keys callers present in the X-API-Key header in place
+	// of the reverse proxy headers, acting as the dealer of the key. API keys are
+	// disabled when nil.
+	APIKeys APIKeyStore
+	// KeyIdentities maps the identity names API keys are issued for to the
+	// identity signing the transactions of their callers.
+	KeyIdentities map[string]RoleIdentity
+	// FunctionPolicy restricts the functions each signing identity may evaluate
+	// and submit, checked before calling the Gateway. Identities are named after
+	// their API key identity, their role, or "org" for the organization's
+	// identity. Every function is allowed when nil.
+	FunctionPolicy *assetclient.FunctionPolicy
+	// StuckTxLog is the file keeping the submitted transactions whose commit was
+	// not confirmed to their submitter, which are resubmitted when lost and
+	// listed by GET /admin/stuck-transactions. They are not followed when empty.
+	StuckTxLog string
+	// StuckTxDeadline is how long a transaction may go without being seen in a
+	// block before it is resent. Defaults to 2 minutes.
+	StuckTxDeadline time.Duration
+	// IdempotentFunctions are the chaincode functions endorsed again when their
+	// transaction fails validation, such as on an MVCC read conflict.
+	IdempotentFunctions []string
+	// MaxResubmissions is how many times a stuck transaction is resubmitted.
+	// Defaults to 3.
+	MaxResubmissions int
+	// SweepInterval is how often SweepExpired is submitted as the admin role to
+	// release the records past their expiry. They are only released by
+	// POST /admin/sweep when zero.
+	SweepInterval time.Duration
+	// SerializeAssets orders the submissions changing the same asset, each
+	// endorsed once the previous one is committed, so that concurrent requests on
+	// a hot wallet do not fail with MVCC read conflicts. Submissions on different
+	// assets stay parallel. The order holds within one server only.
+	SerializeAssets bool
+	// CommitSLO is the objective the submit-to-commit latency of the submitted
+	// transactions is counted against, served with it by GET /metrics.
+	// Defaults to assetclient.DefaultCommitSLO.
+	CommitSLO assetclient.CommitSLO
+	// CommitLatencyLog is the file the commit latency of every submitted
+	// transaction is appended to, summarized by the slo report command of the
+	// client. It is not kept when empty.
+	CommitLatencyLog string
+	// StateStore keeps the responses of the requests carrying an
+	// Idempotency-Key, shared by the replicas of the server. Idempotency keys
+	// are ignored when nil.
+	StateStore assetclient.StateStore
+	// IdempotencyTTL is how long the response of an Idempotency-Key is kept.
+	// Defaults to a day.
+	IdempotencyTTL time.Duration
+
+	roleGateways     map[string]*client.Gateway
+	identityGateways map[string]*client.Gateway
+	readRouter       *assetclient.ReplicaRouter
+	pageTokens       *assetclient.PageTokens
+	txStatuses       *assetclient.TxStatusStore
+	commitWaiter     *assetclient.CommitWaiter
+	stuckTxs         *assetclient.StuckTxManager
+	assetLocks       *assetclient.KeyLocks
+	commitMetrics    *assetclient.CommitMetrics
+	// clients keeps one chaincode client per signing identity, named as by
+	// identityName, shared by all requests.
+	clients map[string]*assetclient.Client
+}
+
+// ReadPeer is a peer of the organization dedicated to evaluated transactions. It
+// shares the TLS CA of the organization's peers.
+type ReadPeer struct {
+	Endpoint    string `json:"endpoint"`
+	GatewayPeer string `json:"gatewayPeer"`
+	// HealthURL is the /healthz endpoint of the peer's operations service.
+	HealthURL string `json:"healthUrl,omitempty"`
 }
 
 // Serve starts http web server.
 func Serve(setups OrgSetup) {
 	http.HandleFunc("/query", setups.Query)
 	http.HandleFunc("/invoke", setups.Invoke)
+	http.Handle("GET /metrics", setups.commitMetrics)
+	setups.registerRoleRoutes(http.DefaultServeMux)
+	if setups.APIKeys != nil {
+		setups.registerAPIKeyRoutes(http.DefaultServeMux)
+	}
+	setups.registerContractRoutes(context.Background(), http.DefaultServeMux)
 	fmt.Println("Listening (http://localhost:3000/)...")
 	if err := http.ListenAndServe(":3000", nil); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,26 @@
+package web
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// assetLockCommitTimeout bounds how long the assets of a submitted transaction
+// are held for its commit, which the response did not wait for.
+const assetLockCommitTimeout = 30 * time.Second
+
+// unlockOnCommit frees the assets of a submitted transaction once it is
+// committed, so that the next submission on them reads its writes. Under the
+// none commit wait there is no commit to wait for, and the assets are freed
+// once the transaction is endorsed.
+func unlockOnCommit(commit *client.Commit, unlock func()) {
+	defer unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), assetLockCommitTimeout)
+	defer cancel()
+	if _, err := commit.StatusWithContext(ctx); err != nil {
+		log.Printf("Failed to wait for the commit of transaction %s: %s", commit.TransactionID(), err)
+	}
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// assetFilter is the filter of GetAssetsFiltered, run by the chaincode's CouchDB
+// query rather than by the API.
+type assetFilter struct {
+	DealerID     string   `json:"dealerid,omitempty"`
+	MaxBalance   *float64 `json:"maxbalance,omitempty"`
+	MinBalance   *float64 `json:"minbalance,omitempty"`
+	Status       string   `json:"status,omitempty"`
+	UpdatedSince string   `json:"updatedsince,omitempty"`
+}
+
+// listAssets returns a page of the assets matching the dealer, status,
+// minBalance, maxBalance and updatedSince query parameters, sorted by sort.
+// fields, such as balance,status, lists the asset fields returned, all of them
+// when empty. Auditors and admins list every asset, dealers only their own.
+func (setup *OrgSetup) listAssets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := assetFilter{
+		DealerID:     query.Get("dealer"),
+		Status:       query.Get("status"),
+		UpdatedSince: query.Get("updatedSince"),
+	}
+	for name, bound := range map[string]**float64{"minBalance": &filter.MinBalance, "maxBalance": &filter.MaxBalance} {
+		if value := query.Get(name); value != "" {
+			amount, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				http.Error(w, name+" must be a number", http.StatusBadRequest)
+				return
+			}
+			*bound = &amount
+		}
+	}
+	if filter.UpdatedSince != "" {
+		if _, err := time.Parse(time.RFC3339, filter.UpdatedSince); err != nil {
+			http.Error(w, "updatedSince must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	requestClaims := claims(r)
+	role := roleAuditor
+	switch {
+	case requestClaims.hasRole(roleAuditor):
+	case requestClaims.hasRole(roleAdmin):
+		role = roleAdmin
+	default:
+		role = roleDealer
+		if requestClaims.DealerID == "" || (filter.DealerID != "" && filter.DealerID != requestClaims.DealerID) {
+			http.Error(w, "dealers may only list their own assets", http.StatusForbidden)
+			return
+		}
+		filter.DealerID = requestClaims.DealerID
+	}
+
+	filterJSON, err := json.Marshal(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fields := query.Get("fields"); fields != "" {
+		setup.evaluatePage(w, r, role, "GetAssetsFilteredFields", fields, string(filterJSON), query.Get("sort"))
+		return
+	}
+	setup.evaluatePage(w, r, role, "GetAssetsFiltered", string(filterJSON), query.Get("sort"))
+}
+
+// assetStats returns the number of assets by status and their total and
+// average balance, aggregated by the chaincode for the ops dashboard.
+func (setup *OrgSetup) assetStats(w http.ResponseWriter, r *http.Request) {
+	role := roleAuditor
+	if !claims(r).hasRole(roleAuditor) {
+		role = roleAdmin
+	}
+	setup.evaluate(w, r, role, "GetAssetStats")
+}
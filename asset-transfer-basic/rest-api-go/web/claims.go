@@ -0,0 +1,114 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Roles of the REST API callers, taken from the groups claim of their OIDC token.
+const (
+	roleAdmin   = "admin"
+	roleDealer  = "dealer"
+	roleAuditor = "auditor"
+)
+
+// Claims are the OIDC claims of an authenticated caller. The server sits behind an
+// authenticating reverse proxy, such as oauth2-proxy, that validates the token and
+// passes its claims as request headers.
+// Callers presenting an API key get the dealer role and the dealer of the key,
+// and sign with its Identity.
+type Claims struct {
+	User     string
+	Roles    []string
+	DealerID string
+	Identity string
+}
+
+type claimsKey struct{}
+
+// claims returns the claims of the request, as read by withRole.
+func claims(r *http.Request) Claims {
+	requestClaims, _ := r.Context().Value(claimsKey{}).(Claims)
+	return requestClaims
+}
+
+// hasRole returns true when the claims grant the role.
+func (c Claims) hasRole(role string) bool {
+	for _, granted := range c.Roles {
+		if granted == role {
+			return true
+		}
+	}
+	return false
+}
+
+// withRole only passes requests of authenticated callers granted the role to next.
+func (setup *OrgSetup) withRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestClaims := setup.readClaims(r)
+		if requestClaims.User == "" {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if !requestClaims.hasRole(role) {
+			http.Error(w, "the "+role+" role is required", http.StatusForbidden)
+			return
+		}
+		if role == roleDealer && requestClaims.DealerID == "" {
+			http.Error(w, "no dealer is associated with the caller", http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, requestClaims)))
+	}
+}
+
+// withAnyRole only passes requests of authenticated callers granted one of the
+// roles to next.
+func (setup *OrgSetup) withAnyRole(roles []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestClaims := setup.readClaims(r)
+		if requestClaims.User == "" {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		for _, role := range roles {
+			if requestClaims.hasRole(role) {
+				next(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, requestClaims)))
+				return
+			}
+		}
+		http.Error(w, "one of the "+strings.Join(roles, ", ")+" roles is required", http.StatusForbidden)
+	}
+}
+
+// readClaims reads the claims of the API key of the request, or the claims
+// passed by the reverse proxy from the request headers. Requests with an invalid
+// API key get no claims.
+func (setup *OrgSetup) readClaims(r *http.Request) Claims {
+	if presented := r.Header.Get("X-API-Key"); presented != "" && setup.APIKeys != nil {
+		key, err := setup.authenticateAPIKey(presented)
+		if err != nil {
+			return Claims{}
+		}
+		return Claims{User: "apikey:" + key.ID, Roles: []string{roleDealer}, DealerID: key.DealerID, Identity: key.Identity}
+	}
+
+	rolesHeader := setup.RolesHeader
+	if rolesHeader == "" {
+		rolesHeader = "X-Forwarded-Groups"
+	}
+	dealerHeader := setup.DealerHeader
+	if dealerHeader == "" {
+		dealerHeader = "X-Forwarded-Dealer"
+	}
+
+	requestClaims := Claims{User: setup.apiUser(r), DealerID: r.Header.Get(dealerHeader)}
+	for _, role := range strings.Split(r.Header.Get(rolesHeader), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			requestClaims.Roles = append(requestClaims.Roles, role)
+		}
+	}
+	return requestClaims
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+
+	"assetTransfer/pkg/assetclient"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// defaultCommitWait is the commit wait of the role routes when none is
+// configured: the response is sent once the orderers accepted the transaction,
+// and its commit is looked up with GET /transactions/{txid}.
+const defaultCommitWait = assetclient.WaitSubmit
+
+// CommitPeer is a peer of another organization whose block events confirm the
+// commits waited for with orgs:N.
+type CommitPeer struct {
+	MspID       string `json:"mspId"`
+	Endpoint    string `json:"endpoint"`
+	GatewayPeer string `json:"gatewayPeer"`
+	// TLSCertPath is the TLS CA of the peer's organization.
+	TLSCertPath string `json:"tlsCertPath"`
+}
+
+// newCommitWaiter returns the waiter of the role routes. The block events of
+// the organization's own peer confirm its commits, and those of the
+// CommitPeers the commits of the other organizations.
+func (setup *OrgSetup) newCommitWaiter(connections *assetclient.ConnectionManager) (*assetclient.CommitWaiter, error) {
+	waiter := &assetclient.CommitWaiter{
+		Orgs: []assetclient.OrgBlocks{assetclient.NetworkBlocks(setup.MSPID, setup.Gateway.GetNetwork(setup.Channel))},
+		SubmitFailed: func(transactionID string, err error) {
+			log.Printf("Failed to submit transaction %s: %s", transactionID, assetclient.NewMultiPeerError(err))
+		},
+	}
+	for _, commitPeer := range setup.CommitPeers {
+		certificate, err := loadCertificate(nil, commitPeer.TLSCertPath)
+		if err != nil {
+			return nil, err
+		}
+		certPool := x509.NewCertPool()
+		certPool.AddCert(certificate)
+		// the pinned fingerprints are those of the organization's own peers
+		tlsOptions := assetclient.TLSOptions{MinVersion: setup.TLSOptions.MinVersion, CipherSuites: setup.TLSOptions.CipherSuites}
+		tlsConfig := tlsOptions.Config(certPool, commitPeer.GatewayPeer)
+		connection := connections.Pool(commitPeer.Endpoint, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+
+		gateway, err := connectGateway(connection, setup.newIdentity(), setup.newSign(), setup.consensus())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to commit peer %s: %w", commitPeer.Endpoint, err)
+		}
+		waiter.Orgs = append(waiter.Orgs, assetclient.NetworkBlocks(commitPeer.MspID, gateway.GetNetwork(setup.Channel)))
+	}
+	return waiter, nil
+}
+
+// commitPolicy returns the commit wait of a request submitting function: the
+// wait query parameter, else the wait configured for function, else the
+// default of the server.
+func (setup *OrgSetup) commitPolicy(r *http.Request, function string) (assetclient.CommitPolicy, error) {
+	wait := r.URL.Query().Get("wait")
+	if wait == "" {
+		wait = setup.CommitWaits[function]
+	}
+	if wait == "" {
+		wait = setup.CommitWait
+	}
+	if wait == "" {
+		return assetclient.CommitPolicy{Wait: defaultCommitWait}, nil
+	}
+	return assetclient.ParseCommitPolicy(wait)
+}
@@ -0,0 +1,436 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// metadataFunction is the system transaction returning the contractapi metadata
+// of the chaincode, describing its contracts and their transactions.
+const metadataFunction = "org.hyperledger.fabric:GetMetadata"
+
+// contractMetadata is the part of the contractapi metadata used to generate the
+// contract routes.
+type contractMetadata struct {
+	Contracts  map[string]contractDescription `json:"contracts"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+type contractDescription struct {
+	Name         string                `json:"name"`
+	Transactions []transactionMetadata `json:"transactions"`
+}
+
+// transactionMetadata describes a transaction function, its parameters in call
+// order and the schema of its result.
+type transactionMetadata struct {
+	Name       string              `json:"name"`
+	Tag        []string            `json:"tag"`
+	Parameters []parameterMetadata `json:"parameters"`
+	Returns    map[string]any      `json:"returns,omitempty"`
+}
+
+type parameterMetadata struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+// evaluated returns true when the transaction is tagged to be evaluated rather
+// than submitted.
+func (tx transactionMetadata) evaluated() bool {
+	for _, tag := range tx.Tag {
+		if strings.EqualFold(tag, "evaluate") || strings.EqualFold(tag, "evaluateTx") {
+			return true
+		}
+	}
+	return false
+}
+
+// contractRoutes exposes the transactions described by the chaincode metadata
+// under /contract/{fn}, with their typed parameters taken from a JSON body.
+type contractRoutes struct {
+	setup        *OrgSetup
+	metadata     *contractMetadata
+	transactions map[string]transactionMetadata
+}
+
+// registerContractRoutes reads the metadata of the chaincode and registers the
+// contract routes and the OpenAPI document describing them. The routes are left
+// out when the metadata cannot be read, as the rest of the API does not need them.
+func (setup *OrgSetup) registerContractRoutes(ctx context.Context, mux *http.ServeMux) {
+	routes, err := setup.newContractRoutes(ctx)
+	if err != nil {
+		log.Printf("Contract routes disabled: %s", err)
+		return
+	}
+
+	mux.HandleFunc("GET /contract", routes.list)
+	mux.HandleFunc("GET /contract/{fn}", routes.call)
+	mux.HandleFunc("POST /contract/{fn}", routes.call)
+	mux.HandleFunc("GET /openapi.json", routes.openAPI)
+	log.Printf("Exposing %d contract functions under /contract", len(routes.transactions))
+}
+
+// newContractRoutes evaluates the metadata transaction of the chaincode.
+func (setup *OrgSetup) newContractRoutes(ctx context.Context) (*contractRoutes, error) {
+	metadataJSON, err := setup.Gateway.GetNetwork(setup.Channel).GetContract(setup.Chaincode).EvaluateWithContext(ctx, metadataFunction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the metadata of %s: %w", setup.Chaincode, err)
+	}
+	return newContractRoutes(setup, metadataJSON)
+}
+
+func newContractRoutes(setup *OrgSetup, metadataJSON []byte) (*contractRoutes, error) {
+	var metadata contractMetadata
+	if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse contract metadata: %w", err)
+	}
+
+	transactions := make(map[string]transactionMetadata)
+	for _, contract := range metadata.Contracts {
+		for _, tx := range contract.Transactions {
+			transactions[tx.Name] = tx
+		}
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("the metadata of %s describes no transactions", setup.Chaincode)
+	}
+	return &contractRoutes{setup: setup, metadata: &metadata, transactions: transactions}, nil
+}
+
+// sortedTransactions returns the transactions ordered by name.
+func (routes *contractRoutes) sortedTransactions() []transactionMetadata {
+	transactions := make([]transactionMetadata, 0, len(routes.transactions))
+	for _, tx := range routes.transactions {
+		transactions = append(transactions, tx)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].Name < transactions[j].Name
+	})
+	return transactions
+}
+
+// list writes the metadata of the exposed transactions.
+func (routes *contractRoutes) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, routes.sortedTransactions())
+}
+
+// call evaluates or submits the transaction named by the path, as tagged in the
+// metadata. Its arguments are read from the JSON object in the request body, or
+// from the query parameters of GET requests, by parameter name.
+func (routes *contractRoutes) call(w http.ResponseWriter, r *http.Request) {
+	tx, ok := routes.transactions[r.PathValue("fn")]
+	if !ok {
+		http.Error(w, "Unknown contract function "+r.PathValue("fn"), http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet && !tx.evaluated() {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, tx.Name+" changes the ledger and must be called with POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request contractRequest
+	var err error
+	if r.Method == http.MethodGet {
+		request.Arguments = tx.queryArguments(r.URL.Query())
+	} else {
+		request, err = readContractRequest(r)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	args, err := tx.arguments(request.Arguments)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if tx.evaluated() {
+		routes.setup.evaluate(w, r, "", tx.Name, args...)
+		return
+	}
+	routes.setup.submit(w, r, "", tx.Name, args, request.Transient, nil)
+}
+
+// contractRequest is the JSON body of a contract call: its arguments by
+// parameter name, and optional transient data.
+type contractRequest struct {
+	Arguments map[string]json.RawMessage
+	Transient map[string][]byte
+}
+
+// readContractRequest reads a JSON object mapping parameter names to their
+// values. Its "transient" member, if any, maps transient keys to their values.
+func readContractRequest(r *http.Request) (contractRequest, error) {
+	request := contractRequest{Arguments: make(map[string]json.RawMessage)}
+	if r.ContentLength == 0 {
+		return request, nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request.Arguments); err != nil {
+		return request, fmt.Errorf("the request body must be a JSON object of arguments: %w", err)
+	}
+
+	if transientJSON, ok := request.Arguments["transient"]; ok {
+		delete(request.Arguments, "transient")
+		var transient map[string]json.RawMessage
+		if err := json.Unmarshal(transientJSON, &transient); err != nil {
+			return request, fmt.Errorf("transient must be a JSON object: %w", err)
+		}
+		request.Transient = make(map[string][]byte, len(transient))
+		for key, value := range transient {
+			var text string
+			if json.Unmarshal(value, &text) == nil {
+				request.Transient[key] = []byte(text)
+			} else {
+				request.Transient[key] = value
+			}
+		}
+	}
+	return request, nil
+}
+
+// queryArguments returns query parameters as JSON values. Values of string
+// parameters, and values that are not valid JSON, are taken as strings.
+func (tx transactionMetadata) queryArguments(query url.Values) map[string]json.RawMessage {
+	values := make(map[string]json.RawMessage, len(query))
+	for name := range query {
+		value := query.Get(name)
+		if json.Valid([]byte(value)) && !tx.stringParameter(name) {
+			values[name] = json.RawMessage(value)
+			continue
+		}
+		values[name], _ = json.Marshal(value)
+	}
+	return values
+}
+
+func (tx transactionMetadata) stringParameter(name string) bool {
+	for _, parameter := range tx.Parameters {
+		if parameter.Name == name {
+			return parameter.Schema["type"] == "string"
+		}
+	}
+	return false
+}
+
+// arguments returns the transaction arguments in parameter order, checking each
+// value against the type of its parameter schema. Strings are passed as is, and
+// other values as their JSON encoding, as the contract parses them.
+func (tx transactionMetadata) arguments(values map[string]json.RawMessage) ([]string, error) {
+	for name := range values {
+		if !tx.hasParameter(name) {
+			return nil, fmt.Errorf("%s has no parameter %s", tx.Name, name)
+		}
+	}
+
+	args := make([]string, len(tx.Parameters))
+	for i, parameter := range tx.Parameters {
+		value, ok := values[parameter.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing parameter %s of %s", parameter.Name, tx.Name)
+		}
+
+		var decoded any
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err != nil {
+			return nil, fmt.Errorf("parameter %s is not valid JSON: %w", parameter.Name, err)
+		}
+		if err := checkSchemaType(parameter.Schema, decoded); err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", parameter.Name, err)
+		}
+
+		if text, ok := decoded.(string); ok {
+			args[i] = text
+		} else {
+			args[i] = string(value)
+		}
+	}
+	return args, nil
+}
+
+func (tx transactionMetadata) hasParameter(name string) bool {
+	for _, parameter := range tx.Parameters {
+		if parameter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSchemaType checks that a decoded JSON value has the type of a schema.
+// Schemas referencing a component must be objects, the contract validating
+// their content.
+func checkSchemaType(schema map[string]any, value any) error {
+	schemaType, _ := schema["type"].(string)
+	if _, ok := schema["$ref"]; ok {
+		schemaType = "object"
+	}
+
+	var ok bool
+	switch schemaType {
+	case "string":
+		_, ok = value.(string)
+	case "integer":
+		var number json.Number
+		if number, ok = value.(json.Number); ok {
+			_, err := number.Int64()
+			ok = err == nil
+		}
+	case "number":
+		_, ok = value.(json.Number)
+	case "boolean":
+		_, ok = value.(bool)
+	case "array":
+		_, ok = value.([]any)
+	case "object":
+		_, ok = value.(map[string]any)
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("expected a JSON %s", schemaType)
+	}
+	return nil
+}
+
+// openAPI writes an OpenAPI 3 document describing the contract routes, with a
+// request example generated from the schema of every parameter.
+func (routes *contractRoutes) openAPI(w http.ResponseWriter, r *http.Request) {
+	paths := make(map[string]any)
+	for _, tx := range routes.sortedTransactions() {
+		properties := make(map[string]any)
+		example := make(map[string]any)
+		required := make([]string, 0, len(tx.Parameters))
+		for _, parameter := range tx.Parameters {
+			properties[parameter.Name] = parameter.Schema
+			example[parameter.Name] = routes.exampleValue(parameter.Schema, 0)
+			required = append(required, parameter.Name)
+		}
+		properties["transient"] = map[string]any{"type": "object", "additionalProperties": true}
+
+		responses := map[string]any{
+			"200": map[string]any{"description": "The transaction result"},
+			"400": map[string]any{"description": "Invalid arguments"},
+		}
+		if len(tx.Returns) > 0 {
+			responses["200"] = map[string]any{
+				"description": "The transaction result",
+				"content":     map[string]any{"application/json": map[string]any{"schema": tx.Returns}},
+			}
+		}
+
+		operation := map[string]any{
+			"operationId": tx.Name,
+			"tags":        tx.Tag,
+			"requestBody": map[string]any{
+				"required": len(required) > 0,
+				"content": map[string]any{"application/json": map[string]any{
+					"schema":  map[string]any{"type": "object", "properties": properties, "required": required},
+					"example": example,
+				}},
+			},
+			"responses": responses,
+		}
+		pathItem := map[string]any{"post": operation}
+		if tx.evaluated() {
+			pathItem["get"] = routes.queryOperation(tx, responses)
+		}
+		paths["/contract/"+tx.Name] = pathItem
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   routes.setup.Chaincode + " contract API",
+			"version": "generated",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": routes.metadata.Components.Schemas},
+	})
+}
+
+// queryOperation describes the GET form of an evaluated transaction, taking its
+// arguments as query parameters.
+func (routes *contractRoutes) queryOperation(tx transactionMetadata, responses map[string]any) map[string]any {
+	parameters := make([]any, 0, len(tx.Parameters))
+	for _, parameter := range tx.Parameters {
+		parameters = append(parameters, map[string]any{
+			"name":     parameter.Name,
+			"in":       "query",
+			"required": true,
+			"schema":   parameter.Schema,
+			"example":  routes.exampleValue(parameter.Schema, 0),
+		})
+	}
+	return map[string]any{
+		"operationId": tx.Name + "Query",
+		"tags":        tx.Tag,
+		"parameters":  parameters,
+		"responses":   responses,
+	}
+}
+
+// exampleValue returns an example value of a schema, following references to
+// the component schemas up to a few levels deep.
+func (routes *contractRoutes) exampleValue(schema map[string]any, depth int) any {
+	if ref, ok := schema["$ref"].(string); ok {
+		component, ok := routes.metadata.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+		if !ok || depth > 3 {
+			return map[string]any{}
+		}
+		var resolved map[string]any
+		if err := json.Unmarshal(component, &resolved); err != nil {
+			return map[string]any{}
+		}
+		return routes.exampleValue(resolved, depth+1)
+	}
+
+	switch schema["type"] {
+	case "string":
+		if format, _ := schema["format"].(string); format == "date-time" {
+			return "2024-01-01T00:00:00Z"
+		}
+		return "string"
+	case "integer":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return false
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		return []any{routes.exampleValue(items, depth+1)}
+	case "object":
+		example := make(map[string]any)
+		properties, _ := schema["properties"].(map[string]any)
+		for name, property := range properties {
+			if propertySchema, ok := property.(map[string]any); ok {
+				example[name] = routes.exampleValue(propertySchema, depth+1)
+			}
+		}
+		return example
+	}
+	return nil
+}
+
+// writeJSON writes a value as a JSON response body.
+func writeJSON(w http.ResponseWriter, statusCode int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+}
@@ -0,0 +1,256 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// summaryTimeout is the deadline shared by the queries of a dealer summary.
+const summaryTimeout = 3 * time.Second
+
+// recentAssetsCount is the number of recently updated assets in a dealer summary.
+const recentAssetsCount = "10"
+
+// consistentReadAttempts is the number of times the queries of a consistent
+// dealer summary are evaluated while the ledger moves on during them.
+const consistentReadAttempts = 3
+
+// summarySection is one query of the dealer summary.
+type summarySection struct {
+	name     string
+	function string
+	args     []string
+}
+
+// dealerSummary returns the balances, float, recently updated assets, monthly
+// usage, feature flags and system state of a dealer in one response, for the
+// dealer portal. The queries are evaluated in parallel with a shared deadline.
+// Sections whose query failed are left out and their error reported under
+// errors, so that one slow or failing query does not fail the whole page. With
+// consistent=true, the queries read the state at one block height, under
+// blockHeight, evaluated again while the ledger moves on during them. Dealers
+// may only read their own summary.
+func (setup *OrgSetup) dealerSummary(w http.ResponseWriter, r *http.Request) {
+	dealerID := r.PathValue("id")
+	role, ok := dealerReportRole(w, r, dealerID)
+	if !ok {
+		return
+	}
+
+	recentFilter, err := json.Marshal(assetFilter{DealerID: dealerID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sections := []summarySection{
+		{"totals", "GetTotals", []string{dealerID}},
+		{"float", "GetDealerFloat", []string{dealerID}},
+		{"recentAssets", "GetAssetsFiltered", []string{string(recentFilter), "updatedat:desc", recentAssetsCount, ""}},
+		{"usage", "GetUsageReport", []string{dealerID, time.Now().UTC().Format("2006-01")}},
+		{"featureFlags", "GetFeatureFlags", nil},
+		{"systemState", "GetSystemState", nil},
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), summaryTimeout)
+	defer cancel()
+	request := r.WithContext(ctx)
+
+	var summary map[string]any
+	var failures map[string]*assetclient.MultiPeerError
+	if r.URL.Query().Get("consistent") == "true" {
+		evaluator, height := setup.pinnedReader(request, role)
+		blockHeight, err := assetclient.ConsistentRead(ctx, evaluator, height, consistentReadAttempts, func(ctx context.Context, pinned *assetclient.PinnedEvaluator) error {
+			summary, failures = evaluateSections(ctx, pinned, sections)
+			var moved *assetclient.SnapshotMovedError
+			for _, failure := range failures {
+				if errors.As(failure, &moved) {
+					return moved
+				}
+			}
+			return nil
+		})
+		var moved *assetclient.SnapshotMovedError
+		if errors.As(err, &moved) {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "the ledger kept moving during the summary: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		summary["blockHeight"] = blockHeight
+	} else {
+		summary, failures = evaluateSections(ctx, roleEvaluator{setup, request, role}, sections)
+	}
+	summary["dealerId"] = dealerID
+
+	if len(failures) == len(sections) {
+		writeGatewayError(w, failures["totals"])
+		return
+	}
+	if len(failures) > 0 {
+		summary["errors"] = failures
+	}
+	response, err := json.Marshal(summary)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResult(w, http.StatusOK, response)
+}
+
+// evaluateSections evaluates the queries of sections in parallel, returning
+// the results and the errors of the failed ones keyed by section name.
+func evaluateSections(ctx context.Context, evaluator assetclient.Evaluator, sections []summarySection) (map[string]any, map[string]*assetclient.MultiPeerError) {
+	results := map[string]any{}
+	failures := map[string]*assetclient.MultiPeerError{}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, section := range sections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := evaluator.EvaluateWithContext(ctx, section.function, client.WithArguments(section.args...))
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				failures[section.name] = assetclient.NewMultiPeerError(err)
+				return
+			}
+			results[section.name] = resultJSON(result)
+		}()
+	}
+	wg.Wait()
+	return results, failures
+}
+
+// pinnedReader returns the evaluator of the transactions a request evaluates
+// as role and the height of the ledger, both served by the same Gateway, for
+// the reads pinned to a block height.
+func (setup *OrgSetup) pinnedReader(r *http.Request, role string) (assetclient.Evaluator, assetclient.HeightReader) {
+	gateway, ok := setup.gateway(r, role)
+	if !ok {
+		gateway = setup.readRouter.ReadGateway()
+	}
+	network := gateway.GetNetwork(setup.Channel)
+	return gatewayEvaluator{roleEvaluator{setup, r, role}, network.GetContract(setup.Chaincode)},
+		assetclient.QSCCHeight(network.GetContract("qscc"), setup.Channel)
+}
+
+// gatewayEvaluator is the roleEvaluator evaluating with contract, of one
+// Gateway, rather than on the read replicas in turn.
+type gatewayEvaluator struct {
+	roleEvaluator
+	contract *client.Contract
+}
+
+func (e gatewayEvaluator) EvaluateWithContext(ctx context.Context, function string, options ...client.ProposalOption) ([]byte, error) {
+	if err := e.setup.FunctionPolicy.Check(e.setup.identityName(e.r, e.role), function, false); err != nil {
+		return nil, err
+	}
+	return e.contract.EvaluateWithContext(ctx, function, options...)
+}
+
+// dealerStatement returns a part of the statement of a dealer, resumed with the
+// nextPageToken of the previous part. The pageSize query parameter bounds the
+// keys the chaincode processes for the part, not the assets returned, so large
+// ledgers are read in parts that each fit in the chaincode execution timeout.
+// Dealers may only read their own statement.
+func (setup *OrgSetup) dealerStatement(w http.ResponseWriter, r *http.Request) {
+	dealerID := r.PathValue("id")
+	role, ok := dealerReportRole(w, r, dealerID)
+	if !ok {
+		return
+	}
+	setup.evaluatePage(w, r, role, "GetDealerStatement", dealerID)
+}
+
+// dealerCamt053 returns the daily statement of a dealer as an ISO 20022
+// camt.053 document, for the partner banks. The date query parameter is the
+// UTC day of the statement, such as 2024-01-31, the previous day by default.
+// Dealers may only read their own statement.
+func (setup *OrgSetup) dealerCamt053(w http.ResponseWriter, r *http.Request) {
+	dealerID := r.PathValue("id")
+	role, ok := dealerReportRole(w, r, dealerID)
+	if !ok {
+		return
+	}
+	date := time.Now().UTC().AddDate(0, 0, -1)
+	if value := r.URL.Query().Get("date"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			http.Error(w, "date must be a day such as 2024-01-31", http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	statement, err := assetclient.ReadDailyStatement(r.Context(), roleEvaluator{setup, r, role}, dealerID, date)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	var document bytes.Buffer
+	if err := statement.WriteCamt053(&document); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("camt053-%s-%s.xml", dealerID, statement.Date.Format("20060102"))))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(document.Bytes()); err != nil {
+		log.Printf("Failed to write camt.053 statement: %s", err)
+	}
+}
+
+// dealerRollup returns the totals of a dealer and of every dealer below it.
+// Dealers may only read their own roll-up, which includes their sub-dealers.
+func (setup *OrgSetup) dealerRollup(w http.ResponseWriter, r *http.Request) {
+	dealerID := r.PathValue("id")
+	role, ok := dealerReportRole(w, r, dealerID)
+	if !ok {
+		return
+	}
+	setup.evaluate(w, r, role, "GetDealerRollup", dealerID)
+}
+
+// dealerAssets returns the assets of a dealer, read through the dealer index of
+// the chaincode. Dealers may only read their own assets.
+func (setup *OrgSetup) dealerAssets(w http.ResponseWriter, r *http.Request) {
+	dealerID := r.PathValue("id")
+	role, ok := dealerReportRole(w, r, dealerID)
+	if !ok {
+		return
+	}
+	setup.evaluate(w, r, role, "QueryAssetsByDealer", dealerID)
+}
+
+// dealerReportRole returns the role reading a report of dealerID for the caller:
+// auditors and admins read any dealer, dealers only their own. Otherwise it
+// writes a forbidden response and returns false.
+func dealerReportRole(w http.ResponseWriter, r *http.Request, dealerID string) (string, bool) {
+	requestClaims := claims(r)
+	switch {
+	case requestClaims.hasRole(roleAuditor):
+		return roleAuditor, true
+	case requestClaims.hasRole(roleAdmin):
+		return roleAdmin, true
+	case dealerID != requestClaims.DealerID:
+		http.Error(w, "dealers may only read their own reports", http.StatusForbidden)
+		return "", false
+	default:
+		return roleDealer, true
+	}
+}
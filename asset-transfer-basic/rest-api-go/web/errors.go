@@ -0,0 +1,35 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+// writeGatewayError logs a failed Gateway call with the errors reported by each
+// peer and writes them to the response as a JSON error body. Domain errors
+// returned by the chaincode are answered with their matching status code, and
+// infrastructure errors worth retrying with a Retry-After header.
+func writeGatewayError(w http.ResponseWriter, err error) {
+	multiErr := assetclient.NewMultiPeerError(err)
+	log.Printf("Gateway call failed: %s", multiErr)
+
+	statusCode := assetclient.HTTPStatus(multiErr)
+	switch multiErr.GRPCCode {
+	case "Unavailable":
+		statusCode = http.StatusServiceUnavailable
+	case "DeadlineExceeded":
+		statusCode = http.StatusGatewayTimeout
+	}
+
+	if multiErr.Retryable {
+		w.Header().Set("Retry-After", "1")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(multiErr); err != nil {
+		log.Printf("Failed to write error response: %s", err)
+	}
+}
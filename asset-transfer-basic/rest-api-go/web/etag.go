@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// expectedVersionTransientKey is the transient map entry the chaincode checks
+// the version of the asset against before changing it.
+const expectedVersionTransientKey = "expected_version"
+
+// defaultCacheControl makes clients revalidate the assets they cached with
+// If-None-Match before using them.
+const defaultCacheControl = "private, no-cache"
+
+// assetETag returns the strong entity tag of an asset, its version quoted.
+func assetETag(assetJSON []byte) (string, error) {
+	var asset struct {
+		Version int64 `json:"version"`
+	}
+	if err := json.Unmarshal(assetJSON, &asset); err != nil {
+		return "", err
+	}
+	return strconv.Quote(strconv.FormatInt(asset.Version, 10)), nil
+}
+
+// etagMatches reports whether header, an If-Match or If-None-Match value, lists
+// etag or is the wildcard.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAsset writes an asset with its ETag and the Cache-Control header, or a
+// 304 Not Modified response when the If-None-Match header lists the ETag.
+func (setup *OrgSetup) writeAsset(w http.ResponseWriter, r *http.Request, assetJSON []byte) {
+	etag, err := assetETag(assetJSON)
+	if err != nil {
+		http.Error(w, "failed to parse the asset: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	cacheControl := setup.CacheControl
+	if cacheControl == "" {
+		cacheControl = defaultCacheControl
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeResult(w, http.StatusOK, assetJSON)
+}
+
+// checkIfMatch checks the If-Match header of a request changing the asset read
+// as assetJSON. It returns the transient entry making the chaincode check the
+// version again, as the asset may change before the transaction is endorsed, or
+// writes a 412 Precondition Failed response and returns false.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, assetJSON []byte) (map[string][]byte, bool) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil, true
+	}
+	etag, err := assetETag(assetJSON)
+	if err != nil {
+		http.Error(w, "failed to parse the asset: "+err.Error(), http.StatusBadGateway)
+		return nil, false
+	}
+	if !etagMatches(ifMatch, etag) {
+		w.Header().Set("ETag", etag)
+		http.Error(w, "the asset has changed, its ETag is "+etag, http.StatusPreconditionFailed)
+		return nil, false
+	}
+	if strings.TrimSpace(ifMatch) == "*" {
+		return nil, true
+	}
+	version, _ := strconv.Unquote(etag)
+	return map[string][]byte{expectedVersionTransientKey: []byte(version)}, true
+}
@@ -0,0 +1,138 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// idempotencyKeyHeader names the request header making a submitting request
+// idempotent: requests repeating the key of an earlier request of the same
+// user get its response, without submitting a transaction again.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentResponse is the record of an Idempotency-Key in the state store,
+// pending until the response of its first request is known.
+type idempotentResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Pending     bool   `json:"pending,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// submitOnce calls submit unless the Idempotency-Key of r was seen before, in
+// which case it answers with the recorded response, or 409 while the first
+// request is in progress. Responses of the server failing, 5xx, are not
+// recorded, so that the request can be retried with the same key. Every
+// request is submitted when no StateStore is configured.
+func (setup *OrgSetup) submitOnce(w http.ResponseWriter, r *http.Request, function string, args []string, submit func(w http.ResponseWriter)) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" || setup.StateStore == nil {
+		submit(w)
+		return
+	}
+	if len(key) > 255 {
+		http.Error(w, "the "+idempotencyKeyHeader+" must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+	storeKey := "idempotency/" + claims(r).User + "/" + key
+	fingerprint := requestFingerprint(r, function, args)
+	ttl := setup.idempotencyTTL()
+
+	pending, _ := json.Marshal(idempotentResponse{Fingerprint: fingerprint, Pending: true})
+	claimed, err := setup.StateStore.PutIfAbsent(r.Context(), storeKey, pending, ttl)
+	if err != nil {
+		log.Printf("Failed to record idempotency key: %s", err)
+		http.Error(w, "the idempotency key could not be recorded", http.StatusServiceUnavailable)
+		return
+	}
+	if !claimed {
+		setup.replayResponse(w, r, storeKey, fingerprint)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	submit(recorder)
+
+	// the record outlives a request cancelled by its caller
+	ctx := context.WithoutCancel(r.Context())
+	if recorder.status >= http.StatusInternalServerError {
+		err = setup.StateStore.Delete(ctx, storeKey)
+	} else {
+		record, _ := json.Marshal(idempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      recorder.status,
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		err = setup.StateStore.Put(ctx, storeKey, record, ttl)
+	}
+	if err != nil {
+		log.Printf("Failed to record the response of idempotency key %s: %s", key, err)
+	}
+}
+
+// replayResponse answers with the response recorded for an idempotency key.
+func (setup *OrgSetup) replayResponse(w http.ResponseWriter, r *http.Request, storeKey string, fingerprint string) {
+	value, ok, err := setup.StateStore.Get(r.Context(), storeKey)
+	if err != nil {
+		log.Printf("Failed to read idempotency key: %s", err)
+		http.Error(w, "the idempotency key could not be read", http.StatusServiceUnavailable)
+		return
+	}
+	var record idempotentResponse
+	if !ok || json.Unmarshal(value, &record) != nil || record.Pending {
+		// a record expiring in between is answered as in progress, to be retried
+		http.Error(w, "a request with this "+idempotencyKeyHeader+" is in progress", http.StatusConflict)
+		return
+	}
+	if record.Fingerprint != fingerprint {
+		http.Error(w, "the "+idempotencyKeyHeader+" was used for a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", record.ContentType)
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	if _, err := w.Write(record.Body); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+}
+
+// idempotencyTTL is how long idempotency keys are remembered, a day by default.
+func (setup *OrgSetup) idempotencyTTL() time.Duration {
+	if setup.IdempotencyTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return setup.IdempotencyTTL
+}
+
+// requestFingerprint identifies the transaction a request submits, so that a
+// key reused for another request is refused.
+func requestFingerprint(r *http.Request, function string, args []string) string {
+	hash := sha256.Sum256([]byte(r.Method + " " + r.URL.Path + "\x00" + function + "\x00" + strings.Join(args, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+// responseRecorder passes a response on while keeping its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(body []byte) (int, error) {
+	r.body.Write(body)
+	return r.ResponseWriter.Write(body)
+}
@@ -1,6 +1,9 @@
 package web
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"log"
@@ -8,6 +11,7 @@ import (
 	"path"
 	"time"
 
+	"assetTransfer/pkg/assetclient"
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	"github.com/hyperledger/fabric-gateway/pkg/hash"
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
@@ -18,50 +22,188 @@ import (
 // Initialize the setup for the organization.
 func Initialize(setup OrgSetup) (*OrgSetup, error) {
 	log.Printf("Initializing connection for %s...\n", setup.OrgName)
-	clientConnection := setup.newGrpcConnection()
+	if err := setup.checkTLS(context.Background()); err != nil {
+		return nil, err
+	}
+	connections := assetclient.NewConnectionManager(
+		assetclient.WithPoolSize(setup.PoolSize),
+		assetclient.WithIdleTimeout(setup.PoolIdleTimeout),
+	)
+	clientConnection := setup.newGrpcConnection(connections, setup.PeerEndpoint, setup.GatewayPeer)
 	id := setup.newIdentity()
 	sign := setup.newSign()
 
-	gateway, err := client.Connect(
-		id,
-		client.WithSign(sign),
-		client.WithHash(hash.SHA256),
-		client.WithClientConnection(clientConnection),
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
+	gateway, err := connectGateway(clientConnection, id, sign, setup.consensus())
 	if err != nil {
 		panic(err)
 	}
 	setup.Gateway = *gateway
+
+	setup.roleGateways = make(map[string]*client.Gateway)
+	for role, roleIdentity := range setup.RoleIdentities {
+		roleSetup := setup
+		roleSetup.CertPath, roleSetup.KeyPath = roleIdentity.CertPath, roleIdentity.KeyPath
+		roleSetup.CertPEM, roleSetup.KeyPEM = roleIdentity.CertPEM, roleIdentity.KeyPEM
+		roleGateway, err := connectGateway(clientConnection, roleSetup.newIdentity(), roleSetup.newSign(), setup.consensus())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect as the %s role: %w", role, err)
+		}
+		setup.roleGateways[role] = roleGateway
+	}
+	if setup.SweepInterval > 0 {
+		if err := setup.startExpirySweeper(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	setup.identityGateways = make(map[string]*client.Gateway)
+	for name, keyIdentity := range setup.KeyIdentities {
+		keySetup := setup
+		keySetup.CertPath, keySetup.KeyPath = keyIdentity.CertPath, keyIdentity.KeyPath
+		keySetup.CertPEM, keySetup.KeyPEM = keyIdentity.CertPEM, keyIdentity.KeyPEM
+		keyGateway, err := connectGateway(clientConnection, keySetup.newIdentity(), keySetup.newSign(), setup.consensus())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect as the %s API key identity: %w", name, err)
+		}
+		setup.identityGateways[name] = keyGateway
+	}
+	setup.clients = map[string]*assetclient.Client{orgIdentityName: assetclient.NewGatewayClient(gateway, setup.Channel, setup.Chaincode, nil)}
+	for role, roleGateway := range setup.roleGateways {
+		setup.clients[role] = assetclient.NewGatewayClient(roleGateway, setup.Channel, setup.Chaincode, nil)
+	}
+	for name, keyGateway := range setup.identityGateways {
+		setup.clients[name] = assetclient.NewGatewayClient(keyGateway, setup.Channel, setup.Chaincode, nil)
+	}
+	setup.Discoverer = assetclient.NewDiscoverer(clientConnection, id, sign)
+
+	replicas := make([]*assetclient.Replica, 0, len(setup.ReadPeers))
+	for _, readPeer := range setup.ReadPeers {
+		readGateway, err := connectGateway(setup.newGrpcConnection(connections, readPeer.Endpoint, readPeer.GatewayPeer), id, sign, setup.consensus())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to read peer %s: %w", readPeer.Endpoint, err)
+		}
+		replicas = append(replicas, &assetclient.Replica{Name: readPeer.Endpoint, Gateway: readGateway, HealthURL: readPeer.HealthURL})
+	}
+	setup.readRouter = assetclient.NewReplicaRouter(&setup.Gateway, replicas...)
+	if len(replicas) > 0 {
+		interval := setup.ReadHealthInterval
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		go setup.readRouter.RunHealthChecks(context.Background(), interval)
+		log.Printf("Routing queries to %d read peers\n", len(replicas))
+	}
+
+	if len(setup.PageTokenKey) == 0 {
+		setup.PageTokenKey = make([]byte, 32)
+		if _, err := rand.Read(setup.PageTokenKey); err != nil {
+			return nil, fmt.Errorf("failed to generate a page token key: %w", err)
+		}
+	}
+	setup.pageTokens = assetclient.NewPageTokens(setup.PageTokenKey)
+
+	ttl := setup.TxStatusTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	setup.txStatuses = assetclient.NewTxStatusStore(ttl)
+	if err := setup.startCommitMetrics(context.Background()); err != nil {
+		return nil, err
+	}
+	if setup.SerializeAssets {
+		setup.assetLocks = assetclient.NewKeyLocks()
+	}
+	for function, wait := range setup.CommitWaits {
+		if _, err := assetclient.ParseCommitPolicy(wait); err != nil {
+			return nil, fmt.Errorf("invalid commit wait of %s: %w", function, err)
+		}
+	}
+	if _, err := assetclient.ParseCommitPolicy(setup.CommitWait); err != nil {
+		return nil, err
+	}
+	if setup.commitWaiter, err = setup.newCommitWaiter(connections); err != nil {
+		return nil, err
+	}
+	if setup.StuckTxLog != "" {
+		if err := setup.startStuckTxManager(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	go setup.logChaincodeVersions(context.Background())
+	go setup.trackCommits(context.Background())
 	log.Println("Initialization complete")
 	return &setup, nil
 }
 
-// newGrpcConnection creates a gRPC connection to the Gateway server.
-func (setup OrgSetup) newGrpcConnection() *grpc.ClientConn {
-	certificate, err := loadCertificate(setup.TLSCertPath)
+// connectGateway connects to the Gateway over clientConnection as the given
+// identity, with submit timeouts suited to the consensus of the orderers.
+func connectGateway(clientConnection grpc.ClientConnInterface, id identity.Identity, sign identity.Sign, consensus assetclient.Consensus) (*client.Gateway, error) {
+	options := []client.ConnectOption{
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5 * time.Second),
+		client.WithEndorseTimeout(15 * time.Second),
+	}
+	return client.Connect(id, append(options, consensus.TimeoutOptions()...)...)
+}
+
+// consensus returns the consensus type of the orderers, Raft unless set.
+func (setup OrgSetup) consensus() assetclient.Consensus {
+	if setup.Consensus == "" {
+		return assetclient.ConsensusRaft
+	}
+	return setup.Consensus
+}
+
+// newGrpcConnection creates a pool of gRPC connections to the Gateway server at endpoint.
+func (setup OrgSetup) newGrpcConnection(connections *assetclient.ConnectionManager, endpoint string, gatewayPeer string) grpc.ClientConnInterface {
+	tlsConfig, err := setup.tlsConfig(gatewayPeer)
 	if err != nil {
 		panic(err)
 	}
+	return connections.Pool(endpoint, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+}
+
+// tlsConfig returns the TLS configuration of connections to the peer named
+// gatewayPeer, trusting the TLS CA of the organization with the TLSOptions applied.
+func (setup OrgSetup) tlsConfig(gatewayPeer string) (*tls.Config, error) {
+	certificate, err := loadCertificate(setup.TLSCertPEM, setup.TLSCertPath)
+	if err != nil {
+		return nil, err
+	}
 
 	certPool := x509.NewCertPool()
 	certPool.AddCert(certificate)
-	transportCredentials := credentials.NewClientTLSFromCert(certPool, setup.GatewayPeer)
+	return setup.TLSOptions.Config(certPool, gatewayPeer), nil
+}
 
-	connection, err := grpc.NewClient(setup.PeerEndpoint, grpc.WithTransportCredentials(transportCredentials))
-	if err != nil {
-		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+// checkTLS completes a TLS handshake with each peer when TLSOptions are set, so
+// that a certificate that is not pinned, or a peer without the required TLS
+// version or cipher suites, fails the startup.
+func (setup OrgSetup) checkTLS(ctx context.Context) error {
+	if !setup.TLSOptions.IsSet() {
+		return nil
 	}
 
-	return connection
+	peers := map[string]string{setup.PeerEndpoint: setup.GatewayPeer}
+	for _, readPeer := range setup.ReadPeers {
+		peers[readPeer.Endpoint] = readPeer.GatewayPeer
+	}
+	for endpoint, gatewayPeer := range peers {
+		tlsConfig, err := setup.tlsConfig(gatewayPeer)
+		if err != nil {
+			return err
+		}
+		if err := assetclient.CheckTLS(ctx, endpoint, tlsConfig); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
 func (setup OrgSetup) newIdentity() *identity.X509Identity {
-	certificate, err := loadCertificate(setup.CertPath)
+	certificate, err := loadCertificate(setup.CertPEM, setup.CertPath)
 	if err != nil {
 		panic(err)
 	}
@@ -76,14 +218,17 @@ func (setup OrgSetup) newIdentity() *identity.X509Identity {
 
 // newSign creates a function that generates a digital signature from a message digest using a private key.
 func (setup OrgSetup) newSign() identity.Sign {
-	files, err := os.ReadDir(setup.KeyPath)
-	if err != nil {
-		panic(fmt.Errorf("failed to read private key directory: %w", err))
-	}
-	privateKeyPEM, err := os.ReadFile(path.Join(setup.KeyPath, files[0].Name()))
+	privateKeyPEM := setup.KeyPEM
+	if privateKeyPEM == nil {
+		files, err := os.ReadDir(setup.KeyPath)
+		if err != nil {
+			panic(fmt.Errorf("failed to read private key directory: %w", err))
+		}
+		privateKeyPEM, err = os.ReadFile(path.Join(setup.KeyPath, files[0].Name()))
 
-	if err != nil {
-		panic(fmt.Errorf("failed to read private key file: %w", err))
+		if err != nil {
+			panic(fmt.Errorf("failed to read private key file: %w", err))
+		}
 	}
 
 	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
@@ -99,10 +244,13 @@ func (setup OrgSetup) newSign() identity.Sign {
 	return sign
 }
 
-func loadCertificate(filename string) (*x509.Certificate, error) {
-	certificatePEM, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+// loadCertificate parses certificatePEM, or the certificate file at filename when it is nil.
+func loadCertificate(certificatePEM []byte, filename string) (*x509.Certificate, error) {
+	if certificatePEM == nil {
+		var err error
+		if certificatePEM, err = os.ReadFile(filename); err != nil {
+			return nil, fmt.Errorf("failed to read certificate file: %w", err)
+		}
 	}
 	return identity.CertificateFromPEM(certificatePEM)
 }
@@ -1,8 +1,10 @@
 package web
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
@@ -19,22 +21,108 @@ func (setup *OrgSetup) Invoke(w http.ResponseWriter, r *http.Request) {
 	function := r.FormValue("function")
 	args := r.Form["args"]
 	fmt.Printf("channel: %s, chaincode: %s, function: %s, args: %s\n", channelID, chainCodeName, function, args)
+	if err := setup.FunctionPolicy.Check(orgIdentityName, function, true); err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	transient := make(map[string][]byte)
+	if transientJSON := r.FormValue("transient"); transientJSON != "" {
+		var err error
+		if transient, err = parseTransient(transientJSON); err != nil {
+			fmt.Fprintf(w, "Error parsing transient data: %s", err)
+			return
+		}
+	}
+	delete(transient, apiUserTransientKey)
+	hasPrivateData := len(transient) > 0
+	options := []client.ProposalOption{client.WithArguments(args...)}
+	if transient = setup.withAPIUser(r, transient); len(transient) > 0 {
+		options = append(options, client.WithTransient(transient))
+	}
+	endorsingOrgs := r.Form["endorsingOrgs"]
+	if len(endorsingOrgs) == 0 && hasPrivateData {
+		var collections []string
+		if names := r.FormValue("collections"); names != "" {
+			collections = strings.Split(names, ",")
+		}
+		var err error
+		if endorsingOrgs, err = setup.privateWriteEndorsers(r, channelID, chainCodeName, collections); err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+	}
+	if len(endorsingOrgs) > 0 {
+		options = append(options, client.WithEndorsingOrganizations(endorsingOrgs...))
+	}
 	network := setup.Gateway.GetNetwork(channelID)
 	contract := network.GetContract(chainCodeName)
-	txn_proposal, err := contract.NewProposal(function, client.WithArguments(args...))
+	txn_proposal, err := contract.NewProposal(function, options...)
 	if err != nil {
-		fmt.Fprintf(w, "Error creating txn proposal: %s", err)
+		writeGatewayError(w, err)
 		return
 	}
-	txn_endorsed, err := txn_proposal.Endorse()
+	txn_endorsed, err := txn_proposal.EndorseWithContext(r.Context())
 	if err != nil {
-		fmt.Fprintf(w, "Error endorsing txn: %s", err)
+		writeGatewayError(w, err)
 		return
 	}
-	txn_committed, err := txn_endorsed.Submit()
+	txn_committed, err := txn_endorsed.SubmitWithContext(r.Context())
 	if err != nil {
-		fmt.Fprintf(w, "Error submitting transaction: %s", err)
+		writeGatewayError(w, err)
 		return
 	}
 	fmt.Fprintf(w, "Transaction ID : %s Response: %s", txn_committed.TransactionID(), txn_endorsed.Result())
 }
+
+// apiUserTransientKey is the transient map entry the chaincode records as the end user of a transaction.
+const apiUserTransientKey = "api_user"
+
+// withAPIUser sets the API user of the request in the transient map, which is
+// recorded in the chaincode audit trail. The entry always comes from the
+// authenticated request and never from the caller's transient data.
+func (setup *OrgSetup) withAPIUser(r *http.Request, transient map[string][]byte) map[string][]byte {
+	delete(transient, apiUserTransientKey)
+	if apiUser := setup.apiUser(r); apiUser != "" {
+		transient[apiUserTransientKey] = []byte(apiUser)
+	}
+	return transient
+}
+
+// privateWriteEndorsers returns the organizations to endorse a transaction writing
+// private data. Private data is only disseminated to collection members, so
+// discovery picks the fewest organizations that satisfy the collection policies.
+// No organizations are returned when discovery is not configured.
+func (setup *OrgSetup) privateWriteEndorsers(r *http.Request, channelID string, chaincodeName string, collections []string) ([]string, error) {
+	if setup.Discoverer == nil {
+		return nil, nil
+	}
+	return setup.Discoverer.EndorsingOrganizations(r.Context(), channelID, chaincodeName, collections...)
+}
+
+// apiUser returns the authenticated API user of the request, if any: the user of
+// its claims, such as the API key, or the user header of the reverse proxy.
+func (setup *OrgSetup) apiUser(r *http.Request) string {
+	if requestClaims := claims(r); requestClaims.User != "" {
+		return requestClaims.User
+	}
+	header := setup.UserHeader
+	if header == "" {
+		header = "X-Forwarded-User"
+	}
+	return r.Header.Get(header)
+}
+
+// parseTransient converts a JSON object into a transient map, keeping the raw
+// JSON of each member as its value.
+func parseTransient(transientJSON string) (map[string][]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(transientJSON), &fields); err != nil {
+		return nil, err
+	}
+
+	transient := make(map[string][]byte, len(fields))
+	for key, value := range fields {
+		transient[key] = value
+	}
+	return transient, nil
+}
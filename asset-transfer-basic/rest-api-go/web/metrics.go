@@ -0,0 +1,39 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+// startCommitMetrics measures the submit-to-commit latency of the submitted
+// transactions against CommitSLO, fed by the block events of trackCommits,
+// and appends it to CommitLatencyLog when set. Transactions not seen in a
+// block within StuckTxDeadline are counted as timed out. Transactions
+// submitted in the background, with the none commit wait, are measured from
+// their endorsement.
+func (setup *OrgSetup) startCommitMetrics(ctx context.Context) error {
+	slo := setup.CommitSLO
+	if slo.Target <= 0 {
+		slo.Target = assetclient.DefaultCommitSLO.Target
+	}
+	if slo.Objective <= 0 || slo.Objective >= 1 {
+		slo.Objective = assetclient.DefaultCommitSLO.Objective
+	}
+	setup.commitMetrics = &assetclient.CommitMetrics{SLO: slo, Timeout: setup.StuckTxDeadline}
+	if setup.CommitLatencyLog != "" {
+		file, err := os.OpenFile(setup.CommitLatencyLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open the commit latency log: %w", err)
+		}
+		setup.commitMetrics.Log = file
+		log.Printf("Logging the commit latency to %s\n", setup.CommitLatencyLog)
+	}
+	setup.txStatuses.OnCommitted = setup.commitMetrics.Committed
+	go setup.commitMetrics.Run(ctx, 10*time.Second)
+	return nil
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+// defaultPageSize is the page size of paginated routes called without one.
+const defaultPageSize = 100
+
+// evaluatePage evaluates a paginated query as role and writes its page, with the
+// chaincode bookmark replaced by a signed nextPageToken. The query function
+// takes its filter arguments followed by the page size and bookmark, read from
+// the pageSize and pageToken query parameters. Tokens of another query or page
+// size are rejected rather than silently returning the wrong page.
+func (setup *OrgSetup) evaluatePage(w http.ResponseWriter, r *http.Request, role string, function string, filter ...string) {
+	query := r.URL.Query()
+	pageSize := defaultPageSize
+	if value := query.Get("pageSize"); value != "" {
+		var err error
+		if pageSize, err = strconv.Atoi(value); err != nil || pageSize <= 0 {
+			http.Error(w, "pageSize must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	queryHash := assetclient.QueryHash(function, filter...)
+	bookmark, err := setup.pageTokens.Decode(query.Get("pageToken"), pageSize, queryHash)
+	if errors.Is(err, assetclient.ErrPageTokenMismatch) || errors.Is(err, assetclient.ErrInvalidPageToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	args := append(filter, strconv.Itoa(pageSize), bookmark)
+	result, err := setup.evaluateResult(r, role, function, args...)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	var page map[string]json.RawMessage
+	if err := json.Unmarshal(result, &page); err != nil {
+		http.Error(w, "failed to parse the page: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	var nextBookmark string
+	if bookmarkJSON, ok := page["bookmark"]; ok {
+		delete(page, "bookmark")
+		if err := json.Unmarshal(bookmarkJSON, &nextBookmark); err != nil {
+			http.Error(w, "failed to parse the page bookmark: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	nextPageToken, err := setup.pageTokens.Encode(nextBookmark, pageSize, queryHash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if nextPageToken != "" {
+		page["nextPageToken"], _ = json.Marshal(nextPageToken)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
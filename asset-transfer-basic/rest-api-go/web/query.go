@@ -3,6 +3,8 @@ package web
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
 // Query handles chaincode query requests.
@@ -14,11 +16,13 @@ func (setup OrgSetup) Query(w http.ResponseWriter, r *http.Request) {
 	function := queryParams.Get("function")
 	args := r.URL.Query()["args"]
 	fmt.Printf("channel: %s, chaincode: %s, function: %s, args: %s\n", channelID, chainCodeName, function, args)
-	network := setup.Gateway.GetNetwork(channelID)
-	contract := network.GetContract(chainCodeName)
-	evaluateResponse, err := contract.EvaluateTransaction(function, args...)
+	if err := setup.FunctionPolicy.Check(orgIdentityName, function, false); err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	evaluateResponse, err := setup.readRouter.Evaluate(r.Context(), channelID, chainCodeName, function, client.WithArguments(args...))
 	if err != nil {
-		fmt.Fprintf(w, "Error: %s", err)
+		writeGatewayError(w, err)
 		return
 	}
 	fmt.Fprintf(w, "Response: %s", evaluateResponse)
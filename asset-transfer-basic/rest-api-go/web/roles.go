@@ -0,0 +1,634 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// assetDetailsCollection is the private data collection holding the asset details.
+const assetDetailsCollection = "assetDetailsCollection"
+
+// submitAttempts and submitBackoff bound the retries of transactions failing
+// with infrastructure errors.
+const (
+	submitAttempts = 3
+	submitBackoff  = 200 * time.Millisecond
+)
+
+// RoleIdentity is the signing identity used for the transactions of a role.
+type RoleIdentity struct {
+	CertPath string `json:"certPath"`
+	KeyPath  string `json:"keyPath"`
+	// CertPEM and KeyPEM are used in place of the files at CertPath and KeyPath when set.
+	CertPEM []byte `json:"-"`
+	KeyPEM  []byte `json:"-"`
+}
+
+// registerRoleRoutes registers the route groups of the admin, dealer and auditor
+// roles. Each route requires its role and runs as the signing identity of the role.
+func (setup *OrgSetup) registerRoleRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /admin/init", setup.withRole(roleAdmin, setup.adminInit))
+	mux.HandleFunc("POST /admin/purge", setup.withRole(roleAdmin, setup.adminPurge))
+	mux.HandleFunc("POST /admin/sweep", setup.withRole(roleAdmin, setup.adminSweep))
+	mux.HandleFunc("POST /admin/dealers/{dealerId}/float", setup.withRole(roleAdmin, setup.adminAllocateFloat))
+	mux.HandleFunc("POST /admin/dealers/{dealerId}/float/return", setup.withRole(roleAdmin, setup.adminReturnFloat))
+	mux.HandleFunc("PUT /admin/dealers/{dealerId}/parent", setup.withRole(roleAdmin, setup.adminSetDealerParent))
+	mux.HandleFunc("GET /admin/system-state", setup.withRole(roleAdmin, setup.adminSystemState))
+	mux.HandleFunc("PUT /admin/system-state", setup.withRole(roleAdmin, setup.adminSetSystemState))
+	mux.HandleFunc("GET /admin/chaincode-version", setup.withRole(roleAdmin, setup.adminChaincodeVersions))
+	mux.HandleFunc("GET /admin/remark-codes", setup.withRole(roleAdmin, setup.adminRemarkCodes))
+	mux.HandleFunc("PUT /admin/remark-codes", setup.withRole(roleAdmin, setup.adminSetRemarkCode))
+	mux.HandleFunc("DELETE /admin/remark-codes/{code}", setup.withRole(roleAdmin, setup.adminDeleteRemarkCode))
+	mux.HandleFunc("GET /admin/limit-policy", setup.withRole(roleAdmin, setup.adminLimitPolicy))
+	mux.HandleFunc("PUT /admin/limit-policy", setup.withRole(roleAdmin, setup.adminSetLimitPolicy))
+	mux.HandleFunc("GET /admin/hold-policy", setup.withRole(roleAdmin, setup.adminHoldPolicy))
+	mux.HandleFunc("PUT /admin/hold-policy", setup.withRole(roleAdmin, setup.adminSetHoldPolicy))
+	mux.HandleFunc("POST /admin/assets/query", setup.withRole(roleAdmin, setup.adminQueryAssets))
+	mux.HandleFunc("GET /admin/assets/status/{status}", setup.withRole(roleAdmin, setup.adminAssetsByStatus))
+	mux.HandleFunc("POST /admin/assets/{id}/mpin-reset/approve", setup.withRole(roleAdmin, setup.adminApproveMPINReset))
+	mux.HandleFunc("POST /admin/assets/{id}/archive", setup.withRole(roleAdmin, setup.adminArchiveAsset))
+	mux.HandleFunc("POST /admin/assets/{id}/archive/restore", setup.withRole(roleAdmin, setup.adminRestoreArchivedAsset))
+	mux.HandleFunc("GET /admin/assets/{id}/holds", setup.withRole(roleAdmin, setup.adminAssetHolds))
+	mux.HandleFunc("DELETE /admin/assets/{id}/holds/{txid}", setup.withRole(roleAdmin, setup.adminReleaseHold))
+	mux.HandleFunc("GET /admin/stuck-transactions", setup.withRole(roleAdmin, setup.adminStuckTransactions))
+	mux.HandleFunc("DELETE /admin/stuck-transactions/{txid}", setup.withRole(roleAdmin, setup.adminDismissStuckTransaction))
+
+	mux.HandleFunc("POST /dealer/assets", setup.withRole(roleDealer, setup.dealerCreateAsset))
+	mux.HandleFunc("GET /dealer/assets/{id}", setup.withRole(roleDealer, setup.dealerReadAsset))
+	mux.HandleFunc("PATCH /dealer/assets/{id}", setup.withRole(roleDealer, setup.dealerPatchAsset))
+	mux.HandleFunc("POST /dealer/assets/{id}/transfer", setup.withRole(roleDealer, setup.dealerTransferAsset))
+	mux.HandleFunc("POST /dealer/assets/{id}/msisdn-change", setup.withRole(roleDealer, setup.dealerRequestMSISDNChange))
+	mux.HandleFunc("POST /dealer/assets/{id}/msisdn-change/confirm", setup.withRole(roleDealer, setup.dealerConfirmMSISDNChange))
+	mux.HandleFunc("POST /dealer/assets/{id}/mpin-reset", setup.withRole(roleDealer, setup.dealerRequestMPINReset))
+	mux.HandleFunc("POST /dealer/assets/{id}/mpin-reset/complete", setup.withRole(roleDealer, setup.dealerCompleteMPINReset))
+	mux.HandleFunc("GET /dealer/float", setup.withRole(roleDealer, setup.dealerFloat))
+
+	mux.HandleFunc("GET /assets", setup.withAnyRole([]string{roleAuditor, roleAdmin, roleDealer}, setup.listAssets))
+	mux.HandleFunc("GET /assets/stats", setup.withAnyRole([]string{roleAuditor, roleAdmin}, setup.assetStats))
+	mux.HandleFunc("GET /dealers/{id}/summary", setup.withAnyRole([]string{roleAuditor, roleAdmin, roleDealer}, setup.dealerSummary))
+	mux.HandleFunc("GET /dealers/{id}/statement", setup.withAnyRole([]string{roleAuditor, roleAdmin, roleDealer}, setup.dealerStatement))
+	mux.HandleFunc("GET /dealers/{id}/statement/camt053", setup.withAnyRole([]string{roleAuditor, roleAdmin, roleDealer}, setup.dealerCamt053))
+	mux.HandleFunc("GET /dealers/{id}/rollup", setup.withAnyRole([]string{roleAuditor, roleAdmin, roleDealer}, setup.dealerRollup))
+	mux.HandleFunc("GET /dealers/{id}/assets", setup.withAnyRole([]string{roleAuditor, roleAdmin, roleDealer}, setup.dealerAssets))
+	mux.HandleFunc("GET /transactions/{txid}", setup.withAnyRole([]string{roleAuditor, roleAdmin, roleDealer}, setup.transactionStatus))
+
+	mux.HandleFunc("GET /auditor/assets/{id}/audit", setup.withRole(roleAuditor, setup.auditorAuditTrail))
+	mux.HandleFunc("GET /auditor/assets/{id}/balances", setup.withRole(roleAuditor, setup.auditorBalanceSeries))
+	mux.HandleFunc("GET /auditor/assets/{id}/history", setup.withRole(roleAuditor, setup.auditorAssetHistory))
+	mux.HandleFunc("GET /auditor/data-quality", setup.withRole(roleAuditor, setup.auditorDataQuality))
+}
+
+// adminInit seeds the ledger with the sample assets.
+func (setup *OrgSetup) adminInit(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "InitLedger", nil, nil, nil)
+}
+
+// adminPurge soft-deletes the assets of a dealer. Without a confirmation token it
+// only evaluates a dry run, returning the token to confirm the deletion with.
+func (setup *OrgSetup) adminPurge(w http.ResponseWriter, r *http.Request) {
+	dealerID := r.FormValue("dealerId")
+	token := r.FormValue("token")
+	if token == "" {
+		setup.evaluate(w, r, roleAdmin, "DeleteAssetsByDealer", dealerID, "true", "")
+		return
+	}
+	setup.submit(w, r, roleAdmin, "DeleteAssetsByDealer", []string{dealerID, "false", token}, nil, nil)
+}
+
+// adminApproveMPINReset approves the MPIN reset requested for an asset.
+func (setup *OrgSetup) adminApproveMPINReset(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "ApproveMPINReset", []string{r.PathValue("id")}, nil, nil)
+}
+
+// adminArchiveAsset moves a closed asset and its audit records to the archive.
+func (setup *OrgSetup) adminArchiveAsset(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "ArchiveAsset", []string{r.PathValue("id")}, nil, nil)
+}
+
+// adminRestoreArchivedAsset moves an archived asset back to the world state.
+func (setup *OrgSetup) adminRestoreArchivedAsset(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "ArchiveRestore", []string{r.PathValue("id")}, nil, nil)
+}
+
+// adminSweep submits SweepExpired to release the records past their expiry.
+func (setup *OrgSetup) adminSweep(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "SweepExpired", []string{r.FormValue("max")}, nil, nil)
+}
+
+// adminAllocateFloat adds to the float a dealer credits its wallets from.
+func (setup *OrgSetup) adminAllocateFloat(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "AllocateFloat", []string{r.PathValue("dealerId"), r.FormValue("amount")}, nil, nil)
+}
+
+// adminReturnFloat takes funds back from the float of a dealer.
+func (setup *OrgSetup) adminReturnFloat(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "ReturnFloat", []string{r.PathValue("dealerId"), r.FormValue("amount")}, nil, nil)
+}
+
+// adminSetDealerParent places a dealer below the super-dealer parentId, or
+// detaches it when parentId is empty.
+func (setup *OrgSetup) adminSetDealerParent(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "SetDealerParent", []string{r.PathValue("dealerId"), r.FormValue("parentId")}, nil, nil)
+}
+
+// adminSystemState returns whether state-changing transactions are paused.
+func (setup *OrgSetup) adminSystemState(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAdmin, "GetSystemState")
+}
+
+// adminSetSystemState pauses or resumes every state-changing transaction, with
+// state PAUSED or ACTIVE and the reason recorded on the ledger.
+func (setup *OrgSetup) adminSetSystemState(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "SetSystemState", []string{r.FormValue("state"), r.FormValue("reason")}, nil, nil)
+}
+
+// adminRemarkCodes returns the reason codes remarks are mapped onto.
+func (setup *OrgSetup) adminRemarkCodes(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAdmin, "GetRemarkCodes")
+}
+
+// adminSetRemarkCode creates or replaces the remark code of the JSON request body.
+func (setup *OrgSetup) adminSetRemarkCode(w http.ResponseWriter, r *http.Request) {
+	code, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	setup.submit(w, r, roleAdmin, "SetRemarkCode", []string{string(code)}, nil, nil)
+}
+
+// adminDeleteRemarkCode removes a remark code.
+func (setup *OrgSetup) adminDeleteRemarkCode(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "DeleteRemarkCode", []string{r.PathValue("code")}, nil, nil)
+}
+
+// adminLimitPolicy returns the limits the debits of assets are warned about.
+func (setup *OrgSetup) adminLimitPolicy(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAdmin, "GetLimitPolicy")
+}
+
+// adminSetLimitPolicy replaces the limit policy with the JSON request body.
+func (setup *OrgSetup) adminSetLimitPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	setup.submit(w, r, roleAdmin, "SetLimitPolicy", []string{string(policy)}, nil, nil)
+}
+
+// adminHoldPolicy returns the hold rules of the transaction types.
+func (setup *OrgSetup) adminHoldPolicy(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAdmin, "GetHoldPolicy")
+}
+
+// adminSetHoldPolicy replaces the hold policy with the JSON request body.
+func (setup *OrgSetup) adminSetHoldPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	setup.submit(w, r, roleAdmin, "SetHoldPolicy", []string{string(policy)}, nil, nil)
+}
+
+// adminAssetHolds returns the holds in place on an asset and the transactions
+// that placed them.
+func (setup *OrgSetup) adminAssetHolds(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAdmin, "GetHolds", r.PathValue("id"))
+}
+
+// adminReleaseHold releases the hold placed on an asset by a transaction.
+func (setup *OrgSetup) adminReleaseHold(w http.ResponseWriter, r *http.Request) {
+	setup.submit(w, r, roleAdmin, "ReleaseHold", []string{r.PathValue("id"), r.PathValue("txid")}, nil, nil)
+}
+
+// adminAssetsByStatus returns the assets of a status, such as SUSPENDED, read
+// through the status index of the chaincode.
+func (setup *OrgSetup) adminAssetsByStatus(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAdmin, "QueryAssetsByStatus", r.PathValue("status"))
+}
+
+// adminQueryAssets returns the assets matching the CouchDB query of the JSON
+// request body, such as {"selector":{"status":"ACTIVE"}}.
+func (setup *OrgSetup) adminQueryAssets(w http.ResponseWriter, r *http.Request) {
+	query, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	setup.evaluate(w, r, roleAdmin, "QueryAssets", string(query))
+}
+
+// dealerCreateAsset creates an asset of the caller's dealer. The MSISDN, MPIN and
+// remarks are passed to the chaincode as transient data.
+func (setup *OrgSetup) dealerCreateAsset(w http.ResponseWriter, r *http.Request) {
+	details, err := json.Marshal(map[string]string{
+		"mpin":    r.FormValue("mpin"),
+		"msisdn":  r.FormValue("msisdn"),
+		"remarks": r.FormValue("remarks"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	endorsingOrgs, err := setup.privateWriteEndorsers(r, setup.Channel, setup.Chaincode, []string{assetDetailsCollection})
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	args := []string{
+		r.FormValue("id"),
+		claims(r).DealerID,
+		r.FormValue("balance"),
+		r.FormValue("status"),
+		r.FormValue("transAmount"),
+		r.FormValue("transType"),
+	}
+	setup.submit(w, r, roleDealer, "CreateAsset", args, map[string][]byte{"asset_details": details}, endorsingOrgs)
+}
+
+// dealerReadAsset returns an asset of the caller's dealer, with its version as
+// ETag. Requests with an If-None-Match header listing it get 304 Not Modified.
+func (setup *OrgSetup) dealerReadAsset(w http.ResponseWriter, r *http.Request) {
+	asset, ok := setup.readOwnAsset(w, r)
+	if ok {
+		setup.writeAsset(w, r, asset)
+	}
+}
+
+// dealerPatchAsset changes the status, remarks or metadata of an asset of the
+// caller's dealer with the JSON merge patch in the request body. With an
+// If-Match header, the asset is only changed while it has that ETag.
+func (setup *OrgSetup) dealerPatchAsset(w http.ResponseWriter, r *http.Request) {
+	asset, ok := setup.readOwnAsset(w, r)
+	if !ok {
+		return
+	}
+	transient, ok := checkIfMatch(w, r, asset)
+	if !ok {
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		http.Error(w, "the request body must be a JSON merge patch", http.StatusBadRequest)
+		return
+	}
+
+	var endorsingOrgs []string
+	if _, ok := fields["remarks"]; ok {
+		// the remarks are private details
+		if endorsingOrgs, err = setup.privateWriteEndorsers(r, setup.Channel, setup.Chaincode, []string{assetDetailsCollection}); err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+	}
+	setup.submit(w, r, roleDealer, "PatchAsset", []string{r.PathValue("id"), string(patch)}, transient, endorsingOrgs)
+}
+
+// dealerTransferAsset transfers an asset of the caller's dealer to another
+// dealer. With an If-Match header, the asset is only transferred while it has
+// that ETag.
+func (setup *OrgSetup) dealerTransferAsset(w http.ResponseWriter, r *http.Request) {
+	asset, ok := setup.readOwnAsset(w, r)
+	if !ok {
+		return
+	}
+	transient, ok := checkIfMatch(w, r, asset)
+	if !ok {
+		return
+	}
+	setup.submit(w, r, roleDealer, "TransferAsset", []string{r.PathValue("id"), r.FormValue("newDealerId")}, transient, nil)
+}
+
+// dealerRequestMSISDNChange starts changing the MSISDN of an asset of the
+// caller's dealer to msisdn, confirmed after the cooling-off period with the
+// one-time password whose hash is otpHash. Both are passed as transient data.
+func (setup *OrgSetup) dealerRequestMSISDNChange(w http.ResponseWriter, r *http.Request) {
+	if _, ok := setup.readOwnAsset(w, r); !ok {
+		return
+	}
+	change, err := json.Marshal(map[string]string{
+		"msisdn":  r.FormValue("msisdn"),
+		"otphash": r.FormValue("otpHash"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	endorsingOrgs, err := setup.privateWriteEndorsers(r, setup.Channel, setup.Chaincode, []string{assetDetailsCollection})
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	setup.submit(w, r, roleDealer, "RequestMSISDNChange", []string{r.PathValue("id"), ""}, map[string][]byte{"msisdn_change": change}, endorsingOrgs)
+}
+
+// dealerConfirmMSISDNChange applies the pending MSISDN change of an asset of the
+// caller's dealer with the hash of the one-time password, otpHash.
+func (setup *OrgSetup) dealerConfirmMSISDNChange(w http.ResponseWriter, r *http.Request) {
+	if _, ok := setup.readOwnAsset(w, r); !ok {
+		return
+	}
+	endorsingOrgs, err := setup.privateWriteEndorsers(r, setup.Channel, setup.Chaincode, []string{assetDetailsCollection})
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	setup.submit(w, r, roleDealer, "ConfirmMSISDNChange", []string{r.PathValue("id"), r.FormValue("otpHash")}, nil, endorsingOrgs)
+}
+
+// dealerRequestMPINReset opens a case resetting the MPIN of an asset of the
+// caller's dealer, to be approved by an admin.
+func (setup *OrgSetup) dealerRequestMPINReset(w http.ResponseWriter, r *http.Request) {
+	if _, ok := setup.readOwnAsset(w, r); !ok {
+		return
+	}
+	setup.submit(w, r, roleDealer, "RequestMPINReset", []string{r.PathValue("id")}, nil, nil)
+}
+
+// dealerCompleteMPINReset sets the MPIN of an asset of the caller's dealer once
+// its reset is approved. The hash of the new MPIN, mpinHash, is passed as
+// transient data.
+func (setup *OrgSetup) dealerCompleteMPINReset(w http.ResponseWriter, r *http.Request) {
+	if _, ok := setup.readOwnAsset(w, r); !ok {
+		return
+	}
+	reset, err := json.Marshal(map[string]string{"mpinhash": r.FormValue("mpinHash")})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	endorsingOrgs, err := setup.privateWriteEndorsers(r, setup.Channel, setup.Chaincode, []string{assetDetailsCollection})
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	setup.submit(w, r, roleDealer, "CompleteMPINReset", []string{r.PathValue("id"), ""}, map[string][]byte{"mpin_reset": reset}, endorsingOrgs)
+}
+
+// dealerFloat returns the float of the caller's dealer.
+func (setup *OrgSetup) dealerFloat(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleDealer, "GetDealerFloat", claims(r).DealerID)
+}
+
+// auditorAuditTrail returns the audit trail of an asset.
+func (setup *OrgSetup) auditorAuditTrail(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAuditor, "GetAuditTrail", r.PathValue("id"))
+}
+
+// auditorAssetHistory returns every committed value of an asset.
+func (setup *OrgSetup) auditorAssetHistory(w http.ResponseWriter, r *http.Request) {
+	setup.evaluate(w, r, roleAuditor, "GetAssetHistory", r.PathValue("id"))
+}
+
+// auditorBalanceSeries returns the balance of an asset sampled over time.
+func (setup *OrgSetup) auditorBalanceSeries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	setup.evaluate(w, r, roleAuditor, "GetBalanceSeries", r.PathValue("id"), query.Get("from"), query.Get("to"), query.Get("interval"))
+}
+
+// auditorDataQuality returns a page of the data quality report, resumed with
+// the nextPageToken of the previous page.
+func (setup *OrgSetup) auditorDataQuality(w http.ResponseWriter, r *http.Request) {
+	setup.evaluatePage(w, r, roleAuditor, "RunDataQualityChecks")
+}
+
+// readOwnAsset reads the asset of the request path, writing a not found response
+// when it does not exist or belongs to another dealer than the caller's.
+func (setup *OrgSetup) readOwnAsset(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if err := setup.FunctionPolicy.Check(setup.identityName(r, roleDealer), "ReadAsset", false); err != nil {
+		writeGatewayError(w, err)
+		return nil, false
+	}
+	assetJSON, err := setup.contract(r, roleDealer).EvaluateWithContext(r.Context(), "ReadAsset", client.WithArguments(r.PathValue("id")))
+	if err != nil {
+		writeGatewayError(w, err)
+		return nil, false
+	}
+
+	var asset struct {
+		DealerID string `json:"dealerid"`
+	}
+	if err := json.Unmarshal(assetJSON, &asset); err != nil || asset.DealerID != claims(r).DealerID {
+		http.Error(w, "asset not found", http.StatusNotFound)
+		return nil, false
+	}
+	return assetJSON, true
+}
+
+// gateway returns the Gateway signing the transactions of role for the caller of
+// r: the identity of the caller's API key, otherwise the identity of the role.
+// It returns false when the caller signs as the organization's identity.
+func (setup *OrgSetup) gateway(r *http.Request, role string) (*client.Gateway, bool) {
+	if gateway, ok := setup.identityGateways[claims(r).Identity]; ok {
+		return gateway, true
+	}
+	if gateway, ok := setup.roleGateways[role]; ok {
+		return gateway, true
+	}
+	return &setup.Gateway, false
+}
+
+// orgIdentityName names the organization's identity in the function policy.
+const orgIdentityName = "org"
+
+// identityName returns the name of the identity signing the transactions of role
+// for the caller of r, as gateway picks it, for the function policy.
+func (setup *OrgSetup) identityName(r *http.Request, role string) string {
+	if identity := claims(r).Identity; identity != "" {
+		if _, ok := setup.identityGateways[identity]; ok {
+			return identity
+		}
+	}
+	if _, ok := setup.roleGateways[role]; ok {
+		return role
+	}
+	return orgIdentityName
+}
+
+// contract returns the shared client of the chaincode for the signing identity
+// of role for the caller of r.
+func (setup *OrgSetup) contract(r *http.Request, role string) *assetclient.Client {
+	return setup.clients[setup.identityName(r, role)]
+}
+
+// evaluate evaluates a transaction as role and writes its result.
+func (setup *OrgSetup) evaluate(w http.ResponseWriter, r *http.Request, role string, function string, args ...string) {
+	result, err := setup.evaluateResult(r, role, function, args...)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	writeResult(w, http.StatusOK, result)
+}
+
+// evaluateResult evaluates a transaction as role and returns its result.
+// Callers signing as the organization's identity evaluate on its read peers.
+func (setup *OrgSetup) evaluateResult(r *http.Request, role string, function string, args ...string) ([]byte, error) {
+	return roleEvaluator{setup, r, role}.EvaluateWithContext(r.Context(), function, client.WithArguments(args...))
+}
+
+// roleEvaluator is the assetclient.Evaluator of the transactions a request
+// evaluates as role, for the readers of the assetclient package.
+type roleEvaluator struct {
+	setup *OrgSetup
+	r     *http.Request
+	role  string
+}
+
+func (e roleEvaluator) EvaluateWithContext(ctx context.Context, function string, options ...client.ProposalOption) ([]byte, error) {
+	setup, r, role := e.setup, e.r, e.role
+	if err := setup.FunctionPolicy.Check(setup.identityName(r, role), function, false); err != nil {
+		return nil, err
+	}
+	if _, ok := setup.gateway(r, role); ok {
+		return setup.contract(r, role).EvaluateWithContext(ctx, function, options...)
+	}
+	return setup.readRouter.Evaluate(ctx, setup.Channel, setup.Chaincode, function, options...)
+}
+
+// submit submits a transaction as role and writes its transaction ID and result
+// once the commit wait of the request is met, with the block and the confirming
+// organizations when it waited for the commit. A transaction failing
+// validation is answered as an error. Requests carrying an Idempotency-Key are
+// submitted once, see submitOnce.
+func (setup *OrgSetup) submit(w http.ResponseWriter, r *http.Request, role string, function string, args []string, transient map[string][]byte, endorsingOrgs []string) {
+	setup.submitOnce(w, r, function, args, func(w http.ResponseWriter) {
+		setup.submitTransaction(w, r, role, function, args, transient, endorsingOrgs)
+	})
+}
+
+// submitTransaction submits the transaction of a request, for submit.
+func (setup *OrgSetup) submitTransaction(w http.ResponseWriter, r *http.Request, role string, function string, args []string, transient map[string][]byte, endorsingOrgs []string) {
+	if err := setup.FunctionPolicy.Check(setup.identityName(r, role), function, true); err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	if transient == nil {
+		transient = make(map[string][]byte)
+	}
+	options := []client.ProposalOption{client.WithArguments(args...)}
+	if transient = setup.withAPIUser(r, transient); len(transient) > 0 {
+		options = append(options, client.WithTransient(transient))
+	}
+	if len(endorsingOrgs) > 0 {
+		options = append(options, client.WithEndorsingOrganizations(endorsingOrgs...))
+	}
+	policy, err := setup.commitPolicy(r, function)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	unlock, err := setup.assetLocks.Lock(r.Context(), assetclient.AssetKeys(function, args)...)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	locked := true
+	defer func() {
+		if locked {
+			unlock()
+		}
+	}()
+
+	// infrastructure errors are retried with a new proposal, business rejections are returned
+	// a transaction submitted in the background is not retried
+	var transaction *client.Transaction
+	var commit *client.Commit
+	var submittedAt time.Time
+	err = assetclient.Retry(r.Context(), submitAttempts, submitBackoff, func(ctx context.Context) error {
+		proposal, err := setup.contract(r, role).NewProposal(function, options...)
+		if err != nil {
+			return err
+		}
+		if transaction, err = proposal.EndorseWithContext(ctx); err != nil {
+			return err
+		}
+		submittedAt = time.Now()
+		if policy.Wait == assetclient.WaitNone {
+			return nil
+		}
+		commit, err = transaction.SubmitWithContext(ctx)
+		return err
+	})
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	if setup.txStatuses != nil {
+		setup.txStatuses.Submitted(transaction.TransactionID())
+	}
+	setup.commitMetrics.Submitted(transaction.TransactionID(), function, submittedAt)
+	setup.trackSubmitted(r, role, function, args, transient, transaction)
+
+	var outcome *assetclient.CommitOutcome
+	if policy.Wait == assetclient.WaitNone {
+		outcome, err = setup.commitWaiter.Submit(r.Context(), transaction, policy)
+	} else {
+		outcome, err = setup.commitWaiter.Wait(r.Context(), commit, policy)
+	}
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	if outcome.Status != nil {
+		setup.resolveSubmitted(outcome.TransactionID)
+	} else if commit != nil {
+		locked = false
+		go unlockOnCommit(commit, unlock)
+	}
+	if outcome.Status != nil && !outcome.Status.Successful {
+		writeGatewayError(w, &client.CommitError{TransactionID: outcome.TransactionID, Code: outcome.Status.Code})
+		return
+	}
+
+	response := struct {
+		TransactionID string          `json:"transactionId"`
+		Result        json.RawMessage `json:"result,omitempty"`
+		Wait          string          `json:"wait"`
+		BlockNumber   uint64          `json:"blockNumber,omitempty"`
+		Orgs          []string        `json:"orgs,omitempty"`
+	}{TransactionID: transaction.TransactionID(), Result: resultJSON(transaction.Result()), Wait: policy.String(), Orgs: outcome.Orgs}
+	if outcome.Status != nil {
+		response.BlockNumber = outcome.Status.BlockNumber
+	}
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResult(w, http.StatusOK, responseJSON)
+}
+
+// resultJSON returns a chaincode result as JSON, quoting results that are plain strings.
+func resultJSON(result []byte) json.RawMessage {
+	if len(result) == 0 || json.Valid(result) {
+		return result
+	}
+	quoted, _ := json.Marshal(string(result))
+	return quoted
+}
+
+func writeResult(w http.ResponseWriter, statusCode int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(resultJSON(body)); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+}
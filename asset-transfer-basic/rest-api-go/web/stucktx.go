@@ -0,0 +1,188 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// stuckTxCheckInterval is how often the stuck transactions are checked.
+const stuckTxCheckInterval = 30 * time.Second
+
+// startStuckTxManager opens the log of the transactions whose commit was not
+// confirmed to their submitter and follows them in the background until ctx
+// is done.
+func (setup *OrgSetup) startStuckTxManager(ctx context.Context) error {
+	txLog, err := assetclient.OpenTrackedTxLog(setup.StuckTxLog)
+	if err != nil {
+		return err
+	}
+	deadline := setup.StuckTxDeadline
+	if deadline <= 0 {
+		deadline = 2 * time.Minute
+	}
+	maxResubmissions := setup.MaxResubmissions
+	if maxResubmissions <= 0 {
+		maxResubmissions = 3
+	}
+	idempotent := make(map[string]bool, len(setup.IdempotentFunctions))
+	for _, function := range setup.IdempotentFunctions {
+		idempotent[function] = true
+	}
+
+	setup.stuckTxs = &assetclient.StuckTxManager{
+		Log:              txLog,
+		Deadline:         deadline,
+		MaxResubmissions: maxResubmissions,
+		Idempotent:       idempotent,
+		Status: func(txID string) (assetclient.TxStatus, bool) {
+			status, ok := setup.txStatuses.Get(txID)
+			return status, ok && status.Committed()
+		},
+		Resend:  setup.resendTransaction,
+		Endorse: setup.endorseAgain,
+	}
+	go setup.stuckTxs.Run(ctx, stuckTxCheckInterval)
+	log.Printf("Following %d stuck transactions\n", len(txLog.List()))
+	return nil
+}
+
+// trackSubmitted starts following a submitted transaction, until its
+// submitter learns its outcome.
+func (setup *OrgSetup) trackSubmitted(r *http.Request, role string, function string, args []string, transient map[string][]byte, transaction *client.Transaction) {
+	if setup.stuckTxs == nil {
+		return
+	}
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		log.Printf("Failed to track transaction %s: %s", transaction.TransactionID(), err)
+		return
+	}
+	err = setup.stuckTxs.Track(assetclient.TrackedTx{
+		TransactionID: transaction.TransactionID(),
+		Function:      function,
+		Args:          args,
+		Identity:      setup.identityName(r, role),
+		Transaction:   transactionBytes,
+		Transient:     len(transient) > 0,
+	})
+	if err != nil {
+		log.Printf("Failed to track transaction %s: %s", transaction.TransactionID(), err)
+	}
+}
+
+// resolveSubmitted stops following a transaction whose outcome its submitter
+// learned.
+func (setup *OrgSetup) resolveSubmitted(txID string) {
+	if setup.stuckTxs == nil {
+		return
+	}
+	if err := setup.stuckTxs.Resolve(txID); err != nil {
+		log.Printf("Failed to stop tracking transaction %s: %s", txID, err)
+	}
+}
+
+// resendTransaction submits a lost transaction again, unchanged, and waits for
+// its commit as long as the manager would before resending it again.
+func (setup *OrgSetup) resendTransaction(ctx context.Context, tx assetclient.TrackedTx) (*assetclient.TxStatus, error) {
+	transaction, err := setup.Gateway.NewTransaction(tx.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore the signed transaction: %w", err)
+	}
+	commit, err := transaction.SubmitWithContext(ctx)
+	if err != nil {
+		return nil, assetclient.NewMultiPeerError(err)
+	}
+
+	statusCtx, cancel := context.WithTimeout(ctx, setup.stuckTxs.Deadline)
+	defer cancel()
+	status, err := commit.StatusWithContext(statusCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assetclient.TxStatus{
+		TransactionID: status.TransactionID,
+		Status:        status.Code.String(),
+		Code:          int32(status.Code),
+		BlockNumber:   status.BlockNumber,
+	}, nil
+}
+
+// endorseAgain endorses the function of an invalidated transaction again as
+// the identity that signed it, and submits it as a new transaction.
+func (setup *OrgSetup) endorseAgain(ctx context.Context, tx assetclient.TrackedTx) (assetclient.TrackedTx, error) {
+	contract, ok := setup.clients[tx.Identity]
+	if !ok {
+		return assetclient.TrackedTx{}, fmt.Errorf("the identity %s is no longer configured", tx.Identity)
+	}
+	proposal, err := contract.NewProposal(tx.Function, client.WithArguments(tx.Args...))
+	if err != nil {
+		return assetclient.TrackedTx{}, err
+	}
+	transaction, err := proposal.EndorseWithContext(ctx)
+	if err != nil {
+		return assetclient.TrackedTx{}, assetclient.NewMultiPeerError(err)
+	}
+	if _, err := transaction.SubmitWithContext(ctx); err != nil {
+		return assetclient.TrackedTx{}, assetclient.NewMultiPeerError(err)
+	}
+	setup.txStatuses.Submitted(transaction.TransactionID())
+
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		return assetclient.TrackedTx{}, err
+	}
+	return assetclient.TrackedTx{
+		TransactionID: transaction.TransactionID(),
+		Function:      tx.Function,
+		Args:          tx.Args,
+		Identity:      tx.Identity,
+		Transaction:   transactionBytes,
+	}, nil
+}
+
+// adminStuckTransactions lists the transactions whose commit was not
+// confirmed, oldest first, optionally only those in the state query parameter:
+// PENDING, LOST or INVALIDATED.
+func (setup *OrgSetup) adminStuckTransactions(w http.ResponseWriter, r *http.Request) {
+	if setup.stuckTxs == nil {
+		http.Error(w, "stuck transactions are not tracked", http.StatusNotFound)
+		return
+	}
+	state := r.URL.Query().Get("state")
+	txs := []assetclient.TrackedTx{}
+	for _, tx := range setup.stuckTxs.Report() {
+		if state == "" || tx.State == state {
+			txs = append(txs, tx)
+		}
+	}
+	writeJSON(w, http.StatusOK, txs)
+}
+
+// adminDismissStuckTransaction stops following a transaction, such as a lost
+// transaction an operator resolved by hand.
+func (setup *OrgSetup) adminDismissStuckTransaction(w http.ResponseWriter, r *http.Request) {
+	if setup.stuckTxs == nil {
+		http.Error(w, "stuck transactions are not tracked", http.StatusNotFound)
+		return
+	}
+	txID := r.PathValue("txid")
+	if _, ok := setup.stuckTxs.Log.Get(txID); !ok {
+		http.Error(w, "transaction "+txID+" is not tracked", http.StatusNotFound)
+		return
+	}
+	if err := setup.stuckTxs.Resolve(txID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
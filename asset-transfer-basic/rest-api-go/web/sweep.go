@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// maxSweeps bounds the SweepExpired transactions submitted at each interval,
+// leaving the rest of a large backlog to the next one.
+const maxSweeps = 20
+
+// sweepResult is the part of the SweepExpired result the sweeper reads.
+type sweepResult struct {
+	More     bool              `json:"more"`
+	Released []json.RawMessage `json:"released"`
+}
+
+// startExpirySweeper submits SweepExpired every SweepInterval as the admin
+// role, until ctx is done, releasing the records past their expiry in the
+// expiry index of the chaincode.
+func (setup *OrgSetup) startExpirySweeper(ctx context.Context) error {
+	if _, ok := setup.clients[roleAdmin]; !ok {
+		return fmt.Errorf("sweeping expired records requires the %s role identity", roleAdmin)
+	}
+	if err := setup.FunctionPolicy.Check(roleAdmin, "SweepExpired", true); err != nil {
+		return err
+	}
+	go setup.runExpirySweeper(ctx, setup.SweepInterval)
+	log.Printf("Sweeping expired records every %s\n", setup.SweepInterval)
+	return nil
+}
+
+func (setup *OrgSetup) runExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := setup.sweepExpired(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to sweep expired records: %s", err)
+			}
+		}
+	}
+}
+
+// sweepExpired submits SweepExpired until it reports nothing more to release,
+// or maxSweeps transactions were submitted.
+func (setup *OrgSetup) sweepExpired(ctx context.Context) error {
+	contract := setup.clients[roleAdmin]
+	for range maxSweeps {
+		resultBytes, err := contract.SubmitWithContext(ctx, "SweepExpired", client.WithArguments("0"))
+		if err != nil {
+			return err
+		}
+		var result sweepResult
+		if err := json.Unmarshal(resultBytes, &result); err != nil {
+			return fmt.Errorf("failed to parse the sweep result: %w", err)
+		}
+		if len(result.Released) > 0 {
+			log.Printf("Released %d expired records", len(result.Released))
+		}
+		if !result.More {
+			return nil
+		}
+	}
+	return nil
+}
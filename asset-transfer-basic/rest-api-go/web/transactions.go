@@ -0,0 +1,39 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// trackCommits records the commit status of the transactions of every block of
+// the channel in the transaction status store, reconnecting to the block events
+// when they stop, until ctx is done.
+func (setup *OrgSetup) trackCommits(ctx context.Context) {
+	network := setup.Gateway.GetNetwork(setup.Channel)
+	for ctx.Err() == nil {
+		blocks, err := network.FilteredBlockEvents(ctx)
+		if err != nil {
+			log.Printf("Failed to read block events: %s", err)
+		} else {
+			setup.txStatuses.Run(ctx, blocks)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// transactionStatus returns the commit status of a recent transaction: PENDING
+// until it is seen in a block, then its validation code.
+func (setup *OrgSetup) transactionStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := setup.txStatuses.Get(r.PathValue("txid"))
+	if !ok {
+		http.Error(w, "no recent transaction "+r.PathValue("txid")+" is known", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
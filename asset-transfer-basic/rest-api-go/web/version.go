@@ -0,0 +1,42 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"assetTransfer/pkg/assetclient"
+)
+
+// logChaincodeVersions logs the chaincode build of the Gateway peer and of every
+// read peer, warning when they differ.
+func (setup *OrgSetup) logChaincodeVersions(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	versions := setup.readRouter.PeerVersions(ctx, setup.Channel, setup.Chaincode, setup.PeerEndpoint)
+	for _, version := range versions {
+		if version.Version == nil {
+			log.Printf("Failed to read the chaincode version of %s: %s", version.Peer, version.Error)
+			continue
+		}
+		log.Printf("Chaincode %s on %s: %s", setup.Chaincode, version.Peer, version.Version)
+	}
+	if assetclient.VersionsDiffer(versions) {
+		log.Printf("Warning: the peers run different builds of chaincode %s", setup.Chaincode)
+	}
+}
+
+// adminChaincodeVersions returns the chaincode build of the Gateway peer and of
+// every read peer, and whether they all run the same build.
+func (setup *OrgSetup) adminChaincodeVersions(w http.ResponseWriter, r *http.Request) {
+	if err := setup.FunctionPolicy.Check(orgIdentityName, "GetVersionInfo", false); err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	versions := setup.readRouter.PeerVersions(r.Context(), setup.Channel, setup.Chaincode, setup.PeerEndpoint)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"peers":      versions,
+		"consistent": !assetclient.VersionsDiffer(versions),
+	})
+}